@@ -0,0 +1,765 @@
+package pool
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// maxPolicyModuleSize is max size of
+// policy module uploaded via admin api
+const maxPolicyModuleSize = 16 << 20
+
+// maxMembershipDocumentSize is max size of
+// membership document imported via admin api
+const maxMembershipDocumentSize = 64 << 20
+
+// maxAdminRequestSize is max size of body of
+// other requests to admin api, e.g. maintenance
+const maxAdminRequestSize = 1 << 20
+
+// reportDefaultWindows is number of windows
+// included in report if from is not given
+const reportDefaultWindows = 24
+
+// defaultMaxDocumentAge is max age of imported
+// membership documents if it is not configured
+const defaultMaxDocumentAge = time.Hour
+
+// adminReadHeaderTimeout is time given to
+// admin api clients to send request headers
+const adminReadHeaderTimeout = 10 * time.Second
+
+// AdminHandler is http handler that expose
+// services list introspection and control endpoints
+type AdminHandler struct {
+	list     IAdmin
+	factory  ServiceFactory
+	discover func(ctx context.Context) error
+
+	signingKeyID string
+	signingKey   ed25519.PrivateKey
+	trustedKeys  map[string]ed25519.PublicKey
+
+	acceptUnsigned bool
+	maxDocumentAge time.Duration
+	imported       *importedDocuments
+
+	authorize       func(r *http.Request) error
+	unauthenticated bool
+
+	mux *http.ServeMux
+}
+
+// AdminHandlerOpts is options that needs
+// to configure AdminHandler instance
+type AdminHandlerOpts struct {
+	ServiceFactory  ServiceFactory                  // creates services from imported membership documents (DefaultServiceFactory by default)
+	SigningKeyID    string                          // id of the key exported documents are signed with
+	SigningKey      ed25519.PrivateKey              // key to sign exported documents (nil to export unsigned documents)
+	TrustedKeys     map[string]ed25519.PublicKey    // keys by id to verify imported documents (nil to refuse imports unless AcceptUnsigned is set)
+	AcceptUnsigned  bool                            // import unsigned documents if no trusted keys are configured
+	MaxDocumentAge  time.Duration                   // imported documents exported longer ago are rejected (1 hour by default)
+	Discover        func(ctx context.Context) error // immediate rediscovery triggered via admin api, e.g. ServicesPool.DiscoverServicesContext (nil to disable)
+	Authorize       func(r *http.Request) error     // authorization of mutating requests, e.g. BearerTokenAuth (nil to refuse them unless Unauthenticated is set)
+	Unauthenticated bool                            // accept mutating requests without authorization, e.g. handler mounted behind own authentication
+}
+
+// serviceView is json representation of service
+type serviceView struct {
+	ID       string `json:"id"`
+	Address  string `json:"address"`
+	NodeName string `json:"node_name"`
+	Status   string `json:"status"`
+}
+
+// reviewView is json representation
+// of service waiting for review
+type reviewView struct {
+	serviceView
+	Reason string    `json:"reason"`
+	Since  time.Time `json:"since"`
+}
+
+// maintenanceRequest is json representation
+// of maintenance window scheduling request
+type maintenanceRequest struct {
+	Action   MaintenanceAction `json:"action"`
+	Start    time.Time         `json:"start"`    // RFC3339, omitted to start immediately
+	Duration string            `json:"duration"` // e.g. "2h", omitted to keep until cancelled
+	Reason   string            `json:"reason"`
+}
+
+// maintenanceView is json representation of maintenance
+// window with human readable duration
+type maintenanceView struct {
+	MaintenanceWindow
+	Duration string `json:"duration,omitempty"`
+}
+
+// featureRequest is json representation
+// of feature flag toggling request
+type featureRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// statsView is json representation of membership
+// sizes and moving statistics of members
+type statsView struct {
+	Healthy  int                       `json:"healthy"`
+	Jailed   int                       `json:"jailed"`
+	Review   int                       `json:"review"`
+	Services map[string]stats.Snapshot `json:"services"` // service id -> moving statistics, empty if statistics are disabled
+}
+
+// errorView is json representation of error
+type errorView struct {
+	Error string `json:"error"`
+}
+
+// NewAdminHandler create new AdminHandler for given services
+// list which refuses mutating requests, authorization of them
+// or explicit opt out of it for handler mounted behind own
+// authentication is configured with NewAdminHandlerWithOpts
+func NewAdminHandler(list IAdmin) *AdminHandler {
+	return NewAdminHandlerWithOpts(list, &AdminHandlerOpts{})
+}
+
+// NewAdminHandlerWithOpts create new AdminHandler
+// for given services list with given configuration
+func NewAdminHandlerWithOpts(list IAdmin, opts *AdminHandlerOpts) *AdminHandler {
+	h := &AdminHandler{
+		list:     list,
+		factory:  opts.ServiceFactory,
+		discover: opts.Discover,
+
+		signingKeyID: opts.SigningKeyID,
+		signingKey:   opts.SigningKey,
+		trustedKeys:  opts.TrustedKeys,
+
+		acceptUnsigned: opts.AcceptUnsigned,
+		maxDocumentAge: opts.MaxDocumentAge,
+		imported:       &importedDocuments{},
+
+		authorize:       opts.Authorize,
+		unauthenticated: opts.Unauthenticated,
+
+		mux: http.NewServeMux(),
+	}
+
+	h.mux.HandleFunc("GET /snapshot", h.handleSnapshot)
+	h.mux.HandleFunc("GET /services", h.handleFind)
+	h.mux.HandleFunc("GET /memory", h.handleMemoryUsage)
+	h.mux.HandleFunc("GET /review", h.handleReviewList)
+	h.mux.HandleFunc("POST /review/{id}/approve", h.handleReviewApprove)
+	h.mux.HandleFunc("POST /review/{id}/reject", h.handleReviewReject)
+	h.mux.HandleFunc("GET /reports/availability", h.handleAvailabilityReport)
+	h.mux.HandleFunc("PUT /policies/{name}", h.handlePolicyReload)
+	h.mux.HandleFunc("GET /membership", h.handleMembershipExport)
+	h.mux.HandleFunc("POST /membership", h.handleMembershipImport)
+	h.mux.HandleFunc("PUT /services/{id}/spare", h.handleSpareSet(true))
+	h.mux.HandleFunc("DELETE /services/{id}/spare", h.handleSpareSet(false))
+	h.mux.HandleFunc("POST /services/{id}/spare/activate", h.handleSpareActivate(true))
+	h.mux.HandleFunc("POST /services/{id}/spare/standby", h.handleSpareActivate(false))
+	h.mux.HandleFunc("POST /services/{id}/maintenance", h.handleMaintenanceSchedule)
+	h.mux.HandleFunc("GET /maintenance", h.handleMaintenanceList)
+	h.mux.HandleFunc("DELETE /maintenance/{id}", h.handleMaintenanceCancel)
+	h.mux.HandleFunc("GET /audit", h.handleAuditLog)
+	h.mux.HandleFunc("GET /tombstones", h.handleTombstones)
+	h.mux.HandleFunc("GET /starved", h.handleStarved)
+	h.mux.HandleFunc("GET /features", h.handleFeatures)
+	h.mux.HandleFunc("PUT /features/{name}", h.handleFeatureSet)
+	h.mux.HandleFunc("POST /services/{id}/jail", h.handleForceJail)
+	h.mux.HandleFunc("POST /services/{id}/recover", h.handleForceRecover)
+	h.mux.HandleFunc("GET /stats", h.handleStats)
+
+	if h.discover != nil {
+		h.mux.HandleFunc("POST /discovery", h.handleDiscover)
+	}
+
+	return h
+}
+
+// ServeHTTP dispatch request to the admin endpoint,
+// mutating requests are authorized first
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.authorizeRequest(r); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	h.mux.ServeHTTP(w, r)
+}
+
+// authorizeRequest check given request is read-only or
+// authorized, mutating requests are refused if
+// authorization is not configured
+func (h *AdminHandler) authorizeRequest(r *http.Request) error {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || h.unauthenticated {
+		return nil
+	}
+
+	if h.authorize == nil {
+		return ErrUnauthorized{Reason: "mutating requests are disabled without authorization"}
+	}
+
+	return h.authorize(r)
+}
+
+// BearerTokenAuth return authorization of admin requests
+// carrying one of given tokens in Authorization header
+func BearerTokenAuth(tokens ...string) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			return ErrUnauthorized{Reason: "bearer token is missing"}
+		}
+
+		for _, expected := range tokens {
+			if expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1 {
+				return nil
+			}
+		}
+
+		return ErrUnauthorized{Reason: "bearer token is not valid"}
+	}
+}
+
+// handleSnapshot respond with services list snapshot
+// including build and configuration information
+func (h *AdminHandler) handleSnapshot(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.list.Snapshot())
+}
+
+// handleFind respond with snapshots of services matched
+// by query parameters, e.g. ?tag=gpu&membership=jailed
+func (h *AdminHandler) handleFind(w http.ResponseWriter, r *http.Request) {
+	matchers, err := ParseQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.list.Find(matchers...))
+}
+
+// handleMemoryUsage respond with estimation
+// of memory used by list bookkeeping
+func (h *AdminHandler) handleMemoryUsage(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.list.MemoryUsage())
+}
+
+// handleMembershipExport respond with portable document with
+// current list membership, signed if signing key is configured
+func (h *AdminHandler) handleMembershipExport(w http.ResponseWriter, _ *http.Request) {
+	doc := ExportMembership(h.list)
+
+	if h.signingKey == nil {
+		writeJSON(w, http.StatusOK, doc)
+		return
+	}
+
+	signed, err := SignMembership(doc, h.signingKeyID, h.signingKey)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, signed)
+}
+
+// handleMembershipImport apply membership document from request
+// body to the list, mode query param is additive or replace.
+// Signed documents are required unless unsigned ones are
+// accepted explicitly, documents older than the last imported
+// one or max age are rejected
+func (h *AdminHandler) handleMembershipImport(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, maxMembershipDocumentSize)
+
+	doc := &MembershipDocument{}
+	switch {
+	case h.trustedKeys != nil:
+		var signed SignedMembershipDocument
+		if err := json.NewDecoder(body).Decode(&signed); err != nil {
+			writeJSON(w, http.StatusBadRequest, &errorView{Error: fmt.Sprintf("decode signed membership document: %s", err)})
+			return
+		}
+
+		var err error
+		if doc, err = VerifyMembership(&signed, h.trustedKeys); err != nil {
+			writeError(w, err)
+			return
+		}
+	case h.acceptUnsigned:
+		if err := json.NewDecoder(body).Decode(doc); err != nil {
+			writeJSON(w, http.StatusBadRequest, &errorView{Error: fmt.Sprintf("decode membership document: %s", err)})
+			return
+		}
+	default:
+		writeError(w, ErrInvalidSignature{Reason: "no trusted keys are configured"})
+		return
+	}
+
+	maxAge := h.maxDocumentAge
+	if maxAge == 0 {
+		maxAge = defaultMaxDocumentAge
+	}
+
+	if err := checkMembership(h.list, doc); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if err := h.imported.admit(doc, maxAge, time.Now()); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	mode := ImportMode(r.URL.Query().Get("mode"))
+	if err := ImportMembership(h.list, doc, mode, h.factory); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReviewList respond with all
+// services waiting for review
+func (h *AdminHandler) handleReviewList(w http.ResponseWriter, _ *http.Request) {
+	items := h.list.UnderReview()
+
+	views := make([]reviewView, 0, len(items))
+	for _, item := range items {
+		views = append(views, reviewView{
+			serviceView: newServiceView(item.Service),
+			Reason:      item.Reason,
+			Since:       item.Since,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+// handleReviewApprove release service
+// with given id from quarantine
+func (h *AdminHandler) handleReviewApprove(w http.ResponseWriter, r *http.Request) {
+	if err := h.list.Approve(r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleReviewReject remove service with
+// given id from quarantine
+func (h *AdminHandler) handleReviewReject(w http.ResponseWriter, r *http.Request) {
+	if err := h.list.Reject(r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSpareSet return handler that designate service with
+// given id as hot spare or remove the designation
+func (h *AdminHandler) handleSpareSet(spare bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.list.SetSpare(r.PathValue("id"), spare); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleSpareActivate return handler that activate spare
+// with given id or return it to standby
+func (h *AdminHandler) handleSpareActivate(active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.list.ActivateSpare(r.PathValue("id"), active); err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleMaintenanceSchedule schedule cordon or jail
+// of service with given id during requested window
+func (h *AdminHandler) handleMaintenanceSchedule(w http.ResponseWriter, r *http.Request) {
+	var req maintenanceRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminRequestSize)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, &errorView{Error: fmt.Sprintf("decode maintenance request: %s", err)})
+		return
+	}
+
+	window := MaintenanceWindow{
+		Service: r.PathValue("id"),
+		Action:  req.Action,
+		Start:   req.Start,
+		Reason:  req.Reason,
+	}
+
+	if req.Duration != "" {
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration < 0 {
+			writeJSON(w, http.StatusBadRequest, &errorView{Error: fmt.Sprintf("invalid duration value %q", req.Duration)})
+			return
+		}
+		window.Duration = duration
+	}
+
+	window, err := h.list.ScheduleMaintenance(window)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newMaintenanceView(window))
+}
+
+// handleMaintenanceList respond with scheduled
+// and active maintenance windows
+func (h *AdminHandler) handleMaintenanceList(w http.ResponseWriter, _ *http.Request) {
+	windows := h.list.Maintenance()
+
+	views := make([]maintenanceView, 0, len(windows))
+	for _, window := range windows {
+		views = append(views, newMaintenanceView(window))
+	}
+
+	writeJSON(w, http.StatusOK, views)
+}
+
+// handleMaintenanceCancel cancel maintenance window with given id
+func (h *AdminHandler) handleMaintenanceCancel(w http.ResponseWriter, r *http.Request) {
+	if err := h.list.CancelMaintenance(r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAuditLog respond with operator
+// actions applied to the list
+func (h *AdminHandler) handleAuditLog(w http.ResponseWriter, _ *http.Request) {
+	entries := h.list.AuditLog()
+	if entries == nil {
+		entries = []AuditEntry{}
+	}
+
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// handleTombstones respond with tombstones
+// of recently removed services
+func (h *AdminHandler) handleTombstones(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.list.Tombstones())
+}
+
+// handleStarved respond with healthy services
+// that are not selected during starvation window
+func (h *AdminHandler) handleStarved(w http.ResponseWriter, _ *http.Request) {
+	starved := h.list.Starved()
+	if starved == nil {
+		starved = []StarvedService{}
+	}
+
+	writeJSON(w, http.StatusOK, starved)
+}
+
+// handleFeatures respond with feature flags of the list
+func (h *AdminHandler) handleFeatures(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.list.Features())
+}
+
+// handleFeatureSet turn feature with given name on or off
+func (h *AdminHandler) handleFeatureSet(w http.ResponseWriter, r *http.Request) {
+	var req featureRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAdminRequestSize)).Decode(&req); err != nil || req.Enabled == nil {
+		writeJSON(w, http.StatusBadRequest, &errorView{Error: "feature request should have enabled flag"})
+		return
+	}
+
+	name := Feature(r.PathValue("name"))
+	h.list.SetFeature(name, *req.Enabled)
+
+	writeJSON(w, http.StatusOK, &FeatureFlag{Name: name, Enabled: *req.Enabled})
+}
+
+// handleForceJail move healthy service
+// with given id to the jail
+func (h *AdminHandler) handleForceJail(w http.ResponseWriter, r *http.Request) {
+	if err := h.list.ForceJail(r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleForceRecover move jailed service
+// with given id back to healthy
+func (h *AdminHandler) handleForceRecover(w http.ResponseWriter, r *http.Request) {
+	if err := h.list.ForceRecover(r.PathValue("id")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleStats respond with membership sizes
+// and moving statistics of members
+func (h *AdminHandler) handleStats(w http.ResponseWriter, _ *http.Request) {
+	view := &statsView{Services: h.list.Stats().Snapshot()}
+	if view.Services == nil {
+		view.Services = map[string]stats.Snapshot{}
+	}
+
+	for _, srv := range h.list.Snapshot().Services {
+		switch srv.Membership {
+		case MembershipHealthy:
+			view.Healthy++
+		case MembershipJailed:
+			view.Jailed++
+		case MembershipReview:
+			view.Review++
+		}
+	}
+
+	writeJSON(w, http.StatusOK, view)
+}
+
+// handleDiscover run immediate rediscovery
+func (h *AdminHandler) handleDiscover(w http.ResponseWriter, r *http.Request) {
+	if err := h.discover(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAvailabilityReport respond with availability report
+// as json or csv, window could be given as duration or
+// as "hourly" and "daily" aliases
+func (h *AdminHandler) handleAvailabilityReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	window, err := parseReportWindow(query.Get("window"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &errorView{Error: err.Error()})
+		return
+	}
+
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			writeJSON(w, http.StatusBadRequest, &errorView{Error: fmt.Sprintf("invalid to value: %s", err)})
+			return
+		}
+	}
+
+	from := to.Add(-reportDefaultWindows * window)
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			writeJSON(w, http.StatusBadRequest, &errorView{Error: fmt.Sprintf("invalid from value: %s", err)})
+			return
+		}
+	}
+
+	report := h.list.AvailabilityReport(window, from, to)
+
+	if query.Get("format") == "csv" {
+		w.Header().Set("content-type", "text/csv")
+		if err := report.WriteCSV(w); err != nil {
+			log().Warn("write availability report error", "error", err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handlePolicyReload replace implementation of reloadable
+// policy with given name by the module from request body
+func (h *AdminHandler) handlePolicyReload(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var reloadable IReloadablePolicy
+	for _, policy := range h.list.Policies() {
+		if p, ok := policy.(IReloadablePolicy); ok && policy.Name() == name {
+			reloadable = p
+			break
+		}
+	}
+
+	if reloadable == nil {
+		writeJSON(w, http.StatusNotFound, &errorView{Error: fmt.Sprintf("reloadable policy %q is not found", name)})
+		return
+	}
+
+	module, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPolicyModuleSize))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &errorView{Error: fmt.Sprintf("read policy module: %s", err)})
+		return
+	}
+
+	if err := reloadable.Reload(module); err != nil {
+		writeJSON(w, http.StatusBadRequest, &errorView{Error: err.Error()})
+		return
+	}
+
+	log().Info("policy is reloaded via admin api", "policy", name)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseReportWindow parse report window
+// from duration string or alias
+func parseReportWindow(v string) (time.Duration, error) {
+	switch v {
+	case "", "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	}
+
+	window, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window value: %w", err)
+	}
+
+	return window, nil
+}
+
+// newMaintenanceView create maintenanceView
+// from given maintenance window
+func newMaintenanceView(window MaintenanceWindow) maintenanceView {
+	view := maintenanceView{MaintenanceWindow: window}
+	if window.Duration > 0 {
+		view.Duration = window.Duration.String()
+	}
+
+	return view
+}
+
+// newServiceView create serviceView from given service
+func newServiceView(srv service.IService) serviceView {
+	return serviceView{
+		ID:       srv.ID(),
+		Address:  srv.Address(),
+		NodeName: srv.NodeName(),
+		Status:   srv.Status().String(),
+	}
+}
+
+// writeJSON write given value to
+// response as json with given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log().Warn("write admin response error", "error", err)
+	}
+}
+
+// writeError write given error to response
+// with status that corresponds to error type
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+
+	var (
+		notFound     ErrServiceNotFound
+		badDocument  ErrUnsupportedDocument
+		badMode      ErrUnsupportedImportMode
+		badSignature ErrInvalidSignature
+		notSpare     ErrNotSpare
+		held         ErrServiceHeld
+		badAction    ErrUnsupportedMaintenanceAction
+		noWindow     ErrMaintenanceNotFound
+		badQuery     ErrInvalidQuery
+		unauthorized ErrUnauthorized
+		rejected     ErrRejectedDocument
+	)
+
+	switch {
+	case errors.As(err, &notFound), errors.As(err, &noWindow):
+		status = http.StatusNotFound
+	case errors.As(err, &badDocument), errors.As(err, &badMode), errors.As(err, &badAction), errors.As(err, &badQuery):
+		status = http.StatusBadRequest
+	case errors.As(err, &badSignature):
+		status = http.StatusForbidden
+	case errors.As(err, &unauthorized):
+		status = http.StatusUnauthorized
+	case errors.As(err, &notSpare), errors.As(err, &held), errors.As(err, &rejected):
+		status = http.StatusConflict
+	}
+
+	writeJSON(w, status, &errorView{Error: err.Error()})
+}
+
+// ServeAdmin serve admin api of the pool list on given address
+// with given configuration until the pool is closed, so operators
+// could inspect the pool, jail or recover services and trigger
+// immediate rediscovery without restarting the process. Mutating
+// requests are refused unless authorization is configured, nil
+// options serve read-only api. Nil is returned once the pool
+// is closed
+func (p *ServicesPool) ServeAdmin(addr string, opts *AdminHandlerOpts) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen admin api of pool %s: %w", p.name, err)
+	}
+
+	return p.serveAdmin(listener, opts)
+}
+
+// serveAdmin serve admin api of the pool list on given
+// listener with given configuration until the pool is closed
+func (p *ServicesPool) serveAdmin(listener net.Listener, opts *AdminHandlerOpts) error {
+	configured := AdminHandlerOpts{}
+	if opts != nil {
+		configured = *opts
+	}
+	if configured.Discover == nil {
+		configured.Discover = p.DiscoverServicesContext
+	}
+
+	server := &http.Server{
+		Handler:           NewAdminHandlerWithOpts(p.list, &configured),
+		ReadHeaderTimeout: adminReadHeaderTimeout,
+	}
+
+	stop := context.AfterFunc(p.ctx, func() {
+		_ = server.Close()
+	})
+	defer stop()
+
+	p.log().Info("admin api listen", "address", listener.Addr().String())
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
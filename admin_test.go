@@ -0,0 +1,168 @@
+package pool
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServeAdmin(t *testing.T) {
+	discovery := &staticDiscovery{}
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:              "testServicesPool",
+		Discovery:         discovery,
+		DiscoveryInterval: time.Hour,
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  time.Hour,
+			ChecksInterval: time.Hour,
+		},
+	}).(*ServicesPool)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected listen error: %s", err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- pool.serveAdmin(listener, &AdminHandlerOpts{Authorize: BearerTokenAuth("secret")})
+	}()
+
+	url := fmt.Sprintf("http://%s", listener.Addr())
+
+	// discovery is refreshed on demand
+	discovery.services = []service.IService{newHealthyService("https://1gateway.fm")}
+
+	resp, err := http.Post(url+"/discovery", "", nil)
+	if err != nil {
+		t.Fatalf("unexpected request error: %s", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized || pool.Count() != 0 {
+		t.Fatalf("request without token should be refused, got %d and %d services", resp.StatusCode, pool.Count())
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, url+"/discovery", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected request error: %s", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent || pool.Count() != 1 {
+		t.Fatalf("services should be discovered, got %d and %d services", resp.StatusCode, pool.Count())
+	}
+
+	resp, err = http.Get(url + "/stats")
+	if err != nil {
+		t.Fatalf("unexpected request error: %s", err)
+	}
+
+	var view statsView
+	err = json.NewDecoder(resp.Body).Decode(&view)
+	resp.Body.Close()
+
+	if err != nil || view.Healthy != 1 || view.Jailed != 0 || view.Services == nil {
+		t.Errorf("unexpected stats %+v: %v", view, err)
+	}
+
+	pool.Close()
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("unexpected serve error: %s", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("admin api should be stopped with the pool")
+	}
+}
+
+func TestAdminHandlerAuthorization(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	tests := []struct {
+		name     string
+		opts     *AdminHandlerOpts
+		token    string
+		expected int
+	}{
+		{"not configured", &AdminHandlerOpts{}, "", http.StatusUnauthorized},
+		{"missing token", &AdminHandlerOpts{Authorize: BearerTokenAuth("secret")}, "", http.StatusUnauthorized},
+		{"invalid token", &AdminHandlerOpts{Authorize: BearerTokenAuth("secret")}, "guess", http.StatusUnauthorized},
+		{"valid token", &AdminHandlerOpts{Authorize: BearerTokenAuth("secret")}, "secret", http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		handler := NewAdminHandlerWithOpts(list, tt.opts)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: read-only request should be served, got %d", tt.name, rec.Code)
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/services/"+srv.ID()+"/jail", nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.expected, rec.Code)
+		}
+	}
+}
+
+func TestAdminHandlerDefaults(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	// mutating requests are refused without authorization
+	rec := httptest.NewRecorder()
+	NewAdminHandler(list).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/services/"+srv.ID()+"/jail", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized mutating request, got %d", rec.Code)
+	}
+
+	// request bodies are bounded, authorization
+	// is opted out explicitly
+	body := `{"action":"cordon","reason":"` + strings.Repeat("a", maxAdminRequestSize) + `"}`
+	rec = httptest.NewRecorder()
+	handler := NewAdminHandlerWithOpts(list, &AdminHandlerOpts{Unauthenticated: true})
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/services/"+srv.ID()+"/maintenance", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected oversized request to be rejected, got %d", rec.Code)
+	}
+	if len(list.Maintenance()) != 0 {
+		t.Errorf("oversized request should not schedule maintenance")
+	}
+}
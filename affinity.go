@@ -0,0 +1,177 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const defaultSessionTTL = 5 * time.Minute
+
+// sessionPin is service session is pinned to
+type sessionPin struct {
+	id      string
+	expires time.Time
+}
+
+// sessions is affinity map of caller sessions to services,
+// pins expire after configured period since theirs last use
+// and expired pins are pruned lazily
+type sessions struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	pins   map[string]sessionPin // session id -> pinned service
+	pruned time.Time
+}
+
+// newSessions create affinity map keeping
+// pins for given period since theirs last use
+func newSessions(ttl time.Duration) *sessions {
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+
+	return &sessions{
+		ttl:  ttl,
+		pins: make(map[string]sessionPin),
+	}
+}
+
+// pinned return id of service given session is pinned to
+func (s *sessions) pinned(session string) (string, bool) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	pin, ok := s.pins[session]
+	if !ok || time.Now().After(pin.expires) {
+		return "", false
+	}
+
+	return pin.id, true
+}
+
+// pin given session to service with given id,
+// pin of the session is extended if it exists
+func (s *sessions) pin(session, id string) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	now := time.Now()
+	if now.Sub(s.pruned) > s.ttl {
+		s.prune(now)
+	}
+
+	s.pins[session] = sessionPin{id: id, expires: now.Add(s.ttl)}
+}
+
+// unpin remove pin of given session
+func (s *sessions) unpin(session string) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	delete(s.pins, session)
+}
+
+// count return number of pinned sessions
+func (s *sessions) count() int {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	return len(s.pins)
+}
+
+// prune remove expired pins. Should
+// be called under the sessions lock
+func (s *sessions) prune(now time.Time) {
+	for session, pin := range s.pins {
+		if now.After(pin.expires) {
+			delete(s.pins, session)
+		}
+	}
+	s.pruned = now
+}
+
+// NextForSession returns service given session is pinned to, so
+// multi-step protocols talk to the same service across calls.
+// Session is pinned to next healthy service on its first call
+// or when pinned service is jailed, removed or not allowed for
+// the request. Pin expires after configured ttl since last call
+func (l *ServicesList) NextForSession(ctx context.Context, session string) service.IService {
+	if id, ok := l.sessions.pinned(session); ok {
+		if srv := l.pinnedService(ctx, id); srv != nil {
+			l.sessions.pin(session, srv.ID())
+			return srv
+		}
+
+		l.log().Info("session is moved from unavailable service", "session", session, "service", id)
+	}
+
+	srv := l.NextContext(ctx)
+	if srv == nil {
+		l.sessions.unpin(session)
+		return nil
+	}
+
+	l.sessions.pin(session, srv.ID())
+
+	return srv
+}
+
+// ReleaseSession remove pin of given session, e.g.
+// when multi-step protocol is finished
+func (l *ServicesList) ReleaseSession(session string) {
+	l.sessions.unpin(session)
+}
+
+// pinnedService return healthy service with given
+// id if it's allowed for given request, nil otherwise
+func (l *ServicesList) pinnedService(ctx context.Context, id string) service.IService {
+	l.mu.Lock()
+
+	srv := findService(l.healthy, id)
+	if srv == nil || srv.Status() != service.StatusHealthy || !l.allow(ctx, srv) {
+		l.mu.Unlock()
+		return nil
+	}
+
+	l.recordSelection(srv)
+	l.mu.Unlock()
+
+	l.metrics.observeSelection(ctx, l.serviceName, srv)
+
+	return srv
+}
+
+// NextForSession returns service given session is pinned
+// to, pins are shared by all shards and session is pinned
+// to next healthy service of any shard
+func (l *ShardedServicesList) NextForSession(ctx context.Context, session string) service.IService {
+	sessions := l.shards[0].sessions
+
+	if id, ok := sessions.pinned(session); ok {
+		if srv := l.shard(id).pinnedService(ctx, id); srv != nil {
+			sessions.pin(session, srv.ID())
+			return srv
+		}
+
+		l.log().Info("session is moved from unavailable service", "session", session, "service", id)
+	}
+
+	srv := l.NextContext(ctx)
+	if srv == nil {
+		sessions.unpin(session)
+		return nil
+	}
+
+	sessions.pin(session, srv.ID())
+
+	return srv
+}
+
+// ReleaseSession remove pin of given session
+func (l *ShardedServicesList) ReleaseSession(session string) {
+	l.shards[0].ReleaseSession(session)
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNextForSession(t *testing.T) {
+	for _, shards := range []int{1, 3} {
+		t.Run(fmt.Sprintf("shards %d", shards), func(t *testing.T) {
+			list := NewServicesList("testServicesList", &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  time.Hour,
+				ChecksInterval: time.Hour,
+				Shards:         shards,
+			})
+			defer list.Close()
+
+			for i := 0; i < 4; i++ {
+				list.Add(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+			}
+
+			ctx := context.Background()
+
+			pinned := list.NextForSession(ctx, "session")
+			for i := 0; i < 10; i++ {
+				if srv := list.NextForSession(ctx, "session"); srv != pinned {
+					t.Fatalf("session should stay on pinned service %s, got %s", pinned.Address(), srv.Address())
+				}
+			}
+
+			list.FromHealthyToJail(pinned.ID())
+
+			moved := list.NextForSession(ctx, "session")
+			if moved == nil || moved == pinned {
+				t.Fatalf("session should be moved from jailed service, got %v", moved)
+			}
+			if srv := list.NextForSession(ctx, "session"); srv != moved {
+				t.Fatalf("session should be pinned to new service %s, got %s", moved.Address(), srv.Address())
+			}
+
+			list.ReleaseSession("session")
+			if id, ok := sessionsOf(list).pinned("session"); ok {
+				t.Errorf("released session should not be pinned to %s", id)
+			}
+		})
+	}
+}
+
+func TestSessionsExpire(t *testing.T) {
+	s := newSessions(20 * time.Millisecond)
+
+	s.pin("session", "id")
+	if id, ok := s.pinned("session"); !ok || id != "id" {
+		t.Fatalf("session should be pinned, got %q", id)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := s.pinned("session"); ok {
+		t.Fatalf("pin should expire after ttl")
+	}
+
+	s.pin("other", "id")
+	if len(s.pins) != 1 {
+		t.Errorf("expired pins should be pruned, got %d pins", len(s.pins))
+	}
+}
+
+// sessionsOf return affinity map of given list
+func sessionsOf(list IServicesList) *sessions {
+	if sharded, ok := list.(*ShardedServicesList); ok {
+		return sharded.shards[0].sessions
+	}
+
+	return list.(*ServicesList).sessions
+}
@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultAuditLogSize is number of
+// entries kept in the audit log by default
+const defaultAuditLogSize = 1000
+
+// AuditEntry is record of operator
+// action applied to the list
+type AuditEntry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Service string    `json:"service,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+}
+
+// auditLog is bounded in-memory log of operator
+// actions, the oldest entries are dropped first
+type auditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+	size    int
+}
+
+// newAuditLog create audit log keeping given
+// number of entries (defaultAuditLogSize if 0)
+func newAuditLog(size int) *auditLog {
+	if size == 0 {
+		size = defaultAuditLogSize
+	}
+
+	return &auditLog{size: size}
+}
+
+// record append entry with given action
+func (a *auditLog) record(action, id, detail string) {
+	defer a.mu.Unlock()
+	a.mu.Lock()
+
+	a.entries = append(a.entries, AuditEntry{
+		Time:    time.Now(),
+		Action:  action,
+		Service: id,
+		Detail:  detail,
+	})
+
+	if len(a.entries) > a.size {
+		a.entries = append([]AuditEntry(nil), a.entries[len(a.entries)-a.size:]...)
+	}
+}
+
+// list return copy of audit entries
+func (a *auditLog) list() []AuditEntry {
+	defer a.mu.Unlock()
+	a.mu.Lock()
+
+	return append([]AuditEntry(nil), a.entries...)
+}
+
+// AuditLog return operator actions
+// applied to the list, oldest first
+func (l *ServicesList) AuditLog() []AuditEntry {
+	return l.audit.list()
+}
+
+// AuditLog return operator actions applied
+// to all shards ordered by time
+func (l *ShardedServicesList) AuditLog() []AuditEntry {
+	var entries []AuditEntry
+	for _, shard := range l.shards {
+		entries = append(entries, shard.AuditLog()...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	return entries
+}
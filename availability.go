@@ -0,0 +1,328 @@
+package pool
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const defaultAvailabilityResolution = time.Hour
+
+// AvailabilityOpts is options that configure
+// collection of healthchecks outcomes
+// for availability reports
+type AvailabilityOpts struct {
+	Resolution time.Duration // size of the smallest aggregation bucket (1h by default)
+	Retention  time.Duration // how long collected buckets are kept
+}
+
+// AvailabilityReport is healthchecks outcomes
+// aggregated by given window per service and per pool
+type AvailabilityReport struct {
+	Window   time.Duration      `json:"window"`
+	From     time.Time          `json:"from"`
+	To       time.Time          `json:"to"`
+	Services []AvailabilityItem `json:"services"`
+	Pool     []AvailabilityItem `json:"pool"`
+}
+
+// AvailabilityItem is healthchecks outcomes
+// of service (or whole pool) during one window
+type AvailabilityItem struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	ServiceID    string    `json:"service_id,omitempty"`
+	Address      string    `json:"address,omitempty"`
+	Checks       int       `json:"checks"`
+	Failures     int       `json:"failures"`
+	Availability float64   `json:"availability"`
+}
+
+// availabilityBucket is healthchecks outcomes
+// counters of one service during one bucket
+type availabilityBucket struct {
+	checks   int
+	failures int
+}
+
+// availabilityTracker collect healthchecks
+// outcomes of services by time buckets
+type availabilityTracker struct {
+	resolution  time.Duration
+	retention   time.Duration
+	maxBuckets  int
+	maxServices int
+
+	addresses map[string]string
+	recorded  map[string]int64
+	buckets   map[string]map[int64]*availabilityBucket
+
+	mu sync.Mutex
+}
+
+// newAvailabilityTracker create new availabilityTracker with
+// given configuration and budget, returns nil if opts are nil
+func newAvailabilityTracker(opts *AvailabilityOpts, budget *MemoryBudget) *availabilityTracker {
+	if opts == nil {
+		return nil
+	}
+
+	resolution := opts.Resolution
+	if resolution == 0 {
+		resolution = defaultAvailabilityResolution
+	}
+
+	t := &availabilityTracker{
+		resolution: resolution,
+		retention:  opts.Retention,
+		addresses:  make(map[string]string),
+		recorded:   make(map[string]int64),
+		buckets:    make(map[string]map[int64]*availabilityBucket),
+	}
+
+	if budget != nil {
+		t.maxBuckets = budget.MaxAvailabilityBuckets
+		t.maxServices = budget.MaxTrackedServices
+	}
+
+	return t
+}
+
+// record register healthcheck outcome of given service
+func (t *availabilityTracker) record(srv service.IService, err error) {
+	if t == nil {
+		return
+	}
+
+	defer t.mu.Unlock()
+	t.mu.Lock()
+
+	now := time.Now()
+	start := now.Truncate(t.resolution).Unix()
+
+	buckets, ok := t.buckets[srv.ID()]
+	if !ok {
+		buckets = make(map[int64]*availabilityBucket)
+		t.buckets[srv.ID()] = buckets
+	}
+	t.addresses[srv.ID()] = srv.Address()
+	t.recorded[srv.ID()] = now.UnixNano()
+
+	bucket, ok := buckets[start]
+	if !ok {
+		bucket = &availabilityBucket{}
+		buckets[start] = bucket
+		t.evict(now)
+		t.evictOverBudget(buckets)
+	}
+
+	bucket.checks++
+	if err != nil {
+		bucket.failures++
+	}
+}
+
+// evict remove buckets that are older than retention.
+// Should be called under the tracker lock
+func (t *availabilityTracker) evict(now time.Time) {
+	if t.retention == 0 {
+		return
+	}
+
+	threshold := now.Add(-t.retention).Unix()
+	for id, buckets := range t.buckets {
+		for start := range buckets {
+			if start < threshold {
+				delete(buckets, start)
+			}
+		}
+
+		if len(buckets) == 0 {
+			t.forget(id)
+		}
+	}
+}
+
+// evictOverBudget remove the oldest buckets of given service
+// and the least recently checked services over the budget.
+// Should be called under the tracker lock
+func (t *availabilityTracker) evictOverBudget(buckets map[int64]*availabilityBucket) {
+	for t.maxBuckets > 0 && len(buckets) > t.maxBuckets {
+		oldest := int64(math.MaxInt64)
+		for start := range buckets {
+			oldest = min(oldest, start)
+		}
+		delete(buckets, oldest)
+	}
+
+	for t.maxServices > 0 && len(t.buckets) > t.maxServices {
+		var (
+			leastID       string
+			leastRecorded = int64(math.MaxInt64)
+		)
+		for id, recorded := range t.recorded {
+			if recorded < leastRecorded {
+				leastID, leastRecorded = id, recorded
+			}
+		}
+		t.forget(leastID)
+	}
+}
+
+// forget remove all collected data of service
+// with given id. Should be called under the tracker lock
+func (t *availabilityTracker) forget(id string) {
+	delete(t.buckets, id)
+	delete(t.addresses, id)
+	delete(t.recorded, id)
+}
+
+// usage return number of tracked services and buckets
+func (t *availabilityTracker) usage() (int, int) {
+	if t == nil {
+		return 0, 0
+	}
+
+	defer t.mu.Unlock()
+	t.mu.Lock()
+
+	buckets := 0
+	for _, serviceBuckets := range t.buckets {
+		buckets += len(serviceBuckets)
+	}
+
+	return len(t.buckets), buckets
+}
+
+// report aggregate collected buckets
+// within [from, to) by given window
+func (t *availabilityTracker) report(window time.Duration, from, to time.Time) *AvailabilityReport {
+	report := &AvailabilityReport{
+		Window:   window,
+		From:     from,
+		To:       to,
+		Services: []AvailabilityItem{},
+		Pool:     []AvailabilityItem{},
+	}
+
+	if t == nil {
+		return report
+	}
+
+	if window < t.resolution {
+		window = t.resolution
+		report.Window = window
+	}
+
+	defer t.mu.Unlock()
+	t.mu.Lock()
+
+	pool := make(map[int64]*AvailabilityItem)
+
+	for id, buckets := range t.buckets {
+		services := make(map[int64]*AvailabilityItem)
+
+		for start, bucket := range buckets {
+			bucketStart := time.Unix(start, 0)
+			if bucketStart.Before(from) || !bucketStart.Before(to) {
+				continue
+			}
+
+			windowStart := bucketStart.Truncate(window)
+
+			item, ok := services[windowStart.Unix()]
+			if !ok {
+				item = &AvailabilityItem{
+					Start:     windowStart,
+					End:       windowStart.Add(window),
+					ServiceID: id,
+					Address:   t.addresses[id],
+				}
+				services[windowStart.Unix()] = item
+			}
+			item.Checks += bucket.checks
+			item.Failures += bucket.failures
+
+			poolItem, ok := pool[windowStart.Unix()]
+			if !ok {
+				poolItem = &AvailabilityItem{
+					Start: windowStart,
+					End:   windowStart.Add(window),
+				}
+				pool[windowStart.Unix()] = poolItem
+			}
+			poolItem.Checks += bucket.checks
+			poolItem.Failures += bucket.failures
+		}
+
+		for _, item := range services {
+			report.Services = append(report.Services, *item.withAvailability())
+		}
+	}
+
+	for _, item := range pool {
+		report.Pool = append(report.Pool, *item.withAvailability())
+	}
+
+	sortAvailabilityItems(report.Services)
+	sortAvailabilityItems(report.Pool)
+
+	return report
+}
+
+// WriteCSV write report to given writer as csv,
+// pool-wide rows have empty service id and address
+func (r *AvailabilityReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write([]string{"start", "end", "service_id", "address", "checks", "failures", "availability"}); err != nil {
+		return err
+	}
+
+	for _, items := range [][]AvailabilityItem{r.Pool, r.Services} {
+		for _, item := range items {
+			record := []string{
+				item.Start.UTC().Format(time.RFC3339),
+				item.End.UTC().Format(time.RFC3339),
+				item.ServiceID,
+				item.Address,
+				strconv.Itoa(item.Checks),
+				strconv.Itoa(item.Failures),
+				strconv.FormatFloat(item.Availability, 'f', 4, 64),
+			}
+
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// withAvailability calculate item availability
+// ratio from its checks and failures
+func (i *AvailabilityItem) withAvailability() *AvailabilityItem {
+	if i.Checks > 0 {
+		i.Availability = float64(i.Checks-i.Failures) / float64(i.Checks)
+	}
+	return i
+}
+
+// sortAvailabilityItems sort given items
+// by window start and service id
+func sortAvailabilityItems(items []AvailabilityItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Start.Equal(items[j].Start) {
+			return items[i].Start.Before(items[j].Start)
+		}
+		return items[i].ServiceID < items[j].ServiceID
+	})
+}
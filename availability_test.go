@@ -0,0 +1,59 @@
+package pool
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAvailabilityReport(t *testing.T) {
+	tracker := newAvailabilityTracker(&AvailabilityOpts{Resolution: time.Minute}, nil)
+
+	first := newHealthyService("https://1gateway.fm")
+	second := newHealthyService("https://2gateway.fm")
+
+	tracker.record(first, nil)
+	tracker.record(first, errors.New("timeout"))
+	tracker.record(second, nil)
+	tracker.record(second, nil)
+
+	now := time.Now()
+	report := tracker.report(time.Hour, now.Add(-time.Hour), now.Add(time.Hour))
+
+	if len(report.Services) != 2 {
+		t.Fatalf("unexpected num of services in report %d", len(report.Services))
+	}
+
+	poolChecks := 0
+	for _, item := range report.Pool {
+		poolChecks += item.Checks
+	}
+
+	if poolChecks != 4 {
+		t.Errorf("unexpected num of pool checks %d", poolChecks)
+	}
+
+	for _, item := range report.Services {
+		switch item.ServiceID {
+		case first.ID():
+			if item.Checks != 2 || item.Failures != 1 || item.Availability != 0.5 {
+				t.Errorf("unexpected first service report %+v", item)
+			}
+		case second.ID():
+			if item.Checks != 2 || item.Failures != 0 || item.Availability != 1 {
+				t.Errorf("unexpected second service report %+v", item)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("unexpected csv error: %s", err)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 1+len(report.Pool)+len(report.Services) {
+		t.Errorf("unexpected num of csv lines %d", len(lines))
+	}
+}
@@ -0,0 +1,63 @@
+package pool
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultBackoffMultiplier  = 2
+	defaultBackoffMaxInterval = 5 * time.Minute
+)
+
+// BackoffOpts is options that configure exponential backoff
+// of tries to up jailed services, so large jailed fleet is not
+// checked in lockstep every try up interval
+type BackoffOpts struct {
+	Multiplier  float64       // growth factor of interval after every failed try (2 by default)
+	MaxInterval time.Duration // cap of grown interval (5m by default)
+	Jitter      float64       // share of interval randomized in both directions, e.g. 0.2 for ±20% (0 to disable)
+}
+
+// backoff is exponential backoff with jitter
+type backoff struct {
+	opts BackoffOpts
+}
+
+// newBackoff create exponential backoff,
+// nil is returned if backoff is disabled
+func newBackoff(opts *BackoffOpts) *backoff {
+	if opts == nil {
+		return nil
+	}
+
+	b := &backoff{opts: *opts}
+
+	if b.opts.Multiplier < 1 {
+		b.opts.Multiplier = defaultBackoffMultiplier
+	}
+	if b.opts.MaxInterval <= 0 {
+		b.opts.MaxInterval = defaultBackoffMaxInterval
+	}
+	b.opts.Jitter = min(max(b.opts.Jitter, 0), 1)
+
+	return b
+}
+
+// interval return interval before given try grown from given
+// base interval, base interval is returned if backoff is disabled
+func (b *backoff) interval(base time.Duration, try int) time.Duration {
+	if b == nil {
+		return base
+	}
+
+	interval := float64(base) * math.Pow(b.opts.Multiplier, float64(max(try-1, 0)))
+	interval = min(interval, float64(max(b.opts.MaxInterval, base)))
+
+	if b.opts.Jitter > 0 {
+		interval += interval * b.opts.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(interval)
+}
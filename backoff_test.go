@@ -0,0 +1,34 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffInterval(t *testing.T) {
+	var disabled *backoff
+	if interval := disabled.interval(time.Second, 5); interval != time.Second {
+		t.Errorf("disabled backoff should keep base interval, got %s", interval)
+	}
+
+	b := newBackoff(&BackoffOpts{MaxInterval: 10 * time.Second})
+	expected := []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second}
+	for try, interval := range expected {
+		if got := b.interval(time.Second, try); got != interval {
+			t.Errorf("unexpected interval of try %d: %s, expected %s", try, got, interval)
+		}
+	}
+
+	jittered := newBackoff(&BackoffOpts{Multiplier: 3, Jitter: 0.5})
+	spread := make(map[time.Duration]struct{})
+	for i := 0; i < 100; i++ {
+		interval := jittered.interval(time.Second, 2)
+		if interval < 1500*time.Millisecond || interval > 4500*time.Millisecond {
+			t.Fatalf("jittered interval %s is out of range", interval)
+		}
+		spread[interval] = struct{}{}
+	}
+	if len(spread) < 2 {
+		t.Errorf("jittered intervals should differ")
+	}
+}
@@ -0,0 +1,67 @@
+package pool
+
+// Balancing represent strategy used by
+// Next to select healthy services
+type Balancing int
+
+const (
+	// BalancingRoundRobin select allowed
+	// healthy services in turn
+	BalancingRoundRobin Balancing = iota
+
+	// BalancingWeightedRoundRobin select allowed healthy services
+	// in turn proportionally to theirs weights, so larger prover
+	// machines receive proportionally more work. Weight is read
+	// by service.Weight, sharded lists apply weights per shard.
+	// It's the same as WeightedRoundRobin strategy
+	BalancingWeightedRoundRobin
+
+	// BalancingLeastConnections select allowed healthy service with
+	// the fewest in-flight connections, e.g. for provers with very
+	// uneven job durations. Connections are tracked by Acquire.
+	// It's the same as LeastConnections strategy counting them
+	BalancingLeastConnections
+)
+
+// strategy return strategy selecting services with given
+// in-flight connections counter, nil is returned for
+// round-robin which is built into the list, so its
+// cursor follows membership changes
+func (b Balancing) strategy(inFlight func(id string) int) IBalancingStrategy {
+	switch b {
+	case BalancingWeightedRoundRobin:
+		return WeightedRoundRobin()
+	case BalancingLeastConnections:
+		return LeastConnections(inFlight)
+	default:
+		return nil
+	}
+}
+
+// ParseBalancing return balancing strategy with given
+// name, empty name is parsed as round-robin
+func ParseBalancing(name string) (Balancing, error) {
+	for _, b := range []Balancing{BalancingRoundRobin, BalancingWeightedRoundRobin, BalancingLeastConnections} {
+		if name == b.String() {
+			return b, nil
+		}
+	}
+
+	if name == "" {
+		return BalancingRoundRobin, nil
+	}
+
+	return 0, ErrUnsupportedBalancing{Name: name}
+}
+
+// String return balancing strategy name
+func (b Balancing) String() string {
+	switch b {
+	case BalancingWeightedRoundRobin:
+		return "weighted_round_robin"
+	case BalancingLeastConnections:
+		return "least_connections"
+	default:
+		return "round_robin"
+	}
+}
@@ -0,0 +1,133 @@
+package pool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+const (
+	modulePath = "github.com/gateway-fm/prover-pool-lib"
+
+	unknownVersion = "unknown"
+
+	fingerprintLength = 12
+)
+
+// BuildInfo is version information
+// of the pool library and runtime
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+}
+
+// ConfigInfo is services list configuration
+// values and theirs fingerprint, could be used to
+// detect configuration drift between instances
+type ConfigInfo struct {
+	Fingerprint string         `json:"fingerprint"`
+	Options     []ConfigOption `json:"options"`
+}
+
+// ConfigOption is one numeric configuration
+// value of the services list
+type ConfigOption struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// ReadBuildInfo return version of the pool library
+// module included into the binary and go version
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   unknownVersion,
+		GoVersion: runtime.Version(),
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if build.Main.Path == modulePath {
+		info.Version = build.Main.Version
+		return info
+	}
+
+	for _, dep := range build.Deps {
+		if dep.Path == modulePath {
+			info.Version = dep.Version
+			break
+		}
+	}
+
+	return info
+}
+
+// newConfigInfo collect configuration values from
+// given options and calculate theirs fingerprint
+func newConfigInfo(opts *ServicesListOpts) ConfigInfo {
+	options := []ConfigOption{
+		{Name: "try_up_tries", Value: float64(opts.TryUpTries)},
+		{Name: "try_up_interval_seconds", Value: opts.TryUpInterval.Seconds()},
+		{Name: "checks_interval_seconds", Value: opts.ChecksInterval.Seconds()},
+		{Name: "shards", Value: float64(max(opts.Shards, 1))},
+		{Name: "removal_strategy", Value: float64(opts.Removal)},
+		{Name: "healthy_order", Value: float64(opts.HealthyOrder)},
+		{Name: "balancing", Value: float64(opts.Balancing)},
+		{Name: "scheduled_checks", Value: float64(len(opts.Checks))},
+	}
+
+	if opts.MaxInFlight > 0 {
+		options = append(options,
+			ConfigOption{Name: "max_in_flight", Value: float64(opts.MaxInFlight)},
+			ConfigOption{Name: "overflow", Value: float64(opts.Overflow)},
+		)
+	}
+
+	if opts.ReviewPolicy != nil {
+		options = append(options,
+			ConfigOption{Name: "review_flap_threshold", Value: float64(opts.ReviewPolicy.FlapThreshold)},
+			ConfigOption{Name: "review_verification_failure_threshold", Value: float64(opts.ReviewPolicy.VerificationFailureThreshold)},
+			ConfigOption{Name: "review_window_seconds", Value: opts.ReviewPolicy.Window.Seconds()},
+		)
+	}
+
+	if opts.Availability != nil {
+		options = append(options,
+			ConfigOption{Name: "availability_resolution_seconds", Value: opts.Availability.Resolution.Seconds()},
+			ConfigOption{Name: "availability_retention_seconds", Value: opts.Availability.Retention.Seconds()},
+		)
+	}
+
+	if opts.MemoryBudget != nil {
+		options = append(options,
+			ConfigOption{Name: "budget_max_history", Value: float64(opts.MemoryBudget.MaxHistory)},
+			ConfigOption{Name: "budget_max_availability_buckets", Value: float64(opts.MemoryBudget.MaxAvailabilityBuckets)},
+			ConfigOption{Name: "budget_max_tracked_services", Value: float64(opts.MemoryBudget.MaxTrackedServices)},
+		)
+	}
+
+	if opts.Shed != nil {
+		options = append(options,
+			ConfigOption{Name: "shed_policy", Value: float64(opts.Shed.Policy)},
+			ConfigOption{Name: "shed_threshold", Value: opts.Shed.Threshold},
+			ConfigOption{Name: "shed_capacity", Value: float64(opts.Shed.Capacity)},
+		)
+	}
+
+	pairs := make([]string, 0, len(options))
+	for _, option := range options {
+		pairs = append(pairs, fmt.Sprintf("%s=%g", option.Name, option.Value))
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(pairs, ";")))
+
+	return ConfigInfo{
+		Fingerprint: hex.EncodeToString(sum[:])[:fingerprintLength],
+		Options:     options,
+	}
+}
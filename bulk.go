@@ -0,0 +1,315 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// mergedService is list entry replaced
+// by changed service during ApplyDiff
+type mergedService struct {
+	srv        service.IService
+	existing   service.IService
+	membership string
+}
+
+// AddAll add given services to the list like Add, but the list
+// lock is taken once and membership generation is bumped once,
+// e.g. for reconciling large discovery results
+func (l *ServicesList) AddAll(services []service.IService) {
+	l.ApplyDiff(services, nil, nil)
+}
+
+// ApplyDiff reconcile the list with given diff of discovery
+// results under single lock hold and single generation bump:
+// added services are added like Add, changed ones are merged
+// into existing entries and removed ones are removed from
+// healthy, jail or review and closed. Healthchecks of new
+// services are run before the lock is taken
+func (l *ServicesList) ApplyDiff(added, removed, changed []service.IService) {
+	candidates := make([]service.IService, 0, len(added)+len(changed))
+	for _, services := range [][]service.IService{added, changed} {
+		for _, srv := range services {
+			if srv != nil {
+				candidates = append(candidates, srv)
+			}
+		}
+	}
+
+	l.mu.RLock()
+	known := make(map[string]bool, len(candidates))
+	for _, srv := range candidates {
+		existing, _ := l.lookup(srv.ID())
+		known[srv.ID()] = existing != nil
+	}
+	l.mu.RUnlock()
+
+	// new services are admitted and checked without holding the lock
+	checks := make(map[string]error, len(candidates))
+	for _, srv := range candidates {
+		if known[srv.ID()] {
+			continue
+		}
+
+		if err := l.admit(srv); err != nil {
+			l.log().Warn("service is not admitted", "service", srv.ID(), "node", srv.NodeName(), "error", err)
+			continue
+		}
+
+		err := l.CheckHealth(context.Background(), srv)
+		l.availability.record(srv, err)
+		checks[srv.ID()] = err
+	}
+
+	var (
+		merged []mergedService
+		jailed []service.IService
+	)
+
+	l.mu.Lock()
+
+	closed := l.removeAll(removed)
+	changes := len(closed)
+
+	for _, srv := range candidates {
+		existing, membership, found := l.replace(srv)
+		if existing != nil {
+			merged = append(merged, mergedService{srv: srv, existing: existing, membership: membership})
+			changes++
+		}
+		if found {
+			continue
+		}
+
+		// the service is not admitted or its
+		// entry is removed concurrently
+		err, checked := checks[srv.ID()]
+		if !checked {
+			continue
+		}
+
+		if l.insert(srv, err) {
+			jailed = append(jailed, srv)
+		}
+		changes++
+	}
+
+	if changes > 0 {
+		l.bumpGeneration()
+	}
+
+	l.mu.Unlock()
+
+	l.flushEvents()
+
+	l.log().Info("diff is applied", "added", len(added), "removed", len(removed), "changed", len(changed), "changes", changes)
+
+	for _, srv := range closed {
+		if err := srv.Close(); err != nil {
+			l.log().Warn("unexpected error during service Close()", "service", srv.ID(), "error", err)
+		}
+	}
+
+	for _, entry := range merged {
+		l.merged(entry.srv, entry.existing, entry.membership)
+	}
+
+	for _, srv := range jailed {
+		l.goTryUp(srv)
+	}
+}
+
+// SyncWith reconcile the list with given complete discovery
+// results: new services are added like Add and members missing
+// in the results are removed from healthy, jail or review and
+// closed, e.g. deregistered ones. Empty results remove all
+// members, so results of failed discovery should not be passed
+func (l *ServicesList) SyncWith(discovered []service.IService) {
+	ids := make(map[string]struct{}, len(discovered))
+	for _, srv := range discovered {
+		if srv != nil {
+			ids[srv.ID()] = struct{}{}
+		}
+	}
+
+	l.mu.RLock()
+	missing := l.missing(ids)
+	l.mu.RUnlock()
+
+	l.ApplyDiff(discovered, missing, nil)
+}
+
+// missing return members which ids are not in
+// given set. Should be called under the list lock
+func (l *ServicesList) missing(ids map[string]struct{}) []service.IService {
+	var missing []service.IService
+
+	for _, srv := range l.healthy {
+		if _, ok := ids[srv.ID()]; !ok {
+			missing = append(missing, srv)
+		}
+	}
+
+	for id, srv := range l.jail {
+		if _, ok := ids[id]; !ok {
+			missing = append(missing, srv)
+		}
+	}
+
+	for id, item := range l.review {
+		if _, ok := ids[id]; !ok {
+			missing = append(missing, item.Service)
+		}
+	}
+
+	return missing
+}
+
+// RemoveByID remove service with given id from healthy,
+// jail or review and close it, false is returned if
+// there is no such member
+func (l *ServicesList) RemoveByID(id string) bool {
+	l.mu.Lock()
+
+	srv, _ := l.lookup(id)
+	if srv == nil {
+		l.mu.Unlock()
+		return false
+	}
+
+	closed := l.removeAll([]service.IService{srv})
+	l.bumpGeneration()
+
+	l.mu.Unlock()
+
+	l.flushEvents()
+
+	l.log().Info("service is removed", "service", id)
+
+	for _, srv := range closed {
+		if err := srv.Close(); err != nil {
+			l.log().Warn("unexpected error during service Close()", "service", srv.ID(), "error", err)
+		}
+	}
+
+	return true
+}
+
+// removeAll remove given services from healthy, jail or
+// review and return removed entries. Should be called
+// under the list lock
+func (l *ServicesList) removeAll(services []service.IService) []service.IService {
+	if len(services) == 0 {
+		return nil
+	}
+
+	ids := make(map[string]struct{}, len(services))
+	for _, srv := range services {
+		if srv != nil {
+			ids[srv.ID()] = struct{}{}
+		}
+	}
+
+	var removed []service.IService
+
+	// backward pass keeps indices of not yet visited services
+	// valid with both order preserving and swap removal
+	for i := len(l.healthy) - 1; i >= 0; i-- {
+		srv := l.healthy[i]
+		if _, ok := ids[srv.ID()]; !ok {
+			continue
+		}
+
+		l.removeFromHealthy(i)
+		l.releaseMember(srv, "removed")
+		removed = append(removed, srv)
+	}
+
+	for id := range ids {
+		if srv, ok := l.jail[id]; ok {
+			l.fromJail(id)
+			l.releaseMember(srv, "removed_from_jail")
+			removed = append(removed, srv)
+		}
+
+		if item, ok := l.review[id]; ok {
+			delete(l.review, id)
+			l.releaseMember(item.Service, "removed")
+			removed = append(removed, item.Service)
+		}
+
+		delete(l.flaps, id)
+		delete(l.verificationFailures, id)
+	}
+
+	return removed
+}
+
+// AddAll add given services to shards they belong
+// to, shards are updated concurrently
+func (l *ShardedServicesList) AddAll(services []service.IService) {
+	l.ApplyDiff(services, nil, nil)
+}
+
+// ApplyDiff split given diff by shards services belong
+// to and apply it to shards concurrently
+func (l *ShardedServicesList) ApplyDiff(added, removed, changed []service.IService) {
+	type diff struct {
+		added, removed, changed []service.IService
+	}
+
+	diffs := make(map[*ServicesList]*diff, len(l.shards))
+	for _, shard := range l.shards {
+		diffs[shard] = &diff{}
+	}
+
+	for _, srv := range added {
+		if srv != nil {
+			d := diffs[l.shard(srv.ID())]
+			d.added = append(d.added, srv)
+		}
+	}
+	for _, srv := range removed {
+		if srv != nil {
+			d := diffs[l.shard(srv.ID())]
+			d.removed = append(d.removed, srv)
+		}
+	}
+	for _, srv := range changed {
+		if srv != nil {
+			d := diffs[l.shard(srv.ID())]
+			d.changed = append(d.changed, srv)
+		}
+	}
+
+	l.each(func(shard *ServicesList) {
+		d := diffs[shard]
+		if len(d.added)+len(d.removed)+len(d.changed) > 0 {
+			shard.ApplyDiff(d.added, d.removed, d.changed)
+		}
+	})
+}
+
+// SyncWith split given discovery results by shards
+// services belong to and reconcile shards concurrently,
+// shards without discovered services are emptied
+func (l *ShardedServicesList) SyncWith(discovered []service.IService) {
+	parts := make(map[*ServicesList][]service.IService, len(l.shards))
+	for _, srv := range discovered {
+		if srv != nil {
+			shard := l.shard(srv.ID())
+			parts[shard] = append(parts[shard], srv)
+		}
+	}
+
+	l.each(func(shard *ServicesList) {
+		shard.SyncWith(parts[shard])
+	})
+}
+
+// RemoveByID remove service with
+// given id from the shard it belongs to
+func (l *ShardedServicesList) RemoveByID(id string) bool {
+	return l.shard(id).RemoveByID(id)
+}
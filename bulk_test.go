@@ -0,0 +1,220 @@
+package pool
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServicesListAddAll(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	var services []service.IService
+	for i := 0; i < 100; i++ {
+		addr := fmt.Sprintf("https://%dgateway.fm", i)
+		if i%10 == 0 {
+			services = append(services, newUnhealthyService(addr))
+			continue
+		}
+		services = append(services, newHealthyService(addr))
+	}
+
+	generation := list.Generation()
+	list.AddAll(services)
+
+	if bumps := list.Generation() - generation; bumps != 1 {
+		t.Errorf("expected single generation bump, got %d", bumps)
+	}
+	if len(list.Healthy()) != 90 || len(list.Jailed()) != 10 {
+		t.Errorf("expected 90 healthy and 10 jailed services, got %d and %d", len(list.Healthy()), len(list.Jailed()))
+	}
+
+	// unchanged services do not change membership
+	generation = list.Generation()
+	list.AddAll(services)
+
+	if list.Generation() != generation {
+		t.Errorf("generation should not be bumped without changes")
+	}
+}
+
+func TestServicesListApplyDiff(t *testing.T) {
+	for _, removal := range []RemovalStrategy{RemovalPreserveOrder, RemovalSwap} {
+		var changedEvents int32
+
+		list := NewServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+			TombstoneTTL:   time.Minute,
+			Removal:        removal,
+			OnEvent: func(event PoolEvent) {
+				if event.Type == EventServiceChanged {
+					atomic.AddInt32(&changedEvents, 1)
+				}
+			},
+		})
+
+		var services []service.IService
+		for i := 0; i < 20; i++ {
+			services = append(services, newMetadataService(fmt.Sprintf("https://%dgateway.fm", i), map[string]string{"zone": "a"}))
+		}
+		jailed := newUnhealthyService("https://jailedgateway.fm")
+		list.AddAll(append(services, jailed))
+
+		removed := []service.IService{services[0], services[7], services[19], jailed}
+		changed := []service.IService{newMetadataService(services[3].Address(), map[string]string{"zone": "b"})}
+		added := []service.IService{newHealthyService("https://newgateway.fm")}
+
+		generation := list.Generation()
+		list.ApplyDiff(added, removed, changed)
+
+		if bumps := list.Generation() - generation; bumps != 1 {
+			t.Errorf("expected single generation bump, got %d", bumps)
+		}
+
+		healthy := make(map[string]service.IService)
+		for _, srv := range list.Healthy() {
+			healthy[srv.ID()] = srv
+		}
+		if len(healthy) != 18 || len(list.Healthy()) != 18 || len(list.Jailed()) != 0 {
+			t.Fatalf("%s removal: expected 18 healthy and no jailed services, got %d and %d", removal, len(list.Healthy()), len(list.Jailed()))
+		}
+
+		for _, srv := range removed {
+			if _, ok := healthy[srv.ID()]; ok {
+				t.Errorf("%s removal: removed service %s is still healthy", removal, srv.Address())
+			}
+			if _, wasRemoved := list.Resolve(srv.ID()); !wasRemoved {
+				t.Errorf("%s removal: removed service %s should leave a tombstone", removal, srv.Address())
+			}
+		}
+
+		if healthy[changed[0].ID()] != changed[0] || atomic.LoadInt32(&changedEvents) != 1 {
+			t.Errorf("%s removal: changed service should be merged into existing entry", removal)
+		}
+		if _, ok := healthy[added[0].ID()]; !ok {
+			t.Errorf("%s removal: added service should be healthy", removal)
+		}
+
+		// round-robin still visits every healthy service once
+		selected := make(map[string]struct{})
+		for i := 0; i < len(healthy); i++ {
+			selected[list.Next().ID()] = struct{}{}
+		}
+		if len(selected) != len(healthy) {
+			t.Errorf("%s removal: expected %d selected services, got %d", removal, len(healthy), len(selected))
+		}
+
+		list.Close()
+	}
+}
+
+func TestServicesListSyncWith(t *testing.T) {
+	for _, shards := range []int{1, 4} {
+		list := NewServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+			Shards:         shards,
+		})
+
+		kept := newHealthyService("https://1gateway.fm")
+		deregistered := newHealthyService("https://2gateway.fm")
+		broken := newUnhealthyService("https://3gateway.fm")
+		list.AddAll([]service.IService{kept, deregistered, broken})
+
+		if len(list.Healthy()) != 2 || len(list.Jailed()) != 1 {
+			t.Fatalf("shards %d: expected 2 healthy and 1 jailed services", shards)
+		}
+
+		added := newHealthyService("https://4gateway.fm")
+		list.SyncWith([]service.IService{kept, added})
+
+		healthy := make(map[string]bool)
+		for _, srv := range list.Healthy() {
+			healthy[srv.ID()] = true
+		}
+		if len(healthy) != 2 || !healthy[kept.ID()] || !healthy[added.ID()] {
+			t.Errorf("shards %d: unexpected healthy services %v", shards, healthy)
+		}
+		if len(list.Jailed()) != 0 {
+			t.Errorf("shards %d: missing jailed service should be removed", shards)
+		}
+
+		list.Close()
+	}
+}
+
+func TestServicesListRemoveByID(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	healthy := newHealthyService("https://1gateway.fm")
+	jailed := newUnhealthyService("https://2gateway.fm")
+	list.AddAll([]service.IService{healthy, jailed})
+
+	generation := list.Generation()
+
+	if !list.RemoveByID(jailed.ID()) || !list.RemoveByID(healthy.ID()) {
+		t.Fatalf("members should be removed")
+	}
+	if len(list.Healthy()) != 0 || len(list.Jailed()) != 0 {
+		t.Errorf("list should be empty")
+	}
+	if list.Generation()-generation != 2 {
+		t.Errorf("every removal should bump generation")
+	}
+
+	if list.RemoveByID(healthy.ID()) {
+		t.Errorf("removed service should not be found again")
+	}
+}
+
+func TestServicesListRemoveByIDDuringTryUp(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  10 * time.Millisecond,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	srv := newSlowService("https://1gateway.fm", 0)
+	list.Add(srv)
+	list.FromHealthyToJail(srv.ID())
+	srv.delay.Store(int64(100 * time.Millisecond))
+
+	done := make(chan struct{})
+	go func() {
+		list.TryUpService(srv, 0)
+		close(done)
+	}()
+
+	// service is removed while its check is in flight
+	time.Sleep(30 * time.Millisecond)
+	if !list.RemoveByID(srv.ID()) {
+		t.Fatalf("jailed service should be removed")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("try up should stop once service is removed")
+	}
+
+	if list.CountAll() != 0 || list.Next() != nil {
+		t.Errorf("removed service should not come back, got %d members", list.CountAll())
+	}
+}
@@ -0,0 +1,89 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// Requirement is capability and circuit size job needs,
+// only services that advertise the capability and handle
+// the circuit size are selected for the job
+type Requirement struct {
+	Capability  string // capability service should advertise, e.g. "plonk", "groth16" or "stark" (empty for any)
+	CircuitSize int    // circuit size of the job, services with smaller max circuit size are skipped (0 for any)
+}
+
+// requirementKey is context key of job requirement
+type requirementKey struct{}
+
+// WithRequirement return context with given requirement of the
+// job, selections with the context are limited to capable services
+func WithRequirement(ctx context.Context, req Requirement) context.Context {
+	return context.WithValue(ctx, requirementKey{}, req)
+}
+
+// RequirementFromContext return requirement of the
+// job and report if requirement is set
+func RequirementFromContext(ctx context.Context) (Requirement, bool) {
+	req, ok := ctx.Value(requirementKey{}).(Requirement)
+	return req, ok
+}
+
+// Satisfied check if given service meets the requirement
+func (r Requirement) Satisfied(srv service.IService) bool {
+	if r.Capability != "" && !service.HasCapability(srv, r.Capability) {
+		return false
+	}
+
+	if r.CircuitSize > 0 {
+		if size := service.MaxCircuitSize(srv); size > 0 && size < r.CircuitSize {
+			return false
+		}
+	}
+
+	return true
+}
+
+// allowedRequirement check if given service meets
+// requirement of the job from given context
+func allowedRequirement(ctx context.Context, srv service.IService) bool {
+	req, ok := RequirementFromContext(ctx)
+	return !ok || req.Satisfied(srv)
+}
+
+// NextCapable returns next healthy service meeting given
+// requirement to take a connection using configured
+// balancing, nil is returned if no service is capable
+func (l *ServicesList) NextCapable(ctx context.Context, req Requirement) service.IService {
+	return l.NextContext(WithRequirement(ctx, req))
+}
+
+// NextCapable returns next healthy service
+// of all shards meeting given requirement
+func (l *ShardedServicesList) NextCapable(ctx context.Context, req Requirement) service.IService {
+	return l.NextContext(WithRequirement(ctx, req))
+}
+
+// Capable return healthy services meeting given requirement
+func (l *ServicesList) Capable(req Requirement) []service.IService {
+	return capable(l.Healthy(), req)
+}
+
+// Capable return healthy services of
+// all shards meeting given requirement
+func (l *ShardedServicesList) Capable(req Requirement) []service.IService {
+	return capable(l.Healthy(), req)
+}
+
+// capable return services meeting given requirement
+func capable(services []service.IService, req Requirement) []service.IService {
+	var matches []service.IService
+	for _, srv := range services {
+		if req.Satisfied(srv) {
+			matches = append(matches, srv)
+		}
+	}
+
+	return matches
+}
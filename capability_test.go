@@ -0,0 +1,66 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestNextCapable(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Shards:         2,
+	})
+	defer list.Close()
+
+	plonk := newMetadataService("https://1gateway.fm", map[string]string{
+		service.CapabilitiesMetadataKey:   "plonk, groth16",
+		service.MaxCircuitSizeMetadataKey: "1024",
+	})
+	stark := newMetadataService("https://2gateway.fm", map[string]string{
+		service.CapabilitiesMetadataKey: "stark",
+	})
+	list.Add(plonk)
+	list.Add(stark)
+
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if srv := list.NextCapable(ctx, Requirement{Capability: "Groth16"}); srv != plonk {
+			t.Fatalf("expected groth16 prover, got %v", srv)
+		}
+		if srv := list.NextCapable(ctx, Requirement{Capability: "stark", CircuitSize: 1 << 20}); srv != stark {
+			t.Fatalf("expected unlimited stark prover, got %v", srv)
+		}
+	}
+
+	if srv := list.NextCapable(ctx, Requirement{Capability: "plonk", CircuitSize: 2048}); srv != nil {
+		t.Errorf("circuit is too large for plonk prover, got %s", srv.Address())
+	}
+
+	if capable := list.Capable(Requirement{CircuitSize: 2048}); len(capable) != 1 || capable[0] != stark {
+		t.Errorf("expected only stark prover to handle the circuit, got %v", capable)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	srv := newMetadataService("https://1gateway.fm", map[string]string{
+		service.CapabilitiesMetadataKey:   " plonk,,stark ",
+		service.MaxCircuitSizeMetadataKey: "invalid",
+	})
+
+	capabilities := service.Capabilities(srv)
+	if len(capabilities) != 2 || capabilities[0] != "plonk" || capabilities[1] != "stark" {
+		t.Errorf("unexpected capabilities %v", capabilities)
+	}
+	if service.MaxCircuitSize(srv) != 0 {
+		t.Errorf("invalid max circuit size should be unlimited, got %d", service.MaxCircuitSize(srv))
+	}
+	if service.HasCapability(newHealthyService("https://2gateway.fm"), "plonk") {
+		t.Errorf("service without metadata should not have capabilities")
+	}
+}
@@ -0,0 +1,275 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// CEL expressions variables
+const (
+	celServiceVar  = "service"  // map with id, address, node_name, status, tags and load keys
+	celMetadataVar = "metadata" // map of service metadata, empty if service doesn't expose it
+	celRequestVar  = "request"  // map of request attributes from context
+	celScoreVar    = "score"    // current selection score of the service
+)
+
+// requestAttributesKey is context key of request attributes
+type requestAttributesKey struct{}
+
+// IContextPolicy is policy that takes request context
+// into account during selection. If policy implements
+// this interface, AllowContext is used instead of Allow
+type IContextPolicy interface {
+	IPolicy
+
+	// AllowContext check if given healthy service could
+	// be selected to take a connection for given request
+	AllowContext(ctx context.Context, srv service.IService) bool
+}
+
+// CELPolicyOpts is options that needs to configure
+// CELPolicy, every expression is optional
+type CELPolicyOpts struct {
+	Name   string         `json:"name" yaml:"name"`
+	Admit  string         `json:"admit" yaml:"admit"`   // bool expression over service to admit it to the list
+	Allow  string         `json:"allow" yaml:"allow"`   // bool expression over service and request to allow selection
+	Score  string         `json:"score" yaml:"score"`   // double expression over service, request and score to adjust score
+	Routes []CELRouteOpts `json:"routes" yaml:"routes"` // routing rules, the first matched one is used instead of Allow
+}
+
+// CELRouteOpts is options of CELPolicy routing rule
+// that routes matched requests to matched services
+type CELRouteOpts struct {
+	Name  string `json:"name" yaml:"name"`
+	Match string `json:"match" yaml:"match"` // bool expression over request to match the rule
+	Allow string `json:"allow" yaml:"allow"` // bool expression over service and request to allow selection of matched request
+}
+
+// CELPolicy is IPolicy implementation that evaluates
+// Common Expression Language expressions over service
+// metadata and request attributes
+type CELPolicy struct {
+	name string
+
+	admit  cel.Program
+	allow  cel.Program
+	score  cel.Program
+	routes []celRoute
+}
+
+// celRoute is compiled CELPolicy routing rule
+type celRoute struct {
+	name  string
+	match cel.Program
+	allow cel.Program
+}
+
+// ContextWithRequestAttributes return context with given request
+// attributes that are available for policies during selection
+func ContextWithRequestAttributes(ctx context.Context, attrs map[string]string) context.Context {
+	return context.WithValue(ctx, requestAttributesKey{}, attrs)
+}
+
+// RequestAttributesFromContext return request
+// attributes from given context
+func RequestAttributesFromContext(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+
+	attrs, _ := ctx.Value(requestAttributesKey{}).(map[string]string)
+	return attrs
+}
+
+// NewCELPolicy compile given expressions
+// and create new CELPolicy
+func NewCELPolicy(opts *CELPolicyOpts) (*CELPolicy, error) {
+	env, err := cel.NewEnv(
+		cel.Variable(celServiceVar, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(celMetadataVar, cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable(celRequestVar, cel.MapType(cel.StringType, cel.StringType)),
+		cel.Variable(celScoreVar, cel.DoubleType),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create cel env: %w", err)
+	}
+
+	p := &CELPolicy{name: opts.Name}
+
+	if p.admit, err = compileCEL(env, opts.Admit, cel.BoolType); err != nil {
+		return nil, fmt.Errorf("compile admit expression: %w", err)
+	}
+
+	if p.allow, err = compileCEL(env, opts.Allow, cel.BoolType); err != nil {
+		return nil, fmt.Errorf("compile allow expression: %w", err)
+	}
+
+	if p.score, err = compileCEL(env, opts.Score, cel.DoubleType); err != nil {
+		return nil, fmt.Errorf("compile score expression: %w", err)
+	}
+
+	for i, opts := range opts.Routes {
+		route := celRoute{name: opts.Name}
+		if route.name == "" {
+			route.name = fmt.Sprintf("#%d", i)
+		}
+
+		if opts.Match == "" || opts.Allow == "" {
+			return nil, fmt.Errorf("route %s: match and allow expressions are required", route.name)
+		}
+
+		if route.match, err = compileCEL(env, opts.Match, cel.BoolType); err != nil {
+			return nil, fmt.Errorf("compile route %s match expression: %w", route.name, err)
+		}
+
+		if route.allow, err = compileCEL(env, opts.Allow, cel.BoolType); err != nil {
+			return nil, fmt.Errorf("compile route %s allow expression: %w", route.name, err)
+		}
+
+		p.routes = append(p.routes, route)
+	}
+
+	return p, nil
+}
+
+// Name return policy name
+func (p *CELPolicy) Name() string {
+	return p.name
+}
+
+// Admit evaluate admit expression over given service
+func (p *CELPolicy) Admit(srv service.IService) error {
+	if p.admit == nil {
+		return nil
+	}
+
+	out, _, err := p.admit.Eval(celActivation(context.Background(), srv, 0))
+	if err != nil {
+		return fmt.Errorf("evaluate admit expression: %w", err)
+	}
+
+	if admitted, ok := out.Value().(bool); !ok || !admitted {
+		return errors.New("not admitted by cel expression")
+	}
+
+	return nil
+}
+
+// Allow evaluate allow expression over
+// given service without request attributes
+func (p *CELPolicy) Allow(srv service.IService) bool {
+	return p.AllowContext(context.Background(), srv)
+}
+
+// AllowContext evaluate allow expression of the first routing
+// rule matching request attributes from given context, or
+// allow expression of the policy if no rule is matched, over
+// given service and the request. Rule is not matched and
+// service is not allowed if evaluation is failed
+func (p *CELPolicy) AllowContext(ctx context.Context, srv service.IService) bool {
+	activation := celActivation(ctx, srv, 0)
+
+	allow := p.allow
+	for _, route := range p.routes {
+		if evalCELBool(route.match, activation) {
+			allow = route.allow
+			break
+		}
+	}
+
+	if allow == nil {
+		return true
+	}
+
+	return evalCELBool(allow, activation)
+}
+
+// Score evaluate score expression over given service and
+// score, given score is returned if evaluation is failed
+func (p *CELPolicy) Score(srv service.IService, score float64) float64 {
+	if p.score == nil {
+		return score
+	}
+
+	out, _, err := p.score.Eval(celActivation(context.Background(), srv, score))
+	if err != nil {
+		return score
+	}
+
+	if adjusted, ok := out.Value().(float64); ok {
+		return adjusted
+	}
+
+	return score
+}
+
+// compileCEL compile given expression and check its output
+// type, returns nil program for empty expression
+func compileCEL(env *cel.Env, expr string, outType *cel.Type) (cel.Program, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+
+	// service fields are dynamic, so expressions
+	// over them are type checked at evaluation
+	if !ast.OutputType().IsExactType(outType) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression %q should return %s, got %s", expr, outType, ast.OutputType())
+	}
+
+	return env.Program(ast)
+}
+
+// evalCELBool evaluate given bool expression,
+// false is returned if evaluation is failed
+func evalCELBool(program cel.Program, activation map[string]interface{}) bool {
+	out, _, err := program.Eval(activation)
+	if err != nil {
+		return false
+	}
+
+	value, ok := out.Value().(bool)
+	return ok && value
+}
+
+// celActivation create expression variables
+// from given context, service and score
+func celActivation(ctx context.Context, srv service.IService, score float64) map[string]interface{} {
+	tags := make([]string, 0, len(srv.Tags()))
+	for tag := range srv.Tags() {
+		tags = append(tags, tag)
+	}
+
+	metadata := service.Metadata(srv)
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+
+	request := RequestAttributesFromContext(ctx)
+	if request == nil {
+		request = map[string]string{}
+	}
+
+	return map[string]interface{}{
+		celServiceVar: map[string]interface{}{
+			"id":        srv.ID(),
+			"address":   srv.Address(),
+			"node_name": srv.NodeName(),
+			"status":    srv.Status().String(),
+			"tags":      tags,
+			"load":      float64(srv.Load()),
+		},
+		celMetadataVar: metadata,
+		celRequestVar:  request,
+		celScoreVar:    score,
+	}
+}
@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestCELPolicy(t *testing.T) {
+	policy, err := NewCELPolicy(&CELPolicyOpts{
+		Name:  "cel",
+		Admit: `"gpu" in service.tags`,
+		Allow: `!("zone" in request) || request.zone in service.tags`,
+		Score: `score * 2.0`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected cel policy error: %s", err)
+	}
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Policies:       []IPolicy{policy},
+	})
+
+	cpu := service.NewService("https://1gateway.fm", "", map[string]struct{}{"cpu": {}}, 0.5)
+	eu := service.NewService("https://2gateway.fm", "", map[string]struct{}{"gpu": {}, "eu": {}}, 0.5)
+	us := service.NewService("https://3gateway.fm", "", map[string]struct{}{"gpu": {}, "us": {}}, 0.5)
+
+	for _, srv := range []service.IService{cpu, eu, us} {
+		srv.(*service.BaseService).SetStatus(service.StatusHealthy)
+		list.Add(srv)
+	}
+
+	if list.CountAll() != 2 {
+		t.Fatalf("service without gpu tag should not be admitted")
+	}
+
+	ctx := ContextWithRequestAttributes(context.Background(), map[string]string{"zone": "us"})
+	for i := 0; i < 5; i++ {
+		if srv := list.NextContext(ctx); srv == nil || srv.ID() != us.ID() {
+			t.Fatalf("only service from requested zone should be selected")
+		}
+	}
+
+	if score := policy.Score(us, 0.25); score != 0.5 {
+		t.Errorf("unexpected adjusted score %f", score)
+	}
+
+	if _, err := NewCELPolicy(&CELPolicyOpts{Allow: `request.zone`}); err == nil {
+		t.Errorf("expected error for non-bool allow expression")
+	}
+}
+
+func TestCELPolicyRoutes(t *testing.T) {
+	policy, err := NewCELPolicy(&CELPolicyOpts{
+		Name:  "cel",
+		Allow: `!("circuit" in metadata && metadata.circuit == "legacy")`,
+		Routes: []CELRouteOpts{
+			{Name: "batch", Match: `request.circuit == "batch"`, Allow: `metadata.circuit == "batch"`},
+			{Name: "zone", Match: `"zone" in request`, Allow: `"zone" in metadata && metadata.zone == request.zone`},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected cel policy error: %s", err)
+	}
+
+	newService := func(addr string, metadata map[string]string) service.IService {
+		srv := service.NewService(addr, "", nil, 0.5).(*service.BaseService)
+		srv.SetMetadata(metadata)
+		return srv
+	}
+
+	batch := newService("https://1gateway.fm", map[string]string{"circuit": "batch", "zone": "eu"})
+	legacy := newService("https://2gateway.fm", map[string]string{"circuit": "legacy", "zone": "us"})
+	plain := newService("https://3gateway.fm", nil)
+
+	cases := []struct {
+		name    string
+		request map[string]string
+		allowed []service.IService
+	}{
+		{"default", nil, []service.IService{batch, plain}},
+		{"batch route", map[string]string{"circuit": "batch", "zone": "us"}, []service.IService{batch}},
+		{"zone route", map[string]string{"zone": "us"}, []service.IService{legacy}},
+	}
+
+	for _, c := range cases {
+		ctx := ContextWithRequestAttributes(context.Background(), c.request)
+		for _, srv := range []service.IService{batch, legacy, plain} {
+			want := false
+			for _, allowed := range c.allowed {
+				want = want || allowed == srv
+			}
+
+			if got := policy.AllowContext(ctx, srv); got != want {
+				t.Errorf("%s: service %s allowed %t, want %t", c.name, srv.Address(), got, want)
+			}
+		}
+	}
+
+	if _, err := NewCELPolicy(&CELPolicyOpts{Routes: []CELRouteOpts{{Match: `true`}}}); err == nil {
+		t.Errorf("expected error for route without allow expression")
+	}
+}
@@ -0,0 +1,39 @@
+package pool
+
+import "time"
+
+// DefaultMaxClockSkew is tolerated difference between local
+// clock and clocks of remote parties supplying timestamps
+const DefaultMaxClockSkew = 2 * time.Minute
+
+// remoteAge return age of externally supplied timestamp relative to
+// given local time. Timestamps from the future within tolerated skew
+// are treated as just created, ErrClockSkew is returned for others.
+// Remote timestamps are compared only once on receipt, durations
+// derived from them should be tracked with local monotonic time
+func remoteAge(t, now time.Time, skew time.Duration) (time.Duration, error) {
+	// monotonic reading of local time is stripped, because
+	// remote timestamp has only the wall clock reading
+	age := now.Round(0).Sub(t)
+	if age >= 0 {
+		return age, nil
+	}
+
+	if -age > skew {
+		return 0, ErrClockSkew{Time: t, Skew: -age}
+	}
+
+	return 0, nil
+}
+
+// isRemoteExpired check if externally supplied timestamp is older than
+// given ttl, the ttl is extended by tolerated skew so timestamps of
+// parties with clocks behind the local one are not expired prematurely
+func isRemoteExpired(t, now time.Time, ttl, skew time.Duration) (bool, error) {
+	age, err := remoteAge(t, now, skew)
+	if err != nil {
+		return false, err
+	}
+
+	return age > ttl+skew, nil
+}
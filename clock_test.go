@@ -0,0 +1,73 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRemoteTimestamps(t *testing.T) {
+	now := time.Now()
+	skew := 2 * time.Minute
+
+	tests := []struct {
+		name    string
+		time    time.Time
+		expired bool
+		err     bool
+	}{
+		{"fresh", now.Add(-10 * time.Second), false, false},
+		{"ahead within skew", now.Add(time.Minute), false, false},
+		{"behind within skew", now.Add(-2 * time.Minute), false, false},
+		{"expired", now.Add(-5 * time.Minute), true, false},
+		{"ahead beyond skew", now.Add(10 * time.Minute), false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// remote timestamps have no monotonic reading
+			expired, err := isRemoteExpired(tt.time.Round(0), now, time.Minute, skew)
+			if tt.err != errors.As(err, &ErrClockSkew{}) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if expired != tt.expired {
+				t.Errorf("expected expired %t, got %t", tt.expired, expired)
+			}
+		})
+	}
+}
+
+func TestGossipSkewedVerdicts(t *testing.T) {
+	g := &Gossip{
+		name:       "local",
+		verdictTTL: time.Minute,
+		skew:       DefaultMaxClockSkew,
+		lists:      make(map[string]IServicesList),
+		probing:    make(map[string]struct{}),
+	}
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Minute,
+		ChecksInterval: time.Minute,
+	})
+	defer list.Close()
+	g.Attach("testServicesList", list)
+
+	srv := newSwitchableService("https://1gateway.fm")
+	list.Add(srv)
+	srv.down.Store(true)
+
+	for _, at := range []time.Time{time.Now().Add(time.Hour), time.Now().Add(-time.Hour)} {
+		g.apply(Verdict{Pool: "testServicesList", Service: srv.ID(), Kind: VerdictJailed, Node: "remote", Time: at})
+		if len(list.Jailed()) != 0 {
+			t.Fatalf("verdict with time %s should be ignored", at)
+		}
+	}
+
+	// verdict of node with clock slightly ahead is applied
+	g.apply(Verdict{Pool: "testServicesList", Service: srv.ID(), Kind: VerdictJailed, Node: "remote", Time: time.Now().Add(time.Minute)})
+	if len(list.Jailed()) != 1 {
+		t.Errorf("verdict within tolerated skew should be applied")
+	}
+}
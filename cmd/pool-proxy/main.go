@@ -0,0 +1,271 @@
+// Command pool-proxy run pools of prover services declared in
+// config file as sidecar: services are discovered and checked
+// by the pools and http and grpc requests accepted on listen
+// address are proxied to healthy services, so services not
+// written in Go get health-aware routing without embedding
+// the library. Pools are introspected and controlled with
+// admin api served on admin address, mutating admin requests
+// require bearer token read from admin token file
+//
+// Usage:
+//
+//	pool-proxy -config pools.yaml -listen :8080 -admin :9090 -admin-token-file token
+//
+// Requests are routed to the pool named in X-Pool header
+// or to the default pool, admin api of pool is served
+// under /pools/{name}/ path. Health verdicts of pools are
+// served to Envoy as REST endpoint discovery on admin
+// address and pushed to HAProxy runtime api if configured
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gateway-fm/scriptorium/logger"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"gopkg.in/yaml.v3"
+
+	pool "github.com/gateway-fm/prover-pool-lib"
+)
+
+// options is command line options
+type options struct {
+	config       string
+	listen       string
+	admin        string
+	defaultPool  string
+	poolHeader   string
+	scheme       string
+	healthchecks bool
+	grpcService  string
+	haproxy      string
+	adminToken   string
+	drainTimeout time.Duration
+}
+
+func main() {
+	opts := options{}
+	flag.StringVar(&opts.config, "config", "pools.yaml", "yaml or json list of pool specs")
+	flag.StringVar(&opts.listen, "listen", ":8080", "address of http and grpc proxy")
+	flag.StringVar(&opts.admin, "admin", ":9090", "address of admin api (empty to disable)")
+	flag.StringVar(&opts.defaultPool, "default-pool", "", "pool of requests without pool header (the only pool by default)")
+	flag.StringVar(&opts.poolHeader, "pool-header", "X-Pool", "request header naming the pool")
+	flag.StringVar(&opts.scheme, "scheme", "http", "scheme of services addresses given without one")
+	flag.BoolVar(&opts.healthchecks, "healthchecks", true, "run healthchecks of pools")
+	flag.StringVar(&opts.grpcService, "grpc-health-service", "", "service name checked by grpc health protocol (empty for overall server health)")
+	flag.StringVar(&opts.haproxy, "haproxy-socket", "", "haproxy runtime api socket health verdicts are pushed to (empty to disable)")
+	flag.StringVar(&opts.adminToken, "admin-token-file", "", "file with bearer token authorizing mutating admin requests (empty for read-only admin api)")
+	flag.DurationVar(&opts.drainTimeout, "drain-timeout", 30*time.Second, "time given to in-flight requests on shutdown")
+	flag.Parse()
+
+	if err := run(opts); err != nil {
+		logger.Log().Error(fmt.Errorf("pool proxy: %w", err).Error())
+		os.Exit(1)
+	}
+}
+
+// run start pools, proxy and admin api and
+// serve them until termination signal
+func run(opts options) error {
+	specs, err := loadSpecs(opts.config)
+	if err != nil {
+		return err
+	}
+
+	if opts.defaultPool == "" && len(specs) == 1 {
+		opts.defaultPool = specs[0].Name
+	}
+
+	registry := pool.NewPoolRegistry(&pool.PoolRegistryOpts{})
+	defer registry.Close()
+
+	// grpc services are checked with standard health
+	// protocol, others with theirs own healthchecks
+	checker := pool.NewGRPCHealthChecker(&pool.GRPCHealthCheckerOpts{Service: opts.grpcService})
+
+	for _, spec := range specs {
+		poolOpts, err := spec.Options()
+		if err != nil {
+			return err
+		}
+		poolOpts.ListOpts.HealthChecker = checker
+		if _, err := registry.Create(poolOpts, opts.healthchecks); err != nil {
+			return err
+		}
+
+		logger.Log().Info(fmt.Sprintf("pool name %s is started with driver %s", spec.Name, spec.Driver))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// health verdicts are served to envoy with
+	// admin api and pushed to haproxy if configured
+	envoy := pool.NewEnvoyEndpoints(opts.scheme)
+	exporters := []pool.IHealthExporter{envoy}
+	if opts.haproxy != "" {
+		exporters = append(exporters, pool.NewHAProxyExporter(&pool.HAProxyExporterOpts{Address: opts.haproxy}))
+	}
+	for _, name := range registry.Names() {
+		p, _ := registry.Get(name)
+		go pool.NewHealthExport(&pool.HealthExportOpts{List: p.List(), Exporters: exporters}).Run(ctx)
+	}
+
+	proxy := pool.NewProxyHandler(&pool.ProxyOpts{
+		Scheme:      opts.scheme,
+		PoolHeader:  opts.poolHeader,
+		DefaultPool: opts.defaultPool,
+	})
+
+	// grpc clients speak cleartext http/2 to the sidecar
+	servers := []*http.Server{{
+		Addr:              opts.listen,
+		Handler:           h2c.NewHandler(proxy.Registry(registry), &http2.Server{}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}}
+	if opts.admin != "" {
+		authorize, err := adminAuth(opts.adminToken)
+		if err != nil {
+			return err
+		}
+
+		servers = append(servers, &http.Server{
+			Addr:              opts.admin,
+			Handler:           newAdminMux(registry, envoy, authorize),
+			ReadHeaderTimeout: 10 * time.Second,
+		})
+	}
+
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		go func() {
+			logger.Log().Info(fmt.Sprintf("pool proxy listen on %s", server.Addr))
+
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	logger.Log().Info("pool proxy is shutting down")
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), opts.drainTimeout)
+	defer cancel()
+
+	for _, server := range servers {
+		_ = server.Shutdown(drainCtx)
+	}
+
+	for _, name := range registry.Names() {
+		if p, ok := registry.Get(name); ok {
+			if drainErr := p.Drain(drainCtx); drainErr != nil {
+				logger.Log().Warn(drainErr.Error())
+			}
+		}
+	}
+
+	return err
+}
+
+// loadSpecs read and validate pool specs from file with given path
+func loadSpecs(path string) ([]pool.PoolSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	if err := pool.ValidateConfig(data); err != nil {
+		return nil, err
+	}
+
+	var specs []pool.PoolSpec
+	if err := yaml.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("decode config: %w", err)
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("config has no pools")
+	}
+
+	return specs, nil
+}
+
+// adminAuth return authorization of mutating admin requests
+// by token read from file with given path, nil if the path
+// is empty, so admin api is read-only
+func adminAuth(path string) (func(r *http.Request) error, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read admin token: %w", err)
+	}
+
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, errors.New("admin token file is empty")
+	}
+
+	return pool.BearerTokenAuth(token), nil
+}
+
+// newAdminMux create handler of admin api of registry pools,
+// mutating requests are authorized with given authorization
+func newAdminMux(registry *pool.PoolRegistry, envoy http.Handler, authorize func(r *http.Request) error) http.Handler {
+	var (
+		mu       sync.Mutex
+		handlers = make(map[pool.IServicesPool]http.Handler)
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.Handle("POST /v3/discovery:endpoints", envoy)
+	mux.HandleFunc("GET /pools", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("content-type", "application/json")
+		_ = json.NewEncoder(w).Encode(registry.Names())
+	})
+	mux.HandleFunc("/pools/{name}/", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+
+		p, ok := registry.Get(name)
+		if !ok {
+			http.Error(w, pool.ErrPoolNotFound{Name: name}.Error(), http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		handler, ok := handlers[p]
+		if !ok {
+			handler = http.StripPrefix("/pools/"+name, pool.NewAdminHandlerWithOpts(p.List(), &pool.AdminHandlerOpts{
+				Discover:  p.DiscoverServicesContext,
+				Authorize: authorize,
+			}))
+			handlers[p] = handler
+		}
+		mu.Unlock()
+
+		handler.ServeHTTP(w, r)
+	})
+
+	return mux
+}
@@ -0,0 +1,184 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// ReleaseFunc finish in-flight connection to service
+// returned by Acquire, it is safe to call it more
+// than once
+type ReleaseFunc func()
+
+// connections is tracker of in-flight
+// connections to services of the list
+type connections struct {
+	mu     sync.Mutex
+	counts map[string]int // service id -> number of in-flight connections
+}
+
+// newConnections create empty connections tracker
+func newConnections() *connections {
+	return &connections{counts: make(map[string]int)}
+}
+
+// acquire register in-flight connection
+// to service with given id
+func (c *connections) acquire(id string) {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	c.counts[id]++
+}
+
+// release finish in-flight connection to service with
+// given id, connections of forgotten services are skipped
+func (c *connections) release(id string) {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	if c.counts[id] <= 1 {
+		delete(c.counts, id)
+		return
+	}
+
+	c.counts[id]--
+}
+
+// count return number of in-flight
+// connections to service with given id
+func (c *connections) count(id string) int {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	return c.counts[id]
+}
+
+// total return number of in-flight
+// connections to all services
+func (c *connections) total() int {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	total := 0
+	for _, count := range c.counts {
+		total += count
+	}
+
+	return total
+}
+
+// forget remove in-flight connections
+// of service with given id
+func (c *connections) forget(id string) {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	delete(c.counts, id)
+}
+
+// Acquire returns next healthy service to take a connection like
+// NextContext and register in-flight connection to it, returned
+// release should be called once the connection is finished.
+// In-flight connections are used by least-connections balancing
+// and in-flight caps, nil is returned if no service is acquired.
+// It doesn't wait for capacity regardless of configured overflow
+func (l *ServicesList) Acquire(ctx context.Context) (service.IService, ReleaseFunc) {
+	srv, release, _ := l.tryAcquire(ctx, false)
+	return srv, release
+}
+
+// acquire select next healthy service and register in-flight
+// connection to it under the same lock, so concurrent callers
+// see each other connections
+func (l *ServicesList) acquire(ctx context.Context) service.IService {
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	srv := l.nextLocked(ctx)
+	if srv != nil {
+		l.connections.acquire(srv.ID())
+	}
+
+	return srv
+}
+
+// releaseFunc return ReleaseFunc that finish
+// in-flight connection to given service once
+func (l *ServicesList) releaseFunc(srv service.IService) ReleaseFunc {
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			l.connections.release(srv.ID())
+			l.capacity.notify()
+			l.checkDrained(srv.ID())
+		})
+	}
+}
+
+// InFlight return number of in-flight connections
+// to service with given id acquired by Acquire
+func (l *ServicesList) InFlight(id string) int {
+	return l.connections.count(id)
+}
+
+// leastConnectionsStrategy select candidate
+// with the fewest in-flight connections
+type leastConnectionsStrategy struct {
+	inFlight func(id string) int
+	current  uint64
+}
+
+// LeastConnections return strategy that select candidate with the
+// fewest in-flight connections reported by given function, e.g.
+// InFlight of the list, ties are broken using round-robin. Lists
+// configured with BalancingLeastConnections count connections
+// registered by Acquire
+func LeastConnections(inFlight func(id string) int) IBalancingStrategy {
+	return &leastConnectionsStrategy{inFlight: inFlight}
+}
+
+// Name return strategy name
+func (s *leastConnectionsStrategy) Name() string {
+	return BalancingLeastConnections.String()
+}
+
+// Select return candidate with the fewest in-flight connections
+func (s *leastConnectionsStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var (
+		selected service.IService
+		minCount int
+	)
+
+	next := int(atomic.AddUint64(&s.current, 1) % uint64(len(candidates)))
+	for i := next; i < len(candidates)+next; i++ {
+		srv := candidates[i%len(candidates)]
+		if count := s.inFlight(srv.ID()); selected == nil || count < minCount {
+			selected, minCount = srv, count
+		}
+	}
+
+	return selected
+}
+
+// Acquire returns next healthy service to take a connection
+// from shards selected using round-robin and register
+// in-flight connection to it without waiting for capacity
+func (l *ShardedServicesList) Acquire(ctx context.Context) (service.IService, ReleaseFunc) {
+	srv, release, _ := l.tryAcquire(ctx, false)
+	return srv, release
+}
+
+// InFlight return number of in-flight connections
+// to service with given id acquired by Acquire
+func (l *ShardedServicesList) InFlight(id string) int {
+	return l.shard(id).InFlight(id)
+}
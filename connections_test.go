@@ -0,0 +1,99 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLeastConnections(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Balancing:      BalancingLeastConnections,
+	})
+
+	for i := 1; i <= 3; i++ {
+		list.Add(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+
+	ctx := context.Background()
+
+	// every service takes one long job
+	releases := make(map[string]ReleaseFunc)
+	for i := 0; i < 3; i++ {
+		srv, release := list.Acquire(ctx)
+		if _, ok := releases[srv.ID()]; ok {
+			t.Fatalf("service %s with in-flight connection is acquired while others are idle", srv.ID())
+		}
+		releases[srv.ID()] = release
+	}
+
+	// the service that finished its job takes the next ones
+	fast := list.Healthy()[1]
+	releases[fast.ID()]()
+	releases[fast.ID()]()
+
+	for i := 0; i < 3; i++ {
+		srv, release := list.Acquire(ctx)
+		if srv.ID() != fast.ID() {
+			t.Fatalf("expected idle service %s, got %s", fast.ID(), srv.ID())
+		}
+		release()
+	}
+
+	for _, srv := range list.Healthy() {
+		expected := 1
+		if srv.ID() == fast.ID() {
+			expected = 0
+		}
+		if inFlight := list.InFlight(srv.ID()); inFlight != expected {
+			t.Errorf("expected %d in-flight connections of %s, got %d", expected, srv.ID(), inFlight)
+		}
+	}
+
+	// removed service does not keep its connections
+	slow := list.Healthy()[0]
+	list.RemoveFromHealthyByIndex(0)
+	releases[slow.ID()]()
+
+	if inFlight := list.InFlight(slow.ID()); inFlight != 0 {
+		t.Errorf("removed service should not have in-flight connections, got %d", inFlight)
+	}
+}
+
+func TestLeastConnectionsConcurrent(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Shards:         2,
+		Balancing:      BalancingLeastConnections,
+	})
+
+	for i := 1; i <= 4; i++ {
+		list.Add(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, release := list.Acquire(context.Background())
+				release()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, srv := range list.Healthy() {
+		if inFlight := list.InFlight(srv.ID()); inFlight != 0 {
+			t.Errorf("released connections should not be in flight, %s has %d", srv.ID(), inFlight)
+		}
+	}
+}
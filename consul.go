@@ -0,0 +1,200 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultConsulAddress  = "http://127.0.0.1:8500"
+	defaultConsulPrefix   = "prover-pool"
+	defaultConsulInterval = 10 * time.Second
+	defaultConsulTimeout  = 5 * time.Second
+)
+
+// ConsulPublisherOpts is options that configure
+// publisher of pool view to Consul KV
+type ConsulPublisherOpts struct {
+	Address    string        // consul agent http address (http://127.0.0.1:8500 by default)
+	Token      string        // consul acl token (empty for anonymous)
+	Datacenter string        // consul datacenter (empty for agent's one)
+	Prefix     string        // kv prefix the view is written under as <prefix>/<pool name> ("prover-pool" by default)
+	Interval   time.Duration // publishing interval (10s by default)
+	Client     *http.Client  // http client to use (client with 5s timeout by default)
+}
+
+// ConsulPoolView is pool view computed by the
+// gateway and published to Consul KV as json
+type ConsulPoolView struct {
+	Name       string              `json:"name"`
+	Generation uint64              `json:"generation"`
+	Status     ListStatus          `json:"status"`
+	Services   []ConsulServiceView `json:"services"`
+}
+
+// ConsulServiceView is health verdict of one
+// list member published as part of ConsulPoolView
+type ConsulServiceView struct {
+	service.Record
+	Healthy    bool    `json:"healthy"`
+	Membership string  `json:"membership"`
+	Score      float64 `json:"score"`  // load adjusted by the list routing policies
+	Weight     uint16  `json:"weight"` // relative weight in [1, 101] range, higher for less loaded services
+}
+
+// ConsulPublisher periodically write services list health
+// verdicts to Consul KV, so other systems could consume
+// the view without embedding the library
+type ConsulPublisher struct {
+	list IServicesList
+	opts ConsulPublisherOpts
+
+	published []byte
+}
+
+// NewConsulPublisher create new ConsulPublisher
+// of given services list with given configuration
+func NewConsulPublisher(list IServicesList, opts *ConsulPublisherOpts) *ConsulPublisher {
+	p := &ConsulPublisher{
+		list: list,
+		opts: *opts,
+	}
+
+	if p.opts.Address == "" {
+		p.opts.Address = defaultConsulAddress
+	}
+	if p.opts.Prefix == "" {
+		p.opts.Prefix = defaultConsulPrefix
+	}
+	if p.opts.Interval == 0 {
+		p.opts.Interval = defaultConsulInterval
+	}
+	if p.opts.Client == nil {
+		p.opts.Client = &http.Client{Timeout: defaultConsulTimeout}
+	}
+
+	return p
+}
+
+// Run publish pool view periodically
+// until given stop channel is closed
+func (p *ConsulPublisher) Run(stop <-chan struct{}) {
+	listLogger(p.list).Info("start consul publisher", "address", p.opts.Address)
+
+	for {
+		select {
+		case <-stop:
+			listLogger(p.list).Warn("stop consul publisher")
+			return
+		default:
+			if err := p.Publish(context.Background()); err != nil {
+				listLogger(p.list).Warn("publish pool view to consul error", "error", err)
+			}
+			Sleep(p.opts.Interval, stop)
+		}
+	}
+}
+
+// Publish write current pool view to Consul KV,
+// unchanged view is not written again
+func (p *ConsulPublisher) Publish(ctx context.Context) error {
+	view := p.View()
+
+	body, err := json.Marshal(view)
+	if err != nil {
+		return fmt.Errorf("marshal pool view: %w", err)
+	}
+
+	if bytes.Equal(body, p.published) {
+		return nil
+	}
+
+	if err := p.put(ctx, view.Name, body); err != nil {
+		return err
+	}
+
+	p.published = body
+
+	return nil
+}
+
+// View return current pool view
+func (p *ConsulPublisher) View() *ConsulPoolView {
+	state := p.list.Snapshot()
+
+	view := &ConsulPoolView{
+		Name:       state.Name,
+		Generation: state.Generation,
+		Status:     state.Status,
+		Services:   make([]ConsulServiceView, 0, len(state.Services)),
+	}
+
+	scores := make(map[string]float64)
+	weights := make(map[string]uint16)
+	for _, srv := range p.list.Healthy() {
+		score := float64(srv.Load())
+		for _, policy := range p.list.Policies() {
+			score = policy.Score(srv, score)
+		}
+
+		scores[srv.ID()] = score
+		weights[srv.ID()] = serviceWeight(srv)
+	}
+
+	for _, srv := range state.Services {
+		_, ok := weights[srv.ID]
+
+		view.Services = append(view.Services, ConsulServiceView{
+			Record:     srv.Record,
+			Healthy:    ok,
+			Membership: srv.Membership,
+			Score:      scores[srv.ID],
+			Weight:     weights[srv.ID],
+		})
+	}
+
+	return view
+}
+
+// put write given value to Consul KV key of given pool
+func (p *ConsulPublisher) put(ctx context.Context, name string, value []byte) error {
+	u, err := url.Parse(p.opts.Address)
+	if err != nil {
+		return fmt.Errorf("parse consul address: %w", err)
+	}
+
+	u.Path = path.Join(u.Path, "/v1/kv", p.opts.Prefix, name)
+	if p.opts.Datacenter != "" {
+		u.RawQuery = url.Values{"dc": {p.opts.Datacenter}}.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(value))
+	if err != nil {
+		return fmt.Errorf("create consul request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if p.opts.Token != "" {
+		req.Header.Set("X-Consul-Token", p.opts.Token)
+	}
+
+	resp, err := p.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("put consul kv: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ErrConsulRequest{Key: path.Join(p.opts.Prefix, name), StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestConsulPublisher(t *testing.T) {
+	var (
+		puts  int
+		path  string
+		token string
+		view  ConsulPoolView
+	)
+
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		puts++
+		path = r.URL.String()
+		token = r.Header.Get("X-Consul-Token")
+
+		if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+			t.Errorf("unexpected body decode error: %s", err)
+		}
+
+		_, _ = w.Write([]byte("true"))
+	}))
+	defer consul.Close()
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+
+	healthy := service.NewService("https://1gateway.fm", "", nil, 0.25)
+	healthy.(*service.BaseService).SetStatus(service.StatusHealthy)
+	list.Add(healthy)
+	list.Add(newUnhealthyService("https://2gateway.fm"))
+
+	publisher := NewConsulPublisher(list, &ConsulPublisherOpts{
+		Address:    consul.URL,
+		Token:      "secret",
+		Datacenter: "dc1",
+	})
+
+	if err := publisher.Publish(context.Background()); err != nil {
+		t.Fatalf("unexpected publish error: %s", err)
+	}
+
+	if path != "/v1/kv/prover-pool/testServicesList?dc=dc1" || token != "secret" {
+		t.Errorf("unexpected consul request %s with token %q", path, token)
+	}
+
+	if len(view.Services) != 2 {
+		t.Fatalf("expected 2 services in view, got %d", len(view.Services))
+	}
+	for _, srv := range view.Services {
+		switch srv.ID {
+		case healthy.ID():
+			if !srv.Healthy || srv.Weight != 76 || srv.Score != 0.25 {
+				t.Errorf("unexpected healthy service view %+v", srv)
+			}
+		default:
+			if srv.Healthy || srv.Weight != 0 {
+				t.Errorf("unexpected jailed service view %+v", srv)
+			}
+		}
+	}
+
+	if err := publisher.Publish(context.Background()); err != nil || puts != 1 {
+		t.Errorf("unchanged view should not be published again")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+
+	var errConsul ErrConsulRequest
+	err := NewConsulPublisher(list, &ConsulPublisherOpts{Address: failing.URL}).Publish(context.Background())
+	if !errors.As(err, &errConsul) || errConsul.StatusCode != http.StatusForbidden {
+		t.Errorf("expected consul request error, got %v", err)
+	}
+}
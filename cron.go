@@ -0,0 +1,195 @@
+package pool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ISchedule is calendar of scheduled check runs
+type ISchedule interface {
+	// Next return the first run time after given time
+	Next(after time.Time) time.Time
+}
+
+// Every is schedule with fixed interval between runs
+type Every time.Duration
+
+// Next return the first run time after given time
+func (e Every) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(e))
+}
+
+// cronSchedule is cron schedule evaluated in UTC
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bitsets of allowed values
+
+	// day matches either day of month or day of week
+	// if both fields are restricted, as in classic cron
+	domRestricted, dowRestricted bool
+}
+
+// cronField is bounds of cron schedule field
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = [5]cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// cronDescriptors is predefined schedules
+var cronDescriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@nightly":  "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// ParseCron parse cron schedule evaluated in UTC. Classic five
+// fields "minute hour day-of-month month day-of-week" are supported
+// with lists, ranges and steps, e.g. "*/10 * * * *" or "0 2 * * 1-5",
+// as well as @hourly, @daily, @nightly, @weekly, @monthly descriptors
+// and "@every <duration>" for fixed intervals
+func ParseCron(spec string) (ISchedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || interval <= 0 {
+			return nil, ErrInvalidSchedule{Spec: spec, Reason: "invalid interval"}
+		}
+		return Every(interval), nil
+	}
+
+	if descriptor, ok := cronDescriptors[spec]; ok {
+		spec = descriptor
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, ErrInvalidSchedule{Spec: spec, Reason: fmt.Sprintf("expected %d fields, got %d", len(cronFields), len(fields))}
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, ErrInvalidSchedule{Spec: spec, Reason: err.Error()}
+		}
+		bits[i] = set
+	}
+
+	return &cronSchedule{
+		minute:        bits[0],
+		hour:          bits[1],
+		dom:           bits[2],
+		month:         bits[3],
+		dow:           bits[4],
+		domRestricted: !strings.HasPrefix(fields[2], "*"),
+		dowRestricted: !strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// MustParseCron is like ParseCron but panics
+// if given schedule could not be parsed
+func MustParseCron(spec string) ISchedule {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+
+	return schedule
+}
+
+// parseCronField return bitset of values
+// allowed by given comma separated field
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(after)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid %s step %q", bounds.name, after)
+			}
+			rng, step = before, n
+		}
+
+		from, to := bounds.min, bounds.max
+		if rng != "*" {
+			lo, hi, isRange := strings.Cut(rng, "-")
+
+			var err error
+			if from, err = strconv.Atoi(lo); err != nil {
+				return 0, fmt.Errorf("invalid %s value %q", bounds.name, lo)
+			}
+			to = from
+			if isRange {
+				if to, err = strconv.Atoi(hi); err != nil {
+					return 0, fmt.Errorf("invalid %s value %q", bounds.name, hi)
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 to the end with step 15
+				to = bounds.max
+			}
+		}
+
+		if from < bounds.min || to > bounds.max || from > to {
+			return 0, fmt.Errorf("%s range %q is out of %d-%d", bounds.name, rng, bounds.min, bounds.max)
+		}
+
+		for v := from; v <= to; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+
+	return set, nil
+}
+
+// Next return the first run time after given time
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+
+	// every matching time is found within five
+	// years, also for schedules like "0 0 29 2 *"
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// matchDay check if day of given time is allowed
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+
+	return dom && dow
+}
@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 5, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"*/10 * * * *", time.Date(2024, 5, 15, 10, 10, 0, 0, time.UTC)},
+		{"5/15 * * * *", time.Date(2024, 5, 15, 10, 20, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, 5, 16, 2, 0, 0, 0, time.UTC)},
+		{"30 9-17 * * 1-5", time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 0 * * 0,6", time.Date(2024, 5, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1,20 * 1", time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@nightly", time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", now.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("unexpected parse error: %s", err)
+			}
+
+			if next := schedule.Next(now); !next.Equal(tt.expected) {
+				t.Errorf("expected next run at %s, got %s", tt.expected, next)
+			}
+		})
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every soon"} {
+		if _, err := ParseCron(spec); !errors.As(err, &ErrInvalidSchedule{}) {
+			t.Errorf("expected invalid schedule error for %q, got %v", spec, err)
+		}
+	}
+
+	if next := MustParseCron("0 0 31 2 *").Next(now); !next.IsZero() {
+		t.Errorf("impossible schedule should have no next run, got %s", next)
+	}
+}
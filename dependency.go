@@ -0,0 +1,122 @@
+package pool
+
+import (
+	"slices"
+	"sync/atomic"
+)
+
+// ListStatus represent overall status of services list
+type ListStatus string
+
+const (
+	// ListStatusHealthy is status of list
+	// with at least one healthy service
+	ListStatusHealthy ListStatus = "healthy"
+
+	// ListStatusUnhealthy is status of
+	// list without healthy services
+	ListStatusUnhealthy ListStatus = "unhealthy"
+
+	// ListStatusDependencyDegraded is status of list which
+	// dependency has no healthy services, failed healthchecks
+	// of its members don't move them to the jail
+	ListStatusDependencyDegraded ListStatus = "dependency_degraded"
+)
+
+// Status return overall status of the list
+func (l *ServicesList) Status() ListStatus {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	return l.statusLocked()
+}
+
+// statusLocked return overall status of the
+// list. Should be called under the list lock
+func (l *ServicesList) statusLocked() ListStatus {
+	if atomic.LoadInt32(&l.degraded) == 1 {
+		return ListStatusDependencyDegraded
+	}
+
+	if len(l.healthy) == 0 {
+		return ListStatusUnhealthy
+	}
+
+	return ListStatusHealthy
+}
+
+// checkDependencies check if any dependency list has no
+// healthy services, update degraded state and emit event
+// on its change. Returns true if list is degraded
+func (l *ServicesList) checkDependencies() bool {
+	l.mu.RLock()
+	dependencies := l.dependencies
+	l.mu.RUnlock()
+
+	var down IServicesList
+	for _, dependency := range dependencies {
+		if len(dependency.Healthy()) == 0 {
+			down = dependency
+			break
+		}
+	}
+
+	degraded := down != nil
+
+	var value int32
+	if degraded {
+		value = 1
+	}
+
+	if atomic.SwapInt32(&l.degraded, value) == value {
+		return degraded
+	}
+
+	if degraded {
+		l.log().Warn("list is degraded due to dependency without healthy services, failed members are not jailed")
+		l.emit(PoolEvent{Type: EventDependencyDegraded})
+	} else {
+		l.log().Info("dependencies are recovered")
+		l.emit(PoolEvent{Type: EventDependencyRecovered})
+	}
+
+	return degraded
+}
+
+// rebindDependency replace given dependency of the list, e.g.
+// pool replaced in the registry, and recheck dependencies
+func (l *ServicesList) rebindDependency(from, to IServicesList) {
+	l.mu.Lock()
+	dependencies := slices.Clone(l.dependencies)
+	for i, dependency := range dependencies {
+		if dependency == from {
+			dependencies[i] = to
+		}
+	}
+	l.dependencies = dependencies
+	l.mu.Unlock()
+
+	l.checkDependencies()
+}
+
+// rebindDependency replace given dependency of all shards
+func (l *ShardedServicesList) rebindDependency(from, to IServicesList) {
+	for _, shard := range l.shards {
+		shard.rebindDependency(from, to)
+	}
+}
+
+// Status return overall status of all shards
+func (l *ShardedServicesList) Status() ListStatus {
+	status := ListStatusUnhealthy
+	for _, shard := range l.shards {
+		switch shard.Status() {
+		case ListStatusDependencyDegraded:
+			return ListStatusDependencyDegraded
+		case ListStatusHealthy:
+			status = ListStatusHealthy
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServicesListDependencyDegraded(t *testing.T) {
+	dependency := NewServicesList("testDependencyList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+
+	var events []PoolEvent
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Dependencies:   []IServicesList{dependency},
+		OnEvent: func(event PoolEvent) {
+			events = append(events, event)
+		},
+	}).(*ServicesList)
+
+	srv := newSwitchableService("https://1gateway.fm")
+	srv.down.Store(true)
+	list.mu.Lock()
+	list.healthy = append(list.healthy, srv)
+	list.mu.Unlock()
+
+	list.HealthChecks()
+
+	if list.Status() != ListStatusDependencyDegraded || list.Snapshot().Status != ListStatusDependencyDegraded {
+		t.Fatalf("list should be degraded due to dependency, got %s", list.Status())
+	}
+
+	if len(events) != 1 || events[0].Type != EventDependencyDegraded {
+		t.Fatalf("dependency degraded event expected, got %+v", events)
+	}
+
+	if len(list.Healthy()) != 1 {
+		t.Fatalf("failed service should not be jailed while dependency is degraded")
+	}
+
+	// service is recovered, so no jail event races the check
+	srv.down.Store(false)
+
+	dependency.Add(newHealthyService("https://2gateway.fm"))
+	list.HealthChecks()
+
+	if list.Status() == ListStatusDependencyDegraded {
+		t.Errorf("list should not be degraded after dependency recovery")
+	}
+
+	if len(events) != 2 || events[1].Type != EventDependencyRecovered {
+		t.Errorf("dependency recovered event expected, got %+v", events)
+	}
+}
@@ -0,0 +1,317 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultConsulAddress  = "http://127.0.0.1:8500"
+	defaultConsulTimeout  = 5 * time.Second
+	defaultConsulWaitTime = 5 * time.Minute
+)
+
+// ConsulDiscoveryOpts is options that needs
+// to configure ConsulDiscovery instance
+type ConsulDiscoveryOpts struct {
+	Address    string        // consul agent http address (http://127.0.0.1:8500 by default)
+	Token      string        // consul acl token (empty for anonymous)
+	Datacenter string        // consul datacenter (empty for agent's one)
+	Tag        string        // consul tag services are filtered by (empty for all)
+	Passing    bool          // discover only services passing consul health checks
+	Scheme     string        // scheme of service addresses, e.g. "http" (empty for host:port addresses)
+	Timeout    time.Duration // one-shot discovery timeout (5 seconds by default)
+	WaitTime   time.Duration // maximum duration of blocking query while watching (5 minutes by default)
+	Client     *http.Client  // http client to use, its timeout should exceed the wait time (http.DefaultClient by default)
+}
+
+// ConsulDiscovery is service discovery driver that reads
+// services registered in consul catalog. Consul service
+// tags and meta are kept as service tags and metadata
+type ConsulDiscovery struct {
+	opts ConsulDiscoveryOpts
+}
+
+// consulServiceEntry is entry of consul health service response
+type consulServiceEntry struct {
+	Node struct {
+		Node    string `json:"Node"`
+		Address string `json:"Address"`
+	} `json:"Node"`
+	Service struct {
+		ID      string            `json:"ID"`
+		Address string            `json:"Address"`
+		Port    int               `json:"Port"`
+		Tags    []string          `json:"Tags"`
+		Meta    map[string]string `json:"Meta"`
+	} `json:"Service"`
+}
+
+// NewConsulDiscovery create new ConsulDiscovery
+// driver with given configuration
+func NewConsulDiscovery(opts *ConsulDiscoveryOpts) *ConsulDiscovery {
+	d := &ConsulDiscovery{opts: *opts}
+
+	if d.opts.Address == "" {
+		d.opts.Address = defaultConsulAddress
+	}
+	if d.opts.Timeout <= 0 {
+		d.opts.Timeout = defaultConsulTimeout
+	}
+	if d.opts.WaitTime <= 0 {
+		d.opts.WaitTime = defaultConsulWaitTime
+	}
+	if d.opts.Client == nil {
+		d.opts.Client = http.DefaultClient
+	}
+
+	return d
+}
+
+// Discover return services registered
+// with given name sorted by address
+func (d *ConsulDiscovery) Discover(name string) ([]service.IService, error) {
+	return d.DiscoverContext(context.Background(), name)
+}
+
+// DiscoverContext return services registered with given name
+// sorted by address, the query is interrupted once given
+// context is done or configured timeout elapses
+func (d *ConsulDiscovery) DiscoverContext(ctx context.Context, name string) ([]service.IService, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+
+	services, _, err := d.query(ctx, name, 0)
+
+	return services, err
+}
+
+// Watch call given function every time services registered with
+// given name change. Consul blocking queries are used, so changes
+// are seen within moments of registration. It blocks until given
+// context is done, nil is returned in this case
+func (d *ConsulDiscovery) Watch(ctx context.Context, name string, changed func()) error {
+	var (
+		index    uint64
+		previous []service.IService
+	)
+
+	for {
+		services, next, err := d.query(ctx, name, index)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("watch services of %s: %w", name, err)
+		}
+
+		if index != 0 && !sameServices(previous, services) {
+			changed()
+		}
+
+		// index going backwards means consul state is reset,
+		// e.g. after restore, and index is never zero so
+		// queries keep blocking
+		if next < index {
+			next = 0
+		}
+
+		index, previous = max(next, 1), services
+	}
+}
+
+// DiscoverPages call given function for every page of services
+// registered with given name in consul catalog order. Consul
+// health endpoint isn't paginated, so the response is decoded
+// entry by entry and only one page of services is buffered.
+// The request is interrupted once given context is done or
+// configured timeout elapses
+func (d *ConsulDiscovery) DiscoverPages(ctx context.Context, name string, pageSize int, fn PageFunc) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+
+	resp, err := d.request(ctx, name, 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return d.decodePages(resp.Body, name, pageSize, fn)
+}
+
+// query request services registered with given name, blocking
+// until consul index exceeds given one if it's not zero, and
+// return them with consul index of the response
+func (d *ConsulDiscovery) query(ctx context.Context, name string, index uint64) ([]service.IService, uint64, error) {
+	resp, err := d.request(ctx, name, index)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var services []service.IService
+	err = d.decodePages(resp.Body, name, DefaultPageSize, func(page []service.IService) error {
+		services = append(services, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Address() < services[j].Address()
+	})
+
+	return services, next, nil
+}
+
+// request send health service request of services registered with
+// given name, blocking until consul index exceeds given one if it's
+// not zero. Body of returned successful response should be closed
+func (d *ConsulDiscovery) request(ctx context.Context, name string, index uint64) (*http.Response, error) {
+	u, err := url.Parse(d.opts.Address)
+	if err != nil {
+		return nil, fmt.Errorf("parse consul address: %w", err)
+	}
+
+	u.Path = path.Join(u.Path, "/v1/health/service", name)
+
+	query := url.Values{}
+	if d.opts.Datacenter != "" {
+		query.Set("dc", d.opts.Datacenter)
+	}
+	if d.opts.Tag != "" {
+		query.Set("tag", d.opts.Tag)
+	}
+	if d.opts.Passing {
+		query.Set("passing", "true")
+	}
+	if index != 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%ds", int(d.opts.WaitTime.Seconds())))
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create consul request: %w", err)
+	}
+
+	if d.opts.Token != "" {
+		req.Header.Set("X-Consul-Token", d.opts.Token)
+	}
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query consul services of %s: %w", name, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("query consul services of %s: consul responded with status %d", name, resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// decodePages decode json array of consul entries from given
+// body entry by entry and call given function for every page
+// of at most pageSize services of given name
+func (d *ConsulDiscovery) decodePages(body io.Reader, name string, pageSize int, fn PageFunc) error {
+	decoder := json.NewDecoder(body)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("decode consul services of %s: %w", name, err)
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("decode consul services of %s: unexpected %v instead of array", name, token)
+	}
+
+	page := make([]service.IService, 0, pageSize)
+	for decoder.More() {
+		var entry consulServiceEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return fmt.Errorf("decode consul services of %s: %w", name, err)
+		}
+
+		page = append(page, d.service(entry))
+		if len(page) < pageSize {
+			continue
+		}
+
+		if err := fn(page); err != nil {
+			return err
+		}
+		page = make([]service.IService, 0, pageSize)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return fmt.Errorf("decode consul services of %s: %w", name, err)
+	}
+
+	if len(page) == 0 {
+		return nil
+	}
+
+	return fn(page)
+}
+
+// service create service of given consul entry, service
+// address falls back to the node address if it's empty
+func (d *ConsulDiscovery) service(entry consulServiceEntry) service.IService {
+	host := entry.Service.Address
+	if host == "" {
+		host = entry.Node.Address
+	}
+
+	addr := net.JoinHostPort(host, strconv.Itoa(entry.Service.Port))
+	if d.opts.Scheme != "" {
+		addr = d.opts.Scheme + "://" + addr
+	}
+
+	record := service.Record{
+		Address:  addr,
+		NodeName: entry.Node.Node,
+		Tags:     entry.Service.Tags,
+		Metadata: entry.Service.Meta,
+	}
+
+	return record.Service()
+}
+
+// sameServices check if given sorted
+// services sets have the same members
+func sameServices(a, b []service.IService) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if !service.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
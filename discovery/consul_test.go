@@ -0,0 +1,160 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// fakeConsul is consul agent serving health
+// service blocking queries from memory
+type fakeConsul struct {
+	mu      sync.Mutex
+	index   uint64
+	entries []consulServiceEntry
+	updated chan struct{}
+}
+
+// register add service entry with given
+// address and port and bump the index
+func (c *fakeConsul) register(addr string, port int) {
+	var entry consulServiceEntry
+	entry.Node.Node = "node-" + addr
+	entry.Node.Address = addr
+	entry.Service.Port = port
+	entry.Service.Tags = []string{"prover"}
+
+	c.mu.Lock()
+	c.entries = append(c.entries, entry)
+	c.index++
+	close(c.updated)
+	c.updated = make(chan struct{})
+	c.mu.Unlock()
+}
+
+func (c *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/health/service/prover" || r.URL.Query().Get("passing") != "true" {
+		http.NotFound(w, r)
+		return
+	}
+
+	c.mu.Lock()
+	index, updated := c.index, c.updated
+	c.mu.Unlock()
+
+	if requested, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); requested != 0 && requested >= index {
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(c.index, 10))
+	_ = json.NewEncoder(w).Encode(c.entries)
+}
+
+func TestConsulDiscovery(t *testing.T) {
+	consul := &fakeConsul{updated: make(chan struct{})}
+	consul.register("10.0.0.2", 8080)
+
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	d := NewConsulDiscovery(&ConsulDiscoveryOpts{
+		Address: server.URL,
+		Passing: true,
+		Scheme:  "http",
+	})
+
+	services, err := d.Discover("prover")
+	if err != nil {
+		t.Fatalf("unexpected discover error: %s", err)
+	}
+	if len(services) != 1 || services[0].Address() != "http://10.0.0.2:8080" || services[0].NodeName() != "node-10.0.0.2" {
+		t.Fatalf("unexpected services %v", services)
+	}
+	if _, ok := services[0].Tags()["prover"]; !ok {
+		t.Errorf("consul tags should be kept")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Watch(ctx, "prover", func() {
+			changed <- struct{}{}
+		})
+	}()
+
+	// give the watch time to block
+	time.Sleep(50 * time.Millisecond)
+	consul.register("10.0.0.1", 8080)
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watch should report registered service")
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("canceled watch should return no error, got %s", err)
+	}
+}
+
+func TestConsulDiscoverPages(t *testing.T) {
+	consul := &fakeConsul{updated: make(chan struct{})}
+	for _, addr := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+		consul.register(addr, 8080)
+	}
+
+	server := httptest.NewServer(consul)
+	defer server.Close()
+
+	d := NewConsulDiscovery(&ConsulDiscoveryOpts{
+		Address: server.URL,
+		Passing: true,
+	})
+
+	var pages [][]string
+	err := d.DiscoverPages(context.Background(), "prover", 2, func(services []service.IService) error {
+		var page []string
+		for _, srv := range services {
+			page = append(page, srv.Address())
+		}
+		pages = append(pages, page)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected discover pages error: %s", err)
+	}
+
+	expected := [][]string{{"10.0.0.1:8080", "10.0.0.2:8080"}, {"10.0.0.3:8080"}}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Errorf("expected pages %v, got %v", expected, pages)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = d.DiscoverPages(context.Background(), "prover", 1, func([]service.IService) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("error of page function should stop the discovery, got %v after %d calls", err, calls)
+	}
+}
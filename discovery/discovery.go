@@ -0,0 +1,155 @@
+package discovery
+
+import (
+	"context"
+
+	"github.com/gateway-fm/scriptorium/logger"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// DefaultPageSize is the number of services
+// returned in a single discovery page
+const DefaultPageSize = 500
+
+// ILogger is structured logger of drivers, args are
+// alternating keys and values. Logger of the pool
+// satisfies it, so drivers could share it
+type ILogger interface {
+	// Warn log message about recoverable problem
+	Warn(msg string, args ...any)
+}
+
+// scriptoriumLogger is default logger of drivers
+// backed by scriptorium zap logger like the pool one
+type scriptoriumLogger struct{}
+
+// Warn log message with warn level
+func (scriptoriumLogger) Warn(msg string, args ...any) {
+	logger.Log().Sugar().Warnw(msg, args...)
+}
+
+// IServiceDiscovery is generic interface
+// for service discovery drivers
+type IServiceDiscovery interface {
+	// Discover returns list of services
+	// registered with given name
+	Discover(name string) ([]service.IService, error)
+}
+
+// IContextDiscovery is service discovery driver which
+// calls could be canceled or limited by deadline
+type IContextDiscovery interface {
+	IServiceDiscovery
+
+	// DiscoverContext returns list of services registered
+	// with given name, the call is interrupted once
+	// given context is done
+	DiscoverContext(ctx context.Context, name string) ([]service.IService, error)
+}
+
+// PageFunc is called for every page of discovered
+// services, returned error stops the discovery
+type PageFunc func(services []service.IService) error
+
+// IStreamingDiscovery is service discovery driver that
+// could retrieve large catalogs page by page instead of
+// buffering all services into a single slice
+type IStreamingDiscovery interface {
+	IServiceDiscovery
+
+	// DiscoverPages call given function for every page of
+	// services registered with given name until given context
+	// is done, pages contain at most pageSize services
+	DiscoverPages(ctx context.Context, name string, pageSize int, fn PageFunc) error
+}
+
+// IWatchingDiscovery is service discovery driver that could
+// notify about changes of registrations instead of waiting
+// for the next rediscovery round
+type IWatchingDiscovery interface {
+	IServiceDiscovery
+
+	// Watch call given function every time registrations of
+	// services with given name change. It blocks until given
+	// context is done or the watch is broken
+	Watch(ctx context.Context, name string, changed func()) error
+}
+
+// DiscoverContext returns services registered with given name
+// using given driver until given context is done. Calls of
+// drivers without context support are abandoned once the
+// context is done and theirs results are dropped
+func DiscoverContext(ctx context.Context, d IServiceDiscovery, name string) ([]service.IService, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if driver, ok := d.(IContextDiscovery); ok {
+		return driver.DiscoverContext(ctx, name)
+	}
+
+	type result struct {
+		services []service.IService
+		err      error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		services, err := d.Discover(name)
+		done <- result{services: services, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-done:
+		return res.services, res.err
+	}
+}
+
+// DiscoverPages call given function for every page of services
+// registered with given name, drivers without pagination
+// support are discovered at once and split into pages
+func DiscoverPages(d IServiceDiscovery, name string, pageSize int, fn PageFunc) error {
+	return DiscoverPagesContext(context.Background(), d, name, pageSize, fn)
+}
+
+// DiscoverPagesContext call given function for every page of
+// services registered with given name like DiscoverPages, the
+// discovery is interrupted once given context is done
+func DiscoverPagesContext(ctx context.Context, d IServiceDiscovery, name string, pageSize int, fn PageFunc) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	page := func(services []service.IService) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		return fn(services)
+	}
+
+	if streaming, ok := d.(IStreamingDiscovery); ok {
+		return streaming.DiscoverPages(ctx, name, pageSize, page)
+	}
+
+	services, err := DiscoverContext(ctx, d, name)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(services); start += pageSize {
+		end := start + pageSize
+		if end > len(services) {
+			end = len(services)
+		}
+
+		if err := page(services[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
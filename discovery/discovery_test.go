@@ -0,0 +1,98 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// blockingDiscovery is driver without context
+// support blocked until it is released
+type blockingDiscovery struct {
+	release chan struct{}
+}
+
+func (d *blockingDiscovery) Discover(string) ([]service.IService, error) {
+	<-d.release
+	return []service.IService{service.NewService("https://1gateway.fm", "", nil, 0)}, nil
+}
+
+func TestDiscoverContext(t *testing.T) {
+	d := &blockingDiscovery{release: make(chan struct{})}
+	defer close(d.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// driver without context support is abandoned
+	if _, err := DiscoverContext(ctx, d, "prover"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+
+	err := DiscoverPagesContext(ctx, d, "prover", 10, func([]service.IService) error {
+		t.Errorf("pages should not be delivered after deadline")
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error of paged discovery, got %v", err)
+	}
+}
+
+func TestConsulDiscoverContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	d := NewConsulDiscovery(&ConsulDiscoveryOpts{
+		Address: server.URL,
+		Timeout: time.Minute,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	if _, err := d.DiscoverContext(ctx, "prover"); err == nil {
+		t.Errorf("canceled query should fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("query should be interrupted on cancel, took %s", elapsed)
+	}
+}
+
+func TestConsulDiscoverPagesContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	d := NewConsulDiscovery(&ConsulDiscoveryOpts{
+		Address: server.URL,
+		Timeout: time.Minute,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// streaming request is interrupted by the caller context
+	start := time.Now()
+	err := DiscoverPagesContext(ctx, d, "prover", 10, func([]service.IService) error {
+		t.Errorf("pages should not be delivered after deadline")
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error of paged discovery, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("paged discovery should be interrupted on deadline, took %s", elapsed)
+	}
+}
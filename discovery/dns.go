@@ -0,0 +1,180 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const defaultDNSTimeout = 5 * time.Second
+
+// DNSRecord represent type of dns
+// record resolved by DNSDiscovery
+type DNSRecord int
+
+const (
+	// DNSRecordSRV resolve SRV record, every target
+	// is discovered as service with its own port
+	DNSRecordSRV DNSRecord = iota
+
+	// DNSRecordA resolve A and AAAA records, every
+	// address is discovered as service with
+	// configured port
+	DNSRecordA
+)
+
+// String return dns record type name
+func (r DNSRecord) String() string {
+	switch r {
+	case DNSRecordA:
+		return "A"
+	default:
+		return "SRV"
+	}
+}
+
+// DNSDiscoveryOpts is options that needs
+// to configure DNSDiscovery instance
+type DNSDiscoveryOpts struct {
+	Record   DNSRecord     // type of resolved record (SRV by default)
+	Name     string        // resolved name, e.g. "provers.pool.svc.cluster.local" (discovered service name by default)
+	Service  string        // SRV service, e.g. "grpc" for "_grpc._tcp.<name>" (empty to resolve name as is)
+	Proto    string        // SRV protocol, e.g. "tcp" (empty to resolve name as is)
+	Port     int           // port of A/AAAA addresses
+	Scheme   string        // scheme of service addresses, e.g. "http" (empty for host:port addresses)
+	Tags     []string      // tags of discovered services
+	Timeout  time.Duration // resolve timeout (5 seconds by default)
+	Resolver *net.Resolver // resolver to use (net.DefaultResolver by default)
+}
+
+// DNSDiscovery is service discovery driver that resolves SRV
+// or A/AAAA records, e.g. of headless kubernetes services
+type DNSDiscovery struct {
+	opts DNSDiscoveryOpts
+}
+
+// NewDNSDiscovery create new DNSDiscovery
+// driver with given configuration
+func NewDNSDiscovery(opts *DNSDiscoveryOpts) *DNSDiscovery {
+	d := &DNSDiscovery{opts: *opts}
+
+	if d.opts.Timeout <= 0 {
+		d.opts.Timeout = defaultDNSTimeout
+	}
+	if d.opts.Resolver == nil {
+		d.opts.Resolver = net.DefaultResolver
+	}
+
+	return d
+}
+
+// Discover resolve configured record and return services sorted
+// by address. SRV targets carry SRV weight as service weight and
+// priority and weight as metadata
+func (d *DNSDiscovery) Discover(name string) ([]service.IService, error) {
+	return d.DiscoverContext(context.Background(), name)
+}
+
+// DiscoverContext resolve configured record and return services
+// sorted by address like Discover, the lookup is interrupted once
+// given context is done or configured timeout elapses
+func (d *DNSDiscovery) DiscoverContext(ctx context.Context, name string) ([]service.IService, error) {
+	if d.opts.Name != "" {
+		name = d.opts.Name
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+
+	var (
+		services []service.IService
+		err      error
+	)
+
+	switch d.opts.Record {
+	case DNSRecordA:
+		services, err = d.discoverA(ctx, name)
+	default:
+		services, err = d.discoverSRV(ctx, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Address() < services[j].Address()
+	})
+
+	return services, nil
+}
+
+// discoverSRV resolve SRV record of given name
+func (d *DNSDiscovery) discoverSRV(ctx context.Context, name string) ([]service.IService, error) {
+	_, records, err := d.opts.Resolver.LookupSRV(ctx, d.opts.Service, d.opts.Proto, name)
+	if err != nil {
+		return nil, fmt.Errorf("lookup SRV record of %s: %w", name, err)
+	}
+
+	services := make([]service.IService, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+
+		srv := service.NewService(d.address(host, int(record.Port)), host, d.tags(), 0).(*service.BaseService)
+		srv.SetWeight(int(record.Weight))
+		srv.SetMetadata(map[string]string{
+			service.PriorityMetadataKey: strconv.Itoa(int(record.Priority)),
+			service.WeightMetadataKey:   strconv.Itoa(int(record.Weight)),
+		})
+
+		services = append(services, srv)
+	}
+
+	return services, nil
+}
+
+// discoverA resolve A and AAAA records of given name
+func (d *DNSDiscovery) discoverA(ctx context.Context, name string) ([]service.IService, error) {
+	if d.opts.Port == 0 {
+		return nil, fmt.Errorf("port of %s addresses is not configured", name)
+	}
+
+	addrs, err := d.opts.Resolver.LookupIPAddr(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("lookup A/AAAA records of %s: %w", name, err)
+	}
+
+	services := make([]service.IService, 0, len(addrs))
+	for _, addr := range addrs {
+		services = append(services, service.NewService(d.address(addr.IP.String(), d.opts.Port), name, d.tags(), 0))
+	}
+
+	return services, nil
+}
+
+// address return service address
+// of given host and port
+func (d *DNSDiscovery) address(host string, port int) string {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	if d.opts.Scheme == "" {
+		return addr
+	}
+
+	return d.opts.Scheme + "://" + addr
+}
+
+// tags return tags of discovered services,
+// every service has its own copy
+func (d *DNSDiscovery) tags() map[string]struct{} {
+	tags := make(map[string]struct{}, len(d.opts.Tags))
+	for _, tag := range d.opts.Tags {
+		tags[tag] = struct{}{}
+	}
+
+	return tags
+}
@@ -0,0 +1,134 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// newTestResolver start dns server answering with given records
+// and return resolver that sends all queries to it
+func newTestResolver(t *testing.T, records ...string) *net.Resolver {
+	t.Helper()
+
+	zone := make(map[uint16][]dns.RR)
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatalf("unexpected record error: %s", err)
+		}
+		zone[rr.Header().Rrtype] = append(zone[rr.Header().Rrtype], rr)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected listen error: %s", err)
+	}
+
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		for _, rr := range zone[req.Question[0].Qtype] {
+			if rr.Header().Name == req.Question[0].Name {
+				resp.Answer = append(resp.Answer, rr)
+			}
+		}
+		_ = w.WriteMsg(resp)
+	})}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = server.Shutdown()
+	})
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", conn.LocalAddr().String())
+		},
+	}
+}
+
+func TestDNSDiscoverySRV(t *testing.T) {
+	resolver := newTestResolver(t,
+		"_grpc._tcp.provers.local. 5 IN SRV 10 3 9000 prover-1.provers.local.",
+		"_grpc._tcp.provers.local. 5 IN SRV 20 1 9001 prover-2.provers.local.",
+	)
+
+	d := NewDNSDiscovery(&DNSDiscoveryOpts{
+		Service:  "grpc",
+		Proto:    "tcp",
+		Scheme:   "http",
+		Tags:     []string{"gpu"},
+		Resolver: resolver,
+	})
+
+	services, err := d.Discover("provers.local")
+	if err != nil {
+		t.Fatalf("unexpected discover error: %s", err)
+	}
+
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+
+	first := services[0]
+	if first.Address() != "http://prover-1.provers.local:9000" || first.NodeName() != "prover-1.provers.local" {
+		t.Errorf("unexpected service %s with node name %s", first.Address(), first.NodeName())
+	}
+	if service.Weight(first) != 3 || service.Metadata(first)["priority"] != "10" {
+		t.Errorf("SRV weight and priority should be kept, got %d and %v", service.Weight(first), service.Metadata(first))
+	}
+	if _, ok := first.Tags()["gpu"]; !ok {
+		t.Errorf("configured tags should be set")
+	}
+	if services[1].Address() != "http://prover-2.provers.local:9001" {
+		t.Errorf("unexpected service %s", services[1].Address())
+	}
+}
+
+func TestDNSDiscoveryA(t *testing.T) {
+	resolver := newTestResolver(t,
+		"provers.local. 5 IN A 10.0.0.2",
+		"provers.local. 5 IN A 10.0.0.1",
+		"provers.local. 5 IN AAAA fd00::1",
+	)
+
+	d := NewDNSDiscovery(&DNSDiscoveryOpts{
+		Record:   DNSRecordA,
+		Name:     "provers.local",
+		Resolver: resolver,
+	})
+
+	if _, err := d.Discover("provers"); err == nil {
+		t.Fatalf("discovery without port should fail")
+	}
+
+	d.opts.Port = 8080
+
+	services, err := d.Discover("provers")
+	if err != nil {
+		t.Fatalf("unexpected discover error: %s", err)
+	}
+
+	var addresses []string
+	for _, srv := range services {
+		addresses = append(addresses, srv.Address())
+	}
+
+	expected := []string{"10.0.0.1:8080", "10.0.0.2:8080", "[fd00::1]:8080"}
+	if len(addresses) != len(expected) {
+		t.Fatalf("expected addresses %v, got %v", expected, addresses)
+	}
+	for i := range expected {
+		if addresses[i] != expected[i] {
+			t.Errorf("expected addresses %v, got %v", expected, addresses)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package discovery
+
+import "fmt"
+
+// ErrUnsupportedDriver is error when no discovery
+// driver is registered with given name
+type ErrUnsupportedDriver struct {
+	Driver string
+}
+
+// Error is throw error as a string
+func (e ErrUnsupportedDriver) Error() string {
+	return fmt.Sprintf("unsupported discovery driver %q", e.Driver)
+}
+
+// ErrInvalidDriverParam is error when setting of
+// discovery driver is missing or has invalid value
+type ErrInvalidDriverParam struct {
+	Driver string
+	Param  string
+	Value  string
+}
+
+// Error is throw error as a string
+func (e ErrInvalidDriverParam) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("discovery driver %q requires %q", e.Driver, e.Param)
+	}
+
+	return fmt.Sprintf("invalid %q of discovery driver %q: %q", e.Param, e.Driver, e.Value)
+}
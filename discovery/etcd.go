@@ -0,0 +1,380 @@
+package discovery
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultEtcdEndpoint = "http://127.0.0.1:2379"
+	defaultEtcdPrefix   = "/prover-pool/services"
+	defaultEtcdTimeout  = 5 * time.Second
+)
+
+// EtcdDiscoveryOpts is options that needs
+// to configure EtcdDiscovery instance
+type EtcdDiscoveryOpts struct {
+	Endpoints []string      // etcd http endpoints tried in order (http://127.0.0.1:2379 by default)
+	Prefix    string        // key prefix services are registered under as <prefix>/<name>/<id> ("/prover-pool/services" by default)
+	Tags      []string      // tags added to discovered services
+	Timeout   time.Duration // range request timeout (5 seconds by default)
+	Client    *http.Client  // http client to use, e.g. with client certificates (http.DefaultClient by default)
+
+	Logger    ILogger                     // logger of the driver, e.g. logger of the pool (scriptorium logger by default)
+	OnInvalid func(key string, err error) // called for skipped registrations with invalid values (logged by Logger by default)
+}
+
+// EtcdDiscovery is service discovery driver that reads service
+// registrations from etcd v3 json gateway. Value of every key
+// under the prefix is service.Record json or plain address,
+// keys with invalid values are skipped
+type EtcdDiscovery struct {
+	opts EtcdDiscoveryOpts
+
+	mu        sync.Mutex
+	revisions map[string]int64 // service name -> revision of the last discovery
+}
+
+// etcdKeyValue is key-value pair of etcd json gateway,
+// keys and values are base64 encoded
+type etcdKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// etcdInt64 is int64 of etcd json gateway,
+// which encodes it as string
+type etcdInt64 int64
+
+// UnmarshalJSON decode int64 given as string or number
+func (i *etcdInt64) UnmarshalJSON(data []byte) error {
+	v, err := strconv.ParseInt(strings.Trim(string(data), `"`), 10, 64)
+	if err != nil {
+		return fmt.Errorf("decode int64: %w", err)
+	}
+
+	*i = etcdInt64(v)
+	return nil
+}
+
+// etcdRangeResponse is response of etcd range request
+type etcdRangeResponse struct {
+	Header struct {
+		Revision etcdInt64 `json:"revision"`
+	} `json:"header"`
+	Kvs  []etcdKeyValue `json:"kvs"`
+	More bool           `json:"more"`
+}
+
+// etcdWatchResponse is message of etcd watch stream
+type etcdWatchResponse struct {
+	Result struct {
+		Canceled     bool           `json:"canceled"`
+		CancelReason string         `json:"cancel_reason"`
+		Events       []etcdKeyValue `json:"events"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewEtcdDiscovery create new EtcdDiscovery
+// driver with given configuration
+func NewEtcdDiscovery(opts *EtcdDiscoveryOpts) *EtcdDiscovery {
+	d := &EtcdDiscovery{
+		opts:      *opts,
+		revisions: make(map[string]int64),
+	}
+
+	if len(d.opts.Endpoints) == 0 {
+		d.opts.Endpoints = []string{defaultEtcdEndpoint}
+	}
+	if d.opts.Prefix == "" {
+		d.opts.Prefix = defaultEtcdPrefix
+	}
+	if d.opts.Timeout <= 0 {
+		d.opts.Timeout = defaultEtcdTimeout
+	}
+	if d.opts.Client == nil {
+		d.opts.Client = http.DefaultClient
+	}
+	if d.opts.Logger == nil {
+		d.opts.Logger = scriptoriumLogger{}
+	}
+	if d.opts.OnInvalid == nil {
+		d.opts.OnInvalid = func(key string, err error) {
+			d.opts.Logger.Warn("skip invalid etcd registration", "key", key, "error", err)
+		}
+	}
+
+	return d
+}
+
+// Discover read services registered with given
+// name and return them sorted by address
+func (d *EtcdDiscovery) Discover(name string) ([]service.IService, error) {
+	return d.DiscoverContext(context.Background(), name)
+}
+
+// DiscoverContext read services registered with given name and
+// return them sorted by address, the request is interrupted once
+// given context is done or configured timeout elapses
+func (d *EtcdDiscovery) DiscoverContext(ctx context.Context, name string) ([]service.IService, error) {
+	var services []service.IService
+
+	err := d.DiscoverPages(ctx, name, DefaultPageSize, func(page []service.IService) error {
+		services = append(services, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Address() < services[j].Address()
+	})
+
+	return services, nil
+}
+
+// DiscoverPages call given function for every page of services
+// registered with given name ordered by key. All pages are read
+// at revision of the first one, so they are consistent snapshot,
+// and the revision is remembered, so the watch could be started
+// right after it. Every request is interrupted once given
+// context is done or configured timeout elapses
+func (d *EtcdDiscovery) DiscoverPages(ctx context.Context, name string, pageSize int, fn PageFunc) error {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+
+	request := d.rangeRequest(name)
+	request["limit"] = pageSize
+	request["sort_order"] = "ASCEND"
+	request["sort_target"] = "KEY"
+
+	var revision int64
+	for {
+		resp, err := d.rangePage(ctx, name, request)
+		if err != nil {
+			return err
+		}
+
+		// the following pages are read at revision of
+		// the first one and start after its last key
+		if revision == 0 {
+			revision = int64(resp.Header.Revision)
+			request["revision"] = revision
+		}
+
+		services := make([]service.IService, 0, len(resp.Kvs))
+		for _, kv := range resp.Kvs {
+			srv, err := d.service(kv)
+			if err != nil {
+				key, _ := base64.StdEncoding.DecodeString(kv.Key)
+				d.opts.OnInvalid(string(key), err)
+				continue
+			}
+
+			services = append(services, srv)
+		}
+
+		if len(services) > 0 {
+			if err := fn(services); err != nil {
+				return err
+			}
+		}
+
+		if !resp.More || len(resp.Kvs) == 0 {
+			break
+		}
+
+		last, err := base64.StdEncoding.DecodeString(resp.Kvs[len(resp.Kvs)-1].Key)
+		if err != nil {
+			return fmt.Errorf("decode key of %s: %w", name, err)
+		}
+		request["key"] = base64.StdEncoding.EncodeToString(append(last, 0))
+	}
+
+	if revision > 0 {
+		d.mu.Lock()
+		d.revisions[name] = revision
+		d.mu.Unlock()
+	}
+
+	return nil
+}
+
+// rangePage send given range request of services
+// with given name and decode the response
+func (d *EtcdDiscovery) rangePage(ctx context.Context, name string, request map[string]interface{}) (*etcdRangeResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+
+	body, err := d.post(ctx, "/v3/kv/range", request)
+	if err != nil {
+		return nil, fmt.Errorf("range services of %s: %w", name, err)
+	}
+	defer body.Close()
+
+	resp := &etcdRangeResponse{}
+	if err := json.NewDecoder(body).Decode(resp); err != nil {
+		return nil, fmt.Errorf("decode services of %s: %w", name, err)
+	}
+
+	return resp, nil
+}
+
+// Watch watch registrations of services with given name and call
+// given function every time they change. The watch starts right
+// after revision of the last discovery, so changes made between
+// the discovery and the watch are reported. It blocks until given
+// context is done, nil is returned in this case
+func (d *EtcdDiscovery) Watch(ctx context.Context, name string, changed func()) error {
+	request := d.rangeRequest(name)
+
+	d.mu.Lock()
+	if revision, ok := d.revisions[name]; ok {
+		request["start_revision"] = revision + 1
+	}
+	d.mu.Unlock()
+
+	body, err := d.post(ctx, "/v3/watch", map[string]interface{}{"create_request": request})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		return fmt.Errorf("watch services of %s: %w", name, err)
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, 16*1024*1024)
+
+	for scanner.Scan() {
+		var resp etcdWatchResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			return fmt.Errorf("decode watch response of %s: %w", name, err)
+		}
+
+		if resp.Error != nil {
+			return fmt.Errorf("watch services of %s: %s", name, resp.Error.Message)
+		}
+		if resp.Result.Canceled {
+			return fmt.Errorf("watch services of %s is canceled: %s", name, resp.Result.CancelReason)
+		}
+
+		if len(resp.Result.Events) != 0 {
+			changed()
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("watch services of %s: %w", name, err)
+	}
+
+	return fmt.Errorf("watch services of %s: %w", name, io.ErrUnexpectedEOF)
+}
+
+// rangeRequest return range request of keys
+// of services registered with given name
+func (d *EtcdDiscovery) rangeRequest(name string) map[string]interface{} {
+	key := strings.TrimSuffix(d.opts.Prefix, "/") + "/" + name + "/"
+
+	return map[string]interface{}{
+		"key":       base64.StdEncoding.EncodeToString([]byte(key)),
+		"range_end": base64.StdEncoding.EncodeToString(prefixEnd([]byte(key))),
+	}
+}
+
+// post send json request to given path of configured endpoints
+// in order and return body of the first successful response
+func (d *EtcdDiscovery) post(ctx context.Context, path string, request interface{}) (io.ReadCloser, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+
+	var errs []error
+	for _, endpoint := range d.opts.Endpoints {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+path, bytes.NewReader(payload))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := d.opts.Client.Do(req)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			errs = append(errs, fmt.Errorf("endpoint %s responded with status %d", endpoint, resp.StatusCode))
+			continue
+		}
+
+		return resp.Body, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// service create service of given key-value pair, value is
+// service.Record json or plain address of the service
+func (d *EtcdDiscovery) service(kv etcdKeyValue) (service.IService, error) {
+	value, err := base64.StdEncoding.DecodeString(kv.Value)
+	if err != nil {
+		return nil, fmt.Errorf("decode value: %w", err)
+	}
+
+	record := service.Record{Address: strings.TrimSpace(string(value))}
+	if bytes.HasPrefix(bytes.TrimSpace(value), []byte("{")) {
+		if err := json.Unmarshal(value, &record); err != nil {
+			return nil, fmt.Errorf("decode record: %w", err)
+		}
+	}
+
+	if record.Address == "" {
+		return nil, fmt.Errorf("service address is empty")
+	}
+
+	record.Tags = append(record.Tags, d.opts.Tags...)
+
+	return record.Service(), nil
+}
+
+// prefixEnd return the end of range of keys with given
+// prefix, that is the prefix with the last byte incremented
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+
+	// every key is in the range
+	return []byte{0}
+}
@@ -0,0 +1,275 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// fakeEtcd is etcd json gateway serving
+// range and watch requests from memory
+type fakeEtcd struct {
+	mu       sync.Mutex
+	kvs      map[string]string
+	revision int64
+	changes  chan string
+
+	ranges        int   // number of served range requests
+	startRevision int64 // start revision of the last watch
+}
+
+// newFakeEtcd start fake etcd gateway
+// and return its http endpoint
+func newFakeEtcd(t *testing.T) (*fakeEtcd, string) {
+	t.Helper()
+
+	etcd := &fakeEtcd{kvs: make(map[string]string), revision: 1, changes: make(chan string, 10)}
+
+	server := httptest.NewServer(http.HandlerFunc(etcd.serveHTTP))
+	t.Cleanup(server.Close)
+
+	return etcd, server.URL
+}
+
+// put store given value and notify watchers
+func (e *fakeEtcd) put(key, value string) {
+	e.mu.Lock()
+	e.kvs[key] = value
+	e.revision++
+	e.mu.Unlock()
+
+	e.changes <- key
+}
+
+func (e *fakeEtcd) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Key           string `json:"key"`
+		RangeEnd      string `json:"range_end"`
+		Limit         int    `json:"limit"`
+		CreateRequest *struct {
+			Key           string `json:"key"`
+			RangeEnd      string `json:"range_end"`
+			StartRevision int64  `json:"start_revision"`
+		} `json:"create_request"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v3/kv/range":
+		from, _ := base64.StdEncoding.DecodeString(req.Key)
+		to, _ := base64.StdEncoding.DecodeString(req.RangeEnd)
+
+		e.mu.Lock()
+		e.ranges++
+		revision := e.revision
+
+		var keys []string
+		for key := range e.kvs {
+			if bytes.Compare([]byte(key), from) >= 0 && bytes.Compare([]byte(key), to) < 0 {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		more := req.Limit > 0 && len(keys) > req.Limit
+		if more {
+			keys = keys[:req.Limit]
+		}
+
+		var kvs []etcdKeyValue
+		for _, key := range keys {
+			kvs = append(kvs, etcdKeyValue{
+				Key:   base64.StdEncoding.EncodeToString([]byte(key)),
+				Value: base64.StdEncoding.EncodeToString([]byte(e.kvs[key])),
+			})
+		}
+		e.mu.Unlock()
+
+		// int64 fields are encoded as strings by the gateway
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"header": map[string]string{"revision": strconv.FormatInt(revision, 10)},
+			"kvs":    kvs,
+			"more":   more,
+		})
+	case "/v3/watch":
+		e.mu.Lock()
+		e.startRevision = req.CreateRequest.StartRevision
+		e.mu.Unlock()
+
+		_, _ = w.Write([]byte(`{"result":{"created":true}}` + "\n"))
+		w.(http.Flusher).Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case key := <-e.changes:
+				_, _ = w.Write([]byte(`{"result":{"events":[{"kv":{"key":"` + base64.StdEncoding.EncodeToString([]byte(key)) + `"}}]}}` + "\n"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestEtcdDiscovery(t *testing.T) {
+	etcd, endpoint := newFakeEtcd(t)
+	etcd.kvs["/provers/prover/a"] = `{"address":"http://10.0.0.2:8080","node_name":"prover-a","tags":["gpu"],"metadata":{"zone":"eu"}}`
+	etcd.kvs["/provers/prover/b"] = "http://10.0.0.1:8080"
+	etcd.kvs["/provers/prover-other/c"] = "http://10.0.0.3:8080"
+
+	d := NewEtcdDiscovery(&EtcdDiscoveryOpts{
+		Endpoints: []string{"http://127.0.0.1:1", endpoint},
+		Prefix:    "/provers/",
+		Tags:      []string{"etcd"},
+	})
+
+	services, err := d.Discover("prover")
+	if err != nil {
+		t.Fatalf("unexpected discover error: %s", err)
+	}
+
+	if len(services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(services))
+	}
+	if services[0].Address() != "http://10.0.0.1:8080" || services[1].Address() != "http://10.0.0.2:8080" {
+		t.Errorf("unexpected addresses %s, %s", services[0].Address(), services[1].Address())
+	}
+
+	record := services[1]
+	if _, ok := record.Tags()["gpu"]; !ok || record.NodeName() != "prover-a" {
+		t.Errorf("service should be decoded from the record")
+	}
+	if _, ok := services[0].Tags()["etcd"]; !ok {
+		t.Errorf("configured tags should be added to discovered services")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	changed := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Watch(ctx, "prover", func() {
+			changed <- struct{}{}
+		})
+	}()
+
+	etcd.put("/provers/prover/d", "http://10.0.0.4:8080")
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("watch should report changed registrations")
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("canceled watch should return no error, got %s", err)
+	}
+
+	// the watch starts right after revision of the discovery
+	if etcd.startRevision != 2 {
+		t.Errorf("expected watch from revision 2, got %d", etcd.startRevision)
+	}
+}
+
+func TestEtcdDiscoverPages(t *testing.T) {
+	etcd, endpoint := newFakeEtcd(t)
+	etcd.kvs["/provers/prover/a"] = "http://10.0.0.1:8080"
+	etcd.kvs["/provers/prover/b"] = `{"address":`
+	etcd.kvs["/provers/prover/c"] = "http://10.0.0.3:8080"
+	etcd.kvs["/provers/prover/d"] = "http://10.0.0.4:8080"
+
+	var invalid []string
+	d := NewEtcdDiscovery(&EtcdDiscoveryOpts{
+		Endpoints: []string{endpoint},
+		Prefix:    "/provers",
+		OnInvalid: func(key string, _ error) { invalid = append(invalid, key) },
+	})
+
+	var pages [][]string
+	err := d.DiscoverPages(context.Background(), "prover", 2, func(services []service.IService) error {
+		var page []string
+		for _, srv := range services {
+			page = append(page, srv.Address())
+		}
+		pages = append(pages, page)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected discover pages error: %s", err)
+	}
+
+	// invalid value is skipped instead of failing the discovery
+	expected := [][]string{{"http://10.0.0.1:8080"}, {"http://10.0.0.3:8080", "http://10.0.0.4:8080"}}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Errorf("expected pages %v, got %v", expected, pages)
+	}
+	if etcd.ranges != 2 {
+		t.Errorf("expected 2 range requests, got %d", etcd.ranges)
+	}
+	if !reflect.DeepEqual(invalid, []string{"/provers/prover/b"}) {
+		t.Errorf("invalid registration should be reported, got %v", invalid)
+	}
+
+	services, err := d.Discover("prover")
+	if err != nil || len(services) != 3 {
+		t.Errorf("discovery should skip invalid registration, got %d services, %v", len(services), err)
+	}
+}
+
+// recordingLogger is logger which remember logged messages
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Warn(msg string, args ...any) {
+	l.messages = append(l.messages, fmt.Sprint(append([]any{msg}, args...)...))
+}
+
+func TestEtcdDiscoverPagesLogger(t *testing.T) {
+	etcd, endpoint := newFakeEtcd(t)
+	etcd.kvs["/provers/prover/a"] = "http://10.0.0.1:8080"
+	etcd.kvs["/provers/prover/b"] = `{"address":`
+
+	logger := &recordingLogger{}
+	d := NewEtcdDiscovery(&EtcdDiscoveryOpts{
+		Endpoints: []string{endpoint},
+		Prefix:    "/provers",
+		Logger:    logger,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// request is not sent once the caller context is done
+	if err := d.DiscoverPages(ctx, "prover", 2, func([]service.IService) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled discovery, got %v", err)
+	}
+
+	services, err := d.DiscoverContext(context.Background(), "prover")
+	if err != nil || len(services) != 1 {
+		t.Fatalf("discovery should skip invalid registration, got %d services, %v", len(services), err)
+	}
+	if len(logger.messages) != 1 || !strings.Contains(logger.messages[0], "/provers/prover/b") {
+		t.Errorf("invalid registration should be logged by given logger, got %v", logger.messages)
+	}
+}
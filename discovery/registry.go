@@ -0,0 +1,255 @@
+package discovery
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Built-in drivers names
+const (
+	DriverConsul = "consul"
+	DriverDNS    = "dns"
+	DriverEtcd   = "etcd"
+	DriverReplay = "replay"
+	DriverStatic = "static"
+)
+
+// DriverConfig is generic configuration of discovery driver
+// built from options given to NewDiscovery. Drivers take the
+// fields they support, driver specific settings are passed
+// as params, e.g. "record" of dns driver or "token" of consul
+type DriverConfig struct {
+	Addresses []string          // driver endpoints, e.g. consul agent or etcd endpoints
+	Path      string            // file path of static and replay drivers
+	Name      string            // resolved name of dns driver
+	Prefix    string            // key prefix of etcd driver
+	Tags      []string          // tags added to discovered services
+	Timeout   time.Duration     // discovery request timeout
+	Params    map[string]string // driver specific settings
+}
+
+// Option configure discovery driver created by NewDiscovery
+type Option func(cfg *DriverConfig)
+
+// WithAddresses set driver endpoints
+func WithAddresses(addrs ...string) Option {
+	return func(cfg *DriverConfig) {
+		cfg.Addresses = append(cfg.Addresses, addrs...)
+	}
+}
+
+// WithPath set file path of static and replay drivers
+func WithPath(path string) Option {
+	return func(cfg *DriverConfig) {
+		cfg.Path = path
+	}
+}
+
+// WithName set resolved name of dns driver
+func WithName(name string) Option {
+	return func(cfg *DriverConfig) {
+		cfg.Name = name
+	}
+}
+
+// WithPrefix set key prefix of etcd driver
+func WithPrefix(prefix string) Option {
+	return func(cfg *DriverConfig) {
+		cfg.Prefix = prefix
+	}
+}
+
+// WithTags set tags added to discovered services
+func WithTags(tags ...string) Option {
+	return func(cfg *DriverConfig) {
+		cfg.Tags = append(cfg.Tags, tags...)
+	}
+}
+
+// WithTimeout set discovery request timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *DriverConfig) {
+		cfg.Timeout = timeout
+	}
+}
+
+// WithParam set driver specific setting
+func WithParam(key, value string) Option {
+	return func(cfg *DriverConfig) {
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string)
+		}
+		cfg.Params[key] = value
+	}
+}
+
+// Factory create discovery driver with given configuration
+type Factory func(cfg *DriverConfig) (IServiceDiscovery, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Factory{
+		DriverConsul: newConsulDriver,
+		DriverDNS:    newDNSDriver,
+		DriverEtcd:   newEtcdDriver,
+		DriverReplay: newReplayDriver,
+		DriverStatic: newStaticDriver,
+	}
+)
+
+// RegisterDriver register factory of discovery driver with
+// given name, driver registered with the same name is replaced
+func RegisterDriver(name string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	drivers[name] = factory
+}
+
+// Drivers return sorted names of registered drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewDiscovery create discovery driver registered
+// with given name configured by given options
+func NewDiscovery(driver string, opts ...Option) (IServiceDiscovery, error) {
+	driversMu.RLock()
+	factory, ok := drivers[driver]
+	driversMu.RUnlock()
+
+	if !ok {
+		return nil, ErrUnsupportedDriver{Driver: driver}
+	}
+
+	cfg := &DriverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return factory(cfg)
+}
+
+// newConsulDriver create ConsulDiscovery, supported params
+// are "token", "datacenter", "tag", "passing" and "scheme"
+func newConsulDriver(cfg *DriverConfig) (IServiceDiscovery, error) {
+	passing, err := boolParam(DriverConsul, cfg.Params, "passing")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &ConsulDiscoveryOpts{
+		Token:      cfg.Params["token"],
+		Datacenter: cfg.Params["datacenter"],
+		Tag:        cfg.Params["tag"],
+		Passing:    passing,
+		Scheme:     cfg.Params["scheme"],
+		Timeout:    cfg.Timeout,
+	}
+	if len(cfg.Addresses) != 0 {
+		opts.Address = cfg.Addresses[0]
+	}
+
+	return NewConsulDiscovery(opts), nil
+}
+
+// newDNSDriver create DNSDiscovery, supported params are
+// "record" ("SRV" or "A"), "service", "proto", "port" and "scheme"
+func newDNSDriver(cfg *DriverConfig) (IServiceDiscovery, error) {
+	opts := &DNSDiscoveryOpts{
+		Name:    cfg.Name,
+		Service: cfg.Params["service"],
+		Proto:   cfg.Params["proto"],
+		Scheme:  cfg.Params["scheme"],
+		Tags:    cfg.Tags,
+		Timeout: cfg.Timeout,
+	}
+
+	switch record := cfg.Params["record"]; record {
+	case "", DNSRecordSRV.String():
+		opts.Record = DNSRecordSRV
+	case DNSRecordA.String():
+		opts.Record = DNSRecordA
+	default:
+		return nil, ErrInvalidDriverParam{Driver: DriverDNS, Param: "record", Value: record}
+	}
+
+	if port, ok := cfg.Params["port"]; ok {
+		var err error
+		if opts.Port, err = strconv.Atoi(port); err != nil {
+			return nil, ErrInvalidDriverParam{Driver: DriverDNS, Param: "port", Value: port}
+		}
+	}
+
+	return NewDNSDiscovery(opts), nil
+}
+
+// newEtcdDriver create EtcdDiscovery
+func newEtcdDriver(cfg *DriverConfig) (IServiceDiscovery, error) {
+	return NewEtcdDiscovery(&EtcdDiscoveryOpts{
+		Endpoints: cfg.Addresses,
+		Prefix:    cfg.Prefix,
+		Tags:      cfg.Tags,
+		Timeout:   cfg.Timeout,
+	}), nil
+}
+
+// newReplayDriver create ReplayDiscovery of recording on
+// configured path, supported param is "speed"
+func newReplayDriver(cfg *DriverConfig) (IServiceDiscovery, error) {
+	if cfg.Path == "" {
+		return nil, ErrInvalidDriverParam{Driver: DriverReplay, Param: "path"}
+	}
+
+	var speed float64
+	if value, ok := cfg.Params["speed"]; ok {
+		var err error
+		if speed, err = strconv.ParseFloat(value, 64); err != nil || speed < 0 {
+			return nil, ErrInvalidDriverParam{Driver: DriverReplay, Param: "speed", Value: value}
+		}
+	}
+
+	return NewReplayDiscovery(&ReplayDiscoveryOpts{
+		Path:  cfg.Path,
+		Speed: speed,
+	})
+}
+
+// newStaticDriver create StaticDiscovery, path is required
+func newStaticDriver(cfg *DriverConfig) (IServiceDiscovery, error) {
+	if cfg.Path == "" {
+		return nil, ErrInvalidDriverParam{Driver: DriverStatic, Param: "path"}
+	}
+
+	return NewStaticDiscovery(&StaticDiscoveryOpts{
+		Path: cfg.Path,
+		Tags: cfg.Tags,
+	}), nil
+}
+
+// boolParam return bool param with given key of
+// given driver, missing param is false
+func boolParam(driver string, params map[string]string, key string) (bool, error) {
+	value, ok := params[key]
+	if !ok {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, ErrInvalidDriverParam{Driver: driver, Param: key, Value: value}
+	}
+
+	return b, nil
+}
@@ -0,0 +1,88 @@
+package discovery
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+type customDiscovery struct {
+	cfg *DriverConfig
+}
+
+func (d *customDiscovery) Discover(string) ([]service.IService, error) {
+	return []service.IService{service.NewService(d.cfg.Addresses[0], "", nil, 0)}, nil
+}
+
+func TestNewDiscovery(t *testing.T) {
+	RegisterDriver("custom", func(cfg *DriverConfig) (IServiceDiscovery, error) {
+		return &customDiscovery{cfg: cfg}, nil
+	})
+
+	d, err := NewDiscovery("custom", WithAddresses("http://10.0.0.1:8080"), WithParam("key", "value"))
+	if err != nil {
+		t.Fatalf("unexpected driver error: %s", err)
+	}
+	if custom := d.(*customDiscovery); custom.cfg.Params["key"] != "value" {
+		t.Errorf("options should configure the driver, got %+v", custom.cfg)
+	}
+
+	path := filepath.Join(t.TempDir(), "provers.yaml")
+	writeFile(t, path, "prover: [http://10.0.0.1:8080]\n")
+
+	tests := []struct {
+		driver   string
+		opts     []Option
+		expected interface{}
+		err      error
+	}{
+		{DriverStatic, []Option{WithPath(path)}, &StaticDiscovery{}, nil},
+		{DriverStatic, nil, nil, ErrInvalidDriverParam{Driver: DriverStatic, Param: "path"}},
+		{DriverDNS, []Option{WithParam("record", "A"), WithParam("port", "8080")}, &DNSDiscovery{}, nil},
+		{DriverDNS, []Option{WithParam("record", "MX")}, nil, ErrInvalidDriverParam{Driver: DriverDNS, Param: "record", Value: "MX"}},
+		{DriverConsul, []Option{WithParam("passing", "true")}, &ConsulDiscovery{}, nil},
+		{DriverConsul, []Option{WithParam("passing", "yes")}, nil, ErrInvalidDriverParam{Driver: DriverConsul, Param: "passing", Value: "yes"}},
+		{DriverEtcd, []Option{WithAddresses("http://10.0.0.1:2379")}, &EtcdDiscovery{}, nil},
+		{"zookeeper", nil, nil, ErrUnsupportedDriver{Driver: "zookeeper"}},
+	}
+
+	for _, tt := range tests {
+		d, err := NewDiscovery(tt.driver, tt.opts...)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: expected error %v, got %v", tt.driver, tt.err, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected driver error: %s", tt.driver, err)
+			continue
+		}
+
+		switch tt.expected.(type) {
+		case *StaticDiscovery:
+			if services, err := d.Discover("prover"); err != nil || len(services) != 1 {
+				t.Errorf("static driver should read configured file, got %v, %v", services, err)
+			}
+		case *DNSDiscovery:
+			if dns, ok := d.(*DNSDiscovery); !ok || dns.opts.Record != DNSRecordA || dns.opts.Port != 8080 {
+				t.Errorf("unexpected dns driver %+v", d)
+			}
+		case *ConsulDiscovery:
+			if consul, ok := d.(*ConsulDiscovery); !ok || !consul.opts.Passing {
+				t.Errorf("unexpected consul driver %+v", d)
+			}
+		case *EtcdDiscovery:
+			if etcd, ok := d.(*EtcdDiscovery); !ok || etcd.opts.Endpoints[0] != "http://10.0.0.1:2379" {
+				t.Errorf("unexpected etcd driver %+v", d)
+			}
+		}
+	}
+
+	if drivers := Drivers(); len(drivers) != 6 || drivers[0] != DriverConsul {
+		t.Errorf("unexpected drivers %v", drivers)
+	}
+}
@@ -0,0 +1,261 @@
+package discovery
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// DiscoveryResponse is recorded discovery response
+// written as json line by recording driver
+type DiscoveryResponse struct {
+	Time     time.Time        `json:"time"`
+	Name     string           `json:"name"`
+	Services []service.Record `json:"services"`
+	Error    string           `json:"error,omitempty"`
+}
+
+// RecordingDiscovery is driver wrapper that writes every
+// response of wrapped driver with its timestamp, so
+// registry-induced incidents could be replayed offline
+type RecordingDiscovery struct {
+	driver IServiceDiscovery
+
+	mu  sync.Mutex
+	out *json.Encoder
+}
+
+// watchingRecordingDiscovery is RecordingDiscovery
+// of driver that supports watching
+type watchingRecordingDiscovery struct {
+	*RecordingDiscovery
+	watcher IWatchingDiscovery
+}
+
+// NewRecordingDiscovery create driver that records responses
+// of given driver to given writer as json lines, watching
+// support of the driver is kept
+func NewRecordingDiscovery(driver IServiceDiscovery, out io.Writer) IServiceDiscovery {
+	d := &RecordingDiscovery{
+		driver: driver,
+		out:    json.NewEncoder(out),
+	}
+
+	if watcher, ok := driver.(IWatchingDiscovery); ok {
+		return &watchingRecordingDiscovery{RecordingDiscovery: d, watcher: watcher}
+	}
+
+	return d
+}
+
+// Discover return services of wrapped driver and record
+// the response, recording failures are returned as well
+func (d *RecordingDiscovery) Discover(name string) ([]service.IService, error) {
+	return d.DiscoverContext(context.Background(), name)
+}
+
+// DiscoverContext return services of wrapped driver discovered
+// until given context is done and record the response
+func (d *RecordingDiscovery) DiscoverContext(ctx context.Context, name string) ([]service.IService, error) {
+	services, err := DiscoverContext(ctx, d.driver, name)
+
+	resp := DiscoveryResponse{
+		Time:     time.Now().UTC(),
+		Name:     name,
+		Services: make([]service.Record, 0, len(services)),
+	}
+	for _, srv := range services {
+		if srv != nil {
+			resp.Services = append(resp.Services, service.NewRecord(srv))
+		}
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	recordErr := d.out.Encode(resp)
+	d.mu.Unlock()
+
+	if recordErr != nil {
+		return services, errors.Join(err, fmt.Errorf("record discovery response: %w", recordErr))
+	}
+
+	return services, err
+}
+
+// Watch watch wrapped driver
+func (d *watchingRecordingDiscovery) Watch(ctx context.Context, name string, changed func()) error {
+	return d.watcher.Watch(ctx, name, changed)
+}
+
+// ReplayDiscoveryOpts is options that needs
+// to configure ReplayDiscovery instance
+type ReplayDiscoveryOpts struct {
+	Path  string  // path of recorded json lines
+	Speed float64 // replay speed, 1 for original and 10 for 10x faster (0 to return the next response on every call)
+}
+
+// ReplayDiscovery is service discovery driver that feeds back
+// recorded responses. With positive speed the recording is
+// replayed on its original timeline scaled by the speed from
+// the first call, the latest response due by then is returned.
+// With zero speed every call returns the next response
+type ReplayDiscovery struct {
+	speed float64
+
+	mu        sync.Mutex
+	responses map[string][]DiscoveryResponse
+	next      map[string]int
+	origin    time.Time // time of the first recorded response
+	started   time.Time // time of the first call
+}
+
+// NewReplayDiscovery load recorded responses and
+// create new ReplayDiscovery driver
+func NewReplayDiscovery(opts *ReplayDiscoveryOpts) (*ReplayDiscovery, error) {
+	f, err := os.Open(opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("open discovery recording: %w", err)
+	}
+	defer f.Close()
+
+	d := &ReplayDiscovery{
+		speed:     opts.Speed,
+		responses: make(map[string][]DiscoveryResponse),
+		next:      make(map[string]int),
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		var resp DiscoveryResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			return nil, fmt.Errorf("decode discovery recording line %d: %w", line, err)
+		}
+
+		if d.origin.IsZero() || resp.Time.Before(d.origin) {
+			d.origin = resp.Time
+		}
+		d.responses[resp.Name] = append(d.responses[resp.Name], resp)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read discovery recording: %w", err)
+	}
+
+	return d, nil
+}
+
+// Discover return recorded services of given name due at the
+// current replay time, recorded errors are returned as well
+func (d *ReplayDiscovery) Discover(name string) ([]service.IService, error) {
+	resp, ok := d.response(name)
+	if !ok {
+		return nil, nil
+	}
+
+	services := make([]service.IService, 0, len(resp.Services))
+	for _, record := range resp.Services {
+		services = append(services, record.Service())
+	}
+
+	if resp.Error != "" {
+		return services, errors.New(resp.Error)
+	}
+
+	return services, nil
+}
+
+// Watch call given function at replay time of every recorded
+// response of given name until the recording ends or given
+// context is done. Nothing is watched with zero speed
+func (d *ReplayDiscovery) Watch(ctx context.Context, name string, changed func()) error {
+	if d.speed <= 0 {
+		<-ctx.Done()
+		return nil
+	}
+
+	d.mu.Lock()
+	d.start()
+	responses := d.responses[name]
+	d.mu.Unlock()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	for _, resp := range responses {
+		timer.Reset(time.Until(d.replayTime(resp.Time)))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+			changed()
+		}
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+// response return recorded response of given name due at the
+// current replay time or the next one with zero speed
+func (d *ReplayDiscovery) response(name string) (DiscoveryResponse, bool) {
+	defer d.mu.Unlock()
+	d.mu.Lock()
+
+	responses := d.responses[name]
+	if len(responses) == 0 {
+		return DiscoveryResponse{}, false
+	}
+
+	if d.speed <= 0 {
+		i := min(d.next[name], len(responses)-1)
+		d.next[name] = i + 1
+
+		return responses[i], true
+	}
+
+	d.start()
+
+	now := time.Now()
+
+	due := -1
+	for i, resp := range responses {
+		if d.replayTime(resp.Time).After(now) {
+			break
+		}
+		due = i
+	}
+
+	// discovery before the first recorded
+	// response sees the registry empty
+	if due < 0 {
+		return DiscoveryResponse{}, false
+	}
+
+	return responses[due], true
+}
+
+// start begin replay if it is not started yet.
+// Should be called under the driver lock
+func (d *ReplayDiscovery) start() {
+	if d.started.IsZero() {
+		d.started = time.Now()
+	}
+}
+
+// replayTime return time given recorded time is replayed at
+func (d *ReplayDiscovery) replayTime(recorded time.Time) time.Time {
+	return d.started.Add(time.Duration(float64(recorded.Sub(d.origin)) / d.speed))
+}
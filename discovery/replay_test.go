@@ -0,0 +1,125 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+type sequenceDiscovery struct {
+	rounds [][]string
+	calls  int
+}
+
+func (d *sequenceDiscovery) Discover(string) ([]service.IService, error) {
+	if d.calls >= len(d.rounds) {
+		return nil, errors.New("registry is down")
+	}
+
+	var services []service.IService
+	for _, addr := range d.rounds[d.calls] {
+		services = append(services, service.NewService(addr, "", nil, 0))
+	}
+	d.calls++
+
+	return services, nil
+}
+
+func TestRecordAndReplayDiscovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "discovery.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected create error: %s", err)
+	}
+
+	recording := NewRecordingDiscovery(&sequenceDiscovery{rounds: [][]string{
+		{"http://10.0.0.1:8080"},
+		{"http://10.0.0.1:8080", "http://10.0.0.2:8080"},
+	}}, f)
+
+	for i := 0; i < 3; i++ {
+		_, _ = recording.Discover("prover")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("unexpected close error: %s", err)
+	}
+
+	replay, err := NewDiscovery(DriverReplay, WithPath(path))
+	if err != nil {
+		t.Fatalf("unexpected replay error: %s", err)
+	}
+
+	// every call returns the next response with zero speed
+	for i, expected := range []int{1, 2, 0} {
+		services, err := replay.Discover("prover")
+		if len(services) != expected {
+			t.Errorf("round %d: expected %d services, got %d", i, expected, len(services))
+		}
+		if (err != nil) != (i == 2) {
+			t.Errorf("round %d: unexpected error %v", i, err)
+		}
+	}
+}
+
+func TestReplayDiscoverySpeed(t *testing.T) {
+	origin := time.Now().Add(-time.Hour)
+
+	path := filepath.Join(t.TempDir(), "discovery.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("unexpected create error: %s", err)
+	}
+
+	out := json.NewEncoder(f)
+	for i := 0; i < 3; i++ {
+		resp := DiscoveryResponse{Time: origin.Add(time.Duration(i) * time.Second), Name: "prover"}
+		for j := 0; j <= i; j++ {
+			resp.Services = append(resp.Services, service.Record{Address: fmt.Sprintf("http://10.0.0.%d:8080", j+1)})
+		}
+		if err := out.Encode(resp); err != nil {
+			t.Fatalf("unexpected encode error: %s", err)
+		}
+	}
+	f.Close()
+
+	// one recorded second is replayed in 100ms
+	replay, err := NewReplayDiscovery(&ReplayDiscoveryOpts{Path: path, Speed: 10})
+	if err != nil {
+		t.Fatalf("unexpected replay error: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := make(chan time.Time, 3)
+	go func() {
+		_ = replay.Watch(ctx, "prover", func() {
+			changed <- time.Now()
+		})
+	}()
+
+	var times []time.Time
+	for i := 0; i < 3; i++ {
+		select {
+		case at := <-changed:
+			times = append(times, at)
+
+			if services, _ := replay.Discover("prover"); len(services) != i+1 {
+				t.Errorf("response %d: expected %d services, got %d", i, i+1, len(services))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("watch should report every recorded response")
+		}
+	}
+
+	if elapsed := times[2].Sub(times[0]); elapsed < 150*time.Millisecond || elapsed > time.Second {
+		t.Errorf("recording should be replayed 10x faster, took %s", elapsed)
+	}
+}
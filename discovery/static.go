@@ -0,0 +1,222 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// StaticDiscoveryOpts is options that needs
+// to configure StaticDiscovery instance
+type StaticDiscoveryOpts struct {
+	Path string   // path of yaml or json file with services by name
+	Tags []string // tags added to discovered services
+}
+
+// StaticEntry is service entry of discovery file, it
+// could be written as plain address string as well
+type StaticEntry struct {
+	Address  string            `yaml:"address"`
+	NodeName string            `yaml:"node_name"`
+	Tags     []string          `yaml:"tags"`
+	Metadata map[string]string `yaml:"metadata"`
+}
+
+// UnmarshalYAML decode entry from
+// mapping or plain address string
+func (e *StaticEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&e.Address)
+	}
+
+	type entry StaticEntry
+	return node.Decode((*entry)(e))
+}
+
+// StaticDiscovery is file-based service discovery driver for fixed fleets,
+// e.g. bare-metal provers or local development. Services are
+// read from yaml or json file that maps service names to
+// entries, e.g.
+//
+//	prover:
+//	  - http://10.0.0.1:8080
+//	  - address: http://10.0.0.2:8080
+//	    node_name: prover-2
+//	    tags: [gpu]
+//	    metadata: {zone: eu-1}
+//
+// The file is re-read on change or SIGHUP while it's watched,
+// invalid file is skipped and the last valid one is kept
+type StaticDiscovery struct {
+	opts StaticDiscoveryOpts
+
+	mu      sync.RWMutex
+	entries map[string][]StaticEntry
+	loaded  bool
+}
+
+// NewStaticDiscovery create new StaticDiscovery
+// driver with given configuration
+func NewStaticDiscovery(opts *StaticDiscoveryOpts) *StaticDiscovery {
+	return &StaticDiscovery{opts: *opts}
+}
+
+// Discover return services registered with given
+// name in the file, the file is read on first use
+func (d *StaticDiscovery) Discover(name string) ([]service.IService, error) {
+	d.mu.RLock()
+	loaded := d.loaded
+	d.mu.RUnlock()
+
+	if !loaded {
+		if _, err := d.Reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	d.mu.RLock()
+	entries := d.entries[name]
+	d.mu.RUnlock()
+
+	services := make([]service.IService, 0, len(entries))
+	for _, entry := range entries {
+		services = append(services, d.service(entry))
+	}
+
+	return services, nil
+}
+
+// Reload re-read the file and return names of services
+// which entries have changed, the last valid file is
+// kept if the file could not be read
+func (d *StaticDiscovery) Reload() ([]string, error) {
+	data, err := os.ReadFile(d.opts.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read discovery file: %w", err)
+	}
+
+	entries := make(map[string][]StaticEntry)
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decode discovery file %s: %w", d.opts.Path, err)
+	}
+
+	for name, byName := range entries {
+		for i, entry := range byName {
+			if entry.Address == "" {
+				return nil, fmt.Errorf("decode discovery file %s: address of %s entry %d is empty", d.opts.Path, name, i)
+			}
+		}
+	}
+
+	defer d.mu.Unlock()
+	d.mu.Lock()
+
+	var changed []string
+	for name := range entries {
+		if !reflect.DeepEqual(entries[name], d.entries[name]) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range d.entries {
+		if _, ok := entries[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+
+	d.entries = entries
+	d.loaded = true
+
+	return changed, nil
+}
+
+// Watch re-read the file on its change or SIGHUP and call
+// given function when entries of given name have changed.
+// It blocks until given context is done
+func (d *StaticDiscovery) Watch(ctx context.Context, name string, changed func()) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	return d.watch(ctx, name, changed, hup)
+}
+
+// watch re-read the file on change of its directory or signal
+// from given channel until given context is done, so atomic
+// replacements of the file are seen as well
+func (d *StaticDiscovery) watch(ctx context.Context, name string, changed func(), signals <-chan os.Signal) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watch discovery file: %w", err)
+	}
+	defer watcher.Close()
+
+	path, err := filepath.Abs(d.opts.Path)
+	if err != nil {
+		return fmt.Errorf("watch discovery file: %w", err)
+	}
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("watch discovery file: %w", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return fmt.Errorf("watch discovery file: watcher is closed")
+			}
+
+			return fmt.Errorf("watch discovery file: %w", err)
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return fmt.Errorf("watch discovery file: watcher is closed")
+			}
+
+			// mounted config maps replace the file by swapping
+			// symlink of the directory, so every change of the
+			// directory is checked
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+		case <-signals:
+		}
+
+		names, err := d.Reload()
+		if err != nil {
+			// file could be partially written,
+			// the last valid one is kept
+			continue
+		}
+
+		for _, n := range names {
+			if n == name {
+				changed()
+				break
+			}
+		}
+	}
+}
+
+// service create service of given entry
+func (d *StaticDiscovery) service(entry StaticEntry) service.IService {
+	record := service.Record{
+		Address:  entry.Address,
+		NodeName: entry.NodeName,
+		Tags:     append(append([]string(nil), entry.Tags...), d.opts.Tags...),
+		Metadata: entry.Metadata,
+	}
+
+	return record.Service()
+}
@@ -0,0 +1,122 @@
+package discovery
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStaticDiscovery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provers.yaml")
+	writeFile(t, path, `
+prover:
+  - http://10.0.0.1:8080
+  - address: http://10.0.0.2:8080
+    node_name: prover-2
+    tags: [gpu]
+    metadata: {zone: eu-1}
+other:
+  - http://10.0.0.3:8080
+`)
+
+	d := NewStaticDiscovery(&StaticDiscoveryOpts{Path: path, Tags: []string{"static"}})
+
+	services, err := d.Discover("prover")
+	if err != nil {
+		t.Fatalf("unexpected discover error: %s", err)
+	}
+	if len(services) != 2 || services[0].Address() != "http://10.0.0.1:8080" || services[1].NodeName() != "prover-2" {
+		t.Fatalf("unexpected services %v", services)
+	}
+	if _, ok := services[1].Tags()["gpu"]; !ok {
+		t.Errorf("entry tags should be kept")
+	}
+	if _, ok := services[0].Tags()["static"]; !ok {
+		t.Errorf("configured tags should be added to discovered services")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	changed := make(chan struct{}, 10)
+	signals := make(chan os.Signal, 1)
+	go func() {
+		_ = d.watch(ctx, "prover", func() {
+			changed <- struct{}{}
+		}, signals)
+	}()
+
+	// give the watcher time to start
+	time.Sleep(50 * time.Millisecond)
+
+	// changes of other names are not reported
+	writeFile(t, path, "prover: [http://10.0.0.1:8080, http://10.0.0.2:8080]\nother: []\n")
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("changed file should be reported")
+	}
+
+	if services, _ := d.Discover("prover"); len(services) != 2 || services[1].NodeName() != "" {
+		t.Errorf("changed file should be re-read, got %v", services)
+	}
+
+	// invalid file keeps the last valid one
+	writeFile(t, path, `{"prover": [{"node_name": "broken"}]}`)
+	time.Sleep(100 * time.Millisecond)
+	if services, _ := d.Discover("prover"); len(services) != 2 {
+		t.Errorf("the last valid file should be kept, got %v", services)
+	}
+
+	cancel()
+
+	// json file changed while it is not watched is re-read on signal
+	writeFile(t, path, `{"prover": ["http://10.0.0.5:8080"]}`)
+	drain(changed)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = d.watch(ctx, "prover", func() {
+			changed <- struct{}{}
+		}, signals)
+	}()
+	signals <- syscall.SIGHUP
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("file should be re-read on signal")
+	}
+
+	if services, _ := d.Discover("prover"); len(services) != 1 || services[0].Address() != "http://10.0.0.5:8080" {
+		t.Errorf("unexpected services %v", services)
+	}
+}
+
+// writeFile atomically replace file on given path with given data
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0o600); err != nil {
+		t.Fatalf("unexpected write error: %s", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("unexpected rename error: %s", err)
+	}
+}
+
+// drain discard buffered notifications
+func drain(changed chan struct{}) {
+	for {
+		select {
+		case <-changed:
+		default:
+			return
+		}
+	}
+}
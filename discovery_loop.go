@@ -0,0 +1,174 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/discovery"
+)
+
+const (
+	defaultAdaptiveFactor       = 2
+	defaultMinDiscoveryInterval = time.Second
+	watchRetryInterval          = 5 * time.Second
+)
+
+// AdaptiveDiscoveryOpts is options that make rediscovery interval
+// adaptive: it's decreased when discovery results change between
+// rounds and increased while they are stable, within given bounds
+type AdaptiveDiscoveryOpts struct {
+	MinInterval time.Duration // the smallest interval used during churn (1s by default)
+	MaxInterval time.Duration // the biggest interval used during stability
+	Factor      float64       // interval multiplier/divisor per round (2 by default)
+}
+
+// Validate check the interval bounds are
+// not negative and max bound is above min one
+func (o *AdaptiveDiscoveryOpts) Validate() error {
+	var errs []error
+	invalid := func(path, reason string, args ...any) {
+		errs = append(errs, ErrInvalidConfig{Path: path, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	if o.MinInterval < 0 {
+		invalid("adaptiveDiscovery.minInterval", "%s should not be negative", o.MinInterval)
+	}
+	if o.MaxInterval < 0 {
+		invalid("adaptiveDiscovery.maxInterval", "%s should not be negative", o.MaxInterval)
+	}
+	if o.MaxInterval > 0 && o.MaxInterval < o.minInterval() {
+		invalid("adaptiveDiscovery.maxInterval", "%s should not be shorter than min interval %s", o.MaxInterval, o.minInterval())
+	}
+	if o.Factor < 0 {
+		invalid("adaptiveDiscovery.factor", "%g should not be negative", o.Factor)
+	}
+
+	return errors.Join(errs...)
+}
+
+// minInterval return configured min interval
+// or the default one if it's not positive
+func (o *AdaptiveDiscoveryOpts) minInterval() time.Duration {
+	if o.MinInterval <= 0 {
+		return defaultMinDiscoveryInterval
+	}
+
+	return o.MinInterval
+}
+
+// discoveryInterval calculate rediscovery intervals
+// according to the adaptive options
+type discoveryInterval struct {
+	current time.Duration
+	opts    *AdaptiveDiscoveryOpts
+}
+
+// newDiscoveryInterval create new discoveryInterval
+// starting from given interval
+func newDiscoveryInterval(interval time.Duration, opts *AdaptiveDiscoveryOpts) *discoveryInterval {
+	d := &discoveryInterval{
+		current: interval,
+		opts:    opts,
+	}
+
+	if opts != nil {
+		d.current = d.clamp(interval)
+	}
+
+	return d
+}
+
+// disabled check if rediscovery is disabled
+// by zero interval without adaptive bounds
+func (d *discoveryInterval) disabled() bool {
+	return d.opts == nil && d.current <= 0
+}
+
+// next return interval to wait before the next
+// round based on the last round results change
+func (d *discoveryInterval) next(changed bool) time.Duration {
+	if d.opts == nil {
+		return d.current
+	}
+
+	factor := d.opts.Factor
+	if factor <= 1 {
+		factor = defaultAdaptiveFactor
+	}
+
+	if changed {
+		d.current = d.clamp(time.Duration(float64(d.current) / factor))
+	} else {
+		d.current = d.clamp(time.Duration(float64(d.current) * factor))
+	}
+
+	return d.current
+}
+
+// clamp return given interval limited by min and max intervals,
+// so the interval never decays to zero and discovery never spins
+func (d *discoveryInterval) clamp(interval time.Duration) time.Duration {
+	if minInterval := d.opts.minInterval(); interval < minInterval {
+		return minInterval
+	}
+
+	if d.opts.MaxInterval > 0 && interval > d.opts.MaxInterval {
+		return d.opts.MaxInterval
+	}
+
+	return interval
+}
+
+// errDiscoveryStopped is returned when pool
+// is closed during discovery round
+var errDiscoveryStopped = errors.New("discovery stopped")
+
+// discoveryFingerprint is order independent fingerprint
+// of discovered services IDs, calculated incrementally
+// without buffering the whole discovered set
+type discoveryFingerprint struct {
+	count int
+	sum   uint64
+	xor   uint64
+}
+
+// add given service ID to the fingerprint
+func (f *discoveryFingerprint) add(id string) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(id))
+
+	f.count++
+	f.sum += h.Sum64()
+	f.xor ^= h.Sum64()
+}
+
+// watchDiscovery run discovery round every time given driver
+// reports changed registrations, broken watch is restarted
+// until the pool is stopped
+func (p *ServicesPool) watchDiscovery(watcher discovery.IWatchingDiscovery) {
+	for {
+		err := watcher.Watch(p.ctx, p.name, func() {
+			if p.Paused() {
+				return
+			}
+
+			if _, err := p.discoverServices(p.ctx); err != nil && !errors.Is(err, errDiscoveryStopped) {
+				p.log().Warn("discovery error", "error", err)
+			}
+		})
+
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+
+		if err != nil {
+			p.log().Warn("restart discovery watch", "error", err)
+		}
+
+		Sleep(watchRetryInterval, p.stop)
+	}
+}
@@ -0,0 +1,321 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/discovery"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+type staticDiscovery struct {
+	services []service.IService
+}
+
+func (d *staticDiscovery) Discover(string) ([]service.IService, error) {
+	return d.services, nil
+}
+
+func TestDiscoveryIntervalAdaptive(t *testing.T) {
+	interval := newDiscoveryInterval(10*time.Second, &AdaptiveDiscoveryOpts{
+		MinInterval: 2 * time.Second,
+		MaxInterval: 40 * time.Second,
+	})
+
+	tests := []struct {
+		changed  bool
+		expected time.Duration
+	}{
+		{false, 20 * time.Second},
+		{false, 40 * time.Second},
+		{false, 40 * time.Second},
+		{true, 20 * time.Second},
+		{true, 10 * time.Second},
+		{true, 5 * time.Second},
+		{true, 2500 * time.Millisecond},
+		{true, 2 * time.Second},
+	}
+
+	for i, tt := range tests {
+		if got := interval.next(tt.changed); got != tt.expected {
+			t.Errorf("round %d: expected interval %s, got %s", i, tt.expected, got)
+		}
+	}
+}
+
+func TestDiscoveryIntervalFloor(t *testing.T) {
+	interval := newDiscoveryInterval(4*time.Second, &AdaptiveDiscoveryOpts{})
+
+	for i := 0; i < 10; i++ {
+		interval.next(true)
+	}
+
+	if got := interval.next(true); got != defaultMinDiscoveryInterval {
+		t.Errorf("expected interval to stop at %s during churn, got %s", defaultMinDiscoveryInterval, got)
+	}
+
+	if !newDiscoveryInterval(0, nil).disabled() || newDiscoveryInterval(0, &AdaptiveDiscoveryOpts{}).disabled() {
+		t.Errorf("only zero interval without adaptive bounds should disable rediscovery")
+	}
+}
+
+func TestAdaptiveDiscoveryOptsValidate(t *testing.T) {
+	if err := (&AdaptiveDiscoveryOpts{MaxInterval: time.Minute}).Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	invalid := []*AdaptiveDiscoveryOpts{
+		{MinInterval: -time.Second},
+		{MinInterval: time.Minute, MaxInterval: time.Second},
+		{MaxInterval: 500 * time.Millisecond},
+		{Factor: -2},
+	}
+	for i, opts := range invalid {
+		if err := opts.Validate(); !errors.As(err, &ErrInvalidConfig{}) {
+			t.Errorf("options %d: expected invalid config error, got %v", i, err)
+		}
+	}
+}
+
+func TestServicesPoolDiscoverServices(t *testing.T) {
+	discovery := &staticDiscovery{services: []service.IService{
+		newHealthyService("https://1gateway.fm"),
+		newHealthyService("https://2gateway.fm"),
+	}}
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:      "TestServicePool",
+		Discovery: discovery,
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	}).(*ServicesPool)
+
+	changed, err := pool.discoverServices(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("first discovery round should report change, err: %v", err)
+	}
+
+	if pool.Count() != 2 {
+		t.Fatalf("expected 2 discovered services, got %d", pool.Count())
+	}
+
+	if changed, _ = pool.discoverServices(context.Background()); changed {
+		t.Errorf("same discovery results should not report change")
+	}
+
+	discovery.services = append(discovery.services, newHealthyService("https://3gateway.fm"))
+	if changed, _ = pool.discoverServices(context.Background()); !changed {
+		t.Errorf("new discovered service should report change")
+	}
+}
+
+type pagedDiscovery struct {
+	staticDiscovery
+	pages []int
+}
+
+func (d *pagedDiscovery) DiscoverPages(_ context.Context, _ string, pageSize int, fn discovery.PageFunc) error {
+	for start := 0; start < len(d.services); start += pageSize {
+		end := min(start+pageSize, len(d.services))
+		d.pages = append(d.pages, end-start)
+
+		if err := fn(d.services[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestServicesPoolDiscoverPages(t *testing.T) {
+	paged := &pagedDiscovery{}
+	for i := 0; i < 25; i++ {
+		paged.services = append(paged.services, newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:              "TestServicePool",
+		Discovery:         paged,
+		DiscoveryPageSize: 10,
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	})
+
+	if err := pool.DiscoverServices(); err != nil {
+		t.Fatalf("unexpected discovery error: %s", err)
+	}
+
+	if !reflect.DeepEqual(paged.pages, []int{10, 10, 5}) {
+		t.Errorf("unexpected discovered pages %v", paged.pages)
+	}
+
+	if pool.Count() != 25 {
+		t.Errorf("expected 25 discovered services, got %d", pool.Count())
+	}
+}
+
+type watchingDiscovery struct {
+	mu       sync.Mutex
+	services []service.IService
+	changes  chan []service.IService
+}
+
+func (d *watchingDiscovery) Discover(string) ([]service.IService, error) {
+	defer d.mu.Unlock()
+	d.mu.Lock()
+
+	return d.services, nil
+}
+
+func (d *watchingDiscovery) Watch(ctx context.Context, _ string, changed func()) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case services := <-d.changes:
+			d.mu.Lock()
+			d.services = services
+			d.mu.Unlock()
+
+			changed()
+		}
+	}
+}
+
+func TestServicesPoolWatchDiscovery(t *testing.T) {
+	discovery := &watchingDiscovery{changes: make(chan []service.IService)}
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:              "TestServicePool",
+		Discovery:         discovery,
+		DiscoveryInterval: time.Hour,
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	})
+	defer pool.Close()
+
+	pool.Start(false)
+
+	discovery.changes <- []service.IService{newHealthyService("https://1gateway.fm")}
+
+	waitFor(t, func() bool {
+		return pool.Count() == 1
+	})
+}
+
+func TestServicesPoolPruneMissing(t *testing.T) {
+	first := newHealthyService("https://1gateway.fm")
+	second := newHealthyService("https://2gateway.fm")
+
+	discovery := &watchingDiscovery{
+		services: []service.IService{first, second},
+		changes:  make(chan []service.IService),
+	}
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:              "TestServicePool",
+		Discovery:         discovery,
+		DiscoveryInterval: time.Hour,
+		PruneMissing:      true,
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	})
+
+	pool.Start(false)
+
+	waitFor(t, func() bool {
+		return pool.Count() == 2
+	})
+
+	discovery.changes <- []service.IService{first}
+	waitFor(t, func() bool {
+		return pool.Count() == 1
+	})
+	if healthy := pool.List().Healthy(); healthy[0].ID() != first.ID() {
+		t.Errorf("unexpected remaining service %s", healthy[0].ID())
+	}
+
+	// empty result does not prune the pool
+	discovery.changes <- nil
+	discovery.changes <- []service.IService{first}
+	if pool.Count() != 1 {
+		t.Errorf("empty discovery result should not prune services, got %d", pool.Count())
+	}
+
+	pool.Close()
+
+	// watch is stopped before close returns
+	select {
+	case discovery.changes <- nil:
+		t.Errorf("discovery watch should be stopped after close")
+	default:
+	}
+}
+
+// hangingDiscovery is driver which
+// calls hang until they are canceled
+type hangingDiscovery struct {
+	staticDiscovery
+	calls chan struct{}
+}
+
+func (d *hangingDiscovery) DiscoverContext(ctx context.Context, _ string) ([]service.IService, error) {
+	d.calls <- struct{}{}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestServicesPoolDiscoverServicesContext(t *testing.T) {
+	hanging := &hangingDiscovery{calls: make(chan struct{}, 1)}
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:              "TestServicePool",
+		Discovery:         hanging,
+		DiscoveryInterval: time.Hour,
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := pool.DiscoverServicesContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error, got %v", err)
+	}
+	<-hanging.calls
+
+	pool.Start(false)
+	<-hanging.calls
+
+	// close interrupts the hanging discovery round
+	closed := make(chan struct{})
+	go func() {
+		pool.Close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("close should interrupt discovery")
+	}
+}
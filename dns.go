@@ -0,0 +1,197 @@
+package pool
+
+import (
+	"math/rand"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const defaultDNSTTL = 5
+
+// DNSOpts is options that needs
+// to configure DNSHandler instance
+type DNSOpts struct {
+	Zone       string // fully qualified name answered with pool addresses, e.g. "provers.gateway.internal."
+	TTL        uint32 // answers ttl in seconds (5 by default)
+	Weighted   bool   // order A/AAAA answers by weighted random based on services load
+	MaxAnswers int    // max number of answers per response (0 for all healthy services)
+}
+
+// DNSHandler is miekg/dns handler that answers A, AAAA and SRV
+// queries for configured zone with current healthy services of
+// the list, so non-Go consumers could use pool health via plain DNS
+type DNSHandler struct {
+	list ISelector
+	opts DNSOpts
+}
+
+// dnsEndpoint is healthy service
+// address resolved for dns answers
+type dnsEndpoint struct {
+	ip     net.IP
+	host   string
+	port   uint16
+	weight uint16
+}
+
+// NewDNSHandler create new DNSHandler
+// for given services list
+func NewDNSHandler(list ISelector, opts *DNSOpts) *DNSHandler {
+	h := &DNSHandler{
+		list: list,
+		opts: *opts,
+	}
+
+	h.opts.Zone = dns.Fqdn(opts.Zone)
+	if h.opts.TTL == 0 {
+		h.opts.TTL = defaultDNSTTL
+	}
+
+	return h
+}
+
+// NewDNSServer create dns server with given handler
+// listening given address and network (udp or tcp)
+func NewDNSServer(addr, network string, handler *DNSHandler) *dns.Server {
+	return &dns.Server{
+		Addr:    addr,
+		Net:     network,
+		Handler: handler,
+	}
+}
+
+// ServeDNS answer given dns request
+func (h *DNSHandler) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(r)
+	resp.Authoritative = true
+
+	for _, question := range r.Question {
+		if !strings.EqualFold(question.Name, h.opts.Zone) {
+			resp.Rcode = dns.RcodeNameError
+			continue
+		}
+
+		resp.Answer = append(resp.Answer, h.answer(question)...)
+	}
+
+	_ = w.WriteMsg(resp)
+}
+
+// answer return resource records for given question
+func (h *DNSHandler) answer(question dns.Question) []dns.RR {
+	endpoints := h.endpoints()
+
+	var answers []dns.RR
+	for _, endpoint := range endpoints {
+		header := dns.RR_Header{Name: question.Name, Rrtype: question.Qtype, Class: dns.ClassINET, Ttl: h.opts.TTL}
+
+		switch {
+		case question.Qtype == dns.TypeA && endpoint.ip.To4() != nil:
+			answers = append(answers, &dns.A{Hdr: header, A: endpoint.ip.To4()})
+		case question.Qtype == dns.TypeAAAA && endpoint.ip != nil && endpoint.ip.To4() == nil:
+			answers = append(answers, &dns.AAAA{Hdr: header, AAAA: endpoint.ip})
+		case question.Qtype == dns.TypeSRV:
+			answers = append(answers, &dns.SRV{Hdr: header, Priority: 0, Weight: endpoint.weight, Port: endpoint.port, Target: dns.Fqdn(endpoint.host)})
+		}
+
+		if h.opts.MaxAnswers > 0 && len(answers) == h.opts.MaxAnswers {
+			break
+		}
+	}
+
+	return answers
+}
+
+// endpoints return healthy services addresses
+// in weighted random order if configured
+func (h *DNSHandler) endpoints() []dnsEndpoint {
+	var endpoints []dnsEndpoint
+	for _, srv := range h.list.Healthy() {
+		if srv.Status() != service.StatusHealthy {
+			continue
+		}
+
+		if endpoint, ok := newDNSEndpoint(srv); ok {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+
+	if h.opts.Weighted {
+		weightedShuffle(endpoints)
+	}
+
+	return endpoints
+}
+
+// newDNSEndpoint resolve host and
+// port of given service address
+func newDNSEndpoint(srv service.IService) (dnsEndpoint, bool) {
+	host, port, ok := splitServiceAddress(srv.Address())
+	if !ok {
+		return dnsEndpoint{}, false
+	}
+
+	return dnsEndpoint{
+		ip:     net.ParseIP(host),
+		host:   host,
+		port:   port,
+		weight: serviceWeight(srv),
+	}, true
+}
+
+// splitServiceAddress return host and port of given service
+// address which could be url or host:port pair
+func splitServiceAddress(address string) (string, uint16, bool) {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+
+		value, err := strconv.ParseUint(port, 10, 16)
+		return u.Hostname(), uint16(value), err == nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, false
+	}
+
+	value, err := strconv.ParseUint(port, 10, 16)
+	return host, uint16(value), err == nil
+}
+
+// weightedShuffle order given endpoints by weighted random,
+// so endpoints with higher weight are more likely to be first
+func weightedShuffle(endpoints []dnsEndpoint) {
+	keys := make(map[int]float64, len(endpoints))
+	for i, endpoint := range endpoints {
+		// Efraimidis-Spirakis weighted random sampling key
+		keys[i] = -rand.ExpFloat64() / float64(endpoint.weight)
+	}
+
+	indexes := make([]int, len(endpoints))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return keys[indexes[i]] > keys[indexes[j]]
+	})
+
+	shuffled := make([]dnsEndpoint, len(endpoints))
+	for i, index := range indexes {
+		shuffled[i] = endpoints[index]
+	}
+	copy(endpoints, shuffled)
+}
@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// dnsRecorder is dns.ResponseWriter
+// that keeps the written message
+type dnsRecorder struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+}
+
+func (w *dnsRecorder) WriteMsg(msg *dns.Msg) error {
+	w.msg = msg
+	return nil
+}
+
+func TestDNSHandler(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+
+	for _, address := range []string{"http://10.0.0.1:8080", "https://10.0.0.2", "http://[fd00::1]:9000", "https://1gateway.fm"} {
+		srv := service.NewService(address, "", nil, 0.5)
+		srv.(*service.BaseService).SetStatus(service.StatusHealthy)
+		list.Add(srv)
+	}
+
+	handler := NewDNSHandler(list, &DNSOpts{Zone: "provers.internal", Weighted: true})
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+
+		w := &dnsRecorder{}
+		handler.ServeDNS(w, req)
+
+		return w.msg
+	}
+
+	resp := query("provers.internal.", dns.TypeA)
+	if len(resp.Answer) != 2 {
+		t.Fatalf("expected 2 A answers, got %d", len(resp.Answer))
+	}
+	for _, rr := range resp.Answer {
+		ip := rr.(*dns.A).A
+		if !ip.Equal(net.ParseIP("10.0.0.1")) && !ip.Equal(net.ParseIP("10.0.0.2")) {
+			t.Errorf("unexpected A answer %s", ip)
+		}
+		if rr.Header().Ttl != defaultDNSTTL {
+			t.Errorf("unexpected ttl %d", rr.Header().Ttl)
+		}
+	}
+
+	resp = query("provers.internal.", dns.TypeAAAA)
+	if len(resp.Answer) != 1 || !resp.Answer[0].(*dns.AAAA).AAAA.Equal(net.ParseIP("fd00::1")) {
+		t.Fatalf("unexpected AAAA answers %v", resp.Answer)
+	}
+
+	resp = query("provers.internal.", dns.TypeSRV)
+	if len(resp.Answer) != 4 {
+		t.Fatalf("expected 4 SRV answers, got %d", len(resp.Answer))
+	}
+	ports := make(map[string]uint16)
+	for _, rr := range resp.Answer {
+		srv := rr.(*dns.SRV)
+		ports[srv.Target] = srv.Port
+		if srv.Weight != 51 {
+			t.Errorf("unexpected SRV weight %d", srv.Weight)
+		}
+	}
+	if ports["10.0.0.1."] != 8080 || ports["10.0.0.2."] != 443 || ports["1gateway.fm."] != 443 {
+		t.Errorf("unexpected SRV ports %v", ports)
+	}
+
+	resp = query("other.internal.", dns.TypeA)
+	if resp.Rcode != dns.RcodeNameError || len(resp.Answer) != 0 {
+		t.Errorf("expected NXDOMAIN for name outside of zone")
+	}
+
+	handler = NewDNSHandler(list, &DNSOpts{Zone: "provers.internal.", MaxAnswers: 1})
+	if resp = query("provers.internal.", dns.TypeSRV); len(resp.Answer) != 1 {
+		t.Errorf("expected answers to be capped, got %d", len(resp.Answer))
+	}
+}
@@ -0,0 +1,153 @@
+package pool
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultDrainTimeout = 25 * time.Second
+	drainPollInterval   = 100 * time.Millisecond
+)
+
+// SetDraining stop or resume selections of the list, draining
+// list selects no service and grants no lease while already
+// leased and acquired services finish theirs work
+func (l *ServicesList) SetDraining(draining bool) {
+	var value int32
+	if draining {
+		value = 1
+	}
+
+	if atomic.SwapInt32(&l.draining, value) == value {
+		return
+	}
+
+	if draining {
+		l.log().Warn("list is draining, selections are stopped")
+		return
+	}
+
+	l.log().Info("list is not draining anymore, selections are resumed")
+}
+
+// Draining check if selections of the list are stopped
+func (l *ServicesList) Draining() bool {
+	return atomic.LoadInt32(&l.draining) == 1
+}
+
+// Pending return number of active and draining
+// leases and in-flight connections of the list
+func (l *ServicesList) Pending() int {
+	return l.leases.total() + l.connections.total()
+}
+
+// expireLeases force-expire all active and draining
+// leases of the list, so holders learn the list is closed
+func (l *ServicesList) expireLeases() {
+	for _, lease := range l.leases.all() {
+		lease.drain(0)
+		lease.expire()
+	}
+}
+
+// SetDraining stop or resume selections of all shards
+func (l *ShardedServicesList) SetDraining(draining bool) {
+	for _, shard := range l.shards {
+		shard.SetDraining(draining)
+	}
+}
+
+// Draining check if selections of shards are stopped
+func (l *ShardedServicesList) Draining() bool {
+	return l.shards[0].Draining()
+}
+
+// Pending return number of active and draining leases
+// and in-flight connections of all shards
+func (l *ShardedServicesList) Pending() int {
+	pending := 0
+	for _, shard := range l.shards {
+		pending += shard.Pending()
+	}
+
+	return pending
+}
+
+// Drain stop selections of the pool, wait until its leases are
+// released and in-flight connections are finished or given
+// context is done, then close the pool, which wait for the history
+// recorder, consul publisher and state persister to flush. Leases
+// that are not released in time are force-expired by the close
+func (p *ServicesPool) Drain(ctx context.Context) error {
+	p.log().Warn("pool is draining")
+
+	p.list.SetDraining(true)
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	var err error
+	for pending := p.list.Pending(); pending > 0 && err == nil; pending = p.list.Pending() {
+		select {
+		case <-ctx.Done():
+			err = ErrDrainTimeout{Pool: p.name, Pending: pending}
+		case <-ticker.C:
+		}
+	}
+
+	p.Close()
+
+	if err != nil {
+		return err
+	}
+
+	p.log().Info("pool is drained and closed")
+
+	return nil
+}
+
+// HandleSignals drain and close the pool on SIGTERM, so rolling
+// restarts of gateways let leased work finish instead of dropping
+// it. Draining is bounded by configured drain timeout. Returned
+// channel is closed once the pool is drained and closed, or
+// given context is done before the signal is received
+func (p *ServicesPool) HandleSignals(ctx context.Context) <-chan struct{} {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM)
+
+	done := make(chan struct{})
+
+	goLabeled(p.name, taskSignals, func() {
+		defer close(done)
+		defer signal.Stop(signals)
+
+		p.handleSignals(ctx, signals)
+	})
+
+	return done
+}
+
+// handleSignals drain the pool once a signal is received from
+// given channel, unless given context is done before it
+func (p *ServicesPool) handleSignals(ctx context.Context, signals <-chan os.Signal) {
+	select {
+	case <-ctx.Done():
+		return
+	case sig := <-signals:
+		p.log().Warn("received signal", "signal", sig.String())
+	}
+
+	// drain is not bound to given context, it's
+	// commonly canceled by the same signal
+	drainCtx, cancel := context.WithTimeout(context.Background(), p.drainTimeout)
+	defer cancel()
+
+	if err := p.Drain(drainCtx); err != nil {
+		p.log().Warn("drain error", "error", err)
+	}
+}
@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestServicesPoolDrainOnSignal(t *testing.T) {
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:      "TestServicePool",
+		Discovery: &staticDiscovery{},
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	}).(*ServicesPool)
+
+	pool.AddService(newHealthyService("https://1gateway.fm"))
+
+	lease, err := pool.list.Lease("job-1")
+	if err != nil {
+		t.Fatalf("unexpected lease error: %s", err)
+	}
+	_, release := pool.list.Acquire(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pool.handleSignals(context.Background(), signals)
+	}()
+
+	signals <- syscall.SIGTERM
+	waitFor(t, pool.list.Draining)
+
+	if srv := pool.NextService(); srv != nil {
+		t.Errorf("draining pool should select no service, got %s", srv.ID())
+	}
+	if _, err := pool.list.Lease("job-2"); !errors.As(err, &ErrPoolDraining{}) {
+		t.Errorf("draining pool should grant no lease, got %v", err)
+	}
+	if !pool.list.Snapshot().Draining {
+		t.Errorf("snapshot should report draining pool")
+	}
+
+	lease.Release()
+
+	select {
+	case <-done:
+		t.Fatalf("pool should not be drained before in-flight connection is released")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	release()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("pool should be drained")
+	}
+
+	select {
+	case <-pool.stop:
+	default:
+		t.Errorf("drained pool should be closed")
+	}
+}
+
+func TestServicesPoolDrainTimeout(t *testing.T) {
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:      "TestServicePool",
+		Discovery: &staticDiscovery{},
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	}).(*ServicesPool)
+
+	pool.AddService(newHealthyService("https://1gateway.fm"))
+
+	lease, err := pool.list.Lease("job-1")
+	if err != nil {
+		t.Fatalf("unexpected lease error: %s", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	var timeout ErrDrainTimeout
+	if err := pool.Drain(ctx); !errors.As(err, &timeout) || timeout.Pending != 1 {
+		t.Fatalf("expected drain timeout with 1 pending lease, got %v", err)
+	}
+
+	select {
+	case <-lease.Expired():
+	default:
+		t.Errorf("pending lease should be expired by the close")
+	}
+
+	// repeated close is no-op
+	pool.Close()
+}
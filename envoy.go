@@ -0,0 +1,237 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// envoyLoadAssignmentType is type url of
+// endpoints served by EnvoyEndpoints
+const envoyLoadAssignmentType = "type.googleapis.com/envoy.config.endpoint.v3.ClusterLoadAssignment"
+
+// EnvoyEndpoints is IHealthExporter serving exported verdicts to
+// Envoy as endpoint discovery service with REST-JSON transport.
+// Every pool is served as cluster with the same name and its
+// backends carry health status, weight and priority level of
+// theirs verdicts, so Envoy clusters configured with api_type
+// REST against the handler route to the same services as the
+// pool. Use it as http handler of "POST /v3/discovery:endpoints"
+type EnvoyEndpoints struct {
+	scheme string
+
+	mu       sync.RWMutex
+	clusters map[string]envoyCluster // pool name -> cluster
+}
+
+// envoyCluster is load assignment of one
+// cluster and its version
+type envoyCluster struct {
+	version    string
+	assignment envoyLoadAssignment
+}
+
+// envoyDiscoveryRequest is json DiscoveryRequest
+type envoyDiscoveryRequest struct {
+	VersionInfo   string   `json:"version_info"`
+	ResourceNames []string `json:"resource_names"`
+	TypeURL       string   `json:"type_url"`
+}
+
+// envoyDiscoveryResponse is json DiscoveryResponse
+type envoyDiscoveryResponse struct {
+	VersionInfo string                `json:"version_info"`
+	Resources   []envoyLoadAssignment `json:"resources"`
+	TypeURL     string                `json:"type_url"`
+}
+
+// envoyLoadAssignment is json ClusterLoadAssignment
+type envoyLoadAssignment struct {
+	Type        string                     `json:"@type"`
+	ClusterName string                     `json:"cluster_name"`
+	Endpoints   []envoyLocalityLbEndpoints `json:"endpoints"`
+	Policy      *envoyAssignmentPolicy     `json:"policy,omitempty"`
+}
+
+// envoyAssignmentPolicy is json ClusterLoadAssignment.Policy
+type envoyAssignmentPolicy struct {
+	OverprovisioningFactor int `json:"overprovisioning_factor,omitempty"` // percents
+}
+
+// envoyLocalityLbEndpoints is json LocalityLbEndpoints
+type envoyLocalityLbEndpoints struct {
+	LbEndpoints []envoyLbEndpoint `json:"lb_endpoints"`
+	Priority    int               `json:"priority,omitempty"`
+}
+
+// envoyLbEndpoint is json LbEndpoint
+type envoyLbEndpoint struct {
+	Endpoint struct {
+		Address struct {
+			SocketAddress struct {
+				Address   string `json:"address"`
+				PortValue int    `json:"port_value"`
+			} `json:"socket_address"`
+		} `json:"address"`
+		Hostname string `json:"hostname,omitempty"`
+	} `json:"endpoint"`
+	HealthStatus        string `json:"health_status,omitempty"`
+	LoadBalancingWeight int    `json:"load_balancing_weight,omitempty"`
+}
+
+// NewEnvoyEndpoints create new EnvoyEndpoints, given scheme is
+// used for services addresses without one to find default port
+func NewEnvoyEndpoints(scheme string) *EnvoyEndpoints {
+	if scheme == "" {
+		scheme = defaultProxyScheme
+	}
+
+	return &EnvoyEndpoints{
+		scheme:   scheme,
+		clusters: make(map[string]envoyCluster),
+	}
+}
+
+// Export replace endpoints of cluster of given pool, backends
+// are grouped by priority level and ones which addresses could
+// not be parsed are skipped
+func (e *EnvoyEndpoints) Export(_ context.Context, pool string, verdicts []HealthVerdict) error {
+	assignment := envoyLoadAssignment{
+		Type:        envoyLoadAssignmentType,
+		ClusterName: pool,
+		Endpoints:   []envoyLocalityLbEndpoints{},
+	}
+
+	levels := make(map[int]int) // priority -> index of endpoints
+	var err error
+	for _, verdict := range verdicts {
+		host, port, addrErr := e.hostPort(verdict.Address)
+		if addrErr != nil {
+			err = addrErr
+			continue
+		}
+
+		var endpoint envoyLbEndpoint
+		endpoint.Endpoint.Address.SocketAddress.Address = host
+		endpoint.Endpoint.Address.SocketAddress.PortValue = port
+		endpoint.Endpoint.Hostname = verdict.NodeName
+		endpoint.HealthStatus = envoyHealthStatus(verdict.State)
+		endpoint.LoadBalancingWeight = verdict.Weight
+
+		i, ok := levels[verdict.Priority]
+		if !ok {
+			i = len(assignment.Endpoints)
+			levels[verdict.Priority] = i
+			assignment.Endpoints = append(assignment.Endpoints, envoyLocalityLbEndpoints{Priority: verdict.Priority})
+		}
+		assignment.Endpoints[i].LbEndpoints = append(assignment.Endpoints[i].LbEndpoints, endpoint)
+	}
+
+	// envoy requires priorities to be contiguous and start at 0
+	sort.SliceStable(assignment.Endpoints, func(i, j int) bool {
+		return assignment.Endpoints[i].Priority < assignment.Endpoints[j].Priority
+	})
+	for i := range assignment.Endpoints {
+		assignment.Endpoints[i].Priority = i
+	}
+
+	data, marshalErr := json.Marshal(assignment)
+	if marshalErr != nil {
+		return marshalErr
+	}
+
+	hash := fnv.New64a()
+	hash.Write(data)
+
+	e.mu.Lock()
+	e.clusters[pool] = envoyCluster{
+		version:    strconv.FormatUint(hash.Sum64(), 16),
+		assignment: assignment,
+	}
+	e.mu.Unlock()
+
+	return err
+}
+
+// ServeHTTP respond to REST-JSON endpoint discovery request
+// with load assignments of requested clusters, Not Modified
+// is responded if the version known by Envoy is current
+func (e *EnvoyEndpoints) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req envoyDiscoveryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decode discovery request: %s", err), http.StatusBadRequest)
+		return
+	}
+	if req.TypeURL != "" && req.TypeURL != envoyLoadAssignmentType {
+		http.Error(w, fmt.Sprintf("unsupported resources type %s", req.TypeURL), http.StatusBadRequest)
+		return
+	}
+
+	e.mu.RLock()
+	resp := envoyDiscoveryResponse{TypeURL: envoyLoadAssignmentType, Resources: []envoyLoadAssignment{}}
+
+	hash := fnv.New64a()
+	for _, name := range req.ResourceNames {
+		cluster, ok := e.clusters[name]
+		if !ok {
+			continue
+		}
+
+		hash.Write([]byte(name + "@" + cluster.version + ";"))
+		resp.Resources = append(resp.Resources, cluster.assignment)
+	}
+	e.mu.RUnlock()
+
+	resp.VersionInfo = strconv.FormatUint(hash.Sum64(), 16)
+	if resp.VersionInfo == req.VersionInfo {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// hostPort return host and port of given service address
+func (e *EnvoyEndpoints) hostPort(address string) (string, int, error) {
+	target, err := serviceURL(address, e.scheme)
+	if err != nil {
+		return "", 0, err
+	}
+
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if target.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	value, err := strconv.Atoi(port)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port of service address %q: %w", address, err)
+	}
+
+	host := target.Hostname()
+	if host == "" {
+		return "", 0, fmt.Errorf("service address %q has no host", address)
+	}
+
+	return host, value, nil
+}
+
+// envoyHealthStatus return envoy health status of given backend state
+func envoyHealthStatus(state BackendState) string {
+	switch state {
+	case BackendHealthy:
+		return "HEALTHY"
+	case BackendDraining:
+		return "DRAINING"
+	default:
+		return "UNHEALTHY"
+	}
+}
@@ -0,0 +1,174 @@
+package pool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// Envoy load balancing policies of cluster lb_policy field
+const (
+	EnvoyRoundRobin   = "ROUND_ROBIN"
+	EnvoyLeastRequest = "LEAST_REQUEST"
+	EnvoyRandom       = "RANDOM"
+)
+
+// EnvoyClusterConfig is pool configuration translated from Envoy
+// cluster, so the pool balance the same endpoints as Envoy does
+type EnvoyClusterConfig struct {
+	Name          string             // cluster name
+	Balancing     Balancing          // built-in balancing of cluster lb_policy
+	Strategy      IBalancingStrategy // custom strategy of cluster lb_policy without built-in balancing, nil otherwise
+	Priorities    *PriorityOpts      // priority levels with cluster overprovisioning factor
+	PassiveHealth *PassiveHealthOpts // passive health of cluster outlier detection, nil if outlier detection is not configured
+	Services      []service.IService // endpoints of cluster static load assignment with weight and priority metadata
+}
+
+// envoyClusterSpec is yaml or json subset of Envoy v3 Cluster
+type envoyClusterSpec struct {
+	Name             string `yaml:"name"`
+	LbPolicy         string `yaml:"lb_policy"`
+	OutlierDetection *struct {
+		Consecutive5xx     *int `yaml:"consecutive_5xx"`
+		MaxEjectionPercent *int `yaml:"max_ejection_percent"`
+	} `yaml:"outlier_detection"`
+	LoadAssignment struct {
+		Endpoints []struct {
+			Priority    int `yaml:"priority"`
+			LbEndpoints []struct {
+				Endpoint struct {
+					Address struct {
+						SocketAddress struct {
+							Address   string `yaml:"address"`
+							PortValue int    `yaml:"port_value"`
+						} `yaml:"socket_address"`
+					} `yaml:"address"`
+					Hostname string `yaml:"hostname"`
+				} `yaml:"endpoint"`
+				LoadBalancingWeight int `yaml:"load_balancing_weight"`
+			} `yaml:"lb_endpoints"`
+		} `yaml:"endpoints"`
+		Policy struct {
+			OverprovisioningFactor int `yaml:"overprovisioning_factor"`
+		} `yaml:"policy"`
+	} `yaml:"load_assignment"`
+}
+
+// ParseEnvoyCluster translate yaml or json Envoy v3 cluster to
+// pool configuration: lb_policy is translated to balancing,
+// overprovisioning factor in percents to priority levels and
+// endpoints to services of given scheme ("http" by default)
+// with theirs weights and priorities, outlier detection is
+// translated to passive health. ROUND_ROBIN is weighted if
+// any endpoint has weight like in Envoy
+func ParseEnvoyCluster(data []byte, scheme string) (*EnvoyClusterConfig, error) {
+	var spec envoyClusterSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("decode envoy cluster: %w", err)
+	}
+
+	if scheme == "" {
+		scheme = defaultProxyScheme
+	}
+
+	config := &EnvoyClusterConfig{Name: spec.Name, Priorities: &PriorityOpts{}}
+	if factor := spec.LoadAssignment.Policy.OverprovisioningFactor; factor > 0 {
+		config.Priorities.OverprovisioningFactor = float64(factor) / 100
+	}
+
+	if outlier := spec.OutlierDetection; outlier != nil {
+		config.PassiveHealth = &PassiveHealthOpts{ConsecutiveFailures: defaultConsecutiveFailures, MaxEjectionPercent: defaultMaxEjectionPercent}
+		if outlier.Consecutive5xx != nil {
+			config.PassiveHealth.ConsecutiveFailures = *outlier.Consecutive5xx
+		}
+		if outlier.MaxEjectionPercent != nil {
+			config.PassiveHealth.MaxEjectionPercent = *outlier.MaxEjectionPercent
+		}
+
+		// envoy disables detection with zero consecutive failures
+		if config.PassiveHealth.ConsecutiveFailures == 0 {
+			config.PassiveHealth = nil
+		}
+	}
+
+	weighted := false
+	for _, locality := range spec.LoadAssignment.Endpoints {
+		for _, lbEndpoint := range locality.LbEndpoints {
+			socket := lbEndpoint.Endpoint.Address.SocketAddress
+			if socket.Address == "" {
+				return nil, fmt.Errorf("endpoint of envoy cluster %s has no address", spec.Name)
+			}
+
+			metadata := map[string]string{service.PriorityMetadataKey: strconv.Itoa(locality.Priority)}
+			if lbEndpoint.LoadBalancingWeight > 0 {
+				weighted = true
+				metadata[service.WeightMetadataKey] = strconv.Itoa(lbEndpoint.LoadBalancingWeight)
+			}
+
+			address := socket.Address
+			if socket.PortValue > 0 {
+				address = fmt.Sprintf("%s:%d", address, socket.PortValue)
+			}
+
+			record := service.Record{
+				Address:  scheme + "://" + address,
+				NodeName: lbEndpoint.Endpoint.Hostname,
+				Metadata: metadata,
+			}
+			config.Services = append(config.Services, record.Service())
+		}
+	}
+
+	switch strings.ToUpper(spec.LbPolicy) {
+	case "", EnvoyRoundRobin:
+		if weighted {
+			config.Balancing = BalancingWeightedRoundRobin
+		}
+	case EnvoyLeastRequest:
+		config.Balancing = BalancingLeastConnections
+	case EnvoyRandom:
+		config.Strategy = Random()
+		if weighted {
+			config.Strategy = WeightedRandom()
+		}
+	default:
+		return nil, ErrUnsupportedBalancing{Name: spec.LbPolicy}
+	}
+
+	return config, nil
+}
+
+// Apply set balancing, priority levels and passive
+// health of given list options from the cluster
+func (c *EnvoyClusterConfig) Apply(opts *ServicesListOpts) {
+	opts.Balancing = c.Balancing
+	opts.Strategy = c.Strategy
+	opts.Priorities = c.Priorities
+	opts.PassiveHealth = c.PassiveHealth
+}
+
+// EnvoyLbPolicy return Envoy cluster lb_policy
+// equivalent to given built-in balancing
+func EnvoyLbPolicy(balancing Balancing) string {
+	if balancing == BalancingLeastConnections {
+		return EnvoyLeastRequest
+	}
+
+	// envoy round robin is weighted by endpoints weights
+	return EnvoyRoundRobin
+}
+
+// EnvoyOverprovisioningFactor return Envoy load assignment
+// overprovisioning factor in percents of given priority levels
+func EnvoyOverprovisioningFactor(opts *PriorityOpts) int {
+	factor := DefaultOverprovisioningFactor
+	if opts != nil && opts.OverprovisioningFactor > 0 {
+		factor = opts.OverprovisioningFactor
+	}
+
+	return int(factor*100 + 0.5)
+}
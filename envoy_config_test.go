@@ -0,0 +1,63 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestParseEnvoyCluster(t *testing.T) {
+	config, err := ParseEnvoyCluster([]byte(`
+name: provers
+lb_policy: ROUND_ROBIN
+outlier_detection:
+  consecutive_5xx: 3
+load_assignment:
+  cluster_name: provers
+  policy:
+    overprovisioning_factor: 120
+  endpoints:
+    - lb_endpoints:
+        - endpoint:
+            address: {socket_address: {address: 10.0.0.1, port_value: 8080}}
+            hostname: prover-1
+          load_balancing_weight: 3
+    - priority: 1
+      lb_endpoints:
+        - endpoint:
+            address: {socket_address: {address: 10.0.0.2, port_value: 8080}}
+`), "")
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+
+	if config.Name != "provers" || config.Balancing != BalancingWeightedRoundRobin || config.Priorities.OverprovisioningFactor != 1.2 {
+		t.Errorf("unexpected cluster config %+v", config)
+	}
+	if config.PassiveHealth == nil || config.PassiveHealth.ConsecutiveFailures != 3 || config.PassiveHealth.MaxEjectionPercent != 10 {
+		t.Errorf("unexpected passive health %+v", config.PassiveHealth)
+	}
+	if len(config.Services) != 2 {
+		t.Fatalf("expected 2 services, got %d", len(config.Services))
+	}
+
+	primary, backup := config.Services[0], config.Services[1]
+	if primary.Address() != "http://10.0.0.1:8080" || primary.NodeName() != "prover-1" || service.Weight(primary) != 3 || service.Priority(primary) != 0 {
+		t.Errorf("unexpected primary service %+v", service.NewRecord(primary))
+	}
+	if service.Weight(backup) != service.DefaultWeight || service.Priority(backup) != 1 {
+		t.Errorf("unexpected backup service %+v", service.NewRecord(backup))
+	}
+
+	opts := &ServicesListOpts{}
+	config.Apply(opts)
+	if EnvoyLbPolicy(opts.Balancing) != EnvoyRoundRobin || EnvoyOverprovisioningFactor(opts.Priorities) != 120 {
+		t.Errorf("translation back to envoy should keep the cluster semantics")
+	}
+
+	_, err = ParseEnvoyCluster([]byte("lb_policy: MAGLEV"), "")
+	if !errors.As(err, &ErrUnsupportedBalancing{}) {
+		t.Errorf("expected unsupported balancing error, got %v", err)
+	}
+}
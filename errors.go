@@ -0,0 +1,490 @@
+package pool
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrServiceNotFound is error when service
+// with given id is not found in the list
+type ErrServiceNotFound struct {
+	ID string
+}
+
+// Error is throw error as a string
+func (e ErrServiceNotFound) Error() string {
+	return fmt.Sprintf("service with id %q is not found", e.ID)
+}
+
+// ErrPoolExists is error when pool with given
+// name is already registered in the registry
+type ErrPoolExists struct {
+	Name string
+}
+
+// Error is throw error as a string
+func (e ErrPoolExists) Error() string {
+	return fmt.Sprintf("pool with name %q already exists", e.Name)
+}
+
+// ErrPoolNotFound is error when pool with given
+// name is not found in the registry
+type ErrPoolNotFound struct {
+	Name string
+}
+
+// Error is throw error as a string
+func (e ErrPoolNotFound) Error() string {
+	return fmt.Sprintf("pool with name %q is not found", e.Name)
+}
+
+// ErrTemplateNotFound is error when pool template
+// with given name is not registered in the registry
+type ErrTemplateNotFound struct {
+	Name string
+}
+
+// Error is throw error as a string
+func (e ErrTemplateNotFound) Error() string {
+	return fmt.Sprintf("pool template with name %q is not found", e.Name)
+}
+
+// ErrTemplateParamMissing is error when template placeholder
+// has no value in instance or default parameters
+type ErrTemplateParamMissing struct {
+	Param string
+}
+
+// Error is throw error as a string
+func (e ErrTemplateParamMissing) Error() string {
+	return fmt.Sprintf("template parameter %q is missing", e.Param)
+}
+
+// ErrUnsupportedDocument is error when membership
+// document has unsupported format version
+type ErrUnsupportedDocument struct {
+	Version int
+}
+
+// Error is throw error as a string
+func (e ErrUnsupportedDocument) Error() string {
+	return fmt.Sprintf("unsupported membership document version %d", e.Version)
+}
+
+// ErrUnsupportedImportMode is error when
+// membership import mode is unknown
+type ErrUnsupportedImportMode struct {
+	Mode string
+}
+
+// Error is throw error as a string
+func (e ErrUnsupportedImportMode) Error() string {
+	return fmt.Sprintf("unsupported membership import mode %q", e.Mode)
+}
+
+// ErrInvalidSignature is error when signed membership
+// document or gossip verdict can't be verified with
+// trusted keys
+type ErrInvalidSignature struct {
+	KeyID  string
+	Reason string
+}
+
+// Error is throw error as a string
+func (e ErrInvalidSignature) Error() string {
+	return fmt.Sprintf("invalid signature with key id %q: %s", e.KeyID, e.Reason)
+}
+
+// ErrConsulRequest is error when Consul
+// responds with unexpected status code
+type ErrConsulRequest struct {
+	Key        string
+	StatusCode int
+}
+
+// Error is throw error as a string
+func (e ErrConsulRequest) Error() string {
+	return fmt.Sprintf("consul request for key %q failed with status code %d", e.Key, e.StatusCode)
+}
+
+// ErrNotSpare is error when service with
+// given id is not designated as hot spare
+type ErrNotSpare struct {
+	ID string
+}
+
+// Error is throw error as a string
+func (e ErrNotSpare) Error() string {
+	return fmt.Sprintf("service with id %q is not a spare", e.ID)
+}
+
+// ErrServiceHeld is error when service with given
+// id is held in the jail by maintenance window
+type ErrServiceHeld struct {
+	ID string
+}
+
+// Error is throw error as a string
+func (e ErrServiceHeld) Error() string {
+	return fmt.Sprintf("service with id %q is held in the jail by maintenance", e.ID)
+}
+
+// ErrServiceDrained is error when service with given id
+// is drained with DrainService and takes no new work
+type ErrServiceDrained struct {
+	ID string
+}
+
+// Error is throw error as a string
+func (e ErrServiceDrained) Error() string {
+	return fmt.Sprintf("service with id %q is drained", e.ID)
+}
+
+// ErrUnsupportedMaintenanceAction is error
+// when maintenance action is unknown
+type ErrUnsupportedMaintenanceAction struct {
+	Action string
+}
+
+// Error is throw error as a string
+func (e ErrUnsupportedMaintenanceAction) Error() string {
+	return fmt.Sprintf("unsupported maintenance action %q", e.Action)
+}
+
+// ErrMaintenanceNotFound is error when maintenance
+// window with given id is not scheduled
+type ErrMaintenanceNotFound struct {
+	ID string
+}
+
+// Error is throw error as a string
+func (e ErrMaintenanceNotFound) Error() string {
+	return fmt.Sprintf("maintenance window with id %q is not found", e.ID)
+}
+
+// ErrNoHealthyServices is error when list with
+// given name has no healthy service to select
+type ErrNoHealthyServices struct {
+	Pool string
+}
+
+// Error is throw error as a string
+func (e ErrNoHealthyServices) Error() string {
+	return fmt.Sprintf("list %q has no healthy services", e.Pool)
+}
+
+// ErrDrainTimeout is error when pool with given name is
+// not drained in time and given number of leases and
+// in-flight connections are still pending
+type ErrDrainTimeout struct {
+	Pool    string
+	Pending int
+}
+
+// Error is throw error as a string
+func (e ErrDrainTimeout) Error() string {
+	return fmt.Sprintf("pool %q is not drained in time, %d leases and connections are pending", e.Pool, e.Pending)
+}
+
+// ErrPoolDraining is error when list with given
+// name is draining and grants no new work
+type ErrPoolDraining struct {
+	Pool string
+}
+
+// Error is throw error as a string
+func (e ErrPoolDraining) Error() string {
+	return fmt.Sprintf("list %q is draining", e.Pool)
+}
+
+// ErrInvalidQuery is error when services query
+// has unsupported parameter or value
+type ErrInvalidQuery struct {
+	Param  string
+	Reason string
+}
+
+// Error is throw error as a string
+func (e ErrInvalidQuery) Error() string {
+	return fmt.Sprintf("invalid query parameter %q: %s", e.Param, e.Reason)
+}
+
+// ErrClockSkew is error when externally supplied timestamp
+// is further in the future than tolerated clock skew
+type ErrClockSkew struct {
+	Time time.Time
+	Skew time.Duration
+}
+
+// Error is throw error as a string
+func (e ErrClockSkew) Error() string {
+	return fmt.Sprintf("timestamp %s is %s ahead of local clock", e.Time.UTC().Format(time.RFC3339), e.Skew)
+}
+
+// ErrInvalidSchedule is error when
+// check schedule could not be parsed
+type ErrInvalidSchedule struct {
+	Spec   string
+	Reason string
+}
+
+// Error is throw error as a string
+func (e ErrInvalidSchedule) Error() string {
+	return fmt.Sprintf("invalid schedule %q: %s", e.Spec, e.Reason)
+}
+
+// ErrUnsupportedBalancing is error when
+// balancing strategy name is unknown
+type ErrUnsupportedBalancing struct {
+	Name string
+}
+
+// Error is throw error as a string
+func (e ErrUnsupportedBalancing) Error() string {
+	return fmt.Sprintf("unsupported balancing strategy %q", e.Name)
+}
+
+// ErrInvalidPoolSpec is error when declarative
+// pool spec could not be turned into a pool
+type ErrInvalidPoolSpec struct {
+	Name   string
+	Reason string
+}
+
+// Error is throw error as a string
+func (e ErrInvalidPoolSpec) Error() string {
+	return fmt.Sprintf("invalid spec of pool %q: %s", e.Name, e.Reason)
+}
+
+// ErrKubernetesRequest is error when Kubernetes
+// API responds with unexpected status code
+type ErrKubernetesRequest struct {
+	Path       string
+	StatusCode int
+}
+
+// Error is throw error as a string
+func (e ErrKubernetesRequest) Error() string {
+	return fmt.Sprintf("kubernetes request for %s failed with status code %d", e.Path, e.StatusCode)
+}
+
+// ErrInvalidConfig is error when pool configuration
+// violates the pool configuration schema
+type ErrInvalidConfig struct {
+	Path   string
+	Reason string
+}
+
+// Error is throw error as a string
+func (e ErrInvalidConfig) Error() string {
+	return fmt.Sprintf("invalid pool config at %s: %s", e.Path, e.Reason)
+}
+
+// ErrUpstreamStatus is error when proxied service
+// responds with server error or grpc unavailability
+type ErrUpstreamStatus struct {
+	Address    string
+	StatusCode int
+	GRPCStatus int
+}
+
+// Error is throw error as a string
+func (e ErrUpstreamStatus) Error() string {
+	if e.GRPCStatus != 0 {
+		return fmt.Sprintf("service %s responded with grpc status %d", e.Address, e.GRPCStatus)
+	}
+
+	return fmt.Sprintf("service %s responded with status code %d", e.Address, e.StatusCode)
+}
+
+// ErrNotServing is error when grpc service
+// reports health status other than SERVING
+type ErrNotServing struct {
+	Address string
+	Status  string
+}
+
+// Error is throw error as a string
+func (e ErrNotServing) Error() string {
+	return fmt.Sprintf("grpc service %s is %s", e.Address, e.Status)
+}
+
+// ErrUnexpectedBody is error when healthcheck response
+// body doesn't contain expected substring
+type ErrUnexpectedBody struct {
+	Address  string
+	Expected string
+}
+
+// Error is throw error as a string
+func (e ErrUnexpectedBody) Error() string {
+	return fmt.Sprintf("healthcheck response of service %s doesn't contain %q", e.Address, e.Expected)
+}
+
+// ErrHAProxyCommand is error when HAProxy runtime
+// API rejects command for servers of given backend
+type ErrHAProxyCommand struct {
+	Backend string
+	Message string
+}
+
+// Error is throw error as a string
+func (e ErrHAProxyCommand) Error() string {
+	return fmt.Sprintf("haproxy command for backend %s failed: %s", e.Backend, e.Message)
+}
+
+// ErrLeaseRevoked is error when lease of service the job is
+// dispatched to is revoked, e.g. the service is jailed
+type ErrLeaseRevoked struct {
+	Lease   string
+	Service string
+	Reason  string
+}
+
+// Error is throw error as a string
+func (e ErrLeaseRevoked) Error() string {
+	return fmt.Sprintf("lease %s of service %s is revoked (%s)", e.Lease, e.Service, e.Reason)
+}
+
+// ErrInvalidTimeout is error when timeout
+// is inconsistent with other settings
+type ErrInvalidTimeout struct {
+	Timeout string
+	Reason  string
+}
+
+// Error is throw error as a string
+func (e ErrInvalidTimeout) Error() string {
+	return fmt.Sprintf("invalid %s timeout: %s", e.Timeout, e.Reason)
+}
+
+// ErrJailedBeforeRestart is error when service with given
+// id is jailed because it was jailed before restart
+type ErrJailedBeforeRestart struct {
+	ID string
+}
+
+// Error is throw error as a string
+func (e ErrJailedBeforeRestart) Error() string {
+	return fmt.Sprintf("service with id %q was jailed before restart", e.ID)
+}
+
+// ErrRedisReply is error when
+// Redis replies with error
+type ErrRedisReply struct {
+	Message string
+}
+
+// Error is throw error as a string
+func (e ErrRedisReply) Error() string {
+	return fmt.Sprintf("redis error reply: %s", e.Message)
+}
+
+// ErrPoolSaturated is error when all allowed healthy
+// services of list with given name are at theirs
+// in-flight cap and the request is not queued
+type ErrPoolSaturated struct {
+	Pool string
+}
+
+// Error is throw error as a string
+func (e ErrPoolSaturated) Error() string {
+	return fmt.Sprintf("list %q is saturated", e.Pool)
+}
+
+// ErrPoolClosed is error when list
+// with given name is already closed
+type ErrPoolClosed struct {
+	Pool string
+}
+
+// Error is throw error as a string
+func (e ErrPoolClosed) Error() string {
+	return fmt.Sprintf("list %q is closed", e.Pool)
+}
+
+// ErrServiceSaturated is error when list with given name has
+// healthy services eligible for the request, but all of them
+// are rejected by balancing strategy of the list
+type ErrServiceSaturated struct {
+	Pool    string
+	Healthy int
+}
+
+// Error is throw error as a string
+func (e ErrServiceSaturated) Error() string {
+	return fmt.Sprintf("list %q has no service allowed for the request, %d healthy services are skipped", e.Pool, e.Healthy)
+}
+
+// ErrNoEligibleService is error when list with given name has
+// healthy services, but none of them is eligible for the request,
+// number of services skipped for every reason is reported
+type ErrNoEligibleService struct {
+	Pool        string
+	Healthy     int
+	Standby     int // standby spares
+	Cordoned    int // cordoned for maintenance
+	Drained     int // drained with DrainService
+	Priority    int // outside of priority level picked for the request
+	Requirement int // not meeting requirement of the request
+	Policy      int // not allowed by list policies
+}
+
+// Error is throw error as a string
+func (e ErrNoEligibleService) Error() string {
+	var reasons []string
+	for _, skipped := range []struct {
+		count  int
+		reason string
+	}{
+		{e.Standby, "standby"},
+		{e.Cordoned, "cordoned"},
+		{e.Drained, "drained"},
+		{e.Priority, "outside of priority level"},
+		{e.Requirement, "not meeting requirement"},
+		{e.Policy, "not allowed by policies"},
+	} {
+		if skipped.count > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d %s", skipped.count, skipped.reason))
+		}
+	}
+
+	return fmt.Sprintf("list %q has no service eligible for the request, %d healthy services are skipped: %s", e.Pool, e.Healthy, strings.Join(reasons, ", "))
+}
+
+// ErrRequestShed is error when request of given
+// priority is shed by overloaded list
+type ErrRequestShed struct {
+	Pool     string
+	Priority Priority
+}
+
+// Error is throw error as a string
+func (e ErrRequestShed) Error() string {
+	return fmt.Sprintf("request of %s priority is shed by overloaded list %q", e.Priority, e.Pool)
+}
+
+// ErrUnauthorized is error when mutating admin
+// request is not authorized for given reason
+type ErrUnauthorized struct {
+	Reason string
+}
+
+// Error is throw error as a string
+func (e ErrUnauthorized) Error() string {
+	return fmt.Sprintf("unauthorized: %s", e.Reason)
+}
+
+// ErrRejectedDocument is error when membership document
+// is not imported into list with given name for given
+// reason, e.g. it's exported from another pool or replayed
+type ErrRejectedDocument struct {
+	Pool   string
+	Reason string
+}
+
+// Error is throw error as a string
+func (e ErrRejectedDocument) Error() string {
+	return fmt.Sprintf("membership document is rejected by list %q: %s", e.Pool, e.Reason)
+}
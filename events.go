@@ -0,0 +1,311 @@
+package pool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// EventType represent type of pool event
+type EventType int
+
+const (
+	// EventServiceChanged is emitted when rediscovered service
+	// with the same ID has changed address or metadata and
+	// is merged into the existing list entry
+	EventServiceChanged EventType = iota
+
+	// EventDependencyDegraded is emitted when any
+	// dependency list has no healthy services
+	EventDependencyDegraded
+
+	// EventDependencyRecovered is emitted when all
+	// dependency lists have healthy services again
+	EventDependencyRecovered
+
+	// EventServiceJailed is emitted when healthy
+	// service is moved to the jail
+	EventServiceJailed
+
+	// EventServiceRecovered is emitted when jailed
+	// service is moved back to healthy
+	EventServiceRecovered
+
+	// EventLeaseDraining is emitted when leased service is
+	// drained and lease holder is notified to hand it off
+	EventLeaseDraining
+
+	// EventLeaseReleased is emitted when draining lease
+	// is released by its holder before the deadline
+	EventLeaseReleased
+
+	// EventLeaseExpired is emitted when draining lease is
+	// force-expired after the handoff window has passed
+	EventLeaseExpired
+
+	// EventServiceStarved is emitted when healthy service
+	// is not selected during configured starvation window
+	EventServiceStarved
+
+	// EventServiceAdded is published to subscribers when
+	// new service is added to the list, healthy or jailed
+	EventServiceAdded
+
+	// EventServiceRemoved is published to subscribers when
+	// service is removed from healthy, jail or review
+	EventServiceRemoved
+
+	// EventHealthCheckFailed is published to
+	// subscribers when healthcheck of service fails
+	EventHealthCheckFailed
+)
+
+const defaultEventBuffer = 64
+
+// String return event type name
+func (t EventType) String() string {
+	switch t {
+	case EventServiceChanged:
+		return "service_changed"
+	case EventDependencyDegraded:
+		return "dependency_degraded"
+	case EventDependencyRecovered:
+		return "dependency_recovered"
+	case EventServiceJailed:
+		return "service_jailed"
+	case EventServiceRecovered:
+		return "service_recovered"
+	case EventLeaseDraining:
+		return "lease_draining"
+	case EventLeaseReleased:
+		return "lease_released"
+	case EventLeaseExpired:
+		return "lease_expired"
+	case EventServiceStarved:
+		return "service_starved"
+	case EventServiceAdded:
+		return "service_added"
+	case EventServiceRemoved:
+		return "service_removed"
+	case EventHealthCheckFailed:
+		return "healthcheck_failed"
+	default:
+		return "unknown"
+	}
+}
+
+// PoolEvent is change of services list membership
+type PoolEvent struct {
+	Type     EventType
+	Pool     string
+	Service  service.IService // nil for list-wide events
+	Previous service.IService // previous instance for EventServiceChanged
+	Lease    *Lease           // lease for lease events
+	Reason   string           // removal reason for EventServiceRemoved
+	Err      error            // healthcheck error for EventHealthCheckFailed
+	Time     time.Time
+}
+
+// emit call configured event handler with given event and
+// publish it to subscribers. Should not be called under the
+// list lock
+func (l *ServicesList) emit(event PoolEvent) {
+	l.callHooks(event)
+
+	if l.onEvent == nil && !l.events.subscribed() {
+		return
+	}
+
+	event.Pool = l.serviceName
+	event.Time = time.Now()
+
+	if l.onEvent != nil {
+		l.onEvent(event)
+	}
+
+	l.events.publish(event)
+}
+
+// publish publish given lifecycle event to subscribers
+// only, the event handler keeps receiving events it was
+// designed for
+func (l *ServicesList) publish(event PoolEvent) {
+	l.callHooks(event)
+
+	if !l.events.subscribed() {
+		return
+	}
+
+	event.Pool = l.serviceName
+	event.Time = time.Now()
+
+	l.events.publish(event)
+}
+
+// hooks is callbacks of services state transitions
+type hooks struct {
+	onJail    ServiceCallback
+	onRecover ServiceCallback
+	onAdd     ServiceCallback
+	onRemove  ServiceCallback
+}
+
+// callHooks call callback of state transition
+// of given event if it's configured
+func (l *ServicesList) callHooks(event PoolEvent) {
+	var hook ServiceCallback
+	switch event.Type {
+	case EventServiceJailed:
+		hook = l.hooks.onJail
+	case EventServiceRecovered:
+		hook = l.hooks.onRecover
+	case EventServiceAdded:
+		hook = l.hooks.onAdd
+	case EventServiceRemoved:
+		hook = l.hooks.onRemove
+	}
+
+	if hook != nil {
+		hook(event.Service)
+	}
+}
+
+// queueEvent queue given lifecycle event to be published
+// once the list lock is released by flushEvents. Should
+// be called under the list lock
+func (l *ServicesList) queueEvent(event PoolEvent) {
+	l.pendingEvents = append(l.pendingEvents, event)
+}
+
+// flushEvents publish events queued under the list lock and
+// close user data released under it. Should not be called
+// under the list lock
+func (l *ServicesList) flushEvents() {
+	l.mu.Lock()
+	events := l.pendingEvents
+	closers := l.pendingClosers
+	l.pendingEvents = nil
+	l.pendingClosers = nil
+	l.mu.Unlock()
+
+	l.closeUserData(closers)
+
+	for _, event := range events {
+		l.publish(event)
+	}
+}
+
+// Subscribe return channel of all list events incl. added,
+// removed and failed healthcheck ones, events are dropped
+// for subscriber which buffer is full, so slow
+// subscriber never blocks the list. The channel is
+// closed on Unsubscribe or when the list is closed
+func (l *ServicesList) Subscribe() <-chan PoolEvent {
+	return l.events.subscribe()
+}
+
+// Unsubscribe stop delivery of events to given
+// channel returned by Subscribe and close it
+func (l *ServicesList) Unsubscribe(events <-chan PoolEvent) {
+	l.events.unsubscribe(events)
+}
+
+// Subscribe return channel of events of all shards
+func (l *ShardedServicesList) Subscribe() <-chan PoolEvent {
+	return l.shards[0].Subscribe()
+}
+
+// Unsubscribe stop delivery of events to given channel
+func (l *ShardedServicesList) Unsubscribe(events <-chan PoolEvent) {
+	l.shards[0].Unsubscribe(events)
+}
+
+// eventBus is subscribers of list events
+type eventBus struct {
+	buffer int
+
+	mu          sync.RWMutex
+	subscribers map[<-chan PoolEvent]chan PoolEvent
+	closed      bool
+}
+
+// newEventBus create event bus with given
+// buffer of every subscriber (64 by default)
+func newEventBus(buffer int) *eventBus {
+	if buffer <= 0 {
+		buffer = defaultEventBuffer
+	}
+
+	return &eventBus{
+		buffer:      buffer,
+		subscribers: make(map[<-chan PoolEvent]chan PoolEvent),
+	}
+}
+
+// subscribe add new subscriber, closed
+// channel is returned for closed bus
+func (b *eventBus) subscribe() <-chan PoolEvent {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+
+	ch := make(chan PoolEvent, b.buffer)
+	if b.closed {
+		close(ch)
+		return ch
+	}
+
+	b.subscribers[ch] = ch
+
+	return ch
+}
+
+// unsubscribe remove and close given subscriber
+func (b *eventBus) unsubscribe(events <-chan PoolEvent) {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+
+	if ch, ok := b.subscribers[events]; ok {
+		delete(b.subscribers, events)
+		close(ch)
+	}
+}
+
+// subscribed check if bus has any subscriber
+func (b *eventBus) subscribed() bool {
+	defer b.mu.RUnlock()
+	b.mu.RLock()
+
+	return len(b.subscribers) > 0
+}
+
+// publish send given event to all subscribers
+// without blocking on full buffers
+func (b *eventBus) publish(event PoolEvent) {
+	defer b.mu.RUnlock()
+	b.mu.RLock()
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log().Warn("event is dropped, subscriber buffer is full", "list", event.Pool, "event", event.Type.String())
+		}
+	}
+}
+
+// close close channels of all subscribers
+func (b *eventBus) close() {
+	defer b.mu.Unlock()
+	b.mu.Lock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+
+	for events, ch := range b.subscribers {
+		delete(b.subscribers, events)
+		close(ch)
+	}
+}
@@ -0,0 +1,164 @@
+package pool
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// nextEvent return next event of given type from given
+// channel skipping the others or fail the test on timeout
+func nextEvent(t *testing.T, events <-chan PoolEvent, typ EventType) PoolEvent {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("events channel is closed before %s event", typ)
+			}
+			if event.Type == typ {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("%s event is not received in time", typ)
+		}
+	}
+}
+
+func TestServicesListSubscribe(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+
+	events := list.Subscribe()
+	other := list.Subscribe()
+
+	srv := newSwitchableService("https://1gateway.fm")
+	list.Add(srv)
+
+	if event := nextEvent(t, events, EventServiceAdded); event.Service != srv || event.Pool != "testServicesList" {
+		t.Fatalf("unexpected added event %+v", event)
+	}
+
+	srv.down.Store(true)
+	list.HealthChecks()
+
+	if event := nextEvent(t, events, EventHealthCheckFailed); event.Service != srv || event.Err == nil {
+		t.Fatalf("unexpected healthcheck failed event %+v", event)
+	}
+	nextEvent(t, events, EventServiceJailed)
+
+	list.RemoveByID(srv.ID())
+
+	if event := nextEvent(t, events, EventServiceRemoved); event.Service != srv || event.Reason != "removed_from_jail" {
+		t.Fatalf("unexpected removed event %+v", event)
+	}
+
+	list.Unsubscribe(events)
+	if _, ok := <-events; ok {
+		t.Errorf("unsubscribed channel should be closed")
+	}
+
+	list.Close()
+	for range other {
+	}
+}
+
+func TestServicesListSubscribeSlow(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		EventBuffer:    1,
+	})
+	defer list.Close()
+
+	events := list.Subscribe()
+
+	// slow subscriber doesn't block the list
+	list.Add(newHealthyService("https://1gateway.fm"))
+	list.Add(newHealthyService("https://2gateway.fm"))
+
+	if len(list.Healthy()) != 2 || len(events) != 1 {
+		t.Errorf("expected 2 healthy services and 1 buffered event, got %d and %d", len(list.Healthy()), len(events))
+	}
+}
+
+func TestShardedServicesListSubscribe(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Shards:         4,
+	})
+	defer list.Close()
+
+	events := list.Subscribe()
+
+	added := map[string]bool{}
+	for _, addr := range []string{"https://1gateway.fm", "https://2gateway.fm", "https://3gateway.fm", "https://4gateway.fm"} {
+		srv := newHealthyService(addr)
+		list.Add(srv)
+		added[srv.ID()] = true
+	}
+
+	for range added {
+		event := nextEvent(t, events, EventServiceAdded)
+		if !added[event.Service.ID()] {
+			t.Fatalf("unexpected added service %s", event.Service.ID())
+		}
+		delete(added, event.Service.ID())
+	}
+}
+
+func TestServicesListHooks(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+	hook := func(name string) ServiceCallback {
+		return func(srv service.IService) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, name+" "+srv.Address())
+		}
+	}
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		OnJail:         hook("jail"),
+		OnRecover:      hook("recover"),
+		OnAdd:          hook("add"),
+		OnRemove:       hook("remove"),
+	})
+	defer list.Close()
+
+	srv := newSwitchableService("https://1gateway.fm")
+	list.Add(srv)
+	list.FromHealthyToJail(srv.ID())
+	list.FromJailToHealthy(srv)
+	list.RemoveByID(srv.ID())
+
+	expected := []string{
+		"add https://1gateway.fm",
+		"jail https://1gateway.fm",
+		"recover https://1gateway.fm",
+		"remove https://1gateway.fm",
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+}
@@ -0,0 +1,249 @@
+package pool
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultFairnessHalfLife      = 10 * time.Minute
+	defaultFairnessThreshold     = 0.5
+	defaultFairnessMinSelections = 100
+)
+
+// FairnessOpts is options that configure boosting of
+// underutilized services. Long-run selection share of every
+// service is tracked with exponential decay and compared to
+// its share of weight, services chronically selected less
+// than intended are boosted until they catch up
+type FairnessOpts struct {
+	HalfLife      time.Duration                      // half-life of tracked selections (10 minutes by default)
+	Threshold     float64                            // services with selection share below this fraction of weight share are boosted (0.5 by default)
+	MinSelections float64                            // decayed selections observed before boosting starts (100 by default)
+	Weight        func(srv service.IService) float64 // intended weight of service (service.Weight with weighted round-robin, equal weights otherwise)
+}
+
+// FairnessShare is long-run selection share
+// of service compared to its intended share
+type FairnessShare struct {
+	ID          string  `json:"id"`
+	Weight      float64 `json:"weight"`
+	TargetShare float64 `json:"target_share"`
+	Share       float64 `json:"share"`
+	Boosts      uint64  `json:"boosts"`
+}
+
+// fairness is decayed selections
+// tracker of healthy services
+type fairness struct {
+	opts FairnessOpts
+
+	mu      sync.Mutex
+	entries map[string]*fairnessEntry
+}
+
+// fairnessEntry is decayed selections of one service
+type fairnessEntry struct {
+	score  float64
+	last   time.Time
+	boosts uint64
+}
+
+// newFairness create selections tracker with given configuration,
+// intended shares follow weights of given balancing strategy by
+// default. Nil is returned if boosting is disabled
+func newFairness(opts *FairnessOpts, balancing Balancing) *fairness {
+	if opts == nil {
+		return nil
+	}
+
+	f := &fairness{
+		opts:    *opts,
+		entries: make(map[string]*fairnessEntry),
+	}
+	if f.opts.HalfLife <= 0 {
+		f.opts.HalfLife = defaultFairnessHalfLife
+	}
+	if f.opts.Threshold <= 0 {
+		f.opts.Threshold = defaultFairnessThreshold
+	}
+	if f.opts.MinSelections <= 0 {
+		f.opts.MinSelections = defaultFairnessMinSelections
+	}
+	if f.opts.Weight == nil && balancing == BalancingWeightedRoundRobin {
+		f.opts.Weight = func(srv service.IService) float64 { return float64(service.Weight(srv)) }
+	}
+	if f.opts.Weight == nil {
+		f.opts.Weight = func(service.IService) float64 { return 1 }
+	}
+
+	return f
+}
+
+// decayed return score of given entry decayed to given time
+func (f *fairness) decayed(entry *fairnessEntry, now time.Time) float64 {
+	if entry == nil {
+		return 0
+	}
+
+	return entry.score * math.Exp2(-float64(now.Sub(entry.last))/float64(f.opts.HalfLife))
+}
+
+// record register selection of given service
+func (f *fairness) record(srv service.IService) {
+	if f == nil || srv == nil {
+		return
+	}
+
+	defer f.mu.Unlock()
+	f.mu.Lock()
+
+	now := time.Now()
+
+	entry, ok := f.entries[srv.ID()]
+	if !ok {
+		entry = &fairnessEntry{}
+		f.entries[srv.ID()] = entry
+	}
+
+	entry.score = f.decayed(entry, now) + 1
+	entry.last = now
+}
+
+// forget remove tracked selections of service with given id
+func (f *fairness) forget(id string) {
+	if f == nil {
+		return
+	}
+
+	defer f.mu.Unlock()
+	f.mu.Lock()
+
+	delete(f.entries, id)
+}
+
+// shares return selection and target shares of given services
+func (f *fairness) shares(services []service.IService, now time.Time) []FairnessShare {
+	shares := make([]FairnessShare, len(services))
+
+	var totalScore, totalWeight float64
+	for i, srv := range services {
+		shares[i] = FairnessShare{
+			ID:     srv.ID(),
+			Weight: max(f.opts.Weight(srv), 0),
+			Share:  f.decayed(f.entries[srv.ID()], now),
+		}
+		if entry, ok := f.entries[srv.ID()]; ok {
+			shares[i].Boosts = entry.boosts
+		}
+
+		totalScore += shares[i].Share
+		totalWeight += shares[i].Weight
+	}
+
+	for i := range shares {
+		if totalScore > 0 {
+			shares[i].Share /= totalScore
+		}
+		if totalWeight > 0 {
+			shares[i].TargetShare = shares[i].Weight / totalWeight
+		}
+	}
+
+	return shares
+}
+
+// boost return healthy allowed service which selection share is the
+// furthest below its weight share if it is below configured
+// threshold. Should be called under the list lock
+func (l *ServicesList) boost(ctx context.Context) service.IService {
+	if l.fairness == nil {
+		return nil
+	}
+
+	var candidates []service.IService
+	for _, srv := range l.healthy {
+		if srv.Status() == service.StatusHealthy && l.allow(ctx, srv) {
+			candidates = append(candidates, srv)
+		}
+	}
+	if len(candidates) < 2 {
+		return nil
+	}
+
+	defer l.fairness.mu.Unlock()
+	l.fairness.mu.Lock()
+
+	now := time.Now()
+
+	var total float64
+	for _, srv := range candidates {
+		total += l.fairness.decayed(l.fairness.entries[srv.ID()], now)
+	}
+	if total < l.fairness.opts.MinSelections {
+		return nil
+	}
+
+	var (
+		boosted  service.IService
+		minRatio = l.fairness.opts.Threshold
+	)
+	for i, share := range l.fairness.shares(candidates, now) {
+		if share.TargetShare == 0 {
+			continue
+		}
+
+		if ratio := share.Share / share.TargetShare; ratio < minRatio {
+			boosted, minRatio = candidates[i], ratio
+		}
+	}
+
+	if boosted != nil {
+		if entry, ok := l.fairness.entries[boosted.ID()]; ok {
+			entry.boosts++
+		} else {
+			l.fairness.entries[boosted.ID()] = &fairnessEntry{last: now, boosts: 1}
+		}
+	}
+
+	return boosted
+}
+
+// Fairness return long-run selection shares of healthy
+// services compared to theirs intended shares, nil is
+// returned if boosting is disabled
+func (l *ServicesList) Fairness() []FairnessShare {
+	if l.fairness == nil {
+		return nil
+	}
+
+	l.mu.RLock()
+	healthy := append([]service.IService(nil), l.healthy...)
+	l.mu.RUnlock()
+
+	l.fairness.mu.Lock()
+	shares := l.fairness.shares(healthy, time.Now())
+	l.fairness.mu.Unlock()
+
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].ID < shares[j].ID
+	})
+
+	return shares
+}
+
+// Fairness return long-run selection shares of healthy
+// services of all shards, shares are relative to the shard
+func (l *ShardedServicesList) Fairness() []FairnessShare {
+	var shares []FairnessShare
+	for _, shard := range l.shards {
+		shares = append(shares, shard.Fairness()...)
+	}
+
+	return shares
+}
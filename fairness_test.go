@@ -0,0 +1,89 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestFairnessBoost(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Fairness:       &FairnessOpts{},
+	})
+
+	sticky := newMetadataService("https://1gateway.fm", map[string]string{"zone": "a"})
+	first := newMetadataService("https://2gateway.fm", map[string]string{"zone": "b"})
+	second := newMetadataService("https://3gateway.fm", map[string]string{"zone": "b"})
+	list.Add(sticky)
+	list.Add(first)
+	list.Add(second)
+
+	// affinity routing pins selections to one service
+	for i := 0; i < 200; i++ {
+		list.NextWhere("zone", "a")
+	}
+
+	selected := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		selected[list.Next().ID()]++
+	}
+
+	if selected[sticky.ID()] > 10 {
+		t.Errorf("overutilized service should not be selected while others are boosted, got %v", selected)
+	}
+	if selected[first.ID()] < 40 || selected[second.ID()] < 40 {
+		t.Errorf("underutilized services should be boosted, got %v", selected)
+	}
+
+	var boosts uint64
+	for _, share := range list.Fairness() {
+		boosts += share.Boosts
+		if share.TargetShare < 0.33 || share.TargetShare > 0.34 {
+			t.Errorf("unexpected target share %+v", share)
+		}
+	}
+	if boosts == 0 {
+		t.Errorf("boosts should be reported")
+	}
+}
+
+func TestFairnessWeights(t *testing.T) {
+	heavy := newHealthyService("https://1gateway.fm")
+	light := newHealthyService("https://2gateway.fm")
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Fairness: &FairnessOpts{
+			MinSelections: 10,
+			Threshold:     0.9,
+			Weight: func(srv service.IService) float64 {
+				if srv.ID() == heavy.ID() {
+					return 3
+				}
+				return 1
+			},
+		},
+	})
+	list.Add(heavy)
+	list.Add(light)
+
+	selected := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		selected[list.Next().ID()]++
+	}
+
+	// round-robin alone would select both services equally
+	if share := float64(selected[heavy.ID()]) / 400; share < 0.65 {
+		t.Errorf("heavy service share should converge to its weight share, got %.2f", share)
+	}
+
+	if shares := NewServicesList("testServicesList", &ServicesListOpts{}).Fairness(); shares != nil {
+		t.Errorf("fairness should be reported only when enabled, got %v", shares)
+	}
+}
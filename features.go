@@ -0,0 +1,128 @@
+package pool
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Feature is name of pool capability gated by
+// feature flag, so it could be rolled out gradually
+// and turned off at runtime without redeploying
+type Feature string
+
+// Built-in features gating risky pool behaviors. Outlier
+// ejection and gossip health are enabled unless turned off,
+// since they should be configured explicitly anyway. Hedging
+// and custom features are disabled unless turned on and
+// are checked by callers with FeatureEnabled
+const (
+	FeatureOutlierEjection Feature = "outlier_ejection" // jailing of services by passive health
+	FeatureGossipHealth    Feature = "gossip_health"    // broadcasting and applying of gossip health verdicts
+	FeatureHedging         Feature = "hedging"          // hedged requests made by callers
+)
+
+// builtinFeatures is default state of built-in features
+var builtinFeatures = map[Feature]bool{
+	FeatureOutlierEjection: true,
+	FeatureGossipHealth:    true,
+	FeatureHedging:         false,
+}
+
+// FeatureFlag is state of feature flag
+type FeatureFlag struct {
+	Name    Feature `json:"name"`
+	Enabled bool    `json:"enabled"`
+}
+
+// features is feature flags of the list
+type features struct {
+	mu    sync.RWMutex
+	flags map[Feature]bool
+}
+
+// newFeatures create feature flags with given initial state
+func newFeatures(flags map[Feature]bool) *features {
+	f := &features{flags: make(map[Feature]bool, len(builtinFeatures)+len(flags))}
+
+	for name, enabled := range builtinFeatures {
+		f.flags[name] = enabled
+	}
+	for name, enabled := range flags {
+		f.flags[name] = enabled
+	}
+
+	return f
+}
+
+// enabled check if given feature is enabled
+func (f *features) enabled(name Feature) bool {
+	defer f.mu.RUnlock()
+	f.mu.RLock()
+
+	return f.flags[name]
+}
+
+// set turn given feature on or off and
+// report if its state has changed
+func (f *features) set(name Feature, enabled bool) bool {
+	defer f.mu.Unlock()
+	f.mu.Lock()
+
+	previous, ok := f.flags[name]
+	f.flags[name] = enabled
+
+	return !ok || previous != enabled
+}
+
+// list return feature flags ordered by name
+func (f *features) list() []FeatureFlag {
+	f.mu.RLock()
+	list := make([]FeatureFlag, 0, len(f.flags))
+	for name, enabled := range f.flags {
+		list = append(list, FeatureFlag{Name: name, Enabled: enabled})
+	}
+	f.mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	return list
+}
+
+// FeatureEnabled check if given feature is enabled for the list
+func (l *ServicesList) FeatureEnabled(name Feature) bool {
+	return l.features.enabled(name)
+}
+
+// SetFeature turn given feature on or off at runtime
+func (l *ServicesList) SetFeature(name Feature, enabled bool) {
+	if !l.features.set(name, enabled) {
+		return
+	}
+
+	l.audit.record("feature_toggled", "", fmt.Sprintf("%s=%t", name, enabled))
+	l.log().Info("feature is set", "feature", string(name), "enabled", enabled)
+}
+
+// Features return feature flags of the list ordered by name
+func (l *ServicesList) Features() []FeatureFlag {
+	return l.features.list()
+}
+
+// FeatureEnabled check if given feature is enabled,
+// flags are shared by all shards
+func (l *ShardedServicesList) FeatureEnabled(name Feature) bool {
+	return l.shards[0].FeatureEnabled(name)
+}
+
+// SetFeature turn given feature on or off at runtime
+func (l *ShardedServicesList) SetFeature(name Feature, enabled bool) {
+	l.shards[0].SetFeature(name, enabled)
+}
+
+// Features return feature flags ordered by name
+func (l *ShardedServicesList) Features() []FeatureFlag {
+	return l.shards[0].Features()
+}
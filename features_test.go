@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		PassiveHealth:  &PassiveHealthOpts{ConsecutiveFailures: 1, MaxEjectionPercent: 100},
+		Features:       map[Feature]bool{FeatureOutlierEjection: false, "canary": true},
+	})
+	defer list.Close()
+
+	if !list.FeatureEnabled(FeatureGossipHealth) || list.FeatureEnabled(FeatureHedging) || !list.FeatureEnabled("canary") {
+		t.Fatalf("unexpected feature flags %v", list.Features())
+	}
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	failure := errors.New("upstream error")
+
+	list.ReportFailure(srv, failure)
+	if len(list.Jailed()) != 0 {
+		t.Fatalf("service should not be ejected while outlier ejection is turned off")
+	}
+
+	list.SetFeature(FeatureOutlierEjection, true)
+	list.ReportFailure(srv, failure)
+	if len(list.Jailed()) != 1 {
+		t.Fatalf("service should be ejected once outlier ejection is turned on")
+	}
+
+	entries := list.AuditLog()
+	if len(entries) != 1 || entries[0].Action != "feature_toggled" || entries[0].Detail != "outlier_ejection=true" {
+		t.Errorf("unexpected audit log %+v", entries)
+	}
+}
+
+func TestFeatureFlagsAdmin(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Shards:         2,
+	})
+	defer list.Close()
+
+	handler := NewAdminHandlerWithOpts(list, &AdminHandlerOpts{Unauthenticated: true})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/features/hedging", strings.NewReader(`{"enabled":true}`)))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", recorder.Code, recorder.Body)
+	}
+
+	// flags are shared by all shards
+	for _, shard := range list.(*ShardedServicesList).shards {
+		if !shard.FeatureEnabled(FeatureHedging) {
+			t.Fatalf("hedging should be enabled for all shards")
+		}
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/features/hedging", strings.NewReader(`{}`)))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("request without flag should be rejected, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/features", nil))
+
+	var flags []FeatureFlag
+	if err := json.NewDecoder(recorder.Body).Decode(&flags); err != nil {
+		t.Fatalf("unexpected decode error: %s", err)
+	}
+
+	expected := []FeatureFlag{{FeatureGossipHealth, true}, {FeatureHedging, true}, {FeatureOutlierEjection, true}}
+	if len(flags) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, flags)
+	}
+	for i := range expected {
+		if flags[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], flags[i])
+		}
+	}
+}
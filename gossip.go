@@ -0,0 +1,445 @@
+package pool
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdlog "log"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/memberlist"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultGossipRetransmitMult = 4
+	defaultGossipVerdictTTL     = time.Minute
+	gossipLeaveTimeout          = time.Second
+)
+
+// VerdictKind represent kind of health verdict
+type VerdictKind string
+
+const (
+	// VerdictJailed is verdict of service moved to the jail
+	VerdictJailed VerdictKind = "jailed"
+
+	// VerdictRecovered is verdict of service moved back to healthy
+	VerdictRecovered VerdictKind = "recovered"
+)
+
+// Verdict is health verdict of one service
+// exchanged between gateway instances
+type Verdict struct {
+	Pool    string      `json:"pool"`
+	Service string      `json:"service"`
+	Kind    VerdictKind `json:"kind"`
+	Node    string      `json:"node"`
+	Time    time.Time   `json:"time"`
+}
+
+// GossipOpts is options that needs
+// to configure Gossip instance
+type GossipOpts struct {
+	NodeName       string        // unique name of the instance in the cluster (hostname by default)
+	BindAddr       string        // address to bind gossip listeners to (0.0.0.0 by default)
+	BindPort       int           // port to bind gossip listeners to (0 for random port)
+	Join           []string      // addresses of known cluster members to join (empty to start new cluster)
+	SecretKey      []byte        // 16, 24 or 32 bytes key to encrypt and authenticate gossip (nil to disable)
+	RetransmitMult int           // verdict retransmissions multiplier (4 by default)
+	LogOutput      io.Writer     // memberlist logs output (stderr by default)
+	VerdictTTL     time.Duration // remote verdicts older than this are ignored (1 minute by default)
+	MaxClockSkew   time.Duration // tolerated clock difference with other instances, extends VerdictTTL (DefaultMaxClockSkew by default)
+
+	SigningKeyID string                       // id of the key broadcast verdicts are signed with
+	SigningKey   ed25519.PrivateKey           // key to sign broadcast verdicts (nil to broadcast unsigned verdicts)
+	TrustedKeys  map[string]ed25519.PublicKey // keys by id to verify received verdicts (nil to accept unsigned verdicts, which requires SecretKey or Insecure)
+	Insecure     bool                         // accept unsigned verdicts without SecretKey, e.g. in tests
+
+	Logger ILogger // logger of the node messages (library logger by default)
+}
+
+// verdictSigningContext is prefix of signed verdict bytes, so
+// signatures of verdicts could not be reused for other payloads
+const verdictSigningContext = "prover-pool-verdict\x00"
+
+// signedVerdict is gossip message with verdict and
+// signature of its exact json bytes if it's signed
+type signedVerdict struct {
+	Verdict   json.RawMessage `json:"verdict"`
+	KeyID     string          `json:"key_id,omitempty"`
+	Signature []byte          `json:"signature,omitempty"`
+}
+
+// Gossip exchange recent jail and recovery verdicts between
+// gateway instances over memberlist. Remote verdicts are not
+// trusted blindly, they trigger immediate local probe of the
+// service, so instances converge faster than waiting for own
+// healthchecks interval without requiring a shared store
+type Gossip struct {
+	name   string
+	logger ILogger
+
+	verdictTTL time.Duration
+	skew       time.Duration
+
+	signingKeyID string
+	signingKey   ed25519.PrivateKey
+	trustedKeys  map[string]ed25519.PublicKey
+
+	members *memberlist.Memberlist
+	queue   *memberlist.TransmitLimitedQueue
+
+	mu      sync.Mutex
+	lists   map[string]IServicesList
+	probing map[string]struct{}
+}
+
+// gossipDelegate is memberlist delegate
+// that handles verdicts messages
+type gossipDelegate struct {
+	gossip *Gossip
+}
+
+// verdictBroadcast is verdict queued for broadcast
+type verdictBroadcast struct {
+	verdict Verdict
+	msg     []byte
+}
+
+// NewGossip create new Gossip and join configured cluster members
+// if any. Received verdicts should be authenticated either by
+// trusted keys or by secret key of the cluster
+func NewGossip(opts *GossipOpts) (*Gossip, error) {
+	if opts.TrustedKeys == nil && opts.SecretKey == nil && !opts.Insecure {
+		return nil, ErrInvalidConfig{Path: "gossip", Reason: "trusted keys or secret key are required to authenticate verdicts"}
+	}
+
+	g := &Gossip{
+		verdictTTL:   opts.VerdictTTL,
+		skew:         opts.MaxClockSkew,
+		signingKeyID: opts.SigningKeyID,
+		signingKey:   opts.SigningKey,
+		trustedKeys:  opts.TrustedKeys,
+		logger:       opts.Logger,
+		lists:        make(map[string]IServicesList),
+		probing:      make(map[string]struct{}),
+	}
+	if g.verdictTTL == 0 {
+		g.verdictTTL = defaultGossipVerdictTTL
+	}
+	if g.skew == 0 {
+		g.skew = DefaultMaxClockSkew
+	}
+
+	config := memberlist.DefaultLANConfig()
+	if opts.NodeName != "" {
+		config.Name = opts.NodeName
+	}
+	if opts.BindAddr != "" {
+		config.BindAddr = opts.BindAddr
+	}
+	config.BindPort = opts.BindPort
+	config.AdvertisePort = opts.BindPort
+	config.SecretKey = opts.SecretKey
+	config.Delegate = &gossipDelegate{gossip: g}
+	if opts.LogOutput != nil {
+		config.Logger = stdlog.New(opts.LogOutput, "", stdlog.LstdFlags)
+	}
+
+	members, err := memberlist.Create(config)
+	if err != nil {
+		return nil, fmt.Errorf("create gossip member: %w", err)
+	}
+
+	retransmitMult := opts.RetransmitMult
+	if retransmitMult == 0 {
+		retransmitMult = defaultGossipRetransmitMult
+	}
+
+	g.name = config.Name
+	g.members = members
+	g.queue = &memberlist.TransmitLimitedQueue{
+		NumNodes:       members.NumMembers,
+		RetransmitMult: retransmitMult,
+	}
+
+	if len(opts.Join) != 0 {
+		if _, err := members.Join(opts.Join); err != nil {
+			_ = members.Shutdown()
+			return nil, fmt.Errorf("join gossip cluster: %w", err)
+		}
+	}
+
+	g.log().Info("gossip node started", "members", members.NumMembers())
+
+	return g, nil
+}
+
+// Attach subscribe given services list to remote
+// verdicts about services of the pool with given name
+func (g *Gossip) Attach(name string, list IServicesList) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lists[name] = list
+}
+
+// OnEvent broadcast jail and recovery events as verdicts,
+// it could be used as services list OnEvent handler
+func (g *Gossip) OnEvent(event PoolEvent) {
+	var kind VerdictKind
+	switch event.Type {
+	case EventServiceJailed:
+		kind = VerdictJailed
+	case EventServiceRecovered:
+		kind = VerdictRecovered
+	default:
+		return
+	}
+
+	g.mu.Lock()
+	list, ok := g.lists[event.Pool]
+	g.mu.Unlock()
+
+	if ok && !list.FeatureEnabled(FeatureGossipHealth) {
+		return
+	}
+
+	g.Broadcast(Verdict{
+		Pool:    event.Pool,
+		Service: event.Service.ID(),
+		Kind:    kind,
+		Time:    event.Time,
+	})
+}
+
+// Broadcast queue given verdict for
+// broadcasting to the cluster members
+func (g *Gossip) Broadcast(verdict Verdict) {
+	verdict.Node = g.name
+	if verdict.Time.IsZero() {
+		verdict.Time = time.Now()
+	}
+
+	msg, err := g.sign(verdict)
+	if err != nil {
+		g.log().Warn("marshal gossip verdict error", "list", verdict.Pool, "service", verdict.Service, "error", err)
+		return
+	}
+
+	g.queue.QueueBroadcast(&verdictBroadcast{verdict: verdict, msg: msg})
+}
+
+// sign return gossip message with given verdict
+// signed with the signing key if it's configured
+func (g *Gossip) sign(verdict Verdict) ([]byte, error) {
+	raw, err := json.Marshal(verdict)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := signedVerdict{Verdict: raw}
+	if g.signingKey != nil {
+		signed.KeyID = g.signingKeyID
+		signed.Signature = ed25519.Sign(g.signingKey, append([]byte(verdictSigningContext), raw...))
+	}
+
+	return json.Marshal(&signed)
+}
+
+// verify return verdict of given gossip message, its
+// signature is verified if trusted keys are configured
+func (g *Gossip) verify(msg []byte) (Verdict, error) {
+	var (
+		signed  signedVerdict
+		verdict Verdict
+	)
+
+	if err := json.Unmarshal(msg, &signed); err != nil {
+		return verdict, fmt.Errorf("unmarshal gossip message: %w", err)
+	}
+
+	if g.trustedKeys != nil {
+		key, ok := g.trustedKeys[signed.KeyID]
+		if !ok {
+			return verdict, ErrInvalidSignature{KeyID: signed.KeyID, Reason: "key is not trusted"}
+		}
+
+		if !ed25519.Verify(key, append([]byte(verdictSigningContext), signed.Verdict...), signed.Signature) {
+			return verdict, ErrInvalidSignature{KeyID: signed.KeyID, Reason: "signature mismatch"}
+		}
+	}
+
+	if err := json.Unmarshal(signed.Verdict, &verdict); err != nil {
+		return verdict, fmt.Errorf("unmarshal gossip verdict: %w", err)
+	}
+
+	return verdict, nil
+}
+
+// Members return names of alive cluster members
+func (g *Gossip) Members() []string {
+	members := g.members.Members()
+
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		names = append(names, member.Name)
+	}
+
+	return names
+}
+
+// Close leave the cluster and stop gossip
+func (g *Gossip) Close() error {
+	if err := g.members.Leave(gossipLeaveTimeout); err != nil {
+		g.log().Warn("leave gossip cluster error", "error", err)
+	}
+
+	return g.members.Shutdown()
+}
+
+// apply probe service of given remote verdict
+// and move it if local probe confirms the verdict
+func (g *Gossip) apply(verdict Verdict) {
+	if verdict.Node == g.name {
+		return
+	}
+
+	// verdict time is supplied by remote clock
+	expired, err := isRemoteExpired(verdict.Time, time.Now(), g.verdictTTL, g.skew)
+	if err != nil {
+		g.log().Warn("verdict is ignored", "list", verdict.Pool, "service", verdict.Service, "from", verdict.Node, "error", err)
+		return
+	}
+	if expired {
+		g.log().Info("stale verdict is ignored", "list", verdict.Pool, "service", verdict.Service, "from", verdict.Node)
+		return
+	}
+
+	key := verdict.Pool + "/" + verdict.Service
+
+	g.mu.Lock()
+	list, ok := g.lists[verdict.Pool]
+	_, probing := g.probing[key]
+	if ok && !probing {
+		g.probing[key] = struct{}{}
+	}
+	g.mu.Unlock()
+
+	if !ok || probing {
+		return
+	}
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.probing, key)
+		g.mu.Unlock()
+	}()
+
+	if !list.FeatureEnabled(FeatureGossipHealth) {
+		return
+	}
+
+	switch verdict.Kind {
+	case VerdictJailed:
+		srv := findService(list.Healthy(), verdict.Service)
+		if srv == nil || list.CheckHealth(context.Background(), srv) == nil {
+			return
+		}
+
+		listLogger(list).Warn("jail verdict is confirmed", "service", verdict.Service, "from", verdict.Node)
+
+		list.FromHealthyToJail(srv.ID())
+		goLabeled(verdict.Pool, taskTryUp, func() {
+			list.TryUpService(srv, 0)
+		})
+	case VerdictRecovered:
+		srv, ok := list.Jailed()[verdict.Service]
+		if !ok || list.CheckHealth(context.Background(), srv) != nil {
+			return
+		}
+
+		listLogger(list).Info("recovery verdict is confirmed", "service", verdict.Service, "from", verdict.Node)
+
+		list.FromJailToHealthy(srv)
+	}
+}
+
+// log return logger of the node, given with options or
+// set with SetLogger, adding the node name to messages
+func (g *Gossip) log() ILogger {
+	logger := g.logger
+	if logger == nil {
+		logger = log()
+	}
+
+	return logger.With("node", g.name)
+}
+
+// findService return service with given id from given slice
+func findService(services []service.IService, id string) service.IService {
+	for _, srv := range services {
+		if srv.ID() == id {
+			return srv
+		}
+	}
+
+	return nil
+}
+
+// NodeMeta return empty node metadata
+func (d *gossipDelegate) NodeMeta(int) []byte {
+	return nil
+}
+
+// NotifyMsg handle verdict received from the cluster,
+// verdicts which could not be verified are dropped
+func (d *gossipDelegate) NotifyMsg(msg []byte) {
+	verdict, err := d.gossip.verify(msg)
+	if err != nil {
+		d.gossip.log().Warn("gossip verdict is dropped", "error", err)
+		return
+	}
+
+	goLabeled(verdict.Pool, taskGossip, func() {
+		d.gossip.apply(verdict)
+	})
+}
+
+// GetBroadcasts return queued verdicts to broadcast
+func (d *gossipDelegate) GetBroadcasts(overhead, limit int) [][]byte {
+	return d.gossip.queue.GetBroadcasts(overhead, limit)
+}
+
+// LocalState return empty state, verdicts
+// are not exchanged on push/pull sync
+func (d *gossipDelegate) LocalState(bool) []byte {
+	return nil
+}
+
+// MergeRemoteState ignore remote state
+func (d *gossipDelegate) MergeRemoteState([]byte, bool) {}
+
+// Invalidates check if given broadcast is
+// older verdict about the same service
+func (b *verdictBroadcast) Invalidates(other memberlist.Broadcast) bool {
+	o, ok := other.(*verdictBroadcast)
+	if !ok {
+		return false
+	}
+
+	return o.verdict.Pool == b.verdict.Pool && o.verdict.Service == b.verdict.Service
+}
+
+// Message return encoded verdict
+func (b *verdictBroadcast) Message() []byte {
+	return b.msg
+}
+
+// Finished is called when verdict is no longer broadcast
+func (b *verdictBroadcast) Finished() {}
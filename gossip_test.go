@@ -0,0 +1,157 @@
+package pool
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestGossipVerdicts(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unexpected key generation error: %s", err)
+	}
+	trusted := map[string]ed25519.PublicKey{"gen-1": public}
+
+	first, err := NewGossip(&GossipOpts{
+		NodeName:     "first",
+		BindAddr:     "127.0.0.1",
+		LogOutput:    io.Discard,
+		SigningKeyID: "gen-1",
+		SigningKey:   private,
+		TrustedKeys:  trusted,
+	})
+	if err != nil {
+		t.Fatalf("unexpected gossip error: %s", err)
+	}
+	defer first.Close()
+
+	second, err := NewGossip(&GossipOpts{
+		NodeName:     "second",
+		BindAddr:     "127.0.0.1",
+		Join:         []string{first.members.LocalNode().Address()},
+		LogOutput:    io.Discard,
+		SigningKeyID: "gen-1",
+		SigningKey:   private,
+		TrustedKeys:  trusted,
+	})
+	if err != nil {
+		t.Fatalf("unexpected gossip join error: %s", err)
+	}
+	defer second.Close()
+
+	if len(first.Members()) != 2 {
+		t.Fatalf("expected 2 gossip members, got %v", first.Members())
+	}
+
+	newList := func(gossip *Gossip) IServicesList {
+		list := NewServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  time.Minute,
+			ChecksInterval: time.Minute,
+			OnEvent:        gossip.OnEvent,
+		})
+		gossip.Attach("testServicesList", list)
+
+		return list
+	}
+
+	firstList, secondList := newList(first), newList(second)
+	defer firstList.Close()
+	defer secondList.Close()
+
+	srv := newSwitchableService("https://1gateway.fm")
+	firstList.Add(srv)
+	secondList.Add(srv)
+
+	waitFor := func(condition func() bool) bool {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(50 * time.Millisecond) {
+			if condition() {
+				return true
+			}
+		}
+		return false
+	}
+
+	srv.down.Store(true)
+	firstList.FromHealthyToJail(srv.ID())
+
+	if !waitFor(func() bool { _, ok := secondList.Jailed()[srv.ID()]; return ok }) {
+		t.Fatalf("jail verdict should be confirmed by the second instance")
+	}
+
+	srv.down.Store(false)
+	firstList.FromJailToHealthy(srv)
+
+	if !waitFor(func() bool { return len(secondList.Healthy()) == 1 }) {
+		t.Fatalf("recovery verdict should be confirmed by the second instance")
+	}
+}
+
+func TestGossipVerdictNotConfirmed(t *testing.T) {
+	gossip := &Gossip{
+		name:    "first",
+		lists:   make(map[string]IServicesList),
+		probing: make(map[string]struct{}),
+	}
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Minute,
+		ChecksInterval: time.Minute,
+	})
+	defer list.Close()
+	gossip.Attach("testServicesList", list)
+
+	srv := newSwitchableService("https://1gateway.fm")
+	list.Add(srv)
+
+	gossip.apply(Verdict{Pool: "testServicesList", Service: srv.ID(), Kind: VerdictJailed, Node: "second"})
+
+	if len(list.Healthy()) != 1 {
+		t.Errorf("jail verdict should not be applied when local probe succeeds")
+	}
+}
+
+func TestGossipVerdictSignature(t *testing.T) {
+	if _, err := NewGossip(&GossipOpts{NodeName: "first", BindAddr: "127.0.0.1", LogOutput: io.Discard}); !errors.As(err, &ErrInvalidConfig{}) {
+		t.Fatalf("gossip without trusted keys or secret key should be refused, got %v", err)
+	}
+
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unexpected key generation error: %s", err)
+	}
+	_, rogue, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unexpected key generation error: %s", err)
+	}
+
+	receiver := &Gossip{trustedKeys: map[string]ed25519.PublicKey{"gen-1": public}}
+	verdict := Verdict{Pool: "testServicesList", Service: "id", Kind: VerdictJailed, Node: "second"}
+
+	for name, sender := range map[string]*Gossip{
+		"unsigned":  {},
+		"untrusted": {signingKeyID: "gen-1", signingKey: rogue},
+	} {
+		msg, err := sender.sign(verdict)
+		if err != nil {
+			t.Fatalf("unexpected sign error: %s", err)
+		}
+
+		if _, err := receiver.verify(msg); !errors.As(err, &ErrInvalidSignature{}) {
+			t.Errorf("%s verdict should be dropped, got %v", name, err)
+		}
+	}
+
+	msg, err := (&Gossip{signingKeyID: "gen-1", signingKey: private}).sign(verdict)
+	if err != nil {
+		t.Fatalf("unexpected sign error: %s", err)
+	}
+
+	if got, err := receiver.verify(msg); err != nil || got.Service != verdict.Service {
+		t.Errorf("signed verdict should be accepted, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,216 @@
+package pool
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/gateway-fm/prover-pool-lib/prover/client"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const defaultGRPCHealthTimeout = 5 * time.Second
+
+// GRPCServingStatus is serving status of
+// grpc.health.v1.HealthCheckResponse
+type GRPCServingStatus int
+
+const (
+	GRPCStatusUnknown        GRPCServingStatus = iota // status is not known
+	GRPCStatusServing                                 // service is serving requests
+	GRPCStatusNotServing                              // service is not serving requests
+	GRPCStatusServiceUnknown                          // checked service is not registered on the server
+)
+
+// String return serving status name
+func (s GRPCServingStatus) String() string {
+	switch s {
+	case GRPCStatusServing:
+		return "SERVING"
+	case GRPCStatusNotServing:
+		return "NOT_SERVING"
+	case GRPCStatusServiceUnknown:
+		return "SERVICE_UNKNOWN"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// GRPCHealthCheckerOpts is options that configure checks
+// with standard grpc.health.v1.Health/Check protocol
+type GRPCHealthCheckerOpts struct {
+	Service  string         // name of checked grpc service (empty to check overall server health)
+	Timeout  time.Duration  // timeout of one check (5s by default)
+	TLS      *tls.Config    // tls configuration of grpcs:// and https:// addresses (system roots by default)
+	Fallback IHealthChecker // checker of services with other transports (theirs own HealthCheck by default)
+
+	Propagation *client.Propagation // trace context and baggage of the check injected into request metadata (W3C trace context and baggage by default)
+}
+
+// GRPCHealthChecker is IHealthChecker that call standard
+// grpc.health.v1.Health/Check method of services with
+// TransportGrpc and require SERVING status. Connections
+// to services are kept open between checks until Close
+type GRPCHealthChecker struct {
+	opts GRPCHealthCheckerOpts
+
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewGRPCHealthChecker create new GRPCHealthChecker
+// with given configuration
+func NewGRPCHealthChecker(opts *GRPCHealthCheckerOpts) *GRPCHealthChecker {
+	c := &GRPCHealthChecker{
+		opts:  *opts,
+		conns: make(map[string]*grpc.ClientConn),
+	}
+
+	if c.opts.Timeout <= 0 {
+		c.opts.Timeout = defaultGRPCHealthTimeout
+	}
+	if c.opts.Propagation == nil {
+		c.opts.Propagation, _ = client.NewPropagation(nil, nil)
+	}
+
+	return c
+}
+
+// Check call health check method of given grpc service, services
+// with other transports are checked with configured fallback
+func (c *GRPCHealthChecker) Check(ctx context.Context, srv service.IService) error {
+	if service.TransportOf(srv) != service.TransportGrpc {
+		if c.opts.Fallback != nil {
+			return c.opts.Fallback.Check(ctx, srv)
+		}
+
+		return service.HealthCheckContext(ctx, srv)
+	}
+
+	status, err := c.Status(ctx, srv.Address())
+	if err != nil {
+		return err
+	}
+	if status != GRPCStatusServing {
+		return ErrNotServing{Address: srv.Address(), Status: status.String()}
+	}
+
+	return nil
+}
+
+// Status return serving status reported by
+// grpc server with given address
+func (c *GRPCHealthChecker) Status(ctx context.Context, address string) (GRPCServingStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	conn, err := c.conn(address)
+	if err != nil {
+		return GRPCStatusUnknown, err
+	}
+
+	md := metadata.MD{}
+	c.opts.Propagation.Inject(ctx, metadataCarrier(md))
+
+	resp, err := healthpb.NewHealthClient(conn).Check(metadata.NewOutgoingContext(ctx, md), &healthpb.HealthCheckRequest{Service: c.opts.Service})
+	if err != nil {
+		if s, ok := status.FromError(err); ok {
+			return GRPCStatusUnknown, ErrUpstreamStatus{Address: address, GRPCStatus: int(s.Code())}
+		}
+
+		return GRPCStatusUnknown, fmt.Errorf("grpc health check of %s: %w", address, err)
+	}
+
+	return GRPCServingStatus(resp.GetStatus()), nil
+}
+
+// Close close connections to all checked services
+func (c *GRPCHealthChecker) Close() {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	for target, conn := range c.conns {
+		_ = conn.Close()
+		delete(c.conns, target)
+	}
+}
+
+// conn return connection to grpc server with given
+// address, it is created on the first check
+func (c *GRPCHealthChecker) conn(address string) (*grpc.ClientConn, error) {
+	target, err := serviceURL(address, "http")
+	if err != nil {
+		return nil, err
+	}
+
+	var creds credentials.TransportCredentials
+	switch target.Scheme {
+	case "http":
+		creds = insecure.NewCredentials()
+	case "https":
+		creds = credentials.NewTLS(c.opts.TLS)
+	default:
+		return nil, fmt.Errorf("unsupported scheme of grpc address %q", address)
+	}
+
+	key := target.Scheme + "://" + target.Host
+
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	if conn, ok := c.conns[key]; ok {
+		return conn, nil
+	}
+
+	// dial is not blocking, connection is
+	// established by the first check
+	conn, err := grpc.Dial(target.Host, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("dial grpc service %s: %w", address, err)
+	}
+	c.conns[key] = conn
+
+	return conn, nil
+}
+
+// metadataCarrier is propagation.TextMapCarrier
+// of outgoing grpc request metadata
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier(nil)
+
+// Get return the first value of given key
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// Set set value of given key
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys return all keys of the metadata
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, strings.ToLower(key))
+	}
+
+	return keys
+}
@@ -0,0 +1,154 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// grpcHealthServer is grpc server implementing grpc.health.v1
+// for "prover" service, it records traceparent of last check
+type grpcHealthServer struct {
+	*health.Server
+	address     string
+	traceparent chan string
+}
+
+// newGRPCHealthServer create cleartext grpc server
+// implementing grpc.health.v1.Health/Check with
+// "prover" service in SERVING status
+func newGRPCHealthServer(t *testing.T) *grpcHealthServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected listen error: %s", err)
+	}
+
+	h := &grpcHealthServer{
+		Server:      health.NewServer(),
+		address:     listener.Addr().String(),
+		traceparent: make(chan string, 16),
+	}
+	h.SetServingStatus("prover", healthpb.HealthCheckResponse_SERVING)
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get("traceparent"); len(values) > 0 {
+			h.traceparent <- values[0]
+		}
+		return handler(ctx, req)
+	}))
+	healthpb.RegisterHealthServer(server, h)
+
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return h
+}
+
+func TestGRPCHealthChecker(t *testing.T) {
+	server := newGRPCHealthServer(t)
+	address := "grpc://" + server.address
+
+	checker := NewGRPCHealthChecker(&GRPCHealthCheckerOpts{Service: "prover", Timeout: time.Second})
+	defer checker.Close()
+
+	prover := service.NewService(address, "", nil, 0)
+	if err := checker.Check(context.Background(), prover); err != nil {
+		t.Errorf("unexpected check error of serving service: %s", err)
+	}
+
+	server.SetServingStatus("prover", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	var notServing ErrNotServing
+	if err := checker.Check(context.Background(), prover); !errors.As(err, &notServing) || notServing.Status != "NOT_SERVING" {
+		t.Errorf("expected not serving error, got %v", err)
+	}
+
+	// transport is read from metadata of services
+	// which addresses have no grpc scheme
+	server.SetServingStatus("prover", healthpb.HealthCheckResponse_SERVING)
+
+	plain := service.NewService(server.address, "", nil, 0).(*service.BaseService)
+	plain.SetMetadata(map[string]string{service.TransportMetadataKey: "grpc"})
+
+	unknown := NewGRPCHealthChecker(&GRPCHealthCheckerOpts{Service: "verifier"})
+	defer unknown.Close()
+
+	var upstream ErrUpstreamStatus
+	if err := unknown.Check(context.Background(), plain); !errors.As(err, &upstream) || upstream.GRPCStatus != 5 {
+		t.Errorf("expected grpc not found status, got %v", err)
+	}
+
+	// services with other transports are checked with fallback
+	fallback := NewGRPCHealthChecker(&GRPCHealthCheckerOpts{
+		Fallback: HealthCheckerFunc(func(context.Context, service.IService) error {
+			return io.ErrUnexpectedEOF
+		}),
+	})
+	if err := fallback.Check(context.Background(), service.NewService(server.address, "", nil, 0)); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected fallback check error, got %v", err)
+	}
+}
+
+func TestServicesListGRPCHealthChecker(t *testing.T) {
+	server := newGRPCHealthServer(t)
+
+	checker := NewGRPCHealthChecker(&GRPCHealthCheckerOpts{Service: "prover"})
+	defer checker.Close()
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		HealthChecker:  checker,
+	})
+	defer list.Close()
+
+	list.Add(service.NewService("grpc://"+server.address, "", nil, 0))
+	if len(list.Healthy()) != 1 {
+		t.Fatalf("serving grpc service should be healthy")
+	}
+
+	server.SetServingStatus("prover", healthpb.HealthCheckResponse_NOT_SERVING)
+	list.HealthChecks()
+
+	waitFor(t, func() bool {
+		return len(list.Jailed()) == 1
+	})
+}
+
+func TestGRPCHealthCheckerTraceContext(t *testing.T) {
+	server := newGRPCHealthServer(t)
+
+	checker := NewGRPCHealthChecker(&GRPCHealthCheckerOpts{Service: "prover", Timeout: time.Second})
+	defer checker.Close()
+
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	if err := checker.Check(ctx, service.NewService("grpc://"+server.address, "", nil, 0)); err != nil {
+		t.Fatalf("unexpected check error: %s", err)
+	}
+
+	if got := <-server.traceparent; !strings.Contains(got, traceID.String()) {
+		t.Errorf("trace context of the check is not propagated: %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package pool
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+const defaultHAProxyTimeout = 5 * time.Second
+
+// HAProxyExporterOpts is options that configure
+// export of verdicts to HAProxy runtime API
+type HAProxyExporterOpts struct {
+	Network string                       // network of runtime api, "unix" or "tcp" ("unix" by default)
+	Address string                       // runtime api socket path or address, e.g. /var/run/haproxy.sock
+	Backend func(pool string) string     // haproxy backend of pool servers (pool name by default)
+	Server  func(v HealthVerdict) string // haproxy server name of backend, empty to skip it (node name or id by default)
+	Timeout time.Duration                // timeout of one export (5s by default)
+}
+
+// HAProxyExporter is IHealthExporter that set state of HAProxy
+// servers with runtime API: healthy backends are set ready,
+// draining ones drain and unhealthy ones maint
+type HAProxyExporter struct {
+	opts HAProxyExporterOpts
+}
+
+// NewHAProxyExporter create new HAProxyExporter with given configuration
+func NewHAProxyExporter(opts *HAProxyExporterOpts) *HAProxyExporter {
+	e := &HAProxyExporter{opts: *opts}
+
+	if e.opts.Network == "" {
+		e.opts.Network = "unix"
+	}
+	if e.opts.Backend == nil {
+		e.opts.Backend = func(pool string) string { return pool }
+	}
+	if e.opts.Server == nil {
+		e.opts.Server = func(v HealthVerdict) string {
+			if v.NodeName != "" {
+				return v.NodeName
+			}
+			return v.ID
+		}
+	}
+	if e.opts.Timeout <= 0 {
+		e.opts.Timeout = defaultHAProxyTimeout
+	}
+
+	return e
+}
+
+// Export set state of servers of given pool backend, all
+// commands are sent in one runtime api request
+func (e *HAProxyExporter) Export(ctx context.Context, pool string, verdicts []HealthVerdict) error {
+	backend := e.opts.Backend(pool)
+
+	var commands []string
+	for _, verdict := range verdicts {
+		server := e.opts.Server(verdict)
+		if server == "" {
+			continue
+		}
+
+		commands = append(commands, fmt.Sprintf("set server %s/%s state %s", backend, server, haproxyState(verdict.State)))
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.opts.Timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, e.opts.Network, e.opts.Address)
+	if err != nil {
+		return fmt.Errorf("dial haproxy runtime api: %w", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write([]byte(strings.Join(commands, ";") + "\n")); err != nil {
+		return fmt.Errorf("write haproxy commands: %w", err)
+	}
+
+	// successful commands respond with empty lines,
+	// others with messages, e.g. "No such server."
+	var errs []error
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			errs = append(errs, ErrHAProxyCommand{Backend: backend, Message: line})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("read haproxy response: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// haproxyState return haproxy server state of given backend state
+func haproxyState(state BackendState) string {
+	switch state {
+	case BackendHealthy:
+		return "ready"
+	case BackendDraining:
+		return "drain"
+	default:
+		return "maint"
+	}
+}
@@ -0,0 +1,156 @@
+package pool
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const defaultHashReplicas = 100
+
+// ringPoint is virtual node of service on hash ring
+type ringPoint struct {
+	hash uint64
+	srv  service.IService
+}
+
+// hashRing is consistent hash ring of healthy services with
+// virtual nodes used by NextForKey. It is rebuilt lazily on
+// the first lookup after membership generation change
+type hashRing struct {
+	mu sync.RWMutex
+
+	replicas   int
+	generation uint64
+	built      bool
+
+	points []ringPoint // virtual nodes sorted by hash
+}
+
+// newHashRing create hash ring with given
+// number of virtual nodes of every service
+func newHashRing(replicas int) *hashRing {
+	if replicas <= 0 {
+		replicas = defaultHashReplicas
+	}
+
+	return &hashRing{replicas: replicas}
+}
+
+// ringHash return hash of given key on the ring
+func ringHash(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	// fnv hashes of keys differing in last bytes are close,
+	// so they are mixed to spread virtual nodes evenly
+	sum := h.Sum64()
+	sum ^= sum >> 33
+	sum *= 0xff51afd7ed558ccd
+	sum ^= sum >> 33
+	sum *= 0xc4ceb9fe1a85ec53
+	sum ^= sum >> 33
+
+	return sum
+}
+
+// lookup return the first service clockwise from given key
+// that fits, the ring is rebuilt from given healthy services
+// if given generation differs from the ring one
+func (r *hashRing) lookup(key string, generation uint64, healthy []service.IService, fits func(service.IService) bool) service.IService {
+	r.mu.RLock()
+	if !r.built || r.generation != generation {
+		r.mu.RUnlock()
+		r.rebuild(generation, healthy)
+		r.mu.RLock()
+	}
+	defer r.mu.RUnlock()
+
+	if len(r.points) == 0 {
+		return nil
+	}
+
+	hash := ringHash(key)
+	start := sort.Search(len(r.points), func(i int) bool {
+		return r.points[i].hash >= hash
+	})
+
+	checked := make(map[string]struct{})
+	for i := 0; i < len(r.points) && len(checked) < len(healthy); i++ {
+		srv := r.points[(start+i)%len(r.points)].srv
+		if _, ok := checked[srv.ID()]; ok {
+			continue
+		}
+		checked[srv.ID()] = struct{}{}
+
+		if fits(srv) {
+			return srv
+		}
+	}
+
+	return nil
+}
+
+// rebuild ring of given generation from given healthy services
+func (r *hashRing) rebuild(generation uint64, healthy []service.IService) {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	if r.built && r.generation == generation {
+		return
+	}
+
+	points := make([]ringPoint, 0, len(healthy)*r.replicas)
+	for _, srv := range healthy {
+		for i := 0; i < r.replicas; i++ {
+			points = append(points, ringPoint{hash: ringHash(srv.ID() + "#" + strconv.Itoa(i)), srv: srv})
+		}
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	r.points = points
+	r.generation = generation
+	r.built = true
+}
+
+// NextForKey returns healthy service owning given key on
+// consistent hash ring, so requests for the same key, e.g.
+// batch or block, go to the same service while it's healthy
+// and only keys of removed services move. Services that are
+// not allowed for the request are skipped clockwise
+func (l *ServicesList) NextForKey(key string) (selected service.IService) {
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	defer func() {
+		l.recordSelection(selected)
+	}()
+
+	ctx := WithHashKey(context.Background(), key)
+
+	return l.ring.lookup(key, l.Generation(), l.healthy, func(srv service.IService) bool {
+		return srv.Status() == service.StatusHealthy && l.allow(ctx, srv)
+	})
+}
+
+// NextForKey returns healthy service owning given key on
+// hash ring of shard picked by the key, following shards
+// are tried if the shard has no services for the key
+func (l *ShardedServicesList) NextForKey(key string) service.IService {
+	start := l.shardIndex(key)
+
+	for i := 0; i < len(l.shards); i++ {
+		if srv := l.shards[(start+i)%len(l.shards)].NextForKey(key); srv != nil {
+			return srv
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestNextForKey(t *testing.T) {
+	for _, shards := range []int{1, 3} {
+		t.Run(fmt.Sprintf("shards %d", shards), func(t *testing.T) {
+			list := NewServicesList("testServicesList", &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  time.Hour,
+				ChecksInterval: time.Hour,
+				Shards:         shards,
+			})
+			defer list.Close()
+
+			services := map[string]service.IService{}
+			for i := 0; i < 5; i++ {
+				srv := newHealthyService(fmt.Sprintf("https://%dgateway.fm", i))
+				services[srv.ID()] = srv
+				list.Add(srv)
+			}
+
+			owners := map[string]service.IService{}
+			for i := 0; i < 200; i++ {
+				key := fmt.Sprintf("batch-%d", i)
+				owners[key] = list.NextForKey(key)
+
+				if owners[key] == nil || list.NextForKey(key) != owners[key] {
+					t.Fatalf("key %s should be routed to the same service", key)
+				}
+			}
+
+			var removed service.IService
+			for _, srv := range services {
+				removed = srv
+				break
+			}
+			list.FromHealthyToJail(removed.ID())
+
+			for key, owner := range owners {
+				srv := list.NextForKey(key)
+				if srv == nil || srv == removed {
+					t.Fatalf("key %s should be routed to healthy service, got %v", key, srv)
+				}
+				if owner != removed && srv != owner {
+					t.Errorf("key %s of healthy service should not move", key)
+				}
+			}
+		})
+	}
+}
+
+func TestHashRingBalance(t *testing.T) {
+	var healthy []service.IService
+	for i := 0; i < 4; i++ {
+		healthy = append(healthy, newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+
+	ring := newHashRing(0)
+	fits := func(service.IService) bool { return true }
+
+	counts := map[service.IService]int{}
+	for i := 0; i < 4000; i++ {
+		counts[ring.lookup(fmt.Sprintf("block-%d", i), 1, healthy, fits)]++
+	}
+
+	for _, srv := range healthy {
+		if counts[srv] < 600 || counts[srv] > 1400 {
+			t.Errorf("keys should be spread evenly, %s owns %d of 4000", srv.Address(), counts[srv])
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// IHealthChecker check health of list members instead of
+// theirs own HealthCheck, e.g. protocol specific check of
+// prover status endpoint, so plain services could be used
+// without writing full IService implementation
+type IHealthChecker interface {
+	// Check check health of given service, non-nil
+	// error moves the service to the jail
+	Check(ctx context.Context, srv service.IService) error
+}
+
+// HealthCheckerFunc is function used as IHealthChecker
+type HealthCheckerFunc func(ctx context.Context, srv service.IService) error
+
+// Check call the function with given service
+func (f HealthCheckerFunc) Check(ctx context.Context, srv service.IService) error {
+	return f(ctx, srv)
+}
+
+// CheckHealth check health of given service with configured
+// checker or by its own HealthCheck if checker is not configured.
+// The check is interrupted once given context is done, check
+// timeout passes or the list is closed
+func (l *ServicesList) CheckHealth(ctx context.Context, srv service.IService) error {
+	ctx, cancel := l.withStop(ctx)
+	defer cancel()
+
+	ctx, cancelTimeout := context.WithTimeout(ctx, l.timeouts.Check)
+	defer cancelTimeout()
+
+	ctx, span := l.tracer.Start(ctx, spanHealthCheck, trace.WithAttributes(attribute.String(attrPool, l.serviceName)))
+	span.SetAttributes(serviceAttributes(srv)...)
+
+	start := time.Now()
+
+	var err error
+	if l.healthChecker != nil {
+		err = l.healthChecker.Check(ctx, srv)
+	} else {
+		err = service.HealthCheckContext(ctx, srv)
+	}
+
+	duration := time.Since(start)
+
+	l.metrics.observeCheck(l.serviceName, srv, duration, err)
+	endSpan(span, err)
+
+	// failed checks are mostly timeouts which
+	// say nothing about latency of the service
+	if s := l.stats.Get(srv.ID()); s != nil && err == nil {
+		s.ObserveCheck(duration)
+	}
+
+	if err != nil {
+		l.publish(PoolEvent{Type: EventHealthCheckFailed, Service: srv, Err: err})
+	}
+
+	return err
+}
+
+// CheckHealth check health of given service
+// with checker of the shard it belongs to
+func (l *ShardedServicesList) CheckHealth(ctx context.Context, srv service.IService) error {
+	return l.shard(srv.ID()).CheckHealth(ctx, srv)
+}
@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServicesListHealthChecker(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]bool{"ready": ready.Load()})
+	}))
+	defer server.Close()
+
+	// prover status endpoint is checked without
+	// custom service implementation
+	checker := HealthCheckerFunc(func(ctx context.Context, srv service.IService) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.Address()+"/status", nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		var status struct {
+			Ready bool `json:"ready"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			return err
+		}
+		if !status.Ready {
+			return fmt.Errorf("prover %s is not ready", srv.Address())
+		}
+
+		return nil
+	})
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: 1 * time.Second,
+		HealthChecker:  checker,
+	})
+	defer list.Close()
+
+	prover := service.NewService(server.URL, "", nil, 0)
+	list.Add(prover)
+
+	if len(list.Healthy()) != 1 {
+		t.Fatalf("service passing custom check should be healthy")
+	}
+
+	ready.Store(false)
+	list.HealthChecks()
+
+	waitFor(t, func() bool {
+		return len(list.Jailed()) == 1
+	})
+
+	if err := list.CheckHealth(context.Background(), prover); err == nil {
+		t.Errorf("custom check should be used instead of service healthcheck")
+	}
+}
@@ -0,0 +1,159 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+const defaultHealthExportInterval = 5 * time.Second
+
+// BackendState is health verdict of pool
+// backend exported to external load balancers
+type BackendState string
+
+const (
+	// BackendHealthy is means that backend
+	// is healthy and takes new requests
+	BackendHealthy BackendState = "healthy"
+
+	// BackendDraining is means that backend is alive but
+	// should not take new requests, e.g. cordoned service,
+	// standby spare or service of draining pool
+	BackendDraining BackendState = "draining"
+
+	// BackendUnhealthy is means that backend is jailed
+	// or waits for review and takes no requests
+	BackendUnhealthy BackendState = "unhealthy"
+)
+
+// HealthVerdict is health verdict of one pool backend
+type HealthVerdict struct {
+	ID       string       `json:"id"`
+	Address  string       `json:"address"`
+	NodeName string       `json:"node_name"`
+	State    BackendState `json:"state"`
+	Weight   int          `json:"weight"`
+	Priority int          `json:"priority,omitempty"`
+}
+
+// IHealthExporter push health verdicts of pool backends to
+// external load balancer, so infrastructure load balancers
+// and the pool agree on which services are alive
+type IHealthExporter interface {
+	// Export push verdicts of all backends
+	// of pool with given name
+	Export(ctx context.Context, pool string, verdicts []HealthVerdict) error
+}
+
+// HealthVerdicts return verdicts of all services
+// of given pool state sorted by service id
+func HealthVerdicts(state *PoolState) []HealthVerdict {
+	verdicts := make([]HealthVerdict, 0, len(state.Services))
+	for _, srv := range state.Services {
+		verdict := HealthVerdict{
+			ID:       srv.ID,
+			Address:  srv.Address,
+			NodeName: srv.NodeName,
+			State:    BackendHealthy,
+			Weight:   srv.Weight,
+			Priority: srv.Priority,
+		}
+
+		switch {
+		case srv.Membership != MembershipHealthy:
+			verdict.State = BackendUnhealthy
+		case state.Draining || srv.Maintenance == MaintenanceCordon || srv.Drained || srv.Spare == SpareStandby:
+			verdict.State = BackendDraining
+		}
+
+		verdicts = append(verdicts, verdict)
+	}
+
+	return verdicts
+}
+
+// HealthExportOpts is options that configure
+// export of pool health verdicts
+type HealthExportOpts struct {
+	List      IAdmin            // list which verdicts are exported
+	Exporters []IHealthExporter // exporters verdicts are pushed to
+	Interval  time.Duration     // interval of verdicts checks, unchanged verdicts are not pushed again (5s by default)
+	Resync    time.Duration     // interval of pushing unchanged verdicts, e.g. after load balancer reload (0 to disable)
+}
+
+// HealthExport periodically push health verdicts
+// of list services to configured exporters
+type HealthExport struct {
+	opts HealthExportOpts
+
+	mu       sync.Mutex
+	exported []exportedVerdicts // per exporter
+}
+
+// exportedVerdicts is verdicts successfully
+// pushed to exporter and time of the push
+type exportedVerdicts struct {
+	verdicts []HealthVerdict
+	time     time.Time
+}
+
+// NewHealthExport create new HealthExport with given configuration
+func NewHealthExport(opts *HealthExportOpts) *HealthExport {
+	e := &HealthExport{
+		opts:     *opts,
+		exported: make([]exportedVerdicts, len(opts.Exporters)),
+	}
+
+	if e.opts.Interval <= 0 {
+		e.opts.Interval = defaultHealthExportInterval
+	}
+
+	return e
+}
+
+// Run push verdicts every interval until given context is done
+func (e *HealthExport) Run(ctx context.Context) {
+	for {
+		if err := e.Sync(ctx); err != nil && ctx.Err() == nil {
+			log().Warn("health export error", "error", err)
+		}
+
+		SleepContext(ctx, e.opts.Interval)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Sync push current verdicts to exporters which last pushed
+// verdicts differ or are older than resync interval. Failed
+// pushes are retried on the next sync
+func (e *HealthExport) Sync(ctx context.Context) error {
+	defer e.mu.Unlock()
+	e.mu.Lock()
+
+	state := e.opts.List.Snapshot()
+	verdicts := HealthVerdicts(state)
+
+	var errs []error
+	for i, exporter := range e.opts.Exporters {
+		last := e.exported[i]
+
+		stale := e.opts.Resync > 0 && time.Since(last.time) >= e.opts.Resync
+		if !last.time.IsZero() && !stale && reflect.DeepEqual(last.verdicts, verdicts) {
+			continue
+		}
+
+		if err := exporter.Export(ctx, state.Name, verdicts); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		e.exported[i] = exportedVerdicts{verdicts: verdicts, time: time.Now()}
+	}
+
+	return errors.Join(errs...)
+}
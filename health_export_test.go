@@ -0,0 +1,201 @@
+package pool
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHAProxy is HAProxy runtime API recording received
+// commands and rejecting commands of unknown servers
+type fakeHAProxy struct {
+	mu       sync.Mutex
+	commands []string
+	servers  map[string]struct{}
+}
+
+// serve answer runtime api connections of given listener
+func (h *fakeHAProxy) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		for _, command := range strings.Split(strings.TrimSpace(line), ";") {
+			h.mu.Lock()
+			h.commands = append(h.commands, command)
+			h.mu.Unlock()
+
+			server := strings.Fields(command)[2]
+			if _, ok := h.servers[server]; !ok {
+				_, _ = conn.Write([]byte("No such server.\n"))
+				continue
+			}
+			_, _ = conn.Write([]byte("\n"))
+		}
+		conn.Close()
+	}
+}
+
+// received return and forget recorded commands
+func (h *fakeHAProxy) received() []string {
+	defer h.mu.Unlock()
+	h.mu.Lock()
+
+	commands := h.commands
+	h.commands = nil
+
+	return commands
+}
+
+func TestHealthExportHAProxy(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "haproxy.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("unexpected listen error: %s", err)
+	}
+	defer listener.Close()
+
+	haproxy := &fakeHAProxy{servers: map[string]struct{}{"provers/prover-a": {}, "provers/prover-b": {}}}
+	go haproxy.serve(listener)
+
+	a := newSwitchableService("http://10.0.0.1:8080")
+	b := newHealthyService("http://10.0.0.2:8080")
+
+	list := NewServicesList("provers", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	list.Add(a)
+	list.Add(b)
+
+	names := map[string]string{a.ID(): "prover-a", b.ID(): "prover-b"}
+	export := NewHealthExport(&HealthExportOpts{
+		List: list,
+		Exporters: []IHealthExporter{NewHAProxyExporter(&HAProxyExporterOpts{
+			Address: socket,
+			Server:  func(v HealthVerdict) string { return names[v.ID] },
+		})},
+	})
+
+	if err := export.Sync(context.Background()); err != nil {
+		t.Fatalf("unexpected export error: %s", err)
+	}
+	if commands := haproxy.received(); len(commands) != 2 || !strings.HasSuffix(commands[0], "state ready") {
+		t.Errorf("unexpected commands %v", commands)
+	}
+
+	// unchanged verdicts are not pushed again
+	if err := export.Sync(context.Background()); err != nil || len(haproxy.received()) != 0 {
+		t.Errorf("unchanged verdicts should not be exported, got %v", err)
+	}
+
+	a.down.Store(true)
+	list.HealthChecks()
+	waitFor(t, func() bool {
+		return len(list.Jailed()) == 1
+	})
+
+	if err := export.Sync(context.Background()); err != nil {
+		t.Fatalf("unexpected export error: %s", err)
+	}
+	if commands := haproxy.received(); !slices.Contains(commands, "set server provers/prover-a state maint") {
+		t.Errorf("jailed service should be set to maint, got %v", commands)
+	}
+
+	// rejected commands are reported
+	err = NewHAProxyExporter(&HAProxyExporterOpts{Address: socket}).Export(context.Background(), "provers", HealthVerdicts(list.Snapshot()))
+
+	var rejected ErrHAProxyCommand
+	if !errors.As(err, &rejected) || rejected.Message != "No such server." {
+		t.Errorf("expected rejected command error, got %v", err)
+	}
+}
+
+func TestEnvoyEndpoints(t *testing.T) {
+	endpoints := NewEnvoyEndpoints("")
+
+	err := endpoints.Export(context.Background(), "provers", []HealthVerdict{
+		{ID: "a", Address: "http://10.0.0.1:8080", State: BackendHealthy},
+		{ID: "b", Address: "10.0.0.2:9090", State: BackendDraining},
+		{ID: "c", Address: "https://prover-c.internal", State: BackendUnhealthy},
+	})
+	if err != nil {
+		t.Fatalf("unexpected export error: %s", err)
+	}
+
+	server := httptest.NewServer(endpoints)
+	defer server.Close()
+
+	discover := func(version string) (*http.Response, envoyDiscoveryResponse) {
+		body, _ := json.Marshal(envoyDiscoveryRequest{
+			VersionInfo:   version,
+			ResourceNames: []string{"provers"},
+			TypeURL:       envoyLoadAssignmentType,
+		})
+
+		resp, err := http.Post(server.URL+"/v3/discovery:endpoints", "application/json", strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatalf("unexpected request error: %s", err)
+		}
+		defer resp.Body.Close()
+
+		var discovery envoyDiscoveryResponse
+		_ = json.NewDecoder(resp.Body).Decode(&discovery)
+
+		return resp, discovery
+	}
+
+	resp, discovery := discover("")
+	if resp.StatusCode != http.StatusOK || len(discovery.Resources) != 1 {
+		t.Fatalf("unexpected discovery response %d %+v", resp.StatusCode, discovery)
+	}
+
+	var statuses []string
+	for _, endpoint := range discovery.Resources[0].Endpoints[0].LbEndpoints {
+		address := endpoint.Endpoint.Address.SocketAddress
+		statuses = append(statuses, address.Address+":"+strconv.Itoa(address.PortValue)+"="+endpoint.HealthStatus)
+	}
+	expected := []string{"10.0.0.1:8080=HEALTHY", "10.0.0.2:9090=DRAINING", "prover-c.internal:443=UNHEALTHY"}
+	if strings.Join(statuses, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected endpoints %v", statuses)
+	}
+
+	// backends are grouped by contiguous priority levels with theirs weights
+	err = endpoints.Export(context.Background(), "backup", []HealthVerdict{
+		{ID: "a", Address: "http://10.0.0.1:8080", State: BackendHealthy, Weight: 3},
+		{ID: "b", Address: "http://10.0.0.2:8080", State: BackendHealthy, Weight: 1, Priority: 2},
+	})
+	if err != nil {
+		t.Fatalf("unexpected export error: %s", err)
+	}
+
+	endpoints.mu.RLock()
+	backup := endpoints.clusters["backup"].assignment
+	endpoints.mu.RUnlock()
+
+	if len(backup.Endpoints) != 2 || backup.Endpoints[1].Priority != 1 || backup.Endpoints[0].LbEndpoints[0].LoadBalancingWeight != 3 {
+		t.Errorf("unexpected backup endpoints %+v", backup.Endpoints)
+	}
+
+	// current version is not sent again
+	if resp, _ := discover(discovery.VersionInfo); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected not modified, got %d", resp.StatusCode)
+	}
+}
@@ -2,13 +2,17 @@ package pool
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gateway-fm/prover-pool-lib/prover"
-	srv "github.com/gateway-fm/prover-pool-lib/service"
-	"github.com/gateway-fm/scriptorium/logger"
+	"github.com/gateway-fm/prover-pool-lib/service"
 )
 
 const (
@@ -16,6 +20,13 @@ const (
 	hcRetrySleepInterval = time.Millisecond * 200
 )
 
+// DefaultProbeFields is healthcheck probe response fields
+// set as live metadata by ProverHTTPProbeHealthcheck
+var DefaultProbeFields = []string{"version", "queue_depth", "free_slots", "supported_circuits"}
+
+// HealthcheckFunc check prover health and return whether failed
+// check should be retried. Prover status is not set by the check,
+// it is set by the services list on transition between its states
 type HealthcheckFunc func(timeOut time.Duration, p prover.IProver) (bool, error)
 
 func ProverMockHealthcheck(timeOut time.Duration) func(iProver prover.IProver) error {
@@ -30,30 +41,178 @@ func proverMockHealthcheck(timeOut time.Duration, p prover.IProver) (bool, error
 	_ = ctx
 
 	load := rand.Float32()
-	p.SetStatus(srv.StatusHealthy)
 	p.SetLoad(load)
 
 	return false, nil
 }
 
+// ProverHTTPHealthcheck return healthcheck that sends GET request
+// to given path of prover address through prover node client, so
+// configured baggage is propagated to the prover. The check has no
+// caller context, ProverHTTPHealthcheckContext should be used to
+// propagate trace context of the check
+func ProverHTTPHealthcheck(timeOut time.Duration, path string) func(iProver prover.IProver) error {
+	return func(p prover.IProver) error {
+		return healthcheckWithRetry(timeOut, p, 0, nil, proverHTTPHealthcheck(path, nil))
+	}
+}
+
+// ProverHTTPProbeHealthcheck return healthcheck like ProverHTTPHealthcheck
+// that parses json object from response body and sets given fields of it
+// as live metadata of the prover, so capacity and capabilities routing
+// stays fresh, DefaultProbeFields are set if no fields are given.
+// Arrays are joined with commas and objects are kept as json. Live
+// metadata is refreshed on every check, so it should not be declared
+// as list index keys which are refreshed on membership changes only
+func ProverHTTPProbeHealthcheck(timeOut time.Duration, path string, fields ...string) func(iProver prover.IProver) error {
+	if len(fields) == 0 {
+		fields = DefaultProbeFields
+	}
+
+	return func(p prover.IProver) error {
+		return healthcheckWithRetry(timeOut, p, 0, nil, proverHTTPHealthcheck(path, fields))
+	}
+}
+
+// ProverHTTPHealthcheckContext return healthcheck like ProverHTTPHealthcheck
+// for ProverOpts.HealthcheckContext, request and its retries are interrupted
+// once context of the check is done, e.g. by check timeout of the list, and
+// trace context of the check is propagated to the prover
+func ProverHTTPHealthcheckContext(path string) func(ctx context.Context, iProver prover.IProver) error {
+	return func(ctx context.Context, p prover.IProver) error {
+		return healthcheckWithRetryContext(ctx, p, path, nil)
+	}
+}
+
+// ProverHTTPProbeHealthcheckContext return healthcheck like
+// ProverHTTPProbeHealthcheck for ProverOpts.HealthcheckContext
+func ProverHTTPProbeHealthcheckContext(path string, fields ...string) func(ctx context.Context, iProver prover.IProver) error {
+	if len(fields) == 0 {
+		fields = DefaultProbeFields
+	}
+
+	return func(ctx context.Context, p prover.IProver) error {
+		return healthcheckWithRetryContext(ctx, p, path, fields)
+	}
+}
+
+func proverHTTPHealthcheck(path string, fields []string) HealthcheckFunc {
+	return func(timeOut time.Duration, p prover.IProver) (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeOut)
+		defer cancel()
+
+		return proverHTTPCheck(ctx, p, path, fields)
+	}
+}
+
+// proverHTTPCheck send healthcheck request to given path of prover
+// address until given context is done and return whether failed
+// check should be retried. Given fields of response are set as
+// live metadata of the prover (nil to skip response parsing)
+func proverHTTPCheck(ctx context.Context, p prover.IProver, path string, fields []string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Address()+path, nil)
+	if err != nil {
+		return false, fmt.Errorf("create healthcheck request: %w", err)
+	}
+
+	resp, err := p.Client().DoRequest(req)
+	if err != nil {
+		return true, fmt.Errorf("send healthcheck request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return true, fmt.Errorf("unexpected healthcheck response status %d", resp.StatusCode)
+	}
+
+	if fields == nil {
+		return false, nil
+	}
+
+	metadata, err := parseProbe(resp.Body, fields)
+	if err != nil {
+		return false, err
+	}
+
+	if live, ok := p.(service.ILiveMetadataService); ok {
+		live.SetLiveMetadata(metadata)
+	}
+
+	return false, nil
+}
+
+// parseProbe decode json object from given probe response
+// body and return given fields of it formatted as metadata
+func parseProbe(body io.Reader, fields []string) (map[string]string, error) {
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+
+	var probe map[string]interface{}
+	if err := decoder.Decode(&probe); err != nil {
+		return nil, fmt.Errorf("decode healthcheck probe response: %w", err)
+	}
+
+	metadata := make(map[string]string, len(fields))
+	for _, field := range fields {
+		value, ok := probe[field]
+		if !ok || value == nil {
+			continue
+		}
+
+		formatted, err := formatProbeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("format healthcheck probe field %s: %w", field, err)
+		}
+
+		metadata[field] = formatted
+	}
+
+	return metadata, nil
+}
+
+// formatProbeValue format given decoded json value as metadata value
+func formatProbeValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			formatted, err := formatProbeValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, formatted)
+		}
+
+		return strings.Join(items, ","), nil
+	default:
+		data, err := json.Marshal(v)
+		return string(data), err
+	}
+}
+
 func healthcheckWithRetry(
 	timeOut time.Duration, p prover.IProver,
 	try int, lastErr error,
 	hcFunc HealthcheckFunc) error {
 
 	if try >= maxHCNumTries {
-		p.SetStatus(srv.StatusUnHealthy)
 		return lastErr
 	}
 
 	if try > 0 {
-		logger.Log().Warn(fmt.Sprintf("retrying healthcheck to service %s %s... current try is %d out of %d, the last error was: %s", p.NodeName(), p.ID(), try+1, maxHCNumTries, lastErr.Error()))
+		log().Warn("retrying healthcheck", "service", p.ID(), "node", p.NodeName(), "try", try+1, "tries", maxHCNumTries, "error", lastErr)
 	}
 
 	retryNeeded, err := hcFunc(timeOut, p)
 	if err == nil {
 		if try > 0 {
-			logger.Log().Info(fmt.Sprintf("the healthcheck to service %s %s is recovered after retry (•‿•)", p.NodeName(), p.ID()))
+			log().Info("healthcheck is recovered after retry", "service", p.ID(), "node", p.NodeName())
 		}
 		return nil
 	}
@@ -65,3 +224,34 @@ func healthcheckWithRetry(
 	time.Sleep(hcRetrySleepInterval)
 	return healthcheckWithRetry(timeOut, p, try+1, err, hcFunc)
 }
+
+// healthcheckWithRetryContext send http healthcheck to given prover
+// and retry it like healthcheckWithRetry until given context is done
+func healthcheckWithRetryContext(ctx context.Context, p prover.IProver, path string, fields []string) error {
+	var err error
+	for try := 0; try < maxHCNumTries; try++ {
+		if try > 0 {
+			log().Warn("retrying healthcheck", "service", p.ID(), "node", p.NodeName(), "try", try+1, "tries", maxHCNumTries, "error", err)
+			SleepContext(ctx, hcRetrySleepInterval)
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		var retryNeeded bool
+		retryNeeded, err = proverHTTPCheck(ctx, p, path, fields)
+		if err == nil {
+			if try > 0 {
+				log().Info("healthcheck is recovered after retry", "service", p.ID(), "node", p.NodeName())
+			}
+			return nil
+		}
+
+		if !retryNeeded {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,120 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/prover"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestProverHTTPProbeHealthcheck(t *testing.T) {
+	body := `{"version": "v1.4.2", "queue_depth": 3, "free_slots": 2, "supported_circuits": ["batch", "aggregation"], "ready": true}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	p, err := prover.NewProver(&prover.ProverOpts{
+		Addr:        server.URL,
+		Healthcheck: ProverHTTPProbeHealthcheck(time.Second, "/health"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected prover error: %s", err)
+	}
+
+	if err := p.HealthCheck(); err != nil {
+		t.Fatalf("unexpected healthcheck error: %s", err)
+	}
+
+	expected := map[string]string{
+		"version":            "v1.4.2",
+		"queue_depth":        "3",
+		"free_slots":         "2",
+		"supported_circuits": "batch,aggregation",
+	}
+	metadata := service.Metadata(p)
+	if len(metadata) != len(expected) {
+		t.Fatalf("unexpected live metadata %v", metadata)
+	}
+	for key, value := range expected {
+		if metadata[key] != value {
+			t.Errorf("expected %s %q, got %q", key, value, metadata[key])
+		}
+	}
+
+	// live metadata follows the probe
+	body = `{"queue_depth": 0, "free_slots": 5}`
+	if err := p.HealthCheck(); err != nil {
+		t.Fatalf("unexpected healthcheck error: %s", err)
+	}
+	if metadata := service.Metadata(p); metadata["free_slots"] != "5" || metadata["version"] != "" {
+		t.Errorf("live metadata should be replaced, got %v", metadata)
+	}
+
+	body = "ok"
+	if err := p.HealthCheck(); err == nil {
+		t.Errorf("malformed probe response should fail the check")
+	}
+}
+
+func TestLiveMetadataIsNotRediscoveryChange(t *testing.T) {
+	discovered := newMetadataService("https://1gateway.fm", map[string]string{"zone": "eu"}).(*service.BaseService)
+	discovered.SetLiveMetadata(map[string]string{"zone": "us", "free_slots": "2"})
+
+	if metadata := discovered.Metadata(); metadata["zone"] != "us" || metadata["free_slots"] != "2" {
+		t.Errorf("live metadata should override static one, got %v", metadata)
+	}
+
+	rediscovered := newMetadataService("https://1gateway.fm", map[string]string{"zone": "eu"})
+	if !service.Equal(discovered, rediscovered) {
+		t.Errorf("live metadata should not be compared")
+	}
+}
+
+func TestProverHTTPHealthcheckContextTimeout(t *testing.T) {
+	canceled := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// prover accepts connection but never answers
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	defer server.Close()
+
+	p, err := prover.NewProver(&prover.ProverOpts{
+		Addr:               server.URL,
+		HealthcheckContext: ProverHTTPHealthcheckContext("/health"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected prover error: %s", err)
+	}
+
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Timeouts:       &Timeouts{Check: 100 * time.Millisecond},
+	})
+	defer list.Close()
+
+	start := time.Now()
+	if err := list.CheckHealth(context.Background(), p); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("check should be interrupted by the timeout, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("check should take about the timeout, took %s", elapsed)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Errorf("hung healthcheck request should be canceled")
+	}
+}
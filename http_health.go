@@ -0,0 +1,160 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/gateway-fm/prover-pool-lib/prover/client"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultHTTPHealthPath    = "/healthz"
+	defaultHTTPHealthTimeout = 5 * time.Second
+
+	// maxHTTPHealthBodySize is max size of response
+	// body searched for expected substring
+	maxHTTPHealthBodySize = 64 << 10
+)
+
+// StatusRange is inclusive range of http status codes
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// Contains check if given status code is in the range
+func (r StatusRange) Contains(code int) bool {
+	return code >= r.Min && code <= r.Max
+}
+
+// HTTPHealthCheckerOpts is options that configure
+// http requests services are checked with
+type HTTPHealthCheckerOpts struct {
+	Path     string        // checked path ("/healthz" by default)
+	Method   string        // GET or HEAD (GET by default)
+	Timeout  time.Duration // timeout of one check (5s by default)
+	Statuses []StatusRange // expected status codes (2xx by default)
+	Body     string        // substring expected in response body (empty to skip body check), requires GET
+	Scheme   string        // scheme of services addresses given without one ("http" by default)
+	Client   *http.Client  // client checks are sent with (http.DefaultClient by default)
+
+	Propagation *client.Propagation // trace context and baggage of the check injected into requests (W3C trace context and baggage by default)
+}
+
+// HTTPHealthChecker is IHealthChecker that send http request
+// to configured path of services and expect configured status
+// codes and optionally a substring in the response body
+type HTTPHealthChecker struct {
+	opts HTTPHealthCheckerOpts
+}
+
+// NewHTTPHealthChecker create new HTTPHealthChecker with given
+// configuration, set it as ServicesListOpts.HealthChecker to
+// check all list services with it
+func NewHTTPHealthChecker(opts *HTTPHealthCheckerOpts) (*HTTPHealthChecker, error) {
+	c := &HTTPHealthChecker{opts: *opts}
+
+	if c.opts.Path == "" {
+		c.opts.Path = defaultHTTPHealthPath
+	}
+	if !strings.HasPrefix(c.opts.Path, "/") {
+		c.opts.Path = "/" + c.opts.Path
+	}
+	if c.opts.Method == "" {
+		c.opts.Method = http.MethodGet
+	}
+	if c.opts.Timeout <= 0 {
+		c.opts.Timeout = defaultHTTPHealthTimeout
+	}
+	if len(c.opts.Statuses) == 0 {
+		c.opts.Statuses = []StatusRange{{Min: 200, Max: 299}}
+	}
+	if c.opts.Scheme == "" {
+		c.opts.Scheme = defaultProxyScheme
+	}
+	if c.opts.Client == nil {
+		c.opts.Client = http.DefaultClient
+	}
+	if c.opts.Propagation == nil {
+		c.opts.Propagation, _ = client.NewPropagation(nil, nil)
+	}
+
+	c.opts.Method = strings.ToUpper(c.opts.Method)
+	if c.opts.Method != http.MethodGet && c.opts.Method != http.MethodHead {
+		return nil, fmt.Errorf("unsupported healthcheck method %s, GET or HEAD is expected", c.opts.Method)
+	}
+	if c.opts.Method == http.MethodHead && c.opts.Body != "" {
+		return nil, errors.New("response body could not be checked with HEAD requests")
+	}
+	for _, r := range c.opts.Statuses {
+		if r.Min > r.Max {
+			return nil, fmt.Errorf("invalid status range %d-%d", r.Min, r.Max)
+		}
+	}
+
+	return c, nil
+}
+
+// Check send healthcheck request to given service and check
+// status code and body of the response, trace context of given
+// context is propagated, so prover-side traces are correlated
+// with the check
+func (c *HTTPHealthChecker) Check(ctx context.Context, srv service.IService) error {
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	target, err := serviceURL(srv.Address(), c.opts.Scheme)
+	if err != nil {
+		return err
+	}
+	target.Path = strings.TrimSuffix(target.Path, "/") + c.opts.Path
+
+	req, err := http.NewRequestWithContext(ctx, c.opts.Method, target.String(), nil)
+	if err != nil {
+		return err
+	}
+	c.opts.Propagation.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http healthcheck of %s: %w", srv.Address(), err)
+	}
+	defer resp.Body.Close()
+
+	if !c.expected(resp.StatusCode) {
+		return ErrUpstreamStatus{Address: srv.Address(), StatusCode: resp.StatusCode}
+	}
+
+	if c.opts.Body == "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPHealthBodySize))
+	if err != nil {
+		return fmt.Errorf("http healthcheck of %s: %w", srv.Address(), err)
+	}
+	if !strings.Contains(string(body), c.opts.Body) {
+		return ErrUnexpectedBody{Address: srv.Address(), Expected: c.opts.Body}
+	}
+
+	return nil
+}
+
+// expected check if given status code is expected
+func (c *HTTPHealthChecker) expected(code int) bool {
+	for _, r := range c.opts.Statuses {
+		if r.Contains(code) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,149 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gateway-fm/prover-pool-lib/prover/client"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestHTTPHealthChecker(t *testing.T) {
+	var code atomic.Int32
+	code.Store(http.StatusOK)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(int(code.Load()))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"status":"ready"}`))
+		}
+	}))
+	defer server.Close()
+
+	prover := service.NewService(server.URL, "", nil, 0)
+
+	checker, err := NewHTTPHealthChecker(&HTTPHealthCheckerOpts{Body: `"ready"`})
+	if err != nil {
+		t.Fatalf("unexpected checker error: %s", err)
+	}
+	if err := checker.Check(context.Background(), prover); err != nil {
+		t.Errorf("unexpected check error: %s", err)
+	}
+
+	code.Store(http.StatusServiceUnavailable)
+
+	var upstream ErrUpstreamStatus
+	if err := checker.Check(context.Background(), prover); !errors.As(err, &upstream) || upstream.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected unexpected status error, got %v", err)
+	}
+
+	// configured ranges accept degraded responses
+	degraded, _ := NewHTTPHealthChecker(&HTTPHealthCheckerOpts{
+		Method:   http.MethodHead,
+		Statuses: []StatusRange{{Min: 200, Max: 299}, {Min: 503, Max: 503}},
+	})
+	if err := degraded.Check(context.Background(), prover); err != nil {
+		t.Errorf("unexpected check error of accepted status: %s", err)
+	}
+
+	code.Store(http.StatusOK)
+
+	var body ErrUnexpectedBody
+	missing, _ := NewHTTPHealthChecker(&HTTPHealthCheckerOpts{Body: "synced"})
+	if err := missing.Check(context.Background(), prover); !errors.As(err, &body) {
+		t.Errorf("expected unexpected body error, got %v", err)
+	}
+
+	if _, err := NewHTTPHealthChecker(&HTTPHealthCheckerOpts{Method: http.MethodHead, Body: "ready"}); err == nil {
+		t.Errorf("body check of HEAD requests should be rejected")
+	}
+	if _, err := NewHTTPHealthChecker(&HTTPHealthCheckerOpts{Method: http.MethodPost}); err == nil {
+		t.Errorf("unsupported method should be rejected")
+	}
+}
+
+func TestServicesListHTTPHealthChecker(t *testing.T) {
+	var ready atomic.Bool
+	ready.Store(true)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	checker, err := NewHTTPHealthChecker(&HTTPHealthCheckerOpts{Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("unexpected checker error: %s", err)
+	}
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		HealthChecker:  checker,
+	})
+	defer list.Close()
+
+	list.Add(service.NewService(server.URL, "", nil, 0))
+	if len(list.Healthy()) != 1 {
+		t.Fatalf("service passing http check should be healthy")
+	}
+
+	ready.Store(false)
+	list.HealthChecks()
+
+	waitFor(t, func() bool {
+		return len(list.Jailed()) == 1
+	})
+}
+
+func TestHTTPHealthCheckerTraceContext(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header.Clone()
+	}))
+	defer server.Close()
+
+	propagation, err := client.NewPropagation(nil, map[string]string{"gateway": "eu-1"})
+	if err != nil {
+		t.Fatalf("unexpected propagation error: %s", err)
+	}
+
+	checker, err := NewHTTPHealthChecker(&HTTPHealthCheckerOpts{Propagation: propagation})
+	if err != nil {
+		t.Fatalf("unexpected checker error: %s", err)
+	}
+
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	if err := checker.Check(ctx, service.NewService(server.URL, "", nil, 0)); err != nil {
+		t.Fatalf("unexpected check error: %s", err)
+	}
+
+	got := <-headers
+	if !strings.Contains(got.Get("traceparent"), traceID.String()) {
+		t.Errorf("trace context of the check is not propagated: %q", got.Get("traceparent"))
+	}
+	if got.Get("baggage") != "gateway=eu-1" {
+		t.Errorf("baggage is not propagated: %q", got.Get("baggage"))
+	}
+}
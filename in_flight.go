@@ -0,0 +1,130 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// Overflow is behaviour of TryAcquire when all allowed
+// healthy services are at theirs in-flight cap
+type Overflow int
+
+const (
+	// OverflowReject return ErrPoolSaturated
+	// without waiting for capacity
+	OverflowReject Overflow = iota
+
+	// OverflowWait block until in-flight connection is released
+	// or given context is done, Acquire never waits
+	OverflowWait
+)
+
+// String return name of the overflow behaviour
+func (o Overflow) String() string {
+	switch o {
+	case OverflowWait:
+		return "wait"
+	default:
+		return "reject"
+	}
+}
+
+// inFlightCap return max number of in-flight connections to
+// given service: cap from its metadata or configured one
+func (l *ServicesList) inFlightCap(srv service.IService) int {
+	if limit := service.MaxInFlight(srv); limit > 0 {
+		return limit
+	}
+
+	return l.maxInFlight
+}
+
+// belowInFlightCap check if given service
+// could take one more in-flight connection
+func (l *ServicesList) belowInFlightCap(srv service.IService) bool {
+	limit := l.inFlightCap(srv)
+	return limit == 0 || l.connections.count(srv.ID()) < limit
+}
+
+// TryAcquire returns next healthy service to take a connection like
+// Acquire. Services at theirs in-flight cap are skipped, when all of
+// them are at the cap ErrPoolSaturated is returned or the call waits
+// for released connection depending on configured overflow, waiting
+// stops with context error once given context is done. Other
+// failures are reported with the same errors as NextE
+func (l *ServicesList) TryAcquire(ctx context.Context) (service.IService, ReleaseFunc, error) {
+	return l.tryAcquire(ctx, l.overflowMode == OverflowWait)
+}
+
+// tryAcquire returns next healthy service to take a connection,
+// waiting for released connection of saturated list if wait is set
+func (l *ServicesList) tryAcquire(ctx context.Context, wait bool) (service.IService, ReleaseFunc, error) {
+	for {
+		freed := l.capacity.wait()
+
+		srv := l.acquire(ctx)
+		if srv != nil {
+			l.metrics.observeSelection(ctx, l.serviceName, srv)
+			return srv, l.releaseFunc(srv), nil
+		}
+
+		if err := l.overflow(ctx, freed, wait, l.nextErr(ctx)); err != nil {
+			l.metrics.observeSelection(ctx, l.serviceName, nil)
+			return nil, func() {}, err
+		}
+	}
+}
+
+// overflow return given error of failed acquisition or wait
+// on given channel for released connection if the list is
+// saturated and wait is set, nil is returned once the
+// connection is released
+func (l *ServicesList) overflow(ctx context.Context, freed <-chan struct{}, wait bool, err error) error {
+	if !errors.As(err, &ErrPoolSaturated{}) || !wait {
+		return err
+	}
+
+	select {
+	case <-freed:
+		return nil
+	case <-l.ctx.Done():
+		return ErrPoolClosed{Pool: l.serviceName}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TryAcquire returns next healthy service to take a connection
+// from shards selected using round-robin like Acquire, waiting
+// for capacity of saturated shards is woken up by release of
+// connection to any shard
+func (l *ShardedServicesList) TryAcquire(ctx context.Context) (service.IService, ReleaseFunc, error) {
+	return l.tryAcquire(ctx, l.shards[0].overflowMode == OverflowWait)
+}
+
+// tryAcquire returns next healthy service of any shard to take a
+// connection, waiting for released connection if wait is set
+func (l *ShardedServicesList) tryAcquire(ctx context.Context, wait bool) (service.IService, ReleaseFunc, error) {
+	first := l.shards[0]
+
+	for {
+		freed := first.capacity.wait()
+
+		start := int(atomic.AddUint64(&l.current, 1) % uint64(len(l.shards)))
+		for i := range l.shards {
+			shard := l.shards[(start+i)%len(l.shards)]
+			if srv := shard.acquire(ctx); srv != nil {
+				first.metrics.observeSelection(ctx, l.serviceName, srv)
+				return srv, shard.releaseFunc(srv), nil
+			}
+		}
+
+		if err := first.overflow(ctx, freed, wait, l.nextErr(ctx)); err != nil {
+			first.metrics.observeSelection(ctx, l.serviceName, nil)
+			return nil, func() {}, err
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestMaxInFlightReject(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		MaxInFlight:    1,
+	})
+	defer list.Close()
+
+	single := newHealthyService("https://1gateway.fm")
+	double := newMetadataService("https://2gateway.fm", map[string]string{
+		service.MaxInFlightMetadataKey: "2",
+	})
+	list.Add(single)
+	list.Add(double)
+
+	ctx := context.Background()
+
+	var releases []ReleaseFunc
+	for i := 0; i < 3; i++ {
+		_, release, err := list.TryAcquire(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error of acquisition %d: %s", i, err)
+		}
+		releases = append(releases, release)
+	}
+
+	if list.InFlight(single.ID()) != 1 || list.InFlight(double.ID()) != 2 {
+		t.Fatalf("expected 1 and 2 in-flight connections, got %d and %d", list.InFlight(single.ID()), list.InFlight(double.ID()))
+	}
+
+	if _, _, err := list.TryAcquire(ctx); !errors.As(err, &ErrPoolSaturated{}) {
+		t.Fatalf("expected saturated pool, got %v", err)
+	}
+	if srv := list.Next(); srv != nil {
+		t.Fatalf("expected saturated services to be skipped, got %s", srv.ID())
+	}
+
+	// released capacity is taken again
+	releases[0]()
+	if srv, _, err := list.TryAcquire(ctx); err != nil || srv == nil {
+		t.Fatalf("expected acquisition after release, got %v", err)
+	}
+
+	empty := NewServicesList("emptyServicesList", &ServicesListOpts{MaxInFlight: 1})
+	defer empty.Close()
+
+	if _, _, err := empty.TryAcquire(ctx); !errors.As(err, &ErrNoHealthyServices{}) {
+		t.Errorf("expected no healthy services, got %v", err)
+	}
+}
+
+func TestMaxInFlightWait(t *testing.T) {
+	for _, shards := range []int{1, 2} {
+		list := NewServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  time.Hour,
+			ChecksInterval: time.Hour,
+			Shards:         shards,
+			MaxInFlight:    1,
+			Overflow:       OverflowWait,
+		})
+
+		list.Add(newHealthyService("https://1gateway.fm"))
+		list.Add(newHealthyService("https://2gateway.fm"))
+
+		ctx := context.Background()
+
+		first, release := list.Acquire(ctx)
+		_, _, err := list.TryAcquire(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// only TryAcquire waits, Acquire keeps failing fast
+		if srv, _ := list.Acquire(ctx); srv != nil {
+			t.Fatalf("expected saturated list with %d shards, got %v", shards, srv)
+		}
+
+		timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		if _, _, err := list.TryAcquire(timeout); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected wait until deadline with %d shards, got %v", shards, err)
+		}
+		cancel()
+
+		acquired := make(chan service.IService)
+		go func() {
+			srv, _, _ := list.TryAcquire(ctx)
+			acquired <- srv
+		}()
+
+		select {
+		case srv := <-acquired:
+			t.Fatalf("expected acquisition to wait for capacity, got %v", srv)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		release()
+
+		select {
+		case srv := <-acquired:
+			if srv == nil || srv.ID() != first.ID() {
+				t.Fatalf("expected released service %s to be acquired with %d shards, got %v", first.ID(), shards, srv)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("acquisition is not woken up by release with %d shards", shards)
+		}
+
+		list.Close()
+	}
+}
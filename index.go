@@ -0,0 +1,167 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// metadataIndex is secondary index of healthy services by
+// values of declared metadata keys. It is rebuilt lazily
+// on the first lookup after membership generation change
+type metadataIndex struct {
+	mu sync.RWMutex
+
+	keys       map[string]struct{}
+	generation uint64
+	built      bool
+
+	values map[string]map[string][]service.IService // key -> value -> healthy services in healthy order
+}
+
+// newMetadataIndex create index of given metadata keys,
+// nil is returned if no keys are declared
+func newMetadataIndex(keys []string) *metadataIndex {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	idx := &metadataIndex{keys: make(map[string]struct{}, len(keys))}
+	for _, key := range keys {
+		idx.keys[key] = struct{}{}
+	}
+
+	return idx
+}
+
+// has check if given metadata key is indexed
+func (idx *metadataIndex) has(key string) bool {
+	if idx == nil {
+		return false
+	}
+
+	_, ok := idx.keys[key]
+	return ok
+}
+
+// lookup return indexed healthy services with given metadata
+// value, the index is rebuilt from given healthy services if
+// given generation differs from the indexed one
+func (idx *metadataIndex) lookup(key, value string, generation uint64, healthy []service.IService) []service.IService {
+	idx.mu.RLock()
+	if idx.built && idx.generation == generation {
+		services := idx.values[key][value]
+		idx.mu.RUnlock()
+		return services
+	}
+	idx.mu.RUnlock()
+
+	defer idx.mu.Unlock()
+	idx.mu.Lock()
+
+	if !idx.built || idx.generation != generation {
+		idx.rebuild(healthy)
+		idx.generation = generation
+		idx.built = true
+	}
+
+	return idx.values[key][value]
+}
+
+// rebuild index given healthy services
+func (idx *metadataIndex) rebuild(healthy []service.IService) {
+	idx.values = make(map[string]map[string][]service.IService, len(idx.keys))
+	for key := range idx.keys {
+		idx.values[key] = make(map[string][]service.IService)
+	}
+
+	for _, srv := range healthy {
+		for key, value := range service.Metadata(srv) {
+			if values, ok := idx.values[key]; ok {
+				values[value] = append(values[value], srv)
+			}
+		}
+	}
+}
+
+// Where return healthy services with given metadata value,
+// declared index keys are looked up in the index and others
+// are found by scanning healthy services
+func (l *ServicesList) Where(key, value string) []service.IService {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	var services []service.IService
+	services = append(services, l.whereLocked(key, value)...)
+
+	return services
+}
+
+// NextWhere returns next healthy service with given
+// metadata value to take a connection using round-robin
+func (l *ServicesList) NextWhere(key, value string) service.IService {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	matches := l.whereLocked(key, value)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	next := int(atomic.AddUint64(&l.whereCurrent, 1) % uint64(len(matches)))
+	for i := 0; i < len(matches); i++ {
+		srv := matches[(next+i)%len(matches)]
+		if srv.Status() == service.StatusHealthy && l.allow(context.Background(), srv) {
+			l.recordSelection(srv)
+			return srv
+		}
+	}
+
+	return nil
+}
+
+// whereLocked return healthy services with given metadata
+// value, returned slice should not be modified. Should be
+// called under the list lock
+func (l *ServicesList) whereLocked(key, value string) []service.IService {
+	if l.index.has(key) {
+		return l.index.lookup(key, value, l.Generation(), l.healthy)
+	}
+
+	var services []service.IService
+	for _, srv := range l.healthy {
+		if metadata, ok := service.Metadata(srv)[key]; ok && metadata == value {
+			services = append(services, srv)
+		}
+	}
+
+	return services
+}
+
+// Where return healthy services of all
+// shards with given metadata value
+func (l *ShardedServicesList) Where(key, value string) []service.IService {
+	var services []service.IService
+	for _, shard := range l.shards {
+		services = append(services, shard.Where(key, value)...)
+	}
+
+	return services
+}
+
+// NextWhere returns next healthy service with given metadata
+// value to take a connection, shards are selected using
+// round-robin and shards without matches are skipped
+func (l *ShardedServicesList) NextWhere(key, value string) service.IService {
+	start := int(atomic.AddUint64(&l.current, 1) % uint64(len(l.shards)))
+
+	for i := 0; i < len(l.shards); i++ {
+		if srv := l.shards[(start+i)%len(l.shards)].NextWhere(key, value); srv != nil {
+			return srv
+		}
+	}
+
+	return nil
+}
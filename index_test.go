@@ -0,0 +1,63 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func newMetadataService(addr string, metadata map[string]string) service.IService {
+	srv := newHealthyService(addr).(*service.BaseService)
+	srv.SetMetadata(metadata)
+
+	return srv
+}
+
+func TestMetadataIndex(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		IndexKeys:      []string{"zone"},
+	})
+
+	eu1 := newMetadataService("https://1gateway.fm", map[string]string{"zone": "eu", "circuit": "batch"})
+	eu2 := newMetadataService("https://2gateway.fm", map[string]string{"zone": "eu", "circuit": "agg"})
+	us := newMetadataService("https://3gateway.fm", map[string]string{"zone": "us", "circuit": "batch"})
+
+	for _, srv := range []service.IService{eu1, eu2, us} {
+		list.Add(srv)
+	}
+
+	if services := list.Where("zone", "eu"); len(services) != 2 {
+		t.Fatalf("expected 2 services in eu zone, got %d", len(services))
+	}
+
+	// not indexed keys are scanned
+	if services := list.Where("circuit", "batch"); len(services) != 2 {
+		t.Fatalf("expected 2 batch services, got %d", len(services))
+	}
+
+	selected := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		srv := list.NextWhere("zone", "eu")
+		if srv == nil {
+			t.Fatalf("expected service in eu zone")
+		}
+		selected[srv.ID()]++
+	}
+	if selected[eu1.ID()] != 2 || selected[eu2.ID()] != 2 {
+		t.Errorf("expected round-robin between eu services, got %v", selected)
+	}
+
+	// index is refreshed after membership change
+	list.FromHealthyToJail(eu1.ID())
+	if services := list.Where("zone", "eu"); len(services) != 1 || services[0].ID() != eu2.ID() {
+		t.Errorf("jailed service should be removed from the index")
+	}
+
+	if srv := list.NextWhere("zone", "ap"); srv != nil {
+		t.Errorf("expected no service in ap zone")
+	}
+}
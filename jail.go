@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// JailItem is jailed service waiting
+// for successful try to up it
+type JailItem struct {
+	Service service.IService
+	Since   time.Time // time service is jailed since
+}
+
+// toJail put given service to the jail, time the service is
+// jailed since is kept if it's already known, e.g. when it's
+// replaced on rediscovery or restored after restart. Should
+// be called under the list lock
+func (l *ServicesList) toJail(srv service.IService) {
+	if _, ok := l.jailedAt[srv.ID()]; !ok {
+		l.jailedAt[srv.ID()] = time.Now()
+	}
+	l.jail[srv.ID()] = srv
+}
+
+// fromJail remove service with given id from
+// the jail. Should be called under the list lock
+func (l *ServicesList) fromJail(id string) {
+	delete(l.jail, id)
+	delete(l.jailedAt, id)
+}
+
+// JailedItems return jailed services with
+// time they are jailed since, longest jailed first
+func (l *ServicesList) JailedItems() []JailItem {
+	l.mu.RLock()
+	items := make([]JailItem, 0, len(l.jail))
+	for id, srv := range l.jail {
+		items = append(items, JailItem{Service: srv, Since: l.jailedAt[id]})
+	}
+	l.mu.RUnlock()
+
+	sortJailItems(items)
+
+	return items
+}
+
+// CountJailed return number of jailed services
+func (l *ServicesList) CountJailed() int {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	return len(l.jail)
+}
+
+// JailedItems return jailed services of all shards
+// with time they are jailed since, longest jailed first
+func (l *ShardedServicesList) JailedItems() []JailItem {
+	var items []JailItem
+	for _, shard := range l.shards {
+		items = append(items, shard.JailedItems()...)
+	}
+
+	sortJailItems(items)
+
+	return items
+}
+
+// CountJailed return number of jailed services of all shards
+func (l *ShardedServicesList) CountJailed() int {
+	count := 0
+	for _, shard := range l.shards {
+		count += shard.CountJailed()
+	}
+
+	return count
+}
+
+// sortJailItems sort given items by time services
+// are jailed since, items of the same time by id
+func sortJailItems(items []JailItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Since.Equal(items[j].Since) {
+			return items[i].Since.Before(items[j].Since)
+		}
+		return items[i].Service.ID() < items[j].Service.ID()
+	})
+}
@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServicesListJailedItems(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	first := newSwitchableService("https://1gateway.fm")
+	second := newSwitchableService("https://2gateway.fm")
+	list.Add(first)
+	list.Add(second)
+
+	if list.CountJailed() != 0 || len(list.JailedItems()) != 0 {
+		t.Fatalf("jail should be empty")
+	}
+
+	start := time.Now()
+	list.FromHealthyToJail(second.ID())
+	time.Sleep(time.Millisecond)
+	list.FromHealthyToJail(first.ID())
+
+	items := list.JailedItems()
+	if list.CountJailed() != 2 || len(items) != 2 {
+		t.Fatalf("expected 2 jailed services, got %d", len(items))
+	}
+
+	if items[0].Service != second || items[1].Service != first {
+		t.Errorf("longest jailed service should be the first")
+	}
+
+	if items[0].Since.Before(start) || !items[0].Since.Before(items[1].Since) {
+		t.Errorf("unexpected jail times %s and %s", items[0].Since, items[1].Since)
+	}
+
+	// rediscovered jailed service keeps its jail time
+	list.Add(second)
+	if since := list.JailedItems()[0].Since; !since.Equal(items[0].Since) {
+		t.Errorf("jail time should be kept, got %s", since)
+	}
+
+	for _, snapshot := range list.Snapshot().Services {
+		if snapshot.JailedSince == nil {
+			t.Errorf("snapshot of %s should report jail time", snapshot.ID)
+		}
+	}
+
+	list.FromJailToHealthy(second)
+	if items := list.JailedItems(); len(items) != 1 || items[0].Service != first {
+		t.Errorf("recovered service should leave the jail, got %v", items)
+	}
+}
+
+func TestShardedServicesListJailedItems(t *testing.T) {
+	list := NewServicesList("testShardedList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Shards:         4,
+	})
+	defer list.Close()
+
+	for _, addr := range []string{"https://1gateway.fm", "https://2gateway.fm", "https://3gateway.fm"} {
+		srv := newHealthyService(addr)
+		list.Add(srv)
+		list.FromHealthyToJail(srv.ID())
+	}
+
+	if list.CountJailed() != 3 || len(list.JailedItems()) != 3 {
+		t.Errorf("expected 3 jailed services of all shards, got %d", list.CountJailed())
+	}
+}
@@ -0,0 +1,560 @@
+package pool
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gateway-fm/prover-pool-lib/discovery"
+)
+
+const (
+	defaultKubernetesKey           = "pools.yaml"
+	defaultKubernetesNamespace     = "default"
+	defaultKubernetesRetryInterval = 5 * time.Second
+	kubernetesServiceAccountDir    = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	defaultSpecDiscoveryInterval = 30 * time.Second
+	defaultSpecChecksInterval    = 10 * time.Second
+	defaultSpecTryUpInterval     = 10 * time.Second
+)
+
+// PoolSpec is declarative definition of pool managed by
+// platform teams, e.g. stored in Kubernetes ConfigMap
+// or custom resource. Durations are strings like "10s"
+type PoolSpec struct {
+	Name              string            `json:"name" yaml:"name"`                           // pool name, it is service name given to discovery (object name of custom resource by default)
+	Driver            string            `json:"driver" yaml:"driver"`                       // discovery driver registered in discovery package, e.g. consul or dns
+	Addresses         []string          `json:"addresses" yaml:"addresses"`                 // driver endpoints
+	Path              string            `json:"path" yaml:"path"`                           // file path of static and replay drivers
+	Prefix            string            `json:"prefix" yaml:"prefix"`                       // key prefix of etcd driver
+	Tags              []string          `json:"tags" yaml:"tags"`                           // tags added to discovered services
+	Params            map[string]string `json:"params" yaml:"params"`                       // driver specific settings
+	Timeout           time.Duration     `json:"timeout" yaml:"timeout"`                     // discovery request timeout (driver default by default)
+	Balancing         string            `json:"balancing" yaml:"balancing"`                 // round_robin, weighted_round_robin or least_connections (round_robin by default)
+	DiscoveryInterval time.Duration     `json:"discoveryInterval" yaml:"discoveryInterval"` // rediscovery interval (30s by default)
+	ChecksInterval    time.Duration     `json:"checksInterval" yaml:"checksInterval"`       // healthchecks interval (10s by default)
+	TryUpInterval     time.Duration     `json:"tryUpInterval" yaml:"tryUpInterval"`         // interval of tries to up jailed services (10s by default)
+	TryUpTries        int               `json:"tryUpTries" yaml:"tryUpTries"`               // number of tries to up jailed services (0 for infinity tries)
+	ShedThreshold     float64           `json:"shedThreshold" yaml:"shedThreshold"`         // saturation low priority requests are shed above (0 to disable shedding)
+	PruneMissing      bool              `json:"pruneMissing" yaml:"pruneMissing"`           // remove services missing in discovery results
+
+	OverprovisioningFactor int             `json:"overprovisioningFactor" yaml:"overprovisioningFactor"` // Envoy overprovisioning factor of priority levels in percents, e.g. 140 (0 to disable priority levels)
+	Timeouts               *Timeouts       `json:"timeouts" yaml:"timeouts"`                             // deadlines of pool operations (DefaultTimeouts by default)
+	Features               map[string]bool `json:"features" yaml:"features"`                             // initial state of feature flags, e.g. outlier_ejection or hedging (built-in defaults by default)
+}
+
+// KubernetesResource is custom resource which objects
+// hold one pool spec each in theirs spec field, e.g.
+// group "pool.gateway.fm", version "v1" and plural "proverpools"
+type KubernetesResource struct {
+	Group   string
+	Version string
+	Plural  string
+}
+
+// KubernetesLoaderOpts is options that configure loader of
+// pools from Kubernetes ConfigMap or custom resources. API
+// access is configured from the pod service account by default
+type KubernetesLoaderOpts struct {
+	Registry      *PoolRegistry                                      // registry pools are created in and removed from
+	Address       string                                             // api server address (in-cluster address by default)
+	Token         string                                             // static bearer token (TokenFile is used if empty)
+	TokenFile     string                                             // file bearer token is read from on every request, so rotated tokens are used (service account token file by default)
+	Client        *http.Client                                       // http client without timeout, watches are long-lived (client trusting in-cluster CA by default)
+	Namespace     string                                             // namespace of watched objects (service account namespace by default)
+	ConfigMap     string                                             // name of ConfigMap holding yaml list of pool specs under Key
+	Key           string                                             // ConfigMap data key ("pools.yaml" by default)
+	Resource      *KubernetesResource                                // custom resource holding pool specs, used instead of ConfigMap
+	Healthchecks  bool                                               // run healthchecks of created pools
+	Customize     func(spec PoolSpec, opts *ServicesPoolsOpts) error // adjust pool options built from spec, e.g. set MutationFnc (nil to keep)
+	RetryInterval time.Duration                                      // interval between attempts after failed API request (5s by default)
+}
+
+// KubernetesLoader create, recreate and remove registry pools
+// according to pool specs stored in Kubernetes, so pools are
+// managed declaratively. Only pools created by the loader are
+// managed, changed pools are recreated
+type KubernetesLoader struct {
+	opts KubernetesLoaderOpts
+
+	mu      sync.Mutex
+	applied map[string]PoolSpec // pool name -> spec the pool is created from
+}
+
+// kubernetesList is list or single object response of Kubernetes API
+type kubernetesList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data  map[string]string `json:"data"`
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec json.RawMessage `json:"spec"`
+	} `json:"items"`
+}
+
+// NewKubernetesLoader create new KubernetesLoader with
+// given configuration, in-cluster defaults are resolved
+func NewKubernetesLoader(opts *KubernetesLoaderOpts) (*KubernetesLoader, error) {
+	l := &KubernetesLoader{
+		opts:    *opts,
+		applied: make(map[string]PoolSpec),
+	}
+
+	if l.opts.Registry == nil {
+		return nil, errors.New("pool registry is not configured")
+	}
+	if (l.opts.ConfigMap == "") == (l.opts.Resource == nil) {
+		return nil, errors.New("exactly one of ConfigMap and Resource should be configured")
+	}
+
+	if l.opts.Address == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("kubernetes api address is not configured and loader is not run in cluster")
+		}
+		l.opts.Address = "https://" + net.JoinHostPort(host, port)
+	}
+	if l.opts.Token == "" && l.opts.TokenFile == "" {
+		if file := path.Join(kubernetesServiceAccountDir, "token"); fileExists(file) {
+			l.opts.TokenFile = file
+		}
+	}
+	if l.opts.Namespace == "" {
+		l.opts.Namespace = defaultKubernetesNamespace
+		if namespace, err := os.ReadFile(path.Join(kubernetesServiceAccountDir, "namespace")); err == nil {
+			l.opts.Namespace = strings.TrimSpace(string(namespace))
+		}
+	}
+	if l.opts.Client == nil {
+		client, err := inClusterClient()
+		if err != nil {
+			return nil, err
+		}
+		l.opts.Client = client
+	}
+	if l.opts.Key == "" {
+		l.opts.Key = defaultKubernetesKey
+	}
+	if l.opts.RetryInterval <= 0 {
+		l.opts.RetryInterval = defaultKubernetesRetryInterval
+	}
+
+	return l, nil
+}
+
+// inClusterClient return http client trusting service account
+// CA, default client is returned outside of the cluster
+func inClusterClient() (*http.Client, error) {
+	ca, err := os.ReadFile(path.Join(kubernetesServiceAccountDir, "ca.crt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return http.DefaultClient, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read kubernetes ca: %w", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("kubernetes ca has no certificates")
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		},
+	}, nil
+}
+
+// Run load pools and keep them in sync with specs by watching
+// Kubernetes objects until given context is done. Failed API
+// requests are retried, specs that can't be applied are logged
+func (l *KubernetesLoader) Run(ctx context.Context) {
+	log().Info("start kubernetes pools loader", "namespace", l.opts.Namespace)
+
+	for {
+		version, err := l.load(ctx)
+		if err == nil {
+			err = l.watch(ctx, version)
+		}
+
+		if ctx.Err() != nil {
+			log().Warn("stop kubernetes pools loader")
+			return
+		}
+
+		if err != nil {
+			log().Warn("kubernetes pools loader error", "namespace", l.opts.Namespace, "error", err)
+			SleepContext(ctx, l.opts.RetryInterval)
+		}
+	}
+}
+
+// Load read pool specs once and create, recreate
+// and remove pools according to them
+func (l *KubernetesLoader) Load(ctx context.Context) error {
+	specs, _, err := l.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	return l.Apply(specs)
+}
+
+// load read and apply pool specs and return resource
+// version to watch from, apply errors are only logged
+func (l *KubernetesLoader) load(ctx context.Context) (string, error) {
+	specs, version, err := l.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if err := l.Apply(specs); err != nil {
+		log().Warn("apply kubernetes pool specs error", "namespace", l.opts.Namespace, "error", err)
+	}
+
+	return version, nil
+}
+
+// Apply create pools of new specs, recreate pools which specs
+// have changed and remove pools which specs are gone. Invalid
+// specs are reported while the rest of them are applied, pools
+// of invalid changed specs are kept as they are
+func (l *KubernetesLoader) Apply(specs []PoolSpec) error {
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	var errs []error
+
+	seen := make(map[string]struct{}, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" {
+			errs = append(errs, ErrInvalidPoolSpec{Reason: "name is empty"})
+			continue
+		}
+		if _, ok := seen[spec.Name]; ok {
+			errs = append(errs, ErrInvalidPoolSpec{Name: spec.Name, Reason: "name is duplicated"})
+			continue
+		}
+		seen[spec.Name] = struct{}{}
+
+		applied, ok := l.applied[spec.Name]
+		if ok && reflect.DeepEqual(applied, spec) {
+			continue
+		}
+
+		opts, err := l.poolOpts(spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// changed pool is swapped with the recreated one,
+		// so the pool is never missing in the registry
+		if ok {
+			if _, err := l.opts.Registry.Replace(opts, l.opts.Healthchecks); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			l.applied[spec.Name] = spec
+
+			log().Info("pool spec is changed, the pool is recreated", "pool", spec.Name)
+			continue
+		}
+
+		if _, err := l.opts.Registry.Create(opts, l.opts.Healthchecks); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		l.applied[spec.Name] = spec
+
+		log().Info("pool is created from kubernetes spec", "pool", spec.Name)
+	}
+
+	for name := range l.applied {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		log().Info("pool spec is removed, the pool is removed", "pool", name)
+
+		delete(l.applied, name)
+		if err := l.opts.Registry.Remove(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Options build pool configuration from the spec,
+// unset intervals are replaced with spec defaults
+func (spec PoolSpec) Options() (*ServicesPoolsOpts, error) {
+	driverOpts := []discovery.Option{
+		discovery.WithAddresses(spec.Addresses...),
+		discovery.WithPath(spec.Path),
+		discovery.WithPrefix(spec.Prefix),
+		discovery.WithTags(spec.Tags...),
+		discovery.WithTimeout(spec.Timeout),
+	}
+	for key, value := range spec.Params {
+		driverOpts = append(driverOpts, discovery.WithParam(key, value))
+	}
+
+	driver, err := discovery.NewDiscovery(spec.Driver, driverOpts...)
+	if err != nil {
+		return nil, ErrInvalidPoolSpec{Name: spec.Name, Reason: err.Error()}
+	}
+
+	balancing, err := ParseBalancing(spec.Balancing)
+	if err != nil {
+		return nil, ErrInvalidPoolSpec{Name: spec.Name, Reason: err.Error()}
+	}
+
+	if spec.ShedThreshold < 0 || spec.ShedThreshold >= 1 {
+		return nil, ErrInvalidPoolSpec{Name: spec.Name, Reason: "shed threshold should be in [0, 1)"}
+	}
+
+	if spec.OverprovisioningFactor < 0 {
+		return nil, ErrInvalidPoolSpec{Name: spec.Name, Reason: "overprovisioning factor should not be negative"}
+	}
+
+	listOpts := &ServicesListOpts{
+		TryUpTries:     spec.TryUpTries,
+		TryUpInterval:  spec.TryUpInterval,
+		ChecksInterval: spec.ChecksInterval,
+		Balancing:      balancing,
+	}
+	if listOpts.TryUpInterval <= 0 {
+		listOpts.TryUpInterval = defaultSpecTryUpInterval
+	}
+	if listOpts.ChecksInterval <= 0 {
+		listOpts.ChecksInterval = defaultSpecChecksInterval
+	}
+	if spec.ShedThreshold > 0 {
+		listOpts.Shed = &ShedOpts{Policy: ShedLowestPriority, Threshold: spec.ShedThreshold}
+	}
+	if spec.OverprovisioningFactor > 0 {
+		listOpts.Priorities = &PriorityOpts{OverprovisioningFactor: float64(spec.OverprovisioningFactor) / 100}
+	}
+	if len(spec.Features) > 0 {
+		listOpts.Features = make(map[Feature]bool, len(spec.Features))
+		for name, enabled := range spec.Features {
+			listOpts.Features[Feature(name)] = enabled
+		}
+	}
+
+	opts := &ServicesPoolsOpts{
+		Name:              spec.Name,
+		Discovery:         driver,
+		DiscoveryInterval: spec.DiscoveryInterval,
+		PruneMissing:      spec.PruneMissing,
+		ListOpts:          listOpts,
+		Timeouts:          spec.Timeouts,
+	}
+	if opts.DiscoveryInterval <= 0 {
+		opts.DiscoveryInterval = defaultSpecDiscoveryInterval
+	}
+
+	if err := spec.Timeouts.Inherit(Timeouts{}).Validate(opts.DiscoveryInterval, listOpts.ChecksInterval); err != nil {
+		return nil, ErrInvalidPoolSpec{Name: spec.Name, Reason: err.Error()}
+	}
+
+	return opts, nil
+}
+
+// poolOpts build pool configuration from given
+// spec adjusted by configured customization
+func (l *KubernetesLoader) poolOpts(spec PoolSpec) (*ServicesPoolsOpts, error) {
+	opts, err := spec.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	if l.opts.Customize != nil {
+		if err := l.opts.Customize(spec, opts); err != nil {
+			return nil, ErrInvalidPoolSpec{Name: spec.Name, Reason: err.Error()}
+		}
+	}
+
+	return opts, nil
+}
+
+// fetch read pool specs and resource version they are read at
+func (l *KubernetesLoader) fetch(ctx context.Context) ([]PoolSpec, string, error) {
+	var (
+		list kubernetesList
+		err  error
+	)
+	if l.opts.ConfigMap != "" {
+		err = l.get(ctx, l.collectionPath()+"/"+l.opts.ConfigMap, nil, &list)
+	} else {
+		err = l.get(ctx, l.collectionPath(), nil, &list)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var specs []PoolSpec
+
+	if l.opts.ConfigMap != "" {
+		data, ok := list.Data[l.opts.Key]
+		if !ok {
+			return nil, "", fmt.Errorf("configmap %s has no %s key", l.opts.ConfigMap, l.opts.Key)
+		}
+
+		// durations are decoded from strings by yaml only
+		if err := yaml.Unmarshal([]byte(data), &specs); err != nil {
+			return nil, "", fmt.Errorf("decode pool specs of configmap %s: %w", l.opts.ConfigMap, err)
+		}
+
+		return specs, list.Metadata.ResourceVersion, nil
+	}
+
+	for _, item := range list.Items {
+		var spec PoolSpec
+		if len(item.Spec) > 0 {
+			// json is valid yaml, so durations are decoded from strings
+			if err := yaml.Unmarshal(item.Spec, &spec); err != nil {
+				return nil, "", fmt.Errorf("decode pool spec of %s: %w", item.Metadata.Name, err)
+			}
+		}
+		if spec.Name == "" {
+			spec.Name = item.Metadata.Name
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs, list.Metadata.ResourceVersion, nil
+}
+
+// watch block until watched objects change after given
+// resource version, the watch is expired or given
+// context is done
+func (l *KubernetesLoader) watch(ctx context.Context, version string) error {
+	query := url.Values{}
+	query.Set("watch", "true")
+	query.Set("resourceVersion", version)
+	if l.opts.ConfigMap != "" {
+		query.Set("fieldSelector", "metadata.name="+l.opts.ConfigMap)
+	}
+
+	resp, err := l.request(ctx, l.collectionPath(), query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// any event, including expired watch error,
+	// is followed by reading the specs again
+	var event struct {
+		Type string `json:"type"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil && ctx.Err() == nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("decode watch event: %w", err)
+	}
+
+	return nil
+}
+
+// collectionPath return api path of collection of watched objects
+func (l *KubernetesLoader) collectionPath() string {
+	if l.opts.Resource != nil {
+		return path.Join("/apis", l.opts.Resource.Group, l.opts.Resource.Version, "namespaces", l.opts.Namespace, l.opts.Resource.Plural)
+	}
+
+	return path.Join("/api/v1/namespaces", l.opts.Namespace, "configmaps")
+}
+
+// get request given api path and decode json response to given value
+func (l *KubernetesLoader) get(ctx context.Context, apiPath string, query url.Values, v interface{}) error {
+	resp, err := l.request(ctx, apiPath, query)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("decode kubernetes response of %s: %w", apiPath, err)
+	}
+
+	return nil
+}
+
+// token return bearer token of api requests, token file is
+// read on every request, since service account tokens are
+// projected with short lifetime and rotated by kubelet
+func (l *KubernetesLoader) token() (string, error) {
+	if l.opts.Token != "" || l.opts.TokenFile == "" {
+		return l.opts.Token, nil
+	}
+
+	token, err := os.ReadFile(l.opts.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("read kubernetes token: %w", err)
+	}
+
+	return strings.TrimSpace(string(token)), nil
+}
+
+// fileExists check if file with given path exists
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
+
+// request send get request to given api path and return
+// response, unexpected status codes are returned as error
+func (l *KubernetesLoader) request(ctx context.Context, apiPath string, query url.Values) (*http.Response, error) {
+	u := strings.TrimSuffix(l.opts.Address, "/") + apiPath
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes request: %w", err)
+	}
+
+	token, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := l.opts.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request kubernetes %s: %w", apiPath, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, ErrKubernetesRequest{Path: apiPath, StatusCode: resp.StatusCode}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,207 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKubernetes is Kubernetes API serving
+// one ConfigMap and its watch from memory
+type fakeKubernetes struct {
+	mu      sync.Mutex
+	version int
+	data    string
+	updated chan struct{}
+}
+
+// update replace pools.yaml of the ConfigMap
+func (k *fakeKubernetes) update(data string) {
+	defer k.mu.Unlock()
+	k.mu.Lock()
+
+	k.version++
+	k.data = data
+	close(k.updated)
+	k.updated = make(chan struct{})
+}
+
+func (k *fakeKubernetes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	k.mu.Lock()
+	version, data, updated := k.version, k.data, k.updated
+	k.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/api/v1/namespaces/provers/configmaps/pools":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"metadata": map[string]string{"resourceVersion": strconv.Itoa(version)},
+			"data":     map[string]string{"pools.yaml": data},
+		})
+	case r.URL.Path == "/api/v1/namespaces/provers/configmaps" && r.URL.Query().Get("watch") == "true":
+		if r.URL.Query().Get("fieldSelector") != "metadata.name=pools" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if r.URL.Query().Get("resourceVersion") == strconv.Itoa(version) {
+			select {
+			case <-updated:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{"type": "MODIFIED"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestKubernetesLoader(t *testing.T) {
+	services := filepath.Join(t.TempDir(), "services.yaml")
+
+	k8s := &fakeKubernetes{updated: make(chan struct{})}
+	k8s.update(`
+- name: provers-a
+  driver: static
+  path: ` + services + `
+  balancing: least_connections
+  checksInterval: 1m
+- name: provers-b
+  driver: static
+  path: ` + services + `
+`)
+
+	server := httptest.NewServer(k8s)
+	defer server.Close()
+
+	registry := NewPoolRegistry(&PoolRegistryOpts{})
+	defer registry.Close()
+
+	var customized []string
+	loader, err := NewKubernetesLoader(&KubernetesLoaderOpts{
+		Registry:  registry,
+		Address:   server.URL,
+		Token:     "token",
+		Client:    server.Client(),
+		Namespace: "provers",
+		ConfigMap: "pools",
+		Customize: func(spec PoolSpec, opts *ServicesPoolsOpts) error {
+			customized = append(customized, spec.Name)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected loader error: %s", err)
+	}
+
+	if err := loader.Load(context.Background()); err != nil {
+		t.Fatalf("unexpected load error: %s", err)
+	}
+	if names := registry.Names(); !reflect.DeepEqual(names, []string{"provers-a", "provers-b"}) {
+		t.Fatalf("unexpected pools %v", names)
+	}
+	if len(customized) != 2 {
+		t.Errorf("pools options should be customized, got %v", customized)
+	}
+
+	first, _ := registry.Get("provers-a")
+	if balancing := first.List().(*ServicesList).balancing; balancing != BalancingLeastConnections {
+		t.Errorf("unexpected balancing %s", balancing)
+	}
+
+	// invalid spec is reported while valid ones are kept
+	err = loader.Apply([]PoolSpec{
+		{Name: "provers-a", Driver: "static", Path: services, Balancing: "least_connections", ChecksInterval: time.Minute},
+		{Name: "provers-b", Driver: "static", Path: services},
+		{Name: "provers-c", Driver: "static", Path: services, Balancing: "fastest"},
+	})
+	var invalid ErrInvalidPoolSpec
+	if !errors.As(err, &invalid) || invalid.Name != "provers-c" {
+		t.Errorf("expected invalid spec error, got %v", err)
+	}
+	if len(registry.Names()) != 2 {
+		t.Errorf("invalid spec should not create pool, got %v", registry.Names())
+	}
+	if pool, _ := registry.Get("provers-a"); pool != first {
+		t.Errorf("unchanged pool should not be recreated")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		loader.Run(ctx)
+		close(done)
+	}()
+
+	// changed pool is recreated and removed one is removed
+	k8s.update(`
+- name: provers-a
+  driver: static
+  path: ` + services + `
+  checksInterval: 2m
+`)
+
+	waitFor(t, func() bool {
+		pool, ok := registry.Get("provers-a")
+		return ok && pool != first && len(registry.Names()) == 1
+	})
+
+	cancel()
+	<-done
+}
+
+func TestKubernetesLoaderTokenFile(t *testing.T) {
+	k8s := &fakeKubernetes{updated: make(chan struct{})}
+	k8s.update("[]")
+
+	server := httptest.NewServer(k8s)
+	defer server.Close()
+
+	registry := NewPoolRegistry(&PoolRegistryOpts{})
+	defer registry.Close()
+
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("expired\n"), 0o600); err != nil {
+		t.Fatalf("unexpected write error: %s", err)
+	}
+
+	loader, err := NewKubernetesLoader(&KubernetesLoaderOpts{
+		Registry:  registry,
+		Address:   server.URL,
+		TokenFile: token,
+		Client:    server.Client(),
+		Namespace: "provers",
+		ConfigMap: "pools",
+	})
+	if err != nil {
+		t.Fatalf("unexpected loader error: %s", err)
+	}
+
+	var request ErrKubernetesRequest
+	if err := loader.Load(context.Background()); !errors.As(err, &request) || request.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized request with expired token, got %v", err)
+	}
+
+	// rotated token is used by the next request
+	if err := os.WriteFile(token, []byte("token\n"), 0o600); err != nil {
+		t.Fatalf("unexpected write error: %s", err)
+	}
+	if err := loader.Load(context.Background()); err != nil {
+		t.Errorf("rotated token should be read, got %v", err)
+	}
+}
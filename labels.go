@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// goroutine task types used as pprof labels
+const (
+	taskHealthChecks   = "healthchecks"
+	taskScheduledCheck = "scheduled_check"
+	taskTryUp          = "try_up"
+	taskReview         = "review"
+	taskMaintenance    = "maintenance"
+	taskLease          = "lease"
+	taskDiscovery      = "discovery"
+	taskRecorder       = "recorder"
+	taskPublisher      = "publisher"
+	taskPersister      = "persister"
+	taskGossip         = "gossip"
+	taskShard          = "shard"
+	taskScheduler      = "scheduler"
+	taskSignals        = "signals"
+	taskSeeds          = "seeds"
+)
+
+// doLabeled run given function on the current goroutine
+// labeled with given pool name and task type, so goroutine
+// dumps and profiles taken during incidents attribute the
+// work to the pool. Pool label is omitted for goroutines
+// shared between pools. Goroutines started by the
+// function inherit the labels
+func doLabeled(pool, task string, fn func()) {
+	labels := pprof.Labels("task", task)
+	if pool != "" {
+		labels = pprof.Labels("pool", pool, "task", task)
+	}
+
+	pprof.Do(context.Background(), labels, func(context.Context) {
+		fn()
+	})
+}
+
+// goLabeled run given function on new goroutine
+// labeled with given pool name and task type
+func goLabeled(pool, task string, fn func()) {
+	go doLabeled(pool, task, fn)
+}
+
+// goTask run given function on new goroutine labeled
+// with the list name and given task type
+func (l *ServicesList) goTask(task string, fn func()) {
+	goLabeled(l.serviceName, task, fn)
+}
+
+// goTryUp start trying to up given service
+// on new labeled goroutine
+func (l *ServicesList) goTryUp(srv service.IService) {
+	l.goTask(taskTryUp, func() {
+		l.TryUpService(srv, 0)
+	})
+}
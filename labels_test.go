@@ -0,0 +1,31 @@
+package pool
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoroutineLabels(t *testing.T) {
+	list := NewServicesList("labeledServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	// try up goroutine of jailed service waits for the next try
+	list.Add(newUnhealthyService("https://1gateway.fm"))
+
+	waitFor(t, func() bool {
+		var dump bytes.Buffer
+		if err := pprof.Lookup("goroutine").WriteTo(&dump, 1); err != nil {
+			t.Fatalf("unexpected goroutine dump error: %s", err)
+		}
+
+		return strings.Contains(dump.String(), `"pool":"labeledServicesList"`) &&
+			strings.Contains(dump.String(), `"task":"try_up"`)
+	})
+}
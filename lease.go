@@ -0,0 +1,344 @@
+package pool
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// LeaseState represent state of lease
+type LeaseState string
+
+const (
+	// LeaseActive is lease of service that takes work as usual
+	LeaseActive LeaseState = "active"
+
+	// LeaseDraining is lease of drained service, holder
+	// should finish or migrate the work until the deadline
+	LeaseDraining LeaseState = "draining"
+
+	// LeaseReleased is lease released by its holder
+	LeaseReleased LeaseState = "released"
+
+	// LeaseExpired is lease force-expired by the list
+	// because the handoff window has passed
+	LeaseExpired LeaseState = "expired"
+)
+
+// Lease is assignment of service to its holder, e.g. proof job
+// dispatched to prover. When leased service is drained holder is
+// notified via Draining channel and should finish or migrate the
+// work before the lease is force-expired
+type Lease struct {
+	ID       string
+	Holder   string
+	Service  service.IService
+	Acquired time.Time
+
+	list *ServicesList
+
+	mu       sync.Mutex
+	state    LeaseState
+	deadline time.Time
+	timer    *time.Timer
+
+	draining chan struct{}
+	expired  chan struct{}
+}
+
+// Draining return channel that is closed
+// when leased service is drained
+func (le *Lease) Draining() <-chan struct{} {
+	return le.draining
+}
+
+// Expired return channel that is closed
+// when lease is force-expired
+func (le *Lease) Expired() <-chan struct{} {
+	return le.expired
+}
+
+// State return current lease state
+func (le *Lease) State() LeaseState {
+	defer le.mu.Unlock()
+	le.mu.Lock()
+
+	return le.state
+}
+
+// Deadline return time the draining lease is force-expired
+// at, zero time is returned for active leases
+func (le *Lease) Deadline() time.Time {
+	defer le.mu.Unlock()
+	le.mu.Lock()
+
+	return le.deadline
+}
+
+// Release release the lease, draining lease released
+// before the deadline is reported as handed off
+func (le *Lease) Release() {
+	le.mu.Lock()
+	previous := le.state
+	if previous == LeaseActive || previous == LeaseDraining {
+		le.state = LeaseReleased
+		if le.timer != nil {
+			le.timer.Stop()
+		}
+	}
+	le.mu.Unlock()
+
+	if previous != LeaseActive && previous != LeaseDraining {
+		return
+	}
+
+	le.list.leases.remove(le)
+	le.list.checkDrained(le.Service.ID())
+
+	if previous == LeaseDraining {
+		le.list.log().Info("lease is handed off", "lease", le.ID, "service", le.Service.ID(), "holder", le.Holder)
+
+		le.list.emit(PoolEvent{Type: EventLeaseReleased, Service: le.Service, Lease: le})
+	}
+}
+
+// drain notify holder that leased service is drained and
+// force-expire the lease after given window, zero window
+// expires the lease immediately
+func (le *Lease) drain(window time.Duration) bool {
+	defer le.mu.Unlock()
+	le.mu.Lock()
+
+	if le.state != LeaseActive {
+		return false
+	}
+
+	le.state = LeaseDraining
+	le.deadline = time.Now().Add(window)
+	le.timer = time.AfterFunc(window, func() {
+		doLabeled(le.list.serviceName, taskLease, le.expire)
+	})
+	close(le.draining)
+
+	return true
+}
+
+// expire force-expire draining lease
+func (le *Lease) expire() {
+	le.mu.Lock()
+	if le.state != LeaseDraining {
+		le.mu.Unlock()
+		return
+	}
+	le.state = LeaseExpired
+	le.mu.Unlock()
+
+	le.list.leases.remove(le)
+	le.list.checkDrained(le.Service.ID())
+	close(le.expired)
+
+	le.list.log().Warn("lease is expired", "lease", le.ID, "service", le.Service.ID(), "holder", le.Holder)
+
+	le.list.emit(PoolEvent{Type: EventLeaseExpired, Service: le.Service, Lease: le})
+}
+
+// leases is set of active and draining leases by service
+type leases struct {
+	mu        sync.Mutex
+	byService map[string]map[string]*Lease // service id -> lease id -> lease
+}
+
+// newLeases create empty leases set
+func newLeases() *leases {
+	return &leases{byService: make(map[string]map[string]*Lease)}
+}
+
+// add add given lease to the set
+func (s *leases) add(lease *Lease) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	id := lease.Service.ID()
+	if s.byService[id] == nil {
+		s.byService[id] = make(map[string]*Lease)
+	}
+	s.byService[id][lease.ID] = lease
+}
+
+// remove remove given lease from the set
+func (s *leases) remove(lease *Lease) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	id := lease.Service.ID()
+	delete(s.byService[id], lease.ID)
+	if len(s.byService[id]) == 0 {
+		delete(s.byService, id)
+	}
+}
+
+// of return leases of service with given id ordered by acquisition
+func (s *leases) of(id string) []*Lease {
+	s.mu.Lock()
+	leases := make([]*Lease, 0, len(s.byService[id]))
+	for _, lease := range s.byService[id] {
+		leases = append(leases, lease)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(leases, func(i, j int) bool {
+		return leases[i].Acquired.Before(leases[j].Acquired)
+	})
+
+	return leases
+}
+
+// count return number of leases of service with given id
+func (s *leases) count(id string) int {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	return len(s.byService[id])
+}
+
+// all return leases of all services
+func (s *leases) all() []*Lease {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	var leases []*Lease
+	for _, byID := range s.byService {
+		for _, lease := range byID {
+			leases = append(leases, lease)
+		}
+	}
+
+	return leases
+}
+
+// total return number of leases of all services
+func (s *leases) total() int {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	total := 0
+	for _, byID := range s.byService {
+		total += len(byID)
+	}
+
+	return total
+}
+
+// Lease select next healthy service and lease it to given holder
+func (l *ServicesList) Lease(holder string) (*Lease, error) {
+	if l.Draining() {
+		return nil, ErrPoolDraining{Pool: l.serviceName}
+	}
+
+	srv, err := l.NextE()
+	if err != nil {
+		return nil, err
+	}
+
+	return l.LeaseService(srv.ID(), holder)
+}
+
+// LeaseService lease healthy service with given id to
+// given holder, e.g. service selected by NextWhere.
+// Drained service is not leased as it takes no new work
+func (l *ServicesList) LeaseService(id, holder string) (*Lease, error) {
+	if l.Draining() {
+		return nil, ErrPoolDraining{Pool: l.serviceName}
+	}
+
+	// the lease is added under the list lock, so service
+	// drained concurrently hands off the lease as well
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	srv := findService(l.healthy, id)
+	if srv == nil {
+		return nil, ErrServiceNotFound{ID: id}
+	}
+
+	if l.drains.has(id) {
+		return nil, ErrServiceDrained{ID: id}
+	}
+
+	lease := &Lease{
+		ID:       newLeaseID(),
+		Holder:   holder,
+		Service:  srv,
+		Acquired: time.Now(),
+		list:     l,
+		state:    LeaseActive,
+		draining: make(chan struct{}),
+		expired:  make(chan struct{}),
+	}
+
+	l.leases.add(lease)
+
+	return lease, nil
+}
+
+// newLeaseID return random lease id, it's longer than
+// maintenance window one as leases are acquired per
+// job and ids should not collide over list lifetime
+func newLeaseID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+// Leases return active and draining leases of service
+// with given id ordered by acquisition
+func (l *ServicesList) Leases(id string) []*Lease {
+	return l.leases.of(id)
+}
+
+// handoffLeases notify holders of leases of service with given id
+// that the service is drained, leases that are not released during
+// configured handoff window are force-expired
+func (l *ServicesList) handoffLeases(id string) {
+	for _, lease := range l.leases.of(id) {
+		if !lease.drain(l.leaseHandoff) {
+			continue
+		}
+
+		l.log().Info("lease is draining", "lease", lease.ID, "service", id, "holder", lease.Holder, "deadline", lease.Deadline().UTC().Format(time.RFC3339))
+
+		if l.onLeaseDrain != nil {
+			l.onLeaseDrain(lease)
+		}
+
+		l.emit(PoolEvent{Type: EventLeaseDraining, Service: lease.Service, Lease: lease})
+	}
+}
+
+// Lease select next healthy service of any
+// shard and lease it to given holder
+func (l *ShardedServicesList) Lease(holder string) (*Lease, error) {
+	srv, err := l.NextE()
+	if err != nil {
+		return nil, err
+	}
+
+	return l.LeaseService(srv.ID(), holder)
+}
+
+// LeaseService lease service with
+// given id to given holder
+func (l *ShardedServicesList) LeaseService(id, holder string) (*Lease, error) {
+	return l.shard(id).LeaseService(id, holder)
+}
+
+// Leases return active and draining
+// leases of service with given id
+func (l *ShardedServicesList) Leases(id string) []*Lease {
+	return l.shard(id).Leases(id)
+}
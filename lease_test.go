@@ -0,0 +1,140 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLeaseHandoff(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []EventType
+		calls  int
+	)
+
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		LeaseHandoff:   100 * time.Millisecond,
+		OnLeaseDrain: func(*Lease) {
+			mu.Lock()
+			calls++
+			mu.Unlock()
+		},
+		OnEvent: func(event PoolEvent) {
+			mu.Lock()
+			events = append(events, event.Type)
+			mu.Unlock()
+		},
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	migrated, err := list.Lease("job-1")
+	if err != nil {
+		t.Fatalf("unexpected lease error: %s", err)
+	}
+	stuck, err := list.LeaseService(srv.ID(), "job-2")
+	if err != nil {
+		t.Fatalf("unexpected lease error: %s", err)
+	}
+
+	if leases := list.Leases(srv.ID()); len(leases) != 2 || leases[0] != migrated {
+		t.Fatalf("unexpected leases %v", leases)
+	}
+
+	if _, err := list.ScheduleMaintenance(MaintenanceWindow{Service: srv.ID(), Action: MaintenanceCordon}); err != nil {
+		t.Fatalf("unexpected schedule error: %s", err)
+	}
+
+	select {
+	case <-migrated.Draining():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("lease holder should be notified")
+	}
+
+	if migrated.State() != LeaseDraining || migrated.Deadline().IsZero() {
+		t.Errorf("unexpected lease state %s", migrated.State())
+	}
+	migrated.Release()
+
+	select {
+	case <-stuck.Expired():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("lease should be force-expired")
+	}
+
+	if migrated.State() != LeaseReleased || stuck.State() != LeaseExpired {
+		t.Errorf("unexpected lease states %s and %s", migrated.State(), stuck.State())
+	}
+	if leases := list.Leases(srv.ID()); len(leases) != 0 {
+		t.Errorf("expected no leases, got %d", len(leases))
+	}
+
+	// releasing expired lease is no-op
+	stuck.Release()
+
+	// expiration event is emitted after the channel is closed
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(events) == 4
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if calls != 2 {
+		t.Errorf("expected 2 drain callbacks, got %d", calls)
+	}
+
+	counts := make(map[EventType]int)
+	for _, event := range events {
+		counts[event]++
+	}
+	if counts[EventLeaseDraining] != 2 || counts[EventLeaseReleased] != 1 || counts[EventLeaseExpired] != 1 {
+		t.Errorf("unexpected lease events %v", counts)
+	}
+}
+
+func TestLeaseUnavailable(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	if _, err := list.Lease("job"); !errors.As(err, &ErrNoHealthyServices{}) {
+		t.Errorf("expected no healthy services error, got %v", err)
+	}
+	if _, err := list.LeaseService("unknown", "job"); !errors.As(err, &ErrServiceNotFound{}) {
+		t.Errorf("expected service not found error, got %v", err)
+	}
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	lease, err := list.Lease("job")
+	if err != nil {
+		t.Fatalf("unexpected lease error: %s", err)
+	}
+
+	// released active lease is not reported as handoff
+	lease.Release()
+
+	if state := list.Snapshot(); state.Services[0].Leases != 0 {
+		t.Errorf("expected no leases, got %d", state.Services[0].Leases)
+	}
+	if err := list.DrainService(srv, nil); err != nil {
+		t.Fatalf("unexpected drain error: %s", err)
+	}
+	if _, err := list.LeaseService(srv.ID(), "job"); !errors.As(err, &ErrServiceDrained{}) {
+		t.Errorf("expected service drained error, got %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package pool
+
+import (
+	"log/slog"
+	"slices"
+	"sync/atomic"
+
+	"github.com/gateway-fm/scriptorium/logger"
+)
+
+// ILogger is leveled structured logger used by pools, lists
+// and other library components, args are alternating keys
+// and values like slog ones. It should be safe for
+// concurrent use
+type ILogger interface {
+	// Debug log verbose message, e.g. of every healthcheck
+	Debug(msg string, args ...any)
+
+	// Info log informational message
+	Info(msg string, args ...any)
+
+	// Warn log message about recoverable problem
+	Warn(msg string, args ...any)
+
+	// Error log message about failure
+	Error(msg string, args ...any)
+
+	// With return logger adding given
+	// key value pairs to every message
+	With(args ...any) ILogger
+}
+
+// loggerHolder hold logger, so loggers of
+// different types could be stored atomically
+type loggerHolder struct {
+	logger ILogger
+}
+
+// currentLogger is logger used by the library
+var currentLogger atomic.Pointer[loggerHolder]
+
+// SetLogger replace logger used by the library, e.g. with
+// NewSlogLogger. Nil restores the default scriptorium logger.
+// Pools and lists configured with own Logger don't use it
+func SetLogger(l ILogger) {
+	if l == nil {
+		currentLogger.Store(nil)
+		return
+	}
+
+	currentLogger.Store(&loggerHolder{logger: l})
+}
+
+// log return logger used by the library
+func log() ILogger {
+	if holder := currentLogger.Load(); holder != nil {
+		return holder.logger
+	}
+
+	return scriptoriumLogger{}
+}
+
+// scriptoriumLogger is default logger
+// backed by scriptorium zap logger
+type scriptoriumLogger struct {
+	fields []any
+}
+
+// Debug log message with debug level
+func (l scriptoriumLogger) Debug(msg string, args ...any) {
+	logger.Log().Sugar().Debugw(msg, slices.Concat(l.fields, args)...)
+}
+
+// Info log message with info level
+func (l scriptoriumLogger) Info(msg string, args ...any) {
+	logger.Log().Sugar().Infow(msg, slices.Concat(l.fields, args)...)
+}
+
+// Warn log message with warn level
+func (l scriptoriumLogger) Warn(msg string, args ...any) {
+	logger.Log().Sugar().Warnw(msg, slices.Concat(l.fields, args)...)
+}
+
+// Error log message with error level
+func (l scriptoriumLogger) Error(msg string, args ...any) {
+	logger.Log().Sugar().Errorw(msg, slices.Concat(l.fields, args)...)
+}
+
+// With return logger with given fields
+func (l scriptoriumLogger) With(args ...any) ILogger {
+	return scriptoriumLogger{fields: slices.Concat(l.fields, args)}
+}
+
+// slogLogger is logger backed by slog
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger return logger writing to given slog logger,
+// levels are mapped to slog ones, so debug messages are
+// filtered out by the handler level
+func NewSlogLogger(l *slog.Logger) ILogger {
+	return slogLogger{logger: l}
+}
+
+// Debug log message with debug level
+func (l slogLogger) Debug(msg string, args ...any) {
+	l.logger.Debug(msg, args...)
+}
+
+// Info log message with info level
+func (l slogLogger) Info(msg string, args ...any) {
+	l.logger.Info(msg, args...)
+}
+
+// Warn log message with warn level
+func (l slogLogger) Warn(msg string, args ...any) {
+	l.logger.Warn(msg, args...)
+}
+
+// Error log message with error level
+func (l slogLogger) Error(msg string, args ...any) {
+	l.logger.Error(msg, args...)
+}
+
+// With return logger with given fields
+func (l slogLogger) With(args ...any) ILogger {
+	return slogLogger{logger: l.logger.With(args...)}
+}
+
+// log return logger of the list, given with options or
+// set with SetLogger, adding the list name to messages
+func (l *ServicesList) log() ILogger {
+	logger := l.logger
+	if logger == nil {
+		logger = log()
+	}
+
+	return logger.With("list", l.serviceName)
+}
+
+// log return logger of the pool, given with options or
+// set with SetLogger, adding the pool name to messages
+func (p *ServicesPool) log() ILogger {
+	logger := p.logger
+	if logger == nil {
+		logger = log()
+	}
+
+	return logger.With("pool", p.name)
+}
+
+// log return logger of the first shard, all
+// shards share the logger and the list name
+func (l *ShardedServicesList) log() ILogger {
+	return l.shards[0].log()
+}
+
+// listLogger return logger of given list if it has
+// own one, otherwise the library logger
+func listLogger(list any) ILogger {
+	if l, ok := list.(interface{ log() ILogger }); ok {
+		return l.log()
+	}
+
+	return log()
+}
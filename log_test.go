@@ -0,0 +1,185 @@
+package pool
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingLogger is logger that records messages
+// with theirs levels and key value pairs
+type recordingLogger struct {
+	mu       *sync.Mutex
+	messages *[]string
+	fields   []any
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{mu: &sync.Mutex{}, messages: &[]string{}}
+}
+
+func (l *recordingLogger) record(level, msg string, args []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line := level + " " + msg
+	for i, arg := range append(slices.Clone(l.fields), args...) {
+		if i%2 == 0 {
+			line += fmt.Sprintf(" %v=", arg)
+		} else {
+			line += fmt.Sprint(arg)
+		}
+	}
+
+	*l.messages = append(*l.messages, line)
+}
+
+func (l *recordingLogger) Debug(msg string, args ...any) { l.record("debug", msg, args) }
+func (l *recordingLogger) Info(msg string, args ...any)  { l.record("info", msg, args) }
+func (l *recordingLogger) Warn(msg string, args ...any)  { l.record("warn", msg, args) }
+func (l *recordingLogger) Error(msg string, args ...any) { l.record("error", msg, args) }
+
+func (l *recordingLogger) With(args ...any) ILogger {
+	return &recordingLogger{mu: l.mu, messages: l.messages, fields: append(slices.Clone(l.fields), args...)}
+}
+
+func (l *recordingLogger) has(prefix string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, msg := range *l.messages {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *recordingLogger) empty() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(*l.messages) == 0
+}
+
+func TestSetLogger(t *testing.T) {
+	recorder := newRecordingLogger()
+
+	SetLogger(recorder)
+	defer SetLogger(nil)
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	srv := newSwitchableService("https://1gateway.fm")
+	list.Add(srv)
+	list.HealthChecks()
+
+	if !recorder.has(fmt.Sprintf("debug service is healthy list=testServicesList service=%s", srv.ID())) {
+		t.Errorf("successful healthcheck should be logged with debug level, got %v", *recorder.messages)
+	}
+
+	srv.down.Store(true)
+	list.HealthChecks()
+
+	if !recorder.has(fmt.Sprintf("warn healthcheck error list=testServicesList service=%s", srv.ID())) {
+		t.Errorf("failed healthcheck should be logged with warn level, got %v", *recorder.messages)
+	}
+}
+
+func TestServicesPoolLogger(t *testing.T) {
+	global, own := newRecordingLogger(), newRecordingLogger()
+
+	SetLogger(global)
+	defer SetLogger(nil)
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name: "testServicesPool",
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  time.Hour,
+			ChecksInterval: time.Hour,
+		},
+		Logger: own,
+	})
+	defer pool.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	pool.List().Add(srv)
+
+	if !own.has(fmt.Sprintf("info service added to list list=testServicesPool service=%s", srv.ID())) {
+		t.Errorf("list should inherit logger of the pool, got %v", *own.messages)
+	}
+
+	if !global.empty() {
+		t.Errorf("pool with own logger should not use the library one, got %v", *global.messages)
+	}
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	l.Debug("verbose")
+	l.Info("started")
+	l.With("list", "provers").Warn("degraded", "healthy", 1)
+
+	out := buf.String()
+	if strings.Contains(out, "verbose") || !strings.Contains(out, "level=INFO msg=started") || !strings.Contains(out, "level=WARN msg=degraded list=provers healthy=1") {
+		t.Errorf("unexpected slog output %q", out)
+	}
+}
+
+func TestStructuredPoolLogs(t *testing.T) {
+	global, own := newRecordingLogger(), newRecordingLogger()
+
+	SetLogger(global)
+	defer SetLogger(nil)
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name: "testServicesPool",
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  time.Hour,
+			ChecksInterval: time.Hour,
+		},
+		Logger: own,
+	})
+	defer pool.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	pool.List().Add(srv)
+	pool.List().RemoveByID(srv.ID())
+
+	if !own.has(fmt.Sprintf("info service is removed list=testServicesPool service=%s", srv.ID())) {
+		t.Errorf("removal should be logged with service field, got %v", *own.messages)
+	}
+
+	stop := make(chan struct{})
+	recorder := NewRecorder(pool.List().(IRecordedList), &RecorderOpts{Dir: t.TempDir(), Interval: time.Hour})
+	done := make(chan struct{})
+	go func() {
+		recorder.Run(stop)
+		close(done)
+	}()
+	close(stop)
+	<-done
+
+	if !own.has("info start history recorder list=testServicesPool") {
+		t.Errorf("recorder should use logger of the list, got %v", *own.messages)
+	}
+
+	if !global.empty() {
+		t.Errorf("pool with own logger should not use the library one, got %v", *global.messages)
+	}
+}
@@ -0,0 +1,418 @@
+package pool
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// MaintenanceAction represent action applied
+// to service during maintenance window
+type MaintenanceAction string
+
+const (
+	// MaintenanceCordon exclude service from selection while
+	// it is kept in the list and healthchecked as usual
+	MaintenanceCordon MaintenanceAction = "cordon"
+
+	// MaintenanceJail move service to the jail and
+	// hold it there until the window ends
+	MaintenanceJail MaintenanceAction = "jail"
+)
+
+// MaintenanceWindow is cordon or jail of service scheduled
+// by operator, reverted automatically when the window ends
+type MaintenanceWindow struct {
+	ID       string            `json:"id"`
+	Service  string            `json:"service"`
+	Action   MaintenanceAction `json:"action"`
+	Start    time.Time         `json:"start"`    // zero to start immediately
+	Duration time.Duration     `json:"duration"` // zero to keep until cancelled
+	Reason   string            `json:"reason,omitempty"`
+	Active   bool              `json:"active"`
+}
+
+// maintenanceEntry is scheduled maintenance
+// window with its start and end timers
+type maintenanceEntry struct {
+	window MaintenanceWindow
+	start  *time.Timer
+	end    *time.Timer
+}
+
+// maintenance is set of scheduled maintenance windows
+type maintenance struct {
+	mu      sync.RWMutex
+	entries map[string]*maintenanceEntry
+
+	cordoned map[string]int // service id -> number of active cordon windows
+	held     map[string]int // service id -> number of active jail windows
+}
+
+// newMaintenance create empty maintenance schedule
+func newMaintenance() *maintenance {
+	return &maintenance{
+		entries:  make(map[string]*maintenanceEntry),
+		cordoned: make(map[string]int),
+		held:     make(map[string]int),
+	}
+}
+
+// isCordoned check if service with given id is cordoned
+func (m *maintenance) isCordoned(id string) bool {
+	defer m.mu.RUnlock()
+	m.mu.RLock()
+
+	return m.cordoned[id] > 0
+}
+
+// isHeld check if service with given
+// id is held in the jail by operator
+func (m *maintenance) isHeld(id string) bool {
+	defer m.mu.RUnlock()
+	m.mu.RLock()
+
+	return m.held[id] > 0
+}
+
+// action return action of active maintenance window
+// of service with given id, jail takes precedence
+func (m *maintenance) action(id string) MaintenanceAction {
+	defer m.mu.RUnlock()
+	m.mu.RLock()
+
+	switch {
+	case m.held[id] > 0:
+		return MaintenanceJail
+	case m.cordoned[id] > 0:
+		return MaintenanceCordon
+	default:
+		return ""
+	}
+}
+
+// ScheduleMaintenance schedule cordon or jail of service
+// during given window and return the scheduled window
+// with assigned id. The action is reverted automatically
+// after the window duration and recorded in the audit log
+func (l *ServicesList) ScheduleMaintenance(window MaintenanceWindow) (MaintenanceWindow, error) {
+	if window.Action != MaintenanceCordon && window.Action != MaintenanceJail {
+		return MaintenanceWindow{}, ErrUnsupportedMaintenanceAction{Action: string(window.Action)}
+	}
+
+	l.mu.RLock()
+	member := l.isMember(window.Service)
+	l.mu.RUnlock()
+
+	if !member {
+		return MaintenanceWindow{}, ErrServiceNotFound{ID: window.Service}
+	}
+
+	window.ID = newMaintenanceID()
+	window.Active = false
+
+	entry := &maintenanceEntry{window: window}
+
+	l.maintenance.mu.Lock()
+	l.maintenance.entries[window.ID] = entry
+	entry.start = time.AfterFunc(time.Until(window.Start), func() {
+		doLabeled(l.serviceName, taskMaintenance, func() {
+			l.startMaintenance(window.ID)
+		})
+	})
+	l.maintenance.mu.Unlock()
+
+	l.audit.record("maintenance_scheduled", window.Service, maintenanceDetail(window))
+
+	l.log().Info("maintenance is scheduled", "maintenance", window.ID, "service", window.Service, "detail", maintenanceDetail(window))
+
+	return window, nil
+}
+
+// CancelMaintenance cancel maintenance window with given
+// id, action of active window is reverted immediately
+func (l *ServicesList) CancelMaintenance(id string) error {
+	l.maintenance.mu.Lock()
+	entry, ok := l.maintenance.entries[id]
+	if ok {
+		entry.start.Stop()
+		if entry.end != nil {
+			entry.end.Stop()
+		}
+	}
+	l.maintenance.mu.Unlock()
+
+	if !ok {
+		return ErrMaintenanceNotFound{ID: id}
+	}
+
+	l.endMaintenance(id, "maintenance_cancelled")
+
+	return nil
+}
+
+// Maintenance return scheduled and active
+// maintenance windows ordered by start
+func (l *ServicesList) Maintenance() []MaintenanceWindow {
+	defer l.maintenance.mu.Unlock()
+	l.maintenance.mu.Lock()
+
+	windows := make([]MaintenanceWindow, 0, len(l.maintenance.entries))
+	for _, entry := range l.maintenance.entries {
+		windows = append(windows, entry.window)
+	}
+
+	sortMaintenanceWindows(windows)
+
+	return windows
+}
+
+// startMaintenance apply action of maintenance window
+// with given id and schedule its revert
+func (l *ServicesList) startMaintenance(id string) {
+	l.maintenance.mu.Lock()
+	entry, ok := l.maintenance.entries[id]
+	if !ok {
+		l.maintenance.mu.Unlock()
+		return
+	}
+
+	entry.window.Active = true
+	window := entry.window
+
+	switch window.Action {
+	case MaintenanceCordon:
+		l.maintenance.cordoned[window.Service]++
+	case MaintenanceJail:
+		l.maintenance.held[window.Service]++
+	}
+
+	if window.Duration > 0 {
+		entry.end = time.AfterFunc(window.Duration, func() {
+			doLabeled(l.serviceName, taskMaintenance, func() {
+				l.endMaintenance(id, "maintenance_ended")
+			})
+		})
+	}
+	l.maintenance.mu.Unlock()
+
+	if window.Action == MaintenanceJail {
+		l.forceJail(window.Service)
+	}
+
+	// selection caches depend on the generation
+	l.mu.Lock()
+	l.bumpGeneration()
+	l.mu.Unlock()
+
+	l.handoffLeases(window.Service)
+
+	l.audit.record("maintenance_started", window.Service, maintenanceDetail(window))
+
+	l.log().Info("maintenance is started", "maintenance", id, "service", window.Service)
+}
+
+// endMaintenance revert action of maintenance window
+// with given id and remove it from the schedule
+func (l *ServicesList) endMaintenance(id, action string) {
+	l.maintenance.mu.Lock()
+	entry, ok := l.maintenance.entries[id]
+	if !ok {
+		l.maintenance.mu.Unlock()
+		return
+	}
+	delete(l.maintenance.entries, id)
+
+	window := entry.window
+	if window.Active {
+		switch window.Action {
+		case MaintenanceCordon:
+			decrementCounter(l.maintenance.cordoned, window.Service)
+		case MaintenanceJail:
+			decrementCounter(l.maintenance.held, window.Service)
+		}
+	}
+	l.maintenance.mu.Unlock()
+
+	l.mu.Lock()
+	l.bumpGeneration()
+	l.mu.Unlock()
+
+	l.audit.record(action, window.Service, maintenanceDetail(window))
+
+	l.log().Info("maintenance is finished", "maintenance", id, "service", window.Service)
+}
+
+// forceJail move healthy service with given id to the jail without
+// counting it as a flap and start trying to up it, the service is
+// not recovered while it is held by maintenance window
+func (l *ServicesList) forceJail(id string) {
+	l.mu.Lock()
+
+	var srv service.IService
+	for i, s := range l.healthy {
+		if s.ID() == id {
+			srv = s
+			l.removeFromHealthy(i)
+			l.setStatus(srv, service.StatusUnHealthy)
+			l.toJail(srv)
+			l.bumpGeneration()
+			break
+		}
+	}
+
+	l.mu.Unlock()
+
+	if srv == nil {
+		return
+	}
+
+	l.log().Info("service is moved from healthy to jail by maintenance", "service", id)
+
+	l.emit(PoolEvent{Type: EventServiceJailed, Service: srv})
+
+	l.goTryUp(srv)
+}
+
+// ForceJail move healthy service with given id to the jail by
+// operator without counting it as a flap, the service is tried
+// to up as usual. Maintenance window should be scheduled to keep
+// the service in the jail
+func (l *ServicesList) ForceJail(id string) error {
+	l.mu.RLock()
+	_, membership := l.lookup(id)
+	l.mu.RUnlock()
+
+	if membership != MembershipHealthy {
+		return ErrServiceNotFound{ID: id}
+	}
+
+	l.forceJail(id)
+	l.audit.record("force_jailed", id, "")
+
+	return nil
+}
+
+// ForceRecover move jailed service with given id back to
+// healthy by operator without waiting for its try up
+func (l *ServicesList) ForceRecover(id string) error {
+	l.mu.RLock()
+	srv, membership := l.lookup(id)
+	l.mu.RUnlock()
+
+	if membership != MembershipJailed {
+		return ErrServiceNotFound{ID: id}
+	}
+
+	if l.maintenance.isHeld(id) {
+		return ErrServiceHeld{ID: id}
+	}
+
+	l.FromJailToHealthy(srv)
+	l.audit.record("force_recovered", id, "")
+
+	return nil
+}
+
+// cancelAllMaintenance stop timers of all maintenance windows
+func (l *ServicesList) cancelAllMaintenance() {
+	defer l.maintenance.mu.Unlock()
+	l.maintenance.mu.Lock()
+
+	for _, entry := range l.maintenance.entries {
+		entry.start.Stop()
+		if entry.end != nil {
+			entry.end.Stop()
+		}
+	}
+}
+
+// ScheduleMaintenance schedule cordon or jail
+// of service during given window
+func (l *ShardedServicesList) ScheduleMaintenance(window MaintenanceWindow) (MaintenanceWindow, error) {
+	return l.shard(window.Service).ScheduleMaintenance(window)
+}
+
+// CancelMaintenance cancel maintenance window with given id
+func (l *ShardedServicesList) CancelMaintenance(id string) error {
+	for _, shard := range l.shards {
+		if err := shard.CancelMaintenance(id); err == nil {
+			return nil
+		}
+	}
+
+	return ErrMaintenanceNotFound{ID: id}
+}
+
+// Maintenance return scheduled and active maintenance
+// windows of all shards ordered by start
+func (l *ShardedServicesList) Maintenance() []MaintenanceWindow {
+	var windows []MaintenanceWindow
+	for _, shard := range l.shards {
+		windows = append(windows, shard.Maintenance()...)
+	}
+
+	sortMaintenanceWindows(windows)
+
+	return windows
+}
+
+// ForceJail move healthy service with
+// given id of its shard to the jail
+func (l *ShardedServicesList) ForceJail(id string) error {
+	return l.shard(id).ForceJail(id)
+}
+
+// ForceRecover move jailed service with
+// given id of its shard back to healthy
+func (l *ShardedServicesList) ForceRecover(id string) error {
+	return l.shard(id).ForceRecover(id)
+}
+
+// sortMaintenanceWindows sort given windows by start and id
+func sortMaintenanceWindows(windows []MaintenanceWindow) {
+	sort.Slice(windows, func(i, j int) bool {
+		if !windows[i].Start.Equal(windows[j].Start) {
+			return windows[i].Start.Before(windows[j].Start)
+		}
+		return windows[i].ID < windows[j].ID
+	})
+}
+
+// maintenanceDetail return audit log
+// detail of given maintenance window
+func maintenanceDetail(window MaintenanceWindow) string {
+	detail := fmt.Sprintf("id=%s action=%s start=%s", window.ID, window.Action, window.Start.UTC().Format(time.RFC3339))
+	if window.Duration > 0 {
+		detail += fmt.Sprintf(" duration=%s", window.Duration)
+	}
+	if window.Reason != "" {
+		detail += fmt.Sprintf(" reason=%q", window.Reason)
+	}
+
+	return detail
+}
+
+// newMaintenanceID return random maintenance window id
+func newMaintenanceID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
+
+// decrementCounter decrement counter with given
+// key and remove it when it reaches zero
+func decrementCounter(counters map[string]int, key string) {
+	if counters[key] <= 1 {
+		delete(counters, key)
+		return
+	}
+
+	counters[key]--
+}
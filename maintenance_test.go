@@ -0,0 +1,254 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestMaintenanceCordon(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	first := newHealthyService("https://1gateway.fm")
+	second := newHealthyService("https://2gateway.fm")
+	list.Add(first)
+	list.Add(second)
+
+	window, err := list.ScheduleMaintenance(MaintenanceWindow{
+		Service:  first.ID(),
+		Action:   MaintenanceCordon,
+		Duration: 200 * time.Millisecond,
+		Reason:   "kernel upgrade",
+	})
+	if err != nil {
+		t.Fatalf("unexpected schedule error: %s", err)
+	}
+
+	waitFor(t, func() bool { return list.maintenance.isCordoned(first.ID()) })
+
+	for i := 0; i < 10; i++ {
+		if srv := list.Next(); srv == nil || srv.ID() == first.ID() {
+			t.Fatalf("cordoned service should not be selected")
+		}
+	}
+
+	if healthy := list.Healthy(); len(healthy) != 2 {
+		t.Errorf("cordoned service should be kept healthy, got %d healthy", len(healthy))
+	}
+
+	// cordon is reverted when the window ends
+	waitFor(t, func() bool { return len(list.Maintenance()) == 0 })
+
+	selected := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		selected[list.Next().ID()] = true
+	}
+	if !selected[first.ID()] {
+		t.Errorf("service should be selected after cordon is reverted")
+	}
+
+	var actions []string
+	for _, entry := range list.AuditLog() {
+		if strings.Contains(entry.Detail, window.ID) {
+			actions = append(actions, entry.Action)
+		}
+	}
+	if strings.Join(actions, ",") != "maintenance_scheduled,maintenance_started,maintenance_ended" {
+		t.Errorf("unexpected audit actions %v", actions)
+	}
+}
+
+func TestMaintenanceJail(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  10 * time.Millisecond,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	window, err := list.ScheduleMaintenance(MaintenanceWindow{Service: srv.ID(), Action: MaintenanceJail})
+	if err != nil {
+		t.Fatalf("unexpected schedule error: %s", err)
+	}
+
+	waitFor(t, func() bool { return len(list.Jailed()) == 1 })
+
+	// healthy service is held in the jail until the window is cancelled
+	time.Sleep(50 * time.Millisecond)
+	if len(list.Jailed()) != 1 {
+		t.Fatalf("held service should not be recovered")
+	}
+	if state := list.Snapshot(); state.Services[0].Maintenance != MaintenanceJail {
+		t.Errorf("unexpected snapshot maintenance %q", state.Services[0].Maintenance)
+	}
+
+	if err := list.CancelMaintenance(window.ID); err != nil {
+		t.Fatalf("unexpected cancel error: %s", err)
+	}
+
+	waitFor(t, func() bool { return len(list.Healthy()) == 1 })
+
+	if err := list.CancelMaintenance(window.ID); !errors.As(err, &ErrMaintenanceNotFound{}) {
+		t.Errorf("expected maintenance not found error, got %v", err)
+	}
+	if _, err := list.ScheduleMaintenance(MaintenanceWindow{Service: srv.ID(), Action: "drain"}); !errors.As(err, &ErrUnsupportedMaintenanceAction{}) {
+		t.Errorf("expected unsupported action error, got %v", err)
+	}
+	if _, err := list.ScheduleMaintenance(MaintenanceWindow{Service: "unknown", Action: MaintenanceCordon}); !errors.As(err, &ErrServiceNotFound{}) {
+		t.Errorf("expected service not found error, got %v", err)
+	}
+}
+
+func TestMaintenanceAdmin(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	handler := NewAdminHandlerWithOpts(list, &AdminHandlerOpts{Unauthenticated: true})
+
+	start := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	body := `{"action":"cordon","start":"` + start + `","duration":"2h","reason":"planned"}`
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/services/"+srv.ID()+"/maintenance", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("unexpected status code %d: %s", rec.Code, rec.Body)
+	}
+
+	var window maintenanceView
+	if err := json.NewDecoder(rec.Body).Decode(&window); err != nil {
+		t.Fatalf("decode maintenance window: %s", err)
+	}
+	if window.Duration != "2h0m0s" || window.Active {
+		t.Errorf("unexpected maintenance window %+v", window)
+	}
+
+	// window in the future does not cordon the service yet
+	if list.Next() == nil {
+		t.Errorf("service should be selected before the window starts")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/maintenance", nil))
+
+	var windows []maintenanceView
+	if err := json.NewDecoder(rec.Body).Decode(&windows); err != nil {
+		t.Fatalf("decode maintenance windows: %s", err)
+	}
+	if len(windows) != 1 || windows[0].ID != window.ID {
+		t.Errorf("unexpected maintenance windows %+v", windows)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/maintenance/"+window.ID, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("unexpected status code %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/maintenance/"+window.ID, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unexpected status code %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/services/"+srv.ID()+"/maintenance", strings.NewReader(`{"action":"cordon","duration":"soon"}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status code %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/audit", nil))
+
+	var entries []AuditEntry
+	if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+		t.Fatalf("decode audit log: %s", err)
+	}
+	if len(entries) != 2 || entries[0].Action != "maintenance_scheduled" || entries[1].Action != "maintenance_cancelled" {
+		t.Errorf("unexpected audit log %+v", entries)
+	}
+}
+
+// waitFor wait until given condition is met or fail the test
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition is not met in time")
+		}
+	}
+}
+
+func TestForceJailAndRecover(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	handler := NewAdminHandlerWithOpts(list, &AdminHandlerOpts{Unauthenticated: true})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/services/"+srv.ID()+"/jail", nil))
+	if recorder.Code != http.StatusNoContent || len(list.Jailed()) != 1 {
+		t.Fatalf("service should be jailed, got %d: %s", recorder.Code, recorder.Body)
+	}
+	if srv.Status() != service.StatusUnHealthy {
+		t.Errorf("force jailed service should be unhealthy, got %s", srv.Status())
+	}
+
+	if err := list.ForceJail(srv.ID()); !errors.As(err, &ErrServiceNotFound{}) {
+		t.Errorf("jailed service should not be jailed again, got %v", err)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/services/"+srv.ID()+"/recover", nil))
+	if recorder.Code != http.StatusNoContent || len(list.Healthy()) != 1 {
+		t.Fatalf("service should be recovered, got %d: %s", recorder.Code, recorder.Body)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/services/"+srv.ID()+"/recover", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("healthy service should not be recovered, got %d", recorder.Code)
+	}
+
+	if _, err := list.ScheduleMaintenance(MaintenanceWindow{Service: srv.ID(), Action: MaintenanceJail}); err != nil {
+		t.Fatalf("unexpected schedule error: %s", err)
+	}
+	waitFor(t, func() bool { return len(list.Jailed()) == 1 })
+
+	if err := list.ForceRecover(srv.ID()); !errors.As(err, &ErrServiceHeld{}) {
+		t.Errorf("service held by maintenance should not be recovered, got %v", err)
+	}
+
+	entries := list.AuditLog()
+	if len(entries) < 2 || entries[0].Action != "force_jailed" || entries[1].Action != "force_recovered" {
+		t.Errorf("unexpected audit log %+v", entries)
+	}
+}
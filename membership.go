@@ -0,0 +1,144 @@
+package pool
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// MembershipDocumentVersion is version of
+// portable membership document format
+const MembershipDocumentVersion = 1
+
+// ImportMode represent how imported
+// membership is applied to the list
+type ImportMode string
+
+const (
+	// ImportAdditive add imported services
+	// to the current membership
+	ImportAdditive ImportMode = "additive"
+
+	// ImportReplace atomically replace the current
+	// membership with imported services
+	ImportReplace ImportMode = "replace"
+)
+
+// MembershipDocument is portable document with list membership
+// used to migrate traffic between gateway instances
+type MembershipDocument struct {
+	Version    int               `json:"version"`
+	Pool       string            `json:"pool"`
+	Generation uint64            `json:"generation"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Services   []ServiceSnapshot `json:"services"`
+}
+
+// ServiceFactory create service from its
+// snapshot in imported membership document
+type ServiceFactory func(entry ServiceSnapshot) (service.IService, error)
+
+// DefaultServiceFactory create BaseService from given snapshot
+func DefaultServiceFactory(entry ServiceSnapshot) (service.IService, error) {
+	return entry.Record.Service(), nil
+}
+
+// ExportMembership return portable document
+// with current membership of given list
+func ExportMembership(list IAdmin) *MembershipDocument {
+	state := list.Snapshot()
+
+	return &MembershipDocument{
+		Version:    MembershipDocumentVersion,
+		Pool:       state.Name,
+		Generation: state.Generation,
+		ExportedAt: state.Time,
+		Services:   state.Services,
+	}
+}
+
+// ImportMembership create services from given document with given
+// factory and apply them to the list according to the import mode.
+// Documents exported from other pools are rejected. Imported
+// services are healthchecked as newly discovered ones
+func ImportMembership(list IAdmin, doc *MembershipDocument, mode ImportMode, factory ServiceFactory) error {
+	if err := checkMembership(list, doc); err != nil {
+		return err
+	}
+
+	if factory == nil {
+		factory = DefaultServiceFactory
+	}
+
+	services := make([]service.IService, 0, len(doc.Services))
+	for _, entry := range doc.Services {
+		srv, err := factory(entry)
+		if err != nil {
+			return fmt.Errorf("create service with address %s: %w", entry.Address, err)
+		}
+		services = append(services, srv)
+	}
+
+	switch mode {
+	case ImportReplace:
+		list.ReplaceAll(services)
+	case ImportAdditive, "":
+		for _, srv := range services {
+			list.Add(srv)
+		}
+	default:
+		return ErrUnsupportedImportMode{Mode: string(mode)}
+	}
+
+	return nil
+}
+
+// checkMembership check given document is
+// supported and is exported from the list
+func checkMembership(list IAdmin, doc *MembershipDocument) error {
+	if doc.Version != MembershipDocumentVersion {
+		return ErrUnsupportedDocument{Version: doc.Version}
+	}
+
+	if name := list.Snapshot().Name; doc.Pool != name {
+		return ErrRejectedDocument{Pool: name, Reason: fmt.Sprintf("document is exported from pool %q", doc.Pool)}
+	}
+
+	return nil
+}
+
+// importedDocuments track the newest membership document imported
+// into the list, so older documents could not be replayed
+type importedDocuments struct {
+	mu         sync.Mutex
+	exportedAt time.Time
+	generation uint64
+}
+
+// admit check given document is exported within given max age
+// before given time and is newer than the last imported one.
+// Documents are ordered by export time and then generation, so
+// documents of restarted exporter with reset generation are
+// admitted
+func (d *importedDocuments) admit(doc *MembershipDocument, maxAge time.Duration, now time.Time) error {
+	reject := func(reason string, args ...any) error {
+		return ErrRejectedDocument{Pool: doc.Pool, Reason: fmt.Sprintf(reason, args...)}
+	}
+
+	if maxAge > 0 && now.Sub(doc.ExportedAt) > maxAge {
+		return reject("document exported at %s is older than %s", doc.ExportedAt.Format(time.RFC3339), maxAge)
+	}
+
+	defer d.mu.Unlock()
+	d.mu.Lock()
+
+	if doc.ExportedAt.Before(d.exportedAt) || (doc.ExportedAt.Equal(d.exportedAt) && doc.Generation <= d.generation && !d.exportedAt.IsZero()) {
+		return reject("document of generation %d is not newer than imported generation %d", doc.Generation, d.generation)
+	}
+
+	d.exportedAt, d.generation = doc.ExportedAt, doc.Generation
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestAdminMembershipImportExport(t *testing.T) {
+	opts := &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	}
+
+	source := NewServicesList("testServicesList", opts)
+	source.Add(service.NewService("https://1gateway.fm", "first", map[string]struct{}{"gpu": {}}, 0.5))
+	source.Add(newHealthyService("https://2gateway.fm"))
+
+	rec := httptest.NewRecorder()
+	NewAdminHandler(source).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/membership", nil))
+
+	var doc MembershipDocument
+	if err := json.NewDecoder(rec.Body).Decode(&doc); err != nil {
+		t.Fatalf("unexpected export decode error: %s", err)
+	}
+
+	if doc.Version != MembershipDocumentVersion || len(doc.Services) != 2 {
+		t.Fatalf("unexpected exported document %+v", doc)
+	}
+
+	target := NewServicesList("testServicesList", opts)
+	target.Add(newHealthyService("https://3gateway.fm"))
+	admin := NewAdminHandlerWithOpts(target, &AdminHandlerOpts{AcceptUnsigned: true, Unauthenticated: true})
+
+	body, _ := json.Marshal(&doc)
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership?mode=additive", bytes.NewReader(body)))
+	if rec.Code != http.StatusNoContent || target.CountAll() != 3 {
+		t.Fatalf("additive import should keep existing services, status %d, count %d", rec.Code, target.CountAll())
+	}
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership?mode=replace", bytes.NewReader(body)))
+	if rec.Code != http.StatusConflict || target.CountAll() != 3 {
+		t.Fatalf("replayed document should be rejected, status %d, count %d", rec.Code, target.CountAll())
+	}
+
+	doc.Generation++
+	body, _ = json.Marshal(&doc)
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership?mode=replace", bytes.NewReader(body)))
+	if rec.Code != http.StatusNoContent || target.CountAll() != 2 {
+		t.Fatalf("replace import should replace membership, status %d, count %d", rec.Code, target.CountAll())
+	}
+
+	for _, entry := range ExportMembership(target).Services {
+		if entry.Address == "https://1gateway.fm" && (entry.NodeName != "first" || len(entry.Tags) != 1) {
+			t.Errorf("imported service metadata should be kept, got %+v", entry)
+		}
+	}
+
+	doc.Generation++
+	doc.Pool = "otherList"
+	body, _ = json.Marshal(&doc)
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership", bytes.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("document of other pool should be rejected, got status %d", rec.Code)
+	}
+
+	doc.Pool = "testServicesList"
+	doc.ExportedAt = time.Now().Add(-2 * time.Hour)
+	body, _ = json.Marshal(&doc)
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership", bytes.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("outdated document should be rejected, got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	NewAdminHandlerWithOpts(target, &AdminHandlerOpts{Unauthenticated: true}).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership", bytes.NewReader(body)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("unsigned document should be refused without trusted keys, got status %d", rec.Code)
+	}
+
+	doc.Version = 0
+	body, _ = json.Marshal(&doc)
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership", bytes.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unsupported document should be rejected, got status %d", rec.Code)
+	}
+}
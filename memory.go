@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"time"
+	"unsafe"
+)
+
+// approximate sizes of bookkeeping entries used
+// for memory usage estimation, maps overhead included
+const (
+	historyEntrySize      = int(unsafe.Sizeof(time.Time{}))
+	availabilityEntrySize = int(unsafe.Sizeof(availabilityBucket{})) + 3*8
+	trackedServiceSize    = 96
+	tombstoneEntrySize    = int(unsafe.Sizeof(Tombstone{})) + 3*8
+	rejoinEntrySize       = int(unsafe.Sizeof(rejoinState{})) + 3*8
+	sessionEntrySize      = int(unsafe.Sizeof(sessionPin{})) + 5*8
+	leaseEntrySize        = int(unsafe.Sizeof(Lease{})) + 6*8
+)
+
+// MemoryBudget is options that cap per-service bookkeeping
+// of the list, so it's safe to embed the pool in
+// memory-constrained gateways. Zero values disable the caps
+type MemoryBudget struct {
+	MaxHistory             int // max flaps and verification failures timestamps kept per service (should not be less than review thresholds)
+	MaxAvailabilityBuckets int // max availability buckets kept per service, the oldest are evicted
+	MaxTrackedServices     int // max services with collected availability, the least recently checked are evicted
+	MaxTombstones          int // max tombstones and rejoin states of removed services kept, the oldest are evicted
+}
+
+// MemoryUsage is estimation of memory
+// used by list bookkeeping
+type MemoryUsage struct {
+	TrackedServices     int `json:"tracked_services"`
+	HistoryEntries      int `json:"history_entries"`
+	AvailabilityBuckets int `json:"availability_buckets"`
+	Tombstones          int `json:"tombstones"`     // tombstones of removed services
+	RejoinStates        int `json:"rejoin_states"`  // states of removed services kept for rejoin
+	Sessions            int `json:"sessions"`       // sessions pinned to services
+	StatsServices       int `json:"stats_services"` // services with moving statistics
+	Leases              int `json:"leases"`         // active and draining leases
+	Bytes               int `json:"bytes"`
+}
+
+// MemoryUsage return estimation of memory
+// used by list bookkeeping
+func (l *ServicesList) MemoryUsage() MemoryUsage {
+	return l.memoryUsage(true)
+}
+
+// memoryUsage return estimation of memory used by list
+// bookkeeping, sessions and statistics could be shared
+// with other lists, so they are counted only if asked
+func (l *ServicesList) memoryUsage(shared bool) MemoryUsage {
+	l.mu.RLock()
+
+	var usage MemoryUsage
+	for _, history := range []map[string][]time.Time{l.flaps, l.verificationFailures} {
+		for _, timestamps := range history {
+			usage.HistoryEntries += cap(timestamps)
+		}
+	}
+
+	l.mu.RUnlock()
+
+	usage.TrackedServices, usage.AvailabilityBuckets = l.availability.usage()
+	usage.Tombstones = l.tombstones.count()
+	usage.RejoinStates = l.rejoins.count()
+	usage.Leases = l.leases.total()
+
+	var statsBytes int
+	if shared {
+		usage.Sessions = l.sessions.count()
+		usage.StatsServices, statsBytes = l.stats.Usage()
+	}
+
+	usage.Bytes = usage.HistoryEntries*historyEntrySize +
+		usage.AvailabilityBuckets*availabilityEntrySize +
+		usage.TrackedServices*trackedServiceSize +
+		usage.Tombstones*tombstoneEntrySize +
+		usage.RejoinStates*rejoinEntrySize +
+		usage.Sessions*sessionEntrySize +
+		usage.Leases*leaseEntrySize +
+		statsBytes
+
+	return usage
+}
+
+// appendHistory append current time to given timestamps
+// trimmed to the review window and history budget
+func (l *ServicesList) appendHistory(timestamps []time.Time) []time.Time {
+	timestamps = trimToWindow(append(timestamps, time.Now()), l.reviewPolicy.Window)
+
+	if l.budget == nil || l.budget.MaxHistory == 0 || len(timestamps) <= l.budget.MaxHistory {
+		return timestamps
+	}
+
+	// copy is used to release the backing array
+	// instead of keeping it growing with re-slicing
+	capped := make([]time.Time, l.budget.MaxHistory)
+	copy(capped, timestamps[len(timestamps)-l.budget.MaxHistory:])
+
+	return capped
+}
@@ -0,0 +1,109 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+)
+
+func TestServicesListMemoryBudget(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		ReviewPolicy:   &ReviewPolicy{VerificationFailureThreshold: 100},
+		Availability:   &AvailabilityOpts{Resolution: time.Minute},
+		MemoryBudget: &MemoryBudget{
+			MaxHistory:         3,
+			MaxTrackedServices: 2,
+		},
+	}).(*ServicesList)
+
+	srv := newHealthyService("https://1gateway.fm")
+	for i := 0; i < 10; i++ {
+		list.ReportVerificationFailure(srv)
+	}
+
+	for i := 0; i < 3; i++ {
+		list.availability.record(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)), nil)
+	}
+
+	usage := list.MemoryUsage()
+	if usage.HistoryEntries != 3 {
+		t.Errorf("expected 3 history entries, got %d", usage.HistoryEntries)
+	}
+
+	if usage.TrackedServices != 2 || usage.AvailabilityBuckets != 2 {
+		t.Errorf("expected 2 tracked services with 2 buckets, got %+v", usage)
+	}
+
+	if usage.Bytes == 0 {
+		t.Errorf("memory usage estimation should not be empty")
+	}
+
+	if _, ok := list.availability.buckets[newHealthyService("https://0gateway.fm").ID()]; ok {
+		t.Errorf("the least recently checked service should be evicted")
+	}
+}
+
+func TestServicesListMemoryUsage(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		TombstoneTTL:   time.Hour,
+		RejoinWindow:   time.Hour,
+		Stats:          stats.NewRegistry(stats.Opts{}),
+	})
+	defer list.Close()
+
+	kept := newHealthyService("https://1gateway.fm")
+	removed := newHealthyService("https://2gateway.fm")
+	list.Add(kept)
+	list.Add(removed)
+	list.RemoveByID(removed.ID())
+
+	if list.NextForSession(context.Background(), "session") == nil {
+		t.Fatalf("session should be pinned to healthy service")
+	}
+	if _, err := list.Lease("job"); err != nil {
+		t.Fatalf("unexpected lease error: %s", err)
+	}
+
+	usage := list.MemoryUsage()
+	if usage.Tombstones != 1 || usage.RejoinStates != 1 || usage.Sessions != 1 || usage.StatsServices != 1 || usage.Leases != 1 {
+		t.Errorf("expected tombstone, rejoin state, session, statistics and lease to be counted, got %+v", usage)
+	}
+
+	empty := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer empty.Close()
+
+	if usage.Bytes <= empty.MemoryUsage().Bytes {
+		t.Errorf("memory usage estimation should include the bookkeeping, got %d bytes", usage.Bytes)
+	}
+
+	sharded := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Shards:         4,
+		Stats:          stats.NewRegistry(stats.Opts{}),
+	})
+	defer sharded.Close()
+
+	for i := 0; i < 8; i++ {
+		sharded.Add(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+
+	// statistics registry is shared by shards
+	if usage := sharded.MemoryUsage(); usage.StatsServices != 8 {
+		t.Errorf("expected 8 services with statistics, got %+v", usage)
+	}
+}
@@ -0,0 +1,120 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// merge replace existing list entry with the same ID by given
+// service if it has changed, membership, history and user data
+// of the entry are kept. Returns false if there is no such entry
+func (l *ServicesList) merge(srv service.IService) bool {
+	l.mu.Lock()
+
+	existing, membership, found := l.replace(srv)
+	if existing != nil {
+		l.bumpGeneration()
+	}
+
+	l.mu.Unlock()
+
+	if existing != nil {
+		l.merged(srv, existing, membership)
+	}
+
+	return found
+}
+
+// replace replace existing list entry with the same ID by given
+// service if it has changed and return the replaced entry and its
+// membership, nil is returned if the entry has not changed. Found
+// is false if there is no such entry. Should be called under the
+// list lock
+func (l *ServicesList) replace(srv service.IService) (existing service.IService, membership string, found bool) {
+	existing, membership = l.lookup(srv.ID())
+	if existing == nil {
+		return nil, "", false
+	}
+
+	if existing == srv || service.Equal(existing, srv) {
+		l.log().Info("service already exists during Add", "service", srv.ID(), "node", srv.NodeName())
+		return nil, membership, true
+	}
+
+	// without re-checking the new instance
+	// inherits the health of the existing one
+	if !l.recheckOnChange {
+		l.setStatus(srv, existing.Status())
+	}
+
+	switch membership {
+	case MembershipHealthy:
+		for i, s := range l.healthy {
+			if s.ID() == srv.ID() {
+				l.healthy[i] = srv
+				l.healthyChanged()
+				break
+			}
+		}
+	case MembershipJailed:
+		l.toJail(srv)
+	case MembershipReview:
+		l.review[srv.ID()].Service = srv
+	}
+
+	return existing, membership, true
+}
+
+// merged finish replacement of given existing service with
+// given membership by given service: close the existing one,
+// emit the change and recheck the service if configured
+func (l *ServicesList) merged(srv, existing service.IService, membership string) {
+	l.log().Info("service is changed and merged into existing entry", "service", srv.ID(), "node", srv.NodeName(), "address", srv.Address())
+
+	if err := existing.Close(); err != nil {
+		l.log().Warn("unexpected error during service Close()", "service", existing.ID(), "error", err)
+	}
+
+	l.emit(PoolEvent{Type: EventServiceChanged, Service: srv, Previous: existing})
+
+	if l.recheckOnChange && membership == MembershipHealthy {
+		l.recheck(srv)
+	}
+}
+
+// recheck healthcheck given healthy service
+// and jail it if the check is failed
+func (l *ServicesList) recheck(srv service.IService) {
+	err := l.CheckHealth(context.Background(), srv)
+	l.availability.record(srv, err)
+
+	if err == nil {
+		return
+	}
+
+	l.log().Warn("healthcheck error of changed service", "service", srv.ID(), "node", srv.NodeName(), "error", err)
+
+	l.FromHealthyToJail(srv.ID())
+	l.goTryUp(srv)
+}
+
+// lookup return list entry with given id and its
+// membership. Should be called under the list lock
+func (l *ServicesList) lookup(id string) (service.IService, string) {
+	if srv, ok := l.jail[id]; ok {
+		return srv, MembershipJailed
+	}
+
+	if item, ok := l.review[id]; ok {
+		return item.Service, MembershipReview
+	}
+
+	for _, srv := range l.healthy {
+		if srv.ID() == id {
+			return srv, MembershipHealthy
+		}
+	}
+
+	return nil, ""
+}
@@ -0,0 +1,85 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServicesListMergeChanged(t *testing.T) {
+	var events []PoolEvent
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		OnEvent: func(event PoolEvent) {
+			events = append(events, event)
+		},
+	})
+
+	original := newHealthyService("https://1gateway.fm")
+	list.Add(original)
+
+	// the same instance is not a change
+	list.Add(service.NewService("https://1gateway.fm", "", nil, 0))
+	if len(events) != 0 {
+		t.Fatalf("unchanged service should not emit events")
+	}
+
+	changed := service.NewService("https://1gateway.fm", "renamed", map[string]struct{}{"gpu": {}}, 0)
+	list.Add(changed)
+
+	if len(events) != 1 || events[0].Type != EventServiceChanged || events[0].Previous != original {
+		t.Fatalf("changed event expected, got %+v", events)
+	}
+
+	healthy := list.Healthy()
+	if len(healthy) != 1 || healthy[0] != changed {
+		t.Fatalf("changed service should replace existing entry")
+	}
+
+	if changed.Status() != service.StatusHealthy {
+		t.Errorf("changed service should inherit status of existing entry")
+	}
+
+	if list.Next() != changed {
+		t.Errorf("changed service should be selected")
+	}
+}
+
+func TestServicesListMergeJailed(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     0,
+		TryUpInterval:  10 * time.Millisecond,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	stale := newSwitchableService("https://1gateway.fm")
+	list.Add(stale)
+
+	stale.down.Store(true)
+	list.FromHealthyToJail(stale.ID())
+	list.goTryUp(stale)
+
+	changed := &switchableService{BaseService: service.NewService("https://1gateway.fm", "renamed", nil, 0).(*service.BaseService)}
+	list.Add(changed)
+
+	if jailed := list.Jailed()[changed.ID()]; jailed != changed {
+		t.Fatalf("changed service should replace jailed entry")
+	}
+
+	// running tries recover the current instance, not the closed one
+	waitFor(t, func() bool {
+		return len(list.Healthy()) == 1
+	})
+
+	if healthy := list.Healthy()[0]; healthy != changed {
+		t.Fatalf("changed service should be recovered, got stale instance")
+	}
+	if list.CountJailed() != 0 {
+		t.Errorf("recovered service should leave the jail")
+	}
+}
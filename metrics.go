@@ -0,0 +1,333 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	metricsNamespace = "prover_pool"
+
+	labelPool    = "pool"
+	labelService = "service"
+	labelResult  = "result"
+
+	resultSuccess = "success"
+	resultFailure = "failure"
+
+	exemplarTraceID = "trace_id"
+)
+
+// Metrics is prometheus collectors of services lists.
+// One instance could be shared between several lists,
+// they are distinguished by the pool label
+type Metrics struct {
+	selections      *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	buildInfo       *prometheus.GaugeVec
+	configInfo      *prometheus.GaugeVec
+	configOptions   *prometheus.GaugeVec
+	paused          *prometheus.GaugeVec
+	starved         *prometheus.GaugeVec
+	shed            *prometheus.CounterVec
+	healthy         *prometheus.GaugeVec
+	jailed          *prometheus.GaugeVec
+	review          *prometheus.GaugeVec
+	checkDuration   *prometheus.HistogramVec
+	checkFailures   *prometheus.CounterVec
+	tryUps          *prometheus.CounterVec
+
+	labeler *serviceLabeler
+}
+
+// NewMetrics create new Metrics collectors with
+// given labels configuration (nil for defaults)
+func NewMetrics(opts *MetricsOpts) *Metrics {
+	m := &Metrics{
+		labeler: newServiceLabeler(opts),
+		selections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "selections_total",
+			Help:      "Number of services selections for taking a connection.",
+		}, []string{labelPool, labelService}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of requests to services reported by callers.",
+			Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+		}, []string{labelPool, labelService}),
+		buildInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "build_info",
+			Help:      "Pool library build information, value is always 1.",
+		}, []string{"version", "go_version"}),
+		configInfo: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "config_info",
+			Help:      "Pool configuration fingerprint, value is always 1.",
+		}, []string{labelPool, "fingerprint"}),
+		configOptions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "config_option",
+			Help:      "Pool configuration option values.",
+		}, []string{labelPool, "option"}),
+		paused: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "paused",
+			Help:      "Whether pool healthchecks and try ups are paused.",
+		}, []string{labelPool}),
+		starved: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "starved",
+			Help:      "Whether healthy service is not selected during starvation window.",
+		}, []string{labelPool, labelService}),
+		shed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "shed_total",
+			Help:      "Number of requests rejected by load shedding.",
+		}, []string{labelPool, "priority"}),
+		healthy: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "healthy_services",
+			Help:      "Number of healthy services.",
+		}, []string{labelPool}),
+		jailed: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "jailed_services",
+			Help:      "Number of jailed services.",
+		}, []string{labelPool}),
+		review: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "review_services",
+			Help:      "Number of services under review.",
+		}, []string{labelPool}),
+		checkDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "healthcheck_duration_seconds",
+			Help:      "Duration of services healthchecks.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+		}, []string{labelPool, labelResult}),
+		checkFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "healthcheck_failures_total",
+			Help:      "Number of failed services healthchecks.",
+		}, []string{labelPool, labelService}),
+		tryUps: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "try_up_attempts_total",
+			Help:      "Number of attempts to up jailed services.",
+		}, []string{labelPool, labelResult}),
+	}
+
+	build := ReadBuildInfo()
+	m.buildInfo.WithLabelValues(build.Version, build.GoVersion).Set(1)
+
+	return m
+}
+
+// Register register all collectors in given registerer
+func (m *Metrics) Register(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.selections, m.requestDuration, m.buildInfo, m.configInfo, m.configOptions, m.paused, m.starved, m.shed,
+		m.healthy, m.jailed, m.review, m.checkDuration, m.checkFailures, m.tryUps,
+	} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterMetrics create Metrics with default labels configuration
+// and register them in given registerer, returned metrics should
+// be set to options of lists to be observed
+func RegisterMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	m := NewMetrics(nil)
+	if err := m.Register(reg); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// observeSelection count selection of given service, trace id
+// from given context is attached as exemplar if it is present
+func (m *Metrics) observeSelection(ctx context.Context, pool string, srv service.IService) {
+	if m == nil || srv == nil {
+		return
+	}
+
+	counter := m.selections.WithLabelValues(pool, m.serviceLabel(pool, srv))
+
+	if exemplar := exemplarFromContext(ctx); exemplar != nil {
+		if adder, ok := counter.(prometheus.ExemplarAdder); ok {
+			adder.AddWithExemplar(1, exemplar)
+			return
+		}
+	}
+
+	counter.Inc()
+}
+
+// observeRequest observe duration of request to given service, trace
+// id from given context is attached as exemplar if it is present
+func (m *Metrics) observeRequest(ctx context.Context, pool string, srv service.IService, duration time.Duration) {
+	if m == nil || srv == nil {
+		return
+	}
+
+	observer := m.requestDuration.WithLabelValues(pool, m.serviceLabel(pool, srv))
+
+	if exemplar := exemplarFromContext(ctx); exemplar != nil {
+		if exemplarObserver, ok := observer.(prometheus.ExemplarObserver); ok {
+			exemplarObserver.ObserveWithExemplar(duration.Seconds(), exemplar)
+			return
+		}
+	}
+
+	observer.Observe(duration.Seconds())
+}
+
+// observeConfig expose given configuration of given
+// pool replacing previously exposed one
+func (m *Metrics) observeConfig(pool string, config ConfigInfo) {
+	if m == nil {
+		return
+	}
+
+	m.configInfo.DeletePartialMatch(prometheus.Labels{labelPool: pool})
+	m.configInfo.WithLabelValues(pool, config.Fingerprint).Set(1)
+
+	for _, option := range config.Options {
+		m.configOptions.WithLabelValues(pool, option.Name).Set(option.Value)
+	}
+}
+
+// serviceLabel return service label value of given service
+// and delete series of services evicted from the top-K
+func (m *Metrics) serviceLabel(pool string, srv service.IService) string {
+	value, evicted := m.labeler.label(pool, srv)
+
+	for _, e := range evicted {
+		m.selections.DeleteLabelValues(pool, e)
+		m.requestDuration.DeleteLabelValues(pool, e)
+		m.starved.DeleteLabelValues(pool, e)
+		m.checkFailures.DeleteLabelValues(pool, e)
+	}
+
+	return value
+}
+
+// exemplarFromContext return exemplar labels with trace id
+// of the span from given context or nil if there is no span
+func exemplarFromContext(ctx context.Context) prometheus.Labels {
+	if ctx == nil {
+		return nil
+	}
+
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.HasTraceID() {
+		return nil
+	}
+
+	return prometheus.Labels{exemplarTraceID: spanCtx.TraceID().String()}
+}
+
+// observePaused set paused state of given pool
+func (m *Metrics) observePaused(pool string, paused bool) {
+	if m == nil {
+		return
+	}
+
+	value := 0.0
+	if paused {
+		value = 1
+	}
+
+	m.paused.WithLabelValues(pool).Set(value)
+}
+
+// observeStarved set starvation state of given service
+func (m *Metrics) observeStarved(pool string, srv service.IService, starved bool) {
+	if m == nil {
+		return
+	}
+
+	if !starved {
+		m.starved.DeleteLabelValues(pool, m.serviceLabel(pool, srv))
+		return
+	}
+
+	m.starved.WithLabelValues(pool, m.serviceLabel(pool, srv)).Set(1)
+}
+
+// observeShed count request of given
+// priority rejected by load shedding
+func (m *Metrics) observeShed(pool string, priority Priority) {
+	if m == nil {
+		return
+	}
+
+	m.shed.WithLabelValues(pool, priority.String()).Inc()
+}
+
+// observeMembership set number of healthy,
+// jailed and under review services of given pool
+func (m *Metrics) observeMembership(pool string, healthy, jailed, review int) {
+	if m == nil {
+		return
+	}
+
+	m.healthy.WithLabelValues(pool).Set(float64(healthy))
+	m.jailed.WithLabelValues(pool).Set(float64(jailed))
+	m.review.WithLabelValues(pool).Set(float64(review))
+}
+
+// addMembership change number of healthy, jailed and under
+// review services of given pool by given deltas, so sizes of
+// all shards of the pool are summed up in the same gauges
+func (m *Metrics) addMembership(pool string, healthy, jailed, review int) {
+	if m == nil {
+		return
+	}
+
+	m.healthy.WithLabelValues(pool).Add(float64(healthy))
+	m.jailed.WithLabelValues(pool).Add(float64(jailed))
+	m.review.WithLabelValues(pool).Add(float64(review))
+}
+
+// observeCheck observe duration of healthcheck of given
+// service and count the failure if the check is failed
+func (m *Metrics) observeCheck(pool string, srv service.IService, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+
+	if err != nil {
+		m.checkDuration.WithLabelValues(pool, resultFailure).Observe(duration.Seconds())
+		m.checkFailures.WithLabelValues(pool, m.serviceLabel(pool, srv)).Inc()
+		return
+	}
+
+	m.checkDuration.WithLabelValues(pool, resultSuccess).Observe(duration.Seconds())
+}
+
+// observeTryUp count attempt to up jailed service
+func (m *Metrics) observeTryUp(pool string, err error) {
+	if m == nil {
+		return
+	}
+
+	result := resultSuccess
+	if err != nil {
+		result = resultFailure
+	}
+
+	m.tryUps.WithLabelValues(pool, result).Inc()
+}
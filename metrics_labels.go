@@ -0,0 +1,240 @@
+package pool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	// otherServicesLabel is service label value of
+	// services that are not in the top-K services
+	otherServicesLabel = "other"
+
+	defaultTopKRefresh = 30 * time.Second
+
+	addressHashLength = 12
+)
+
+// ServiceLabelMode represent how
+// services are labeled in metrics
+type ServiceLabelMode int
+
+const (
+	// ServiceLabelID label services by theirs unique ID
+	ServiceLabelID ServiceLabelMode = iota
+
+	// ServiceLabelAddress label services by theirs address
+	ServiceLabelAddress
+
+	// ServiceLabelAddressHash label services by
+	// short hash of theirs address
+	ServiceLabelAddressHash
+
+	// ServiceLabelNone disable per-service labels,
+	// all services are aggregated per pool
+	ServiceLabelNone
+)
+
+// MetricsOpts is options that control
+// cardinality of metrics labels
+type MetricsOpts struct {
+	ServiceLabel ServiceLabelMode // how services are labeled (by ID by default)
+	TopK         int              // label only K most active services per pool, others are labeled as "other" (0 to label all)
+	TopKRefresh  time.Duration    // how often top-K services are recalculated, activity counters are halved every period (30s by default)
+	MaxCounters  int              // max activity counters kept per pool for top-K ranking, the least active are evicted (0 for unbounded)
+}
+
+// serviceLabeler produce service label values
+// according to the label mode and top-K limit
+type serviceLabeler struct {
+	mode        ServiceLabelMode
+	topK        int
+	refresh     time.Duration
+	maxCounters int
+
+	pools map[string]*topKServices
+
+	mu sync.Mutex
+}
+
+// topKServices is activity counters and
+// current top-K services of one pool
+type topKServices struct {
+	counts      map[string]uint64
+	top         map[string]struct{}
+	refreshedAt time.Time
+	decayedAt   time.Time
+}
+
+// newServiceLabeler create new serviceLabeler with given configuration
+func newServiceLabeler(opts *MetricsOpts) *serviceLabeler {
+	labeler := &serviceLabeler{
+		refresh: defaultTopKRefresh,
+		pools:   make(map[string]*topKServices),
+	}
+
+	if opts == nil {
+		return labeler
+	}
+
+	labeler.mode = opts.ServiceLabel
+	labeler.topK = opts.TopK
+	labeler.maxCounters = opts.MaxCounters
+	if opts.TopKRefresh != 0 {
+		labeler.refresh = opts.TopKRefresh
+	}
+
+	return labeler
+}
+
+// label return label value for given service of given pool
+// and label values of services evicted from the top-K
+// which series should be deleted
+func (l *serviceLabeler) label(pool string, srv service.IService) (string, []string) {
+	value := l.value(srv)
+
+	if l.topK == 0 || l.mode == ServiceLabelNone {
+		return value, nil
+	}
+
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	services, ok := l.pools[pool]
+	if !ok {
+		services = &topKServices{
+			counts:    make(map[string]uint64),
+			top:       make(map[string]struct{}),
+			decayedAt: time.Now(),
+		}
+		l.pools[pool] = services
+	}
+
+	services.decay(l.refresh)
+	services.counts[value]++
+	if l.maxCounters > 0 && len(services.counts) > l.maxCounters {
+		services.evictCounters(l.maxCounters)
+	}
+
+	var evicted []string
+	if _, ok := services.top[value]; !ok {
+		if len(services.top) < l.topK {
+			services.top[value] = struct{}{}
+		} else if time.Since(services.refreshedAt) >= l.refresh {
+			evicted = services.recalculate(l.topK)
+		}
+	}
+
+	if _, ok := services.top[value]; !ok {
+		return otherServicesLabel, evicted
+	}
+
+	return value, evicted
+}
+
+// value return label value of given service according to the mode
+func (l *serviceLabeler) value(srv service.IService) string {
+	switch l.mode {
+	case ServiceLabelAddress:
+		return srv.Address()
+	case ServiceLabelAddressHash:
+		sum := sha256.Sum256([]byte(srv.Address()))
+		return hex.EncodeToString(sum[:])[:addressHashLength]
+	case ServiceLabelNone:
+		return ""
+	default:
+		return srv.ID()
+	}
+}
+
+// recalculate choose k services with the biggest activity
+// counters as top-K and return services evicted from it
+func (s *topKServices) recalculate(k int) []string {
+	values := make([]string, 0, len(s.counts))
+	for value := range s.counts {
+		values = append(values, value)
+	}
+
+	// on equal counters current top-K services
+	// are preferred to avoid series flapping
+	sort.Slice(values, func(i, j int) bool {
+		if s.counts[values[i]] != s.counts[values[j]] {
+			return s.counts[values[i]] > s.counts[values[j]]
+		}
+
+		_, iTop := s.top[values[i]]
+		_, jTop := s.top[values[j]]
+		return iTop && !jTop
+	})
+
+	if len(values) > k {
+		values = values[:k]
+	}
+
+	top := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		top[value] = struct{}{}
+	}
+
+	var evicted []string
+	for value := range s.top {
+		if _, ok := top[value]; !ok {
+			evicted = append(evicted, value)
+		}
+	}
+
+	s.top = top
+	s.refreshedAt = time.Now()
+
+	return evicted
+}
+
+// decay halve activity counters once per every refresh period
+// elapsed since the previous decay and remove zeroed ones, so
+// counters reflect recent activity and services which stopped
+// taking connections leave the top-K eventually
+func (s *topKServices) decay(refresh time.Duration) {
+	periods := time.Since(s.decayedAt) / refresh
+	if periods == 0 {
+		return
+	}
+
+	s.decayedAt = s.decayedAt.Add(periods * refresh)
+
+	halvings := min(uint64(periods), 63)
+	for value, count := range s.counts {
+		if count >>= halvings; count == 0 {
+			delete(s.counts, value)
+			continue
+		}
+		s.counts[value] = count
+	}
+}
+
+// evictCounters remove the least active counters of services
+// out of the top-K leaving half of given limit to amortize
+// eviction over subsequent new services
+func (s *topKServices) evictCounters(limit int) {
+	values := make([]string, 0, len(s.counts))
+	for value := range s.counts {
+		if _, ok := s.top[value]; !ok {
+			values = append(values, value)
+		}
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return s.counts[values[i]] < s.counts[values[j]]
+	})
+
+	for _, value := range values {
+		if len(s.counts) <= limit/2 {
+			return
+		}
+		delete(s.counts, value)
+	}
+}
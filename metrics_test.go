@@ -0,0 +1,167 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMetricsSelectionExemplar(t *testing.T) {
+	metrics := NewMetrics(nil)
+
+	reg := prometheus.NewRegistry()
+	if err := metrics.Register(reg); err != nil {
+		t.Fatalf("unexpected register error: %s", err)
+	}
+
+	list := NewServicesList("metered", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Metrics:        metrics,
+	})
+	list.Add(newHealthyService("https://1gateway.fm"))
+
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	}))
+
+	srv := list.NextContext(ctx)
+	if srv == nil {
+		t.Fatalf("unexpected no healthy services")
+	}
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("unexpected gather error: %s", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != "prover_pool_selections_total" {
+			continue
+		}
+
+		exemplar := family.GetMetric()[0].GetCounter().GetExemplar()
+		if exemplar == nil || exemplar.GetLabel()[0].GetValue() != traceID.String() {
+			t.Fatalf("unexpected exemplar %v", exemplar)
+		}
+		return
+	}
+
+	t.Fatalf("selections metric is not found")
+}
+
+func TestMetricsTopKLabels(t *testing.T) {
+	labeler := newServiceLabeler(&MetricsOpts{TopK: 1, TopKRefresh: time.Hour})
+
+	first := newHealthyService("https://1gateway.fm")
+	second := newHealthyService("https://2gateway.fm")
+
+	for i := 0; i < 10; i++ {
+		if value, _ := labeler.label("pool", first); value != first.ID() {
+			t.Fatalf("first service should be in top-K")
+		}
+	}
+
+	for i := 0; i < 8; i++ {
+		if value, _ := labeler.label("pool", second); value != otherServicesLabel {
+			t.Fatalf("second service should be labeled as other")
+		}
+	}
+
+	// the first service is idle for few refresh periods,
+	// so its counter decays below the second service one
+	services := labeler.pools["pool"]
+	services.refreshedAt = services.refreshedAt.Add(-2 * time.Hour)
+	services.decayedAt = services.decayedAt.Add(-2 * time.Hour)
+
+	value, evicted := labeler.label("pool", second)
+	if value != second.ID() || len(evicted) != 1 || evicted[0] != first.ID() {
+		t.Errorf("second service should replace first in top-K, got %s %v", value, evicted)
+	}
+	if count := services.counts[first.ID()]; count != 2 {
+		t.Errorf("counter of first service should be halved twice, got %d", count)
+	}
+}
+
+func TestMetricsPoolState(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := RegisterMetrics(reg)
+	if err != nil {
+		t.Fatalf("unexpected register error: %s", err)
+	}
+
+	list := NewServicesList("observed", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Metrics:        metrics,
+	})
+	defer list.Close()
+
+	srv := newSwitchableService("https://1gateway.fm")
+	list.Add(srv)
+	list.Add(newHealthyService("https://2gateway.fm"))
+
+	if healthy := testutil.ToFloat64(metrics.healthy.WithLabelValues("observed")); healthy != 2 {
+		t.Fatalf("expected 2 healthy services, got %v", healthy)
+	}
+
+	srv.down.Store(true)
+	list.HealthChecks()
+
+	waitFor(t, func() bool {
+		return testutil.ToFloat64(metrics.jailed.WithLabelValues("observed")) == 1 &&
+			testutil.ToFloat64(metrics.tryUps.WithLabelValues("observed", resultFailure)) == 1
+	})
+
+	if healthy := testutil.ToFloat64(metrics.healthy.WithLabelValues("observed")); healthy != 1 {
+		t.Errorf("expected 1 healthy service, got %v", healthy)
+	}
+	if failures := testutil.ToFloat64(metrics.checkFailures.WithLabelValues("observed", srv.ID())); failures < 2 {
+		t.Errorf("expected healthcheck and try up failures of the service, got %v", failures)
+	}
+	if checks := testutil.CollectAndCount(metrics.checkDuration); checks != 2 {
+		t.Errorf("expected successful and failed healthcheck durations, got %d", checks)
+	}
+
+	if _, err := RegisterMetrics(reg); err == nil {
+		t.Errorf("expected error of duplicated registration")
+	}
+}
+
+func TestMetricsShardedPoolState(t *testing.T) {
+	metrics, err := RegisterMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("unexpected register error: %s", err)
+	}
+
+	list := NewServicesList("observed", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Shards:         4,
+		Metrics:        metrics,
+	})
+	defer list.Close()
+
+	for i := 0; i < 10; i++ {
+		list.Add(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+	list.FromHealthyToJail(list.Next().ID())
+
+	// gauges sum up sizes of all shards
+	if healthy := testutil.ToFloat64(metrics.healthy.WithLabelValues("observed")); healthy != 9 {
+		t.Errorf("expected 9 healthy services, got %v", healthy)
+	}
+	if jailed := testutil.ToFloat64(metrics.jailed.WithLabelValues("observed")); jailed != 1 {
+		t.Errorf("expected 1 jailed service, got %v", jailed)
+	}
+}
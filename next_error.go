@@ -0,0 +1,144 @@
+package pool
+
+import (
+	"context"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// NextE returns next healthy service to take a connection like
+// Next, but reports why no service is selected: ErrPoolClosed,
+// ErrPoolDraining, ErrNoHealthyServices if the list has no
+// healthy services, ErrPoolSaturated if eligible ones are at
+// theirs in-flight cap, ErrNoEligibleService if none of them
+// is eligible for the request, e.g. cordoned or not allowed
+// by policies, ErrRequestShed if the request is shed and
+// ErrServiceSaturated if balancing strategy rejects them
+func (l *ServicesList) NextE() (service.IService, error) {
+	ctx := context.Background()
+
+	if srv := l.NextContext(ctx); srv != nil {
+		return srv, nil
+	}
+
+	return nil, l.nextErr(ctx)
+}
+
+// NextE returns next healthy service to take a connection
+// from shards like Next, but reports why no service
+// is selected like ServicesList.NextE
+func (l *ShardedServicesList) NextE() (service.IService, error) {
+	ctx := context.Background()
+
+	if srv := l.NextContext(ctx); srv != nil {
+		return srv, nil
+	}
+
+	return nil, l.nextErr(ctx)
+}
+
+// closed check if the list is closed
+func (l *ServicesList) closed() bool {
+	return l.ctx.Err() != nil
+}
+
+// selectionSkips is numbers of healthy services
+// skipped by selection for every reason
+type selectionSkips struct {
+	ErrNoEligibleService
+
+	capped   int  // eligible, but at in-flight cap
+	eligible int  // eligible and below in-flight cap
+	shed     bool // request could be shed
+}
+
+// add add skips of other shard
+func (s *selectionSkips) add(other selectionSkips) {
+	s.Healthy += other.Healthy
+	s.Standby += other.Standby
+	s.Cordoned += other.Cordoned
+	s.Drained += other.Drained
+	s.Priority += other.Priority
+	s.Requirement += other.Requirement
+	s.Policy += other.Policy
+	s.capped += other.capped
+	s.eligible += other.eligible
+	s.shed = s.shed || other.shed
+}
+
+// skips classify healthy services by the first
+// reason they are skipped for given request
+func (l *ServicesList) skips(ctx context.Context) selectionSkips {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	s := selectionSkips{ErrNoEligibleService: ErrNoEligibleService{Pool: l.serviceName, Healthy: len(l.healthy)}}
+	if l.shedding != nil {
+		s.shed = l.shedding.mayShed(PriorityFromContext(ctx), l.saturationLocked())
+	}
+
+	ctx = l.withPriority(ctx)
+
+	for _, srv := range l.healthy {
+		switch {
+		case srv.Status() != service.StatusHealthy:
+		case l.standby(srv):
+			s.Standby++
+		case l.maintenance.isCordoned(srv.ID()):
+			s.Cordoned++
+		case l.drains.has(srv.ID()):
+			s.Drained++
+		case !allowedPriority(ctx, srv):
+			s.Priority++
+		case !allowedRequirement(ctx, srv):
+			s.Requirement++
+		case !l.allowedByPolicies(ctx, srv):
+			s.Policy++
+		case !l.belowInFlightCap(srv):
+			s.capped++
+		default:
+			s.eligible++
+		}
+	}
+
+	return s
+}
+
+// nextErr return reason no service
+// is selected for given request
+func (l *ServicesList) nextErr(ctx context.Context) error {
+	return selectionErr(ctx, l.serviceName, l.closed(), l.Draining(), l.skips(ctx))
+}
+
+// nextErr return reason no service of any
+// shard is selected for given request
+func (l *ShardedServicesList) nextErr(ctx context.Context) error {
+	var skips selectionSkips
+	for _, shard := range l.shards {
+		skips.add(shard.skips(ctx))
+	}
+	skips.Pool = l.serviceName
+
+	return selectionErr(ctx, l.serviceName, l.shards[0].closed(), l.Draining(), skips)
+}
+
+// selectionErr return error of failed selection of given
+// request from list with given name, state and skips
+func selectionErr(ctx context.Context, name string, closed, draining bool, skips selectionSkips) error {
+	switch {
+	case closed:
+		return ErrPoolClosed{Pool: name}
+	case draining:
+		return ErrPoolDraining{Pool: name}
+	case skips.Healthy == 0:
+		return ErrNoHealthyServices{Pool: name}
+	case skips.eligible == 0 && skips.capped > 0:
+		return ErrPoolSaturated{Pool: name}
+	case skips.eligible == 0:
+		return skips.ErrNoEligibleService
+	case skips.shed:
+		return ErrRequestShed{Pool: name, Priority: PriorityFromContext(ctx)}
+	default:
+		return ErrServiceSaturated{Pool: name, Healthy: skips.Healthy}
+	}
+}
@@ -0,0 +1,177 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestNextE(t *testing.T) {
+	for _, shards := range []int{1, 2} {
+		list := NewServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  time.Hour,
+			ChecksInterval: time.Hour,
+			Shards:         shards,
+		})
+
+		if _, err := list.NextE(); !errors.As(err, &ErrNoHealthyServices{}) {
+			t.Fatalf("expected no healthy services with %d shards, got %v", shards, err)
+		}
+
+		srv := newHealthyService("https://1gateway.fm")
+		list.Add(srv)
+
+		if got, err := list.NextE(); err != nil || got.ID() != srv.ID() {
+			t.Fatalf("expected service %s with %d shards, got %v", srv.ID(), shards, err)
+		}
+
+		if err := list.DrainService(srv, nil); err != nil {
+			t.Fatalf("unexpected drain error: %s", err)
+		}
+
+		var skipped ErrNoEligibleService
+		if _, err := list.NextE(); !errors.As(err, &skipped) || skipped.Healthy != 1 || skipped.Drained != 1 {
+			t.Fatalf("expected drained healthy service with %d shards, got %v", shards, err)
+		}
+
+		list.SetDraining(true)
+		if _, err := list.NextE(); !errors.As(err, &ErrPoolDraining{}) {
+			t.Fatalf("expected draining list with %d shards, got %v", shards, err)
+		}
+
+		list.Close()
+		if _, err := list.NextE(); !errors.As(err, &ErrPoolClosed{}) {
+			t.Fatalf("expected closed list with %d shards, got %v", shards, err)
+		}
+	}
+}
+
+func TestNextECordoned(t *testing.T) {
+	for _, shards := range []int{1, 2} {
+		list := NewServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  time.Hour,
+			ChecksInterval: time.Hour,
+			Shards:         shards,
+		})
+
+		srv := newHealthyService("https://1gateway.fm")
+		list.Add(srv)
+
+		if _, err := list.ScheduleMaintenance(MaintenanceWindow{Service: srv.ID(), Action: MaintenanceCordon, Duration: time.Hour}); err != nil {
+			t.Fatalf("unexpected schedule error: %s", err)
+		}
+		waitFor(t, func() bool { return list.Next() == nil })
+
+		var skipped ErrNoEligibleService
+		if _, err := list.NextE(); !errors.As(err, &skipped) || skipped.Healthy != 1 || skipped.Cordoned != 1 {
+			t.Errorf("expected cordoned healthy service with %d shards, got %v", shards, err)
+		}
+
+		list.Close()
+	}
+}
+
+func TestNextEPolicy(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Policies:       []IPolicy{&addressPolicy{filtered: "https://1gateway.fm"}},
+	})
+	defer list.Close()
+
+	list.Add(newHealthyService("https://1gateway.fm"))
+
+	var skipped ErrNoEligibleService
+	if _, err := list.NextE(); !errors.As(err, &skipped) || skipped.Healthy != 1 || skipped.Policy != 1 {
+		t.Errorf("expected healthy service not allowed by policies, got %v", err)
+	}
+}
+
+func TestNextEPriority(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Priorities:     &PriorityOpts{},
+	})
+	defer list.Close()
+
+	primary := newHealthyService("https://1gateway.fm")
+	backup := newMetadataService("https://2gateway.fm", map[string]string{service.PriorityMetadataKey: "1"})
+	list.Add(primary)
+	list.Add(backup)
+
+	// healthy highest priority level takes all requests
+	// even when its only service is drained
+	if err := list.DrainService(primary, nil); err != nil {
+		t.Fatalf("unexpected drain error: %s", err)
+	}
+
+	var skipped ErrNoEligibleService
+	if _, err := list.NextE(); !errors.As(err, &skipped) || skipped.Healthy != 2 || skipped.Drained != 1 || skipped.Priority != 1 {
+		t.Errorf("expected drained service and service of other priority level, got %v", err)
+	}
+}
+
+func TestTryAcquireRequirement(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	list.Add(newMetadataService("https://1gateway.fm", map[string]string{service.CapabilitiesMetadataKey: "plonk"}))
+
+	ctx := WithRequirement(context.Background(), Requirement{Capability: "stark"})
+
+	var skipped ErrNoEligibleService
+	if _, _, err := list.TryAcquire(ctx); !errors.As(err, &skipped) || skipped.Healthy != 1 || skipped.Requirement != 1 {
+		t.Errorf("expected healthy service not meeting requirement, got %v", err)
+	}
+}
+
+func TestNextEShed(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Shed:           &ShedOpts{Policy: ShedLowestPriority, Threshold: 0.4},
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm").(*service.BaseService)
+	srv.SetLoad(1)
+	list.Add(srv)
+
+	var shed ErrRequestShed
+	if _, err := list.NextE(); !errors.As(err, &shed) || shed.Priority != PriorityNormal {
+		t.Errorf("expected shed request of normal priority, got %v", err)
+	}
+}
+
+func TestNextEInFlightCap(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		MaxInFlight:    1,
+	})
+	defer list.Close()
+
+	list.Add(newHealthyService("https://1gateway.fm"))
+
+	if srv, _ := list.Acquire(context.Background()); srv == nil {
+		t.Fatalf("expected acquired service")
+	}
+
+	if _, err := list.NextE(); !errors.As(err, &ErrPoolSaturated{}) {
+		t.Errorf("expected saturated list, got %v", err)
+	}
+}
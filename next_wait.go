@@ -0,0 +1,106 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// nextWaitRecheck is interval selection of waiting NextWait
+// is retried at regardless of notifications, so changes
+// without notification, e.g. uncordon, are not missed
+const nextWaitRecheck = time.Second
+
+// changeSignal notify waiters that state of the
+// list is changed, e.g. in-flight connection is
+// released or healthy services are changed
+type changeSignal struct {
+	mu      sync.Mutex
+	changed chan struct{}
+}
+
+// newChangeSignal create change signal without waiters
+func newChangeSignal() *changeSignal {
+	return &changeSignal{changed: make(chan struct{})}
+}
+
+// wait return channel closed on the next change,
+// it should be taken before the state is checked,
+// so change between the check and wait is not lost
+func (s *changeSignal) wait() <-chan struct{} {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	return s.changed
+}
+
+// notify wake up all waiters
+func (s *changeSignal) notify() {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// NextWait returns next healthy service to take a connection
+// like NextContext, but blocks until any service is selected
+// instead of returning nil, e.g. on cold start while the list
+// is empty. Context error is returned once given context is
+// done, ErrPoolClosed or ErrPoolDraining is returned if the
+// list is closed or draining
+func (l *ServicesList) NextWait(ctx context.Context) (service.IService, error) {
+	return nextWait(ctx, l.ctx.Done(), func() service.IService {
+		return l.NextContext(ctx)
+	}, func() error {
+		return l.nextErr(ctx)
+	}, l.membership, l.capacity)
+}
+
+// NextWait returns next healthy service to take a connection
+// from shards like NextContext, but blocks until any service
+// is selected, change of healthy services of any shard and
+// release of connection to any shard wake up the wait
+func (l *ShardedServicesList) NextWait(ctx context.Context) (service.IService, error) {
+	first := l.shards[0]
+
+	return nextWait(ctx, first.ctx.Done(), func() service.IService {
+		return l.NextContext(ctx)
+	}, func() error {
+		return l.nextErr(ctx)
+	}, first.membership, first.capacity)
+}
+
+// nextWait retry given selection until it returns service or
+// the list is closed or draining, retries are made on change
+// of healthy services, release of in-flight connection and
+// periodically
+func nextWait(ctx context.Context, stop <-chan struct{}, next func() service.IService, nextErr func() error, membership, capacity *changeSignal) (service.IService, error) {
+	recheck := time.NewTicker(nextWaitRecheck)
+	defer recheck.Stop()
+
+	for {
+		changed, freed := membership.wait(), capacity.wait()
+
+		if srv := next(); srv != nil {
+			return srv, nil
+		}
+
+		err := nextErr()
+		if errors.As(err, &ErrPoolClosed{}) || errors.As(err, &ErrPoolDraining{}) {
+			return nil, err
+		}
+
+		select {
+		case <-changed:
+		case <-freed:
+		case <-recheck.C:
+		case <-stop:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestNextWait(t *testing.T) {
+	for _, shards := range []int{1, 2} {
+		list := NewServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  time.Hour,
+			ChecksInterval: time.Hour,
+			Shards:         shards,
+		})
+
+		ctx := context.Background()
+
+		timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		if _, err := list.NextWait(timeout); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected wait until deadline with %d shards, got %v", shards, err)
+		}
+		cancel()
+
+		selected := make(chan service.IService)
+		go func() {
+			srv, _ := list.NextWait(ctx)
+			selected <- srv
+		}()
+
+		select {
+		case srv := <-selected:
+			t.Fatalf("expected selection to wait for healthy service, got %v", srv)
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		srv := newHealthyService("https://1gateway.fm")
+		list.Add(srv)
+
+		select {
+		case got := <-selected:
+			if got == nil || got.ID() != srv.ID() {
+				t.Fatalf("expected added service %s to be selected with %d shards, got %v", srv.ID(), shards, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("selection is not woken up by added service with %d shards", shards)
+		}
+
+		list.Close()
+	}
+}
+
+func TestNextWaitClosed(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+
+	errs := make(chan error)
+	go func() {
+		_, err := list.NextWait(context.Background())
+		errs <- err
+	}()
+
+	list.Close()
+
+	select {
+	case err := <-errs:
+		if !errors.As(err, &ErrPoolClosed{}) {
+			t.Fatalf("expected closed list error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("selection is not interrupted by closed list")
+	}
+}
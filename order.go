@@ -0,0 +1,133 @@
+package pool
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// HealthyOrder represent order of
+// services returned by Healthy
+type HealthyOrder int
+
+const (
+	// HealthyOrderNone keep internal order of healthy services
+	// which is used by round-robin and shifts after removals
+	HealthyOrderNone HealthyOrder = iota
+
+	// HealthyOrderByID sort healthy services by ID
+	HealthyOrderByID
+
+	// HealthyOrderByAdded sort healthy services by the time
+	// they joined the list, ties are broken by ID
+	HealthyOrderByAdded
+)
+
+// String return healthy order name
+func (o HealthyOrder) String() string {
+	switch o {
+	case HealthyOrderByID:
+		return "by_id"
+	case HealthyOrderByAdded:
+		return "by_added"
+	default:
+		return "none"
+	}
+}
+
+// orderedService is healthy service with
+// the time it has joined the list
+type orderedService struct {
+	srv   service.IService
+	added time.Time
+}
+
+// HealthySorted return copy of
+// healthy services in given order
+func (l *ServicesList) HealthySorted(order HealthyOrder) []service.IService {
+	return sortServices(l.orderedHealthy(), order)
+}
+
+// orderedHealthy return healthy services
+// with the time they have joined the list
+func (l *ServicesList) orderedHealthy() []orderedService {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	entries := make([]orderedService, 0, len(l.healthy))
+	for _, srv := range l.healthy {
+		entries = append(entries, orderedService{srv: srv, added: l.added[srv.ID()]})
+	}
+
+	return entries
+}
+
+// trackAdded remember the time service with given id has joined
+// the list, the time is kept while service moves between healthy,
+// jail and review. Should be called under the list lock
+func (l *ServicesList) trackAdded(id string) {
+	if _, ok := l.added[id]; !ok {
+		l.added[id] = time.Now()
+		l.stats.Track(id)
+	}
+}
+
+// releaseMember release list entry bookkeeping of given service
+// removed from the list: its user data, spare designation and the
+// time it has joined, and leave its tombstone with given removal
+// reason. Learned state is kept for the rejoin window. Should be
+// called under the list lock
+func (l *ServicesList) releaseMember(srv service.IService, reason string) {
+	l.keepRejoinState(srv)
+	l.queueEvent(PoolEvent{Type: EventServiceRemoved, Service: srv, Reason: reason})
+	delete(l.added, srv.ID())
+	l.connections.forget(srv.ID())
+	l.drains.remove(srv.ID())
+	l.spares.forget(srv.ID())
+	l.releaseUserData(srv.ID())
+	l.failedChecks.forget(srv.ID())
+	l.fairness.forget(srv.ID())
+	l.stats.Forget(srv.ID())
+	l.passive.forget(srv.ID())
+	if l.starvation.forget(srv.ID()) {
+		l.metrics.observeStarved(l.serviceName, srv, false)
+	}
+	l.tombstones.bury(srv, reason)
+}
+
+// HealthySorted return copy of healthy
+// services of all shards in given order
+func (l *ShardedServicesList) HealthySorted(order HealthyOrder) []service.IService {
+	var entries []orderedService
+	for _, shard := range l.shards {
+		entries = append(entries, shard.orderedHealthy()...)
+	}
+
+	return sortServices(entries, order)
+}
+
+// sortServices return services of given
+// entries sorted in given order
+func sortServices(entries []orderedService, order HealthyOrder) []service.IService {
+	switch order {
+	case HealthyOrderByID:
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].srv.ID() < entries[j].srv.ID()
+		})
+	case HealthyOrderByAdded:
+		sort.Slice(entries, func(i, j int) bool {
+			if !entries[i].added.Equal(entries[j].added) {
+				return entries[i].added.Before(entries[j].added)
+			}
+			return entries[i].srv.ID() < entries[j].srv.ID()
+		})
+	}
+
+	services := make([]service.IService, 0, len(entries))
+	for _, entry := range entries {
+		services = append(services, entry.srv)
+	}
+
+	return services
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func serviceIDs(services []service.IService) []string {
+	ids := make([]string, 0, len(services))
+	for _, srv := range services {
+		ids = append(ids, srv.ID())
+	}
+	return ids
+}
+
+func TestHealthySorted(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Removal:        RemovalSwap,
+	})
+
+	var added []string
+	for _, address := range []string{"https://1gateway.fm", "https://2gateway.fm", "https://3gateway.fm", "https://4gateway.fm"} {
+		srv := newHealthyService(address)
+		list.Add(srv)
+		added = append(added, srv.ID())
+		time.Sleep(time.Millisecond)
+	}
+
+	// swap removal moves the last service to the removed place
+	list.RemoveFromHealthyByIndex(0)
+	added = added[1:]
+
+	if got := serviceIDs(list.HealthySorted(HealthyOrderByAdded)); !slices.Equal(got, added) {
+		t.Errorf("expected services in added order %v, got %v", added, got)
+	}
+
+	byID := append([]string(nil), added...)
+	sort.Strings(byID)
+	if got := serviceIDs(list.HealthySorted(HealthyOrderByID)); !slices.Equal(got, byID) {
+		t.Errorf("expected services sorted by id %v, got %v", byID, got)
+	}
+
+	// added time is kept while service is in the jail
+	list.FromHealthyToJail(added[0])
+	list.FromJailToHealthy(list.Jailed()[added[0]])
+
+	if got := serviceIDs(list.HealthySorted(HealthyOrderByAdded)); !slices.Equal(got, added) {
+		t.Errorf("recovered service should keep its added time, got %v", got)
+	}
+}
+
+func TestHealthyOrderOption(t *testing.T) {
+	for _, shards := range []int{1, 3} {
+		list := NewServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+			Shards:         shards,
+			HealthyOrder:   HealthyOrderByID,
+		})
+
+		for _, address := range []string{"https://1gateway.fm", "https://2gateway.fm", "https://3gateway.fm", "https://4gateway.fm", "https://5gateway.fm"} {
+			list.Add(newHealthyService(address))
+		}
+
+		ids := serviceIDs(list.Healthy())
+		if len(ids) != 5 || !sort.StringsAreSorted(ids) {
+			t.Errorf("%d shards: expected healthy services sorted by id, got %v", shards, ids)
+		}
+	}
+}
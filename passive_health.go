@@ -0,0 +1,209 @@
+package pool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultConsecutiveFailures = 5
+	defaultMaxEjectionPercent  = 10
+)
+
+// PassiveHealthOpts is options that configure jailing of services
+// by outcomes of real requests reported by callers, with the same
+// semantics as Envoy outlier detection consecutive_5xx and
+// max_ejection_percent
+type PassiveHealthOpts struct {
+	ConsecutiveFailures int           // consecutive failed requests jailing the service (5 by default)
+	Window              time.Duration // failures older than window are forgotten, so sparse failures never jail the service (0 to keep all)
+	MaxEjectionPercent  int           // max percent of members jailed by passive health at once, at least one is always allowed (10 by default)
+}
+
+// passiveHealth is consecutive failures of members
+// reported by callers and services they have jailed
+type passiveHealth struct {
+	opts PassiveHealthOpts
+
+	mu       sync.Mutex
+	failures map[string][]time.Time // service id -> times of consecutive failures
+	ejected  map[string]struct{}    // ids of services jailed by passive health
+
+	// ejecting serialize ejections, so services being
+	// jailed are counted by max ejection percent
+	ejecting sync.Mutex
+}
+
+// newPassiveHealth create passive health tracking,
+// nil is returned if passive health is disabled
+func newPassiveHealth(opts *PassiveHealthOpts) *passiveHealth {
+	if opts == nil {
+		return nil
+	}
+
+	p := &passiveHealth{
+		opts:     *opts,
+		failures: make(map[string][]time.Time),
+		ejected:  make(map[string]struct{}),
+	}
+
+	if p.opts.ConsecutiveFailures <= 0 {
+		p.opts.ConsecutiveFailures = defaultConsecutiveFailures
+	}
+	if p.opts.MaxEjectionPercent <= 0 {
+		p.opts.MaxEjectionPercent = defaultMaxEjectionPercent
+	}
+
+	return p
+}
+
+// success reset consecutive failures of service with given id
+func (p *passiveHealth) success(id string) {
+	if p == nil {
+		return
+	}
+
+	defer p.mu.Unlock()
+	p.mu.Lock()
+
+	delete(p.failures, id)
+}
+
+// failure record failure of service with given id
+// and report if the service should be jailed
+func (p *passiveHealth) failure(id string) bool {
+	if p == nil {
+		return false
+	}
+
+	defer p.mu.Unlock()
+	p.mu.Lock()
+
+	now := time.Now()
+	failures := append(p.failures[id], now)
+	if p.opts.Window > 0 {
+		for len(failures) > 0 && now.Sub(failures[0]) > p.opts.Window {
+			failures = failures[1:]
+		}
+	}
+	p.failures[id] = failures
+
+	return len(failures) >= p.opts.ConsecutiveFailures
+}
+
+// eject register service with given id jailed by passive
+// health unless max ejection percent of given number of
+// members would be exceeded by jailed ones
+func (p *passiveHealth) eject(id string, members int, jailed func(id string) bool) bool {
+	defer p.mu.Unlock()
+	p.mu.Lock()
+
+	ejected := 0
+	for ejectedID := range p.ejected {
+		if !jailed(ejectedID) {
+			delete(p.ejected, ejectedID)
+			continue
+		}
+		ejected++
+	}
+
+	if ejected > 0 && (ejected+1)*100 > members*p.opts.MaxEjectionPercent {
+		return false
+	}
+
+	p.ejected[id] = struct{}{}
+	delete(p.failures, id)
+
+	return true
+}
+
+// forget remove failures of service with given id
+func (p *passiveHealth) forget(id string) {
+	if p == nil {
+		return
+	}
+
+	defer p.mu.Unlock()
+	p.mu.Lock()
+
+	delete(p.failures, id)
+	delete(p.ejected, id)
+}
+
+// ReportSuccess report successful request to given
+// service, its consecutive failures are reset
+func (l *ServicesList) ReportSuccess(srv service.IService) {
+	l.passive.success(srv.ID())
+}
+
+// ReportFailure report failed request to given service, the
+// service is jailed without waiting for the next healthcheck
+// once it fails configured number of consecutive requests,
+// unless outlier ejection feature is turned off
+func (l *ServicesList) ReportFailure(srv service.IService, err error) {
+	if !l.passive.failure(srv.ID()) || !l.FeatureEnabled(FeatureOutlierEjection) {
+		return
+	}
+
+	// failures are caused by the dependency
+	// rather than by the service itself
+	if l.checkDependencies() {
+		return
+	}
+
+	defer l.passive.ejecting.Unlock()
+	l.passive.ejecting.Lock()
+
+	l.mu.RLock()
+	members := len(l.healthy) + len(l.jail) + len(l.review)
+	ejected := l.passive.eject(srv.ID(), members, func(id string) bool {
+		_, ok := l.jail[id]
+		return ok
+	})
+	l.mu.RUnlock()
+
+	if !ejected {
+		l.log().Warn("service is not jailed by passive health, max ejection percent is reached", "service", srv.ID(), "node", srv.NodeName())
+		return
+	}
+
+	l.log().Warn("service failed consecutive requests", "service", srv.ID(), "node", srv.NodeName(), "failures", l.passive.opts.ConsecutiveFailures, "error", err)
+
+	// service is jailed synchronously, so
+	// it's excluded from the next selection
+	if l.fromHealthyToJail(srv.ID()) == nil {
+		l.passive.forget(srv.ID())
+		return
+	}
+	l.emit(PoolEvent{Type: EventServiceJailed, Service: srv})
+
+	l.goTask(taskTryUp, func() {
+		l.TryUpService(srv, 0)
+	})
+}
+
+// reportResult report outcome of request
+// to given service to passive health
+func (l *ServicesList) reportResult(srv service.IService, err error) {
+	if l.passive == nil {
+		return
+	}
+
+	if err != nil {
+		l.ReportFailure(srv, err)
+		return
+	}
+	l.ReportSuccess(srv)
+}
+
+// ReportSuccess report successful request to given service
+func (l *ShardedServicesList) ReportSuccess(srv service.IService) {
+	l.shard(srv.ID()).ReportSuccess(srv)
+}
+
+// ReportFailure report failed request to given service
+func (l *ShardedServicesList) ReportFailure(srv service.IService, err error) {
+	l.shard(srv.ID()).ReportFailure(srv, err)
+}
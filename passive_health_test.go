@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestPassiveHealth(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		PassiveHealth:  &PassiveHealthOpts{ConsecutiveFailures: 3, MaxEjectionPercent: 50},
+	})
+	defer list.Close()
+
+	services := []service.IService{
+		newHealthyService("https://1gateway.fm"),
+		newHealthyService("https://2gateway.fm"),
+		newHealthyService("https://3gateway.fm"),
+		newHealthyService("https://4gateway.fm"),
+	}
+	list.AddAll(services)
+
+	failure := errors.New("upstream error")
+
+	// success resets consecutive failures
+	list.ReportFailure(services[0], failure)
+	list.ReportFailure(services[0], failure)
+	list.ReportSuccess(services[0])
+	list.ReportFailure(services[0], failure)
+	list.ReportFailure(services[0], failure)
+	if len(list.Jailed()) != 0 {
+		t.Fatalf("service should not be jailed without consecutive failures")
+	}
+
+	// outcomes observed by the caller are reported as well
+	list.ObserveResult(context.Background(), services[0], time.Millisecond, failure)
+	if _, ok := list.Jailed()[services[0].ID()]; !ok {
+		t.Fatalf("service should be jailed after consecutive failures")
+	}
+
+	for i := 0; i < 3; i++ {
+		list.ReportFailure(services[1], failure)
+		list.ReportFailure(services[2], failure)
+	}
+
+	// max ejection percent keeps half of services
+	if jailed := list.Jailed(); len(jailed) != 2 {
+		t.Errorf("expected 2 jailed services, got %d", len(jailed))
+	}
+}
+
+func TestPassiveHealthWindow(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		PassiveHealth:  &PassiveHealthOpts{ConsecutiveFailures: 2, Window: 50 * time.Millisecond},
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	list.ReportFailure(srv, errors.New("upstream error"))
+	time.Sleep(100 * time.Millisecond)
+	list.ReportFailure(srv, errors.New("upstream error"))
+
+	if len(list.Jailed()) != 0 {
+		t.Errorf("failures outside of window should not jail the service")
+	}
+}
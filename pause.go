@@ -0,0 +1,133 @@
+package pool
+
+import (
+	"sync"
+)
+
+// pauser suspend background activity
+// until it's resumed
+type pauser struct {
+	resumed chan struct{} // closed on resume, nil while not paused
+
+	mu sync.Mutex
+}
+
+// pause suspend background activity,
+// returns false if it's already paused
+func (p *pauser) pause() bool {
+	defer p.mu.Unlock()
+	p.mu.Lock()
+
+	if p.resumed != nil {
+		return false
+	}
+
+	p.resumed = make(chan struct{})
+	return true
+}
+
+// resume release all waiting background activity,
+// returns false if it's not paused
+func (p *pauser) resume() bool {
+	defer p.mu.Unlock()
+	p.mu.Lock()
+
+	if p.resumed == nil {
+		return false
+	}
+
+	close(p.resumed)
+	p.resumed = nil
+	return true
+}
+
+// paused check if background activity is paused
+func (p *pauser) paused() bool {
+	defer p.mu.Unlock()
+	p.mu.Lock()
+
+	return p.resumed != nil
+}
+
+// wait block while background activity is paused
+// or until given stop channel is closed
+func (p *pauser) wait(stop <-chan struct{}) {
+	p.mu.Lock()
+	resumed := p.resumed
+	p.mu.Unlock()
+
+	if resumed == nil {
+		return
+	}
+
+	select {
+	case <-resumed:
+	case <-stop:
+	}
+}
+
+// Pause suspend healthchecks and try ups of the list,
+// selection keeps working on the frozen membership
+func (l *ServicesList) Pause() {
+	if !l.pause.pause() {
+		return
+	}
+
+	l.metrics.observePaused(l.serviceName, true)
+	l.log().Info("healthchecks and try ups are paused")
+}
+
+// Resume continue paused healthchecks and try ups of the list
+func (l *ServicesList) Resume() {
+	if !l.pause.resume() {
+		return
+	}
+
+	l.metrics.observePaused(l.serviceName, false)
+	l.log().Info("healthchecks and try ups are resumed")
+}
+
+// Paused check if list background activity is paused
+func (l *ServicesList) Paused() bool {
+	return l.pause.paused()
+}
+
+// Pause suspend healthchecks and try ups of all shards
+func (l *ShardedServicesList) Pause() {
+	for _, shard := range l.shards {
+		shard.Pause()
+	}
+}
+
+// Resume continue paused healthchecks
+// and try ups of all shards
+func (l *ShardedServicesList) Resume() {
+	for _, shard := range l.shards {
+		shard.Resume()
+	}
+}
+
+// Paused check if background activity of shards is paused
+func (l *ShardedServicesList) Paused() bool {
+	return l.shards[0].Paused()
+}
+
+// Pause suspend rediscovery, healthchecks and try ups,
+// e.g. during registry maintenance. Selection keeps
+// working on the frozen membership
+func (p *ServicesPool) Pause() {
+	p.pause.pause()
+	p.list.Pause()
+}
+
+// Resume continue paused rediscovery,
+// healthchecks and try ups
+func (p *ServicesPool) Resume() {
+	p.pause.resume()
+	p.list.Resume()
+}
+
+// Paused check if pool background activity is paused
+func (p *ServicesPool) Paused() bool {
+	return p.pause.paused()
+}
@@ -0,0 +1,49 @@
+package pool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestServicesPoolPause(t *testing.T) {
+	pool := newServicesPool(time.Second, time.Second, nil)
+	list := pool.List()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+	list.FromHealthyToJail(srv.ID())
+
+	pool.Pause()
+
+	if !pool.Paused() || !list.Snapshot().Paused {
+		t.Fatalf("paused state should be reflected in snapshot")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		list.TryUpService(srv, 0)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("try up should wait while pool is paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if len(list.Healthy()) != 0 {
+		t.Fatalf("service should stay in jail while pool is paused")
+	}
+
+	pool.Resume()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("try up should continue after resume")
+	}
+
+	if pool.Paused() || len(list.Healthy()) != 1 {
+		t.Errorf("service should be up after resume")
+	}
+}
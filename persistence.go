@@ -0,0 +1,354 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultPersistInterval = time.Minute
+	defaultPersistMaxAge   = 10 * time.Minute
+	defaultPersistTimeout  = 5 * time.Second
+)
+
+// PersistedState is state of the list kept across restarts, so
+// services known to be bad before restart are not given traffic
+// right after it and learned state is not lost
+type PersistedState struct {
+	Saved   time.Time              `json:"saved"`
+	Jailed  map[string]time.Time   `json:"jailed,omitempty"`  // service id -> time it's jailed since
+	Flaps   map[string][]time.Time `json:"flaps,omitempty"`   // service id -> times of recent healthy to jail transitions
+	Weights map[string]int         `json:"weights,omitempty"` // service id -> relative capacity set explicitly
+}
+
+// IStateStore is storage of
+// state kept across restarts
+type IStateStore interface {
+	// Load return saved state of pool with given
+	// name, nil is returned if state is not saved
+	Load(ctx context.Context, pool string) (*PersistedState, error)
+
+	// Save replace saved state of pool with given name
+	Save(ctx context.Context, pool string, state *PersistedState) error
+}
+
+// PersistenceOpts is options that configure
+// persistence of pool state across restarts
+type PersistenceOpts struct {
+	Store    IStateStore   // storage of the state, e.g. NewFileStateStore or NewRedisStateStore
+	Interval time.Duration // state saving interval, state is saved on pool close as well (1m by default)
+	MaxAge   time.Duration // saved state older than given age is not restored (10m by default)
+	Timeout  time.Duration // deadline of one load or save of the state (5s by default)
+}
+
+// weightSetter is implemented by services
+// which weight could be set explicitly
+type weightSetter interface {
+	SetWeight(weight int)
+}
+
+// restoredService is state of service persisted
+// before restart applied once it's added to the list
+type restoredService struct {
+	jailedSince time.Time
+	flaps       []time.Time
+	weight      int
+}
+
+// restoredServices is state of services persisted before
+// restart waiting for them to be added to the list, state
+// is dropped if services are not added in time
+type restoredServices struct {
+	mu      sync.Mutex
+	entries map[string]restoredService
+	expires time.Time
+	held    map[string]struct{} // ids of restored jailed services not tried to up yet
+}
+
+// newRestoredServices create empty restored state
+func newRestoredServices() *restoredServices {
+	return &restoredServices{
+		entries: make(map[string]restoredService),
+		held:    make(map[string]struct{}),
+	}
+}
+
+// set replace restored state with given
+// one which is dropped at given time
+func (r *restoredServices) set(state *PersistedState, expires time.Time) {
+	entries := make(map[string]restoredService)
+
+	for id, since := range state.Jailed {
+		entry := entries[id]
+		entry.jailedSince = since
+		entries[id] = entry
+	}
+	for id, flaps := range state.Flaps {
+		entry := entries[id]
+		entry.flaps = slices.Clone(flaps)
+		entries[id] = entry
+	}
+	for id, weight := range state.Weights {
+		entry := entries[id]
+		entry.weight = weight
+		entries[id] = entry
+	}
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	r.entries = entries
+	r.expires = expires
+}
+
+// take return and forget restored state of service with given id
+func (r *restoredServices) take(id string) (restoredService, bool) {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	if len(r.entries) == 0 {
+		return restoredService{}, false
+	}
+
+	if time.Now().After(r.expires) {
+		clear(r.entries)
+		return restoredService{}, false
+	}
+
+	entry, ok := r.entries[id]
+	delete(r.entries, id)
+
+	return entry, ok
+}
+
+// hold mark service with given id as jailed before restart
+func (r *restoredServices) hold(id string) {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	r.held[id] = struct{}{}
+}
+
+// release forget mark of service with given id and
+// report if it was jailed before restart
+func (r *restoredServices) release(id string) bool {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	_, ok := r.held[id]
+	delete(r.held, id)
+
+	return ok
+}
+
+// ExportState return state of the list kept across restarts:
+// jailed services, recent flaps and explicitly set weights
+func (l *ServicesList) ExportState() *PersistedState {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	state := &PersistedState{
+		Saved:   time.Now(),
+		Jailed:  make(map[string]time.Time, len(l.jail)),
+		Flaps:   make(map[string][]time.Time),
+		Weights: make(map[string]int),
+	}
+
+	for id := range l.jail {
+		state.Jailed[id] = l.jailedAt[id]
+	}
+
+	for id, flaps := range l.flaps {
+		if len(flaps) > 0 {
+			state.Flaps[id] = slices.Clone(flaps)
+		}
+	}
+
+	exportWeight := func(srv service.IService) {
+		if weighted, ok := srv.(service.IWeightedService); ok && weighted.Weight() > 0 {
+			state.Weights[srv.ID()] = weighted.Weight()
+		}
+	}
+	for _, srv := range l.healthy {
+		exportWeight(srv)
+	}
+	for _, srv := range l.jail {
+		exportWeight(srv)
+	}
+	for _, item := range l.review {
+		exportWeight(item.Service)
+	}
+
+	return state
+}
+
+// RestoreState restore given state saved before restart, state of
+// services is applied once they are added to the list, e.g. by the
+// first discovery round. Services jailed before restart are jailed
+// again and given try up interval before the first try. State older
+// than given age is ignored and state of services that are not added
+// until the state reaches the age is dropped
+func (l *ServicesList) RestoreState(state *PersistedState, maxAge time.Duration) {
+	if state == nil {
+		return
+	}
+
+	expires := state.Saved.Add(maxAge)
+	if time.Now().After(expires) {
+		l.log().Info("state is too old to be restored", "saved", state.Saved.Format(time.RFC3339))
+		return
+	}
+
+	l.restored.set(state, expires)
+
+	l.log().Info("state is restored", "saved", state.Saved.Format(time.RFC3339), "jailed", len(state.Jailed))
+}
+
+// restorePersistedState apply state of given service persisted
+// before restart and report if the service was jailed before it.
+// Should be called under the list lock
+func (l *ServicesList) restorePersistedState(srv service.IService) bool {
+	restored, ok := l.restored.take(srv.ID())
+	if !ok {
+		return false
+	}
+
+	if len(restored.flaps) > 0 && l.reviewPolicy != nil {
+		l.flaps[srv.ID()] = trimToWindow(restored.flaps, l.reviewPolicy.Window)
+	}
+
+	if restored.weight > 0 {
+		weighted, explicit := srv.(service.IWeightedService)
+		if setter, ok := srv.(weightSetter); ok && (!explicit || weighted.Weight() == 0) {
+			setter.SetWeight(restored.weight)
+		}
+	}
+
+	if restored.jailedSince.IsZero() {
+		return false
+	}
+
+	l.jailedAt[srv.ID()] = restored.jailedSince
+	l.restored.hold(srv.ID())
+
+	return true
+}
+
+// ExportState return state of all shards kept across restarts
+func (l *ShardedServicesList) ExportState() *PersistedState {
+	state := &PersistedState{
+		Saved:   time.Now(),
+		Jailed:  make(map[string]time.Time),
+		Flaps:   make(map[string][]time.Time),
+		Weights: make(map[string]int),
+	}
+
+	for _, shard := range l.shards {
+		shardState := shard.ExportState()
+		for id, since := range shardState.Jailed {
+			state.Jailed[id] = since
+		}
+		for id, flaps := range shardState.Flaps {
+			state.Flaps[id] = flaps
+		}
+		for id, weight := range shardState.Weights {
+			state.Weights[id] = weight
+		}
+	}
+
+	return state
+}
+
+// RestoreState restore given state saved before restart
+// in every shard, state of services is applied by shards
+// they are added to
+func (l *ShardedServicesList) RestoreState(state *PersistedState, maxAge time.Duration) {
+	for _, shard := range l.shards {
+		shard.RestoreState(state, maxAge)
+	}
+}
+
+// statePersister periodically save state of
+// the list and restore it on pool creation
+type statePersister struct {
+	name   string
+	list   IServicesList
+	opts   PersistenceOpts
+	logger ILogger
+}
+
+// newStatePersister create persister of state of
+// given list logging with given pool logger
+func newStatePersister(name string, list IServicesList, opts *PersistenceOpts, logger ILogger) *statePersister {
+	p := &statePersister{
+		name:   name,
+		list:   list,
+		opts:   *opts,
+		logger: logger,
+	}
+
+	if p.opts.Interval <= 0 {
+		p.opts.Interval = defaultPersistInterval
+	}
+	if p.opts.MaxAge <= 0 {
+		p.opts.MaxAge = defaultPersistMaxAge
+	}
+	if p.opts.Timeout <= 0 {
+		p.opts.Timeout = defaultPersistTimeout
+	}
+
+	return p
+}
+
+// restore load saved state and restore it in the list
+func (p *statePersister) restore() error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	defer cancel()
+
+	state, err := p.opts.Store.Load(ctx, p.name)
+	if err != nil {
+		return fmt.Errorf("load state of pool %s: %w", p.name, err)
+	}
+
+	p.list.RestoreState(state, p.opts.MaxAge)
+
+	return nil
+}
+
+// save save current state of the list
+func (p *statePersister) save() error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.opts.Timeout)
+	defer cancel()
+
+	if err := p.opts.Store.Save(ctx, p.name, p.list.ExportState()); err != nil {
+		return fmt.Errorf("save state of pool %s: %w", p.name, err)
+	}
+
+	return nil
+}
+
+// Run save state of the list periodically until
+// given channel is closed and once more after it
+func (p *statePersister) Run(stop <-chan struct{}) {
+	p.logger.Info("start state persister")
+
+	for {
+		Sleep(p.opts.Interval, stop)
+
+		if err := p.save(); err != nil {
+			p.logger.Warn("save state error", "error", err)
+		}
+
+		select {
+		case <-stop:
+			p.logger.Warn("stop state persister")
+			return
+		default:
+		}
+	}
+}
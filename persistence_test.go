@@ -0,0 +1,127 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServicesListRestoreState(t *testing.T) {
+	opts := &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		ReviewPolicy:   &ReviewPolicy{FlapThreshold: 3, Window: time.Hour},
+	}
+
+	before := newServicesList("testServicesList", opts)
+
+	bad := newHealthyService("https://1gateway.fm")
+	heavy := newHealthyService("https://2gateway.fm")
+	heavy.(*service.BaseService).SetWeight(5)
+	before.Add(bad)
+	before.Add(heavy)
+	before.FromHealthyToJail(bad.ID())
+
+	state := before.ExportState()
+	before.Close()
+
+	if _, ok := state.Jailed[bad.ID()]; !ok || state.Weights[heavy.ID()] != 5 || len(state.Flaps[bad.ID()]) != 1 {
+		t.Fatalf("unexpected exported state %+v", state)
+	}
+
+	after := newServicesList("testServicesList", opts)
+	defer after.Close()
+
+	after.RestoreState(state, time.Minute)
+
+	// services are rediscovered healthy after restart
+	rediscoveredBad := newHealthyService("https://1gateway.fm")
+	rediscoveredHeavy := newHealthyService("https://2gateway.fm")
+	after.Add(rediscoveredBad)
+	after.Add(rediscoveredHeavy)
+
+	items := after.JailedItems()
+	if len(items) != 1 || items[0].Service != rediscoveredBad || !items[0].Since.Equal(state.Jailed[bad.ID()]) {
+		t.Fatalf("service jailed before restart should be jailed since the same time, got %v", items)
+	}
+
+	// service is not tried to up right after restart
+	time.Sleep(20 * time.Millisecond)
+	if after.CountJailed() != 1 {
+		t.Errorf("service jailed before restart should wait for try up interval")
+	}
+
+	if service.Weight(rediscoveredHeavy) != 5 {
+		t.Errorf("weight should be restored, got %d", service.Weight(rediscoveredHeavy))
+	}
+
+	if len(after.flaps[bad.ID()]) != 1 {
+		t.Errorf("flaps should be restored")
+	}
+}
+
+func TestServicesListRestoreStateTooOld(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.RestoreState(&PersistedState{
+		Saved:  time.Now().Add(-time.Hour),
+		Jailed: map[string]time.Time{srv.ID(): time.Now().Add(-time.Hour)},
+	}, time.Minute)
+
+	list.Add(srv)
+	if list.CountJailed() != 0 {
+		t.Errorf("too old state should not be restored")
+	}
+}
+
+func TestServicesPoolPersistence(t *testing.T) {
+	store := NewFileStateStore(t.TempDir())
+
+	newPool := func() IServicesPool {
+		return NewServicesPool(&ServicesPoolsOpts{
+			Name: "TestServicePool",
+			Seeds: []service.IService{
+				newHealthyService("https://1gateway.fm"),
+				newHealthyService("https://2gateway.fm"),
+			},
+			ListOpts: &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  time.Hour,
+				ChecksInterval: time.Hour,
+			},
+			Persistence: &PersistenceOpts{Store: store, Interval: time.Hour},
+		})
+	}
+
+	pool := newPool()
+	pool.Start(false)
+
+	waitFor(t, func() bool { return pool.Count() == 2 })
+	jailed := pool.List().Healthy()[0]
+	pool.List().FromHealthyToJail(jailed.ID())
+
+	// state is saved before close returns
+	pool.Close()
+	state, err := store.Load(context.Background(), "TestServicePool")
+	if err != nil || state == nil || len(state.Jailed) != 1 {
+		t.Fatalf("state should be saved on close, got %+v, %v", state, err)
+	}
+
+	restarted := newPool()
+	defer restarted.Close()
+
+	waitFor(t, func() bool { return restarted.List().CountAll() == 2 })
+
+	if _, ok := restarted.List().Jailed()[jailed.ID()]; !ok || restarted.Count() != 1 {
+		t.Errorf("seed jailed before restart should be jailed")
+	}
+}
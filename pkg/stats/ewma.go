@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// EWMA is exponentially weighted moving average of observed
+// values decayed by time: weight of observation is halved every
+// half-life, so the average follows recent values regardless
+// of how often they are observed
+type EWMA struct {
+	halfLife time.Duration
+
+	mu     sync.Mutex
+	sum    float64 // decayed sum of observed values
+	weight float64 // decayed number of observed values
+	last   time.Time
+}
+
+// NewEWMA create empty moving average
+// with given half-life of observations
+func NewEWMA(halfLife time.Duration) *EWMA {
+	return &EWMA{halfLife: halfLife}
+}
+
+// Update observe given value now
+func (e *EWMA) Update(value float64) {
+	e.UpdateAt(value, time.Now())
+}
+
+// UpdateAt observe given value at given time,
+// observations older than the last one are
+// treated as made at the time of the last one
+func (e *EWMA) UpdateAt(value float64, now time.Time) {
+	defer e.mu.Unlock()
+	e.mu.Lock()
+
+	e.decay(now)
+
+	e.sum += value
+	e.weight++
+}
+
+// Value return current average, 0 is
+// returned if nothing is observed yet
+func (e *EWMA) Value() float64 {
+	defer e.mu.Unlock()
+	e.mu.Lock()
+
+	if e.weight == 0 {
+		return 0
+	}
+
+	// decay does not change the ratio
+	return e.sum / e.weight
+}
+
+// Weight return decayed number of observations
+// the average is based on at given time
+func (e *EWMA) Weight(now time.Time) float64 {
+	defer e.mu.Unlock()
+	e.mu.Lock()
+
+	return e.weight * decayFactor(now.Sub(e.last), e.halfLife)
+}
+
+// Reset forget all observations
+func (e *EWMA) Reset() {
+	defer e.mu.Unlock()
+	e.mu.Lock()
+
+	e.sum, e.weight, e.last = 0, 0, time.Time{}
+}
+
+// decay decay observations to given time.
+// Should be called under the lock
+func (e *EWMA) decay(now time.Time) {
+	if e.last.IsZero() {
+		e.last = now
+		return
+	}
+
+	if elapsed := now.Sub(e.last); elapsed > 0 {
+		factor := decayFactor(elapsed, e.halfLife)
+		e.sum *= factor
+		e.weight *= factor
+		e.last = now
+	}
+}
+
+// decayFactor return factor observation weight is
+// multiplied by after given time with given half-life,
+// zero half-life disables decay
+func decayFactor(elapsed, halfLife time.Duration) float64 {
+	if halfLife <= 0 || elapsed <= 0 {
+		return 1
+	}
+
+	return math.Exp2(-float64(elapsed) / float64(halfLife))
+}
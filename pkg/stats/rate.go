@@ -0,0 +1,67 @@
+package stats
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Rate is exponentially decayed rate of events per
+// second, e.g. throughput of requests. Events are
+// counted with the weight halved every half-life,
+// so the rate follows recent traffic
+type Rate struct {
+	halfLife time.Duration
+
+	mu    sync.Mutex
+	count float64 // decayed number of events
+	last  time.Time
+}
+
+// NewRate create empty rate with
+// given half-life of events
+func NewRate(halfLife time.Duration) *Rate {
+	return &Rate{halfLife: halfLife}
+}
+
+// Mark register given number of events now
+func (r *Rate) Mark(n int) {
+	r.MarkAt(n, time.Now())
+}
+
+// MarkAt register given number of events at given time
+func (r *Rate) MarkAt(n int, now time.Time) {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	if !r.last.IsZero() && now.After(r.last) {
+		r.count *= decayFactor(now.Sub(r.last), r.halfLife)
+	}
+	if r.last.IsZero() || now.After(r.last) {
+		r.last = now
+	}
+
+	r.count += float64(n)
+}
+
+// Rate return current number of events per second
+func (r *Rate) Rate() float64 {
+	return r.RateAt(time.Now())
+}
+
+// RateAt return number of events per second at given time.
+// Decayed count of steady traffic converges to the rate
+// multiplied by mean lifetime of event, so the rate is
+// underestimated during the first half-lives
+func (r *Rate) RateAt(now time.Time) float64 {
+	if r.halfLife <= 0 {
+		return 0
+	}
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	lifetime := r.halfLife.Seconds() / math.Ln2
+
+	return r.count * decayFactor(now.Sub(r.last), r.halfLife) / lifetime
+}
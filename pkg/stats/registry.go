@@ -0,0 +1,139 @@
+package stats
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// Registry is statistics of set of services by
+// theirs ids. Services list tracks its members in
+// the registry, so balancers and policies holding
+// the same registry could read statistics of
+// services they select from
+type Registry struct {
+	opts Opts
+
+	mu       sync.RWMutex
+	services map[string]*Service
+}
+
+// NewRegistry create empty registry creating
+// statistics of services with given configuration
+func NewRegistry(opts Opts) *Registry {
+	return &Registry{
+		opts:     opts,
+		services: make(map[string]*Service),
+	}
+}
+
+// Track return statistics of service with given
+// id, statistics are created on the first call
+func (r *Registry) Track(id string) *Service {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	s, ok := r.services[id]
+	r.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	if s, ok = r.services[id]; !ok {
+		s = NewService(r.opts)
+		r.services[id] = s
+	}
+
+	return s
+}
+
+// Get return statistics of service with given
+// id, nil is returned if service is not tracked
+func (r *Registry) Get(id string) *Service {
+	if r == nil {
+		return nil
+	}
+
+	defer r.mu.RUnlock()
+	r.mu.RLock()
+
+	return r.services[id]
+}
+
+// Restore set given statistics of service with given id,
+// e.g. kept while the service was removed from the list
+func (r *Registry) Restore(id string, s *Service) {
+	if r == nil {
+		return
+	}
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	r.services[id] = s
+}
+
+// Forget remove statistics of service with given id
+func (r *Registry) Forget(id string) {
+	if r == nil {
+		return
+	}
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	delete(r.services, id)
+}
+
+// Usage return number of tracked services and estimation
+// of memory used by theirs statistics in bytes
+func (r *Registry) Usage() (int, int) {
+	if r == nil {
+		return 0, 0
+	}
+
+	r.mu.RLock()
+	services := len(r.services)
+	r.mu.RUnlock()
+
+	buckets := r.opts.Buckets
+	if buckets <= 0 {
+		buckets = DefaultBuckets
+	}
+
+	// service with its averages, rate and two rolling
+	// windows, map entry overhead is included
+	size := int(unsafe.Sizeof(Service{})) + 3*int(unsafe.Sizeof(EWMA{})) + int(unsafe.Sizeof(Rate{})) +
+		2*(int(unsafe.Sizeof(Window{}))+buckets*int(unsafe.Sizeof(windowBucket{}))) + 3*8
+
+	return services, services * size
+}
+
+// Snapshot return current statistics
+// of all tracked services by theirs ids
+func (r *Registry) Snapshot() map[string]Snapshot {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	services := make(map[string]*Service, len(r.services))
+	for id, s := range r.services {
+		services[id] = s
+	}
+	r.mu.RUnlock()
+
+	now := time.Now()
+
+	snapshot := make(map[string]Snapshot, len(services))
+	for id, s := range services {
+		snapshot[id] = s.SnapshotAt(now)
+	}
+
+	return snapshot
+}
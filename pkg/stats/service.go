@@ -0,0 +1,157 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHalfLife = time.Minute
+	DefaultWindow   = time.Minute
+	DefaultBuckets  = 10
+)
+
+// Opts is options that configure statistics of one service
+type Opts struct {
+	HalfLife time.Duration // half-life of latency, error rate and throughput averages (1 minute by default)
+	Window   time.Duration // size of the rolling window of requests and failures counters (1 minute by default)
+	Buckets  int           // number of buckets the rolling window is split to (10 by default)
+}
+
+// Snapshot is point in time statistics of one service
+type Snapshot struct {
+	Latency      time.Duration `json:"latency"`       // moving average of request duration
+	CheckLatency time.Duration `json:"check_latency"` // moving average of successful healthcheck duration
+	ErrorRate    float64       `json:"error_rate"`    // moving average of failed requests fraction
+	Throughput   float64       `json:"throughput"`    // moving rate of requests per second
+	Requests     int           `json:"requests"`      // requests within the rolling window
+	Failures     int           `json:"failures"`      // failed requests within the rolling window
+	Updated      time.Time     `json:"updated"`       // time of the last observed request (zero if none)
+}
+
+// Service is moving statistics of requests made to one
+// service: latency, error rate and throughput, and of
+// its healthchecks latency
+type Service struct {
+	latency      *EWMA
+	checkLatency *EWMA
+	errors       *EWMA
+	throughput   *Rate
+	requests     *Window
+	failures     *Window
+
+	mu      sync.Mutex
+	updated time.Time
+}
+
+// NewService create empty service statistics
+// with given configuration, zero fields are
+// replaced with defaults
+func NewService(opts Opts) *Service {
+	if opts.HalfLife <= 0 {
+		opts.HalfLife = DefaultHalfLife
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.Buckets <= 0 {
+		opts.Buckets = DefaultBuckets
+	}
+
+	return &Service{
+		latency:      NewEWMA(opts.HalfLife),
+		checkLatency: NewEWMA(opts.HalfLife),
+		errors:       NewEWMA(opts.HalfLife),
+		throughput:   NewRate(opts.HalfLife),
+		requests:     NewWindow(opts.Window, opts.Buckets),
+		failures:     NewWindow(opts.Window, opts.Buckets),
+	}
+}
+
+// Observe register request with given duration and
+// error made now, nil error is successful request
+func (s *Service) Observe(duration time.Duration, err error) {
+	s.ObserveAt(duration, err, time.Now())
+}
+
+// ObserveAt register request with given
+// duration and error made at given time
+func (s *Service) ObserveAt(duration time.Duration, err error, now time.Time) {
+	var failed float64
+	if err != nil {
+		failed = 1
+	}
+
+	s.latency.UpdateAt(float64(duration), now)
+	s.errors.UpdateAt(failed, now)
+	s.throughput.MarkAt(1, now)
+	s.requests.AddAt(1, now)
+	s.failures.AddAt(failed, now)
+
+	s.mu.Lock()
+	if now.After(s.updated) {
+		s.updated = now
+	}
+	s.mu.Unlock()
+}
+
+// Latency return moving average of request duration
+func (s *Service) Latency() time.Duration {
+	return time.Duration(s.latency.Value())
+}
+
+// ObserveCheck register successful healthcheck with given
+// duration made now. Healthchecks are not counted as requests
+func (s *Service) ObserveCheck(duration time.Duration) {
+	s.checkLatency.Update(float64(duration))
+}
+
+// CheckLatency return moving average
+// of successful healthcheck duration
+func (s *Service) CheckLatency() time.Duration {
+	return time.Duration(s.checkLatency.Value())
+}
+
+// ExpectedLatency return moving average of request duration,
+// average of healthcheck duration is returned if no requests
+// are observed yet, e.g. for just discovered services
+func (s *Service) ExpectedLatency() time.Duration {
+	if latency := s.Latency(); latency > 0 {
+		return latency
+	}
+
+	return s.CheckLatency()
+}
+
+// ErrorRate return moving average of
+// failed requests fraction in [0, 1]
+func (s *Service) ErrorRate() float64 {
+	return s.errors.Value()
+}
+
+// Throughput return moving rate of requests per second
+func (s *Service) Throughput() float64 {
+	return s.throughput.Rate()
+}
+
+// Snapshot return current statistics
+func (s *Service) Snapshot() Snapshot {
+	return s.SnapshotAt(time.Now())
+}
+
+// SnapshotAt return statistics at given time
+func (s *Service) SnapshotAt(now time.Time) Snapshot {
+	s.mu.Lock()
+	updated := s.updated
+	s.mu.Unlock()
+
+	return Snapshot{
+		Latency:      s.Latency(),
+		CheckLatency: s.CheckLatency(),
+		ErrorRate:    s.ErrorRate(),
+		Throughput:   s.throughput.RateAt(now),
+		Requests:     s.requests.CountAt(now),
+		Failures:     int(s.failures.SumAt(now)),
+		Updated:      updated,
+	}
+}
@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEWMA(t *testing.T) {
+	start := time.Now()
+
+	e := NewEWMA(time.Minute)
+	if e.Value() != 0 {
+		t.Fatalf("empty average should be 0, got %f", e.Value())
+	}
+
+	// simultaneous observations are averaged equally
+	e.UpdateAt(10, start)
+	e.UpdateAt(20, start)
+	if e.Value() != 15 {
+		t.Errorf("expected average 15, got %f", e.Value())
+	}
+
+	// observations a half-life ago weigh half of the new one
+	e.UpdateAt(40, start.Add(time.Minute))
+	if got := e.Value(); math.Abs(got-(15+40)/2.0) > 1e-9 {
+		t.Errorf("expected average 27.5, got %f", got)
+	}
+
+	if w := e.Weight(start.Add(2 * time.Minute)); math.Abs(w-1) > 1e-9 {
+		t.Errorf("expected decayed weight 1, got %f", w)
+	}
+
+	e.Reset()
+	if e.Value() != 0 {
+		t.Errorf("reset average should be 0, got %f", e.Value())
+	}
+}
+
+func TestRate(t *testing.T) {
+	start := time.Now()
+
+	r := NewRate(10 * time.Second)
+
+	// steady traffic of 5 events per second
+	for i := 0; i < 5*120; i++ {
+		r.MarkAt(1, start.Add(time.Duration(i)*200*time.Millisecond))
+	}
+
+	if got := r.RateAt(start.Add(2 * time.Minute)); math.Abs(got-5) > 0.5 {
+		t.Errorf("expected rate about 5, got %f", got)
+	}
+
+	if got := r.RateAt(start.Add(30 * time.Minute)); got > 0.01 {
+		t.Errorf("rate should decay without traffic, got %f", got)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	start := time.Unix(1000, 0)
+
+	w := NewWindow(10*time.Second, 10)
+	for i := 0; i < 10; i++ {
+		w.AddAt(float64(i), start.Add(time.Duration(i)*time.Second))
+	}
+
+	now := start.Add(9 * time.Second)
+	if w.CountAt(now) != 10 || w.SumAt(now) != 45 || w.MeanAt(now) != 4.5 {
+		t.Errorf("unexpected window count %d sum %f mean %f", w.CountAt(now), w.SumAt(now), w.MeanAt(now))
+	}
+
+	// the oldest buckets are expired as the window moves
+	now = start.Add(14 * time.Second)
+	if w.CountAt(now) != 5 || w.SumAt(now) != 35 {
+		t.Errorf("unexpected moved window count %d sum %f", w.CountAt(now), w.SumAt(now))
+	}
+
+	w.AddAt(100, now)
+	if w.CountAt(now) != 6 || w.SumAt(now) != 135 {
+		t.Errorf("unexpected reused bucket count %d sum %f", w.CountAt(now), w.SumAt(now))
+	}
+
+	if w.CountAt(start.Add(time.Hour)) != 0 || w.MeanAt(start.Add(time.Hour)) != 0 {
+		t.Errorf("window should be empty after its size")
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry(Opts{HalfLife: time.Minute})
+
+	if registry.Get("id") != nil {
+		t.Fatalf("untracked service should not have statistics")
+	}
+
+	start := time.Now()
+
+	s := registry.Track("id")
+	s.ObserveAt(100*time.Millisecond, nil, start)
+	s.ObserveAt(300*time.Millisecond, errors.New("failed"), start)
+
+	if registry.Track("id") != s || registry.Get("id") != s {
+		t.Fatalf("tracked service statistics should be reused")
+	}
+
+	snapshot := registry.Snapshot()["id"]
+	if snapshot.Latency != 200*time.Millisecond || snapshot.ErrorRate != 0.5 {
+		t.Errorf("unexpected snapshot %+v", snapshot)
+	}
+	if snapshot.Requests != 2 || snapshot.Failures != 1 || !snapshot.Updated.Equal(start) {
+		t.Errorf("unexpected snapshot counters %+v", snapshot)
+	}
+
+	registry.Forget("id")
+	if registry.Get("id") != nil || len(registry.Snapshot()) != 0 {
+		t.Errorf("forgotten service should not have statistics")
+	}
+
+	var disabled *Registry
+	if disabled.Track("id") != nil || disabled.Snapshot() != nil {
+		t.Errorf("nil registry should not track services")
+	}
+}
+
+func TestServiceExpectedLatency(t *testing.T) {
+	s := NewService(Opts{})
+	if s.ExpectedLatency() != 0 {
+		t.Fatalf("unobserved service should have zero latency")
+	}
+
+	s.ObserveCheck(10 * time.Millisecond)
+	if s.ExpectedLatency() != 10*time.Millisecond || s.Snapshot().Requests != 0 {
+		t.Errorf("healthcheck latency should be expected without requests, got %s", s.ExpectedLatency())
+	}
+
+	s.Observe(30*time.Millisecond, nil)
+	if s.ExpectedLatency() != 30*time.Millisecond || s.Snapshot().CheckLatency != 10*time.Millisecond {
+		t.Errorf("request latency should be expected once observed, got %s", s.ExpectedLatency())
+	}
+}
@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// Window is rolling window of observed values split to
+// buckets, values are expired bucket by bucket as the
+// window moves, e.g. number of requests in the last minute
+type Window struct {
+	bucket time.Duration
+
+	mu      sync.Mutex
+	buckets []windowBucket
+}
+
+// windowBucket is observations of one bucket
+type windowBucket struct {
+	start int64 // bucket number since the epoch
+	count int
+	sum   float64
+}
+
+// NewWindow create empty rolling window of given size
+// split to given number of buckets (at least one)
+func NewWindow(size time.Duration, buckets int) *Window {
+	if buckets < 1 {
+		buckets = 1
+	}
+
+	bucket := size / time.Duration(buckets)
+	if bucket <= 0 {
+		bucket = 1
+	}
+
+	return &Window{
+		bucket:  bucket,
+		buckets: make([]windowBucket, buckets),
+	}
+}
+
+// Add observe given value now
+func (w *Window) Add(value float64) {
+	w.AddAt(value, time.Now())
+}
+
+// AddAt observe given value at given time, values
+// older than the window are dropped
+func (w *Window) AddAt(value float64, now time.Time) {
+	defer w.mu.Unlock()
+	w.mu.Lock()
+
+	start := w.start(now)
+
+	b := &w.buckets[start%int64(len(w.buckets))]
+	if b.start > start {
+		return
+	}
+	if b.start != start {
+		*b = windowBucket{start: start}
+	}
+
+	b.count++
+	b.sum += value
+}
+
+// Count return number of values observed within the window
+func (w *Window) Count() int {
+	count, _ := w.aggregate(time.Now())
+	return count
+}
+
+// Sum return sum of values observed within the window
+func (w *Window) Sum() float64 {
+	_, sum := w.aggregate(time.Now())
+	return sum
+}
+
+// Mean return mean of values observed within the
+// window, 0 is returned if nothing is observed
+func (w *Window) Mean() float64 {
+	return w.MeanAt(time.Now())
+}
+
+// CountAt return number of values observed
+// within the window ending at given time
+func (w *Window) CountAt(now time.Time) int {
+	count, _ := w.aggregate(now)
+	return count
+}
+
+// SumAt return sum of values observed
+// within the window ending at given time
+func (w *Window) SumAt(now time.Time) float64 {
+	_, sum := w.aggregate(now)
+	return sum
+}
+
+// MeanAt return mean of values observed
+// within the window ending at given time
+func (w *Window) MeanAt(now time.Time) float64 {
+	count, sum := w.aggregate(now)
+	if count == 0 {
+		return 0
+	}
+
+	return sum / float64(count)
+}
+
+// aggregate return number and sum of values
+// observed within the window ending at given time
+func (w *Window) aggregate(now time.Time) (count int, sum float64) {
+	defer w.mu.Unlock()
+	w.mu.Lock()
+
+	current := w.start(now)
+	oldest := current - int64(len(w.buckets)) + 1
+
+	for _, b := range w.buckets {
+		if b.count == 0 || b.start < oldest || b.start > current {
+			continue
+		}
+
+		count += b.count
+		sum += b.sum
+	}
+
+	return count, sum
+}
+
+// start return number of bucket given time falls to
+func (w *Window) start(t time.Time) int64 {
+	return t.UnixNano() / int64(w.bucket)
+}
@@ -0,0 +1,115 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// IPolicy is pluggable routing policy that could be
+// compiled-in or loaded from Go plugin at runtime.
+// Allow and Score are called under the list read lock
+// by concurrent selections, so they should be fast, safe
+// for concurrent use and must not call the list methods
+type IPolicy interface {
+	// Name return policy name for logging
+	Name() string
+
+	// Admit check if given service could be added
+	// to the list, non-nil error rejects the service
+	Admit(srv service.IService) error
+
+	// Allow check if given healthy service
+	// could be selected to take a connection
+	Allow(srv service.IService) bool
+
+	// Score adjust given selection score of
+	// given service, lower score is preferred
+	Score(srv service.IService, score float64) float64
+}
+
+// BasePolicy is no-op IPolicy implementation
+// that could be embedded to implement
+// only needed policy methods
+type BasePolicy struct{}
+
+// Name return policy name
+func (BasePolicy) Name() string {
+	return "base"
+}
+
+// Admit admit every service
+func (BasePolicy) Admit(service.IService) error {
+	return nil
+}
+
+// Allow allow every service
+func (BasePolicy) Allow(service.IService) bool {
+	return true
+}
+
+// Score return given score as is
+func (BasePolicy) Score(_ service.IService, score float64) float64 {
+	return score
+}
+
+// Policies return list routing policies
+func (l *ServicesList) Policies() []IPolicy {
+	return l.policies
+}
+
+// admit check given service against all list policies
+func (l *ServicesList) admit(srv service.IService) error {
+	for _, policy := range l.policies {
+		if err := policy.Admit(srv); err != nil {
+			return fmt.Errorf("rejected by policy %s: %w", policy.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// allow check if the list is not draining, given service is
+// neither standby spare, cordoned nor drained, belongs to priority level
+// picked for given request, meets requirement of the request, all
+// list policies allow it to take a connection for the request and
+// it's below its in-flight cap
+func (l *ServicesList) allow(ctx context.Context, srv service.IService) bool {
+	return l.admitted(ctx, srv) && l.belowInFlightCap(srv)
+}
+
+// admitted check if given service is allowed for given request
+// regardless of its in-flight cap
+func (l *ServicesList) admitted(ctx context.Context, srv service.IService) bool {
+	return !l.Draining() && !l.standby(srv) && !l.maintenance.isCordoned(srv.ID()) && !l.drains.has(srv.ID()) && allowedPriority(ctx, srv) && allowedRequirement(ctx, srv) && l.allowedByPolicies(ctx, srv)
+}
+
+// allowedByPolicies check if all list policies allow given
+// service to take a connection for given request
+func (l *ServicesList) allowedByPolicies(ctx context.Context, srv service.IService) bool {
+	for _, policy := range l.policies {
+		if contextPolicy, ok := policy.(IContextPolicy); ok {
+			if !contextPolicy.AllowContext(ctx, srv) {
+				return false
+			}
+			continue
+		}
+
+		if !policy.Allow(srv) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// score adjust given selection score of given
+// service by all list policies sequentially
+func (l *ServicesList) score(srv service.IService, score float64) float64 {
+	for _, policy := range l.policies {
+		score = policy.Score(srv, score)
+	}
+
+	return score
+}
@@ -0,0 +1,38 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package pool
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// PolicyPluginSymbol is name of the symbol that Go plugin
+// should export to provide a policy. The symbol should be
+// either IPolicy variable or func() IPolicy constructor
+const PolicyPluginSymbol = "Policy"
+
+// LoadPolicyPlugin load policy from Go plugin
+// (built with -buildmode=plugin) by given path
+func LoadPolicyPlugin(path string) (IPolicy, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open policy plugin %s: %w", path, err)
+	}
+
+	symbol, err := p.Lookup(PolicyPluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("lookup policy in plugin %s: %w", path, err)
+	}
+
+	switch policy := symbol.(type) {
+	case *IPolicy:
+		return *policy, nil
+	case IPolicy:
+		return policy, nil
+	case func() IPolicy:
+		return policy(), nil
+	default:
+		return nil, fmt.Errorf("unexpected policy symbol type %T in plugin %s", symbol, path)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package pool
+
+import "fmt"
+
+// PolicyPluginSymbol is name of the symbol that Go plugin
+// should export to provide a policy. The symbol should be
+// either IPolicy variable or func() IPolicy constructor
+const PolicyPluginSymbol = "Policy"
+
+// LoadPolicyPlugin is not supported on this platform,
+// compiled-in policies should be used instead
+func LoadPolicyPlugin(path string) (IPolicy, error) {
+	return nil, fmt.Errorf("load policy plugin %s: go plugins are not supported on this platform", path)
+}
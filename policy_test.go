@@ -0,0 +1,52 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+type addressPolicy struct {
+	BasePolicy
+	rejected string
+	filtered string
+}
+
+func (p *addressPolicy) Admit(srv service.IService) error {
+	if srv.Address() == p.rejected {
+		return errors.New("rejected address")
+	}
+	return nil
+}
+
+func (p *addressPolicy) Allow(srv service.IService) bool {
+	return srv.Address() != p.filtered
+}
+
+func TestServicesListPolicies(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Policies: []IPolicy{&addressPolicy{
+			rejected: "https://1gateway.fm",
+			filtered: "https://2gateway.fm",
+		}},
+	})
+
+	list.Add(newHealthyService("https://1gateway.fm"))
+	list.Add(newHealthyService("https://2gateway.fm"))
+	list.Add(newHealthyService("https://3gateway.fm"))
+
+	if list.CountAll() != 2 {
+		t.Fatalf("rejected service should not be added")
+	}
+
+	for i := 0; i < 10; i++ {
+		if srv := list.Next(); srv == nil || srv.Address() != "https://3gateway.fm" {
+			t.Fatalf("filtered service should not be selected")
+		}
+	}
+}
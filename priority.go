@@ -0,0 +1,186 @@
+package pool
+
+import (
+	"context"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// DefaultOverprovisioningFactor is overprovisioning factor of
+// priority levels, it's the same as Envoy default of 140
+const DefaultOverprovisioningFactor = 1.4
+
+// PriorityOpts is options that configure priority levels of
+// services with Envoy semantics: requests go to the highest
+// priority level (0) while it is healthy enough and spill
+// over to lower levels as its services become unhealthy
+type PriorityOpts struct {
+	OverprovisioningFactor float64 // multiplier of level healthy share, level with 1/factor of healthy services takes all its load (1.4 by default)
+}
+
+// PriorityLevel is health and share of requests
+// routed to services of one priority level
+type PriorityLevel struct {
+	Priority int     `json:"priority"`
+	Healthy  int     `json:"healthy"`
+	Total    int     `json:"total"`
+	Health   float64 `json:"health"` // healthy share multiplied by overprovisioning factor, capped at 1
+	Load     float64 `json:"load"`   // share of requests routed to the level
+}
+
+// priorities is priority levels
+// configuration of the list
+type priorities struct {
+	overprovisioning float64
+}
+
+// priorityLevelKey is context key of priority
+// level selected for the request
+type priorityLevelKey struct{}
+
+// newPriorities create priority levels configuration,
+// nil is returned if priority levels are disabled
+func newPriorities(opts *PriorityOpts) *priorities {
+	if opts == nil {
+		return nil
+	}
+
+	p := &priorities{overprovisioning: opts.OverprovisioningFactor}
+	if p.overprovisioning <= 0 {
+		p.overprovisioning = DefaultOverprovisioningFactor
+	}
+
+	return p
+}
+
+// PriorityLoad compute health and share of requests of given
+// priority levels like Envoy does: health of level is its
+// healthy share multiplied by overprovisioning factor capped
+// at 1, levels take load in priority order proportionally to
+// theirs health normalized by total health capped at 1. Load
+// of levels without services is 0, all load goes to the
+// highest priority level if no level is healthy
+func PriorityLoad(levels []PriorityLevel, overprovisioning float64) []PriorityLevel {
+	levels = append([]PriorityLevel(nil), levels...)
+	sort.Slice(levels, func(i, j int) bool {
+		return levels[i].Priority < levels[j].Priority
+	})
+
+	var total float64
+	for i := range levels {
+		levels[i].Health, levels[i].Load = 0, 0
+		if levels[i].Total > 0 {
+			levels[i].Health = min(float64(levels[i].Healthy)/float64(levels[i].Total)*overprovisioning, 1)
+		}
+		total += levels[i].Health
+	}
+
+	if total == 0 {
+		if len(levels) > 0 {
+			levels[0].Load = 1
+		}
+		return levels
+	}
+
+	total = min(total, 1)
+
+	remaining := 1.0
+	for i := range levels {
+		levels[i].Load = min(remaining, levels[i].Health/total)
+		remaining -= levels[i].Load
+	}
+
+	return levels
+}
+
+// priorityLevelsLocked return priority levels of the list
+// members with theirs load. Should be called under the list lock
+func (l *ServicesList) priorityLevelsLocked() []PriorityLevel {
+	byPriority := make(map[int]*PriorityLevel)
+	level := func(srv service.IService) *PriorityLevel {
+		priority := service.Priority(srv)
+		if byPriority[priority] == nil {
+			byPriority[priority] = &PriorityLevel{Priority: priority}
+		}
+		return byPriority[priority]
+	}
+
+	for _, srv := range l.healthy {
+		lvl := level(srv)
+		lvl.Total++
+		if srv.Status() == service.StatusHealthy {
+			lvl.Healthy++
+		}
+	}
+	for _, srv := range l.jail {
+		level(srv).Total++
+	}
+	for _, item := range l.review {
+		level(item.Service).Total++
+	}
+
+	levels := make([]PriorityLevel, 0, len(byPriority))
+	for _, lvl := range byPriority {
+		levels = append(levels, *lvl)
+	}
+
+	return PriorityLoad(levels, l.priorities.overprovisioning)
+}
+
+// withPriority return context with priority level picked for the
+// request according to levels load. Should be called under the
+// list lock, given context is returned if levels are disabled
+func (l *ServicesList) withPriority(ctx context.Context) context.Context {
+	if l.priorities == nil {
+		return ctx
+	}
+
+	levels := l.priorityLevelsLocked()
+	if len(levels) < 2 {
+		return ctx
+	}
+
+	pick, picked := rand.Float64(), levels[len(levels)-1].Priority
+	for _, lvl := range levels {
+		if pick < lvl.Load {
+			picked = lvl.Priority
+			break
+		}
+		pick -= lvl.Load
+	}
+
+	return context.WithValue(ctx, priorityLevelKey{}, picked)
+}
+
+// allowedPriority check if given service belongs to priority
+// level picked for the request, if any level is picked
+func allowedPriority(ctx context.Context, srv service.IService) bool {
+	picked, ok := ctx.Value(priorityLevelKey{}).(int)
+	return !ok || service.Priority(srv) == picked
+}
+
+// PriorityLevels return health and load of priority
+// levels, nil is returned if levels are disabled
+func (l *ServicesList) PriorityLevels() []PriorityLevel {
+	if l.priorities == nil {
+		return nil
+	}
+
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	return l.priorityLevelsLocked()
+}
+
+// PriorityLevels return health and load of priority
+// levels of all shards, levels are relative to the shard
+func (l *ShardedServicesList) PriorityLevels() []PriorityLevel {
+	var levels []PriorityLevel
+	for _, shard := range l.shards {
+		levels = append(levels, shard.PriorityLevels()...)
+	}
+
+	return levels
+}
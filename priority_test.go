@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestPriorityLoad(t *testing.T) {
+	cases := []struct {
+		name     string
+		levels   []PriorityLevel
+		expected []float64
+	}{
+		{
+			name:     "healthy highest priority takes all load",
+			levels:   []PriorityLevel{{Priority: 0, Healthy: 4, Total: 4}, {Priority: 1, Healthy: 2, Total: 2}},
+			expected: []float64{1, 0},
+		},
+		{
+			name:     "overprovisioning keeps load of degraded level",
+			levels:   []PriorityLevel{{Priority: 0, Healthy: 3, Total: 4}, {Priority: 1, Healthy: 2, Total: 2}},
+			expected: []float64{1, 0},
+		},
+		{
+			name:     "unhealthy level spills load over",
+			levels:   []PriorityLevel{{Priority: 1, Healthy: 2, Total: 2}, {Priority: 0, Healthy: 1, Total: 4}},
+			expected: []float64{0.35, 0.65},
+		},
+		{
+			name:     "load is normalized by total health",
+			levels:   []PriorityLevel{{Priority: 0, Healthy: 1, Total: 4}, {Priority: 1, Healthy: 1, Total: 4}},
+			expected: []float64{0.5, 0.5},
+		},
+		{
+			name:     "no healthy levels route to highest priority",
+			levels:   []PriorityLevel{{Priority: 0, Total: 2}, {Priority: 1, Total: 2}},
+			expected: []float64{1, 0},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			levels := PriorityLoad(c.levels, DefaultOverprovisioningFactor)
+			for i, level := range levels {
+				if level.Priority != i || math.Abs(level.Load-c.expected[i]) > 1e-9 {
+					t.Errorf("unexpected load of level %d: %+v", i, level)
+				}
+			}
+		})
+	}
+}
+
+func TestServicesListPriorities(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Priorities:     &PriorityOpts{},
+	})
+	defer list.Close()
+
+	primary := []service.IService{newHealthyService("https://1gateway.fm"), newHealthyService("https://2gateway.fm")}
+	backup := newMetadataService("https://3gateway.fm", map[string]string{service.PriorityMetadataKey: "1"})
+
+	for _, srv := range append(primary, backup) {
+		list.Add(srv)
+	}
+
+	for i := 0; i < 20; i++ {
+		if list.Next().ID() == backup.ID() {
+			t.Fatalf("lower priority service should not be selected while highest priority is healthy")
+		}
+	}
+
+	for _, srv := range primary {
+		list.FromHealthyToJail(srv.ID())
+	}
+
+	if srv := list.Next(); srv == nil || srv.ID() != backup.ID() {
+		t.Errorf("load should spill over to lower priority level, got %v", srv)
+	}
+
+	levels := list.PriorityLevels()
+	if len(levels) != 2 || levels[0].Total != 2 || levels[0].Healthy != 0 || levels[1].Load != 1 {
+		t.Errorf("unexpected priority levels %+v", levels)
+	}
+}
@@ -3,8 +3,14 @@ package client
 import (
 	"net/http"
 	"time"
+
+	"go.opentelemetry.io/otel/propagation"
 )
 
+// defaultHTTPClientTimeout is timeout of requests
+// to prover, proving requests could take minutes
+const defaultHTTPClientTimeout = 600 * time.Second
+
 // HTTPClient is basic http client implementation
 // of INodeClient
 type HTTPClient struct {
@@ -12,13 +18,43 @@ type HTTPClient struct {
 	addr   string
 }
 
+// HTTPClientOpts is options that needs
+// to configure HTTPClient instance
+type HTTPClientOpts struct {
+	Propagator propagation.TextMapPropagator // trace context propagator (W3C trace context and baggage by default)
+	Baggage    map[string]string             // baggage members added to every request
+	Timeout    time.Duration                 // timeout of every request including reading of response body (600s by default)
+}
+
 // NewHttpClient create new HTTPClient with given address
 func NewHttpClient(addr string) (INodeClient, error) {
 	return &HTTPClient{
 		client: &http.Client{
 			Transport: newRoundTripper(http.DefaultTransport),
-			// TODO set timeout via config
-			Timeout: time.Second * 600,
+			Timeout:   defaultHTTPClientTimeout,
+		},
+		addr: addr,
+	}, nil
+}
+
+// NewHttpClientWithOpts create new HTTPClient with given address
+// and request timeout that propagates trace context and baggage
+// from requests context
+func NewHttpClientWithOpts(addr string, opts *HTTPClientOpts) (INodeClient, error) {
+	transport, err := newPropagatingRoundTripper(http.DefaultTransport, opts.Propagator, opts.Baggage)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPClientTimeout
+	}
+
+	return &HTTPClient{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
 		},
 		addr: addr,
 	}, nil
@@ -29,6 +65,8 @@ func (c *HTTPClient) Close() {
 	c.client.CloseIdleConnections()
 }
 
-func (c *HTTPClient) DoRequest(*http.Request) (*http.Response, error) {
-	return nil, nil
+// DoRequest send given request to prover,
+// trace context is propagated from request context
+func (c *HTTPClient) DoRequest(r *http.Request) (*http.Response, error) {
+	return c.client.Do(r)
 }
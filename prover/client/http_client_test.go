@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestHTTPClientPropagation(t *testing.T) {
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	c, err := NewHttpClientWithOpts(server.URL, &HTTPClientOpts{
+		Baggage: map[string]string{"gateway": "eu-1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected client error: %s", err)
+	}
+	defer c.Close()
+
+	traceID := trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("unexpected request error: %s", err)
+	}
+
+	resp, err := c.DoRequest(req)
+	if err != nil {
+		t.Fatalf("unexpected do request error: %s", err)
+	}
+	resp.Body.Close()
+
+	if !strings.Contains(headers.Get("traceparent"), traceID.String()) {
+		t.Errorf("trace context is not propagated: %q", headers.Get("traceparent"))
+	}
+
+	if headers.Get("baggage") != "gateway=eu-1" {
+		t.Errorf("baggage is not propagated: %q", headers.Get("baggage"))
+	}
+}
+
+func TestHTTPClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	c, err := NewHttpClientWithOpts(server.URL, &HTTPClientOpts{Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected client error: %s", err)
+	}
+	defer c.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatalf("unexpected request error: %s", err)
+	}
+
+	if _, err := c.DoRequest(req); err == nil {
+		t.Errorf("request should be interrupted by configured timeout")
+	}
+}
@@ -1,7 +1,12 @@
 package client
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // INodeClient is low-level abstraction that
@@ -17,10 +22,23 @@ type INodeClient interface {
 // transport implementation
 type roundTripper struct {
 	r http.RoundTripper
+
+	propagation *Propagation
 }
 
 func newRoundTripper(r http.RoundTripper) *roundTripper {
-	return &roundTripper{r}
+	return &roundTripper{r: r}
+}
+
+// newPropagatingRoundTripper create roundTripper that inject
+// trace context and given baggage members to all requests
+func newPropagatingRoundTripper(r http.RoundTripper, propagator propagation.TextMapPropagator, members map[string]string) (*roundTripper, error) {
+	p, err := NewPropagation(propagator, members)
+	if err != nil {
+		return nil, err
+	}
+
+	return &roundTripper{r: r, propagation: p}, nil
 }
 
 // RoundTrip set default headers to
@@ -29,5 +47,61 @@ func (mrt roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
 	r.Header.Add("accept", "application/json")
 	r.Header.Add("content-type", "application/json")
 
+	if mrt.propagation != nil {
+		mrt.propagation.Inject(r.Context(), propagation.HeaderCarrier(r.Header))
+	}
+
 	return mrt.r.RoundTrip(r)
 }
+
+// Propagation inject trace context and baggage of
+// given context into headers of outgoing requests,
+// e.g. healthcheck probes, with configured baggage
+// members merged into the baggage
+type Propagation struct {
+	propagator propagation.TextMapPropagator
+	baggage    []baggage.Member
+}
+
+// NewPropagation create Propagation with given propagator (W3C
+// trace context and baggage if nil) and given baggage members
+func NewPropagation(propagator propagation.TextMapPropagator, members map[string]string) (*Propagation, error) {
+	if propagator == nil {
+		propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+
+	p := &Propagation{propagator: propagator}
+
+	for key, value := range members {
+		member, err := baggage.NewMember(key, value)
+		if err != nil {
+			return nil, fmt.Errorf("create baggage member %q: %w", key, err)
+		}
+		p.baggage = append(p.baggage, member)
+	}
+
+	return p, nil
+}
+
+// Inject inject trace context and baggage
+// of given context into given carrier
+func (p *Propagation) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	p.propagator.Inject(p.withBaggage(ctx), carrier)
+}
+
+// withBaggage return given context with configured
+// baggage members merged into its baggage
+func (p *Propagation) withBaggage(ctx context.Context) context.Context {
+	if len(p.baggage) == 0 {
+		return ctx
+	}
+
+	bag := baggage.FromContext(ctx)
+	for _, member := range p.baggage {
+		// members are validated on creation, so
+		// error is not possible here
+		bag, _ = bag.SetMember(member)
+	}
+
+	return baggage.ContextWithBaggage(ctx, bag)
+}
@@ -1,8 +1,11 @@
 package prover
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"math"
 	"sync"
 	"sync/atomic"
 
@@ -22,6 +25,10 @@ type IProver interface {
 
 	MessageId() string
 
+	// Client return prover node client, requests sent
+	// through it propagate trace context and baggage
+	Client() client.INodeClient
+
 	service.IService
 }
 
@@ -30,18 +37,26 @@ type Prover struct {
 	status    int32
 	id        string
 	messageId string
-	addr      string
+	addr      string   // currently active address
+	addrs     []string // primary and fallback addresses
 	name      string
 
-	healthcheck func(n IProver) error
+	healthcheck        func(n IProver) error
+	healthcheckContext func(ctx context.Context, n IProver) error
 
-	client client.INodeClient
+	client     client.INodeClient
+	clientOpts *client.HTTPClientOpts
 
 	mu sync.Mutex
 
+	endpointMu sync.RWMutex // guards active address and client during failover
+
 	tags map[string]struct{}
 
-	load float32 // rating between [0.0, 1.0]
+	load uint32 // bits of rating between [0.0, 1.0], accessed atomically
+
+	metadataMu sync.RWMutex
+	live       map[string]string // metadata reported by the prover, e.g. by healthcheck probe
 }
 
 type ProverOpts struct {
@@ -50,17 +65,32 @@ type ProverOpts struct {
 	MessageId   string
 	Healthcheck func(n IProver) error
 	Tags        map[string]struct{}
+	ClientOpts  *client.HTTPClientOpts // node client configuration (trace context and baggage propagation, request timeout)
+
+	// HealthcheckContext is healthcheck interrupted once given
+	// context is done, so hung connection to the prover is closed
+	// by the check timeout of the list. It is used by lists instead
+	// of Healthcheck (nil to abandon Healthcheck on the timeout)
+	HealthcheckContext func(ctx context.Context, n IProver) error
+
+	// FallbackAddrs is redundant ingress addresses of the prover
+	// which are tried in order when healthcheck on the active
+	// address fails, before prover is considered unhealthy
+	FallbackAddrs []string
 }
 
 func NewProver(opts *ProverOpts) (*Prover, error) {
 	p := &Prover{
-		name:        opts.Name,
-		addr:        opts.Addr,
-		healthcheck: opts.Healthcheck,
-		tags:        opts.Tags,
-		status:      int32(service.StatusUnHealthy),
-		id:          service.GenerateServiceID(opts.Addr),
-		messageId:   opts.MessageId,
+		name:               opts.Name,
+		addr:               opts.Addr,
+		addrs:              append([]string{opts.Addr}, opts.FallbackAddrs...),
+		healthcheck:        opts.Healthcheck,
+		healthcheckContext: opts.HealthcheckContext,
+		tags:               opts.Tags,
+		status:             int32(service.StatusUnHealthy),
+		id:                 service.GenerateServiceID(opts.Addr),
+		messageId:          opts.MessageId,
+		clientOpts:         opts.ClientOpts,
 	}
 	if err := p.initNodeClient(); err != nil {
 		return nil, err
@@ -79,7 +109,11 @@ func (p *Prover) initNodeClient() (err error) {
 		p.client.Close()
 	}
 
-	p.client, err = client.NewHttpClient(p.addr)
+	if p.clientOpts != nil {
+		p.client, err = client.NewHttpClientWithOpts(p.addr, p.clientOpts)
+	} else {
+		p.client, err = client.NewHttpClient(p.addr)
+	}
 	if err != nil {
 		return fmt.Errorf("create new prover client: %w", err)
 	}
@@ -99,15 +133,116 @@ func (p *Prover) HealthCheck() error {
 		return errors.New("nil healthcheck function")
 	}
 
-	return p.healthcheck(p)
+	check := func() error {
+		return p.healthcheck(p)
+	}
+
+	err := check()
+	if err == nil || len(p.addrs) < 2 {
+		return err
+	}
+
+	return p.failover(err, check)
+}
+
+// HealthCheckContext check prover health like HealthCheck until
+// given context is done. Healthcheck without context support is
+// abandoned once the context is done and its result is dropped
+func (p *Prover) HealthCheckContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if p.healthcheckContext == nil {
+		done := make(chan error, 1)
+		go func() {
+			done <- p.HealthCheck()
+		}()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-done:
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	check := func() error {
+		return p.healthcheckContext(ctx, p)
+	}
+
+	err := check()
+	if err == nil || len(p.addrs) < 2 || ctx.Err() != nil {
+		return err
+	}
+
+	return p.failover(err, check)
+}
+
+// failover try prover addresses after the active one with given
+// check and keep the first healthy of them as active address.
+// Active address is kept if all addresses are unhealthy
+func (p *Prover) failover(err error, check func() error) error {
+	active := p.Address()
+
+	start := 0
+	for i, addr := range p.addrs {
+		if addr == active {
+			start = i
+			break
+		}
+	}
+
+	errs := []error{fmt.Errorf("address %s: %w", active, err)}
+
+	for i := 1; i < len(p.addrs); i++ {
+		addr := p.addrs[(start+i)%len(p.addrs)]
+
+		if err := p.setAddress(addr); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := check(); err != nil {
+			errs = append(errs, fmt.Errorf("address %s: %w", addr, err))
+			continue
+		}
+
+		return nil
+	}
+
+	if err := p.setAddress(active); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// setAddress set given address as
+// active and recreate node client
+func (p *Prover) setAddress(addr string) error {
+	defer p.endpointMu.Unlock()
+	p.endpointMu.Lock()
+
+	p.addr = addr
+	return p.initNodeClient()
+}
+
+// Addresses return primary and
+// fallback addresses of the prover
+func (p *Prover) Addresses() []string {
+	return p.addrs
 }
 
 func (p *Prover) Load() float32 {
-	return p.load
+	return math.Float32frombits(atomic.LoadUint32(&p.load))
 }
 
 func (p *Prover) SetLoad(load float32) {
-	p.load = load
+	atomic.StoreUint32(&p.load, math.Float32bits(load))
 }
 
 // Status return Prover current status
@@ -125,12 +260,23 @@ func (p *Prover) ID() string {
 	return p.id
 }
 
+// Client return prover node client
+func (p *Prover) Client() client.INodeClient {
+	defer p.endpointMu.RUnlock()
+	p.endpointMu.RLock()
+
+	return p.client
+}
+
 func (p *Prover) MessageId() string {
 	return p.messageId
 }
 
-// Address return Prover address
+// Address return Prover currently active address
 func (p *Prover) Address() string {
+	defer p.endpointMu.RUnlock()
+	p.endpointMu.RLock()
+
 	return p.addr
 }
 
@@ -140,10 +286,34 @@ func (p *Prover) Tags() map[string]struct{} {
 
 // Close all prover connections
 func (p *Prover) Close() error {
-	p.client.Close()
+	p.Client().Close()
 	return nil
 }
 
 func (p *Prover) NodeName() string {
 	return p.name
 }
+
+// Metadata return metadata reported by the
+// prover, returned map should not be modified
+func (p *Prover) Metadata() map[string]string {
+	defer p.metadataMu.RUnlock()
+	p.metadataMu.RLock()
+
+	return p.live
+}
+
+// StaticMetadata return nil, prover
+// has no metadata from discovery
+func (p *Prover) StaticMetadata() map[string]string {
+	return nil
+}
+
+// SetLiveMetadata replace metadata reported
+// by the prover with copy of given one
+func (p *Prover) SetLiveMetadata(metadata map[string]string) {
+	defer p.metadataMu.Unlock()
+	p.metadataMu.Lock()
+
+	p.live = maps.Clone(metadata)
+}
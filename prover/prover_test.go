@@ -0,0 +1,44 @@
+package prover
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestProverAddressFailover(t *testing.T) {
+	healthy := "https://fallback.gateway.fm"
+
+	p, err := NewProver(&ProverOpts{
+		Addr:          "https://primary.gateway.fm",
+		FallbackAddrs: []string{"https://broken.gateway.fm", healthy},
+		Healthcheck: func(p IProver) error {
+			if p.Address() != healthy {
+				return fmt.Errorf("address %s is unreachable", p.Address())
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected prover error: %s", err)
+	}
+
+	id := p.ID()
+
+	if err := p.HealthCheck(); err != nil {
+		t.Fatalf("healthcheck should fail over to healthy address, got %s", err)
+	}
+
+	if p.Address() != healthy || p.ID() != id {
+		t.Errorf("healthy fallback should be active keeping prover id, got %s", p.Address())
+	}
+
+	healthy = "none"
+
+	if err := p.HealthCheck(); err == nil {
+		t.Fatalf("healthcheck should fail if all addresses are unhealthy")
+	}
+
+	if p.Address() != "https://fallback.gateway.fm" {
+		t.Errorf("active address should be kept if all addresses are unhealthy, got %s", p.Address())
+	}
+}
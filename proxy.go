@@ -0,0 +1,248 @@
+package pool
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	defaultProxyScheme     = "http"
+	defaultProxyPoolHeader = "X-Pool"
+)
+
+// grpc status codes written by the proxy
+const (
+	grpcStatusUnavailable = 14
+	grpcStatusNotFound    = 5
+)
+
+// ProxyOpts is options that configure reverse proxy
+// of http and grpc requests to pool services
+type ProxyOpts struct {
+	Scheme        string            // scheme of services addresses given without one ("http" by default)
+	Transport     http.RoundTripper // transport of http requests (http.DefaultTransport by default)
+	GRPCTransport http.RoundTripper // transport of grpc requests (cleartext http/2 by default)
+	PoolHeader    string            // request header naming registry pool ("X-Pool" by default)
+	DefaultPool   string            // registry pool of requests without pool header (empty to reject them)
+	DialTimeout   time.Duration     // dial timeout of default transports, request timeouts are taken from lists (5s by default)
+}
+
+// ProxyHandler is http handler that proxy http and grpc requests
+// to healthy services selected from services list. Requests are
+// counted as in-flight connections and theirs results are
+// reported to the list, so routing follows services health
+type ProxyHandler struct {
+	opts ProxyOpts
+
+	http *httputil.ReverseProxy
+	grpc *httputil.ReverseProxy
+}
+
+// proxyRequest is selected target and
+// outcome of one proxied request
+type proxyRequest struct {
+	target *url.URL
+	err    error
+}
+
+// proxyRequestKey is context key of proxyRequest
+type proxyRequestKey struct{}
+
+// NewProxyHandler create new ProxyHandler with given configuration
+func NewProxyHandler(opts *ProxyOpts) *ProxyHandler {
+	h := &ProxyHandler{opts: *opts}
+
+	if h.opts.Scheme == "" {
+		h.opts.Scheme = defaultProxyScheme
+	}
+	if h.opts.DialTimeout <= 0 {
+		h.opts.DialTimeout = defaultDialTimeout
+	}
+
+	dialer := &net.Dialer{Timeout: h.opts.DialTimeout}
+	if h.opts.Transport == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DialContext = dialer.DialContext
+		h.opts.Transport = transport
+	}
+	if h.opts.GRPCTransport == nil {
+		h.opts.GRPCTransport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		}
+	}
+	if h.opts.PoolHeader == "" {
+		h.opts.PoolHeader = defaultProxyPoolHeader
+	}
+
+	h.http = h.reverseProxy(h.opts.Transport, false)
+	h.grpc = h.reverseProxy(h.opts.GRPCTransport, true)
+
+	return h
+}
+
+// reverseProxy create reverse proxy to targets
+// selected by the handler using given transport
+func (h *ProxyHandler) reverseProxy(transport http.RoundTripper, grpc bool) *httputil.ReverseProxy {
+	proxy := &httputil.ReverseProxy{
+		Transport: transport,
+		Rewrite: func(r *httputil.ProxyRequest) {
+			req := r.In.Context().Value(proxyRequestKey{}).(*proxyRequest)
+
+			r.SetURL(req.target)
+			r.SetXForwarded()
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			req := resp.Request.Context().Value(proxyRequestKey{}).(*proxyRequest)
+			req.err = responseError(resp, grpc)
+
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			req := r.Context().Value(proxyRequestKey{}).(*proxyRequest)
+			req.err = err
+
+			log().Warn("proxy request error", "target", req.target, "error", err)
+			writeProxyError(w, grpc, http.StatusBadGateway, grpcStatusUnavailable, err)
+		},
+	}
+
+	// streamed grpc messages are not buffered
+	if grpc {
+		proxy.FlushInterval = -1
+	}
+
+	return proxy
+}
+
+// Pool return http handler that proxy requests
+// to services of given list with given name
+func (h *ProxyHandler) Pool(name string, list IServicesList) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.serve(w, r, name, list)
+	})
+}
+
+// Registry return http handler that proxy requests to services of
+// registry pool named by the pool header or to the default pool
+func (h *ProxyHandler) Registry(registry *PoolRegistry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.Header.Get(h.opts.PoolHeader)
+		if name == "" {
+			name = h.opts.DefaultPool
+		}
+
+		pool, ok := registry.Get(name)
+		if !ok {
+			writeProxyError(w, isGRPC(r), http.StatusNotFound, grpcStatusNotFound, ErrPoolNotFound{Name: name})
+			return
+		}
+
+		r.Header.Del(h.opts.PoolHeader)
+		h.serve(w, r, name, pool.List())
+	})
+}
+
+// serve proxy given request to service acquired from given list
+// within request timeout of the list and report duration and
+// outcome of the request to the list
+func (h *ProxyHandler) serve(w http.ResponseWriter, r *http.Request, name string, list IServicesList) {
+	grpc := isGRPC(r)
+
+	srv, release, err := list.TryAcquire(r.Context())
+	if err != nil {
+		writeProxyError(w, grpc, http.StatusServiceUnavailable, grpcStatusUnavailable, err)
+		return
+	}
+	defer release()
+
+	target, err := serviceURL(srv.Address(), h.opts.Scheme)
+	if err != nil {
+		writeProxyError(w, grpc, http.StatusBadGateway, grpcStatusUnavailable, err)
+		return
+	}
+
+	req := &proxyRequest{target: target}
+	ctx, cancel := context.WithTimeout(r.Context(), list.Timeouts().Request)
+	defer cancel()
+	ctx = context.WithValue(ctx, proxyRequestKey{}, req)
+
+	proxy := h.http
+	if grpc {
+		proxy = h.grpc
+	}
+
+	start := time.Now()
+	proxy.ServeHTTP(w, r.WithContext(ctx))
+
+	list.ObserveResult(r.Context(), srv, time.Since(start), req.err)
+}
+
+// serviceURL return url of service with given address, given
+// scheme is used for addresses without one. Grpc schemes are
+// replaced with http ones grpc is transported with
+func serviceURL(address, scheme string) (*url.URL, error) {
+	if !strings.Contains(address, "://") {
+		address = scheme + "://" + address
+	}
+
+	target, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("parse service address %q: %w", address, err)
+	}
+
+	switch target.Scheme {
+	case "grpc":
+		target.Scheme = "http"
+	case "grpcs":
+		target.Scheme = "https"
+	}
+
+	return target, nil
+}
+
+// responseError return error of service response, server
+// errors and grpc unavailability are counted as failures
+func responseError(resp *http.Response, grpc bool) error {
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return ErrUpstreamStatus{Address: resp.Request.URL.Host, StatusCode: resp.StatusCode}
+	}
+
+	// trailers-only grpc responses carry status in headers
+	if grpc && resp.Header.Get("Grpc-Status") == strconv.Itoa(grpcStatusUnavailable) {
+		return ErrUpstreamStatus{Address: resp.Request.URL.Host, StatusCode: resp.StatusCode, GRPCStatus: grpcStatusUnavailable}
+	}
+
+	return nil
+}
+
+// isGRPC check if given request is grpc call
+func isGRPC(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// writeProxyError write given error as http status
+// or as trailers-only grpc response with grpc status
+func writeProxyError(w http.ResponseWriter, grpc bool, status, grpcStatus int, err error) {
+	if !grpc {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(grpcStatus))
+	w.Header().Set("Grpc-Message", url.PathEscape(err.Error()))
+	w.WriteHeader(http.StatusOK)
+}
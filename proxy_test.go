@@ -0,0 +1,161 @@
+package pool
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// newProxyList create services list of given addresses
+// which services are considered healthy without checks
+func newProxyList(t *testing.T, addresses ...string) IServicesList {
+	t.Helper()
+
+	list := NewServicesList("testProxyList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Stats:          stats.NewRegistry(stats.Opts{}),
+		HealthChecker: HealthCheckerFunc(func(context.Context, service.IService) error {
+			return nil
+		}),
+	})
+	t.Cleanup(list.Close)
+
+	for _, address := range addresses {
+		list.Add(service.NewService(address, "", nil, 0))
+	}
+
+	return list
+}
+
+func TestProxyHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = io.WriteString(w, "proved "+r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	list := newProxyList(t, upstream.URL)
+
+	proxy := NewProxyHandler(&ProxyOpts{})
+
+	server := httptest.NewServer(proxy.Pool("provers", list))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/batch/1")
+	if err != nil {
+		t.Fatalf("unexpected request error: %s", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "proved /batch/1" {
+		t.Errorf("unexpected response %d %q", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(server.URL + "/fail")
+	if err != nil {
+		t.Fatalf("unexpected request error: %s", err)
+	}
+	resp.Body.Close()
+
+	if snapshot := list.Stats().Get(list.Healthy()[0].ID()).Snapshot(); snapshot.Requests != 2 || snapshot.Failures != 1 {
+		t.Errorf("proxied results should be observed, got %+v", snapshot)
+	}
+	if inFlight := list.InFlight(list.Healthy()[0].ID()); inFlight != 0 {
+		t.Errorf("connections should be released, got %d in flight", inFlight)
+	}
+
+	// pool without healthy services is unavailable
+	empty := httptest.NewServer(proxy.Pool("empty", newProxyList(t)))
+	defer empty.Close()
+
+	resp, err = http.Get(empty.URL)
+	if err != nil {
+		t.Fatalf("unexpected request error: %s", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected unavailable status, got %d", resp.StatusCode)
+	}
+}
+
+func TestProxyHandlerRegistryGRPC(t *testing.T) {
+	// grpc server answering every call with trailers
+	upstream := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = w.Write([]byte{0, 0, 0, 0, 0})
+		w.Header().Set("Grpc-Status", "0")
+	}), &http2.Server{}))
+	defer upstream.Close()
+
+	registry := NewPoolRegistry(&PoolRegistryOpts{})
+	defer registry.Close()
+
+	if _, err := registry.Create(&ServicesPoolsOpts{
+		Name: "provers",
+		ListOpts: &ServicesListOpts{
+			TryUpInterval:  time.Hour,
+			ChecksInterval: time.Hour,
+			HealthChecker: HealthCheckerFunc(func(context.Context, service.IService) error {
+				return nil
+			}),
+		},
+	}, false); err != nil {
+		t.Fatalf("unexpected pool error: %s", err)
+	}
+	provers, _ := registry.Get("provers")
+	provers.List().Add(service.NewService(upstream.URL, "", nil, 0))
+
+	proxy := NewProxyHandler(&ProxyOpts{})
+
+	server := httptest.NewServer(h2c.NewHandler(proxy.Registry(registry), &http2.Server{}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}}
+
+	call := func(pool string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/prover.Prover/Prove", http.NoBody)
+		req.Header.Set("Content-Type", "application/grpc")
+		req.Header.Set("X-Pool", pool)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected call error: %s", err)
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	if resp := call("provers"); resp.Trailer.Get("Grpc-Status") != "0" {
+		t.Errorf("grpc trailers should be proxied, got %v", resp.Trailer)
+	}
+	if resp := call("missing"); resp.Header.Get("Grpc-Status") != "5" {
+		t.Errorf("unknown pool should be not found, got %v", resp.Header)
+	}
+}
@@ -0,0 +1,196 @@
+package pool
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// ZoneMetadataKey is metadata key of service zone matched by ByZone
+const ZoneMetadataKey = "zone"
+
+// Matcher is predicate over service snapshot used by Find,
+// matchers are composed with AllOf, AnyOf and Not
+type Matcher func(srv ServiceSnapshot) bool
+
+// ByID match service with given id
+func ByID(id string) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		return srv.ID == id
+	}
+}
+
+// ByTag match services with given tag
+func ByTag(tag string) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		for _, t := range srv.Tags {
+			if t == tag {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ByMembership match services with any of given memberships,
+// e.g. ByMembership(MembershipJailed, MembershipReview)
+func ByMembership(memberships ...string) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		for _, membership := range memberships {
+			if srv.Membership == membership {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// ByStatus match services with given health status
+func ByStatus(status service.Status) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		return srv.Status == status.String()
+	}
+}
+
+// ByMetadata match services with given metadata value
+func ByMetadata(key, value string) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		v, ok := srv.Metadata[key]
+		return ok && v == value
+	}
+}
+
+// ByZone match services with given zone metadata value
+func ByZone(zone string) Matcher {
+	return ByMetadata(ZoneMetadataKey, zone)
+}
+
+// ByMaintenance match services with given action of active
+// maintenance window, empty action match services that
+// are not under maintenance
+func ByMaintenance(action MaintenanceAction) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		return srv.Maintenance == action
+	}
+}
+
+// AllOf match services matched by all given matchers
+func AllOf(matchers ...Matcher) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		for _, match := range matchers {
+			if !match(srv) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// AnyOf match services matched by any of given matchers
+func AnyOf(matchers ...Matcher) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		for _, match := range matchers {
+			if match(srv) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not match services not matched by given matcher
+func Not(matcher Matcher) Matcher {
+	return func(srv ServiceSnapshot) bool {
+		return !matcher(srv)
+	}
+}
+
+// Find return snapshots of services of the state
+// matched by all given matchers ordered by id
+func (s *PoolState) Find(matchers ...Matcher) []ServiceSnapshot {
+	match := AllOf(matchers...)
+
+	found := make([]ServiceSnapshot, 0, len(s.Services))
+	for _, srv := range s.Services {
+		if match(srv) {
+			found = append(found, srv)
+		}
+	}
+
+	return found
+}
+
+// Find return snapshots of healthy, jailed and quarantined
+// services matched by all given matchers ordered by id
+func (l *ServicesList) Find(matchers ...Matcher) []ServiceSnapshot {
+	return l.Snapshot().Find(matchers...)
+}
+
+// Find return snapshots of services of all shards
+// matched by all given matchers ordered by id
+func (l *ShardedServicesList) Find(matchers ...Matcher) []ServiceSnapshot {
+	return l.Snapshot().Find(matchers...)
+}
+
+// ParseQuery create matchers of given query parameters, e.g.
+// "tag=gpu&membership=jailed&zone=eu-1&metadata=arch=arm64".
+// Values of the same parameter are alternatives, different
+// parameters must all match
+func ParseQuery(query url.Values) ([]Matcher, error) {
+	var matchers []Matcher
+
+	for param, values := range query {
+		alternatives := make([]Matcher, 0, len(values))
+
+		for _, value := range values {
+			matcher, err := parseMatcher(param, value)
+			if err != nil {
+				return nil, err
+			}
+
+			alternatives = append(alternatives, matcher)
+		}
+
+		matchers = append(matchers, AnyOf(alternatives...))
+	}
+
+	return matchers, nil
+}
+
+// parseMatcher create matcher of given query parameter value
+func parseMatcher(param, value string) (Matcher, error) {
+	switch param {
+	case "id":
+		return ByID(value), nil
+	case "tag":
+		return ByTag(value), nil
+	case "membership":
+		switch value {
+		case MembershipHealthy, MembershipJailed, MembershipReview:
+			return ByMembership(value), nil
+		}
+	case "status":
+		status, err := service.ServiceStatusFromString(value)
+		if err == nil {
+			return ByStatus(status), nil
+		}
+	case "zone":
+		return ByZone(value), nil
+	case "metadata":
+		if key, v, ok := strings.Cut(value, "="); ok && key != "" {
+			return ByMetadata(key, v), nil
+		}
+	case "maintenance":
+		return ByMaintenance(MaintenanceAction(value)), nil
+	default:
+		return nil, ErrInvalidQuery{Param: param, Reason: "unsupported parameter"}
+	}
+
+	return nil, ErrInvalidQuery{Param: param, Reason: fmt.Sprintf("unsupported value %q", value)}
+}
@@ -0,0 +1,88 @@
+package pool
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// newTaggedService create healthy service with given tag and zone
+func newTaggedService(addr, tag, zone string) *service.BaseService {
+	srv := service.NewService(addr, "", map[string]struct{}{tag: {}}, 0).(*service.BaseService)
+	srv.SetStatus(service.StatusHealthy)
+	srv.SetMetadata(map[string]string{ZoneMetadataKey: zone})
+
+	return srv
+}
+
+func TestServicesListFind(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	gpu := newTaggedService("https://1gateway.fm", "gpu", "eu-1")
+	cpu := newTaggedService("https://2gateway.fm", "cpu", "eu-1")
+	jailed := newTaggedService("https://3gateway.fm", "gpu", "us-1")
+	list.Add(gpu)
+	list.Add(cpu)
+	list.Add(jailed)
+	list.FromHealthyToJail(jailed.ID())
+
+	tests := []struct {
+		name     string
+		matchers []Matcher
+		expected []string
+	}{
+		{"all", nil, []string{gpu.ID(), cpu.ID(), jailed.ID()}},
+		{"tag", []Matcher{ByTag("gpu")}, []string{gpu.ID(), jailed.ID()}},
+		{"jailed gpu", []Matcher{ByTag("gpu"), ByMembership(MembershipJailed)}, []string{jailed.ID()}},
+		{"zone", []Matcher{ByZone("eu-1"), Not(ByTag("cpu"))}, []string{gpu.ID()}},
+		{"any", []Matcher{AnyOf(ByTag("cpu"), ByZone("us-1"))}, []string{cpu.ID(), jailed.ID()}},
+		{"status", []Matcher{ByStatus(service.StatusUnHealthy)}, []string{jailed.ID()}},
+	}
+
+	for _, tt := range tests {
+		found := list.Find(tt.matchers...)
+
+		ids := make(map[string]struct{}, len(found))
+		for _, srv := range found {
+			ids[srv.ID] = struct{}{}
+		}
+
+		if len(ids) != len(tt.expected) {
+			t.Errorf("%s: expected %d services, got %d", tt.name, len(tt.expected), len(ids))
+			continue
+		}
+		for _, id := range tt.expected {
+			if _, ok := ids[id]; !ok {
+				t.Errorf("%s: service %s should be found", tt.name, id)
+			}
+		}
+	}
+
+	handler := NewAdminHandler(list)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/services?tag=gpu&membership=jailed&membership=review", nil))
+
+	var found []ServiceSnapshot
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
+		t.Fatalf("decode services: %s", err)
+	}
+	if len(found) != 1 || found[0].ID != jailed.ID() {
+		t.Errorf("unexpected services %+v", found)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/services?membership=lost", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid query should be rejected, got status %d", rec.Code)
+	}
+}
@@ -0,0 +1,249 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultQueueJailCheckInterval = time.Second
+	defaultQueueRetryInterval     = time.Second
+	defaultQueueRequeueDelay      = time.Second
+	defaultQueueLeaseWait         = 5 * time.Second
+)
+
+// Reasons of revoked leases
+const (
+	LeaseRevokedJailed   = "jailed"
+	LeaseRevokedDraining = "draining"
+	LeaseRevokedExpired  = "expired"
+)
+
+// IQueueMessage is proof-job message consumed from message queue
+type IQueueMessage interface {
+	// Key return job key, it's holder of the lease
+	// of the prover the job is dispatched to
+	Key() string
+
+	// Data return message payload
+	Data() []byte
+
+	// Ack acknowledge the message, so
+	// it's removed from the queue
+	Ack() error
+
+	// Requeue return the message to the queue to be
+	// redelivered after given delay, e.g. NATS Nak
+	// with delay or AMQP Nack with requeue
+	Requeue(delay time.Duration) error
+}
+
+// IQueue is source of proof-job messages consumed by QueueWorker.
+// Broker adapters are not provided by the package, consumers of
+// NATS JetStream, Kafka consumer groups or AMQP channels should
+// be wrapped by the caller to implement it
+type IQueue interface {
+	// Receive block until the next message is
+	// consumed or given context is done
+	Receive(ctx context.Context) (IQueueMessage, error)
+}
+
+// QueueWorkerOpts is options that configure
+// dispatch of queue messages to pool services
+type QueueWorkerOpts struct {
+	Queue             IQueue                                                                             // queue messages are consumed from
+	List              IServicesList                                                                      // list leased services are selected from
+	Dispatch          func(ctx context.Context, srv service.IService, msg IQueueMessage) ([]byte, error) // dispatch job to given service and return its result, context is canceled when the lease is revoked
+	Verify            func(ctx context.Context, msg IQueueMessage, result []byte) error                  // verify job result before ack, failed verification is reported to the list and the message is requeued (nil to ack every result)
+	Concurrency       int                                                                                // number of messages handled concurrently (1 by default)
+	RequeueDelay      time.Duration                                                                      // redelivery delay of requeued messages (1s by default)
+	LeaseWait         time.Duration                                                                      // max wait for healthy service if none could be leased before the message is requeued (5s by default)
+	JailCheckInterval time.Duration                                                                      // interval of checks if leased service is still healthy (1s by default)
+	RetryInterval     time.Duration                                                                      // interval between receive attempts after queue error (1s by default)
+}
+
+// QueueWorker consume proof-job messages from queue and dispatch
+// each to leased service of the list. Message is acked once its
+// result is verified and requeued if no service is available,
+// dispatch or verification fails or the leased service is jailed
+// or drained during dispatch
+type QueueWorker struct {
+	opts QueueWorkerOpts
+}
+
+// NewQueueWorker create new QueueWorker with given configuration
+func NewQueueWorker(opts *QueueWorkerOpts) (*QueueWorker, error) {
+	w := &QueueWorker{opts: *opts}
+
+	if w.opts.Queue == nil || w.opts.List == nil || w.opts.Dispatch == nil {
+		return nil, errors.New("queue, list and dispatch of queue worker should be configured")
+	}
+	if w.opts.Concurrency <= 0 {
+		w.opts.Concurrency = 1
+	}
+	if w.opts.JailCheckInterval <= 0 {
+		w.opts.JailCheckInterval = defaultQueueJailCheckInterval
+	}
+	if w.opts.RetryInterval <= 0 {
+		w.opts.RetryInterval = defaultQueueRetryInterval
+	}
+	if w.opts.RequeueDelay <= 0 {
+		w.opts.RequeueDelay = defaultQueueRequeueDelay
+	}
+	if w.opts.LeaseWait <= 0 {
+		w.opts.LeaseWait = defaultQueueLeaseWait
+	}
+
+	return w, nil
+}
+
+// Run consume and dispatch messages until given context
+// is done and wait for handled messages to be finished,
+// messages in flight are requeued on cancellation
+func (w *QueueWorker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.consume(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// consume handle received messages one
+// by one until given context is done
+func (w *QueueWorker) consume(ctx context.Context) {
+	for {
+		msg, err := w.opts.Queue.Receive(ctx)
+		if ctx.Err() != nil {
+			if msg != nil {
+				w.requeue(msg, ctx.Err())
+			}
+			return
+		}
+
+		if err != nil {
+			log().Warn("queue worker receive error", "error", err)
+			SleepContext(ctx, w.opts.RetryInterval)
+			continue
+		}
+
+		if err := w.Handle(ctx, msg); err != nil {
+			log().Warn("queue worker job error", "job", msg.Key(), "error", err)
+		}
+	}
+}
+
+// Handle dispatch given message to leased service, ack it once
+// its result is verified or requeue it. Returned error is the
+// reason the message is requeued
+func (w *QueueWorker) Handle(ctx context.Context, msg IQueueMessage) error {
+	lease, err := w.lease(ctx, msg)
+	if err != nil {
+		return w.requeue(msg, err)
+	}
+	defer lease.Release()
+
+	srv := lease.Service
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	go w.watch(jobCtx, lease, cancel)
+
+	start := time.Now()
+	result, err := w.opts.Dispatch(jobCtx, srv, msg)
+
+	// revoked lease and cancellation are not failures of the service
+	var revoked ErrLeaseRevoked
+	if cause := context.Cause(jobCtx); errors.As(cause, &revoked) {
+		return w.requeue(msg, revoked)
+	}
+	if ctx.Err() != nil {
+		return w.requeue(msg, ctx.Err())
+	}
+
+	w.opts.List.ObserveResult(ctx, srv, time.Since(start), err)
+	if err != nil {
+		return w.requeue(msg, fmt.Errorf("dispatch to service %s: %w", srv.Address(), err))
+	}
+
+	if w.opts.Verify != nil {
+		if err := w.opts.Verify(ctx, msg, result); err != nil {
+			w.opts.List.ReportVerificationFailure(srv)
+			return w.requeue(msg, fmt.Errorf("verify result of service %s: %w", srv.Address(), err))
+		}
+	}
+
+	if err := msg.Ack(); err != nil {
+		return fmt.Errorf("ack: %w", err)
+	}
+
+	return nil
+}
+
+// lease lease service of the list for given message, if
+// no service could be leased it waits for healthy service
+// up to LeaseWait, so messages are not requeued in a loop
+// while the list is empty or saturated
+func (w *QueueWorker) lease(ctx context.Context, msg IQueueMessage) (*Lease, error) {
+	lease, err := w.opts.List.Lease(msg.Key())
+	if err == nil {
+		return lease, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, w.opts.LeaseWait)
+	defer cancel()
+
+	if _, waitErr := w.opts.List.NextWait(waitCtx); waitErr != nil {
+		return nil, err
+	}
+
+	return w.opts.List.Lease(msg.Key())
+}
+
+// watch cancel given job context once the leased
+// service is jailed, drained or the lease is expired
+func (w *QueueWorker) watch(ctx context.Context, lease *Lease, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(w.opts.JailCheckInterval)
+	defer ticker.Stop()
+
+	revoke := func(reason string) {
+		cancel(ErrLeaseRevoked{Lease: lease.ID, Service: lease.Service.ID(), Reason: reason})
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-lease.Draining():
+			revoke(LeaseRevokedDraining)
+			return
+		case <-lease.Expired():
+			revoke(LeaseRevokedExpired)
+			return
+		case <-ticker.C:
+			if lease.Service.Status() != service.StatusHealthy {
+				revoke(LeaseRevokedJailed)
+				return
+			}
+		}
+	}
+}
+
+// requeue return given message to the queue
+// and return reason of the requeue
+func (w *QueueWorker) requeue(msg IQueueMessage, reason error) error {
+	if err := msg.Requeue(w.opts.RequeueDelay); err != nil {
+		return errors.Join(reason, fmt.Errorf("requeue: %w", err))
+	}
+
+	return reason
+}
@@ -0,0 +1,155 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// fakeMessage is queue message recording its outcome
+type fakeMessage struct {
+	key string
+
+	mu       sync.Mutex
+	acked    bool
+	requeued int
+	delay    time.Duration
+}
+
+func (m *fakeMessage) Key() string  { return m.key }
+func (m *fakeMessage) Data() []byte { return []byte(m.key) }
+
+func (m *fakeMessage) Ack() error {
+	defer m.mu.Unlock()
+	m.mu.Lock()
+
+	m.acked = true
+	return nil
+}
+
+func (m *fakeMessage) Requeue(delay time.Duration) error {
+	defer m.mu.Unlock()
+	m.mu.Lock()
+
+	m.requeued++
+	m.delay = delay
+	return nil
+}
+
+func (m *fakeMessage) outcome() (bool, int) {
+	defer m.mu.Unlock()
+	m.mu.Lock()
+
+	return m.acked, m.requeued
+}
+
+// fakeQueue is queue of buffered messages
+type fakeQueue chan IQueueMessage
+
+func (q fakeQueue) Receive(ctx context.Context) (IQueueMessage, error) {
+	select {
+	case msg := <-q:
+		return msg, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestQueueWorker(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	prover := newHealthyService("https://1gateway.fm")
+	list.Add(prover)
+
+	queue := make(fakeQueue, 3)
+	worker, err := NewQueueWorker(&QueueWorkerOpts{
+		Queue: queue,
+		List:  list,
+		Dispatch: func(ctx context.Context, srv service.IService, msg IQueueMessage) ([]byte, error) {
+			if len(list.Leases(srv.ID())) != 1 {
+				t.Errorf("dispatched job should hold lease of the service")
+			}
+
+			switch msg.Key() {
+			case "failed":
+				return nil, errors.New("prover error")
+			case "jailed":
+				list.FromHealthyToJail(srv.ID())
+				<-ctx.Done()
+				return nil, ctx.Err()
+			}
+			return msg.Data(), nil
+		},
+		Verify: func(_ context.Context, msg IQueueMessage, result []byte) error {
+			if string(result) != msg.Key() {
+				return errors.New("invalid proof")
+			}
+			return nil
+		},
+		JailCheckInterval: 10 * time.Millisecond,
+		LeaseWait:         20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("unexpected worker error: %s", err)
+	}
+
+	verified, failed, jailed := &fakeMessage{key: "verified"}, &fakeMessage{key: "failed"}, &fakeMessage{key: "jailed"}
+	queue <- verified
+	queue <- failed
+	queue <- jailed
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	waitFor(t, func() bool {
+		_, requeued := jailed.outcome()
+		return requeued == 1
+	})
+	cancel()
+	<-done
+
+	if acked, requeued := verified.outcome(); !acked || requeued != 0 {
+		t.Errorf("verified job should be acked")
+	}
+	if acked, requeued := failed.outcome(); acked || requeued != 1 {
+		t.Errorf("failed job should be requeued")
+	}
+	if len(list.Leases(prover.ID())) != 0 {
+		t.Errorf("leases should be released after jobs are handled")
+	}
+
+	// job is requeued if there are no healthy services
+	msg := &fakeMessage{key: "unavailable"}
+	var unavailable ErrNoHealthyServices
+	if err := worker.Handle(context.Background(), msg); !errors.As(err, &unavailable) {
+		t.Errorf("expected no healthy services error, got %v", err)
+	}
+	if _, requeued := msg.outcome(); requeued != 1 || msg.delay != defaultQueueRequeueDelay {
+		t.Errorf("job without healthy services should be requeued with delay, got delay %s", msg.delay)
+	}
+
+	// job waits for service added while no one could be leased
+	msg = &fakeMessage{key: "waiting"}
+	worker.opts.LeaseWait = time.Minute
+	time.AfterFunc(20*time.Millisecond, func() { list.FromJailToHealthy(prover) })
+
+	if err := worker.Handle(context.Background(), msg); err != nil {
+		t.Errorf("job should wait for recovered service, got %v", err)
+	}
+	if acked, requeued := msg.outcome(); !acked || requeued != 0 {
+		t.Errorf("job dispatched to recovered service should be acked")
+	}
+}
@@ -0,0 +1,404 @@
+package pool
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultRecorderInterval = time.Minute
+	recorderFileExt         = ".csv"
+	recorderTimeFormat      = "20060102T150405.000000000"
+
+	recordKindSnapshot   = "snapshot"
+	recordKindTransition = "transition"
+)
+
+// RecorderOpts is options that configure recorder
+// of pool history to rotating csv files
+type RecorderOpts struct {
+	Dir         string        // directory to write files to
+	Interval    time.Duration // snapshots interval (1m by default)
+	MaxFileSize int64         // rotate file after it reaches given size in bytes (0 to disable)
+	MaxFileAge  time.Duration // rotate file after it reaches given age (0 to disable)
+	MaxFiles    int           // number of rotated files to keep (0 to keep all)
+}
+
+// IRecordedList is services list which history is recorded,
+// membership transitions are taken from the list events
+type IRecordedList interface {
+	IAdmin
+
+	// Subscribe return channel of list events,
+	// closed when the list is closed
+	Subscribe() <-chan PoolEvent
+
+	// Unsubscribe stop delivery of events to
+	// given channel returned by Subscribe
+	Unsubscribe(events <-chan PoolEvent)
+}
+
+// Recorder periodically append services list snapshots and
+// membership transitions to rotating csv files for offline
+// analysis without metrics stack
+type Recorder struct {
+	name   string
+	list   IRecordedList
+	opts   RecorderOpts
+	events <-chan PoolEvent
+
+	file     *os.File
+	writer   *csv.Writer
+	size     int64
+	openedAt time.Time
+
+	previous map[string]ServiceSnapshot
+}
+
+// NewRecorder create new Recorder of given services list with
+// given configuration, events of the list are recorded since now
+func NewRecorder(list IRecordedList, opts *RecorderOpts) *Recorder {
+	r := &Recorder{
+		name:   list.Snapshot().Name,
+		list:   list,
+		opts:   *opts,
+		events: list.Subscribe(),
+	}
+
+	if r.opts.Interval == 0 {
+		r.opts.Interval = defaultRecorderInterval
+	}
+
+	return r
+}
+
+// Run record snapshots periodically and transitions once they
+// happen until given stop channel is closed, transitions which
+// happened before the stop are flushed before return
+func (r *Recorder) Run(stop <-chan struct{}) {
+	listLogger(r.list).Info("start history recorder", "dir", r.opts.Dir)
+
+	defer r.close()
+	defer r.list.Unsubscribe(r.events)
+
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+
+	if err := r.Record(); err != nil {
+		listLogger(r.list).Warn("record pool history error", "error", err)
+	}
+
+	events := r.events
+	for {
+		select {
+		case <-stop:
+			if err := r.drain(); err != nil {
+				listLogger(r.list).Warn("record pool history error", "error", err)
+			}
+			listLogger(r.list).Warn("stop history recorder")
+			return
+		case event, ok := <-events:
+			// events are closed with the list
+			if !ok {
+				events = nil
+				continue
+			}
+			if err := r.transition(event); err != nil {
+				listLogger(r.list).Warn("record pool history error", "error", err)
+			}
+		case <-ticker.C:
+			if err := r.Record(); err != nil {
+				listLogger(r.list).Warn("record pool history error", "error", err)
+			}
+		}
+	}
+}
+
+// Record append transitions since the previous snapshot and current
+// snapshot to the current file. Transitions are taken from the list
+// events, so short ones, e.g. jail and recovery within one interval,
+// are not missed. Transitions without events, e.g. to review, are
+// taken from the difference with the previous snapshot
+func (r *Recorder) Record() error {
+	if err := r.rotate(r.name, time.Now()); err != nil {
+		return err
+	}
+
+	if err := r.drain(); err != nil {
+		return err
+	}
+
+	state := r.list.Snapshot()
+
+	current := make(map[string]ServiceSnapshot, len(state.Services))
+
+	for _, srv := range state.Services {
+		current[srv.ID] = srv
+
+		previous, ok := r.previous[srv.ID]
+		if r.previous != nil && (!ok || previous.Membership != srv.Membership) {
+			if err := r.write(state.Time, recordKindTransition, srv, previous.Membership); err != nil {
+				return err
+			}
+		}
+
+		if err := r.write(state.Time, recordKindSnapshot, srv, ""); err != nil {
+			return err
+		}
+	}
+
+	for id, previous := range r.previous {
+		if _, ok := current[id]; ok {
+			continue
+		}
+
+		removed := previous
+		removed.Membership = ""
+		if err := r.write(state.Time, recordKindTransition, removed, previous.Membership); err != nil {
+			return err
+		}
+	}
+
+	r.previous = current
+
+	r.writer.Flush()
+	return r.writer.Error()
+}
+
+// drain append transitions of all received events
+func (r *Recorder) drain() error {
+	for {
+		select {
+		case event, ok := <-r.events:
+			if !ok {
+				return nil
+			}
+			if err := r.transition(event); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// transition append membership transition of given event to
+// the current file, events of other types and transitions which
+// are already recorded, e.g. by the snapshot, are skipped
+func (r *Recorder) transition(event PoolEvent) error {
+	if event.Service == nil {
+		return nil
+	}
+
+	id := event.Service.ID()
+	previous := r.previous[id].Membership
+
+	var membership string
+	switch event.Type {
+	case EventServiceJailed:
+		previous, membership = MembershipHealthy, MembershipJailed
+	case EventServiceRecovered:
+		previous, membership = MembershipJailed, MembershipHealthy
+	case EventServiceAdded:
+		membership = MembershipJailed
+		if event.Service.Status() == service.StatusHealthy {
+			membership = MembershipHealthy
+		}
+	case EventServiceRemoved:
+	default:
+		return nil
+	}
+
+	if r.recorded(id, membership) {
+		return nil
+	}
+
+	if r.file == nil {
+		if err := r.rotate(r.name, event.Time); err != nil {
+			return err
+		}
+	}
+
+	srv := newServiceSnapshot(event.Service, membership)
+	if err := r.write(event.Time, recordKindTransition, srv, previous); err != nil {
+		return err
+	}
+
+	// transitions are not recorded again
+	// from the difference with snapshot
+	if r.previous != nil {
+		if membership == "" {
+			delete(r.previous, id)
+		} else {
+			r.previous[id] = srv
+		}
+	}
+
+	r.writer.Flush()
+	return r.writer.Error()
+}
+
+// recorded check if given membership of service with given id
+// is already recorded, empty membership is of removed service
+func (r *Recorder) recorded(id, membership string) bool {
+	if r.previous == nil {
+		return false
+	}
+
+	srv, ok := r.previous[id]
+	if membership == "" {
+		return !ok
+	}
+
+	return ok && srv.Membership == membership
+}
+
+// write append one record to the current file
+func (r *Recorder) write(t time.Time, kind string, srv ServiceSnapshot, previous string) error {
+	record := []string{
+		t.UTC().Format(time.RFC3339Nano),
+		kind,
+		srv.ID,
+		srv.Address,
+		srv.NodeName,
+		previous,
+		srv.Membership,
+		srv.Status,
+		strconv.FormatFloat(float64(srv.Load), 'f', 4, 32),
+	}
+
+	if err := r.writer.Write(record); err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+
+	// csv writer is buffered, so size is estimated
+	// by the record length to make rotation decisions
+	r.size += int64(len(strings.Join(record, ",")) + 1)
+
+	return nil
+}
+
+// rotate open new file if there is no opened
+// file or current one exceeds size or age limits
+func (r *Recorder) rotate(name string, now time.Time) error {
+	if r.file != nil {
+		sizeExceeded := r.opts.MaxFileSize > 0 && r.size >= r.opts.MaxFileSize
+		ageExceeded := r.opts.MaxFileAge > 0 && now.Sub(r.openedAt) >= r.opts.MaxFileAge
+
+		if !sizeExceeded && !ageExceeded {
+			return nil
+		}
+
+		r.close()
+	}
+
+	if err := os.MkdirAll(r.opts.Dir, 0o755); err != nil {
+		return fmt.Errorf("create recorder dir: %w", err)
+	}
+
+	file, err := os.Create(filepath.Join(r.opts.Dir, fmt.Sprintf("%s-%s%s", safeFileName(name), now.UTC().Format(recorderTimeFormat), recorderFileExt)))
+	if err != nil {
+		return fmt.Errorf("create recorder file: %w", err)
+	}
+
+	r.file = file
+	r.writer = csv.NewWriter(file)
+	r.size = 0
+	r.openedAt = now
+
+	if err := r.writer.Write([]string{"time", "kind", "service_id", "address", "node_name", "previous_membership", "membership", "status", "load"}); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	r.cleanup(name)
+
+	return nil
+}
+
+// cleanup remove the oldest files of the
+// list with given name exceeding MaxFiles limit
+func (r *Recorder) cleanup(name string) {
+	if r.opts.MaxFiles == 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(r.opts.Dir)
+	if err != nil {
+		listLogger(r.list).Warn("list recorder files error", "dir", r.opts.Dir, "error", err)
+		return
+	}
+
+	// files of lists which names start with given
+	// name followed by dash should not be matched
+	own := regexp.MustCompile(`^` + regexp.QuoteMeta(safeFileName(name)) + `-\d{8}T\d{6}\.\d{9}` + regexp.QuoteMeta(recorderFileExt) + `$`)
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && own.MatchString(entry.Name()) {
+			files = append(files, filepath.Join(r.opts.Dir, entry.Name()))
+		}
+	}
+
+	if len(files) <= r.opts.MaxFiles {
+		return
+	}
+
+	// files names contain creation time, so
+	// lexical order is chronological order
+	sort.Strings(files)
+
+	for _, file := range files[:len(files)-r.opts.MaxFiles] {
+		if err := os.Remove(file); err != nil {
+			listLogger(r.list).Warn("remove recorder file error", "file", file, "error", err)
+		}
+	}
+}
+
+// close flush and close the current file
+func (r *Recorder) close() {
+	if r.file == nil {
+		return
+	}
+
+	r.writer.Flush()
+	if err := r.file.Close(); err != nil {
+		listLogger(r.list).Warn("close recorder file error", "error", err)
+	}
+
+	r.file = nil
+	r.writer = nil
+}
+
+// safeFileName return given pool name usable as file name, any
+// character except letters, digits, dot, dash and underscore is
+// replaced with underscore and hash of the name is appended to
+// changed names, so different names are not mapped to one file
+func safeFileName(name string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r == '.' && name != "." && name != "..":
+			return r
+		}
+		return '_'
+	}, name)
+
+	if safe == name && name != "" {
+		return name
+	}
+
+	sum := sha256.Sum256([]byte(name))
+	return safe + "_" + hex.EncodeToString(sum[:4])
+}
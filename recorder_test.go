@@ -0,0 +1,153 @@
+package pool
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorderRotation(t *testing.T) {
+	dir := t.TempDir()
+
+	list := NewServicesList("recorded", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	recorder := NewRecorder(list, &RecorderOpts{
+		Dir:         dir,
+		MaxFileSize: 1,
+		MaxFiles:    2,
+	})
+	defer recorder.close()
+
+	if err := recorder.Record(); err != nil {
+		t.Fatalf("unexpected record error: %s", err)
+	}
+
+	list.FromHealthyToJail(srv.ID())
+
+	for i := 0; i < 2; i++ {
+		if err := recorder.Record(); err != nil {
+			t.Fatalf("unexpected record error: %s", err)
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "recorded-*.csv"))
+	if err != nil {
+		t.Fatalf("unexpected glob error: %s", err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("expected 2 rotated files, got %d", len(files))
+	}
+
+	var transitions int
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unexpected read error: %s", err)
+		}
+		transitions += strings.Count(string(content), recordKindTransition)
+	}
+
+	if transitions != 1 {
+		t.Errorf("unexpected num of transitions %d", transitions)
+	}
+}
+
+func TestRecorderCleanupOwnFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	// file of "recorded-other" list matches "recorded-*"
+	foreign := filepath.Join(dir, "recorded-other-20200101T000000.000000000.csv")
+	if err := os.WriteFile(foreign, nil, 0o600); err != nil {
+		t.Fatalf("unexpected write error: %s", err)
+	}
+
+	for _, name := range []string{"recorded", "../escaped"} {
+		list := NewServicesList(name, &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		})
+		list.Add(newHealthyService("https://1gateway.fm"))
+
+		recorder := NewRecorder(list, &RecorderOpts{Dir: dir, MaxFileSize: 1, MaxFiles: 1})
+		for i := 0; i < 3; i++ {
+			if err := recorder.Record(); err != nil {
+				t.Fatalf("unexpected record error: %s", err)
+			}
+		}
+		recorder.close()
+	}
+
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("file of other list should be kept, got %v", err)
+	}
+
+	escaped, _ := filepath.Glob(filepath.Join(filepath.Dir(dir), "escaped-*.csv"))
+	if len(escaped) != 0 {
+		t.Errorf("files should not be written outside of recorder dir, got %v", escaped)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.csv"))
+	if len(files) != 3 {
+		t.Errorf("expected one file per list and foreign file, got %v", files)
+	}
+}
+
+func TestRecorderTransitionsWithinInterval(t *testing.T) {
+	dir := t.TempDir()
+
+	list := NewServicesList("recorded", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	recorder := NewRecorder(list, &RecorderOpts{Dir: dir, Interval: time.Hour})
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		recorder.Run(stop)
+		close(done)
+	}()
+
+	// jail and recovery between snapshots are recorded
+	list.FromHealthyToJail(srv.ID())
+	list.FromJailToHealthy(srv)
+
+	close(stop)
+	<-done
+
+	files, _ := filepath.Glob(filepath.Join(dir, "recorded-*.csv"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("unexpected read error: %s", err)
+	}
+
+	for _, transition := range []string{",healthy,jailed,", ",jailed,healthy,"} {
+		if !strings.Contains(string(content), transition) {
+			t.Errorf("transition %s should be recorded, got:\n%s", transition, content)
+		}
+	}
+	if transitions := strings.Count(string(content), recordKindTransition); transitions != 2 {
+		t.Errorf("expected 2 transitions, got %d", transitions)
+	}
+}
@@ -0,0 +1,312 @@
+package pool
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/gateway-fm/prover-pool-lib/discovery"
+)
+
+// PoolRegistryOpts is options that needs to
+// configure PoolRegistry shared resources
+type PoolRegistryOpts struct {
+	Scheduler *SchedulerOpts              // shared healthchecks and discovery scheduler configuration
+	Metrics   *Metrics                    // shared prometheus collectors, pools are distinguished by pool label (nil to disable)
+	Discovery discovery.IServiceDiscovery // shared discovery driver used by pools without own one
+	Timeouts  *Timeouts                   // deadlines inherited by pools, zero ones are inherited from DefaultTimeouts (nil to inherit all)
+}
+
+// PoolRegistry holds many isolated pools of one process which
+// share healthchecks scheduler, metrics and discovery driver,
+// so creating a pool doesn't spawn own loops and clients
+type PoolRegistry struct {
+	scheduler *Scheduler
+	metrics   *Metrics
+	discovery discovery.IServiceDiscovery
+	timeouts  *Timeouts
+
+	pools     map[string]IServicesPool
+	dependsOn map[string][]string // pool name -> names of registry pools it depends on
+	templates map[string]*PoolTemplate
+
+	mu sync.RWMutex
+}
+
+// NewPoolRegistry create new PoolRegistry
+// with given shared resources
+func NewPoolRegistry(opts *PoolRegistryOpts) *PoolRegistry {
+	return &PoolRegistry{
+		scheduler: NewScheduler(opts.Scheduler),
+		metrics:   opts.Metrics,
+		discovery: opts.Discovery,
+		timeouts:  opts.Timeouts,
+		pools:     make(map[string]IServicesPool),
+		dependsOn: make(map[string][]string),
+		templates: make(map[string]*PoolTemplate),
+	}
+}
+
+// Create create new pool with given configuration using
+// registry shared resources and start it, pool names
+// should be unique within the registry. Configured timeouts
+// of the registry, pool and list are validated together.
+// The pool is started outside the registry lock, so slow
+// seeds don't block other registry calls
+func (r *PoolRegistry) Create(opts *ServicesPoolsOpts, healthchecks bool) (IServicesPool, error) {
+	r.mu.RLock()
+	_, exists := r.pools[opts.Name]
+	poolOpts, bound, err := r.prepare(opts)
+	r.mu.RUnlock()
+
+	if exists {
+		return nil, ErrPoolExists{Name: opts.Name}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pool := startPool(poolOpts, healthchecks)
+
+	r.mu.Lock()
+	// pool with the same name could be created concurrently
+	if _, ok := r.pools[opts.Name]; ok {
+		r.mu.Unlock()
+		pool.Close()
+		return nil, ErrPoolExists{Name: opts.Name}
+	}
+	r.pools[opts.Name] = pool
+	r.dependsOn[opts.Name] = opts.DependsOn
+	stale := r.staleDependencies(pool, opts.DependsOn, bound)
+	r.mu.Unlock()
+
+	stale.rebind()
+
+	return pool, nil
+}
+
+// Replace create and start new pool with given configuration
+// like Create and atomically swap it with existing pool of the
+// same name, which is closed after the swap, so the name always
+// resolves to a running pool. Existing pool is kept if the new
+// configuration is invalid. Pools depending on the replaced
+// pool are rebound to the new one before it's closed
+func (r *PoolRegistry) Replace(opts *ServicesPoolsOpts, healthchecks bool) (IServicesPool, error) {
+	r.mu.RLock()
+	poolOpts, bound, err := r.prepare(opts)
+	r.mu.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	pool := startPool(poolOpts, healthchecks)
+
+	r.mu.Lock()
+	replaced, ok := r.pools[opts.Name]
+	r.pools[opts.Name] = pool
+	r.dependsOn[opts.Name] = opts.DependsOn
+	stale := r.staleDependencies(pool, opts.DependsOn, bound)
+	if ok {
+		stale = append(stale, r.dependents(opts.Name, replaced, pool)...)
+	}
+	r.mu.Unlock()
+
+	stale.rebind()
+
+	if ok {
+		replaced.Close()
+	}
+
+	return pool, nil
+}
+
+// dependencyRebind is dependency of the pool list
+// that should be replaced with the current one
+type dependencyRebind struct {
+	list     IServicesList
+	from, to IServicesList
+}
+
+// dependencyRebinds is list of dependencies to rebind
+type dependencyRebinds []dependencyRebind
+
+// rebind replace dependencies of the lists and recheck
+// theirs status, lists not supporting rebind are skipped
+func (d dependencyRebinds) rebind() {
+	for _, rebind := range d {
+		if list, ok := rebind.list.(interface {
+			rebindDependency(from, to IServicesList)
+		}); ok {
+			list.rebindDependency(rebind.from, rebind.to)
+		}
+	}
+}
+
+// dependents return rebinds of registry pools depending on
+// pool with given name from replaced pool to given one.
+// Should be called under the lock
+func (r *PoolRegistry) dependents(name string, replaced, pool IServicesPool) dependencyRebinds {
+	var rebinds dependencyRebinds
+	for dependent, names := range r.dependsOn {
+		if slices.Contains(names, name) {
+			rebinds = append(rebinds, dependencyRebind{list: r.pools[dependent].List(), from: replaced.List(), to: pool.List()})
+		}
+	}
+
+	return rebinds
+}
+
+// staleDependencies return rebinds of given pool dependencies
+// bound during its creation that are replaced in the meantime.
+// Should be called under the lock
+func (r *PoolRegistry) staleDependencies(pool IServicesPool, names []string, bound []IServicesList) dependencyRebinds {
+	var rebinds dependencyRebinds
+	for i, name := range names {
+		current, ok := r.pools[name]
+		if ok && current.List() != bound[i] {
+			rebinds = append(rebinds, dependencyRebind{list: pool.List(), from: bound[i], to: current.List()})
+		}
+	}
+
+	return rebinds
+}
+
+// prepare validate given configuration and return pool
+// options using registry shared resources with lists of
+// pools it depends on. Should be called under the lock
+func (r *PoolRegistry) prepare(opts *ServicesPoolsOpts) (*ServicesPoolsOpts, []IServicesList, error) {
+	poolOpts := *opts
+	poolOpts.Scheduler = r.scheduler
+	if poolOpts.Discovery == nil {
+		poolOpts.Discovery = r.discovery
+	}
+
+	listOpts := ServicesListOpts{}
+	if opts.ListOpts != nil {
+		listOpts = *opts.ListOpts
+	}
+	listOpts.Scheduler = r.scheduler
+	if listOpts.Metrics == nil {
+		listOpts.Metrics = r.metrics
+	}
+
+	bound := make([]IServicesList, 0, len(opts.DependsOn))
+	for _, name := range opts.DependsOn {
+		dependency, ok := r.pools[name]
+		if !ok {
+			return nil, nil, ErrPoolNotFound{Name: name}
+		}
+		bound = append(bound, dependency.List())
+	}
+	listOpts.Dependencies = slices.Concat(listOpts.Dependencies, bound)
+	poolOpts.ListOpts = &listOpts
+
+	poolTimeouts := opts.Timeouts.Inherit(r.timeouts.Inherit(Timeouts{}))
+	poolOpts.Timeouts = &poolTimeouts
+
+	if poolOpts.AdaptiveDiscovery != nil {
+		if err := poolOpts.AdaptiveDiscovery.Validate(); err != nil {
+			return nil, nil, fmt.Errorf("adaptive discovery of pool %q: %w", opts.Name, err)
+		}
+	}
+
+	configured := listOpts.Timeouts.Inherit(poolTimeouts)
+	if err := configured.Validate(poolOpts.DiscoveryInterval, listOpts.ChecksInterval); err != nil {
+		return nil, nil, fmt.Errorf("timeouts of pool %q: %w", opts.Name, err)
+	}
+
+	return &poolOpts, bound, nil
+}
+
+// startPool create and start pool with given prepared options
+func startPool(opts *ServicesPoolsOpts, healthchecks bool) IServicesPool {
+	pool := NewServicesPool(opts)
+	pool.Start(healthchecks)
+
+	return pool
+}
+
+// CreateAll create and start pools with given configurations
+// in given order, e.g. "prover-small", "prover-large" and
+// "aggregator" pools of one process, so pools could depend
+// on pools created before them. Creation stops on first
+// error, pools created before it are kept in the registry
+func (r *PoolRegistry) CreateAll(opts []*ServicesPoolsOpts, healthchecks bool) error {
+	for _, poolOpts := range opts {
+		if _, err := r.Create(poolOpts, healthchecks); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get return pool with given name
+func (r *PoolRegistry) Get(name string) (IServicesPool, bool) {
+	defer r.mu.RUnlock()
+	r.mu.RLock()
+
+	pool, ok := r.pools[name]
+	return pool, ok
+}
+
+// List return services list of pool with given name
+func (r *PoolRegistry) List(name string) (IServicesList, bool) {
+	pool, ok := r.Get(name)
+	if !ok {
+		return nil, false
+	}
+
+	return pool.List(), true
+}
+
+// Names return sorted names of all registry pools
+func (r *PoolRegistry) Names() []string {
+	defer r.mu.RUnlock()
+	r.mu.RLock()
+
+	names := make([]string, 0, len(r.pools))
+	for name := range r.pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Remove close and remove pool with given name
+func (r *PoolRegistry) Remove(name string) error {
+	r.mu.Lock()
+
+	pool, ok := r.pools[name]
+	if !ok {
+		r.mu.Unlock()
+		return ErrPoolNotFound{Name: name}
+	}
+	delete(r.pools, name)
+	delete(r.dependsOn, name)
+
+	r.mu.Unlock()
+
+	pool.Close()
+
+	return nil
+}
+
+// Close close all registry pools
+// and shared scheduler
+func (r *PoolRegistry) Close() {
+	r.mu.Lock()
+	pools := r.pools
+	r.pools = make(map[string]IServicesPool)
+	r.dependsOn = make(map[string][]string)
+	r.mu.Unlock()
+
+	for _, pool := range pools {
+		pool.Close()
+	}
+
+	r.scheduler.Close()
+}
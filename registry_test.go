@@ -0,0 +1,172 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestPoolRegistry(t *testing.T) {
+	registry := NewPoolRegistry(&PoolRegistryOpts{
+		Scheduler: &SchedulerOpts{Workers: 2, Tick: 10 * time.Millisecond},
+		Discovery: &staticDiscovery{services: []service.IService{
+			newHealthyService("https://1gateway.fm"),
+			newHealthyService("https://2gateway.fm"),
+		}},
+	})
+	defer registry.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := registry.Create(&ServicesPoolsOpts{
+			Name:              fmt.Sprintf("network-%d", i),
+			DiscoveryInterval: time.Second,
+			ListOpts: &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  1 * time.Second,
+				ChecksInterval: 1 * time.Second,
+			},
+		}, true)
+		if err != nil {
+			t.Fatalf("unexpected create error: %s", err)
+		}
+	}
+
+	if _, err := registry.Create(&ServicesPoolsOpts{Name: "network-0"}, true); !errors.As(err, &ErrPoolExists{}) {
+		t.Fatalf("duplicated pool name should be rejected, got %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for _, name := range registry.Names() {
+		pool, _ := registry.Get(name)
+		for pool.Count() != 2 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if pool.Count() != 2 {
+			t.Errorf("pool %s should be discovered by shared scheduler", name)
+		}
+	}
+
+	if err := registry.Remove("network-0"); err != nil {
+		t.Fatalf("unexpected remove error: %s", err)
+	}
+
+	if _, ok := registry.Get("network-0"); ok || len(registry.Names()) != 4 {
+		t.Errorf("removed pool should not be in registry")
+	}
+
+	if err := registry.Remove("network-0"); !errors.As(err, &ErrPoolNotFound{}) {
+		t.Errorf("unexpected error on removing missing pool %v", err)
+	}
+}
+
+func TestPoolRegistryCreateAll(t *testing.T) {
+	discovery := &staticDiscovery{services: []service.IService{
+		newHealthyService("https://1gateway.fm"),
+	}}
+
+	registry := NewPoolRegistry(&PoolRegistryOpts{
+		Scheduler: &SchedulerOpts{Workers: 2, Tick: 10 * time.Millisecond},
+		Discovery: discovery,
+	})
+	defer registry.Close()
+
+	var opts []*ServicesPoolsOpts
+	for _, name := range []string{"prover-small", "prover-large", "aggregator"} {
+		opts = append(opts, &ServicesPoolsOpts{
+			Name:              name,
+			DiscoveryInterval: time.Second,
+			ListOpts: &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  1 * time.Second,
+				ChecksInterval: 1 * time.Second,
+			},
+		})
+	}
+
+	if err := registry.CreateAll(opts, true); err != nil {
+		t.Fatalf("unexpected create error: %s", err)
+	}
+
+	for _, name := range []string{"prover-small", "prover-large", "aggregator"} {
+		list, ok := registry.List(name)
+		if !ok {
+			t.Fatalf("list of pool %s should be in registry", name)
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for len(list.Healthy()) != 1 && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if len(list.Healthy()) != 1 {
+			t.Errorf("list of pool %s should be discovered by shared discovery", name)
+		}
+	}
+
+	if _, ok := registry.List("prover-medium"); ok {
+		t.Errorf("list of missing pool should not be found")
+	}
+
+	if err := registry.CreateAll(opts[:1], true); !errors.As(err, &ErrPoolExists{}) {
+		t.Errorf("duplicated pool name should be rejected, got %v", err)
+	}
+}
+
+func TestPoolRegistryReplace(t *testing.T) {
+	registry := NewPoolRegistry(&PoolRegistryOpts{
+		Discovery: &staticDiscovery{services: []service.IService{newHealthyService("https://1gateway.fm")}},
+	})
+	defer registry.Close()
+
+	opts := func(checks time.Duration) *ServicesPoolsOpts {
+		return &ServicesPoolsOpts{
+			Name:              "network",
+			DiscoveryInterval: time.Second,
+			ListOpts: &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  time.Second,
+				ChecksInterval: checks,
+			},
+		}
+	}
+
+	first, err := registry.Create(opts(time.Second), false)
+	if err != nil {
+		t.Fatalf("unexpected create error: %s", err)
+	}
+
+	aggregatorOpts := opts(time.Second)
+	aggregatorOpts.Name = "aggregator"
+	aggregatorOpts.DependsOn = []string{"network"}
+	aggregator, err := registry.Create(aggregatorOpts, false)
+	if err != nil {
+		t.Fatalf("unexpected create error: %s", err)
+	}
+
+	invalid := opts(time.Second)
+	invalid.AdaptiveDiscovery = &AdaptiveDiscoveryOpts{MinInterval: -time.Second}
+	if _, err := registry.Replace(invalid, false); !errors.As(err, &ErrInvalidConfig{}) {
+		t.Fatalf("invalid configuration should be rejected, got %v", err)
+	}
+	if pool, _ := registry.Get("network"); pool != first {
+		t.Fatalf("pool should be kept if replacement is invalid")
+	}
+
+	second, err := registry.Replace(opts(2*time.Second), false)
+	if err != nil {
+		t.Fatalf("unexpected replace error: %s", err)
+	}
+	if pool, _ := registry.Get("network"); pool != second {
+		t.Errorf("pool should be swapped with replacement")
+	}
+	if !first.List().(*ServicesList).closed() {
+		t.Errorf("replaced pool should be closed")
+	}
+	if dependencies := aggregator.List().(*ServicesList).dependencies; len(dependencies) != 1 || dependencies[0] != second.List() {
+		t.Errorf("dependent pool should be rebound to replacement")
+	}
+}
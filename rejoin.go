@@ -0,0 +1,163 @@
+package pool
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// rejoinState is state learned about removed
+// service restored when it rejoins the list
+type rejoinState struct {
+	removed  time.Time
+	added    time.Time              // time the service has joined the list first
+	stats    *stats.Service         // moving statistics the service is scored by
+	userData map[string]interface{} // user data except values implementing io.Closer, which are closed on removal
+}
+
+// rejoins is state of recently removed services kept
+// for the rejoin window, expired states are pruned
+// lazily on access
+type rejoins struct {
+	window time.Duration
+	max    int
+
+	mu      sync.Mutex
+	entries map[string]rejoinState
+}
+
+// newRejoins create state of removed services kept for given
+// window, at most max tombstones of given budget states are kept.
+// Nil is returned if rejoin fast-path is disabled
+func newRejoins(window time.Duration, budget *MemoryBudget) *rejoins {
+	if window <= 0 {
+		return nil
+	}
+
+	r := &rejoins{
+		window:  window,
+		entries: make(map[string]rejoinState),
+	}
+	if budget != nil {
+		r.max = budget.MaxTombstones
+	}
+
+	return r
+}
+
+// keep remember given state of removed service with given id
+func (r *rejoins) keep(id string, state rejoinState) {
+	if r == nil {
+		return
+	}
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	r.prune(state.removed)
+	r.entries[id] = state
+
+	if r.max > 0 && len(r.entries) > r.max {
+		oldest := ""
+		for entryID, entry := range r.entries {
+			if oldest == "" || entry.removed.Before(r.entries[oldest].removed) {
+				oldest = entryID
+			}
+		}
+		delete(r.entries, oldest)
+	}
+}
+
+// take return and forget state of service with given
+// id if it was removed within the rejoin window
+func (r *rejoins) take(id string) (rejoinState, bool) {
+	if r == nil {
+		return rejoinState{}, false
+	}
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	r.prune(time.Now())
+
+	state, ok := r.entries[id]
+	delete(r.entries, id)
+
+	return state, ok
+}
+
+// count return number of kept states,
+// zero if rejoin fast-path is disabled
+func (r *rejoins) count() int {
+	if r == nil {
+		return 0
+	}
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	return len(r.entries)
+}
+
+// prune remove states expired at given time.
+// Should be called under rejoins lock
+func (r *rejoins) prune(now time.Time) {
+	for id, state := range r.entries {
+		if now.Sub(state.removed) >= r.window {
+			delete(r.entries, id)
+		}
+	}
+}
+
+// keepRejoinState remember state of given service being removed,
+// so it is restored if the service rejoins within the window.
+// Should be called under the list lock before the state is released
+func (l *ServicesList) keepRejoinState(srv service.IService) {
+	if l.rejoins == nil {
+		return
+	}
+
+	state := rejoinState{
+		removed: time.Now(),
+		added:   l.added[srv.ID()],
+		stats:   l.stats.Get(srv.ID()),
+	}
+
+	for key, value := range l.userData[srv.ID()] {
+		if _, ok := value.(io.Closer); ok {
+			continue
+		}
+		if state.userData == nil {
+			state.userData = make(map[string]interface{})
+		}
+		state.userData[key] = value
+	}
+
+	l.rejoins.keep(srv.ID(), state)
+}
+
+// restoreRejoinState restore state of given service if it was
+// removed within the rejoin window, so restarted service is not
+// treated as brand new. Should be called under the list lock
+// before the service is tracked
+func (l *ServicesList) restoreRejoinState(srv service.IService) {
+	state, ok := l.rejoins.take(srv.ID())
+	if !ok {
+		return
+	}
+
+	if !state.added.IsZero() {
+		l.added[srv.ID()] = state.added
+	}
+	if state.stats != nil {
+		l.stats.Restore(srv.ID(), state.stats)
+	}
+	if state.userData != nil {
+		l.userData[srv.ID()] = state.userData
+	}
+
+	l.log().Info("service rejoined after removal, its state is restored", "service", srv.ID(), "node", srv.NodeName(), "after", time.Since(state.removed).Round(time.Millisecond).String())
+}
@@ -0,0 +1,83 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+)
+
+func TestRejoinRestoresState(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Stats:          stats.NewRegistry(stats.Opts{}),
+		RejoinWindow:   time.Minute,
+	}).(*ServicesList)
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+	list.ObserveResult(context.Background(), srv, time.Second, errors.New("proof failed"))
+
+	closer := &closerData{}
+	if err := list.SetUserData(srv.ID(), "region", "eu"); err != nil {
+		t.Fatalf("unexpected user data error: %s", err)
+	}
+	if err := list.SetUserData(srv.ID(), "conn", closer); err != nil {
+		t.Fatalf("unexpected user data error: %s", err)
+	}
+
+	joined := list.orderedHealthy()[0].added
+	learned := list.Stats().Get(srv.ID())
+
+	if !list.RemoveByID(srv.ID()) {
+		t.Fatalf("service should be removed")
+	}
+	if !closer.closed {
+		t.Errorf("closer user data should be closed on removal")
+	}
+
+	// restarted prover is rediscovered with the same id
+	restarted := newHealthyService("https://1gateway.fm")
+	list.Add(restarted)
+
+	if s := list.Stats().Get(restarted.ID()); s != learned || s.ErrorRate() == 0 {
+		t.Errorf("statistics of rejoined service should be restored")
+	}
+	if region, ok := list.UserData(restarted.ID(), "region"); !ok || region != "eu" {
+		t.Errorf("user data of rejoined service should be restored, got %v", region)
+	}
+	if _, ok := list.UserData(restarted.ID(), "conn"); ok {
+		t.Errorf("closed user data should not be restored")
+	}
+	if added := list.orderedHealthy()[0].added; !added.Equal(joined) {
+		t.Errorf("join time of rejoined service should be restored, got %s instead of %s", added, joined)
+	}
+}
+
+func TestRejoinWindowExpired(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Stats:          stats.NewRegistry(stats.Opts{}),
+		RejoinWindow:   time.Millisecond,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+	learned := list.Stats().Get(srv.ID())
+
+	list.RemoveByID(srv.ID())
+	time.Sleep(5 * time.Millisecond)
+	list.Add(newHealthyService("https://1gateway.fm"))
+
+	if s := list.Stats().Get(srv.ID()); s == nil || s == learned {
+		t.Errorf("service rejoined after the window should start with fresh statistics")
+	}
+}
@@ -0,0 +1,217 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// replacement is set of services prepared
+// to replace the list membership
+type replacement struct {
+	services []service.IService
+	checks   map[string]error // healthchecks outcomes of services that are new for the list
+}
+
+// ReplaceAll atomically swap the whole membership to given
+// services in one generation bump. Services present in both
+// current and given sets keep theirs instance, membership and
+// history, new services are admitted and healthchecked before
+// the swap and services missing in given set are closed
+func (l *ServicesList) ReplaceAll(services []service.IService) {
+	r := l.prepareReplacement(services)
+
+	l.mu.Lock()
+	removed, jailed := l.applyReplacement(r)
+	l.mu.Unlock()
+
+	l.finishReplacement(removed, jailed)
+}
+
+// prepareReplacement deduplicate given services and admit
+// and healthcheck the ones that are new for the list, so
+// the swap itself doesn't wait for the network
+func (l *ServicesList) prepareReplacement(services []service.IService) *replacement {
+	r := &replacement{
+		services: make([]service.IService, 0, len(services)),
+		checks:   make(map[string]error),
+	}
+
+	seen := make(map[string]struct{}, len(services))
+
+	for _, srv := range services {
+		if srv == nil {
+			continue
+		}
+
+		if _, ok := seen[srv.ID()]; ok {
+			continue
+		}
+		seen[srv.ID()] = struct{}{}
+
+		if !l.IsServiceExists(srv) {
+			if err := l.admit(srv); err != nil {
+				l.log().Warn("service is not admitted", "service", srv.ID(), "node", srv.NodeName(), "error", err)
+				continue
+			}
+
+			err := l.CheckHealth(context.Background(), srv)
+			l.availability.record(srv, err)
+			r.checks[srv.ID()] = err
+		}
+
+		r.services = append(r.services, srv)
+	}
+
+	return r
+}
+
+// applyReplacement swap membership to prepared services and
+// return removed services and new services put to the jail.
+// Should be called under the list lock
+func (l *ServicesList) applyReplacement(r *replacement) ([]service.IService, []service.IService) {
+	wanted := make(map[string]service.IService, len(r.services))
+	for _, srv := range r.services {
+		wanted[srv.ID()] = srv
+	}
+
+	var removed, jailed []service.IService
+
+	healthy := make([]service.IService, 0, len(r.services))
+	for _, srv := range l.healthy {
+		if _, ok := wanted[srv.ID()]; !ok {
+			removed = append(removed, srv)
+			continue
+		}
+		healthy = append(healthy, srv)
+		delete(wanted, srv.ID())
+	}
+
+	for id, srv := range l.jail {
+		if _, ok := wanted[id]; !ok {
+			removed = append(removed, srv)
+			l.fromJail(id)
+			continue
+		}
+		delete(wanted, id)
+	}
+
+	for id, item := range l.review {
+		if _, ok := wanted[id]; !ok {
+			removed = append(removed, item.Service)
+			delete(l.review, id)
+			continue
+		}
+		delete(wanted, id)
+	}
+
+	// services left are new for the list, the ones that were
+	// not checked during preparation appeared concurrently and
+	// were removed since, so they are checked by try up mechanics
+	for _, srv := range r.services {
+		if _, ok := wanted[srv.ID()]; !ok {
+			continue
+		}
+
+		l.restoreRejoinState(srv)
+		l.trackAdded(srv.ID())
+		l.spares.admit(srv)
+		l.queueEvent(PoolEvent{Type: EventServiceAdded, Service: srv})
+
+		jailedBefore := l.restorePersistedState(srv)
+		if err, checked := r.checks[srv.ID()]; checked && err == nil && !jailedBefore {
+			l.setStatus(srv, service.StatusHealthy)
+			healthy = append(healthy, srv)
+			continue
+		}
+
+		l.setStatus(srv, service.StatusUnHealthy)
+		l.toJail(srv)
+		jailed = append(jailed, srv)
+	}
+
+	for _, srv := range removed {
+		delete(l.flaps, srv.ID())
+		delete(l.verificationFailures, srv.ID())
+		l.releaseMember(srv, "replaced")
+	}
+
+	l.healthy = healthy
+	l.healthyChanged()
+	if atomic.LoadUint64(&l.current) >= uint64(len(l.healthy)) {
+		atomic.StoreUint64(&l.current, 0)
+	}
+
+	l.bumpGeneration()
+
+	l.log().Info("membership is replaced", "healthy", len(l.healthy), "jailed", len(jailed), "removed", len(removed))
+
+	return removed, jailed
+}
+
+// finishReplacement emit membership events, close
+// removed services and try to up jailed new services
+func (l *ServicesList) finishReplacement(removed, jailed []service.IService) {
+	l.flushEvents()
+
+	for _, srv := range removed {
+		if err := srv.Close(); err != nil {
+			l.log().Warn("unexpected error during service Close()", "service", srv.ID(), "error", err)
+		}
+	}
+
+	for _, srv := range jailed {
+		l.goTryUp(srv)
+	}
+}
+
+// ReplaceAll atomically swap the whole membership of all
+// shards to given services. All shards are locked during
+// the swap, so selections never observe partial membership
+func (l *ShardedServicesList) ReplaceAll(services []service.IService) {
+	grouped := make([][]service.IService, len(l.shards))
+	for _, srv := range services {
+		if srv == nil {
+			continue
+		}
+
+		i := l.shardIndex(srv.ID())
+		grouped[i] = append(grouped[i], srv)
+	}
+
+	replacements := make([]*replacement, len(l.shards))
+	for i, shard := range l.shards {
+		replacements[i] = shard.prepareReplacement(grouped[i])
+	}
+
+	// shards are always locked in the same order to avoid deadlocks
+	for _, shard := range l.shards {
+		shard.mu.Lock()
+	}
+
+	removed := make([][]service.IService, len(l.shards))
+	jailed := make([][]service.IService, len(l.shards))
+	for i, shard := range l.shards {
+		removed[i], jailed[i] = shard.applyReplacement(replacements[i])
+	}
+
+	for _, shard := range l.shards {
+		shard.mu.Unlock()
+	}
+
+	for i, shard := range l.shards {
+		shard.finishReplacement(removed[i], jailed[i])
+	}
+}
+
+// Generation return membership generation of
+// all shards which is increased on every change
+func (l *ShardedServicesList) Generation() uint64 {
+	var generation uint64
+	for _, shard := range l.shards {
+		generation += shard.Generation()
+	}
+
+	return generation
+}
@@ -0,0 +1,75 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServicesListReplaceAll(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+
+	kept := newHealthyService("https://1gateway.fm")
+	removed := newHealthyService("https://2gateway.fm")
+	list.Add(kept)
+	list.Add(removed)
+
+	generation := list.Generation()
+
+	// new instance of kept service should not replace the existing one
+	list.ReplaceAll([]service.IService{
+		newHealthyService("https://1gateway.fm"),
+		newHealthyService("https://3gateway.fm"),
+	})
+
+	if list.Generation() != generation+1 {
+		t.Errorf("replacement should bump generation once, got %d after %d", list.Generation(), generation)
+	}
+
+	healthy := make(map[string]service.IService)
+	for _, srv := range list.Healthy() {
+		healthy[srv.Address()] = srv
+	}
+
+	if len(healthy) != 2 {
+		t.Fatalf("expected 2 healthy services, got %d", len(healthy))
+	}
+
+	if healthy["https://1gateway.fm"] != kept {
+		t.Errorf("kept service instance should be preserved")
+	}
+
+	if _, ok := healthy["https://2gateway.fm"]; ok || list.IsServiceExists(removed) {
+		t.Errorf("service missing in replacement should be removed")
+	}
+}
+
+func TestServicesListReplaceAllStatus(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	// new services start unhealthy until the list admits them
+	up := newSwitchableService("https://1gateway.fm")
+	up.SetStatus(service.StatusUnHealthy)
+	down := newSwitchableService("https://2gateway.fm")
+	down.down.Store(true)
+
+	list.ReplaceAll([]service.IService{up, down})
+
+	if srv := list.Next(); srv == nil || srv.ID() != up.ID() {
+		t.Fatalf("expected replaced healthy member to be selected, got %v", srv)
+	}
+
+	if up.Status() != service.StatusHealthy || down.Status() != service.StatusUnHealthy {
+		t.Errorf("expected healthy and jailed statuses, got %s and %s", up.Status(), down.Status())
+	}
+}
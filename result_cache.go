@@ -0,0 +1,217 @@
+package pool
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const defaultResultCacheCapacity = 10000
+
+// IResultStorage is storage of cached job results, e.g.
+// in-memory MemoryResultStorage or adapter of shared cache
+// like Redis, so replicas reuse results of each other
+type IResultStorage interface {
+	// Get return cached result of job with given
+	// key and report if the result is found
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	// Set cache result of job with given key for given period,
+	// zero ttl keeps the result and negative one removes it
+	Set(ctx context.Context, key string, result []byte, ttl time.Duration) error
+}
+
+// ResultCacheOpts is options that configure
+// cache of results of dispatched jobs
+type ResultCacheOpts struct {
+	Pool    string         // pool name used in errors
+	List    ISelector      // list jobs are dispatched to, configure it with ConsistentHash strategy to route retries of uncached jobs to the same service
+	Storage IResultStorage // results storage (MemoryResultStorage of 10000 results by default)
+	TTL     time.Duration  // period results are cached for (0 to keep results until evicted)
+}
+
+// ResultCache is cache of job results over selection of
+// services by job key, repeated requests of the same job,
+// e.g. retries or replays, are answered from the cache
+// without dispatching to services. Concurrent requests of
+// the same job are dispatched once
+type ResultCache struct {
+	opts ResultCacheOpts
+
+	mu       sync.Mutex
+	inflight map[string]*resultCall // job key -> dispatch in flight
+}
+
+// resultCall is dispatch of job in flight
+type resultCall struct {
+	done   chan struct{}
+	result []byte
+	err    error
+}
+
+// NewResultCache create new ResultCache with given configuration
+func NewResultCache(opts *ResultCacheOpts) *ResultCache {
+	c := &ResultCache{
+		opts:     *opts,
+		inflight: make(map[string]*resultCall),
+	}
+
+	if c.opts.Storage == nil {
+		c.opts.Storage = NewMemoryResultStorage(defaultResultCacheCapacity)
+	}
+
+	return c
+}
+
+// Do return cached result of job with given key or dispatch the
+// job with given function to service selected for the key and
+// cache its result. Failed dispatches are not cached and
+// result which could not be stored is returned anyway
+func (c *ResultCache) Do(ctx context.Context, key string, dispatch func(ctx context.Context, srv service.IService) ([]byte, error)) ([]byte, error) {
+	result, ok, err := c.opts.Storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return result, nil
+	}
+
+	c.mu.Lock()
+	if call, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.result, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	call := &resultCall{done: make(chan struct{})}
+	c.inflight[key] = call
+	c.mu.Unlock()
+
+	call.result, call.err = c.dispatch(ctx, key, dispatch)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	c.mu.Unlock()
+	close(call.done)
+
+	return call.result, call.err
+}
+
+// dispatch dispatch job with given key to service selected
+// for the key and cache its result on success
+func (c *ResultCache) dispatch(ctx context.Context, key string, dispatch func(ctx context.Context, srv service.IService) ([]byte, error)) ([]byte, error) {
+	srv := c.opts.List.NextContext(WithHashKey(ctx, key))
+	if srv == nil {
+		return nil, ErrNoHealthyServices{Pool: c.opts.Pool}
+	}
+
+	start := time.Now()
+	result, err := dispatch(ctx, srv)
+	c.opts.List.ObserveResult(ctx, srv, time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.opts.Storage.Set(ctx, key, result, c.opts.TTL); err != nil {
+		log().Warn("result of job is not cached", "pool", c.opts.Pool, "job", key, "error", err)
+	}
+
+	return result, nil
+}
+
+// Forget remove cached result of job with given key,
+// e.g. after the result is found invalid
+func (c *ResultCache) Forget(ctx context.Context, key string) error {
+	return c.opts.Storage.Set(ctx, key, nil, -1)
+}
+
+// MemoryResultStorage is in-memory IResultStorage
+// evicting the least recently used results
+type MemoryResultStorage struct {
+	capacity int
+
+	mu      sync.Mutex
+	order   *list.List               // the most recently used first
+	entries map[string]*list.Element // key -> element of memoryResult
+}
+
+// memoryResult is cached result of one job
+type memoryResult struct {
+	key     string
+	result  []byte
+	expires time.Time // zero for results without ttl
+}
+
+// NewMemoryResultStorage create new MemoryResultStorage
+// keeping up to given number of results
+func NewMemoryResultStorage(capacity int) *MemoryResultStorage {
+	if capacity <= 0 {
+		capacity = defaultResultCacheCapacity
+	}
+
+	return &MemoryResultStorage{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get return cached result of job with given key
+func (s *MemoryResultStorage) Get(_ context.Context, key string) ([]byte, bool, error) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	entry := element.Value.(*memoryResult)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		s.order.Remove(element)
+		delete(s.entries, key)
+		return nil, false, nil
+	}
+
+	s.order.MoveToFront(element)
+
+	return entry.result, true, nil
+}
+
+// Set cache result of job with given key, negative
+// ttl removes cached result. The least recently
+// used result is evicted when capacity is reached
+func (s *MemoryResultStorage) Set(_ context.Context, key string, result []byte, ttl time.Duration) error {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	if element, ok := s.entries[key]; ok {
+		s.order.Remove(element)
+		delete(s.entries, key)
+	}
+	if ttl < 0 {
+		return nil
+	}
+
+	entry := &memoryResult{key: key, result: result}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	s.entries[key] = s.order.PushFront(entry)
+
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryResult).key)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestResultCache(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Strategy:       ConsistentHash(),
+	})
+	defer list.Close()
+
+	list.Add(newHealthyService("https://1gateway.fm"))
+	list.Add(newHealthyService("https://2gateway.fm"))
+
+	cache := NewResultCache(&ResultCacheOpts{Pool: "testServicesList", List: list})
+
+	var dispatched atomic.Int32
+	release := make(chan struct{})
+	dispatch := func(_ context.Context, srv service.IService) ([]byte, error) {
+		dispatched.Add(1)
+		<-release
+		return []byte(srv.ID()), nil
+	}
+
+	// concurrent requests of the same job are dispatched once
+	var wg sync.WaitGroup
+	results := make([][]byte, 3)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = cache.Do(context.Background(), "job-1", dispatch)
+		}()
+	}
+	waitFor(t, func() bool {
+		return dispatched.Load() == 1
+	})
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if dispatched.Load() != 1 || string(results[0]) != string(results[2]) {
+		t.Fatalf("job should be dispatched once, got %d dispatches", dispatched.Load())
+	}
+
+	// repeated request is answered from the cache
+	result, err := cache.Do(context.Background(), "job-1", dispatch)
+	if err != nil || string(result) != string(results[0]) || dispatched.Load() != 1 {
+		t.Errorf("repeated job should be answered from cache, got %s %v", result, err)
+	}
+
+	// failed results are not cached
+	failing := func(context.Context, service.IService) ([]byte, error) {
+		return nil, errors.New("prover error")
+	}
+	if _, err := cache.Do(context.Background(), "job-2", failing); err == nil {
+		t.Errorf("expected dispatch error")
+	}
+	if _, ok, _ := cache.opts.Storage.Get(context.Background(), "job-2"); ok {
+		t.Errorf("failed result should not be cached")
+	}
+
+	if err := cache.Forget(context.Background(), "job-1"); err != nil {
+		t.Fatalf("unexpected forget error: %s", err)
+	}
+	if _, ok, _ := cache.opts.Storage.Get(context.Background(), "job-1"); ok {
+		t.Errorf("forgotten result should not be cached")
+	}
+}
+
+func TestMemoryResultStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewMemoryResultStorage(2)
+
+	_ = storage.Set(ctx, "a", []byte("a"), 0)
+	_ = storage.Set(ctx, "b", []byte("b"), 0)
+	_, _, _ = storage.Get(ctx, "a")
+	_ = storage.Set(ctx, "c", []byte("c"), 0)
+
+	if _, ok, _ := storage.Get(ctx, "b"); ok {
+		t.Errorf("the least recently used result should be evicted")
+	}
+	if _, ok, _ := storage.Get(ctx, "a"); !ok {
+		t.Errorf("recently used result should be kept")
+	}
+
+	_ = storage.Set(ctx, "d", []byte("d"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := storage.Get(ctx, "d"); ok {
+		t.Errorf("expired result should not be returned")
+	}
+}
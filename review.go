@@ -0,0 +1,351 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	defaultReviewWebhookTimeout       = 10 * time.Second
+	defaultReviewWebhookRetries       = 3
+	defaultReviewWebhookRetryInterval = time.Second
+)
+
+// ReviewPolicy is options that configure quarantine
+// of services which flap or fail verification too often.
+// Quarantined services are not healthchecked and can be
+// released only by manual approval or webhook response
+type ReviewPolicy struct {
+	FlapThreshold                int           // number of healthy to jail transitions within Window to quarantine service (0 to disable)
+	VerificationFailureThreshold int           // number of reported verification failures within Window to quarantine service (0 to disable)
+	Window                       time.Duration // sliding window for flaps and verification failures counting
+	WebhookURL                   string        // optional url to send review requests to
+	WebhookTimeout               time.Duration // review webhook request timeout (10 seconds by default)
+	WebhookToken                 string        // bearer token sent with review requests, so the webhook could authenticate the list (empty to send none)
+	WebhookRetries               int           // retries of review request after delivery failure or unsuccessful status (3 by default, negative to disable)
+	WebhookRetryInterval         time.Duration // interval before the first retry (1 second by default)
+	WebhookBackoff               *BackoffOpts  // growth of interval between retries (doubling up to 5m by default)
+}
+
+// ReviewItem is service that waits
+// for manual review in quarantine
+type ReviewItem struct {
+	Service service.IService
+	Reason  string
+	Since   time.Time
+}
+
+// reviewRequest is body of review webhook request
+type reviewRequest struct {
+	ID       string    `json:"id"`
+	Address  string    `json:"address"`
+	NodeName string    `json:"node_name"`
+	Reason   string    `json:"reason"`
+	Since    time.Time `json:"since"`
+}
+
+// reviewResponse is body of review webhook response
+type reviewResponse struct {
+	Approved bool `json:"approved"`
+}
+
+// UnderReview return slice of all services
+// waiting for review in quarantine
+func (l *ServicesList) UnderReview() []ReviewItem {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	items := make([]ReviewItem, 0, len(l.review))
+	for _, item := range l.review {
+		items = append(items, *item)
+	}
+
+	return items
+}
+
+// Approve release service with given id from quarantine
+// to jail, so it can be up by the regular try up mechanics
+func (l *ServicesList) Approve(id string) error {
+	l.mu.Lock()
+
+	item, ok := l.review[id]
+	if !ok {
+		l.mu.Unlock()
+		return ErrServiceNotFound{ID: id}
+	}
+
+	delete(l.review, id)
+	delete(l.flaps, id)
+	delete(l.verificationFailures, id)
+	l.toJail(item.Service)
+	l.bumpGeneration()
+
+	l.mu.Unlock()
+
+	l.log().Info("service is approved and released from quarantine to jail", "service", id, "node", item.Service.NodeName())
+
+	l.goTryUp(item.Service)
+
+	return nil
+}
+
+// Reject remove service with given
+// id from quarantine and close it
+func (l *ServicesList) Reject(id string) error {
+	l.mu.Lock()
+
+	item, ok := l.review[id]
+	if !ok {
+		l.mu.Unlock()
+		return ErrServiceNotFound{ID: id}
+	}
+
+	delete(l.review, id)
+	delete(l.flaps, id)
+	delete(l.verificationFailures, id)
+	l.releaseMember(item.Service, "rejected")
+	l.bumpGeneration()
+
+	l.mu.Unlock()
+
+	l.flushEvents()
+
+	l.log().Info("service is rejected and removed from quarantine", "service", id, "node", item.Service.NodeName())
+
+	if err := item.Service.Close(); err != nil {
+		l.log().Warn("unexpected error during service Close()", "service", id, "error", err)
+	}
+
+	return nil
+}
+
+// ReportVerificationFailure register failed verification
+// of given service result and quarantine the service if
+// verification failures threshold is reached
+func (l *ServicesList) ReportVerificationFailure(srv service.IService) {
+	if l.reviewPolicy == nil || l.reviewPolicy.VerificationFailureThreshold == 0 {
+		return
+	}
+
+	l.mu.Lock()
+
+	// late report of removed service should not bring it back
+	if _, ok := l.tombstones.lookup(srv.ID()); ok && l.member(srv.ID()) == nil {
+		l.mu.Unlock()
+		l.log().Warn("verification failure of removed service is ignored", "service", srv.ID())
+		return
+	}
+
+	failures := l.appendHistory(l.verificationFailures[srv.ID()])
+	l.verificationFailures[srv.ID()] = failures
+
+	var item *ReviewItem
+	if len(failures) >= l.reviewPolicy.VerificationFailureThreshold {
+		item = l.quarantine(srv, fmt.Sprintf("%d verification failures within %s", len(failures), l.reviewPolicy.Window))
+	}
+
+	l.mu.Unlock()
+
+	if item != nil {
+		l.goTask(taskReview, func() {
+			l.requestReview(item)
+		})
+	}
+}
+
+// recordFlap register healthy to jail transition of given
+// service and check if flaps threshold is reached. Should be
+// called under the list lock
+func (l *ServicesList) recordFlap(srv service.IService) bool {
+	if l.reviewPolicy == nil || l.reviewPolicy.FlapThreshold == 0 {
+		return false
+	}
+
+	flaps := l.appendHistory(l.flaps[srv.ID()])
+	l.flaps[srv.ID()] = flaps
+
+	return len(flaps) >= l.reviewPolicy.FlapThreshold
+}
+
+// quarantine move given service from healthy or jail
+// to review map. Should be called under the list lock
+func (l *ServicesList) quarantine(srv service.IService, reason string) *ReviewItem {
+	if item, ok := l.review[srv.ID()]; ok {
+		return item
+	}
+
+	for i, s := range l.healthy {
+		if s.ID() == srv.ID() {
+			l.removeFromHealthy(i)
+			break
+		}
+	}
+	l.fromJail(srv.ID())
+	l.setStatus(srv, service.StatusUnHealthy)
+
+	item := &ReviewItem{
+		Service: srv,
+		Reason:  reason,
+		Since:   time.Now(),
+	}
+	l.review[srv.ID()] = item
+	l.bumpGeneration()
+
+	l.log().Warn("service is quarantined and needs review", "service", srv.ID(), "node", srv.NodeName(), "reason", reason)
+
+	return item
+}
+
+// isServiceUnderReview check if service exist in review map
+func (l *ServicesList) isServiceUnderReview(srv service.IService) bool {
+	if srv == nil {
+		return false
+	}
+
+	_, ok := l.review[srv.ID()]
+	return ok
+}
+
+// requestReview send review request to the configured
+// webhook and approve service if webhook respond with
+// positive decision. Failed deliveries are retried with
+// backoff while the service is still under review
+func (l *ServicesList) requestReview(item *ReviewItem) {
+	if l.reviewPolicy.WebhookURL == "" {
+		return
+	}
+
+	id := item.Service.ID()
+
+	body, err := json.Marshal(&reviewRequest{
+		ID:       id,
+		Address:  item.Service.Address(),
+		NodeName: item.Service.NodeName(),
+		Reason:   item.Reason,
+		Since:    item.Since,
+	})
+	if err != nil {
+		l.log().Warn("marshal review request", "service", id, "error", err)
+		return
+	}
+
+	retries := l.reviewPolicy.WebhookRetries
+	if retries == 0 {
+		retries = defaultReviewWebhookRetries
+	}
+
+	interval := l.reviewPolicy.WebhookRetryInterval
+	if interval <= 0 {
+		interval = defaultReviewWebhookRetryInterval
+	}
+
+	backoff := newBackoff(l.reviewPolicy.WebhookBackoff)
+	if backoff == nil {
+		backoff = newBackoff(&BackoffOpts{})
+	}
+
+	for try := 1; ; try++ {
+		approved, err := l.sendReview(body)
+		if err == nil && !approved {
+			l.log().Info("review webhook did not approve service, waiting for manual review", "service", id)
+			return
+		}
+
+		if err == nil {
+			if err := l.Approve(id); err != nil {
+				l.log().Warn("approve service by review webhook", "service", id, "error", err)
+			}
+			return
+		}
+
+		if try > retries {
+			l.log().Warn("review request is not delivered, waiting for manual review", "service", id, "tries", try, "error", err)
+			return
+		}
+
+		l.log().Warn("review request is not delivered, retrying", "service", id, "try", try, "error", err)
+
+		select {
+		case <-time.After(backoff.interval(interval, try)):
+		case <-l.ctx.Done():
+			return
+		}
+
+		// service could be approved or rejected meanwhile
+		if !l.pendingReview(id) {
+			return
+		}
+	}
+}
+
+// sendReview send given review request to the configured
+// webhook and report if it approves the service
+func (l *ServicesList) sendReview(body []byte) (bool, error) {
+	timeout := l.reviewPolicy.WebhookTimeout
+	if timeout == 0 {
+		timeout = defaultReviewWebhookTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(l.ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.reviewPolicy.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("create review request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if l.reviewPolicy.WebhookToken != "" {
+		req.Header.Set("Authorization", "Bearer "+l.reviewPolicy.WebhookToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("send review request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("review webhook respond with status %d", resp.StatusCode)
+	}
+
+	var decision reviewResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return false, fmt.Errorf("decode review response: %w", err)
+	}
+
+	return decision.Approved, nil
+}
+
+// pendingReview check if service with
+// given id is still waiting for review
+func (l *ServicesList) pendingReview(id string) bool {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	_, ok := l.review[id]
+	return ok
+}
+
+// trimToWindow return given timestamps
+// that are not older than given window
+func trimToWindow(timestamps []time.Time, window time.Duration) []time.Time {
+	if window == 0 {
+		return timestamps
+	}
+
+	threshold := time.Now().Add(-window)
+	for i, t := range timestamps {
+		if t.After(threshold) {
+			return timestamps[i:]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,222 @@
+package pool
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServicesListReviewFlaps(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		ReviewPolicy: &ReviewPolicy{
+			FlapThreshold: 2,
+			Window:        time.Minute,
+		},
+	})
+
+	srv := &healthyService{0, service.NewService("https://1gateway.fm", "", nil, 1.0).(*service.BaseService)}
+
+	list.Add(srv)
+	list.FromHealthyToJail(srv.ID())
+	list.FromJailToHealthy(srv)
+	list.FromHealthyToJail(srv.ID())
+
+	if len(list.UnderReview()) != 1 {
+		t.Fatalf("expected service to be under review")
+	}
+
+	if len(list.Healthy()) != 0 || len(list.Jailed()) != 0 {
+		t.Errorf("service under review should not be healthy or jailed")
+	}
+
+	if !list.IsServiceExists(srv) {
+		t.Errorf("service under review should exist in list")
+	}
+
+	if err := list.Approve("unknown"); err == nil {
+		t.Errorf("expected error on approve of unknown service")
+	}
+
+	if err := list.Approve(srv.ID()); err != nil {
+		t.Fatalf("unexpected approve error: %s", err)
+	}
+
+	if len(list.UnderReview()) != 0 {
+		t.Errorf("approved service should not be under review")
+	}
+}
+
+func TestServicesListReviewVerificationFailures(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		ReviewPolicy: &ReviewPolicy{
+			VerificationFailureThreshold: 3,
+			Window:                       time.Minute,
+		},
+	})
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	list.ReportVerificationFailure(srv)
+	list.ReportVerificationFailure(srv)
+
+	if len(list.UnderReview()) != 0 {
+		t.Fatalf("service should not be under review before threshold")
+	}
+
+	list.ReportVerificationFailure(srv)
+
+	if len(list.UnderReview()) != 1 || len(list.Healthy()) != 0 {
+		t.Fatalf("service should be moved to review after threshold")
+	}
+
+	handler := NewAdminHandlerWithOpts(list, &AdminHandlerOpts{Unauthenticated: true})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/review/"+srv.ID()+"/reject", nil))
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("unexpected reject status %d", rec.Code)
+	}
+
+	if list.CountAll() != 0 {
+		t.Errorf("rejected service should be removed from list")
+	}
+}
+
+func TestServicesListReviewWebhookRetries(t *testing.T) {
+	var (
+		requests atomic.Int32
+		tokens   = make(chan string, 10)
+	)
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens <- r.Header.Get("Authorization")
+
+		// the first two deliveries fail
+		if requests.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(reviewResponse{Approved: true})
+	}))
+	defer webhook.Close()
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		ReviewPolicy: &ReviewPolicy{
+			VerificationFailureThreshold: 1,
+			Window:                       time.Minute,
+			WebhookURL:                   webhook.URL,
+			WebhookToken:                 "secret",
+			WebhookRetryInterval:         10 * time.Millisecond,
+		},
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+	list.ReportVerificationFailure(srv)
+
+	waitFor(t, func() bool { return len(list.UnderReview()) == 0 && list.CountAll() == 1 })
+
+	if requests.Load() != 3 {
+		t.Errorf("expected 3 review requests, got %d", requests.Load())
+	}
+
+	for i := 0; i < 3; i++ {
+		if token := <-tokens; token != "Bearer secret" {
+			t.Errorf("review request should carry bearer token, got %q", token)
+		}
+	}
+}
+
+func TestServicesListReviewWebhookGivesUp(t *testing.T) {
+	var requests atomic.Int32
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer webhook.Close()
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		ReviewPolicy: &ReviewPolicy{
+			VerificationFailureThreshold: 1,
+			Window:                       time.Minute,
+			WebhookURL:                   webhook.URL,
+			WebhookRetries:               2,
+			WebhookRetryInterval:         10 * time.Millisecond,
+		},
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+	list.ReportVerificationFailure(srv)
+
+	waitFor(t, func() bool { return requests.Load() == 3 })
+
+	// no more retries, the service waits for manual review
+	time.Sleep(100 * time.Millisecond)
+	if requests.Load() != 3 || len(list.UnderReview()) != 1 {
+		t.Errorf("expected 3 review requests and service under review, got %d requests", requests.Load())
+	}
+}
+
+func TestReviewApprovalAuthorization(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		ReviewPolicy: &ReviewPolicy{
+			VerificationFailureThreshold: 1,
+			Window:                       time.Minute,
+		},
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+	list.ReportVerificationFailure(srv)
+
+	handler := NewAdminHandlerWithOpts(list, &AdminHandlerOpts{Authorize: BearerTokenAuth("secret")})
+
+	for _, action := range []string{"approve", "reject"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/review/"+srv.ID()+"/"+action, nil))
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s without token should be refused, got %d", action, rec.Code)
+		}
+	}
+
+	if len(list.UnderReview()) != 1 {
+		t.Fatalf("unauthorized requests should not release the service")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/review/"+srv.ID()+"/approve", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || len(list.UnderReview()) != 0 {
+		t.Errorf("authorized approve should release the service, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,195 @@
+package pool
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// ScheduledCheck is additional check of healthy services run on
+// its own schedule next to the healthcheck run every checks
+// interval, e.g. synthetic proof probe run nightly or deep
+// capacity check run every 10 minutes
+type ScheduledCheck struct {
+	Name     string                           // check name used in logs
+	Schedule ISchedule                        // calendar of check runs, e.g. Every(10*time.Minute) or MustParseCron("@nightly")
+	Check    func(srv service.IService) error // check of one service, failed service is moved to the jail
+}
+
+// failedChecks is scheduled checks failed by jailed services,
+// they are run again before the service is recovered
+type failedChecks struct {
+	mu     sync.Mutex
+	checks map[string][]ScheduledCheck // service id -> failed checks
+}
+
+// newFailedChecks create empty failed checks set
+func newFailedChecks() *failedChecks {
+	return &failedChecks{checks: make(map[string][]ScheduledCheck)}
+}
+
+// add register given check failed by service with given id
+func (f *failedChecks) add(id string, check ScheduledCheck) {
+	defer f.mu.Unlock()
+	f.mu.Lock()
+
+	for _, failed := range f.checks[id] {
+		if failed.Name == check.Name {
+			return
+		}
+	}
+	f.checks[id] = append(f.checks[id], check)
+}
+
+// recheck run again checks failed by given service, passed checks
+// are forgotten and the first error of others is returned
+func (f *failedChecks) recheck(srv service.IService) error {
+	f.mu.Lock()
+	checks := f.checks[srv.ID()]
+	f.mu.Unlock()
+
+	var (
+		failed  []ScheduledCheck
+		lastErr error
+	)
+	for _, check := range checks {
+		if err := check.Check(srv); err != nil {
+			failed = append(failed, check)
+			if lastErr == nil {
+				lastErr = fmt.Errorf("scheduled check %s: %w", check.Name, err)
+			}
+		}
+	}
+
+	f.mu.Lock()
+	if len(failed) == 0 {
+		delete(f.checks, srv.ID())
+	} else {
+		f.checks[srv.ID()] = failed
+	}
+	f.mu.Unlock()
+
+	return lastErr
+}
+
+// forget remove failed checks of service with given id
+func (f *failedChecks) forget(id string) {
+	defer f.mu.Unlock()
+	f.mu.Lock()
+
+	delete(f.checks, id)
+}
+
+// startScheduledChecks run configured scheduled checks on the
+// shared scheduler or on own goroutines until the list is stopped
+func (l *ServicesList) startScheduledChecks() {
+	for _, check := range l.checks {
+		if l.scheduler != nil {
+			l.goTask(taskScheduledCheck, func() {
+				l.scheduleCheck(check)
+			})
+			continue
+		}
+
+		l.goTask(taskScheduledCheck, func() {
+			l.scheduledCheckLoop(check)
+		})
+	}
+}
+
+// scheduledCheckLoop run given check on
+// its schedule until the list is stopped
+func (l *ServicesList) scheduledCheckLoop(check ScheduledCheck) {
+	for {
+		next := check.Schedule.Next(time.Now())
+		if next.IsZero() {
+			l.log().Warn("scheduled check has no next run, it is stopped", "check", check.Name)
+			return
+		}
+
+		Sleep(time.Until(next), l.Stop)
+
+		select {
+		case <-l.Stop:
+			return
+		default:
+		}
+
+		if !l.Paused() {
+			l.runScheduledCheck(check)
+		}
+	}
+}
+
+// scheduleCheck run given check on the shared
+// scheduler until the list is stopped
+func (l *ServicesList) scheduleCheck(check ScheduledCheck) {
+	next := check.Schedule.Next(time.Now())
+	if next.IsZero() {
+		l.log().Warn("scheduled check has no next run, it is stopped", "check", check.Name)
+		return
+	}
+
+	cancel := l.scheduler.scheduleAfter(time.Until(next), func() time.Duration {
+		if !l.Paused() {
+			doLabeled(l.serviceName, taskScheduledCheck, func() {
+				l.runScheduledCheck(check)
+			})
+		}
+
+		// schedule without next run is re-evaluated daily
+		next := check.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return 24 * time.Hour
+		}
+
+		return time.Until(next)
+	})
+
+	<-l.Stop
+	cancel()
+}
+
+// runScheduledCheck run given check for all healthy services
+// and move failed services to the jail
+func (l *ServicesList) runScheduledCheck(check ScheduledCheck) {
+	l.log().Info("run scheduled check", "check", check.Name)
+
+	degraded := l.checkDependencies()
+
+	for _, srv := range l.Healthy() {
+		err := check.Check(srv)
+		if err == nil {
+			continue
+		}
+
+		l.log().Warn("scheduled check error", "check", check.Name, "service", srv.ID(), "node", srv.NodeName(), "error", err)
+
+		// failures are caused by the dependency
+		// rather than by the service itself
+		if degraded {
+			continue
+		}
+
+		// service is not recovered until the check passes again
+		l.failedChecks.add(srv.ID(), check)
+
+		l.goTask(taskTryUp, func() {
+			l.jailAndTryUp(srv)
+		})
+	}
+}
+
+// jailAndTryUp move given failed service to the jail and start
+// trying to up it, service that is already moved is skipped
+func (l *ServicesList) jailAndTryUp(srv service.IService) {
+	if l.fromHealthyToJail(srv.ID()) == nil {
+		return
+	}
+	l.emit(PoolEvent{Type: EventServiceJailed, Service: srv})
+
+	l.log().Warn("service added to jail", "service", srv.ID(), "node", srv.NodeName())
+	l.TryUpService(srv, 0)
+}
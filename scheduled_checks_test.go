@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestScheduledChecks(t *testing.T) {
+	var (
+		failing atomic.Bool
+		runs    atomic.Int32
+	)
+	failing.Store(true)
+
+	srv := newHealthyService("https://1gateway.fm")
+	other := newHealthyService("https://2gateway.fm")
+
+	for _, scheduler := range []*Scheduler{nil, NewScheduler(&SchedulerOpts{Tick: 5 * time.Millisecond})} {
+		failing.Store(true)
+
+		list := newServicesList("testServicesList", &ServicesListOpts{
+			TryUpTries:     0,
+			TryUpInterval:  10 * time.Millisecond,
+			ChecksInterval: time.Minute,
+			Scheduler:      scheduler,
+			Checks: []ScheduledCheck{{
+				Name:     "deep",
+				Schedule: Every(20 * time.Millisecond),
+				Check: func(s service.IService) error {
+					runs.Add(1)
+					if s.ID() == srv.ID() && failing.Load() {
+						return errors.New("capacity check failed")
+					}
+					return nil
+				},
+			}},
+		})
+
+		list.Add(srv)
+		list.Add(other)
+
+		go list.HealthChecksLoop()
+
+		waitFor(t, func() bool { return len(list.Jailed()) == 1 })
+
+		// cheap healthcheck passes but service is held until the check passes
+		time.Sleep(50 * time.Millisecond)
+		if _, ok := list.Jailed()[srv.ID()]; !ok {
+			t.Fatalf("service failed scheduled check should be kept in the jail")
+		}
+
+		failing.Store(false)
+		waitFor(t, func() bool { return len(list.Healthy()) == 2 })
+
+		list.Close()
+		if scheduler != nil {
+			scheduler.Close()
+		}
+	}
+
+	if runs.Load() == 0 {
+		t.Errorf("scheduled check should be run")
+	}
+}
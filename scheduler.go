@@ -0,0 +1,157 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultSchedulerWorkers = 8
+	defaultSchedulerTick    = 100 * time.Millisecond
+)
+
+// SchedulerOpts is options that needs
+// to configure Scheduler instance
+type SchedulerOpts struct {
+	Workers int           // number of jobs run concurrently (8 by default)
+	Tick    time.Duration // how often due jobs are dispatched (100ms by default)
+}
+
+// Scheduler run periodic healthchecks and discovery jobs of
+// many pools on a bounded number of workers, so pools in one
+// process don't spawn own loops per pool
+type Scheduler struct {
+	tick time.Duration
+
+	jobs map[*scheduledJob]struct{}
+	work chan *scheduledJob
+
+	stop chan struct{}
+	once sync.Once
+
+	mu sync.Mutex
+}
+
+// scheduledJob is periodic job which run
+// function returns interval to the next run
+type scheduledJob struct {
+	run     func() time.Duration
+	next    time.Time
+	running bool
+}
+
+// NewScheduler create new Scheduler and
+// start its dispatcher and workers
+func NewScheduler(opts *SchedulerOpts) *Scheduler {
+	workers, tick := defaultSchedulerWorkers, defaultSchedulerTick
+	if opts != nil && opts.Workers > 0 {
+		workers = opts.Workers
+	}
+	if opts != nil && opts.Tick > 0 {
+		tick = opts.Tick
+	}
+
+	s := &Scheduler{
+		tick: tick,
+		jobs: make(map[*scheduledJob]struct{}),
+		work: make(chan *scheduledJob),
+		stop: make(chan struct{}),
+	}
+
+	for i := 0; i < workers; i++ {
+		goLabeled("", taskScheduler, s.worker)
+	}
+	goLabeled("", taskScheduler, s.dispatch)
+
+	return s
+}
+
+// Close stop scheduler dispatcher and workers,
+// running jobs are finished
+func (s *Scheduler) Close() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+// schedule add given periodic job which is run immediately
+// and returns function that removes the job
+func (s *Scheduler) schedule(run func() time.Duration) func() {
+	return s.scheduleAfter(0, run)
+}
+
+// scheduleAfter add given periodic job which is first run after
+// given delay and returns function that removes the job
+func (s *Scheduler) scheduleAfter(delay time.Duration, run func() time.Duration) func() {
+	job := &scheduledJob{
+		run:  run,
+		next: time.Now().Add(delay),
+	}
+
+	s.mu.Lock()
+	s.jobs[job] = struct{}{}
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		delete(s.jobs, job)
+		s.mu.Unlock()
+	}
+}
+
+// dispatch send due jobs to workers every tick
+func (s *Scheduler) dispatch() {
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			for _, job := range s.due(now) {
+				select {
+				case s.work <- job:
+				case <-s.stop:
+					return
+				}
+			}
+		}
+	}
+}
+
+// due return jobs that should be run at given
+// time and mark them as running
+func (s *Scheduler) due(now time.Time) []*scheduledJob {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	var jobs []*scheduledJob
+	for job := range s.jobs {
+		if job.running || job.next.After(now) {
+			continue
+		}
+
+		job.running = true
+		jobs = append(jobs, job)
+	}
+
+	return jobs
+}
+
+// worker run jobs and schedule theirs next run
+func (s *Scheduler) worker() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case job := <-s.work:
+			interval := job.run()
+
+			s.mu.Lock()
+			job.next = time.Now().Add(interval)
+			job.running = false
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,395 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/gateway-fm/prover-pool-lib/discovery"
+)
+
+// ConfigSchemaID is identifier of published pool configuration schema
+const ConfigSchemaID = "https://github.com/gateway-fm/prover-pool-lib/schema/pool-config.schema.json"
+
+const (
+	durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	boolPattern     = `^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$`
+	numberPattern   = `^[0-9]+(\.[0-9]+)?$`
+	portPattern     = `^[0-9]{1,5}$`
+)
+
+// schemaObject is json schema node
+type schemaObject = map[string]interface{}
+
+// ConfigSchema return json schema of pool configuration, which is
+// list of pool specs in json or yaml as read by KubernetesLoader.
+// All registered discovery drivers and balancing strategies are
+// included, settings of built-in drivers are checked per driver
+func ConfigSchema() []byte {
+	data, err := json.MarshalIndent(configSchema(), "", "  ")
+	if err != nil {
+		panic(fmt.Errorf("marshal config schema: %w", err))
+	}
+
+	return append(data, '\n')
+}
+
+// ValidateConfig validate pool configuration in json or yaml
+// against ConfigSchema, so that configs could be checked before
+// gateways are deployed. All violations are returned joined
+// as ErrInvalidConfig errors
+func ValidateConfig(data []byte) error {
+	var config interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return ErrInvalidConfig{Path: "/", Reason: err.Error()}
+	}
+
+	var schema schemaObject
+	if err := json.Unmarshal(ConfigSchema(), &schema); err != nil {
+		return fmt.Errorf("unmarshal config schema: %w", err)
+	}
+
+	errs := validateSchema(schema, config, "")
+
+	// uniqueness of names is not expressible by the schema
+	pools, _ := config.([]interface{})
+	names := make(map[string]int, len(pools))
+	for i, spec := range pools {
+		fields, _ := spec.(map[string]interface{})
+		name, ok := fields["name"].(string)
+		if !ok || name == "" {
+			continue
+		}
+
+		if first, ok := names[name]; ok {
+			errs = append(errs, ErrInvalidConfig{
+				Path:   fmt.Sprintf("/%d/name", i),
+				Reason: fmt.Sprintf("pool %q is already defined at /%d", name, first),
+			})
+			continue
+		}
+		names[name] = i
+	}
+
+	return errors.Join(errs...)
+}
+
+// configSchema build json schema of pool configuration
+func configSchema() schemaObject {
+	str := func(description string) schemaObject {
+		return schemaObject{"type": "string", "description": description}
+	}
+	duration := func(description string) schemaObject {
+		return schemaObject{"type": "string", "pattern": durationPattern, "description": description}
+	}
+	list := func(description string) schemaObject {
+		return schemaObject{"type": "array", "items": schemaObject{"type": "string", "minLength": 1}, "description": description}
+	}
+
+	var balancing []string
+	for _, b := range []Balancing{BalancingRoundRobin, BalancingWeightedRoundRobin, BalancingLeastConnections} {
+		balancing = append(balancing, b.String())
+	}
+
+	spec := schemaObject{
+		"type":                 "object",
+		"required":             []string{"name", "driver"},
+		"additionalProperties": false,
+		"properties": schemaObject{
+			"name":                   schemaObject{"type": "string", "minLength": 1, "description": "pool name, it is service name given to discovery"},
+			"driver":                 schemaObject{"type": "string", "enum": discovery.Drivers(), "description": "discovery driver"},
+			"addresses":              list("driver endpoints"),
+			"path":                   str("file path of static and replay drivers"),
+			"prefix":                 str("key prefix of etcd driver"),
+			"tags":                   list("tags added to discovered services"),
+			"params":                 schemaObject{"type": "object", "additionalProperties": schemaObject{"type": "string"}, "description": "driver specific settings"},
+			"timeout":                duration("discovery request timeout"),
+			"balancing":              schemaObject{"type": "string", "enum": balancing, "description": "balancing strategy (round_robin by default)"},
+			"discoveryInterval":      duration("rediscovery interval (30s by default)"),
+			"checksInterval":         duration("healthchecks interval (10s by default)"),
+			"tryUpInterval":          duration("interval of tries to up jailed services (10s by default)"),
+			"tryUpTries":             schemaObject{"type": "integer", "minimum": 0, "description": "number of tries to up jailed services (0 for infinity tries)"},
+			"shedThreshold":          schemaObject{"type": "number", "minimum": 0, "exclusiveMaximum": 1, "description": "saturation low priority requests are shed above (0 to disable shedding)"},
+			"pruneMissing":           schemaObject{"type": "boolean", "description": "remove services missing in discovery results"},
+			"overprovisioningFactor": schemaObject{"type": "integer", "minimum": 0, "description": "Envoy overprovisioning factor of priority levels in percents, e.g. 140 (0 to disable priority levels)"},
+			"timeouts": schemaObject{
+				"type":                 "object",
+				"additionalProperties": false,
+				"description":          "deadlines of pool operations, unset ones are inherited from defaults",
+				"properties": schemaObject{
+					"discovery": duration("one discovery round, shorter than discovery interval (10s by default)"),
+					"check":     duration("one healthcheck of service, shorter than checks interval (5s by default)"),
+					"dial":      duration("connection to service, not longer than check and request timeouts (5s by default)"),
+					"request":   duration("one request proxied to service (10m by default)"),
+					"drain":     duration("work given to finish on shutdown (25s by default)"),
+				},
+			},
+			"features": schemaObject{"type": "object", "additionalProperties": schemaObject{"type": "boolean"}, "description": "initial state of feature flags, e.g. outlier_ejection, gossip_health or hedging"},
+		},
+		"allOf": driverRules(),
+	}
+
+	return schemaObject{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         ConfigSchemaID,
+		"title":       "prover pool configuration",
+		"description": "list of declarative pool specs",
+		"type":        "array",
+		"items":       spec,
+	}
+}
+
+// driverRules return settings checks of built-in drivers
+// applied when the driver is registered
+func driverRules() []schemaObject {
+	params := func(properties schemaObject) schemaObject {
+		return schemaObject{"properties": schemaObject{
+			"params": schemaObject{"properties": properties, "additionalProperties": false},
+		}}
+	}
+	required := func(field string) schemaObject {
+		return schemaObject{
+			"required":   []string{field},
+			"properties": schemaObject{field: schemaObject{"minLength": 1}},
+		}
+	}
+	str := schemaObject{"type": "string"}
+	pattern := func(pattern string) schemaObject {
+		return schemaObject{"type": "string", "pattern": pattern}
+	}
+
+	builtin := map[string][]schemaObject{
+		discovery.DriverConsul: {params(schemaObject{
+			"token":      str,
+			"datacenter": str,
+			"tag":        str,
+			"passing":    pattern(boolPattern),
+			"scheme":     str,
+		})},
+		discovery.DriverDNS: {params(schemaObject{
+			"record":  schemaObject{"type": "string", "enum": []string{"SRV", "A"}},
+			"service": str,
+			"proto":   str,
+			"port":    pattern(portPattern),
+			"scheme":  str,
+		})},
+		discovery.DriverEtcd:   {params(schemaObject{})},
+		discovery.DriverReplay: {required("path"), params(schemaObject{"speed": pattern(numberPattern)})},
+		discovery.DriverStatic: {required("path"), params(schemaObject{})},
+	}
+
+	var rules []schemaObject
+	for _, driver := range discovery.Drivers() {
+		for _, then := range builtin[driver] {
+			rules = append(rules, schemaObject{
+				"if": schemaObject{
+					"required":   []string{"driver"},
+					"properties": schemaObject{"driver": schemaObject{"const": driver}},
+				},
+				"then": then,
+			})
+		}
+	}
+
+	return rules
+}
+
+// validateSchema validate given value against given json schema
+// node at given json pointer path. Keywords used by ConfigSchema
+// are supported only
+func validateSchema(schema schemaObject, value interface{}, path string) []error {
+	invalid := func(format string, args ...interface{}) []error {
+		return []error{ErrInvalidConfig{Path: pathOrRoot(path), Reason: fmt.Sprintf(format, args...)}}
+	}
+
+	if typ, ok := schema["type"].(string); ok && !schemaType(typ, value) {
+		return invalid("expected %s, got %s", typ, valueType(value))
+	}
+
+	if expected, ok := schema["const"]; ok && !reflect.DeepEqual(expected, value) {
+		return invalid("expected %v", expected)
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			found = found || reflect.DeepEqual(allowed, value)
+		}
+		if !found {
+			allowed := make([]string, len(enum))
+			for i, v := range enum {
+				allowed[i] = fmt.Sprint(v)
+			}
+			return invalid("%v is not one of %s", value, strings.Join(allowed, ", "))
+		}
+	}
+
+	var errs []error
+
+	switch v := value.(type) {
+	case string:
+		if minLength, ok := schema["minLength"].(float64); ok && float64(len([]rune(v))) < minLength {
+			errs = append(errs, invalid("should not be shorter than %v", minLength)...)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(v) {
+			errs = append(errs, invalid("%q does not match %s", v, pattern)...)
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(schemaObject); ok {
+			for i, item := range v {
+				errs = append(errs, validateSchema(items, item, fmt.Sprintf("%s/%d", path, i))...)
+			}
+		}
+	case map[string]interface{}:
+		errs = append(errs, validateObject(schema, v, path)...)
+	default:
+		if number, ok := toNumber(v); ok {
+			if minimum, ok := schema["minimum"].(float64); ok && number < minimum {
+				errs = append(errs, invalid("%v is less than %v", number, minimum)...)
+			}
+			if maximum, ok := schema["exclusiveMaximum"].(float64); ok && number >= maximum {
+				errs = append(errs, invalid("%v is not less than %v", number, maximum)...)
+			}
+		}
+	}
+
+	for _, rule := range schemaList(schema["allOf"]) {
+		if cond, ok := rule["if"].(schemaObject); ok {
+			if len(validateSchema(cond, value, path)) != 0 {
+				continue
+			}
+			if then, ok := rule["then"].(schemaObject); ok {
+				errs = append(errs, validateSchema(then, value, path)...)
+			}
+			continue
+		}
+
+		errs = append(errs, validateSchema(rule, value, path)...)
+	}
+
+	return errs
+}
+
+// validateObject validate fields of given object against
+// properties keywords of given json schema node
+func validateObject(schema schemaObject, value map[string]interface{}, path string) []error {
+	var errs []error
+
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, field := range required {
+			if _, ok := value[field.(string)]; !ok {
+				errs = append(errs, ErrInvalidConfig{Path: pathOrRoot(path), Reason: fmt.Sprintf("missing required field %q", field)})
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(schemaObject)
+
+	fields := make([]string, 0, len(value))
+	for field := range value {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		fieldPath := path + "/" + field
+
+		if property, ok := properties[field].(schemaObject); ok {
+			errs = append(errs, validateSchema(property, value[field], fieldPath)...)
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errs = append(errs, ErrInvalidConfig{Path: fieldPath, Reason: "unknown field"})
+			}
+		case schemaObject:
+			errs = append(errs, validateSchema(additional, value[field], fieldPath)...)
+		}
+	}
+
+	return errs
+}
+
+// schemaList return list of json schema nodes
+func schemaList(v interface{}) []schemaObject {
+	list, _ := v.([]interface{})
+
+	nodes := make([]schemaObject, 0, len(list))
+	for _, node := range list {
+		if obj, ok := node.(schemaObject); ok {
+			nodes = append(nodes, obj)
+		}
+	}
+
+	return nodes
+}
+
+// schemaType check if given value has given json schema type
+func schemaType(typ string, value interface{}) bool {
+	switch typ {
+	case "integer":
+		number, ok := toNumber(value)
+		return ok && number == math.Trunc(number)
+	case "number":
+		_, ok := toNumber(value)
+		return ok
+	default:
+		return valueType(value) == typ
+	}
+}
+
+// valueType return json schema type name of given decoded value
+func valueType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	if _, ok := toNumber(value); ok {
+		return "number"
+	}
+
+	return fmt.Sprintf("%T", value)
+}
+
+// toNumber return given decoded json or yaml number as float
+func toNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+
+	return 0, false
+}
+
+// pathOrRoot return given json pointer path, root path for empty one
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
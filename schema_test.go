@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateSchema = flag.Bool("update-schema", false, "update published pool config schema")
+
+func TestConfigSchemaFile(t *testing.T) {
+	path := filepath.Join("schema", "pool-config.schema.json")
+
+	if *updateSchema {
+		if err := os.WriteFile(path, ConfigSchema(), 0o644); err != nil {
+			t.Fatalf("unexpected write error: %s", err)
+		}
+	}
+
+	published, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected read error: %s", err)
+	}
+	if !bytes.Equal(published, ConfigSchema()) {
+		t.Errorf("published schema is outdated, run make schema")
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	valid := `
+- name: provers-a
+  driver: consul
+  addresses: [127.0.0.1:8500]
+  params:
+    passing: "true"
+  balancing: least_connections
+  checksInterval: 1m30s
+  shedThreshold: 0.9
+- name: provers-b
+  driver: static
+  path: /etc/provers.yaml
+  tryUpTries: 3
+`
+	if err := ValidateConfig([]byte(valid)); err != nil {
+		t.Errorf("unexpected error of valid config: %s", err)
+	}
+
+	if err := ValidateConfig([]byte(`[{"name": "provers", "driver": "dns", "params": {"record": "SRV", "port": "443"}}]`)); err != nil {
+		t.Errorf("unexpected error of valid json config: %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		path   string
+	}{
+		{"not list", `name: provers`, "/"},
+		{"missing driver", `[{"name": "provers"}]`, "/0"},
+		{"unknown driver", `[{"name": "provers", "driver": "zookeeper"}]`, "/0/driver"},
+		{"unknown field", `[{"name": "provers", "driver": "etcd", "interval": "1s"}]`, "/0/interval"},
+		{"unknown balancing", `[{"name": "provers", "driver": "etcd", "balancing": "fastest"}]`, "/0/balancing"},
+		{"invalid duration", `[{"name": "provers", "driver": "etcd", "checksInterval": "10 seconds"}]`, "/0/checksInterval"},
+		{"negative tries", `[{"name": "provers", "driver": "etcd", "tryUpTries": -1}]`, "/0/tryUpTries"},
+		{"fractional tries", `[{"name": "provers", "driver": "etcd", "tryUpTries": 1.5}]`, "/0/tryUpTries"},
+		{"shed threshold", `[{"name": "provers", "driver": "etcd", "shedThreshold": 1}]`, "/0/shedThreshold"},
+		{"static path", `[{"name": "provers", "driver": "static"}]`, "/0"},
+		{"replay speed", `[{"name": "provers", "driver": "replay", "path": "a.jsonl", "params": {"speed": "fast"}}]`, "/0/params/speed"},
+		{"dns record", `[{"name": "provers", "driver": "dns", "params": {"record": "AAAA"}}]`, "/0/params/record"},
+		{"consul param", `[{"name": "provers", "driver": "consul", "params": {"namespace": "prod"}}]`, "/0/params/namespace"},
+		{"duplicate name", `[{"name": "provers", "driver": "etcd"}, {"name": "provers", "driver": "etcd"}]`, "/1/name"},
+		{"timeout duration", `[{"name": "provers", "driver": "etcd", "timeouts": {"check": "5 seconds"}}]`, "/0/timeouts/check"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig([]byte(tt.config))
+
+			var invalid ErrInvalidConfig
+			if !errors.As(err, &invalid) {
+				t.Fatalf("expected invalid config error, got %v", err)
+			}
+			if invalid.Path != tt.path {
+				t.Errorf("expected violation at %s, got %s", tt.path, err)
+			}
+		})
+	}
+}
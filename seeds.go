@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"sync"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// addSeeds add given static services to the list, so the pool
+// could serve traffic before the first discovery round is done.
+// Seeds are checked concurrently in the background, so creation
+// of the pool is not blocked by slow or unreachable seeds
+func (p *ServicesPool) addSeeds(seeds []service.IService) {
+	if len(seeds) == 0 {
+		return
+	}
+
+	p.seeds = make(map[string]struct{}, len(seeds))
+	p.seeded = make(chan struct{})
+
+	admitted := make([]service.IService, 0, len(seeds))
+	for _, srv := range seeds {
+		if p.MutationFnc != nil {
+			var err error
+			if srv, err = p.MutationFnc(srv); err != nil {
+				p.log().Warn("mutate seed service error", "error", err)
+				continue
+			}
+		}
+
+		p.seeds[srv.ID()] = struct{}{}
+		admitted = append(admitted, srv)
+	}
+
+	p.loops.Add(1)
+	goLabeled(p.name, taskSeeds, func() {
+		defer p.loops.Done()
+		defer close(p.seeded)
+
+		var wg sync.WaitGroup
+		for _, srv := range admitted {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.list.Add(srv)
+			}()
+		}
+		wg.Wait()
+
+		p.log().Info("pool is seeded", "seeds", len(admitted))
+	})
+}
+
+// removeSeeds remove seed services with given
+// ids that are not confirmed by discovery
+func (p *ServicesPool) removeSeeds(ids map[string]struct{}) {
+	for id := range ids {
+		p.log().Info("seed service is not discovered and is removed", "service", id)
+
+		p.list.RemoveByID(id)
+	}
+}
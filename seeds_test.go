@@ -0,0 +1,82 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServicesPoolSeeds(t *testing.T) {
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name: "TestServicePool",
+		Discovery: &staticDiscovery{services: []service.IService{
+			newHealthyService("https://1gateway.fm"),
+			newHealthyService("https://3gateway.fm"),
+		}},
+		Seeds: []service.IService{
+			newHealthyService("https://1gateway.fm"),
+			newHealthyService("https://2gateway.fm"),
+		},
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	})
+
+	waitFor(t, func() bool { return pool.Count() == 2 })
+	if pool.NextService() == nil {
+		t.Fatalf("seed services should be served before discovery")
+	}
+
+	if err := pool.DiscoverServices(); err != nil {
+		t.Fatalf("unexpected discovery error: %s", err)
+	}
+
+	addresses := make(map[string]struct{})
+	for _, srv := range pool.List().Healthy() {
+		addresses[srv.Address()] = struct{}{}
+	}
+
+	if _, ok := addresses["https://2gateway.fm"]; ok || len(addresses) != 2 {
+		t.Errorf("seed missing in discovery should be removed, got %v", addresses)
+	}
+}
+
+func TestServicesPoolSeedsInBackground(t *testing.T) {
+	seeds := make([]service.IService, 0, 3)
+	for _, addr := range []string{"https://1gateway.fm", "https://2gateway.fm", "https://3gateway.fm"} {
+		seeds = append(seeds, newSlowService(addr, 200*time.Millisecond))
+	}
+
+	start := time.Now()
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name:      "TestServicePool",
+		Discovery: &staticDiscovery{},
+		Seeds:     seeds,
+		ListOpts: &ServicesListOpts{
+			TryUpTries:     5,
+			TryUpInterval:  1 * time.Second,
+			ChecksInterval: 1 * time.Second,
+		},
+	})
+	defer pool.Close()
+
+	// slow seeds are checked concurrently after the pool is created
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("pool creation should not wait for seed checks, took %s", elapsed)
+	}
+	waitFor(t, func() bool { return pool.Count() == 3 })
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("seeds should be checked concurrently, took %s", elapsed)
+	}
+
+	// empty discovery result doesn't confirm seeds removal
+	if err := pool.DiscoverServices(); err != nil {
+		t.Fatalf("unexpected discovery error: %s", err)
+	}
+	if pool.Count() != 3 {
+		t.Errorf("seeds should be kept after empty discovery, got %d", pool.Count())
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CapabilitiesMetadataKey is metadata key comma separated
+// capabilities are read from for services without explicit
+// capabilities, e.g. "plonk,groth16" from discovery metadata
+// or from healthcheck probe
+const CapabilitiesMetadataKey = "capabilities"
+
+// MaxCircuitSizeMetadataKey is metadata key max circuit size
+// is read from for services without explicit capabilities
+const MaxCircuitSizeMetadataKey = "max_circuit_size"
+
+// MaxInFlightMetadataKey is metadata key max number of
+// requests service runs at once is read from, e.g. "2"
+// for prover running two proofs at most
+const MaxInFlightMetadataKey = "max_in_flight"
+
+// ICapableService is implemented by services that
+// advertise proof systems and circuit size they handle
+type ICapableService interface {
+	IService
+
+	// Capabilities return names of capabilities, e.g. proof systems
+	Capabilities() []string
+
+	// MaxCircuitSize return max circuit size, 0 for unlimited
+	MaxCircuitSize() int
+}
+
+// Capabilities return capabilities of given service: its
+// explicit capabilities or capabilities from its metadata
+func Capabilities(srv IService) []string {
+	if capable, ok := srv.(ICapableService); ok {
+		return capable.Capabilities()
+	}
+
+	var capabilities []string
+	for _, capability := range strings.Split(Metadata(srv)[CapabilitiesMetadataKey], ",") {
+		if capability = strings.TrimSpace(capability); capability != "" {
+			capabilities = append(capabilities, capability)
+		}
+	}
+
+	return capabilities
+}
+
+// HasCapability check if given service advertises
+// given capability, case is ignored
+func HasCapability(srv IService, capability string) bool {
+	for _, c := range Capabilities(srv) {
+		if strings.EqualFold(c, capability) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MaxCircuitSize return max circuit size of given service:
+// its explicit size, size from its metadata or 0 for unlimited
+func MaxCircuitSize(srv IService) int {
+	if capable, ok := srv.(ICapableService); ok {
+		return max(capable.MaxCircuitSize(), 0)
+	}
+
+	if size, err := strconv.Atoi(Metadata(srv)[MaxCircuitSizeMetadataKey]); err == nil && size > 0 {
+		return size
+	}
+
+	return 0
+}
+
+// MaxInFlight return max number of requests given service
+// runs at once read from its metadata, 0 for unlimited
+func MaxInFlight(srv IService) int {
+	if limit, err := strconv.Atoi(Metadata(srv)[MaxInFlightMetadataKey]); err == nil && limit > 0 {
+		return limit
+	}
+
+	return 0
+}
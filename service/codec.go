@@ -0,0 +1,317 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// ContentTypeJSON is content type of JSONCodec encoding
+	ContentTypeJSON = "application/json"
+
+	// ContentTypeProto is content type of ProtoCodec encoding
+	ContentTypeProto = "application/x-protobuf"
+)
+
+// ICodec encode and decode service records
+type ICodec interface {
+	// ContentType return content type of the encoding
+	ContentType() string
+
+	// Encode return encoded given records
+	Encode(records []Record) ([]byte, error)
+
+	// Decode return records decoded from given data
+	Decode(data []byte) ([]Record, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]ICodec{
+		ContentTypeJSON:  JSONCodec{},
+		ContentTypeProto: ProtoCodec{},
+	}
+)
+
+// RegisterCodec register given codec by its content type,
+// codec registered for the same content type is replaced
+func RegisterCodec(codec ICodec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[codec.ContentType()] = codec
+}
+
+// CodecFor return codec registered for given content type
+func CodecFor(contentType string) (ICodec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[contentType]
+	if !ok {
+		return nil, ErrUnsupportedCodec{ContentType: contentType}
+	}
+
+	return codec, nil
+}
+
+// JSONCodec encode records as json array
+type JSONCodec struct{}
+
+// ContentType return content type of the encoding
+func (JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+// Encode return encoded given records
+func (JSONCodec) Encode(records []Record) ([]byte, error) {
+	if records == nil {
+		records = []Record{}
+	}
+
+	return json.Marshal(records)
+}
+
+// Decode return records decoded from given data
+func (JSONCodec) Decode(data []byte) ([]Record, error) {
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("decode json records: %w", err)
+	}
+
+	return records, nil
+}
+
+// ProtoCodec encode records as Records
+// protobuf message described in record.proto
+type ProtoCodec struct{}
+
+// proto fields numbers of Record message
+const (
+	protoFieldID protowire.Number = iota + 1
+	protoFieldAddress
+	protoFieldNodeName
+	protoFieldTags
+	protoFieldAddresses
+	protoFieldStatus
+	protoFieldLoad
+	protoFieldMetadata
+)
+
+// proto fields numbers of metadata map entry
+const (
+	protoFieldEntryKey protowire.Number = iota + 1
+	protoFieldEntryValue
+)
+
+// protoFieldRecords is field number of Records.records
+const protoFieldRecords protowire.Number = 1
+
+// ContentType return content type of the encoding
+func (ProtoCodec) ContentType() string {
+	return ContentTypeProto
+}
+
+// Encode return encoded given records
+func (ProtoCodec) Encode(records []Record) ([]byte, error) {
+	var data []byte
+	for _, record := range records {
+		data = protowire.AppendTag(data, protoFieldRecords, protowire.BytesType)
+		data = protowire.AppendBytes(data, encodeProtoRecord(record))
+	}
+
+	return data, nil
+}
+
+// Decode return records decoded from given data
+func (ProtoCodec) Decode(data []byte) ([]Record, error) {
+	var records []Record
+
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != protoFieldRecords || typ != protowire.BytesType {
+			return nil
+		}
+
+		record, err := decodeProtoRecord(value)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("decode proto records: %w", err)
+	}
+
+	return records, nil
+}
+
+// encodeProtoRecord return Record message
+// encoding, empty fields are omitted
+func encodeProtoRecord(record Record) []byte {
+	var data []byte
+
+	appendString := func(num protowire.Number, value string) {
+		if value == "" {
+			return
+		}
+		data = protowire.AppendTag(data, num, protowire.BytesType)
+		data = protowire.AppendString(data, value)
+	}
+
+	appendString(protoFieldID, record.ID)
+	appendString(protoFieldAddress, record.Address)
+	appendString(protoFieldNodeName, record.NodeName)
+	for _, tag := range record.Tags {
+		data = protowire.AppendTag(data, protoFieldTags, protowire.BytesType)
+		data = protowire.AppendString(data, tag)
+	}
+	for _, address := range record.Addresses {
+		data = protowire.AppendTag(data, protoFieldAddresses, protowire.BytesType)
+		data = protowire.AppendString(data, address)
+	}
+	appendString(protoFieldStatus, record.Status)
+	if record.Load != 0 {
+		data = protowire.AppendTag(data, protoFieldLoad, protowire.Fixed32Type)
+		data = protowire.AppendFixed32(data, math.Float32bits(record.Load))
+	}
+
+	// map entries are sorted to keep encoding stable
+	keys := make([]string, 0, len(record.Metadata))
+	for key := range record.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		var entry []byte
+		entry = protowire.AppendTag(entry, protoFieldEntryKey, protowire.BytesType)
+		entry = protowire.AppendString(entry, key)
+		entry = protowire.AppendTag(entry, protoFieldEntryValue, protowire.BytesType)
+		entry = protowire.AppendString(entry, record.Metadata[key])
+
+		data = protowire.AppendTag(data, protoFieldMetadata, protowire.BytesType)
+		data = protowire.AppendBytes(data, entry)
+	}
+
+	return data
+}
+
+// decodeProtoRecord return Record decoded from given
+// message, unknown fields are skipped
+func decodeProtoRecord(data []byte) (Record, error) {
+	var record Record
+
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num == protoFieldLoad {
+			if typ != protowire.Fixed32Type {
+				return fmt.Errorf("unexpected type of field %d", num)
+			}
+
+			bits, n := protowire.ConsumeFixed32(value)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			record.Load = math.Float32frombits(bits)
+
+			return nil
+		}
+
+		if typ != protowire.BytesType {
+			return nil
+		}
+
+		switch num {
+		case protoFieldID:
+			record.ID = string(value)
+		case protoFieldAddress:
+			record.Address = string(value)
+		case protoFieldNodeName:
+			record.NodeName = string(value)
+		case protoFieldTags:
+			record.Tags = append(record.Tags, string(value))
+		case protoFieldAddresses:
+			record.Addresses = append(record.Addresses, string(value))
+		case protoFieldStatus:
+			record.Status = string(value)
+		case protoFieldMetadata:
+			key, value, err := decodeProtoEntry(value)
+			if err != nil {
+				return err
+			}
+
+			if record.Metadata == nil {
+				record.Metadata = make(map[string]string)
+			}
+			record.Metadata[key] = value
+		}
+
+		return nil
+	})
+
+	return record, err
+}
+
+// decodeProtoEntry return key and value
+// of given map entry message
+func decodeProtoEntry(data []byte) (string, string, error) {
+	var key, value string
+
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, field []byte) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+
+		switch num {
+		case protoFieldEntryKey:
+			key = string(field)
+		case protoFieldEntryValue:
+			value = string(field)
+		}
+
+		return nil
+	})
+
+	return key, value, err
+}
+
+// consumeProtoFields call given function for every field of given
+// message, value is field payload for length-delimited fields and
+// raw field encoding for others
+func consumeProtoFields(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		if typ == protowire.BytesType {
+			bytes, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return protowire.ParseError(m)
+			}
+			value, n = bytes, m
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value = data[:n]
+		}
+		data = data[n:]
+
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCodecsRoundTrip(t *testing.T) {
+	srv := NewService("https://1gateway.fm", "node", map[string]struct{}{"gpu": {}, "eu": {}}, 0.25)
+
+	records := []Record{
+		NewRecord(srv),
+		{ID: "2", Address: "https://2gateway.fm", Addresses: []string{"https://2gateway.fm", "https://3gateway.fm"}, Status: "healthy", Metadata: map[string]string{"zone": "eu", "circuit": "batch"}},
+	}
+
+	if !reflect.DeepEqual(records[0].Tags, []string{"eu", "gpu"}) {
+		t.Errorf("record tags should be sorted, got %v", records[0].Tags)
+	}
+
+	for _, contentType := range []string{ContentTypeJSON, ContentTypeProto} {
+		codec, err := CodecFor(contentType)
+		if err != nil {
+			t.Fatalf("unexpected codec error: %s", err)
+		}
+
+		data, err := codec.Encode(records)
+		if err != nil {
+			t.Fatalf("%s: unexpected encode error: %s", contentType, err)
+		}
+
+		decoded, err := codec.Decode(data)
+		if err != nil {
+			t.Fatalf("%s: unexpected decode error: %s", contentType, err)
+		}
+
+		if !reflect.DeepEqual(decoded, records) {
+			t.Errorf("%s: decoded records %+v differ from %+v", contentType, decoded, records)
+		}
+	}
+
+	restored := records[0].Service()
+	if restored.ID() != srv.ID() || !Equal(restored, srv) {
+		t.Errorf("service restored from record should be equal to the original")
+	}
+
+	var errCodec ErrUnsupportedCodec
+	if _, err := CodecFor("text/plain"); !errors.As(err, &errCodec) {
+		t.Errorf("expected unsupported codec error, got %v", err)
+	}
+
+	if _, err := (ProtoCodec{}).Decode([]byte{0x0a, 0x05}); err == nil {
+		t.Errorf("expected error for truncated proto message")
+	}
+}
@@ -0,0 +1,36 @@
+package service
+
+import "maps"
+
+// IEqualer is implemented by services that define own
+// equality used to detect changes of rediscovered services
+type IEqualer interface {
+	// Equal check if given service describe
+	// the same instance with the same metadata
+	Equal(other IService) bool
+}
+
+// Equal check if given services with the same ID describe
+// the same instance: services implementing IEqualer are
+// compared by it, others by address, node name, tags and static metadata
+func Equal(a, b IService) bool {
+	if equaler, ok := a.(IEqualer); ok {
+		return equaler.Equal(b)
+	}
+
+	if a.Address() != b.Address() || a.NodeName() != b.NodeName() {
+		return false
+	}
+
+	if len(a.Tags()) != len(b.Tags()) {
+		return false
+	}
+
+	for tag := range a.Tags() {
+		if _, ok := b.Tags()[tag]; !ok {
+			return false
+		}
+	}
+
+	return maps.Equal(StaticMetadata(a), StaticMetadata(b))
+}
@@ -12,3 +12,14 @@ type ErrUnsupportedStatus struct {
 func (e ErrUnsupportedStatus) Error() string {
 	return fmt.Sprintf("unsupported service status %q", e.Status)
 }
+
+// ErrUnsupportedCodec is error when no codec
+// is registered for given content type
+type ErrUnsupportedCodec struct {
+	ContentType string
+}
+
+// Error is throw error as a string
+func (e ErrUnsupportedCodec) Error() string {
+	return fmt.Sprintf("unsupported service codec content type %q", e.ContentType)
+}
@@ -0,0 +1,59 @@
+package service
+
+import "sort"
+
+// Record is stable wire and storage representation of
+// service shared by persistence, federation, admin export
+// and events, so features don't invent own service schema
+type Record struct {
+	ID        string            `json:"id"`
+	Address   string            `json:"address"`
+	NodeName  string            `json:"node_name"`
+	Tags      []string          `json:"tags,omitempty"`
+	Addresses []string          `json:"addresses,omitempty"`
+	Status    string            `json:"status"`
+	Load      float32           `json:"load"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// NewRecord create Record of given service,
+// tags are sorted to keep encoding stable
+func NewRecord(srv IService) Record {
+	var tags []string
+	for tag := range srv.Tags() {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var addresses []string
+	if multiAddress, ok := srv.(IMultiAddressService); ok {
+		addresses = multiAddress.Addresses()
+	}
+
+	return Record{
+		ID:        srv.ID(),
+		Address:   srv.Address(),
+		NodeName:  srv.NodeName(),
+		Tags:      tags,
+		Addresses: addresses,
+		Status:    srv.Status().String(),
+		Load:      srv.Load(),
+		Metadata:  Metadata(srv),
+	}
+}
+
+// Service create BaseService from the record, the
+// service is unhealthy until it is healthchecked
+func (r Record) Service() IService {
+	tags := make(map[string]struct{}, len(r.Tags))
+	for _, tag := range r.Tags {
+		tags[tag] = struct{}{}
+	}
+
+	srv := NewService(r.Address, r.NodeName, tags, r.Load).(*BaseService)
+	if len(r.Metadata) != 0 {
+		srv.SetMetadata(r.Metadata)
+	}
+
+	return srv
+}
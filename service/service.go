@@ -1,10 +1,31 @@
 package service
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"maps"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
 )
 
+// DefaultWeight is weight of services which
+// weight is not set or is not positive
+const DefaultWeight = 1
+
+// WeightMetadataKey is metadata key the weight is read from
+// for services without explicit weight, e.g. copied from
+// Consul service metadata
+const WeightMetadataKey = "weight"
+
+// PriorityMetadataKey is metadata key the priority level is
+// read from for services without explicit priority, e.g.
+// copied from SRV record priority
+const PriorityMetadataKey = "priority"
+
 type IService interface {
 	// HealthCheck check service health by
 	// sending status request
@@ -31,28 +52,120 @@ type IService interface {
 	SetLoad(float32)
 }
 
+// IStatusService is implemented by services
+// which status could be set externally
+type IStatusService interface {
+	IService
+
+	// SetStatus set service current status
+	SetStatus(status Status)
+}
+
+// IContextService is implemented by services which
+// healthchecks could be canceled or limited by deadline
+type IContextService interface {
+	IService
+
+	// HealthCheckContext check service health by sending
+	// status request interrupted once given context is done
+	HealthCheckContext(ctx context.Context) error
+}
+
+// runningCheck is healthcheck of service without context
+// support, shared by callers until it's finished
+type runningCheck struct {
+	done chan struct{}
+	err  error
+}
+
+// runningChecks is running healthchecks of services
+// without context support by service instance, so
+// instances with the same id don't share checks
+var runningChecks sync.Map
+
+// HealthCheckContext check health of given service until given
+// context is done. Healthchecks of services without context
+// support are abandoned once the context is done, but no new
+// check of the service is started until the abandoned one is
+// finished, so stuck checks don't pile up
+func HealthCheckContext(ctx context.Context, srv IService) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if contextService, ok := srv.(IContextService); ok {
+		return contextService.HealthCheckContext(ctx)
+	}
+
+	// healthcheck of BaseService itself never blocks and checks
+	// which could not be interrupted need no separate goroutine
+	if _, ok := srv.(*BaseService); ok || ctx.Done() == nil {
+		return srv.HealthCheck()
+	}
+
+	check := startCheck(srv)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-check.done:
+		return check.err
+	}
+}
+
+// startCheck start healthcheck of given service
+// or return already running check of it
+func startCheck(srv IService) *runningCheck {
+	check := &runningCheck{done: make(chan struct{})}
+
+	// instances of not comparable types could not be
+	// keys, so theirs checks are not shared
+	shared := reflect.TypeOf(srv).Comparable()
+	if shared {
+		if running, loaded := runningChecks.LoadOrStore(srv, check); loaded {
+			return running.(*runningCheck)
+		}
+	}
+
+	go func() {
+		check.err = srv.HealthCheck()
+		if shared {
+			runningChecks.Delete(srv)
+		}
+		close(check.done)
+	}()
+
+	return check
+}
+
 // TODO split address field to host and port
 
-// BaseService represent basic service
-// model implementation
+// BaseService represent basic service model implementation,
+// status and load are safe for concurrent use
 type BaseService struct {
 	id       string              // service unique id - sha256(address)
-	status   Status              // service current status
+	status   int32               // service current status, accessed atomically
 	address  string              // service address to connect
 	nodeName string              // prover name from discovery
 	tags     map[string]struct{} // service tags
-	load     float32             // rating between [0.0, 1.0]
+	load     uint32              // bits of rating between [0.0, 1.0], accessed atomically
+	weight   int32               // relative capacity for weighted balancing (0 if not set), accessed atomically
+
+	metadataMu sync.RWMutex
+	metadata   map[string]string // key-value metadata, e.g. circuit, zone or version
+	live       map[string]string // metadata reported by the service itself, e.g. by healthcheck probe
+	merged     map[string]string // metadata overridden by live metadata
 }
 
 // NewService create new BaseService with address and discovery
 func NewService(address, nodeName string, tags map[string]struct{}, load float32) IService {
 	return &BaseService{
 		id:       GenerateServiceID(address),
-		status:   StatusUnHealthy,
+		status:   int32(StatusUnHealthy),
 		address:  address,
 		nodeName: nodeName,
 		tags:     tags,
-		load:     load,
+		load:     math.Float32bits(load),
 	}
 }
 
@@ -65,7 +178,7 @@ func (n *BaseService) HealthCheck() error {
 
 // Status return BaseService current status
 func (n *BaseService) Status() Status {
-	return n.status
+	return Status(atomic.LoadInt32(&n.status))
 }
 
 // ID return service unique ID
@@ -83,16 +196,31 @@ func (n *BaseService) NodeName() string {
 	return n.nodeName
 }
 
+// SetStatus set BaseService current status
 func (n *BaseService) SetStatus(status Status) {
-	n.status = status
+	atomic.StoreInt32(&n.status, int32(status))
 }
 
+// Load return BaseService current load rating
 func (n *BaseService) Load() float32 {
-	return n.load
+	return math.Float32frombits(atomic.LoadUint32(&n.load))
 }
 
+// SetLoad set BaseService current load rating
 func (n *BaseService) SetLoad(load float32) {
-	n.load = load
+	atomic.StoreUint32(&n.load, math.Float32bits(load))
+}
+
+// Weight return BaseService relative
+// capacity, zero if it is not set
+func (n *BaseService) Weight() int {
+	return int(atomic.LoadInt32(&n.weight))
+}
+
+// SetWeight set BaseService relative capacity, e.g. larger
+// prover machines receive proportionally more work
+func (n *BaseService) SetWeight(weight int) {
+	atomic.StoreInt32(&n.weight, int32(weight))
 }
 
 func (n *BaseService) Tags() map[string]struct{} {
@@ -103,6 +231,63 @@ func (n *BaseService) Close() error {
 	return nil
 }
 
+// Metadata return service key-value metadata overridden
+// by live metadata, returned map should not be modified
+func (n *BaseService) Metadata() map[string]string {
+	defer n.metadataMu.RUnlock()
+	n.metadataMu.RLock()
+
+	if n.merged != nil {
+		return n.merged
+	}
+
+	return n.metadata
+}
+
+// SetMetadata replace service
+// metadata with copy of given one
+func (n *BaseService) SetMetadata(metadata map[string]string) {
+	defer n.metadataMu.Unlock()
+	n.metadataMu.Lock()
+
+	n.metadata = maps.Clone(metadata)
+	n.merge()
+}
+
+// StaticMetadata return service metadata without live
+// metadata, returned map should not be modified
+func (n *BaseService) StaticMetadata() map[string]string {
+	defer n.metadataMu.RUnlock()
+	n.metadataMu.RLock()
+
+	return n.metadata
+}
+
+// SetLiveMetadata replace live metadata
+// of the service with copy of given one
+func (n *BaseService) SetLiveMetadata(metadata map[string]string) {
+	defer n.metadataMu.Unlock()
+	n.metadataMu.Lock()
+
+	n.live = maps.Clone(metadata)
+	n.merge()
+}
+
+// merge rebuild metadata overridden by live
+// metadata. Should be called under metadata lock
+func (n *BaseService) merge() {
+	if len(n.live) == 0 {
+		n.merged = nil
+		return
+	}
+
+	merged := make(map[string]string, len(n.metadata)+len(n.live))
+	maps.Copy(merged, n.metadata)
+	maps.Copy(merged, n.live)
+
+	n.merged = merged
+}
+
 // GenerateServiceID create BaseService unique id by
 // hashing given address string
 func GenerateServiceID(addr string) string {
@@ -112,3 +297,107 @@ func GenerateServiceID(addr string) string {
 
 	return hex.EncodeToString(sum)
 }
+
+// IMultiAddressService is implemented by services that advertise
+// several addresses (primary and fallbacks, or per-transport
+// endpoints), Address returns the currently active one
+type IMultiAddressService interface {
+	IService
+
+	// Addresses return all service addresses
+	Addresses() []string
+}
+
+// IMetadataService is implemented by services that expose
+// key-value metadata (circuit, zone, version) used for
+// filtered selection
+type IMetadataService interface {
+	IService
+
+	// Metadata return service key-value metadata
+	Metadata() map[string]string
+}
+
+// ILiveMetadataService is implemented by services which metadata
+// could be updated from the service itself, e.g. capacity and
+// capabilities parsed from healthcheck probe responses. Live
+// metadata overrides static one and is not taken into account
+// when rediscovered services are compared
+type ILiveMetadataService interface {
+	IMetadataService
+
+	// StaticMetadata return metadata without live metadata
+	StaticMetadata() map[string]string
+
+	// SetLiveMetadata replace live metadata
+	SetLiveMetadata(metadata map[string]string)
+}
+
+// StaticMetadata return metadata of given service without
+// live metadata, nil if service doesn't expose metadata
+func StaticMetadata(srv IService) map[string]string {
+	if live, ok := srv.(ILiveMetadataService); ok {
+		return live.StaticMetadata()
+	}
+
+	return Metadata(srv)
+}
+
+// Metadata return metadata of given service,
+// nil if service doesn't expose metadata
+func Metadata(srv IService) map[string]string {
+	if metadataService, ok := srv.(IMetadataService); ok {
+		return metadataService.Metadata()
+	}
+
+	return nil
+}
+
+// IWeightedService is implemented by services that
+// carry relative capacity used by weighted balancing
+type IWeightedService interface {
+	IService
+
+	// Weight return service relative capacity
+	Weight() int
+}
+
+// Weight return relative capacity of given service: its explicit
+// weight, weight from its metadata or DefaultWeight otherwise
+func Weight(srv IService) int {
+	if weighted, ok := srv.(IWeightedService); ok {
+		if weight := weighted.Weight(); weight > 0 {
+			return weight
+		}
+	}
+
+	if weight, err := strconv.Atoi(Metadata(srv)[WeightMetadataKey]); err == nil && weight > 0 {
+		return weight
+	}
+
+	return DefaultWeight
+}
+
+// IPriorityService is implemented by services that
+// belong to priority level, 0 is the highest priority
+type IPriorityService interface {
+	IService
+
+	// Priority return service priority level
+	Priority() int
+}
+
+// Priority return priority level of given service: its explicit
+// priority, priority from its metadata or 0 otherwise. Like in
+// Envoy and SRV records lower value is higher priority
+func Priority(srv IService) int {
+	if prioritized, ok := srv.(IPriorityService); ok {
+		return max(prioritized.Priority(), 0)
+	}
+
+	if priority, err := strconv.Atoi(Metadata(srv)[PriorityMetadataKey]); err == nil && priority > 0 {
+		return priority
+	}
+
+	return 0
+}
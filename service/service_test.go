@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type stuckService struct {
+	*BaseService
+	checks  atomic.Int32
+	release chan struct{}
+}
+
+func (s *stuckService) HealthCheck() error {
+	s.checks.Add(1)
+	<-s.release
+	return nil
+}
+
+func TestHealthCheckContextAbandoned(t *testing.T) {
+	srv := &stuckService{
+		BaseService: NewService("https://1gateway.fm", "", nil, 0).(*BaseService),
+		release:     make(chan struct{}),
+	}
+
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if err := HealthCheckContext(ctx, srv); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected abandoned check, got %v", err)
+		}
+		cancel()
+	}
+
+	if checks := srv.checks.Load(); checks != 1 {
+		t.Fatalf("expected stuck check to be reused, got %d checks", checks)
+	}
+
+	close(srv.release)
+	if err := HealthCheckContext(context.Background(), srv); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
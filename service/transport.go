@@ -0,0 +1,46 @@
+package service
+
+import "strings"
+
+// Transport is protocol service is reached with
+type Transport string
+
+const (
+	TransportHTTP Transport = "http" // plain http requests
+	TransportGrpc Transport = "grpc" // grpc calls over http/2
+)
+
+// TransportMetadataKey is metadata key the transport is read
+// from for services without explicit transport, e.g. copied
+// from Consul service metadata
+const TransportMetadataKey = "transport"
+
+// ITransportService is implemented by services
+// that declare protocol they are reached with
+type ITransportService interface {
+	IService
+
+	// Transport return service transport
+	Transport() Transport
+}
+
+// TransportOf return transport of given service: its explicit
+// transport, transport from its metadata or address scheme,
+// TransportHTTP otherwise
+func TransportOf(srv IService) Transport {
+	if transportService, ok := srv.(ITransportService); ok {
+		if transport := transportService.Transport(); transport != "" {
+			return transport
+		}
+	}
+
+	if transport := Metadata(srv)[TransportMetadataKey]; transport != "" {
+		return Transport(strings.ToLower(transport))
+	}
+
+	if strings.HasPrefix(srv.Address(), "grpc://") || strings.HasPrefix(srv.Address(), "grpcs://") {
+		return TransportGrpc
+	}
+
+	return TransportHTTP
+}
@@ -0,0 +1,186 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// serviceDrain is drain of one service
+type serviceDrain struct {
+	srv       service.IService
+	onDrained ServiceCallback
+	notified  bool
+}
+
+// serviceDrains is set of drained services, drained service
+// stays in the list but takes no new work and is not checked
+type serviceDrains struct {
+	mu      sync.Mutex
+	entries map[string]*serviceDrain // service id -> drain
+}
+
+// newServiceDrains create empty set of drained services
+func newServiceDrains() *serviceDrains {
+	return &serviceDrains{entries: make(map[string]*serviceDrain)}
+}
+
+// add drain given service, callback of already
+// drained service is replaced and report if the
+// service is drained for the first time
+func (d *serviceDrains) add(srv service.IService, onDrained ServiceCallback) bool {
+	defer d.mu.Unlock()
+	d.mu.Lock()
+
+	_, ok := d.entries[srv.ID()]
+	d.entries[srv.ID()] = &serviceDrain{srv: srv, onDrained: onDrained}
+
+	return !ok
+}
+
+// remove drain of service with given id
+// and report if the service was drained
+func (d *serviceDrains) remove(id string) bool {
+	defer d.mu.Unlock()
+	d.mu.Lock()
+
+	_, ok := d.entries[id]
+	delete(d.entries, id)
+
+	return ok
+}
+
+// has check if service with given id is drained
+func (d *serviceDrains) has(id string) bool {
+	defer d.mu.Unlock()
+	d.mu.Lock()
+
+	_, ok := d.entries[id]
+	return ok
+}
+
+// idle mark drain of service with given id as finished
+// and return it, nil is returned if the service is not
+// drained or the drain is already finished
+func (d *serviceDrains) idle(id string) *serviceDrain {
+	defer d.mu.Unlock()
+	d.mu.Lock()
+
+	drain, ok := d.entries[id]
+	if !ok || drain.notified {
+		return nil
+	}
+	drain.notified = true
+
+	return drain
+}
+
+// ids return sorted ids of drained services
+func (d *serviceDrains) ids() []string {
+	d.mu.Lock()
+	ids := make([]string, 0, len(d.entries))
+	for id := range d.entries {
+		ids = append(ids, id)
+	}
+	d.mu.Unlock()
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// DrainService stop selection of given member for new work, e.g.
+// before rolling upgrade of prover. Unlike jail drained service
+// stays in the list and is not healthchecked, so shutting down
+// prover isn't flapped. Given callback is called once in-flight
+// connections acquired by Acquire and leases of the service are
+// finished (nil to skip)
+func (l *ServicesList) DrainService(srv service.IService, onDrained ServiceCallback) error {
+	l.mu.Lock()
+	member := findService(l.healthy, srv.ID())
+	if member == nil {
+		member = l.jail[srv.ID()]
+	}
+	if member == nil {
+		l.mu.Unlock()
+		return ErrServiceNotFound{ID: srv.ID()}
+	}
+
+	if l.drains.add(member, onDrained) {
+		// selection caches depend on the generation
+		l.bumpGeneration()
+	}
+	l.mu.Unlock()
+
+	l.audit.record("service_drained", member.ID(), "")
+
+	l.log().Info("service is drained", "service", member.ID(), "connections", l.connections.count(member.ID()), "leases", l.leases.count(member.ID()))
+
+	l.checkDrained(member.ID())
+
+	return nil
+}
+
+// UndrainService resume selection of drained service with given id
+func (l *ServicesList) UndrainService(id string) {
+	if !l.drains.remove(id) {
+		return
+	}
+
+	l.mu.Lock()
+	l.bumpGeneration()
+	l.mu.Unlock()
+
+	l.audit.record("service_undrained", id, "")
+
+	l.log().Info("service is not drained anymore", "service", id)
+}
+
+// DrainedServices return sorted ids of drained services
+func (l *ServicesList) DrainedServices() []string {
+	return l.drains.ids()
+}
+
+// checkDrained call drain callback of service with given id
+// if it's drained and has no in-flight connections and leases
+func (l *ServicesList) checkDrained(id string) {
+	if l.connections.count(id) > 0 || l.leases.count(id) > 0 {
+		return
+	}
+
+	drain := l.drains.idle(id)
+	if drain == nil {
+		return
+	}
+
+	l.log().Info("drained service has no in-flight work", "service", id)
+
+	if drain.onDrained != nil {
+		drain.onDrained(drain.srv)
+	}
+}
+
+// DrainService stop selection of given
+// member of its shard for new work
+func (l *ShardedServicesList) DrainService(srv service.IService, onDrained ServiceCallback) error {
+	return l.shard(srv.ID()).DrainService(srv, onDrained)
+}
+
+// UndrainService resume selection of
+// drained service with given id
+func (l *ShardedServicesList) UndrainService(id string) {
+	l.shard(id).UndrainService(id)
+}
+
+// DrainedServices return sorted ids of
+// drained services of all shards
+func (l *ShardedServicesList) DrainedServices() []string {
+	var ids []string
+	for _, shard := range l.shards {
+		ids = append(ids, shard.DrainedServices()...)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
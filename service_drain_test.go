@@ -0,0 +1,120 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestDrainService(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	drained := newSwitchableService("https://1gateway.fm")
+	other := newHealthyService("https://2gateway.fm")
+	list.Add(drained)
+	list.Add(other)
+
+	// take in-flight work of the drained service
+	var release ReleaseFunc
+	for release == nil {
+		srv, r := list.Acquire(context.Background())
+		if srv == drained {
+			release = r
+			continue
+		}
+		r()
+	}
+
+	idle := make(chan service.IService, 1)
+	if err := list.DrainService(drained, func(srv service.IService) { idle <- srv }); err != nil {
+		t.Fatalf("unexpected drain error: %s", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if srv := list.Next(); srv != other {
+			t.Fatalf("drained service should not be selected, got %s", srv.Address())
+		}
+	}
+
+	// failing drained service isn't jailed
+	drained.down.Store(true)
+	list.HealthChecks()
+
+	if len(list.Healthy()) != 2 || len(list.Jailed()) != 0 {
+		t.Fatalf("drained service should stay in the list")
+	}
+
+	select {
+	case <-idle:
+		t.Fatalf("callback should not be called while work is in-flight")
+	default:
+	}
+
+	release()
+
+	select {
+	case srv := <-idle:
+		if srv != drained {
+			t.Errorf("unexpected drained service %s", srv.Address())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("callback should be called once work is finished")
+	}
+
+	if ids := list.DrainedServices(); len(ids) != 1 || ids[0] != drained.ID() {
+		t.Errorf("unexpected drained services %v", ids)
+	}
+
+	list.UndrainService(drained.ID())
+	drained.down.Store(false)
+
+	selected := map[service.IService]bool{}
+	for i := 0; i < 4; i++ {
+		selected[list.Next()] = true
+	}
+	if !selected[drained] {
+		t.Errorf("undrained service should be selected again")
+	}
+
+	if err := list.DrainService(newHealthyService("https://3gateway.fm"), nil); !errors.As(err, &ErrServiceNotFound{}) {
+		t.Errorf("drain of missing service should fail, got %v", err)
+	}
+}
+
+func TestDrainServiceIdle(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Shards:         2,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	called := 0
+	if err := list.DrainService(srv, func(service.IService) { called++ }); err != nil {
+		t.Fatalf("unexpected drain error: %s", err)
+	}
+
+	if called != 1 {
+		t.Errorf("callback of idle service should be called immediately once, got %d", called)
+	}
+
+	if list.Next() != nil {
+		t.Errorf("drained service should not be selected")
+	}
+
+	if services := list.Snapshot().Services; len(services) != 1 || !services[0].Drained {
+		t.Errorf("snapshot should report drained service, got %+v", services)
+	}
+}
@@ -1,52 +1,395 @@
 package pool
 
 import (
+	"context"
 	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/gateway-fm/scriptorium/logger"
+	"go.opentelemetry.io/otel/trace"
 
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
 	"github.com/gateway-fm/prover-pool-lib/pkg/utils"
 	"github.com/gateway-fm/prover-pool-lib/service"
 )
 
-// IServicesList is generic interface for services list
-type IServicesList interface {
+// ISelector is consumer side of services list
+// used to pick services for requests
+type ISelector interface {
 	// Healthy return slice of all healthy services
 	Healthy() []service.IService
 
-	// Unhealthy return slice of all unHealthy services
-	Unhealthy() []service.IService
-
 	// Next returns next healthy service
 	// to take a connection
 	Next() service.IService
 
+	// NextContext returns next healthy service to take
+	// a connection, request attributes from given context
+	// are passed to policies and trace is linked to the
+	// selection metrics as exemplar
+	NextContext(ctx context.Context) service.IService
+
+	// NextE returns next healthy service to take a connection,
+	// reporting why no service is selected: ErrPoolClosed,
+	// ErrPoolDraining, ErrNoHealthyServices, ErrPoolSaturated,
+	// ErrNoEligibleService, ErrRequestShed or ErrServiceSaturated
+	NextE() (service.IService, error)
+
+	// NextWait returns next healthy service to take a connection,
+	// blocking until any service is selected or given context
+	// is done instead of returning nil
+	NextWait(ctx context.Context) (service.IService, error)
+
+	// NextCapable returns next healthy service advertising
+	// capability and circuit size given job requires
+	NextCapable(ctx context.Context, req Requirement) service.IService
+
+	// Capable return healthy services meeting given requirement
+	Capable(req Requirement) []service.IService
+
+	// NextForKey returns healthy service owning given
+	// key on consistent hash ring, e.g. batch or block
+	NextForKey(key string) service.IService
+
+	// NextForSession returns service given session is pinned
+	// to, session is pinned to next healthy service on its
+	// first call or when pinned service becomes unavailable
+	NextForSession(ctx context.Context, session string) service.IService
+
+	// ReleaseSession remove pin of given session
+	ReleaseSession(session string)
+
+	// ObserveRequest report duration of request
+	// to given service made by the caller
+	ObserveRequest(ctx context.Context, srv service.IService, duration time.Duration)
+
+	// ObserveResult report duration and outcome of request to
+	// given service made by the caller, nil error is success
+	ObserveResult(ctx context.Context, srv service.IService, duration time.Duration, err error)
+
+	// ReportSuccess report successful request to given
+	// service, its consecutive failures are reset
+	ReportSuccess(srv service.IService)
+
+	// ReportFailure report failed request to given service,
+	// it's jailed after configured consecutive failures
+	ReportFailure(srv service.IService, err error)
+
+	// NextLeastLoaded returns the least
+	// loaded healthy service with given tag
 	NextLeastLoaded(tag string) service.IService
 
 	// AnyByTag returns any service with given tag from healthy list
 	AnyByTag(tag string) service.IService
 
+	// HealthySorted return copy of
+	// healthy services in given order
+	HealthySorted(order HealthyOrder) []service.IService
+
+	// Where return healthy services
+	// with given metadata value
+	Where(key, value string) []service.IService
+
+	// NextWhere returns next healthy service with
+	// given metadata value to take a connection
+	NextWhere(key, value string) service.IService
+
+	// Lease select next healthy service
+	// and lease it to given holder
+	Lease(holder string) (*Lease, error)
+
+	// LeaseService lease healthy service
+	// with given id to given holder
+	LeaseService(id, holder string) (*Lease, error)
+
+	// Resolve return member or recently removed
+	// service with given id, e.g. for late completions
+	Resolve(id string) (srv service.IService, removed bool)
+
+	// Acquire returns next healthy service to take a connection
+	// and register in-flight connection to it, returned release
+	// should be called once the connection is finished. It never
+	// waits for capacity, nil is returned if no service is acquired
+	Acquire(ctx context.Context) (service.IService, ReleaseFunc)
+
+	// TryAcquire returns next healthy service to take a
+	// connection like Acquire, ErrPoolSaturated is returned
+	// or the call waits for capacity when all services are
+	// at theirs in-flight cap, other failures are reported
+	// with the same errors as NextE
+	TryAcquire(ctx context.Context) (service.IService, ReleaseFunc, error)
+
+	// FeatureEnabled check if given
+	// feature is enabled for the list
+	FeatureEnabled(name Feature) bool
+}
+
+// IAdmin is operator side of services list
+// used to inspect and change membership
+type IAdmin interface {
 	// Add service to the list
 	Add(srv service.IService)
 
+	// AddAll add given services to the list
+	// under single lock hold
+	AddAll(services []service.IService)
+
+	// ApplyDiff reconcile the list with given diff of
+	// discovery results under single lock hold
+	ApplyDiff(added, removed, changed []service.IService)
+
+	// SyncWith reconcile the list with given complete
+	// discovery results: new services are added and
+	// members missing in the results are removed
+	SyncWith(discovered []service.IService)
+
+	// RemoveByID remove service with given id from
+	// healthy, jail or review and report if it was found
+	RemoveByID(id string) bool
+
 	// IsServiceExists check is given service is
-	// already in list (healthy or jail)
+	// already in list (healthy, jail or review)
 	IsServiceExists(srv service.IService) bool
 
+	// Unhealthy return slice of all unHealthy services
+	Unhealthy() []service.IService
+
+	// CountAll returns sum of num healthy, jailed and
+	// under review services together
+	CountAll() int
+
+	// Jailed returns a copy of jail map
+	Jailed() map[string]service.IService
+
+	// JailedItems return jailed services with
+	// time they are jailed since, longest jailed first
+	JailedItems() []JailItem
+
+	// CountJailed return number of jailed services
+	CountJailed() int
+
+	// ExportState return state of the list kept across restarts
+	ExportState() *PersistedState
+
+	// RestoreState restore given state saved before restart
+	// unless it's older than given age, state of services is
+	// applied once they are added to the list
+	RestoreState(state *PersistedState, maxAge time.Duration)
+
+	// UnderReview return slice of all services
+	// waiting for review in quarantine
+	UnderReview() []ReviewItem
+
+	// Approve release service with given id from
+	// quarantine to jail
+	Approve(id string) error
+
+	// Reject remove service with given
+	// id from quarantine and close it
+	Reject(id string) error
+
+	// ReportVerificationFailure register failed
+	// verification of given service result
+	ReportVerificationFailure(srv service.IService)
+
+	// AvailabilityReport return healthchecks outcomes
+	// within [from, to) aggregated by given window
+	AvailabilityReport(window time.Duration, from, to time.Time) *AvailabilityReport
+
+	// Snapshot return point-in-time snapshot
+	// of services list membership
+	Snapshot() *PoolState
+
+	// Find return snapshots of services
+	// matched by all given matchers
+	Find(matchers ...Matcher) []ServiceSnapshot
+
+	// Policies return list routing policies
+	Policies() []IPolicy
+
+	// MemoryUsage return estimation of memory
+	// used by list bookkeeping
+	MemoryUsage() MemoryUsage
+
+	// ReplaceAll atomically swap the whole membership to
+	// given services, preserving state of services that
+	// are present in both current and given sets
+	ReplaceAll(services []service.IService)
+
+	// Generation return membership generation which
+	// is increased on every membership change
+	Generation() uint64
+
+	// SetUserData attach given value with given key to the
+	// list entry of service with given id, data is released
+	// when service is removed from the list
+	SetUserData(id, key string, value interface{}) error
+
+	// UserData return value attached with given key
+	// to the list entry of service with given id
+	UserData(id, key string) (interface{}, bool)
+
+	// Status return overall status of the list
+	Status() ListStatus
+
+	// SetSpare designate service with given id as
+	// hot spare or remove the designation
+	SetSpare(id string, spare bool) error
+
+	// ActivateSpare manually activate spare
+	// with given id or return it to standby
+	ActivateSpare(id string, active bool) error
+
+	// ScheduleMaintenance schedule cordon or jail of
+	// service during given window, reverted afterwards
+	ScheduleMaintenance(window MaintenanceWindow) (MaintenanceWindow, error)
+
+	// CancelMaintenance cancel maintenance window with given id
+	CancelMaintenance(id string) error
+
+	// Maintenance return scheduled and
+	// active maintenance windows
+	Maintenance() []MaintenanceWindow
+
+	// ForceJail move healthy service with given id
+	// to the jail without counting it as a flap
+	ForceJail(id string) error
+
+	// ForceRecover move jailed service with given
+	// id back to healthy without waiting for try up
+	ForceRecover(id string) error
+
+	// AuditLog return operator
+	// actions applied to the list
+	AuditLog() []AuditEntry
+
+	// Leases return active and draining
+	// leases of service with given id
+	Leases(id string) []*Lease
+
+	// InFlight return number of in-flight connections
+	// to service with given id acquired by Acquire
+	InFlight(id string) int
+
+	// Fairness return long-run selection shares
+	// of healthy services and theirs intended shares
+	Fairness() []FairnessShare
+
+	// PriorityLevels return health and share of
+	// requests of services priority levels
+	PriorityLevels() []PriorityLevel
+
+	// Starved return healthy services that
+	// are not selected during configured window
+	Starved() []StarvedService
+
+	// Saturation return mean saturation
+	// of healthy services in [0, 1]
+	Saturation() float64
+
+	// Stats return registry of members moving
+	// statistics, nil if statistics are disabled
+	Stats() *stats.Registry
+
+	// Tombstones return tombstones
+	// of recently removed services
+	Tombstones() []Tombstone
+
+	// SetFeature turn given feature on or off at runtime
+	SetFeature(name Feature, enabled bool)
+
+	// Features return feature flags ordered by name
+	Features() []FeatureFlag
+}
+
+// ILifecycle is lifecycle side of services
+// list that runs background activity
+type ILifecycle interface {
 	// HealthChecks pings the healthy services
 	// and update the statuses
 	HealthChecks()
 
+	// HealthChecksContext pings the healthy services and
+	// update the statuses until given context is done
+	HealthChecksContext(ctx context.Context)
+
 	// HealthChecksLoop spawn healthchecks for
 	// all healthy services periodically
 	HealthChecksLoop()
 
-	// TryUpService recursively try to up service
+	// CheckHealth check health of given service
+	// with configured checker until given context
+	// is done
+	CheckHealth(ctx context.Context, srv service.IService) error
+
+	// Pause suspend healthchecks and try ups, selection
+	// keeps working on the frozen membership
+	Pause()
+
+	// Resume continue paused healthchecks and try ups
+	Resume()
+
+	// Paused check if background activity is paused
+	Paused() bool
+
+	// SetDraining stop or resume selections
+	// while leased work finishes
+	SetDraining(draining bool)
+
+	// Draining check if selections are stopped
+	Draining() bool
+
+	// Pending return number of active and draining
+	// leases and in-flight connections
+	Pending() int
+
+	// DrainService stop selection of given member for new
+	// work without jailing it, given callback is called
+	// once its in-flight work is finished
+	DrainService(srv service.IService, onDrained ServiceCallback) error
+
+	// UndrainService resume selection of
+	// drained service with given id
+	UndrainService(id string)
+
+	// DrainedServices return sorted ids of drained services
+	DrainedServices() []string
+
+	// Subscribe return channel of list events,
+	// closed when the list is closed
+	Subscribe() <-chan PoolEvent
+
+	// Unsubscribe stop delivery of events to
+	// given channel returned by Subscribe
+	Unsubscribe(events <-chan PoolEvent)
+
+	// Close Stop service list
+	Close()
+}
+
+// IServicesList is generic interface for services list
+//
+// Deprecated: IServicesList mixes consumer, operator and internal
+// methods, depend on ISelector, IAdmin or ILifecycle instead. Methods
+// that are not part of them are internal and will be removed in v2
+type IServicesList interface {
+	ISelector
+	IAdmin
+	ILifecycle
+
+	// TryUpService try to up service until it's up
 	TryUpService(srv service.IService, try int)
 
+	// Timeouts return deadlines of list
+	// operations with inherited defaults
+	Timeouts() Timeouts
+
+	// TryUpServiceContext try to up
+	// service until given context is done
+	TryUpServiceContext(ctx context.Context, srv service.IService, try int)
+
 	// FromHealthyToJail move Unhealthy service
 	// from Healthy slice to Jail map
 	FromHealthyToJail(id string)
@@ -61,20 +404,18 @@ type IServicesList interface {
 
 	// RemoveFromHealthyByIndex removes
 	// service from healthy slice by given srv index in that slice
+	//
+	// Deprecated: indexes are shifted by concurrent
+	// removals, use RemoveFromHealthyByID instead
 	RemoveFromHealthyByIndex(i int)
 
-	// Close Stop service list
-	Close()
+	// RemoveFromHealthyByID removes service with given id
+	// from healthy slice and report if it was found
+	RemoveFromHealthyByID(id string) bool
 
 	// Shuffle randomly shuffles list
 	Shuffle()
 
-	// CountAll returns sum of num healthy and num jailed services together
-	CountAll() int
-
-	// Jailed returns a copy of jail map
-	Jailed() map[string]service.IService
-
 	ModifyHealthy(modifier func(srv service.IService))
 }
 
@@ -83,56 +424,306 @@ type IServicesList interface {
 type ServicesList struct {
 	serviceName string
 
-	current uint64
+	current      uint64
+	whereCurrent uint64
+	generation   uint64
+
+	// membership sizes exposed by metrics, shards of the
+	// pool add their changes to the shared gauges
+	observedSizes [3]int
+
+	healthy     []service.IService
+	healthyView atomic.Pointer[[]service.IService] // immutable copy of healthy, dropped on its changes
+	index       *metadataIndex
+	ring        *hashRing
+	spares      *spares
+
+	sessions *sessions
+	drains   *serviceDrains
+
+	balancing    Balancing
+	strategy     IBalancingStrategy
+	connections  *connections
+	capacity     *changeSignal // notified on release of in-flight connections
+	membership   *changeSignal // notified on change of healthy services
+	maxInFlight  int
+	overflowMode Overflow
+
+	maintenance *maintenance
+	audit       *auditLog
+
+	leases       *leases
+	leaseHandoff time.Duration
+	onLeaseDrain func(lease *Lease)
+
+	tombstones *tombstones
+	rejoins    *rejoins
+
+	checks       []ScheduledCheck
+	failedChecks *failedChecks
+
+	fairness   *fairness
+	priorities *priorities
+	starvation *starvation
+	shedding   *shedding
+
+	healthChecker IHealthChecker
+	stats         *stats.Registry
+	passive       *passiveHealth
+
+	jail     map[string]service.IService
+	jailedAt map[string]time.Time // service id -> time it's jailed since
+	restored *restoredServices
+
+	review               map[string]*ReviewItem
+	reviewPolicy         *ReviewPolicy
+	flaps                map[string][]time.Time
+	verificationFailures map[string][]time.Time
+
+	userData map[string]map[string]interface{}
+	added    map[string]time.Time
+
+	availability *availabilityTracker
+	budget       *MemoryBudget
 
-	healthy []service.IService
+	metrics *Metrics
+	tracer  trace.Tracer
+	config  ConfigInfo
 
-	jail map[string]service.IService
+	policies []IPolicy
 
-	//muMain sync.Mutex
-	//muJail sync.Mutex
+	removal      RemovalStrategy
+	healthyOrder HealthyOrder
 
+	onEvent         func(event PoolEvent)
+	events          *eventBus
+	hooks           hooks
+	features        *features
+	pendingEvents   []PoolEvent        // events queued under the lock, emitted by flushEvents
+	pendingClosers  []releasedUserData // user data released under the lock, closed by flushEvents
+	recheckOnChange bool
+
+	scheduler *Scheduler
+
+	dependencies []IServicesList
+	degraded     int32
+	draining     int32
+
+	pause pauser
+
+	// mu guards healthy, jail and review, read paths take
+	// the read lock, so they run concurrently with each other
 	mu sync.RWMutex
 
 	TryUpTries    int
 	CheckInterval time.Duration
 	TryUpInterval time.Duration
 
+	tryUpBackoff      *backoff
+	timeouts          Timeouts
+	checksConcurrency int
+
 	Stop chan struct{}
+
+	ctx    context.Context // canceled on Close
+	cancel context.CancelFunc
+	tryUps workers // try up loops, waited by Close
+
+	logger ILogger // own logger of the list, nil to use the library one
 }
 
 // ServicesListOpts is options that needs
 // to configure ServicesList instance
 type ServicesListOpts struct {
-	TryUpTries     int           // number of attempts to try up service from jail (0 for infinity tries)
-	TryUpInterval  time.Duration // interval for try up service from jail
-	ChecksInterval time.Duration // healthchecks interval
+	TryUpTries     int                  // number of attempts to try up service from jail (0 for infinity tries)
+	TryUpInterval  time.Duration        // interval for try up service from jail
+	TryUpBackoff   *BackoffOpts         // exponential backoff with jitter of try up interval (nil for fixed interval)
+	Timeouts       *Timeouts            // deadlines of list operations, zero ones are inherited from the pool or DefaultTimeouts (nil to inherit all)
+	ChecksInterval time.Duration        // healthchecks interval
+	ChecksParallel int                  // number of services healthchecked concurrently in one round, every check is limited by Timeouts.Check (1 by default)
+	ReviewPolicy   *ReviewPolicy        // quarantine policy for flapping services (nil to disable)
+	Availability   *AvailabilityOpts    // healthchecks outcomes collection for availability reports (nil to disable)
+	Metrics        *Metrics             // prometheus collectors, could be shared between lists (nil to disable)
+	TracerProvider trace.TracerProvider // provider of healthchecks spans and spans of selections made within existing traces (global provider by default)
+	Policies       []IPolicy            // admission, selection and scoring policies applied in given order
+	MemoryBudget   *MemoryBudget        // caps of per-service bookkeeping (nil for unbounded)
+	Shards         int                  // number of independently locked shards for very large pools (0 or 1 to disable sharding)
+	Removal        RemovalStrategy      // how services are removed from healthy (order preserving by default)
+	HealthyOrder   HealthyOrder         // order of services returned by Healthy (internal order by default)
+	Balancing      Balancing            // built-in strategy used by Next to select healthy services, weighted and least connections ones are adapters of the same strategies (round-robin by default)
+	Strategy       IBalancingStrategy   // custom strategy used by Next instead of built-in one, e.g. Random() (nil to use Balancing)
+	IndexKeys      []string             // metadata keys indexed for Where and NextWhere, index is refreshed on membership changes (others are scanned)
+	HashReplicas   int                  // virtual nodes of every service on hash ring of NextForKey (100 by default)
+	SessionTTL     time.Duration        // period session of NextForSession stays pinned to its service since the last call (5 minutes by default)
+	Spares         *SparesOpts          // hot spares configuration (nil for manual designation only)
+	AuditLogSize   int                  // number of operator actions kept in the audit log (1000 by default)
+	LeaseHandoff   time.Duration        // window given to lease holders to finish or migrate work of drained service before leases are force-expired (0 to expire immediately)
+	OnLeaseDrain   func(*Lease)         // called when holder of lease should hand off drained service, in addition to Lease.Draining channel (nil to disable)
+	TombstoneTTL   time.Duration        // period removed services are still resolved by id for late reports and completions (0 to disable)
+	RejoinWindow   time.Duration        // period learned state of removed services (join time, statistics and user data) is kept and restored if they are rediscovered, e.g. after prover restart (0 to disable)
+	Checks         []ScheduledCheck     // additional checks of healthy services run on own schedules next to healthchecks
+	Fairness       *FairnessOpts        // boosting of chronically underutilized services (nil to disable)
+	Priorities     *PriorityOpts        // Envoy-like priority levels of services, lower levels take load as higher ones become unhealthy (nil to disable)
+	Starvation     *StarvationOpts      // reporting of healthy services that are not selected for a long time (nil to disable)
+	HealthChecker  IHealthChecker       // check of members used instead of theirs own HealthCheck, e.g. HTTPHealthChecker, GRPCHealthChecker or HealthCheckerFunc (nil to use HealthCheck)
+	Shed           *ShedOpts            // rejection of requests when healthy services are saturated (nil to disable)
+	MaxInFlight    int                  // in-flight connections acquired by Acquire per service, services at the cap are skipped by selections, service.MaxInFlightMetadataKey metadata overrides it (0 for unlimited)
+	Overflow       Overflow             // behaviour of TryAcquire when all allowed services are at theirs in-flight cap (reject with ErrPoolSaturated by default)
+	Stats          *stats.Registry      // moving latency, error rate and throughput of members fed by ObserveResult, could be shared with custom strategy or policies (nil to disable)
+	PassiveHealth  *PassiveHealthOpts   // jailing of services failing consecutive requests reported by ReportFailure or ObserveResult (nil to disable)
+	OnEvent        func(PoolEvent)      // membership events handler, called synchronously, added, removed and failed healthcheck events are delivered to subscribers only (nil to disable)
+	EventBuffer    int                  // events buffered for every subscriber, events are dropped for subscribers with full buffer (64 by default)
+	OnJail         ServiceCallback      // called synchronously when service is moved to the jail, e.g. to alert or drain its work (nil to disable)
+	OnRecover      ServiceCallback      // called synchronously when jailed service is moved back to healthy (nil to disable)
+	OnAdd          ServiceCallback      // called synchronously when new service is added to the list (nil to disable)
+	OnRemove       ServiceCallback      // called synchronously when service is removed from the list (nil to disable)
+	Features       map[Feature]bool     // initial state of feature flags toggled at runtime with SetFeature (outlier ejection and gossip health are enabled, other features are disabled by default)
+	RecheckChanged bool                 // healthcheck changed services merged on rediscovery instead of keeping theirs status
+	Scheduler      *Scheduler           // shared scheduler to run healthchecks on instead of own loop (nil for own loop)
+	Dependencies   []IServicesList      // lists this list depends on, members are not jailed while any of them has no healthy services
+	Logger         ILogger              // logger of the list, messages carry the list name (nil to use logger set with SetLogger)
 }
 
 // NewServicesList create new ServiceList instance
-// with given configuration
+// with given configuration, ShardedServicesList is
+// created if more than one shard is configured
 func NewServicesList(serviceName string, opts *ServicesListOpts) IServicesList {
-	return &ServicesList{
-		serviceName:   serviceName,
-		jail:          make(map[string]service.IService),
-		TryUpTries:    opts.TryUpTries,
-		CheckInterval: opts.ChecksInterval,
-		TryUpInterval: opts.TryUpInterval,
-		Stop:          make(chan struct{}),
+	if opts.Shards > 1 {
+		return newShardedServicesList(serviceName, opts)
+	}
+
+	return newServicesList(serviceName, opts)
+}
+
+// newServicesList create new ServiceList
+// instance with given configuration
+func newServicesList(serviceName string, opts *ServicesListOpts) *ServicesList {
+	l := &ServicesList{
+		serviceName:          serviceName,
+		jail:                 make(map[string]service.IService),
+		jailedAt:             make(map[string]time.Time),
+		restored:             newRestoredServices(),
+		review:               make(map[string]*ReviewItem),
+		reviewPolicy:         opts.ReviewPolicy,
+		flaps:                make(map[string][]time.Time),
+		verificationFailures: make(map[string][]time.Time),
+		userData:             make(map[string]map[string]interface{}),
+		added:                make(map[string]time.Time),
+		index:                newMetadataIndex(opts.IndexKeys),
+		ring:                 newHashRing(opts.HashReplicas),
+		sessions:             newSessions(opts.SessionTTL),
+		drains:               newServiceDrains(),
+		spares:               newSpares(opts.Spares),
+		balancing:            opts.Balancing,
+		connections:          newConnections(),
+		capacity:             newChangeSignal(),
+		membership:           newChangeSignal(),
+		maxInFlight:          opts.MaxInFlight,
+		overflowMode:         opts.Overflow,
+		maintenance:          newMaintenance(),
+		audit:                newAuditLog(opts.AuditLogSize),
+		leases:               newLeases(),
+		leaseHandoff:         opts.LeaseHandoff,
+		onLeaseDrain:         opts.OnLeaseDrain,
+		tombstones:           newTombstones(opts.TombstoneTTL, opts.MemoryBudget),
+		rejoins:              newRejoins(opts.RejoinWindow, opts.MemoryBudget),
+		checks:               opts.Checks,
+		failedChecks:         newFailedChecks(),
+		fairness:             newFairness(opts.Fairness, opts.Balancing),
+		priorities:           newPriorities(opts.Priorities),
+		starvation:           newStarvation(opts.Starvation),
+		stats:                opts.Stats,
+		passive:              newPassiveHealth(opts.PassiveHealth),
+		healthChecker:        opts.HealthChecker,
+		shedding:             newShedding(opts.Shed),
+		availability:         newAvailabilityTracker(opts.Availability, opts.MemoryBudget),
+		budget:               opts.MemoryBudget,
+		metrics:              opts.Metrics,
+		tracer:               newTracer(opts.TracerProvider),
+		config:               newConfigInfo(opts),
+		policies:             opts.Policies,
+		removal:              opts.Removal,
+		healthyOrder:         opts.HealthyOrder,
+		onEvent:              opts.OnEvent,
+		events:               newEventBus(opts.EventBuffer),
+		hooks: hooks{
+			onJail:    opts.OnJail,
+			onRecover: opts.OnRecover,
+			onAdd:     opts.OnAdd,
+			onRemove:  opts.OnRemove,
+		},
+		features:          newFeatures(opts.Features),
+		recheckOnChange:   opts.RecheckChanged,
+		scheduler:         opts.Scheduler,
+		dependencies:      opts.Dependencies,
+		logger:            opts.Logger,
+		TryUpTries:        opts.TryUpTries,
+		CheckInterval:     opts.ChecksInterval,
+		TryUpInterval:     opts.TryUpInterval,
+		tryUpBackoff:      newBackoff(opts.TryUpBackoff),
+		timeouts:          opts.Timeouts.Inherit(DefaultTimeouts()),
+		checksConcurrency: max(opts.ChecksParallel, 1),
+		Stop:              make(chan struct{}),
 	}
+
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+
+	l.strategy = opts.Strategy
+	if l.strategy == nil {
+		l.strategy = opts.Balancing.strategy(l.connections.count)
+	}
+
+	l.metrics.observeConfig(serviceName, l.config)
+	l.metrics.observePaused(serviceName, false)
+	l.metrics.observeMembership(serviceName, 0, 0, 0)
+
+	return l
 }
 
 // Healthy return slice of all healthy services
+// in configured order
 func (l *ServicesList) Healthy() []service.IService {
-	defer l.mu.RUnlock()
-	l.mu.RLock()
+	if l.healthyOrder != HealthyOrderNone {
+		return l.HealthySorted(l.healthyOrder)
+	}
 
 	var healthy []service.IService
-	healthy = append(healthy, l.healthy...)
+	healthy = append(healthy, l.healthySnapshot()...)
 
 	return healthy
 }
 
+// healthySnapshot return immutable copy of healthy services,
+// the copy is shared by readers until healthy is changed, so
+// they don't contend for the list lock. It must not be modified
+func (l *ServicesList) healthySnapshot() []service.IService {
+	if view := l.healthyView.Load(); view != nil {
+		return *view
+	}
+
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	// changes are made under the write lock, so the
+	// copy can't be outdated until the lock is released
+	if view := l.healthyView.Load(); view != nil {
+		return *view
+	}
+
+	view := append([]service.IService{}, l.healthy...)
+	l.healthyView.Store(&view)
+
+	return view
+}
+
+// healthyChanged drop immutable copy of healthy services and wake
+// up NextWait waiters. Should be called under the list lock on
+// every change of healthy
+func (l *ServicesList) healthyChanged() {
+	l.healthyView.Store(nil)
+	l.membership.notify()
+}
+
 // Unhealthy return slice of all unHealthy services
 func (l *ServicesList) Unhealthy() []service.IService {
 	defer l.mu.RUnlock()
@@ -150,11 +741,75 @@ func (l *ServicesList) Unhealthy() []service.IService {
 // Next returns next healthy service
 // to take a connection
 func (l *ServicesList) Next() service.IService {
-	defer l.mu.Unlock()
-	l.mu.Lock()
+	return l.NextContext(context.Background())
+}
+
+// NextContext returns next healthy service to take
+// a connection, request attributes from given context
+// are passed to policies and trace is linked to the
+// selection metrics as exemplar
+func (l *ServicesList) NextContext(ctx context.Context) service.IService {
+	ctx, span, traced := traceNext(ctx, l.tracer, l.serviceName)
+
+	srv := l.next(ctx)
+	l.metrics.observeSelection(ctx, l.serviceName, srv)
+
+	if traced {
+		endNext(span, srv)
+	}
+
+	return srv
+}
+
+// ObserveRequest report duration of request
+// to given service made by the caller
+func (l *ServicesList) ObserveRequest(ctx context.Context, srv service.IService, duration time.Duration) {
+	l.ObserveResult(ctx, srv, duration, nil)
+}
+
+// next returns next healthy service allowed for given request
+// using configured balancing strategy. Selections are made under
+// the read lock, so they don't contend with each other
+func (l *ServicesList) next(ctx context.Context) service.IService {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	return l.nextLocked(ctx)
+}
+
+// nextLocked returns next healthy service allowed for given request
+// using configured balancing strategy. Should be called under the
+// list lock
+func (l *ServicesList) nextLocked(ctx context.Context) (selected service.IService) {
+	defer func() {
+		l.recordSelection(selected)
+	}()
 
 	if len(l.healthy) == 0 {
-		logger.Log().Info(fmt.Sprintf("list name %s no healthy services are present during list's Next() call", l.serviceName))
+		l.log().Info("no healthy services are present during list's Next() call")
+		return nil
+	}
+
+	if l.shedLocked(ctx) {
+		return nil
+	}
+
+	ctx = l.withPriority(ctx)
+
+	if srv := l.warmUpSpare(ctx); srv != nil {
+		return srv
+	}
+
+	if srv := l.boost(ctx); srv != nil {
+		return srv
+	}
+
+	if l.strategy != nil {
+		if srv := l.nextByStrategy(ctx); srv != nil {
+			return srv
+		}
+
+		l.log().Info("no healthy services are selected by strategy during list's Next() call", "strategy", l.strategy.Name())
 		return nil
 	}
 
@@ -162,7 +817,7 @@ func (l *ServicesList) Next() service.IService {
 	length := len(l.healthy) + next
 	for i := next; i < length; i++ {
 		idx := i % len(l.healthy)
-		if l.healthy[idx].Status() == service.StatusHealthy {
+		if l.healthy[idx].Status() == service.StatusHealthy && l.allow(ctx, l.healthy[idx]) {
 			if i != next {
 				atomic.StoreUint64(&l.current, uint64(idx))
 			}
@@ -170,52 +825,52 @@ func (l *ServicesList) Next() service.IService {
 		}
 	}
 
-	logger.Log().Info(fmt.Sprintf("list name %s no healthy services are present after forloop during list's Next() call", l.serviceName))
+	l.log().Info("no healthy services are present after forloop during list's Next() call")
 	return nil
 }
 
 // todo: refactor this
 // we might need to have another map
 func (l *ServicesList) AnyByTag(tag string) service.IService {
-	defer l.mu.Unlock()
-	l.mu.Lock()
+	defer l.mu.RUnlock()
+	l.mu.RLock()
 
 	if len(l.healthy) == 0 {
-		logger.Log().Warn(fmt.Sprintf("list name %s no healthy services are present during list's AnyByTag(%s) call", l.serviceName, tag))
+		l.log().Warn("no healthy services are present during list's AnyByTag() call", "tag", tag)
 		return nil
 	}
 
 	for _, srv := range l.healthy {
 		_, isTagPresent := srv.Tags()[tag]
-		if !isTagPresent {
+		if !isTagPresent || !l.allow(context.Background(), srv) {
 			continue
 		}
 		return srv
 	}
-	logger.Log().Warn(fmt.Sprintf("list name %s not found tag %s", l.serviceName, tag))
+	l.log().Warn("not found tag", "tag", tag)
 
 	return nil
 }
 
 func (l *ServicesList) NextLeastLoaded(tag string) service.IService {
-	defer l.mu.Unlock()
-	l.mu.Lock()
+	defer l.mu.RUnlock()
+	l.mu.RLock()
 
 	if len(l.healthy) == 0 {
-		logger.Log().Info(fmt.Sprintf("list name %s no healthy services are present during list's Next() call", l.serviceName))
+		l.log().Info("no healthy services are present during list's Next() call")
 		return nil
 	}
 
 	var leastLoadedSrv service.IService
-	minLoad := float32(1.01)
+	minLoad := math.MaxFloat64
 
 	for _, srv := range l.healthy {
 		_, isTagPresent := srv.Tags()[tag]
-		if !isTagPresent {
+		if !isTagPresent || !l.allow(context.Background(), srv) {
 			continue
 		}
 
-		load := srv.Load()
+		load := l.score(srv, float64(srv.Load()))
 		if load < minLoad {
 			leastLoadedSrv = srv
 			minLoad = load
@@ -227,30 +882,78 @@ func (l *ServicesList) NextLeastLoaded(tag string) service.IService {
 
 // Add service to the list
 func (l *ServicesList) Add(srv service.IService) {
-	if l.IsServiceExists(srv) {
-		logger.Log().Info(fmt.Sprintf("list name %s service already exists during Add, service with id %s with nodeName %s", l.serviceName, srv.ID(), srv.NodeName()))
+	// existing entry is updated if rediscovered service has changed
+	if l.merge(srv) {
 		return
 	}
 
-	l.mu.Lock()
+	if err := l.admit(srv); err != nil {
+		l.log().Warn("service is not admitted", "service", srv.ID(), "node", srv.NodeName(), "error", err)
+		return
+	}
 
-	if err := srv.HealthCheck(); err != nil {
-		l.jail[srv.ID()] = srv
-		logger.Log().Warn(fmt.Sprintf("list name %s service with id %s with nodeName %s can't be added to healthy due to healthcheck error: %s", l.serviceName, srv.ID(), srv.NodeName(), err.Error()))
+	// healthcheck is run without holding the lock,
+	// so selections are not blocked by slow services
+	err := l.CheckHealth(context.Background(), srv)
+	l.availability.record(srv, err)
 
-		go l.TryUpService(srv, 0)
+	l.mu.Lock()
 
+	// service could be added concurrently while it is checked
+	if existing, _ := l.lookup(srv.ID()); existing != nil {
 		l.mu.Unlock()
+		l.merge(srv)
 		return
 	}
 
-	l.healthy = append(l.healthy, srv)
-	logger.Log().Info(fmt.Sprintf("list name %s service with id %s with nodeName %s with address %s added to list", l.serviceName, srv.ID(), srv.NodeName(), srv.Address()))
+	jailed := l.insert(srv, err)
+	l.bumpGeneration()
+
 	l.mu.Unlock()
+
+	l.flushEvents()
+
+	if jailed {
+		l.goTryUp(srv)
+	}
+}
+
+// insert add given new service to healthy or to jail if given
+// healthcheck error is not nil and return true if the service
+// is jailed. Should be called under the list lock
+func (l *ServicesList) insert(srv service.IService, err error) bool {
+	// service moved from the jail is still tracked as member
+	if _, member := l.added[srv.ID()]; !member {
+		l.queueEvent(PoolEvent{Type: EventServiceAdded, Service: srv})
+	}
+
+	l.restoreRejoinState(srv)
+	l.trackAdded(srv.ID())
+	l.spares.admit(srv)
+	l.tombstones.forget(srv.ID())
+
+	if l.restorePersistedState(srv) && err == nil {
+		err = ErrJailedBeforeRestart{ID: srv.ID()}
+	}
+
+	if err != nil {
+		l.toJail(srv)
+		l.setStatus(srv, service.StatusUnHealthy)
+		l.log().Warn("service can't be added to healthy due to healthcheck error", "service", srv.ID(), "node", srv.NodeName(), "error", err)
+
+		return true
+	}
+
+	l.healthy = append(l.healthy, srv)
+	l.healthyChanged()
+	l.setStatus(srv, service.StatusHealthy)
+	l.log().Info("service added to list", "service", srv.ID(), "node", srv.NodeName(), "address", srv.Address())
+
+	return false
 }
 
 // IsServiceExists check is given service is
-// already in list (healthy or jail)
+// already in list (healthy, jail or review)
 func (l *ServicesList) IsServiceExists(srv service.IService) bool {
 	defer l.mu.RUnlock()
 	l.mu.RLock()
@@ -259,6 +962,10 @@ func (l *ServicesList) IsServiceExists(srv service.IService) bool {
 		return true
 	}
 
+	if l.isServiceUnderReview(srv) {
+		return true
+	}
+
 	if l.isServiceInHealthy(srv) {
 		return true
 	}
@@ -269,71 +976,266 @@ func (l *ServicesList) IsServiceExists(srv service.IService) bool {
 // HealthChecks pings the healthy services
 // and update the status
 func (l *ServicesList) HealthChecks() {
-	for _, srv := range l.Healthy() {
-		if srv == nil {
-			logger.Log().Info(fmt.Sprintf("list name %s service is nil during hc loop, skipping the healthcheck for it", l.serviceName))
-			continue
+	l.HealthChecksContext(context.Background())
+}
+
+// HealthChecksContext pings the healthy services and update the
+// statuses until given context is done or the list is closed,
+// services which checks are interrupted keep theirs status.
+// Configured number of services is checked concurrently
+func (l *ServicesList) HealthChecksContext(ctx context.Context) {
+	ctx, cancel := l.withStop(ctx)
+	defer cancel()
+
+	degraded := l.checkDependencies()
+
+	healthy := l.Healthy()
+	checks := make(chan service.IService)
+
+	// services are checked by bounded number of workers,
+	// so slow service doesn't delay the whole round
+	var wg sync.WaitGroup
+	for i := 0; i < min(l.checksConcurrency, len(healthy)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for srv := range checks {
+				l.checkHealthy(ctx, srv, degraded)
+			}
+		}()
+	}
+
+	for _, srv := range healthy {
+		select {
+		case checks <- srv:
+		case <-ctx.Done():
 		}
+	}
+	close(checks)
+	wg.Wait()
 
-		// TODO need to implement advanced logging level
+	if ctx.Err() != nil {
+		return
+	}
 
-		if err := srv.HealthCheck(); err != nil {
-			logger.Log().Warn(fmt.Errorf("healthcheck error on list with name %s, service with id %s with nodeName %s: %w", l.serviceName, srv.ID(), srv.NodeName(), err).Error())
+	l.detectStarvation()
+}
 
-			go func(service service.IService) {
-				l.FromHealthyToJail(service.ID())
-				logger.Log().Warn(fmt.Sprintf("%s service %s added to jail", l.serviceName, service.ID()))
-				l.TryUpService(service, 0)
-			}(srv)
+// checkHealthy healthcheck given healthy service and
+// start trying to up it if the check is failed
+func (l *ServicesList) checkHealthy(ctx context.Context, srv service.IService, degraded bool) {
+	if ctx.Err() != nil {
+		return
+	}
 
-			continue
-		}
+	if srv == nil {
+		l.log().Info("service is nil during hc loop, skipping the healthcheck for it")
+		return
+	}
+
+	// drained services are going
+	// down and shouldn't be flapped
+	if l.drains.has(srv.ID()) {
+		return
+	}
+
+	l.log().Debug("healthcheck of service", "service", srv.ID(), "node", srv.NodeName())
+
+	err := l.CheckHealth(ctx, srv)
+	if ctx.Err() != nil {
+		return
+	}
+
+	l.availability.record(srv, err)
+
+	if err == nil {
+		l.log().Debug("service is healthy", "service", srv.ID(), "node", srv.NodeName())
+		return
 	}
+
+	l.log().Warn("healthcheck error", "service", srv.ID(), "node", srv.NodeName(), "error", err)
+
+	// failures are caused by the dependency
+	// rather than by the service itself
+	if degraded {
+		return
+	}
+
+	l.goTask(taskTryUp, func() {
+		l.jailAndTryUp(srv)
+	})
 }
 
 // HealthChecksLoop spawn healthchecks for
 // all healthy periodically
 func (l *ServicesList) HealthChecksLoop() {
-	logger.Log().Info("start healthchecks loop")
+	l.log().Info("start healthchecks loop")
+
+	l.startScheduledChecks()
+
+	if l.scheduler != nil {
+		l.scheduledHealthChecks()
+		return
+	}
 
 	for {
 		select {
 		case <-l.Stop:
-			logger.Log().Warn("stop healthchecks loop")
+			l.log().Warn("stop healthchecks loop")
 			return
 		default:
+			l.pause.wait(l.Stop)
 			l.HealthChecks()
 			Sleep(l.CheckInterval, l.Stop)
 		}
 	}
 }
 
-// TryUpService recursively try to up service
+// scheduledHealthChecks run healthchecks on the
+// shared scheduler until the list is stopped
+func (l *ServicesList) scheduledHealthChecks() {
+	cancel := l.scheduler.schedule(func() time.Duration {
+		if !l.Paused() {
+			doLabeled(l.serviceName, taskHealthChecks, l.HealthChecks)
+		}
+		return l.CheckInterval
+	})
+
+	<-l.Stop
+	cancel()
+
+	l.log().Warn("stop healthchecks loop")
+}
+
+// withStop return context derived from given one
+// which is also canceled once the list is closed
+func (l *ServicesList) withStop(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(l.ctx, cancel)
+
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// TryUpService try to up service until it's up
 func (l *ServicesList) TryUpService(srv service.IService, try int) {
-	if l.TryUpTries != 0 && try >= l.TryUpTries {
-		logger.Log().Warn(fmt.Sprintf("list name %s maximum %d try to Up service with id %s with nodeName %s reached.... service will remove from service list", l.serviceName, l.TryUpTries, srv.ID(), srv.NodeName()))
-		l.RemoveFromJail(srv)
+	l.TryUpServiceContext(context.Background(), srv, try)
+}
+
+// TryUpServiceContext try to up service until given context is
+// done or the list is closed, service which tries are interrupted
+// is kept in the jail. Tries are tracked by the list, so Close
+// waits for them and no tries are started on closed list
+func (l *ServicesList) TryUpServiceContext(ctx context.Context, srv service.IService, try int) {
+	if !l.tryUps.add() {
 		return
 	}
+	defer l.tryUps.done()
 
-	logger.Log().Info(fmt.Sprintf("list name %s %d try to up service with id %s with address %s with nodeName %s", l.serviceName, try, srv.ID(), srv.Address(), srv.NodeName()))
+	ctx, cancel := l.withStop(ctx)
+	defer cancel()
 
-	if err := srv.HealthCheck(); err != nil {
-		logger.Log().Warn(fmt.Errorf("list name %s service with id %s with nodeName %s healthcheck error: %w", l.serviceName, srv.ID(), srv.NodeName(), err).Error())
+	l.tryUp(ctx, srv, try)
+}
 
-		Sleep(l.TryUpInterval, l.Stop)
-		l.TryUpService(srv, try+1)
-		return
+// tryUp try to up service until it's up, is removed from
+// the jail, tries are over or given context is done
+func (l *ServicesList) tryUp(ctx context.Context, srv service.IService, try int) {
+	// service known to be bad before restart
+	// isn't given traffic right after it
+	if l.restored.release(srv.ID()) {
+		SleepContext(ctx, l.TryUpInterval)
 	}
 
-	logger.Log().Info(fmt.Sprintf("list name %s service with id %s with nodeName %s is alive!", l.serviceName, srv.ID(), srv.NodeName()))
+	for ctx.Err() == nil {
+		l.mu.RLock()
+		// jailed entry could be replaced by merge of changed
+		// service, tries continue with the current instance
+		if current, ok := l.jail[srv.ID()]; ok {
+			srv = current
+		}
+		underReview := l.isServiceUnderReview(srv)
+		jailed := l.isServiceInJail(srv)
+		l.mu.RUnlock()
+
+		if underReview {
+			l.log().Info("service is under review, stop trying to up it", "service", srv.ID(), "node", srv.NodeName())
+			return
+		}
+
+		// service could be recovered concurrently, e.g. by gossip
+		// verdict, or removed, e.g. by RemoveByID or SyncWith
+		if !jailed {
+			return
+		}
+
+		// tries are not spent while the list is paused
+		l.pause.wait(ctx.Done())
 
-	l.FromJailToHealthy(srv)
+		// tries are not spent while service is held by maintenance
+		if l.maintenance.isHeld(srv.ID()) {
+			SleepContext(ctx, l.TryUpInterval)
+			continue
+		}
+
+		if l.TryUpTries != 0 && try >= l.TryUpTries {
+			l.log().Warn("maximum tries to Up service reached.... service will remove from service list", "tries", l.TryUpTries, "service", srv.ID(), "node", srv.NodeName())
+			l.RemoveFromJail(srv)
+			return
+		}
+
+		l.log().Info("try to up service", "try", try, "service", srv.ID(), "address", srv.Address(), "node", srv.NodeName())
+
+		err := l.CheckHealth(ctx, srv)
+		if ctx.Err() != nil {
+			return
+		}
+
+		l.availability.record(srv, err)
+
+		if err == nil {
+			err = l.failedChecks.recheck(srv)
+		}
+
+		l.metrics.observeTryUp(l.serviceName, err)
+
+		if err != nil {
+			l.log().Warn("healthcheck error", "service", srv.ID(), "node", srv.NodeName(), "error", err)
+
+			// tries are not spent while dependency is degraded
+			if atomic.LoadInt32(&l.degraded) == 0 {
+				try++
+			}
+
+			SleepContext(ctx, l.tryUpBackoff.interval(l.TryUpInterval, try))
+			continue
+		}
+
+		// checked instance is replaced or removed during the check,
+		// so the current one is checked or tries are stopped
+		if !l.fromJailToHealthy(srv, true) {
+			continue
+		}
+
+		l.log().Info("service is alive!", "service", srv.ID(), "node", srv.NodeName())
+		return
+	}
 }
 
 // FromHealthyToJail move Unhealthy service
 // from Healthy slice to Jail map
 func (l *ServicesList) FromHealthyToJail(id string) {
+	if srv := l.fromHealthyToJail(id); srv != nil {
+		l.emit(PoolEvent{Type: EventServiceJailed, Service: srv})
+	}
+}
+
+// fromHealthyToJail move service with given id from
+// Healthy slice to Jail map and return it, nil is
+// returned if service is not found or quarantined
+func (l *ServicesList) fromHealthyToJail(id string) service.IService {
 	defer l.mu.Unlock()
 	l.mu.Lock()
 
@@ -351,59 +1253,167 @@ func (l *ServicesList) FromHealthyToJail(id string) {
 	}
 
 	if index == -1 {
-		logger.Log().Warn(fmt.Sprintf("list name %s service with id %s is not found in healthy during FromHealthyToJail", l.serviceName, id))
-		return
+		l.log().Warn("service is not found in healthy during FromHealthyToJail", "service", id)
+		return nil
+	}
+
+	if l.recordFlap(srv) {
+		item := l.quarantine(srv, fmt.Sprintf("%d flaps within %s", len(l.flaps[id]), l.reviewPolicy.Window))
+		l.goTask(taskReview, func() {
+			l.requestReview(item)
+		})
+		return nil
 	}
 
-	l.healthy = deleteFromSlice(l.healthy, index)
-	l.jail[srv.ID()] = srv
+	l.removeFromHealthy(index)
+	l.toJail(srv)
+	l.setStatus(srv, service.StatusUnHealthy)
+	l.bumpGeneration()
 
-	logger.Log().Info(fmt.Sprintf("list name %s service with id %s is moved from healthy to jail", l.serviceName, id))
+	l.log().Info("service is moved from healthy to jail", "service", id)
+
+	return srv
+}
+
+// setStatus set status of given service on its transition
+// between list states, so status read concurrently always
+// matches the membership. Services which status could not
+// be set externally are skipped. Should be called under
+// the list lock
+func (l *ServicesList) setStatus(srv service.IService, status service.Status) {
+	if setter, ok := srv.(service.IStatusService); ok {
+		setter.SetStatus(status)
+	}
 }
 
 // FromJailToHealthy move Healthy service
 // from Jail map to Healthy slice
 func (l *ServicesList) FromJailToHealthy(srv service.IService) {
+	l.fromJailToHealthy(srv, false)
+}
+
+// fromJailToHealthy move given service from Jail map to Healthy
+// slice. Jailed entry could be replaced by changed instance, e.g.
+// by merge, then the entry is moved instead of given stale one or,
+// if exact, nothing is moved and false is returned. Exact move of
+// service which is removed from the jail is skipped as well
+func (l *ServicesList) fromJailToHealthy(srv service.IService, exact bool) bool {
 	l.mu.Lock()
-	delete(l.jail, srv.ID())
+	current, jailed := l.jail[srv.ID()]
+	if exact && (!jailed || current != srv) {
+		l.mu.Unlock()
+		return false
+	}
+	if jailed {
+		srv = current
+	}
+	l.fromJail(srv.ID())
 	l.mu.Unlock()
 
 	l.Add(srv)
 
-	logger.Log().Info(fmt.Sprintf("list name %s service with id %s with nodeName %s is moved from jail to healthy", l.serviceName, srv.ID(), srv.NodeName()))
+	l.log().Info("service is moved from jail to healthy", "service", srv.ID(), "node", srv.NodeName())
+
+	l.mu.RLock()
+	_, membership := l.lookup(srv.ID())
+	l.mu.RUnlock()
+
+	if jailed && membership == MembershipHealthy {
+		l.emit(PoolEvent{Type: EventServiceRecovered, Service: srv})
+	}
+
+	return true
 }
 
+// RemoveFromHealthyByIndex removes service from healthy
+// slice by given srv index in that slice, out of range
+// indexes are skipped
+//
+// Deprecated: indexes are shifted by concurrent
+// removals, use RemoveFromHealthyByID instead
 func (l *ServicesList) RemoveFromHealthyByIndex(i int) {
+	defer l.flushEvents()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if i < 0 || i >= len(l.healthy) {
+		l.log().Warn("index is out of healthy range during RemoveFromHealthyByIndex", "index", i)
+		return
+	}
+
+	l.log().Info("service is about to be removed from healthy by index", "service", l.healthy[i].ID(), "node", l.healthy[i].NodeName())
+
+	l.removeHealthy(i)
+}
+
+// RemoveFromHealthyByID removes service with given id
+// from healthy slice and report if it was found, unlike
+// removal by index it's safe under concurrent mutation
+func (l *ServicesList) RemoveFromHealthyByID(id string) bool {
+	defer l.flushEvents()
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	for i, srv := range l.healthy {
+		if srv.ID() != id {
+			continue
+		}
+
+		l.log().Info("service is about to be removed from healthy", "service", srv.ID(), "node", srv.NodeName())
+
+		l.removeHealthy(i)
+		return true
+	}
+
+	return false
+}
+
+// removeHealthy close and remove healthy service with
+// given index. Should be called under the list lock
+func (l *ServicesList) removeHealthy(i int) {
 	srv := l.healthy[i]
-	logger.Log().Info(fmt.Sprintf("list name %s service with id %s with nodeName %s is about to be removed from healthy by index", l.serviceName, srv.ID(), srv.NodeName()))
 
 	if err := srv.Close(); err != nil {
-		logger.Log().Warn(fmt.Errorf("unexpected error during service Close(): %w", err).Error())
+		l.log().Warn("unexpected error during service Close()", "service", srv.ID(), "error", err)
 	}
 
-	l.healthy = deleteFromSlice(l.healthy, i)
+	l.removeFromHealthy(i)
+	l.releaseMember(srv, "removed")
+	l.bumpGeneration()
 }
 
 // RemoveFromJail remove given
 // service from jail map
 func (l *ServicesList) RemoveFromJail(srv service.IService) {
+	defer l.flushEvents()
+
 	defer l.mu.Unlock()
 	l.mu.Lock()
 
-	logger.Log().Info(fmt.Sprintf("list name %s service with id %s with nodeName %s is about to be removed from jail", l.serviceName, srv.ID(), srv.NodeName()))
+	l.log().Info("service is about to be removed from jail", "service", srv.ID(), "node", srv.NodeName())
 
 	if err := srv.Close(); err != nil {
-		logger.Log().Warn(fmt.Errorf("unexpected error during service Close(): %w", err).Error())
+		l.log().Warn("unexpected error during service Close()", "service", srv.ID(), "error", err)
 	}
 
-	delete(l.jail, srv.ID())
+	l.fromJail(srv.ID())
+	delete(l.flaps, srv.ID())
+	delete(l.verificationFailures, srv.ID())
+	l.releaseMember(srv, "removed_from_jail")
+	l.bumpGeneration()
 }
 
-// Close Stop service list handling
+// Close Stop service list handling, remaining leases
+// are force-expired and running try up loops are
+// waited for
 func (l *ServicesList) Close() {
+	l.cancelAllMaintenance()
+	l.expireLeases()
+	l.cancel()
+	l.tryUps.close()
+	l.events.close()
 	close(l.Stop)
 }
 
@@ -417,20 +1427,21 @@ func (l *ServicesList) Shuffle() {
 	}
 
 	utils.ShuffleSlice(l.healthy)
+	l.healthyChanged()
 
 	newCurrent := utils.RandomUint64(length)
 	atomic.StoreUint64(&l.current, newCurrent)
 }
 
 func (l *ServicesList) CountAll() int {
-	// no mutex lock here since l.Healthy() has its own lock
-	numHealthy := len(l.Healthy())
+	// no mutex lock here since healthy snapshot has its own lock
+	numHealthy := len(l.healthySnapshot())
 
 	// len is not concurrency safe
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	return numHealthy + len(l.jail)
+	return numHealthy + len(l.jail) + len(l.review)
 }
 
 func (l *ServicesList) Jailed() map[string]service.IService {
@@ -455,10 +1466,16 @@ func (l *ServicesList) ModifyHealthy(modifier func(srv service.IService)) {
 	}
 }
 
+// AvailabilityReport return healthchecks outcomes
+// within [from, to) aggregated by given window
+func (l *ServicesList) AvailabilityReport(window time.Duration, from, to time.Time) *AvailabilityReport {
+	return l.availability.report(window, from, to)
+}
+
 // isServiceInJail check if service exist in jail
 func (l *ServicesList) isServiceInJail(srv service.IService) bool {
 	if srv == nil {
-		logger.Log().Warn("nil srv provided when calling isServiceInJail")
+		l.log().Warn("nil srv provided when calling isServiceInJail")
 		return false
 	}
 
@@ -473,13 +1490,13 @@ func (l *ServicesList) isServiceInJail(srv service.IService) bool {
 // healthy slice
 func (l *ServicesList) isServiceInHealthy(srv service.IService) bool {
 	if srv == nil {
-		logger.Log().Warn("nil srv provided when calling isServiceInHealthy")
+		l.log().Warn("nil srv provided when calling isServiceInHealthy")
 		return false
 	}
 
 	for _, oldService := range l.healthy {
 		if oldService == nil {
-			logger.Log().Warn("nil oldService in healthy slice of ServicesList")
+			l.log().Warn("nil oldService in healthy slice of ServicesList")
 			continue
 		}
 
@@ -490,6 +1507,51 @@ func (l *ServicesList) isServiceInHealthy(srv service.IService) bool {
 	return false
 }
 
+// Generation return membership generation which
+// is increased on every membership change
+func (l *ServicesList) Generation() uint64 {
+	return atomic.LoadUint64(&l.generation)
+}
+
+// bumpGeneration increase membership generation and
+// expose membership sizes. Should be called under the list lock
+func (l *ServicesList) bumpGeneration() {
+	atomic.AddUint64(&l.generation, 1)
+
+	sizes := [3]int{len(l.healthy), len(l.jail), len(l.review)}
+	l.metrics.addMembership(l.serviceName,
+		sizes[0]-l.observedSizes[0], sizes[1]-l.observedSizes[1], sizes[2]-l.observedSizes[2])
+	l.observedSizes = sizes
+}
+
+// removeFromHealthy remove service with given index from healthy
+// and move round-robin cursor, so the service that would be
+// selected next is not skipped. Should be called under the list lock
+func (l *ServicesList) removeFromHealthy(index int) {
+	// cursor points to the last selected service
+	current := int(atomic.LoadUint64(&l.current) % uint64(len(l.healthy)))
+
+	l.healthy = deleteFromSlice(l.healthy, index, l.removal)
+	l.healthyChanged()
+
+	if len(l.healthy) == 0 {
+		atomic.StoreUint64(&l.current, 0)
+		return
+	}
+
+	// services after the removed one are shifted on order preserving
+	// removal, last service takes its place on swap removal
+	shifted := index < current && l.removal == RemovalPreserveOrder
+	if index == current || shifted {
+		current--
+	}
+	if current < 0 || current >= len(l.healthy) {
+		current = len(l.healthy) - 1
+	}
+
+	atomic.StoreUint64(&l.current, uint64(current))
+}
+
 // nextIndex atomically increase the
 // counter and return an index
 func (l *ServicesList) nextIndex() int {
@@ -1,8 +1,11 @@
 package pool
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -76,3 +79,462 @@ func TestServicesListTryUp(t *testing.T) {
 		t.Errorf("unexpected no healthy services")
 	}
 }
+
+// stuckService is service which healthcheck
+// never finishes once it is stuck
+type stuckService struct {
+	*service.BaseService
+	stuck atomic.Bool
+}
+
+func (s *stuckService) HealthCheck() error {
+	if s.stuck.Load() {
+		select {}
+	}
+	return nil
+}
+
+func TestServicesListHealthChecksContext(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	stuck := &stuckService{BaseService: newHealthyService("https://1gateway.fm").(*service.BaseService)}
+	list.Add(stuck)
+	list.Add(newHealthyService("https://2gateway.fm"))
+	stuck.stuck.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		list.HealthChecksContext(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("healthchecks should be interrupted on deadline")
+	}
+
+	// interrupted check does not jail the service
+	if len(list.Healthy()) != 2 || len(list.Jailed()) != 0 {
+		t.Errorf("services should keep theirs status, got %d healthy and %d jailed", len(list.Healthy()), len(list.Jailed()))
+	}
+}
+
+func TestServicesListTryUpStopsOnClose(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     0,
+		TryUpInterval:  10 * time.Millisecond,
+		ChecksInterval: 1 * time.Second,
+	})
+
+	srv := newUnhealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	done := make(chan struct{})
+	go func() {
+		list.TryUpService(srv, 0)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	list.Close()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("infinite tries should stop once the list is closed")
+	}
+
+	if len(list.Jailed()) != 1 {
+		t.Errorf("service which tries are interrupted should be kept in jail")
+	}
+}
+
+func TestServicesListConcurrentTransitions(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     0,
+		TryUpInterval:  5 * time.Millisecond,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	var services []*switchableService
+	for i := 1; i <= 5; i++ {
+		srv := newSwitchableService(fmt.Sprintf("https://%dgateway.fm", i))
+		services = append(services, srv)
+		list.Add(srv)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		stop = make(chan struct{})
+	)
+
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+					fn(i)
+				}
+			}
+		}()
+	}
+
+	// services flap while healthchecks, try ups and callers run concurrently
+	run(func(i int) {
+		services[i%len(services)].down.Store(i%3 == 0)
+		time.Sleep(time.Millisecond)
+	})
+	run(func(int) {
+		list.HealthChecks()
+	})
+	run(func(int) {
+		list.Next()
+	})
+	run(func(i int) {
+		srv := services[i%len(services)]
+		srv.SetLoad(srv.Load())
+		_ = srv.Status()
+	})
+
+	time.Sleep(200 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	// status matches membership once transitions are settled
+	for _, srv := range list.Jailed() {
+		if srv.Status() != service.StatusUnHealthy {
+			t.Errorf("jailed service %s has status %s", srv.ID(), srv.Status())
+		}
+	}
+
+	for _, srv := range services {
+		srv.down.Store(false)
+	}
+
+	waitFor(t, func() bool {
+		return len(list.Healthy()) == len(services)
+	})
+
+	for _, srv := range list.Healthy() {
+		if srv.Status() != service.StatusHealthy {
+			t.Errorf("healthy service %s has status %s", srv.ID(), srv.Status())
+		}
+	}
+}
+
+// newBenchmarkList create list with given number of healthy
+// and jailed services with indexed zone metadata
+func newBenchmarkList(healthy, jailed int) (*ServicesList, []service.IService) {
+	list := newServicesList("benchmarkServicesList", &ServicesListOpts{
+		TryUpTries:     1,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		IndexKeys:      []string{"zone"},
+	})
+
+	var services []service.IService
+	for i := 0; i < healthy; i++ {
+		services = append(services, newMetadataService(fmt.Sprintf("https://%dgateway.fm", i), map[string]string{"zone": fmt.Sprint(i % 4)}))
+	}
+	for i := 0; i < jailed; i++ {
+		services = append(services, newUnhealthyService(fmt.Sprintf("https://%djailedgateway.fm", i)))
+	}
+	list.AddAll(services)
+
+	return list, services
+}
+
+// BenchmarkServicesListReads measure concurrent read paths
+// (membership checks, healthy copies, jail and index
+// lookups) racing with selections
+func BenchmarkServicesListReads(b *testing.B) {
+	list, services := newBenchmarkList(200, 20)
+	defer list.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			srv := services[i%len(services)]
+			switch i % 5 {
+			case 0:
+				list.IsServiceExists(srv)
+			case 1:
+				list.Healthy()
+			case 2:
+				list.Jailed()
+			case 3:
+				list.Where("zone", "1")
+			default:
+				list.NextLeastLoaded("")
+			}
+		}
+	})
+}
+
+// BenchmarkServicesListSelections measure concurrent
+// selections racing with adds of rediscovered services
+func BenchmarkServicesListSelections(b *testing.B) {
+	list, services := newBenchmarkList(200, 20)
+	defer list.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			switch i % 10 {
+			case 0:
+				list.Add(services[i%len(services)])
+			case 1:
+				list.AnyByTag("")
+			default:
+				list.Next()
+			}
+		}
+	})
+}
+
+func TestServicesListHealthChecksMultipleFailures(t *testing.T) {
+	for _, shards := range []int{1, 3} {
+		t.Run(fmt.Sprintf("shards %d", shards), func(t *testing.T) {
+			list := NewServicesList("testServicesList", &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  time.Hour,
+				ChecksInterval: time.Hour,
+				Shards:         shards,
+			})
+			defer list.Close()
+
+			failing := map[string]bool{}
+			for i := 0; i < 10; i++ {
+				srv := newSwitchableService(fmt.Sprintf("https://%dgateway.fm", i))
+				list.Add(srv)
+
+				// every other service fails, so removals
+				// shift positions of the following ones
+				if i%2 == 0 {
+					srv.down.Store(true)
+					failing[srv.ID()] = true
+				}
+			}
+
+			list.HealthChecks()
+			waitFor(t, func() bool { return len(list.Jailed()) == len(failing) })
+
+			for id := range list.Jailed() {
+				if !failing[id] {
+					t.Errorf("healthy service with id %s is jailed", id)
+				}
+			}
+			for _, srv := range list.Healthy() {
+				if failing[srv.ID()] {
+					t.Errorf("failing service with id %s is left healthy", srv.ID())
+				}
+			}
+		})
+	}
+}
+
+func TestServicesListRemoveFromHealthyByID(t *testing.T) {
+	for _, shards := range []int{1, 3} {
+		t.Run(fmt.Sprintf("shards %d", shards), func(t *testing.T) {
+			list := NewServicesList("testServicesList", &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  time.Hour,
+				ChecksInterval: time.Hour,
+				Shards:         shards,
+			})
+			defer list.Close()
+
+			var services []service.IService
+			for i := 0; i < 5; i++ {
+				srv := newHealthyService(fmt.Sprintf("https://%dgateway.fm", i))
+				services = append(services, srv)
+				list.Add(srv)
+			}
+
+			var wg sync.WaitGroup
+			for _, srv := range services[:3] {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if !list.RemoveFromHealthyByID(srv.ID()) {
+						t.Errorf("service with id %s should be removed", srv.ID())
+					}
+				}()
+			}
+			wg.Wait()
+
+			if list.RemoveFromHealthyByID(services[0].ID()) {
+				t.Errorf("removed service should not be found")
+			}
+
+			healthy := list.Healthy()
+			if len(healthy) != 2 {
+				t.Fatalf("expected 2 healthy services, got %d", len(healthy))
+			}
+			for _, srv := range healthy {
+				if srv != services[3] && srv != services[4] {
+					t.Errorf("unexpected healthy service %s", srv.Address())
+				}
+			}
+
+			// out of range index is skipped
+			list.RemoveFromHealthyByIndex(len(healthy))
+			if len(list.Healthy()) != 2 {
+				t.Errorf("out of range index should not remove services")
+			}
+		})
+	}
+}
+
+func TestServicesListHealthySnapshot(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+	})
+	defer list.Close()
+
+	first := newHealthyService("https://1gateway.fm")
+	list.Add(first)
+
+	healthy := list.Healthy()
+	healthy[0] = nil
+
+	if list.Healthy()[0] != first {
+		t.Fatalf("modification of returned slice should not change the list")
+	}
+
+	second := newHealthyService("https://2gateway.fm")
+	list.Add(second)
+	if len(list.Healthy()) != 2 {
+		t.Fatalf("snapshot should be refreshed on add")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if srv := list.Next(); srv == nil {
+					t.Errorf("healthy service should be selected")
+					return
+				}
+				_ = list.Healthy()
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		list.FromHealthyToJail(second.ID())
+		list.FromJailToHealthy(second)
+	}
+	wg.Wait()
+
+	list.RemoveFromHealthyByID(first.ID())
+	if healthy := list.Healthy(); len(healthy) != 1 || healthy[0] != second {
+		t.Errorf("snapshot should be refreshed on removal, got %v", healthy)
+	}
+}
+
+type countingService struct {
+	*service.BaseService
+	checks atomic.Int32
+}
+
+func (s *countingService) HealthCheck() error {
+	s.checks.Add(1)
+	return fmt.Errorf("service %s is down", s.Address())
+}
+
+func TestServicesListCloseStopsTryUps(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     0,
+		TryUpInterval:  time.Millisecond,
+		ChecksInterval: time.Hour,
+	})
+
+	srv := &countingService{BaseService: newHealthyService("https://1gateway.fm").(*service.BaseService)}
+	list.Add(srv)
+
+	// infinity tries should not grow the stack
+	waitFor(t, func() bool { return srv.checks.Load() > 100 })
+
+	list.Close()
+
+	checks := srv.checks.Load()
+	time.Sleep(20 * time.Millisecond)
+	if srv.checks.Load() != checks {
+		t.Fatalf("try up loop should be finished on Close")
+	}
+
+	list.TryUpService(srv, 0)
+	if srv.checks.Load() != checks {
+		t.Errorf("try up should not be started on closed list")
+	}
+}
+
+type slowService struct {
+	*service.BaseService
+	delay atomic.Int64
+}
+
+func (s *slowService) HealthCheck() error {
+	time.Sleep(time.Duration(s.delay.Load()))
+	return nil
+}
+
+func newSlowService(addr string, delay time.Duration) *slowService {
+	srv := &slowService{BaseService: newHealthyService(addr).(*service.BaseService)}
+	srv.delay.Store(int64(delay))
+
+	return srv
+}
+
+func TestServicesListParallelHealthChecks(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		ChecksParallel: 4,
+		Timeouts:       &Timeouts{Check: 200 * time.Millisecond},
+	})
+	defer list.Close()
+
+	for i := 0; i < 4; i++ {
+		list.Add(newSlowService(fmt.Sprintf("https://%dgateway.fm", i), 100*time.Millisecond))
+	}
+
+	hanging := newSlowService("https://hanging.gateway.fm", 0)
+	list.Add(hanging)
+	hanging.delay.Store(int64(time.Hour))
+
+	start := time.Now()
+	list.HealthChecks()
+
+	// 4 slow checks run next to each other and
+	// hanging one is limited by the check timeout
+	if elapsed := time.Since(start); elapsed > 450*time.Millisecond {
+		t.Errorf("round should take about the check timeout, took %s", elapsed)
+	}
+
+	waitFor(t, func() bool { return list.Jailed()[hanging.ID()] != nil })
+
+	if len(list.Healthy()) != 4 {
+		t.Errorf("expected 4 healthy services, got %d", len(list.Healthy()))
+	}
+}
@@ -1,6 +1,17 @@
 package pool
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gateway-fm/prover-pool-lib/discovery"
 	"github.com/gateway-fm/prover-pool-lib/service"
 )
 
@@ -11,6 +22,29 @@ type IServicesPool interface {
 	// and healthchecks loops
 	Start(healthchecks bool)
 
+	// DiscoverServices discover services
+	// and add new ones to the list
+	DiscoverServices() error
+
+	// DiscoverServicesContext discover services and add
+	// new ones to the list until given context is done
+	DiscoverServicesContext(ctx context.Context) error
+
+	// DiscoverServicesLoop discover services periodically
+	// until the pool is closed
+	DiscoverServicesLoop()
+
+	// Pause suspend rediscovery, healthchecks and try ups,
+	// selection keeps working on the frozen membership
+	Pause()
+
+	// Resume continue paused rediscovery,
+	// healthchecks and try ups
+	Resume()
+
+	// Paused check if pool background activity is paused
+	Paused() bool
+
 	// NextService returns next active service
 	// to take a connection
 	NextService() service.IService
@@ -22,6 +56,18 @@ type IServicesPool interface {
 	// List return ServicesPool ServicesList instance
 	List() IServicesList
 
+	// Drain stop selections, wait for leased and acquired
+	// work to finish and close the pool
+	Drain(ctx context.Context) error
+
+	// HandleSignals drain and close the pool on SIGTERM
+	HandleSignals(ctx context.Context) <-chan struct{}
+
+	// ServeAdmin serve admin api of the pool on given address
+	// until the pool is closed, mutating requests are refused
+	// unless authorization is configured in given options
+	ServeAdmin(addr string, opts *AdminHandlerOpts) error
+
 	// Close Stop all service pool
 	Close()
 
@@ -37,7 +83,34 @@ type ServicesPool struct {
 
 	list IServicesList
 
-	stop chan struct{}
+	discovery         discovery.IServiceDiscovery
+	discoveryInterval *discoveryInterval
+	discoveryPageSize int
+	lastDiscovered    discoveryFingerprint
+	discoveryMu       sync.Mutex
+	scheduler         *Scheduler
+	seeds             map[string]struct{}
+	seeded            chan struct{} // closed once seeds are checked and added
+	pruneMissing      bool
+
+	recorder  *Recorder
+	publisher *ConsulPublisher
+	persister *statePersister
+	flushers  sync.WaitGroup // recorder, publisher and persister goroutines
+
+	drainTimeout     time.Duration
+	discoveryTimeout time.Duration
+
+	tracer trace.Tracer
+	logger ILogger // own logger of the pool, nil to use the library one
+
+	pause pauser
+
+	stop      chan struct{}
+	ctx       context.Context // canceled on Close, interrupts discovery calls
+	cancel    context.CancelFunc
+	closeOnce sync.Once
+	loops     sync.WaitGroup // discovery loop, watch and seeding goroutines
 
 	MutationFnc func(srv service.IService) (service.IService, error)
 }
@@ -45,8 +118,25 @@ type ServicesPool struct {
 // ServicesPoolsOpts is options that needs
 // to configure ServicePool instance
 type ServicesPoolsOpts struct {
-	Name     string            // service name to use in service pool
-	ListOpts *ServicesListOpts // service list configuration
+	Name              string                                               // service name to use in service pool
+	Discovery         discovery.IServiceDiscovery                          // service discovery driver (nil to disable rediscovery)
+	DiscoveryInterval time.Duration                                        // rediscovery interval (0 without adaptive discovery to discover once on start)
+	AdaptiveDiscovery *AdaptiveDiscoveryOpts                               // adaptive rediscovery interval bounds (nil for fixed interval)
+	DiscoveryPageSize int                                                  // number of services ingested per discovery page (discovery.DefaultPageSize by default)
+	MutationFnc       func(srv service.IService) (service.IService, error) // mutation of discovered services before adding to the list
+	Scheduler         *Scheduler                                           // shared scheduler to run discovery on instead of own loop (nil for own loop)
+	DependsOn         []string                                             // names of registry pools this pool depends on, resolved by PoolRegistry
+	Seeds             []service.IService                                   // static services added at construction, the ones missing in the first discovery round are removed
+	PruneMissing      bool                                                 // remove listed services missing in discovery results, incl. manually added ones (only new services are added by default)
+	ListOpts          *ServicesListOpts                                    // service list configuration
+	Recorder          *RecorderOpts                                        // pool history recorder configuration (nil to disable)
+	Consul            *ConsulPublisherOpts                                 // pool view publisher to consul kv configuration (nil to disable)
+	Persistence       *PersistenceOpts                                     // jail membership, flaps and weights kept across restarts (nil to disable)
+	DrainTimeout      time.Duration                                        // time given to leased and acquired work to finish on SIGTERM, overrides drain timeout of Timeouts (25 seconds by default)
+	Timeouts          *Timeouts                                            // deadlines of pool operations inherited by the list, zero ones are inherited from DefaultTimeouts (nil to inherit all)
+	TracerProvider    trace.TracerProvider                                 // provider of discovery spans, inherited by the list unless list options have own (global provider by default)
+	RecordDiscovery   io.Writer                                            // discovery responses are recorded to as json lines for offline replay, e.g. file (nil to disable)
+	Logger            ILogger                                              // logger of the pool, inherited by the list unless list options have own (nil to use logger set with SetLogger)
 }
 
 type ServiceCallbackE func(srv service.IService) error
@@ -57,11 +147,62 @@ type ServiceCallbackB func(srv service.IService) bool
 // based on given params
 func NewServicesPool(opts *ServicesPoolsOpts) IServicesPool {
 	pool := &ServicesPool{
-		name: opts.Name,
-		stop: make(chan struct{}),
+		name:              opts.Name,
+		discovery:         opts.Discovery,
+		discoveryInterval: newDiscoveryInterval(opts.DiscoveryInterval, opts.AdaptiveDiscovery),
+		discoveryPageSize: opts.DiscoveryPageSize,
+		scheduler:         opts.Scheduler,
+		pruneMissing:      opts.PruneMissing,
+		drainTimeout:      opts.DrainTimeout,
+		stop:              make(chan struct{}),
+		MutationFnc:       opts.MutationFnc,
+		tracer:            newTracer(opts.TracerProvider),
+		logger:            opts.Logger,
+	}
+
+	pool.ctx, pool.cancel = context.WithCancel(context.Background())
+
+	timeouts := opts.Timeouts.Inherit(DefaultTimeouts())
+	if pool.drainTimeout <= 0 {
+		pool.drainTimeout = timeouts.Drain
+	}
+	pool.discoveryTimeout = timeouts.Discovery
+
+	if opts.RecordDiscovery != nil && pool.discovery != nil {
+		pool.discovery = discovery.NewRecordingDiscovery(pool.discovery, opts.RecordDiscovery)
+	}
+
+	// list inherits timeouts of the pool
+	listOpts := *opts.ListOpts
+	listTimeouts := listOpts.Timeouts.Inherit(timeouts)
+	listOpts.Timeouts = &listTimeouts
+	if listOpts.TracerProvider == nil {
+		listOpts.TracerProvider = opts.TracerProvider
+	}
+	if listOpts.Logger == nil {
+		listOpts.Logger = opts.Logger
+	}
+
+	pool.list = NewServicesList(opts.Name, &listOpts)
+
+	// state is restored before any service is added,
+	// so seeds are jailed if they were jailed before
+	if opts.Persistence != nil {
+		pool.persister = newStatePersister(opts.Name, pool.list, opts.Persistence, pool.log())
+		if err := pool.persister.restore(); err != nil {
+			pool.log().Warn("restore state error", "error", err)
+		}
 	}
 
-	pool.list = NewServicesList(opts.Name, opts.ListOpts)
+	pool.addSeeds(opts.Seeds)
+
+	if opts.Recorder != nil {
+		pool.recorder = NewRecorder(pool.list, opts.Recorder)
+	}
+
+	if opts.Consul != nil {
+		pool.publisher = NewConsulPublisher(pool.list, opts.Consul)
+	}
 
 	return pool
 }
@@ -69,9 +210,269 @@ func NewServicesPool(opts *ServicesPoolsOpts) IServicesPool {
 // Start run service pool discovering
 // and healthchecks loops
 func (p *ServicesPool) Start(healthchecks bool) {
+	if p.discovery != nil {
+		p.loops.Add(1)
+		goLabeled(p.name, taskDiscovery, func() {
+			defer p.loops.Done()
+			p.DiscoverServicesLoop()
+		})
+	}
+
+	if watcher, ok := p.discovery.(discovery.IWatchingDiscovery); ok {
+		p.loops.Add(1)
+		goLabeled(p.name, taskDiscovery, func() {
+			defer p.loops.Done()
+			p.watchDiscovery(watcher)
+		})
+	}
+
 	if healthchecks {
-		go p.list.HealthChecksLoop()
+		goLabeled(p.name, taskHealthChecks, p.list.HealthChecksLoop)
+	}
+
+	if p.recorder != nil {
+		p.flushers.Add(1)
+		goLabeled(p.name, taskRecorder, func() {
+			defer p.flushers.Done()
+			p.recorder.Run(p.stop)
+		})
+	}
+
+	if p.publisher != nil {
+		p.flushers.Add(1)
+		goLabeled(p.name, taskPublisher, func() {
+			defer p.flushers.Done()
+			p.publisher.Run(p.stop)
+		})
+	}
+
+	if p.persister != nil {
+		p.flushers.Add(1)
+		goLabeled(p.name, taskPersister, func() {
+			defer p.flushers.Done()
+			p.persister.Run(p.stop)
+		})
+	}
+}
+
+// DiscoverServices discover services
+// and add new ones to the list
+func (p *ServicesPool) DiscoverServices() error {
+	return p.DiscoverServicesContext(context.Background())
+}
+
+// DiscoverServicesContext discover services and add new ones
+// to the list until given context is done or the pool is closed
+func (p *ServicesPool) DiscoverServicesContext(ctx context.Context) error {
+	_, err := p.discoverServices(ctx)
+	return err
+}
+
+// discoverServices discover services page by page, add
+// new ones to the list, remove missing ones if pruning is
+// enabled and report if discovered set has changed since
+// the previous round. Discovery is interrupted once given
+// context is done or the pool is closed
+func (p *ServicesPool) discoverServices(ctx context.Context) (bool, error) {
+	if p.discovery == nil {
+		return false, nil
+	}
+
+	ctx, span := p.tracer.Start(ctx, spanDiscover, trace.WithAttributes(attribute.String(attrPool, p.name)))
+
+	changed, err := p.discoverRound(ctx)
+	span.SetAttributes(attribute.Bool(attrChanged, changed))
+	endSpan(span, err)
+
+	return changed, err
+}
+
+// discoverRound run one discovery round of discoverServices
+func (p *ServicesPool) discoverRound(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.discoveryTimeout)
+	defer cancel()
+	defer context.AfterFunc(p.ctx, cancel)()
+
+	// rounds of the loop and the watch are serialized
+	defer p.discoveryMu.Unlock()
+	p.discoveryMu.Lock()
+
+	var fingerprint discoveryFingerprint
+
+	unconfirmed := make(map[string]struct{}, len(p.seeds))
+	for id := range p.seeds {
+		unconfirmed[id] = struct{}{}
+	}
+
+	var seen map[string]struct{}
+	if p.pruneMissing {
+		seen = make(map[string]struct{})
+	}
+
+	err := discovery.DiscoverPagesContext(ctx, p.discovery, p.name, p.discoveryPageSize, func(services []service.IService) error {
+		page := make([]service.IService, 0, len(services))
+		for _, srv := range services {
+			if srv == nil {
+				continue
+			}
+
+			if p.MutationFnc != nil {
+				var err error
+				if srv, err = p.MutationFnc(srv); err != nil {
+					p.log().Warn("mutate discovered service error", "error", err)
+					continue
+				}
+			}
+
+			fingerprint.add(srv.ID())
+			delete(unconfirmed, srv.ID())
+			if seen != nil {
+				seen[srv.ID()] = struct{}{}
+			}
+			page = append(page, srv)
+		}
+
+		// page is added under single list lock hold
+		p.list.AddAll(page)
+
+		return nil
+	})
+	if p.ctx.Err() != nil {
+		return false, errDiscoveryStopped
+	}
+	if err != nil {
+		return false, fmt.Errorf("discover services: %w", err)
 	}
+
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int(attrDiscovered, fingerprint.count))
+
+	changed := fingerprint != p.lastDiscovered
+	p.lastDiscovered = fingerprint
+
+	// empty result is rather registry outage
+	// than all services going away at once
+	if p.seeds != nil && fingerprint.count > 0 {
+		// seeds are removed once they are added
+		select {
+		case <-p.seeded:
+		case <-ctx.Done():
+			return false, fmt.Errorf("wait for seeds: %w", ctx.Err())
+		}
+
+		p.removeSeeds(unconfirmed)
+		p.seeds = nil
+	}
+
+	if seen != nil && fingerprint.count > 0 {
+		p.removeMissing(seen)
+	}
+
+	return changed, nil
+}
+
+// removeMissing remove members of the list in healthy,
+// jail or review with ids missing in given discovered set
+func (p *ServicesPool) removeMissing(seen map[string]struct{}) {
+	var missing []service.IService
+	for _, srv := range p.list.Healthy() {
+		if _, ok := seen[srv.ID()]; !ok {
+			missing = append(missing, srv)
+		}
+	}
+	for id, srv := range p.list.Jailed() {
+		if _, ok := seen[id]; !ok {
+			missing = append(missing, srv)
+		}
+	}
+	for _, item := range p.list.UnderReview() {
+		if _, ok := seen[item.Service.ID()]; !ok {
+			missing = append(missing, item.Service)
+		}
+	}
+
+	if len(missing) == 0 {
+		return
+	}
+
+	for _, srv := range missing {
+		p.log().Info("service is not discovered anymore and is removed", "service", srv.ID())
+	}
+
+	p.list.ApplyDiff(nil, missing, nil)
+}
+
+// DiscoverServicesLoop discover services periodically
+// with adaptive interval until the pool is closed
+func (p *ServicesPool) DiscoverServicesLoop() {
+	p.log().Info("start discovery loop")
+
+	if p.discoveryInterval.disabled() {
+		p.discoverOnce()
+		return
+	}
+
+	if p.scheduler != nil {
+		p.scheduledDiscovery()
+		return
+	}
+
+	for {
+		select {
+		case <-p.stop:
+			p.log().Warn("stop discovery loop")
+			return
+		default:
+			p.pause.wait(p.stop)
+
+			changed, err := p.discoverServices(p.ctx)
+			if errors.Is(err, errDiscoveryStopped) {
+				return
+			}
+			if err != nil {
+				p.log().Warn("discovery error", "error", err)
+			}
+
+			Sleep(p.discoveryInterval.next(changed), p.stop)
+		}
+	}
+}
+
+// discoverOnce run single discovery round of pool which
+// rediscovery is disabled, later changes are discovered
+// by watching drivers only
+func (p *ServicesPool) discoverOnce() {
+	p.log().Warn("discovery interval is not set, periodic rediscovery is disabled")
+
+	p.pause.wait(p.stop)
+
+	if _, err := p.discoverServices(p.ctx); err != nil && !errors.Is(err, errDiscoveryStopped) {
+		p.log().Warn("discovery error", "error", err)
+	}
+
+	<-p.stop
+	p.log().Warn("stop discovery loop")
+}
+
+// scheduledDiscovery run discovery on the shared
+// scheduler until the pool is stopped
+func (p *ServicesPool) scheduledDiscovery() {
+	cancel := p.scheduler.schedule(func() time.Duration {
+		if p.Paused() {
+			return p.discoveryInterval.current
+		}
+
+		changed, err := p.discoverServices(p.ctx)
+		if err != nil && !errors.Is(err, errDiscoveryStopped) {
+			p.log().Warn("discovery error", "error", err)
+		}
+
+		return p.discoveryInterval.next(changed)
+	})
+
+	<-p.stop
+	cancel()
+
+	p.log().Warn("stop discovery loop")
 }
 
 // NextService returns next active service
@@ -101,8 +502,15 @@ func (p *ServicesPool) List() IServicesList {
 	return p.list
 }
 
-// Close Stop all service pool
+// Close Stop all service pool and wait for discovery loops
+// to return and for the history recorder, consul publisher
+// and state persister to flush, repeated calls are no-op
 func (p *ServicesPool) Close() {
-	p.list.Close()
-	close(p.stop)
+	p.closeOnce.Do(func() {
+		p.list.Close()
+		p.cancel()
+		close(p.stop)
+		p.loops.Wait()
+		p.flushers.Wait()
+	})
 }
@@ -0,0 +1,439 @@
+package pool
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// ShardedServicesList is IServicesList implementation for very
+// large pools that stripes services across N ServicesList shards
+// by service ID. Every shard has its own lock, so healthchecks
+// and reconciliation on one shard don't block selections on others
+type ShardedServicesList struct {
+	serviceName string
+
+	current uint64
+
+	shards []*ServicesList
+}
+
+// newShardedServicesList create new ShardedServicesList
+// with given number of shards sharing given configuration
+func newShardedServicesList(serviceName string, opts *ServicesListOpts) *ShardedServicesList {
+	l := &ShardedServicesList{
+		serviceName: serviceName,
+		shards:      make([]*ServicesList, opts.Shards),
+	}
+
+	for i := range l.shards {
+		l.shards[i] = newServicesList(serviceName, opts)
+	}
+
+	// subscribers receive events of all shards, feature flags
+	// are toggled for all shards, sessions are pinned to
+	// services of any shard and release of connection to or
+	// change of healthy services of any shard wakes up
+	// acquisitions and selections waiting for it
+	for _, shard := range l.shards[1:] {
+		shard.events = l.shards[0].events
+		shard.features = l.shards[0].features
+		shard.sessions = l.shards[0].sessions
+		shard.capacity = l.shards[0].capacity
+		shard.membership = l.shards[0].membership
+	}
+
+	return l
+}
+
+// Healthy return slice of all healthy
+// services in configured order
+func (l *ShardedServicesList) Healthy() []service.IService {
+	if order := l.shards[0].healthyOrder; order != HealthyOrderNone {
+		return l.HealthySorted(order)
+	}
+
+	var healthy []service.IService
+	for _, shard := range l.shards {
+		healthy = append(healthy, shard.Healthy()...)
+	}
+
+	return healthy
+}
+
+// Unhealthy return slice of all unHealthy services
+func (l *ShardedServicesList) Unhealthy() []service.IService {
+	var unHealthy []service.IService
+	for _, shard := range l.shards {
+		unHealthy = append(unHealthy, shard.Unhealthy()...)
+	}
+
+	return unHealthy
+}
+
+// Next returns next healthy service
+// to take a connection
+func (l *ShardedServicesList) Next() service.IService {
+	return l.NextContext(context.Background())
+}
+
+// NextContext returns next healthy service to take a
+// connection, shards are selected in proportion to their
+// healthy count, so every member takes an even share of
+// connections whatever shard owns it. Shard which has no
+// eligible service falls back to the following ones
+func (l *ShardedServicesList) NextContext(ctx context.Context) service.IService {
+	ctx, span, traced := traceNext(ctx, l.shards[0].tracer, l.serviceName)
+
+	start := l.pickShard(atomic.AddUint64(&l.current, 1))
+
+	var srv service.IService
+	for i := 0; i < len(l.shards) && srv == nil; i++ {
+		srv = l.shards[(start+i)%len(l.shards)].next(ctx)
+	}
+
+	l.shards[0].metrics.observeSelection(ctx, l.serviceName, srv)
+
+	if traced {
+		endNext(span, srv)
+	}
+
+	return srv
+}
+
+// pickShard return index of the shard which owns given
+// position of the round over healthy services of all shards
+func (l *ShardedServicesList) pickShard(position uint64) int {
+	counts := make([]int, len(l.shards))
+
+	var total int
+	for i, shard := range l.shards {
+		counts[i] = len(shard.healthySnapshot())
+		total += counts[i]
+	}
+
+	if total == 0 {
+		return int(position % uint64(len(l.shards)))
+	}
+
+	left := int(position % uint64(total))
+	for i, count := range counts {
+		if left < count {
+			return i
+		}
+		left -= count
+	}
+
+	return 0
+}
+
+// ObserveRequest report duration of request
+// to given service made by the caller
+func (l *ShardedServicesList) ObserveRequest(ctx context.Context, srv service.IService, duration time.Duration) {
+	l.shard(srv.ID()).ObserveRequest(ctx, srv, duration)
+}
+
+// NextLeastLoaded returns the least loaded healthy
+// service with given tag across all shards
+func (l *ShardedServicesList) NextLeastLoaded(tag string) service.IService {
+	var leastLoadedSrv service.IService
+	minLoad := math.MaxFloat64
+
+	for _, shard := range l.shards {
+		srv := shard.NextLeastLoaded(tag)
+		if srv == nil {
+			continue
+		}
+
+		load := shard.score(srv, float64(srv.Load()))
+		if load < minLoad {
+			leastLoadedSrv = srv
+			minLoad = load
+		}
+	}
+
+	return leastLoadedSrv
+}
+
+// AnyByTag returns any service with given tag from healthy list
+func (l *ShardedServicesList) AnyByTag(tag string) service.IService {
+	for _, shard := range l.shards {
+		if srv := shard.AnyByTag(tag); srv != nil {
+			return srv
+		}
+	}
+
+	return nil
+}
+
+// Add service to the list
+func (l *ShardedServicesList) Add(srv service.IService) {
+	l.shard(srv.ID()).Add(srv)
+}
+
+// IsServiceExists check is given service is
+// already in list (healthy, jail or review)
+func (l *ShardedServicesList) IsServiceExists(srv service.IService) bool {
+	if srv == nil {
+		return false
+	}
+
+	return l.shard(srv.ID()).IsServiceExists(srv)
+}
+
+// HealthChecks pings the healthy services
+// of all shards concurrently and update the statuses
+func (l *ShardedServicesList) HealthChecks() {
+	l.HealthChecksContext(context.Background())
+}
+
+// HealthChecksContext pings the healthy services of all
+// shards concurrently until given context is done
+func (l *ShardedServicesList) HealthChecksContext(ctx context.Context) {
+	l.each(func(shard *ServicesList) {
+		shard.HealthChecksContext(ctx)
+	})
+}
+
+// HealthChecksLoop spawn independent healthchecks
+// loops for all shards and wait for them to stop
+func (l *ShardedServicesList) HealthChecksLoop() {
+	l.each(func(shard *ServicesList) {
+		shard.HealthChecksLoop()
+	})
+}
+
+// TryUpService try to up service until it's up
+func (l *ShardedServicesList) TryUpService(srv service.IService, try int) {
+	l.shard(srv.ID()).TryUpService(srv, try)
+}
+
+// TryUpServiceContext try to up
+// service until given context is done
+func (l *ShardedServicesList) TryUpServiceContext(ctx context.Context, srv service.IService, try int) {
+	l.shard(srv.ID()).TryUpServiceContext(ctx, srv, try)
+}
+
+// FromHealthyToJail move Unhealthy service
+// from Healthy slice to Jail map
+func (l *ShardedServicesList) FromHealthyToJail(id string) {
+	l.shard(id).FromHealthyToJail(id)
+}
+
+// FromJailToHealthy move Healthy service
+// from Jail map to Healthy slice
+func (l *ShardedServicesList) FromJailToHealthy(srv service.IService) {
+	l.shard(srv.ID()).FromJailToHealthy(srv)
+}
+
+// RemoveFromJail remove given
+// service from jail map
+func (l *ShardedServicesList) RemoveFromJail(srv service.IService) {
+	l.shard(srv.ID()).RemoveFromJail(srv)
+}
+
+// RemoveFromHealthyByIndex removes service from healthy by
+// given index in the slice returned by Healthy
+//
+// Deprecated: indexes are shifted by concurrent
+// removals, use RemoveFromHealthyByID instead
+func (l *ShardedServicesList) RemoveFromHealthyByIndex(i int) {
+	healthy := l.Healthy()
+	if i < 0 || i >= len(healthy) {
+		return
+	}
+
+	l.RemoveFromHealthyByID(healthy[i].ID())
+}
+
+// RemoveFromHealthyByID removes service with given
+// id from healthy slice of the shard it belongs to
+func (l *ShardedServicesList) RemoveFromHealthyByID(id string) bool {
+	return l.shard(id).RemoveFromHealthyByID(id)
+}
+
+// Close Stop all shards
+func (l *ShardedServicesList) Close() {
+	for _, shard := range l.shards {
+		shard.Close()
+	}
+}
+
+// Shuffle randomly shuffles every shard
+func (l *ShardedServicesList) Shuffle() {
+	for _, shard := range l.shards {
+		shard.Shuffle()
+	}
+}
+
+// CountAll returns sum of num healthy, jailed and
+// under review services of all shards together
+func (l *ShardedServicesList) CountAll() int {
+	count := 0
+	for _, shard := range l.shards {
+		count += shard.CountAll()
+	}
+
+	return count
+}
+
+// Jailed returns a copy of jail maps of all shards
+func (l *ShardedServicesList) Jailed() map[string]service.IService {
+	jailed := make(map[string]service.IService)
+	for _, shard := range l.shards {
+		for id, srv := range shard.Jailed() {
+			jailed[id] = srv
+		}
+	}
+
+	return jailed
+}
+
+func (l *ShardedServicesList) ModifyHealthy(modifier func(srv service.IService)) {
+	for _, shard := range l.shards {
+		shard.ModifyHealthy(modifier)
+	}
+}
+
+// UnderReview return slice of all services
+// waiting for review in quarantine
+func (l *ShardedServicesList) UnderReview() []ReviewItem {
+	var items []ReviewItem
+	for _, shard := range l.shards {
+		items = append(items, shard.UnderReview()...)
+	}
+
+	return items
+}
+
+// Approve release service with given id from
+// quarantine to jail
+func (l *ShardedServicesList) Approve(id string) error {
+	return l.shard(id).Approve(id)
+}
+
+// Reject remove service with given
+// id from quarantine and close it
+func (l *ShardedServicesList) Reject(id string) error {
+	return l.shard(id).Reject(id)
+}
+
+// ReportVerificationFailure register failed
+// verification of given service result
+func (l *ShardedServicesList) ReportVerificationFailure(srv service.IService) {
+	l.shard(srv.ID()).ReportVerificationFailure(srv)
+}
+
+// AvailabilityReport return healthchecks outcomes of all
+// shards within [from, to) aggregated by given window
+func (l *ShardedServicesList) AvailabilityReport(window time.Duration, from, to time.Time) *AvailabilityReport {
+	report := l.shards[0].AvailabilityReport(window, from, to)
+
+	pool := make(map[int64]*AvailabilityItem, len(report.Pool))
+	for i := range report.Pool {
+		pool[report.Pool[i].Start.Unix()] = &report.Pool[i]
+	}
+
+	for _, shard := range l.shards[1:] {
+		shardReport := shard.AvailabilityReport(window, from, to)
+		report.Services = append(report.Services, shardReport.Services...)
+
+		for _, shardItem := range shardReport.Pool {
+			item, ok := pool[shardItem.Start.Unix()]
+			if !ok {
+				item = &AvailabilityItem{Start: shardItem.Start, End: shardItem.End}
+				pool[shardItem.Start.Unix()] = item
+			}
+			item.Checks += shardItem.Checks
+			item.Failures += shardItem.Failures
+		}
+	}
+
+	report.Pool = make([]AvailabilityItem, 0, len(pool))
+	for _, item := range pool {
+		report.Pool = append(report.Pool, *item.withAvailability())
+	}
+
+	sortAvailabilityItems(report.Services)
+	sortAvailabilityItems(report.Pool)
+
+	return report
+}
+
+// Snapshot return point-in-time snapshot
+// of membership of all shards
+func (l *ShardedServicesList) Snapshot() *PoolState {
+	state := l.shards[0].Snapshot()
+	state.Generation = l.Generation()
+	state.Status = l.Status()
+	for _, shard := range l.shards[1:] {
+		state.Services = append(state.Services, shard.Snapshot().Services...)
+	}
+
+	sort.Slice(state.Services, func(i, j int) bool {
+		return state.Services[i].ID < state.Services[j].ID
+	})
+
+	return state
+}
+
+// Policies return list routing policies
+func (l *ShardedServicesList) Policies() []IPolicy {
+	return l.shards[0].Policies()
+}
+
+// MemoryUsage return estimation of memory
+// used by bookkeeping of all shards
+func (l *ShardedServicesList) MemoryUsage() MemoryUsage {
+	var usage MemoryUsage
+	for i, shard := range l.shards {
+		// sessions and statistics are shared by shards
+		shardUsage := shard.memoryUsage(i == 0)
+		usage.TrackedServices += shardUsage.TrackedServices
+		usage.HistoryEntries += shardUsage.HistoryEntries
+		usage.AvailabilityBuckets += shardUsage.AvailabilityBuckets
+		usage.Tombstones += shardUsage.Tombstones
+		usage.RejoinStates += shardUsage.RejoinStates
+		usage.Sessions += shardUsage.Sessions
+		usage.StatsServices += shardUsage.StatsServices
+		usage.Leases += shardUsage.Leases
+		usage.Bytes += shardUsage.Bytes
+	}
+
+	return usage
+}
+
+// shard return shard that owns service with given id
+func (l *ShardedServicesList) shard(id string) *ServicesList {
+	return l.shards[l.shardIndex(id)]
+}
+
+// shardIndex return index of shard
+// that owns service with given id
+func (l *ShardedServicesList) shardIndex(id string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+
+	return int(h.Sum32() % uint32(len(l.shards)))
+}
+
+// each call given function for
+// every shard concurrently and wait
+func (l *ShardedServicesList) each(fn func(shard *ServicesList)) {
+	var wg sync.WaitGroup
+	for _, shard := range l.shards {
+		wg.Add(1)
+		shard.goTask(taskShard, func() {
+			defer wg.Done()
+			fn(shard)
+		})
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,123 @@
+package pool
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestShardedServicesList(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Shards:         4,
+	})
+
+	sharded, ok := list.(*ShardedServicesList)
+	if !ok {
+		t.Fatalf("sharded list expected, got %T", list)
+	}
+
+	for i := 0; i < 100; i++ {
+		list.Add(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+
+	if list.CountAll() != 100 || len(list.Healthy()) != 100 {
+		t.Fatalf("expected 100 healthy services, got %d", list.CountAll())
+	}
+
+	for i, shard := range sharded.shards {
+		if len(shard.Healthy()) == 0 {
+			t.Errorf("shard %d should own some services", i)
+		}
+	}
+
+	srv := list.Next()
+	if srv == nil {
+		t.Fatalf("next service expected")
+	}
+
+	list.FromHealthyToJail(srv.ID())
+
+	if _, ok := list.Jailed()[srv.ID()]; !ok {
+		t.Errorf("service should be moved to the jail of its shard")
+	}
+
+	if !list.IsServiceExists(srv) || len(list.Healthy()) != 99 {
+		t.Errorf("jailed service should still exist in the list")
+	}
+
+	if state := list.Snapshot(); len(state.Services) != 100 {
+		t.Errorf("snapshot should contain services of all shards, got %d", len(state.Services))
+	}
+}
+
+func TestShardedServicesListSpread(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Shards:         4,
+	})
+	defer list.Close()
+
+	for i := 0; i < 10; i++ {
+		list.Add(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+
+	// members of shards of any size take even share of selections
+	selections := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		srv := list.Next()
+		if srv == nil {
+			t.Fatalf("next service expected")
+		}
+		selections[srv.ID()]++
+	}
+
+	if len(selections) != 10 {
+		t.Fatalf("every member should be selected, got %d", len(selections))
+	}
+	for id, count := range selections {
+		if count < 90 || count > 110 {
+			t.Errorf("member %s should take about 100 of 1000 selections, got %d", id, count)
+		}
+	}
+}
+
+func TestShardedServicesListSaturation(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Shards:         4,
+	})
+	defer list.Close()
+
+	sharded := list.(*ShardedServicesList)
+
+	services := make([]*service.BaseService, 10)
+	for i := range services {
+		services[i] = newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)).(*service.BaseService)
+		services[i].SetLoad(0)
+		list.Add(services[i])
+	}
+
+	// members of the shard of the first one are saturated
+	busy := sharded.shard(services[0].ID())
+	saturated := 0
+	for _, srv := range services {
+		if sharded.shard(srv.ID()) == busy {
+			srv.SetLoad(1)
+			saturated++
+		}
+	}
+
+	expected := float64(saturated) / float64(len(services))
+	if saturation := list.Saturation(); saturation < expected-0.001 || saturation > expected+0.001 {
+		t.Errorf("expected saturation weighted by shard size %.2f, got %.2f", expected, saturation)
+	}
+}
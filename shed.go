@@ -0,0 +1,246 @@
+package pool
+
+import (
+	"context"
+	"math/rand"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const defaultShedThreshold = 0.8
+
+// ShedPolicy represent how requests are
+// shed when the list is saturated
+type ShedPolicy int
+
+const (
+	// ShedNone select services regardless of saturation
+	ShedNone ShedPolicy = iota
+
+	// ShedLowestPriority reject requests of the lowest priority first,
+	// higher priorities are rejected as saturation grows further
+	// above the threshold. Critical requests are never rejected
+	ShedLowestPriority
+
+	// ShedProbabilistic reject non-critical requests with probability
+	// proportional to saturation above the threshold, so load is
+	// reduced gradually before services are fully saturated
+	ShedProbabilistic
+)
+
+// String return shed policy name
+func (p ShedPolicy) String() string {
+	switch p {
+	case ShedLowestPriority:
+		return "lowest_priority"
+	case ShedProbabilistic:
+		return "probabilistic"
+	default:
+		return "none"
+	}
+}
+
+// Priority represent importance of request
+// taken into account by load shedding
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+// String return priority name
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return "normal"
+	}
+}
+
+// priorityKey is context key of request priority
+type priorityKey struct{}
+
+// ContextWithPriority return context with given request
+// priority that is taken into account by load shedding
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext return request priority from given
+// context, PriorityNormal is returned if it is not set
+func PriorityFromContext(ctx context.Context) Priority {
+	if ctx == nil {
+		return PriorityNormal
+	}
+
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+
+	return PriorityNormal
+}
+
+// ShedOpts is options that configure load shedding. Saturation
+// of the list is the mean saturation of its healthy services,
+// which is theirs reported load or share of configured capacity
+// taken by in-flight connections, whichever is higher
+type ShedOpts struct {
+	Policy    ShedPolicy // how requests are shed above the threshold
+	Threshold float64    // saturation in (0, 1) above which requests are shed (0.8 by default)
+	Capacity  int        // in-flight connections per service taken as its full saturation (0 to use reported load only)
+}
+
+// shedding is load shedding policy of the list
+type shedding struct {
+	opts ShedOpts
+}
+
+// newShedding create load shedding policy with given
+// configuration, nil is returned if shedding is disabled
+func newShedding(opts *ShedOpts) *shedding {
+	if opts == nil || opts.Policy == ShedNone {
+		return nil
+	}
+
+	s := &shedding{opts: *opts}
+	if s.opts.Threshold <= 0 || s.opts.Threshold >= 1 {
+		s.opts.Threshold = defaultShedThreshold
+	}
+
+	return s
+}
+
+// mayShed check if request of given priority could be
+// rejected at given saturation, probabilistic shedding
+// could reject any request above the threshold
+func (s *shedding) mayShed(priority Priority, saturation float64) bool {
+	if s.opts.Policy != ShedProbabilistic {
+		return s.shed(priority, saturation)
+	}
+
+	return priority < PriorityCritical && saturation > s.opts.Threshold
+}
+
+// shed check if request of given priority
+// is rejected at given saturation
+func (s *shedding) shed(priority Priority, saturation float64) bool {
+	if priority >= PriorityCritical {
+		return false
+	}
+
+	excess := (saturation - s.opts.Threshold) / (1 - s.opts.Threshold)
+	if excess <= 0 {
+		return false
+	}
+
+	switch s.opts.Policy {
+	case ShedLowestPriority:
+		return excess > float64(max(priority, 0))/float64(PriorityCritical)
+	case ShedProbabilistic:
+		return rand.Float64() < excess
+	default:
+		return false
+	}
+}
+
+// saturation return saturation of given service
+func (s *shedding) saturation(srv service.IService, inFlight int) float64 {
+	saturation := float64(srv.Load())
+	if s.opts.Capacity > 0 {
+		saturation = max(saturation, float64(inFlight)/float64(s.opts.Capacity))
+	}
+
+	return min(saturation, 1)
+}
+
+// saturationLocked return mean saturation of healthy
+// services. Should be called under the list lock
+func (l *ServicesList) saturationLocked() float64 {
+	total, count := l.saturationSumLocked()
+	if count == 0 {
+		return 0
+	}
+
+	return total / float64(count)
+}
+
+// saturationSumLocked return total saturation and number
+// of healthy services. Should be called under the list lock
+func (l *ServicesList) saturationSumLocked() (float64, int) {
+	var (
+		total float64
+		count int
+	)
+	for _, srv := range l.healthy {
+		if srv.Status() != service.StatusHealthy {
+			continue
+		}
+
+		if l.shedding == nil {
+			total += min(float64(srv.Load()), 1)
+		} else {
+			total += l.shedding.saturation(srv, l.connections.count(srv.ID()))
+		}
+		count++
+	}
+
+	return total, count
+}
+
+// shedLocked check if request with given context is rejected
+// by configured shed policy. Should be called under the list lock
+func (l *ServicesList) shedLocked(ctx context.Context) bool {
+	if l.shedding == nil {
+		return false
+	}
+
+	priority := PriorityFromContext(ctx)
+	if !l.shedding.shed(priority, l.saturationLocked()) {
+		return false
+	}
+
+	l.metrics.observeShed(l.serviceName, priority)
+
+	return true
+}
+
+// Saturation return mean saturation of healthy services
+// in [0, 1] that is taken into account by load shedding
+func (l *ServicesList) Saturation() float64 {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	return l.saturationLocked()
+}
+
+// Saturation return mean saturation of healthy services
+// of all shards, so every shard is weighted by the
+// number of its healthy services
+func (l *ShardedServicesList) Saturation() float64 {
+	var (
+		total float64
+		count int
+	)
+	for _, shard := range l.shards {
+		shard.mu.RLock()
+		sum, n := shard.saturationSumLocked()
+		shard.mu.RUnlock()
+
+		total += sum
+		count += n
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return total / float64(count)
+}
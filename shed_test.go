@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestShedLowestPriority(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Shed:           &ShedOpts{Policy: ShedLowestPriority, Threshold: 0.4},
+	})
+
+	srv := newHealthyService("https://1gateway.fm").(*service.BaseService)
+	list.Add(srv)
+
+	tests := []struct {
+		load     float32
+		selected []Priority
+		shed     []Priority
+	}{
+		{0.3, []Priority{PriorityLow, PriorityNormal, PriorityHigh, PriorityCritical}, nil},
+		{0.5, []Priority{PriorityNormal, PriorityHigh, PriorityCritical}, []Priority{PriorityLow}},
+		{0.7, []Priority{PriorityHigh, PriorityCritical}, []Priority{PriorityLow, PriorityNormal}},
+		{1, []Priority{PriorityCritical}, []Priority{PriorityLow, PriorityNormal, PriorityHigh}},
+	}
+
+	for _, tt := range tests {
+		srv.SetLoad(tt.load)
+
+		for _, priority := range tt.selected {
+			if list.NextContext(ContextWithPriority(context.Background(), priority)) == nil {
+				t.Errorf("load %.1f: request of %s priority should not be shed", tt.load, priority)
+			}
+		}
+		for _, priority := range tt.shed {
+			if list.NextContext(ContextWithPriority(context.Background(), priority)) != nil {
+				t.Errorf("load %.1f: request of %s priority should be shed", tt.load, priority)
+			}
+		}
+	}
+}
+
+func TestShedProbabilistic(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Shed:           &ShedOpts{Policy: ShedProbabilistic, Threshold: 0.5, Capacity: 4},
+	})
+
+	for _, addr := range []string{"https://1gateway.fm", "https://2gateway.fm"} {
+		srv := newHealthyService(addr).(*service.BaseService)
+		srv.SetLoad(0)
+		list.Add(srv)
+	}
+
+	for i := 0; i < 4; i++ {
+		if srv, _ := list.Acquire(context.Background()); srv == nil {
+			t.Fatalf("unsaturated list should not shed requests")
+		}
+	}
+
+	critical := ContextWithPriority(context.Background(), PriorityCritical)
+
+	// 6 of 8 slots are taken, half of requests above the threshold are shed
+	for i := 0; i < 2; i++ {
+		if srv, _ := list.Acquire(critical); srv == nil {
+			t.Fatalf("critical requests should never be shed")
+		}
+	}
+	if saturation := list.Saturation(); saturation != 0.75 {
+		t.Fatalf("expected saturation 0.75, got %.2f", saturation)
+	}
+
+	shed := 0
+	for i := 0; i < 1000; i++ {
+		if list.Next() == nil {
+			shed++
+		}
+	}
+	if shed < 400 || shed > 600 {
+		t.Errorf("expected about half of requests shed, got %d of 1000", shed)
+	}
+
+	for i := 0; i < 100; i++ {
+		if list.NextContext(critical) == nil {
+			t.Fatalf("critical requests should never be shed")
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+)
+
+// SignedMembershipDocument is membership document with ed25519
+// signature of its exact json bytes, so intermediaries can't
+// inject rogue services into the membership
+type SignedMembershipDocument struct {
+	Document  json.RawMessage `json:"document"`
+	KeyID     string          `json:"key_id"`
+	Signature []byte          `json:"signature"`
+}
+
+// SignMembership sign given membership document
+// with given private key identified by key id
+func SignMembership(doc *MembershipDocument, keyID string, key ed25519.PrivateKey) (*SignedMembershipDocument, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal membership document: %w", err)
+	}
+
+	return &SignedMembershipDocument{
+		Document:  raw,
+		KeyID:     keyID,
+		Signature: ed25519.Sign(key, raw),
+	}, nil
+}
+
+// VerifyMembership verify signature of given document with
+// trusted public key of its key id and return the document
+func VerifyMembership(signed *SignedMembershipDocument, trusted map[string]ed25519.PublicKey) (*MembershipDocument, error) {
+	key, ok := trusted[signed.KeyID]
+	if !ok {
+		return nil, ErrInvalidSignature{KeyID: signed.KeyID, Reason: "key is not trusted"}
+	}
+
+	if !ed25519.Verify(key, signed.Document, signed.Signature) {
+		return nil, ErrInvalidSignature{KeyID: signed.KeyID, Reason: "signature mismatch"}
+	}
+
+	var doc MembershipDocument
+	if err := json.Unmarshal(signed.Document, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal membership document: %w", err)
+	}
+
+	return &doc, nil
+}
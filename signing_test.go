@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignedMembershipImport(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("unexpected key generation error: %s", err)
+	}
+
+	opts := &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	}
+
+	source := NewServicesList("testServicesList", opts)
+	source.Add(newHealthyService("https://1gateway.fm"))
+
+	rec := httptest.NewRecorder()
+	NewAdminHandlerWithOpts(source, &AdminHandlerOpts{SigningKeyID: "gen-1", SigningKey: private}).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/membership", nil))
+
+	var signed SignedMembershipDocument
+	if err := json.NewDecoder(rec.Body).Decode(&signed); err != nil {
+		t.Fatalf("unexpected signed document decode error: %s", err)
+	}
+
+	target := NewServicesList("testServicesList", opts)
+	admin := NewAdminHandlerWithOpts(target, &AdminHandlerOpts{TrustedKeys: map[string]ed25519.PublicKey{"gen-1": public}, Unauthenticated: true})
+
+	// rogue service injected by intermediary
+	tampered := signed
+	tampered.Document = bytes.Replace(signed.Document, []byte("https://1gateway.fm"), []byte("https://rogue.fm"), 1)
+
+	body, _ := json.Marshal(&tampered)
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusForbidden || target.CountAll() != 0 {
+		t.Fatalf("tampered document should be rejected, got status %d", rec.Code)
+	}
+
+	body, _ = json.Marshal(&signed)
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusNoContent || target.CountAll() != 1 {
+		t.Fatalf("signed document should be imported, got status %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/membership", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("replayed signed document should be rejected, got status %d", rec.Code)
+	}
+
+	if _, err := VerifyMembership(&signed, nil); !errors.As(err, &ErrInvalidSignature{}) {
+		t.Errorf("document signed with untrusted key should be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package pool
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	// MembershipHealthy is means that service
+	// is in healthy slice of the list
+	MembershipHealthy = "healthy"
+
+	// MembershipJailed is means that service is in jail
+	MembershipJailed = "jailed"
+
+	// MembershipReview is means that service
+	// is quarantined and waits for review
+	MembershipReview = "review"
+)
+
+// PoolState is point-in-time snapshot
+// of services list membership
+type PoolState struct {
+	Name       string            `json:"name"`
+	Time       time.Time         `json:"time"`
+	Generation uint64            `json:"generation"`
+	Paused     bool              `json:"paused"`
+	Draining   bool              `json:"draining,omitempty"`
+	Status     ListStatus        `json:"status"`
+	Build      BuildInfo         `json:"build"`
+	Config     ConfigInfo        `json:"config"`
+	Services   []ServiceSnapshot `json:"services"`
+}
+
+// ServiceSnapshot is point-in-time
+// snapshot of one list member
+type ServiceSnapshot struct {
+	service.Record
+	Membership  string            `json:"membership"`
+	Weight      int               `json:"weight"`             // relative capacity of weighted balancing
+	Priority    int               `json:"priority,omitempty"` // priority level, 0 is the highest
+	Spare       SpareState        `json:"spare,omitempty"`
+	Maintenance MaintenanceAction `json:"maintenance,omitempty"`  // action of active maintenance window
+	Drained     bool              `json:"drained,omitempty"`      // service is drained and takes no new work
+	JailedSince *time.Time        `json:"jailed_since,omitempty"` // time jailed service is jailed since
+	Leases      int               `json:"leases,omitempty"`       // number of active and draining leases
+	InFlight    int               `json:"in_flight,omitempty"`    // number of in-flight connections acquired by Acquire
+}
+
+// Snapshot return point-in-time snapshot of
+// services list membership sorted by service id
+func (l *ServicesList) Snapshot() *PoolState {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	state := &PoolState{
+		Name:       l.serviceName,
+		Time:       time.Now(),
+		Generation: l.Generation(),
+		Paused:     l.Paused(),
+		Draining:   l.Draining(),
+		Status:     l.statusLocked(),
+		Build:      ReadBuildInfo(),
+		Config:     l.config,
+		Services:   make([]ServiceSnapshot, 0, len(l.healthy)+len(l.jail)+len(l.review)),
+	}
+
+	for _, srv := range l.healthy {
+		state.Services = append(state.Services, newServiceSnapshot(srv, MembershipHealthy))
+	}
+
+	for id, srv := range l.jail {
+		snapshot := newServiceSnapshot(srv, MembershipJailed)
+		if since, ok := l.jailedAt[id]; ok {
+			snapshot.JailedSince = &since
+		}
+		state.Services = append(state.Services, snapshot)
+	}
+
+	for _, item := range l.review {
+		state.Services = append(state.Services, newServiceSnapshot(item.Service, MembershipReview))
+	}
+
+	for i := range state.Services {
+		state.Services[i].Spare = l.spares.state(state.Services[i].ID, state.Generation, l.healthy)
+		state.Services[i].Maintenance = l.maintenance.action(state.Services[i].ID)
+		state.Services[i].Drained = l.drains.has(state.Services[i].ID)
+		state.Services[i].Leases = l.leases.count(state.Services[i].ID)
+		state.Services[i].InFlight = l.connections.count(state.Services[i].ID)
+	}
+
+	sort.Slice(state.Services, func(i, j int) bool {
+		return state.Services[i].ID < state.Services[j].ID
+	})
+
+	return state
+}
+
+// newServiceSnapshot create ServiceSnapshot
+// of given service with given membership
+func newServiceSnapshot(srv service.IService, membership string) ServiceSnapshot {
+	return ServiceSnapshot{
+		Record:     service.NewRecord(srv),
+		Membership: membership,
+		Weight:     service.Weight(srv),
+		Priority:   service.Priority(srv),
+	}
+}
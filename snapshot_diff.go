@@ -0,0 +1,188 @@
+package pool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangeKind represent kind of
+// service change between snapshots
+type ChangeKind string
+
+const (
+	// ChangeAdded is service that
+	// is present only in the later snapshot
+	ChangeAdded ChangeKind = "added"
+
+	// ChangeRemoved is service that is
+	// present only in the earlier snapshot
+	ChangeRemoved ChangeKind = "removed"
+
+	// ChangeModified is service present in both
+	// snapshots with different fields
+	ChangeModified ChangeKind = "modified"
+)
+
+// FieldChange is change of one field between snapshots
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ServiceChange is change of one service between snapshots
+type ServiceChange struct {
+	ID      string        `json:"id"`
+	Address string        `json:"address"`
+	Kind    ChangeKind    `json:"kind"`
+	Fields  []FieldChange `json:"fields,omitempty"` // changed fields of modified service
+}
+
+// StateDiff is difference between two pool snapshots
+type StateDiff struct {
+	Name     string          `json:"name"`
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`
+	Pool     []FieldChange   `json:"pool,omitempty"` // changed pool-wide fields
+	Services []ServiceChange `json:"services,omitempty"`
+}
+
+// DiffStates return difference between given earlier and later
+// snapshots: added and removed services, changes of membership,
+// status, load and other fields of the same services and
+// changes of pool status. Load is compared with two decimals
+// precision, so noise is not reported
+func DiffStates(from, to *PoolState) *StateDiff {
+	diff := &StateDiff{
+		Name: to.Name,
+		From: from.Time,
+		To:   to.Time,
+		Pool: diffFields(poolFields(from), poolFields(to)),
+	}
+
+	earlier := make(map[string]ServiceSnapshot, len(from.Services))
+	for _, srv := range from.Services {
+		earlier[srv.ID] = srv
+	}
+
+	later := make(map[string]struct{}, len(to.Services))
+	for _, srv := range to.Services {
+		later[srv.ID] = struct{}{}
+
+		previous, ok := earlier[srv.ID]
+		if !ok {
+			diff.Services = append(diff.Services, ServiceChange{ID: srv.ID, Address: srv.Address, Kind: ChangeAdded})
+			continue
+		}
+
+		if fields := diffFields(serviceFields(previous), serviceFields(srv)); len(fields) != 0 {
+			diff.Services = append(diff.Services, ServiceChange{ID: srv.ID, Address: srv.Address, Kind: ChangeModified, Fields: fields})
+		}
+	}
+
+	// snapshots are sorted by id, so removed
+	// services are reported in the same order
+	for _, srv := range from.Services {
+		if _, ok := later[srv.ID]; !ok {
+			diff.Services = append(diff.Services, ServiceChange{ID: srv.ID, Address: srv.Address, Kind: ChangeRemoved})
+		}
+	}
+
+	return diff
+}
+
+// Empty check if snapshots are the same
+func (d *StateDiff) Empty() bool {
+	return len(d.Pool) == 0 && len(d.Services) == 0
+}
+
+// String render human-readable change report, e.g.
+//
+//	pool prover changes from 10:00:00 to 10:05:00:
+//	  status: healthy -> unhealthy
+//	  + 1a2b3c https://1gateway.fm
+//	  - 4d5e6f https://2gateway.fm
+//	  ~ 7a8b9c https://3gateway.fm membership: healthy -> jailed, status: healthy -> unhealthy
+func (d *StateDiff) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "pool %s changes from %s to %s:\n", d.Name, d.From.UTC().Format(time.RFC3339), d.To.UTC().Format(time.RFC3339))
+
+	if d.Empty() {
+		b.WriteString("  no changes\n")
+		return b.String()
+	}
+
+	for _, field := range d.Pool {
+		fmt.Fprintf(&b, "  %s\n", field)
+	}
+
+	for _, change := range d.Services {
+		switch change.Kind {
+		case ChangeAdded:
+			fmt.Fprintf(&b, "  + %s %s\n", change.ID, change.Address)
+		case ChangeRemoved:
+			fmt.Fprintf(&b, "  - %s %s\n", change.ID, change.Address)
+		default:
+			fields := make([]string, 0, len(change.Fields))
+			for _, field := range change.Fields {
+				fields = append(fields, field.String())
+			}
+
+			fmt.Fprintf(&b, "  ~ %s %s %s\n", change.ID, change.Address, strings.Join(fields, ", "))
+		}
+	}
+
+	return b.String()
+}
+
+// String render field change, empty values are rendered as "none"
+func (c FieldChange) String() string {
+	from, to := c.From, c.To
+	if from == "" {
+		from = "none"
+	}
+	if to == "" {
+		to = "none"
+	}
+
+	return fmt.Sprintf("%s: %s -> %s", c.Field, from, to)
+}
+
+// diffFields return changes between given ordered field values
+func diffFields(from, to [][2]string) []FieldChange {
+	var changes []FieldChange
+	for i := range from {
+		if from[i][1] != to[i][1] {
+			changes = append(changes, FieldChange{Field: from[i][0], From: from[i][1], To: to[i][1]})
+		}
+	}
+
+	return changes
+}
+
+// poolFields return compared pool-wide fields of given snapshot
+func poolFields(state *PoolState) [][2]string {
+	return [][2]string{
+		{"status", string(state.Status)},
+		{"paused", strconv.FormatBool(state.Paused)},
+		{"draining", strconv.FormatBool(state.Draining)},
+		{"config", state.Config.Fingerprint},
+	}
+}
+
+// serviceFields return compared fields of given service snapshot
+func serviceFields(srv ServiceSnapshot) [][2]string {
+	return [][2]string{
+		{"address", srv.Address},
+		{"membership", srv.Membership},
+		{"status", srv.Status},
+		{"load", strconv.FormatFloat(float64(srv.Load), 'f', 2, 32)},
+		{"spare", string(srv.Spare)},
+		{"maintenance", string(srv.Maintenance)},
+		{"leases", strconv.Itoa(srv.Leases)},
+		{"in_flight", strconv.Itoa(srv.InFlight)},
+	}
+}
@@ -0,0 +1,71 @@
+package pool
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestDiffStates(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	kept := newHealthyService("https://1gateway.fm").(*service.BaseService)
+	removed := newHealthyService("https://2gateway.fm")
+	jailed := newHealthyService("https://3gateway.fm")
+	list.Add(kept)
+	list.Add(removed)
+	list.Add(jailed)
+
+	from := list.Snapshot()
+
+	if diff := DiffStates(from, list.Snapshot()); !diff.Empty() {
+		t.Fatalf("same membership should not be reported, got %s", diff)
+	}
+
+	// load noise is not reported
+	kept.SetLoad(kept.Load() - 0.001)
+
+	added := newHealthyService("https://4gateway.fm")
+	list.Add(added)
+	list.ApplyDiff(nil, []service.IService{removed}, nil)
+	list.FromHealthyToJail(jailed.ID())
+
+	diff := DiffStates(from, list.Snapshot())
+
+	kinds := make(map[string]ChangeKind)
+	for _, change := range diff.Services {
+		kinds[change.ID] = change.Kind
+	}
+
+	expected := map[string]ChangeKind{
+		added.ID():   ChangeAdded,
+		removed.ID(): ChangeRemoved,
+		jailed.ID():  ChangeModified,
+	}
+	if len(kinds) != len(expected) {
+		t.Fatalf("unexpected changes %+v", diff.Services)
+	}
+	for id, kind := range expected {
+		if kinds[id] != kind {
+			t.Errorf("service %s: expected %s change, got %s", id, kind, kinds[id])
+		}
+	}
+
+	report := diff.String()
+	for _, line := range []string{
+		"+ " + added.ID() + " https://4gateway.fm",
+		"- " + removed.ID() + " https://2gateway.fm",
+		"~ " + jailed.ID() + " https://3gateway.fm membership: healthy -> jailed, status: healthy -> unhealthy",
+	} {
+		if !strings.Contains(report, line) {
+			t.Errorf("report should contain %q, got:\n%s", line, report)
+		}
+	}
+}
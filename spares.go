@@ -0,0 +1,240 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// SpareState represent state of hot spare service
+type SpareState string
+
+const (
+	// SpareStandby is spare that receives only
+	// healthchecks and warm-up traffic
+	SpareStandby SpareState = "standby"
+
+	// SpareActive is spare activated manually or because
+	// of low healthy capacity, it takes regular traffic
+	SpareActive SpareState = "active"
+)
+
+// SparesOpts is options that configure
+// hot spares of services list
+type SparesOpts struct {
+	Tag         string  // services with given tag are designated as spares on admission (empty for manual designation only)
+	MinActive   int     // standby spares are activated automatically while fewer active healthy services are present, per shard for sharded lists (0 to disable)
+	WarmUpRatio float64 // share of selections routed to healthy standby spares to keep them warm (0 to disable)
+}
+
+// spares is designation of hot spares that are
+// excluded from selection until activated
+type spares struct {
+	opts SparesOpts
+
+	mu         sync.RWMutex
+	designated map[string]bool // spare id -> manually activated
+
+	// low capacity is cached per membership generation
+	generation  uint64
+	computed    bool
+	lowCapacity bool
+}
+
+// newSpares create spares designation with given
+// configuration, spares could be designated
+// manually even without configuration
+func newSpares(opts *SparesOpts) *spares {
+	s := &spares{designated: make(map[string]bool)}
+	if opts != nil {
+		s.opts = *opts
+	}
+
+	return s
+}
+
+// admit designate given service as spare if it
+// has configured tag. Should be called under the list lock
+func (s *spares) admit(srv service.IService) {
+	if s.opts.Tag == "" {
+		return
+	}
+
+	if _, ok := srv.Tags()[s.opts.Tag]; !ok {
+		return
+	}
+
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	if _, ok := s.designated[srv.ID()]; !ok {
+		s.designated[srv.ID()] = false
+	}
+}
+
+// forget remove designation of service with given id
+func (s *spares) forget(id string) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	delete(s.designated, id)
+}
+
+// state return spare state of service with given id, empty
+// state is returned for services that are not spares
+func (s *spares) state(id string, generation uint64, healthy []service.IService) SpareState {
+	// most services are not spares and low capacity is
+	// cached per generation, so read lock is enough
+	s.mu.RLock()
+	activated, ok := s.designated[id]
+	cached := s.opts.MinActive == 0 || s.computed && s.generation == generation
+	if !ok || activated || cached {
+		state := s.stateLocked(ok, activated, generation, healthy)
+		s.mu.RUnlock()
+		return state
+	}
+	s.mu.RUnlock()
+
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	activated, ok = s.designated[id]
+
+	return s.stateLocked(ok, activated, generation, healthy)
+}
+
+// stateLocked return spare state of service with given designation.
+// Should be called under spares lock, the write lock is needed if
+// low capacity is not cached for given generation
+func (s *spares) stateLocked(ok, activated bool, generation uint64, healthy []service.IService) SpareState {
+	switch {
+	case !ok:
+		return ""
+	case activated || s.isLowCapacity(generation, healthy):
+		return SpareActive
+	default:
+		return SpareStandby
+	}
+}
+
+// isLowCapacity check if there are fewer active healthy services
+// than configured minimum. Should be called under spares lock
+func (s *spares) isLowCapacity(generation uint64, healthy []service.IService) bool {
+	if s.opts.MinActive == 0 {
+		return false
+	}
+
+	if s.computed && s.generation == generation {
+		return s.lowCapacity
+	}
+
+	active := 0
+	for _, srv := range healthy {
+		if activated, ok := s.designated[srv.ID()]; !ok || activated {
+			active++
+		}
+	}
+
+	s.generation = generation
+	s.computed = true
+	s.lowCapacity = active < s.opts.MinActive
+
+	return s.lowCapacity
+}
+
+// standby check if given service is standby spare
+// excluded from selection. Should be called under the list lock
+func (l *ServicesList) standby(srv service.IService) bool {
+	return l.spares.state(srv.ID(), l.Generation(), l.healthy) == SpareStandby
+}
+
+// warmUpSpare return random healthy standby spare with configured
+// probability, so spares are kept warm by the share of real
+// traffic. Should be called under the list lock
+func (l *ServicesList) warmUpSpare(ctx context.Context) service.IService {
+	if l.spares.opts.WarmUpRatio <= 0 || l.Draining() || rand.Float64() >= l.spares.opts.WarmUpRatio {
+		return nil
+	}
+
+	var candidates []service.IService
+	for _, srv := range l.healthy {
+		if srv.Status() == service.StatusHealthy && l.standby(srv) && !l.maintenance.isCordoned(srv.ID()) && l.allowedByPolicies(ctx, srv) {
+			candidates = append(candidates, srv)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// SetSpare designate service with given id as hot spare or
+// remove the designation, designated spare is in standby
+// until it is activated
+func (l *ServicesList) SetSpare(id string, spare bool) error {
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	if !l.isMember(id) {
+		return ErrServiceNotFound{ID: id}
+	}
+
+	l.spares.mu.Lock()
+	if _, ok := l.spares.designated[id]; ok != spare {
+		if spare {
+			l.spares.designated[id] = false
+		} else {
+			delete(l.spares.designated, id)
+		}
+	}
+	l.spares.mu.Unlock()
+
+	l.bumpGeneration()
+	l.audit.record("spare_designated", id, fmt.Sprintf("spare=%t", spare))
+
+	l.log().Info("service spare designation is set", "service", id, "spare", spare)
+
+	return nil
+}
+
+// ActivateSpare manually activate spare with given id
+// or return it to standby
+func (l *ServicesList) ActivateSpare(id string, active bool) error {
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	l.spares.mu.Lock()
+	_, ok := l.spares.designated[id]
+	if ok {
+		l.spares.designated[id] = active
+	}
+	l.spares.mu.Unlock()
+
+	if !ok {
+		return ErrNotSpare{ID: id}
+	}
+
+	l.bumpGeneration()
+	l.audit.record("spare_activated", id, fmt.Sprintf("active=%t", active))
+
+	l.log().Info("spare activation is set", "service", id, "active", active)
+
+	return nil
+}
+
+// SetSpare designate service with given id
+// as hot spare or remove the designation
+func (l *ShardedServicesList) SetSpare(id string, spare bool) error {
+	return l.shard(id).SetSpare(id, spare)
+}
+
+// ActivateSpare manually activate spare
+// with given id or return it to standby
+func (l *ShardedServicesList) ActivateSpare(id string, active bool) error {
+	return l.shard(id).ActivateSpare(id, active)
+}
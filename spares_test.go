@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestSpares(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Spares:         &SparesOpts{Tag: "spare", MinActive: 2},
+	})
+
+	first := newHealthyService("https://1gateway.fm")
+	second := newHealthyService("https://2gateway.fm")
+	spare := service.NewService("https://3gateway.fm", "", map[string]struct{}{"spare": {}}, 0)
+	spare.(*service.BaseService).SetStatus(service.StatusHealthy)
+
+	for _, srv := range []service.IService{first, second, spare} {
+		list.Add(srv)
+	}
+
+	for i := 0; i < 10; i++ {
+		if srv := list.Next(); srv == nil || srv.ID() == spare.ID() {
+			t.Fatalf("standby spare should not be selected")
+		}
+	}
+
+	// spare is activated automatically on low capacity
+	list.FromHealthyToJail(first.ID())
+
+	selected := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		selected[list.Next().ID()] = true
+	}
+	if !selected[spare.ID()] {
+		t.Errorf("spare should be activated when healthy capacity is low")
+	}
+
+	list.FromJailToHealthy(first)
+
+	if err := list.ActivateSpare(first.ID(), true); !errors.As(err, &ErrNotSpare{}) {
+		t.Errorf("expected not spare error, got %v", err)
+	}
+
+	if err := list.SetSpare(first.ID(), true); err != nil {
+		t.Fatalf("unexpected set spare error: %s", err)
+	}
+	if err := list.ActivateSpare(spare.ID(), true); err != nil {
+		t.Fatalf("unexpected activate spare error: %s", err)
+	}
+
+	states := make(map[string]SpareState)
+	for _, srv := range list.Snapshot().Services {
+		states[srv.ID] = srv.Spare
+	}
+	if states[first.ID()] != SpareStandby || states[spare.ID()] != SpareActive || states[second.ID()] != "" {
+		t.Errorf("unexpected spare states %v", states)
+	}
+
+	if err := list.SetSpare("unknown", true); !errors.As(err, &ErrServiceNotFound{}) {
+		t.Errorf("expected service not found error, got %v", err)
+	}
+}
+
+func TestSparesWarmUp(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Spares:         &SparesOpts{WarmUpRatio: 1},
+	})
+
+	active := newHealthyService("https://1gateway.fm")
+	spare := newHealthyService("https://2gateway.fm")
+	list.Add(active)
+	list.Add(spare)
+
+	handler := NewAdminHandlerWithOpts(list, &AdminHandlerOpts{Unauthenticated: true})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/services/"+spare.ID()+"/spare", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected status code %d", rec.Code)
+	}
+
+	if srv := list.Next(); srv == nil || srv.ID() != spare.ID() {
+		t.Errorf("standby spare should take warm-up traffic")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/services/"+active.ID()+"/spare/activate", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected conflict for service that is not a spare, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,236 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// StarvationOpts is options that configure detection of
+// healthy services that are not selected for a long time
+// while the list is serving traffic, e.g. because of
+// balancer bug or misconfigured policy
+type StarvationOpts struct {
+	Window time.Duration // healthy service not selected during this window is reported as starved
+}
+
+// StarvedService is healthy service that is
+// not selected during configured window
+type StarvedService struct {
+	ID           string    `json:"id"`
+	LastSelected time.Time `json:"last_selected"` // zero if never selected
+	Since        time.Time `json:"since"`         // time the service is reported as starved
+}
+
+// starvation is tracker of the last
+// selections of healthy services
+type starvation struct {
+	window time.Duration
+
+	mu       sync.Mutex
+	selected map[string]time.Time // service id -> last selection
+	eligible map[string]time.Time // service id -> time it is seen eligible for selection
+	starved  map[string]starvedEntry
+	last     time.Time // the last selection of any service
+}
+
+// starvedEntry is service reported as starved
+type starvedEntry struct {
+	srv   service.IService
+	since time.Time
+}
+
+// newStarvation create selections tracker with given
+// configuration, nil is returned if detection is disabled
+func newStarvation(opts *StarvationOpts) *starvation {
+	if opts == nil || opts.Window <= 0 {
+		return nil
+	}
+
+	return &starvation{
+		window:   opts.Window,
+		selected: make(map[string]time.Time),
+		eligible: make(map[string]time.Time),
+		starved:  make(map[string]starvedEntry),
+	}
+}
+
+// record register selection of given service and return
+// true if the service was reported as starved
+func (s *starvation) record(srv service.IService) bool {
+	if s == nil || srv == nil {
+		return false
+	}
+
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	now := time.Now()
+	s.selected[srv.ID()] = now
+	s.last = now
+
+	if _, ok := s.starved[srv.ID()]; ok {
+		delete(s.starved, srv.ID())
+		return true
+	}
+
+	return false
+}
+
+// detect return given eligible services that are not selected during
+// the window and not reported yet. Services are tracked from the time
+// they are first seen eligible, starved services that are no longer
+// eligible are forgotten and returned as dropped
+func (s *starvation) detect(eligible []service.IService, now time.Time) (starved, dropped []service.IService) {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	current := make(map[string]time.Time, len(eligible))
+	for _, srv := range eligible {
+		since, ok := s.eligible[srv.ID()]
+		if !ok {
+			since = now
+		}
+		current[srv.ID()] = since
+	}
+	s.eligible = current
+
+	for id, entry := range s.starved {
+		if _, ok := current[id]; !ok {
+			delete(s.starved, id)
+			dropped = append(dropped, entry.srv)
+		}
+	}
+
+	// idle list does not starve its services
+	if now.Sub(s.last) > s.window {
+		return nil, dropped
+	}
+
+	for _, srv := range eligible {
+		if _, ok := s.starved[srv.ID()]; ok {
+			continue
+		}
+
+		baseline := current[srv.ID()]
+		if selected := s.selected[srv.ID()]; selected.After(baseline) {
+			baseline = selected
+		}
+
+		if now.Sub(baseline) > s.window {
+			s.starved[srv.ID()] = starvedEntry{srv: srv, since: now}
+			starved = append(starved, srv)
+		}
+	}
+
+	return starved, dropped
+}
+
+// forget remove last selection of service with given
+// id and return true if it was reported as starved
+func (s *starvation) forget(id string) bool {
+	if s == nil {
+		return false
+	}
+
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	_, starved := s.starved[id]
+
+	delete(s.selected, id)
+	delete(s.eligible, id)
+	delete(s.starved, id)
+
+	return starved
+}
+
+// recordSelection register selection of given service for
+// fairness and starvation tracking. Service reported as
+// starved is reported as fed again
+func (l *ServicesList) recordSelection(srv service.IService) {
+	l.fairness.record(srv)
+
+	if l.starvation.record(srv) {
+		l.metrics.observeStarved(l.serviceName, srv, false)
+
+		l.log().Info("starved service is selected again", "service", srv.ID())
+	}
+}
+
+// detectStarvation report healthy services eligible for selection
+// that are not selected during configured window while the list
+// is serving traffic. Standby spares and cordoned services are
+// not eligible
+func (l *ServicesList) detectStarvation() {
+	if l.starvation == nil {
+		return
+	}
+
+	l.mu.RLock()
+	var eligible []service.IService
+	for _, srv := range l.healthy {
+		if srv.Status() == service.StatusHealthy && !l.standby(srv) && !l.maintenance.isCordoned(srv.ID()) {
+			eligible = append(eligible, srv)
+		}
+	}
+	l.mu.RUnlock()
+
+	starved, dropped := l.starvation.detect(eligible, time.Now())
+	for _, srv := range dropped {
+		l.metrics.observeStarved(l.serviceName, srv, false)
+	}
+
+	for _, srv := range starved {
+		l.log().Warn("healthy service is not selected within starvation window", "service", srv.ID(), "node", srv.NodeName(), "window", l.starvation.window.String())
+
+		l.metrics.observeStarved(l.serviceName, srv, true)
+		l.emit(PoolEvent{Type: EventServiceStarved, Service: srv})
+	}
+}
+
+// Starved return healthy services currently
+// reported as starved ordered by id
+func (l *ServicesList) Starved() []StarvedService {
+	if l.starvation == nil {
+		return nil
+	}
+
+	defer l.starvation.mu.Unlock()
+	l.starvation.mu.Lock()
+
+	starved := make([]StarvedService, 0, len(l.starvation.starved))
+	for id, entry := range l.starvation.starved {
+		starved = append(starved, StarvedService{
+			ID:           id,
+			LastSelected: l.starvation.selected[id],
+			Since:        entry.since,
+		})
+	}
+
+	sortStarved(starved)
+
+	return starved
+}
+
+// Starved return healthy services of all
+// shards currently reported as starved
+func (l *ShardedServicesList) Starved() []StarvedService {
+	var starved []StarvedService
+	for _, shard := range l.shards {
+		starved = append(starved, shard.Starved()...)
+	}
+
+	sortStarved(starved)
+
+	return starved
+}
+
+// sortStarved sort given starved services by id
+func sortStarved(starved []StarvedService) {
+	sort.Slice(starved, func(i, j int) bool {
+		return starved[i].ID < starved[j].ID
+	})
+}
@@ -0,0 +1,107 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestStarvation(t *testing.T) {
+	metrics := NewMetrics(nil)
+
+	reg := prometheus.NewRegistry()
+	if err := metrics.Register(reg); err != nil {
+		t.Fatalf("unexpected register error: %s", err)
+	}
+
+	var (
+		mu      sync.Mutex
+		starved []string
+	)
+
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Metrics:        metrics,
+		Starvation:     &StarvationOpts{Window: 50 * time.Millisecond},
+		OnEvent: func(event PoolEvent) {
+			if event.Type == EventServiceStarved {
+				mu.Lock()
+				starved = append(starved, event.Service.ID())
+				mu.Unlock()
+			}
+		},
+	})
+
+	fed := newMetadataService("https://1gateway.fm", map[string]string{"zone": "a"})
+	hungry := newMetadataService("https://2gateway.fm", map[string]string{"zone": "b"})
+	list.Add(fed)
+	list.Add(hungry)
+
+	// idle list does not starve its services
+	list.HealthChecks()
+	time.Sleep(60 * time.Millisecond)
+	list.HealthChecks()
+	if len(list.Starved()) != 0 {
+		t.Fatalf("services of idle list should not be starved")
+	}
+
+	// misconfigured filter routes everything to one service
+	list.NextWhere("zone", "a")
+	list.HealthChecks()
+	for i := 0; i < 6; i++ {
+		time.Sleep(10 * time.Millisecond)
+		list.NextWhere("zone", "a")
+	}
+	list.HealthChecks()
+
+	report := list.Starved()
+	if len(report) != 1 || report[0].ID != hungry.ID() || !report[0].LastSelected.IsZero() {
+		t.Fatalf("unexpected starved services %+v", report)
+	}
+
+	// starvation is reported once
+	list.HealthChecks()
+
+	mu.Lock()
+	if len(starved) != 1 || starved[0] != hungry.ID() {
+		t.Errorf("unexpected starvation events %v", starved)
+	}
+	mu.Unlock()
+
+	if gauges := starvedGauges(t, reg); gauges != 1 {
+		t.Errorf("expected 1 starved gauge, got %d", gauges)
+	}
+
+	for i := 0; i < 2; i++ {
+		list.Next()
+	}
+
+	if len(list.Starved()) != 0 {
+		t.Errorf("selected service should not be starved")
+	}
+	if gauges := starvedGauges(t, reg); gauges != 0 {
+		t.Errorf("expected no starved gauges, got %d", gauges)
+	}
+}
+
+// starvedGauges return number of starved gauges in given registry
+func starvedGauges(t *testing.T, reg *prometheus.Registry) int {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("unexpected gather error: %s", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() == "prover_pool_starved" {
+			return len(family.GetMetric())
+		}
+	}
+
+	return 0
+}
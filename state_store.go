@@ -0,0 +1,399 @@
+package pool
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	stateFileExt = ".json"
+
+	defaultRedisStatePrefix = "prover-pool:state:"
+	defaultRedisDialTimeout = 5 * time.Second
+	defaultRedisMaxIdle     = 2
+)
+
+// FileStateStore is IStateStore keeping state of
+// every pool in json file of given directory
+type FileStateStore struct {
+	dir string
+}
+
+// NewFileStateStore create new FileStateStore
+// writing files to given directory
+func NewFileStateStore(dir string) *FileStateStore {
+	return &FileStateStore{dir: dir}
+}
+
+// path return path of state file of given pool,
+// the name is sanitized, so it can't escape the dir
+func (s *FileStateStore) path(pool string) string {
+	return filepath.Join(s.dir, safeFileName(pool)+stateFileExt)
+}
+
+// Load return state of given pool
+// saved to the file, nil if not saved
+func (s *FileStateStore) Load(_ context.Context, pool string) (*PersistedState, error) {
+	data, err := os.ReadFile(s.path(pool))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read state file: %w", err)
+	}
+
+	var state PersistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode state file: %w", err)
+	}
+
+	return &state, nil
+}
+
+// Save replace state file of given pool, file is
+// replaced atomically and synced with the directory,
+// so crash during or right after the write doesn't
+// corrupt or lose the previous state
+func (s *FileStateStore) Save(_ context.Context, pool string, state *PersistedState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("create state directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, safeFileName(pool)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write state file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close state file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(pool)); err != nil {
+		return fmt.Errorf("replace state file: %w", err)
+	}
+
+	return syncDir(s.dir)
+}
+
+// syncDir sync given directory, so
+// renames of its files are durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open state directory: %w", err)
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("sync state directory: %w", err)
+	}
+
+	return nil
+}
+
+// RedisStateStoreOpts is options that
+// configure store of state in Redis
+type RedisStateStoreOpts struct {
+	Addr      string        // redis address, e.g. "127.0.0.1:6379"
+	Username  string        // ACL username of AUTH command (empty for default user)
+	Password  string        // password of AUTH command (empty to skip authentication)
+	DB        int           // database number (0 by default)
+	TLS       *tls.Config   // tls configuration of connections (nil to connect without tls)
+	KeyPrefix string        // prefix of keys states are stored under as <prefix><pool name> ("prover-pool:state:" by default)
+	TTL       time.Duration // expiration of saved states (0 to keep them forever)
+	MaxIdle   int           // number of idle connections kept for next calls (2 by default)
+}
+
+// RedisStateStore is IStateStore keeping state of every pool in
+// Redis, so gateway replicas and restarted gateways share it. It
+// speaks RESP protocol directly over pooled connections, which
+// are authenticated and switched to configured database once
+type RedisStateStore struct {
+	opts RedisStateStoreOpts
+
+	mu     sync.Mutex
+	idle   []*redisConn
+	closed bool
+}
+
+// redisConn is connection to Redis
+// with buffered reader and writer
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+// NewRedisStateStore create new RedisStateStore
+// with given configuration
+func NewRedisStateStore(opts *RedisStateStoreOpts) *RedisStateStore {
+	s := &RedisStateStore{opts: *opts}
+
+	if s.opts.KeyPrefix == "" {
+		s.opts.KeyPrefix = defaultRedisStatePrefix
+	}
+	if s.opts.MaxIdle <= 0 {
+		s.opts.MaxIdle = defaultRedisMaxIdle
+	}
+
+	return s
+}
+
+// Load return state of given pool
+// saved to Redis, nil if not saved
+func (s *RedisStateStore) Load(ctx context.Context, pool string) (*PersistedState, error) {
+	reply, err := s.do(ctx, "GET", s.opts.KeyPrefix+pool)
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, nil
+	}
+
+	var state PersistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode state: %w", err)
+	}
+
+	return &state, nil
+}
+
+// Save replace state of given pool saved to Redis
+func (s *RedisStateStore) Save(ctx context.Context, pool string, state *PersistedState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode state: %w", err)
+	}
+
+	args := []string{"SET", s.opts.KeyPrefix + pool, string(data)}
+	if s.opts.TTL > 0 {
+		args = append(args, "PX", strconv.FormatInt(s.opts.TTL.Milliseconds(), 10))
+	}
+
+	_, err = s.do(ctx, args...)
+	return err
+}
+
+// Close close idle connections, connections
+// in use are closed once theirs calls finish
+func (s *RedisStateStore) Close() {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	for _, c := range s.idle {
+		_ = c.conn.Close()
+	}
+	s.idle, s.closed = nil, true
+}
+
+// do send given command on pooled connection and return
+// reply to it. Command failed on idle connection, e.g.
+// closed by server timeout, is retried on new connection
+func (s *RedisStateStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	c, reused, err := s.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := s.roundTrip(ctx, c, args)
+	if err != nil && reused && !errors.As(err, &ErrRedisReply{}) && ctx.Err() == nil {
+		if c, err = s.dial(ctx); err != nil {
+			return nil, err
+		}
+		reply, err = s.roundTrip(ctx, c, args)
+	}
+
+	return reply, err
+}
+
+// roundTrip send given command on given connection and read
+// reply to it. Connection is returned to the pool unless it's
+// broken, error replies don't break the connection
+func (s *RedisStateStore) roundTrip(ctx context.Context, c *redisConn, args []string) (interface{}, error) {
+	deadline, _ := ctx.Deadline()
+	_ = c.conn.SetDeadline(deadline)
+
+	writeRedisCommand(c.writer, args)
+	if err := c.writer.Flush(); err != nil {
+		_ = c.conn.Close()
+		return nil, fmt.Errorf("send redis command: %w", err)
+	}
+
+	reply, err := readRedisReply(c.reader)
+	if err != nil && !errors.As(err, &ErrRedisReply{}) {
+		_ = c.conn.Close()
+		return nil, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+
+	s.release(c)
+
+	if err != nil {
+		return nil, fmt.Errorf("redis %s: %w", args[0], err)
+	}
+
+	return reply, nil
+}
+
+// conn return idle connection if any or new one
+// and whether the connection is reused
+func (s *RedisStateStore) conn(ctx context.Context) (*redisConn, bool, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		c := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+
+		return c, true, nil
+	}
+	s.mu.Unlock()
+
+	c, err := s.dial(ctx)
+	return c, false, err
+}
+
+// release return given connection to idle ones
+// or close it if there are enough idle ones
+func (s *RedisStateStore) release(c *redisConn) {
+	s.mu.Lock()
+	if !s.closed && len(s.idle) < s.opts.MaxIdle {
+		s.idle = append(s.idle, c)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	_ = c.conn.Close()
+}
+
+// dial open new connection authenticated
+// and switched to configured database
+func (s *RedisStateStore) dial(ctx context.Context) (*redisConn, error) {
+	dialer := net.Dialer{Timeout: defaultRedisDialTimeout}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.opts.TLS != nil {
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: s.opts.TLS}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", s.opts.Addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", s.opts.Addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("dial redis: %w", err)
+	}
+
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn), writer: bufio.NewWriter(conn)}
+
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	var commands [][]string
+	switch {
+	case s.opts.Username != "":
+		commands = append(commands, []string{"AUTH", s.opts.Username, s.opts.Password})
+	case s.opts.Password != "":
+		commands = append(commands, []string{"AUTH", s.opts.Password})
+	}
+	if s.opts.DB != 0 {
+		commands = append(commands, []string{"SELECT", strconv.Itoa(s.opts.DB)})
+	}
+
+	// commands are pipelined and replies are read in order
+	for _, command := range commands {
+		writeRedisCommand(c.writer, command)
+	}
+	if err := c.writer.Flush(); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("send redis command: %w", err)
+	}
+
+	for _, command := range commands {
+		if _, err := readRedisReply(c.reader); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("redis %s: %w", command[0], err)
+		}
+	}
+
+	return c, nil
+}
+
+// writeRedisCommand write given command
+// as RESP array of bulk strings
+func writeRedisCommand(w *bufio.Writer, args []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+}
+
+// readRedisReply read one RESP reply: string for simple
+// strings, int64 for integers, []byte for bulk strings
+// and nil for null bulk string. Error replies are
+// returned as ErrRedisReply
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("malformed reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, ErrRedisReply{Message: line[1:]}
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed bulk string size: %w", err)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		return data[:size], nil
+	default:
+		return nil, fmt.Errorf("unsupported reply type %q", line[0])
+	}
+}
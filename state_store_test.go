@@ -0,0 +1,282 @@
+package pool
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileStateStore(t *testing.T) {
+	store := NewFileStateStore(t.TempDir())
+
+	state, err := store.Load(context.Background(), "pool")
+	if err != nil || state != nil {
+		t.Fatalf("missing state should be nil, got %v %v", state, err)
+	}
+
+	saved := &PersistedState{
+		Saved:   time.Now().Round(0),
+		Jailed:  map[string]time.Time{"1": time.Now().Round(0)},
+		Weights: map[string]int{"2": 3},
+	}
+	if err := store.Save(context.Background(), "pool", saved); err != nil {
+		t.Fatalf("unexpected save error: %s", err)
+	}
+
+	state, err = store.Load(context.Background(), "pool")
+	if err != nil {
+		t.Fatalf("unexpected load error: %s", err)
+	}
+
+	if !state.Saved.Equal(saved.Saved) || !state.Jailed["1"].Equal(saved.Jailed["1"]) || state.Weights["2"] != 3 {
+		t.Errorf("loaded state %+v differs from saved %+v", state, saved)
+	}
+}
+
+// fakeRedis is redis server supporting
+// AUTH, SELECT, GET and SET commands
+type fakeRedis struct {
+	listener net.Listener
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	commands []string
+	conns    int
+}
+
+func newFakeRedis(t *testing.T, password string) *fakeRedis {
+	return newFakeRedisTLS(t, password, nil)
+}
+
+// newFakeRedisTLS start fake redis accepting tls
+// connections with given config (nil to disable tls)
+func newFakeRedisTLS(t *testing.T, password string, config *tls.Config) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected listen error: %s", err)
+	}
+	if config != nil {
+		listener = tls.NewListener(listener, config)
+	}
+
+	r := &fakeRedis{listener: listener, password: password, values: make(map[string]string)}
+	go r.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return r
+}
+
+func (r *fakeRedis) serve() {
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		r.mu.Lock()
+		r.conns++
+		r.mu.Unlock()
+
+		go r.handle(conn)
+	}
+}
+
+func (r *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	authenticated := r.password == ""
+
+	for {
+		args, err := readFakeRedisCommand(reader)
+		if err != nil {
+			return
+		}
+
+		r.mu.Lock()
+		r.commands = append(r.commands, strings.Join(args, " "))
+
+		var reply string
+		switch {
+		case args[0] == "AUTH" && len(args) == 3:
+			// ACL user "gateway" shares the password
+			authenticated = args[1] == "gateway" && args[2] == r.password
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid username-password pair\r\n"
+			}
+		case args[0] == "AUTH":
+			authenticated = args[1] == r.password
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required\r\n"
+		case args[0] == "SELECT":
+			reply = "+OK\r\n"
+		case args[0] == "SET":
+			r.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case args[0] == "GET":
+			value, ok := r.values[args[1]]
+			reply = "$-1\r\n"
+			if ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		r.mu.Unlock()
+
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func readFakeRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+
+	return args, nil
+}
+
+func TestRedisStateStore(t *testing.T) {
+	redis := newFakeRedis(t, "secret")
+
+	store := NewRedisStateStore(&RedisStateStoreOpts{
+		Addr:     redis.listener.Addr().String(),
+		Password: "secret",
+		DB:       2,
+		TTL:      time.Minute,
+	})
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	state, err := store.Load(ctx, "pool")
+	if err != nil || state != nil {
+		t.Fatalf("missing state should be nil, got %v %v", state, err)
+	}
+
+	if err := store.Save(ctx, "pool", &PersistedState{Weights: map[string]int{"1": 4}}); err != nil {
+		t.Fatalf("unexpected save error: %s", err)
+	}
+
+	state, err = store.Load(ctx, "pool")
+	if err != nil || state == nil || state.Weights["1"] != 4 {
+		t.Fatalf("saved state should be loaded, got %v %v", state, err)
+	}
+
+	redis.mu.Lock()
+	commands, conns := strings.Join(redis.commands, "\n"), redis.conns
+	redis.mu.Unlock()
+
+	if !strings.Contains(commands, "SELECT 2") || !strings.Contains(commands, "PX 60000") || !strings.Contains(commands, "GET prover-pool:state:pool") {
+		t.Errorf("unexpected commands:\n%s", commands)
+	}
+
+	// connection is authenticated once and reused
+	if conns != 1 || strings.Count(commands, "AUTH") != 1 {
+		t.Errorf("expected one authenticated connection, got %d connections:\n%s", conns, commands)
+	}
+
+	wrong := NewRedisStateStore(&RedisStateStoreOpts{Addr: redis.listener.Addr().String(), Password: "wrong"})
+
+	var replyErr ErrRedisReply
+	if _, err := wrong.Load(ctx, "pool"); !errors.As(err, &replyErr) {
+		t.Errorf("error reply expected, got %v", err)
+	}
+}
+
+func TestRedisStateStoreTLS(t *testing.T) {
+	// certificate of test server is reused by fake redis
+	server := httptest.NewUnstartedServer(nil)
+	server.StartTLS()
+	defer server.Close()
+
+	redis := newFakeRedisTLS(t, "secret", &tls.Config{Certificates: server.TLS.Certificates})
+
+	store := NewRedisStateStore(&RedisStateStoreOpts{
+		Addr:     redis.listener.Addr().String(),
+		Username: "gateway",
+		Password: "secret",
+		TLS:      server.Client().Transport.(*http.Transport).TLSClientConfig,
+	})
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := store.Save(ctx, "pool", &PersistedState{Weights: map[string]int{"1": 4}}); err != nil {
+		t.Fatalf("unexpected save error over tls: %s", err)
+	}
+
+	redis.mu.Lock()
+	commands := strings.Join(redis.commands, "\n")
+	redis.mu.Unlock()
+
+	if !strings.Contains(commands, "AUTH gateway secret") {
+		t.Errorf("expected ACL authentication, got:\n%s", commands)
+	}
+}
+
+func TestFileStateStoreSanitizedName(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "states")
+	store := NewFileStateStore(dir)
+
+	if err := store.Save(context.Background(), "../escaped", &PersistedState{Weights: map[string]int{"1": 2}}); err != nil {
+		t.Fatalf("unexpected save error: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escaped"+stateFileExt)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("state should not be written outside of store dir, got %v", err)
+	}
+
+	state, err := store.Load(context.Background(), "../escaped")
+	if err != nil || state == nil || state.Weights["1"] != 2 {
+		t.Errorf("state of sanitized name should be loaded, got %v %v", state, err)
+	}
+}
@@ -0,0 +1,41 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// ObserveResult report duration and outcome of request to given
+// service made by the caller, nil error is success. Statistics
+// are updated only for members of the list and the outcome is
+// reported to passive health
+func (l *ServicesList) ObserveResult(ctx context.Context, srv service.IService, duration time.Duration, err error) {
+	l.metrics.observeRequest(ctx, l.serviceName, srv, duration)
+
+	if s := l.stats.Get(srv.ID()); s != nil {
+		s.Observe(duration, err)
+	}
+
+	l.reportResult(srv, err)
+}
+
+// Stats return registry of members moving
+// statistics, nil if statistics are disabled
+func (l *ServicesList) Stats() *stats.Registry {
+	return l.stats
+}
+
+// ObserveResult report duration and outcome of
+// request to given service made by the caller
+func (l *ShardedServicesList) ObserveResult(ctx context.Context, srv service.IService, duration time.Duration, err error) {
+	l.shard(srv.ID()).ObserveResult(ctx, srv, duration, err)
+}
+
+// Stats return registry of members moving statistics
+// shared by all shards, nil if statistics are disabled
+func (l *ShardedServicesList) Stats() *stats.Registry {
+	return l.shards[0].Stats()
+}
@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+)
+
+func TestServicesListStats(t *testing.T) {
+	registry := stats.NewRegistry(stats.Opts{})
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Shards:         2,
+		Stats:          registry,
+	})
+
+	member := newHealthyService("https://1gateway.fm")
+	stranger := newHealthyService("https://2gateway.fm")
+	list.Add(member)
+
+	ctx := context.Background()
+	list.ObserveRequest(ctx, member, 100*time.Millisecond)
+	list.ObserveResult(ctx, member, 300*time.Millisecond, errors.New("failed"))
+	list.ObserveResult(ctx, stranger, time.Second, nil)
+
+	if list.Stats() != registry {
+		t.Fatalf("configured registry should be returned")
+	}
+
+	snapshot := registry.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("only members should be tracked, got %v", snapshot)
+	}
+	// observations made a moment apart are weighted almost equally
+	s := snapshot[member.ID()]
+	if s.Latency < 199*time.Millisecond || s.Latency > 201*time.Millisecond || s.ErrorRate < 0.49 || s.ErrorRate > 0.51 || s.Requests != 2 {
+		t.Errorf("unexpected member statistics %+v", s)
+	}
+
+	list.RemoveFromHealthyByIndex(0)
+	if registry.Get(member.ID()) != nil {
+		t.Errorf("statistics of removed service should be forgotten")
+	}
+
+	if NewServicesList("testServicesList", &ServicesListOpts{}).Stats() != nil {
+		t.Errorf("statistics should be disabled by default")
+	}
+}
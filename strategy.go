@@ -0,0 +1,321 @@
+package pool
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// IBalancingStrategy select service to take a connection
+// among candidates prepared by the list. Strategy could be
+// shared between lists and shards, so it should be safe
+// for concurrent use
+type IBalancingStrategy interface {
+	// Name return strategy name for logging
+	Name() string
+
+	// Select return one of given candidates to take a connection
+	// for given request, nil is returned if none of them fits or
+	// there are no candidates. Candidates are healthy services
+	// allowed for the request in the list order, the list calls
+	// it with at least one of them
+	Select(ctx context.Context, candidates []service.IService) service.IService
+}
+
+// roundRobinStrategy select candidates in turn
+type roundRobinStrategy struct {
+	current uint64
+}
+
+// RoundRobin return strategy that select candidates in turn,
+// unlike the built-in round-robin its cursor is not moved on
+// membership changes
+func RoundRobin() IBalancingStrategy {
+	return &roundRobinStrategy{}
+}
+
+// Name return strategy name
+func (s *roundRobinStrategy) Name() string {
+	return "round_robin"
+}
+
+// Select return next candidate in turn
+func (s *roundRobinStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	next := atomic.AddUint64(&s.current, 1) - 1
+	return candidates[next%uint64(len(candidates))]
+}
+
+// randomStrategy select random candidate
+type randomStrategy struct{}
+
+// Random return strategy that select random candidate
+func Random() IBalancingStrategy {
+	return randomStrategy{}
+}
+
+// Name return strategy name
+func (randomStrategy) Name() string {
+	return "random"
+}
+
+// Select return random candidate
+func (randomStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// weightedRandomStrategy select random
+// candidate proportionally to its weight
+type weightedRandomStrategy struct{}
+
+// WeightedRandom return strategy that select random candidate
+// with probability proportional to its weight, see service.Weight
+func WeightedRandom() IBalancingStrategy {
+	return weightedRandomStrategy{}
+}
+
+// Name return strategy name
+func (weightedRandomStrategy) Name() string {
+	return "weighted_random"
+}
+
+// Select return random candidate
+// proportionally to its weight
+func (weightedRandomStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	weights := make([]int, len(candidates))
+
+	var total int
+	for i, srv := range candidates {
+		weights[i] = service.Weight(srv)
+		total += weights[i]
+	}
+
+	pick := rand.Intn(total)
+	for i, weight := range weights {
+		if pick < weight {
+			return candidates[i]
+		}
+		pick -= weight
+	}
+
+	return candidates[len(candidates)-1]
+}
+
+// leastLoadedStrategy select the least loaded candidate
+type leastLoadedStrategy struct{}
+
+// LeastLoaded return strategy that select the candidate with
+// the lowest load rating, ties are broken by the list order
+func LeastLoaded() IBalancingStrategy {
+	return leastLoadedStrategy{}
+}
+
+// Name return strategy name
+func (leastLoadedStrategy) Name() string {
+	return "least_loaded"
+}
+
+// Select return the least loaded candidate
+func (leastLoadedStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	var (
+		selected service.IService
+		minLoad  = math.MaxFloat64
+	)
+
+	for _, srv := range candidates {
+		if load := float64(srv.Load()); load < minLoad {
+			selected, minLoad = srv, load
+		}
+	}
+
+	return selected
+}
+
+// expectedLatency return moving latency of given service from
+// given registry, zero if the service is not observed yet
+func expectedLatency(registry *stats.Registry, srv service.IService) time.Duration {
+	if s := registry.Get(srv.ID()); s != nil {
+		return s.ExpectedLatency()
+	}
+
+	return 0
+}
+
+// lowestLatencyStrategy select candidate
+// with the lowest moving latency
+type lowestLatencyStrategy struct {
+	stats *stats.Registry
+}
+
+// LowestLatency return strategy that select the candidate with the
+// lowest moving latency from given registry, shared with the list
+// as ServicesListOpts.Stats. Request latency reported by ObserveResult
+// is used, healthcheck latency is used for services without requests.
+// Services without observations are preferred, so they are measured
+// soon, ties are broken by the list order
+func LowestLatency(registry *stats.Registry) IBalancingStrategy {
+	return lowestLatencyStrategy{stats: registry}
+}
+
+// Name return strategy name
+func (lowestLatencyStrategy) Name() string {
+	return "lowest_latency"
+}
+
+// Select return candidate with the lowest latency
+func (s lowestLatencyStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	var (
+		selected service.IService
+		lowest   time.Duration
+	)
+
+	for _, srv := range candidates {
+		if latency := expectedLatency(s.stats, srv); selected == nil || latency < lowest {
+			selected, lowest = srv, latency
+		}
+	}
+
+	return selected
+}
+
+// powerOfTwoStrategy select faster of
+// two random candidates
+type powerOfTwoStrategy struct {
+	stats *stats.Registry
+}
+
+// PowerOfTwo return strategy that pick two random candidates and
+// select the one with lower moving latency from given registry
+// like LowestLatency. Unlike LowestLatency it doesn't send all
+// requests to the fastest service, so it isn't overloaded while
+// its latency average catches up, and slow services still get
+// some traffic to measure theirs recovery
+func PowerOfTwo(registry *stats.Registry) IBalancingStrategy {
+	return powerOfTwoStrategy{stats: registry}
+}
+
+// Name return strategy name
+func (powerOfTwoStrategy) Name() string {
+	return "power_of_two"
+}
+
+// Select return faster of two random candidates
+func (s powerOfTwoStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	switch len(candidates) {
+	case 0:
+		return nil
+	case 1:
+		return candidates[0]
+	}
+
+	first := rand.Intn(len(candidates))
+	second := rand.Intn(len(candidates) - 1)
+	if second >= first {
+		second++
+	}
+
+	if expectedLatency(s.stats, candidates[second]) < expectedLatency(s.stats, candidates[first]) {
+		return candidates[second]
+	}
+
+	return candidates[first]
+}
+
+// hashKey is context key of request hash key
+type hashKey struct{}
+
+// WithHashKey return context with given hash key of the
+// request, e.g. job key, used by ConsistentHash strategy
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKey{}, key)
+}
+
+// HashKeyFromContext return hash key of
+// the request, empty if it's not set
+func HashKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(hashKey{}).(string)
+	return key
+}
+
+// consistentHashStrategy select candidate
+// by rendezvous hash of request key
+type consistentHashStrategy struct{}
+
+// ConsistentHash return strategy that select candidate with
+// the highest rendezvous hash of request hash key set by
+// WithHashKey and candidate id, so requests with the same
+// key go to the same service while it's healthy and only
+// keys of removed service move. Requests without key are
+// routed to random candidate
+func ConsistentHash() IBalancingStrategy {
+	return consistentHashStrategy{}
+}
+
+// Name return strategy name
+func (consistentHashStrategy) Name() string {
+	return "consistent_hash"
+}
+
+// Select return candidate with the highest
+// hash of request key and its id
+func (consistentHashStrategy) Select(ctx context.Context, candidates []service.IService) service.IService {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	key := HashKeyFromContext(ctx)
+	if key == "" {
+		return candidates[rand.Intn(len(candidates))]
+	}
+
+	var (
+		selected service.IService
+		highest  uint64
+	)
+
+	for _, srv := range candidates {
+		hash := fnv.New64a()
+		hash.Write([]byte(key))
+		hash.Write([]byte(srv.ID()))
+
+		if sum := hash.Sum64(); selected == nil || sum > highest {
+			selected, highest = srv, sum
+		}
+	}
+
+	return selected
+}
+
+// nextByStrategy return allowed healthy service selected by
+// configured strategy. Should be called under the list lock
+func (l *ServicesList) nextByStrategy(ctx context.Context) service.IService {
+	var candidates []service.IService
+	for _, srv := range l.healthy {
+		if srv.Status() == service.StatusHealthy && l.allow(ctx, srv) {
+			candidates = append(candidates, srv)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	return l.strategy.Select(ctx, candidates)
+}
@@ -0,0 +1,232 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/pkg/stats"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// lastStrategy select the last candidate
+// and remember the candidates it has got
+type lastStrategy struct {
+	candidates []service.IService
+}
+
+func (s *lastStrategy) Name() string {
+	return "last"
+}
+
+func (s *lastStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	s.candidates = candidates
+	return candidates[len(candidates)-1]
+}
+
+func TestBalancingStrategyInjected(t *testing.T) {
+	strategy := &lastStrategy{}
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Strategy:       strategy,
+		Policies:       []IPolicy{&addressPolicy{filtered: "https://3gateway.fm"}},
+	})
+	defer list.Close()
+
+	for i := 1; i <= 3; i++ {
+		list.Add(newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+	list.Add(newUnhealthyService("https://4gateway.fm"))
+
+	if srv := list.Next(); srv == nil || srv.Address() != "https://2gateway.fm" {
+		t.Fatalf("expected service selected by strategy, got %v", srv)
+	}
+
+	// jailed and filtered services are not candidates
+	if len(strategy.candidates) != 2 {
+		t.Errorf("expected 2 candidates, got %d", len(strategy.candidates))
+	}
+}
+
+func TestBalancingStrategies(t *testing.T) {
+	var candidates []service.IService
+	for i := 1; i <= 3; i++ {
+		srv := newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)).(*service.BaseService)
+		srv.SetLoad(float32(4-i) / 4)
+		srv.SetWeight(i * i)
+		candidates = append(candidates, srv)
+	}
+
+	ctx := context.Background()
+
+	roundRobin := RoundRobin()
+	for i := 0; i < 6; i++ {
+		if srv := roundRobin.Select(ctx, candidates); srv != candidates[i%3] {
+			t.Fatalf("round-robin should select candidates in turn")
+		}
+	}
+
+	if srv := LeastLoaded().Select(ctx, candidates); srv != candidates[2] {
+		t.Errorf("expected the least loaded candidate, got %s", srv.Address())
+	}
+
+	selected := make(map[service.IService]int)
+	for i := 0; i < 1400; i++ {
+		selected[Random().Select(ctx, candidates)]++
+	}
+	if len(selected) != 3 {
+		t.Errorf("random should select every candidate, got %d", len(selected))
+	}
+
+	// weights are 1, 4 and 9
+	selected = make(map[service.IService]int)
+	for i := 0; i < 1400; i++ {
+		selected[WeightedRandom().Select(ctx, candidates)]++
+	}
+	if selected[candidates[2]] < 700 || selected[candidates[0]] > 250 {
+		t.Errorf("weighted random should select candidates proportionally to weights, got %d, %d and %d", selected[candidates[0]], selected[candidates[1]], selected[candidates[2]])
+	}
+
+	weightedRoundRobin := WeightedRoundRobin()
+	selected = make(map[service.IService]int)
+	for i := 0; i < 14; i++ {
+		selected[weightedRoundRobin.Select(ctx, candidates)]++
+	}
+	if selected[candidates[0]] != 1 || selected[candidates[1]] != 4 || selected[candidates[2]] != 9 {
+		t.Errorf("weighted round-robin should select candidates proportionally to weights, got %d, %d and %d", selected[candidates[0]], selected[candidates[1]], selected[candidates[2]])
+	}
+
+	inFlight := map[string]int{candidates[0].ID(): 2, candidates[1].ID(): 1, candidates[2].ID(): 1}
+	leastConnections := LeastConnections(func(id string) int { return inFlight[id] })
+	selected = make(map[service.IService]int)
+	for i := 0; i < 4; i++ {
+		selected[leastConnections.Select(ctx, candidates)]++
+	}
+	if selected[candidates[0]] != 0 || selected[candidates[1]] == 0 || selected[candidates[2]] == 0 {
+		t.Errorf("least connections should select candidates with the fewest connections in turn, got %d, %d and %d", selected[candidates[0]], selected[candidates[1]], selected[candidates[2]])
+	}
+}
+
+func TestBalancingStrategiesWithoutCandidates(t *testing.T) {
+	registry := stats.NewRegistry(stats.Opts{})
+
+	strategies := []IBalancingStrategy{
+		RoundRobin(),
+		Random(),
+		WeightedRandom(),
+		WeightedRoundRobin(),
+		LeastConnections(func(string) int { return 0 }),
+		LeastLoaded(),
+		LowestLatency(registry),
+		PowerOfTwo(registry),
+		ConsistentHash(),
+	}
+
+	for _, strategy := range strategies {
+		if srv := strategy.Select(WithHashKey(context.Background(), "job"), nil); srv != nil {
+			t.Errorf("strategy %s should not select service without candidates, got %s", strategy.Name(), srv.Address())
+		}
+	}
+}
+
+func TestConsistentHashStrategy(t *testing.T) {
+	var candidates []service.IService
+	for i := 1; i <= 5; i++ {
+		candidates = append(candidates, newHealthyService(fmt.Sprintf("https://%dgateway.fm", i)))
+	}
+
+	strategy := ConsistentHash()
+	selected := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("job-%d", i)
+		srv := strategy.Select(WithHashKey(context.Background(), key), candidates)
+		if again := strategy.Select(WithHashKey(context.Background(), key), candidates); again != srv {
+			t.Fatalf("the same key should be routed to the same service")
+		}
+		selected[key] = srv.ID()
+	}
+
+	// only keys of removed service move
+	removed := candidates[0].ID()
+	for key, id := range selected {
+		srv := strategy.Select(WithHashKey(context.Background(), key), candidates[1:])
+		if id != removed && srv.ID() != id {
+			t.Errorf("key %s of remaining service should not move", key)
+		}
+	}
+}
+
+func TestLatencyStrategies(t *testing.T) {
+	registry := stats.NewRegistry(stats.Opts{})
+
+	var candidates []service.IService
+	for i := 1; i <= 4; i++ {
+		srv := newHealthyService(fmt.Sprintf("https://%dgateway.fm", i))
+		registry.Track(srv.ID())
+		candidates = append(candidates, srv)
+	}
+
+	// latencies are 40ms, 30ms, 20ms and 10ms, the first
+	// service is measured by healthchecks only
+	registry.Get(candidates[0].ID()).ObserveCheck(40 * time.Millisecond)
+	for i := 1; i < 4; i++ {
+		registry.Get(candidates[i].ID()).ObserveCheck(time.Millisecond)
+		registry.Get(candidates[i].ID()).Observe(time.Duration(4-i)*10*time.Millisecond, nil)
+	}
+
+	ctx := context.Background()
+
+	if srv := LowestLatency(registry).Select(ctx, candidates); srv != candidates[3] {
+		t.Errorf("expected the fastest candidate, got %s", srv.Address())
+	}
+
+	unobserved := newHealthyService("https://5gateway.fm")
+	if srv := LowestLatency(registry).Select(ctx, append(candidates, unobserved)); srv != unobserved {
+		t.Errorf("unobserved candidate should be preferred to be measured, got %s", srv.Address())
+	}
+
+	selected := make(map[service.IService]int)
+	for i := 0; i < 1200; i++ {
+		selected[PowerOfTwo(registry).Select(ctx, candidates)]++
+	}
+
+	// the slowest service is never selected as the fastest is
+	// always selected from its pairs, i.e. in half of selections
+	if selected[candidates[0]] != 0 || selected[candidates[3]] < 500 || selected[candidates[1]] == 0 {
+		t.Errorf("power of two should prefer faster candidates, got %d, %d, %d and %d", selected[candidates[0]], selected[candidates[1]], selected[candidates[2]], selected[candidates[3]])
+	}
+}
+
+func TestHealthCheckLatencyIsObserved(t *testing.T) {
+	registry := stats.NewRegistry(stats.Opts{})
+
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Stats:          registry,
+		Strategy:       LowestLatency(registry),
+	})
+	defer list.Close()
+
+	slow := newSlowService("https://1gateway.fm", 20*time.Millisecond)
+	fast := newHealthyService("https://2gateway.fm")
+	list.Add(slow)
+	list.Add(fast)
+
+	list.HealthChecks()
+
+	if latency := registry.Get(slow.ID()).CheckLatency(); latency < 20*time.Millisecond {
+		t.Fatalf("healthcheck latency should be observed, got %s", latency)
+	}
+
+	for i := 0; i < 5; i++ {
+		if srv := list.Next(); srv != fast {
+			t.Fatalf("service with the lowest healthcheck latency should be selected")
+		}
+	}
+}
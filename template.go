@@ -0,0 +1,125 @@
+package pool
+
+import (
+	"os"
+
+	"github.com/gateway-fm/prover-pool-lib/discovery"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// TemplateParams is parameters of pool template
+// instance, e.g. blockchain network or rollup name
+type TemplateParams map[string]string
+
+// PoolTemplate is pool definition which is declared once
+// and instantiated per network with parameters substitution.
+// ${param} placeholders in pool name and review webhook url are
+// substituted, factories build per-instance driver, checker
+// and policies from the parameters
+type PoolTemplate struct {
+	Pool      ServicesPoolsOpts                                                            // base pool configuration copied to every instance
+	Defaults  TemplateParams                                                               // default parameters values
+	Discovery func(params TemplateParams) (discovery.IServiceDiscovery, error)             // discovery driver factory (nil to use registry shared driver)
+	Checker   func(params TemplateParams) func(service.IService) (service.IService, error) // discovered services mutation factory, e.g. provers with network healthcheck (nil to keep Pool.MutationFnc)
+	Policies  func(params TemplateParams) ([]IPolicy, error)                               // routing policies factory (nil to keep Pool.ListOpts.Policies)
+}
+
+// instantiate build pool configuration
+// from the template with given parameters
+func (t *PoolTemplate) instantiate(params TemplateParams) (*ServicesPoolsOpts, error) {
+	merged := make(TemplateParams, len(t.Defaults)+len(params))
+	for name, value := range t.Defaults {
+		merged[name] = value
+	}
+	for name, value := range params {
+		merged[name] = value
+	}
+
+	opts := t.Pool
+
+	var err error
+	if opts.Name, err = merged.expand(t.Pool.Name); err != nil {
+		return nil, err
+	}
+
+	listOpts := ServicesListOpts{}
+	if t.Pool.ListOpts != nil {
+		listOpts = *t.Pool.ListOpts
+	}
+
+	if listOpts.ReviewPolicy != nil {
+		review := *listOpts.ReviewPolicy
+		if review.WebhookURL, err = merged.expand(review.WebhookURL); err != nil {
+			return nil, err
+		}
+		listOpts.ReviewPolicy = &review
+	}
+
+	if t.Discovery != nil {
+		if opts.Discovery, err = t.Discovery(merged); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Checker != nil {
+		opts.MutationFnc = t.Checker(merged)
+	}
+
+	if t.Policies != nil {
+		if listOpts.Policies, err = t.Policies(merged); err != nil {
+			return nil, err
+		}
+	}
+
+	opts.ListOpts = &listOpts
+
+	return &opts, nil
+}
+
+// expand substitute ${param} placeholders in given string,
+// missing parameters are reported as error
+func (p TemplateParams) expand(s string) (string, error) {
+	var missing string
+
+	expanded := os.Expand(s, func(name string) string {
+		value, ok := p[name]
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+
+	if missing != "" {
+		return "", ErrTemplateParamMissing{Param: missing}
+	}
+
+	return expanded, nil
+}
+
+// RegisterTemplate register pool template
+// with given name in the registry
+func (r *PoolRegistry) RegisterTemplate(name string, template *PoolTemplate) {
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	r.templates[name] = template
+}
+
+// Instantiate create and start new pool from the template with
+// given name, template placeholders are substituted by given params
+func (r *PoolRegistry) Instantiate(template string, params TemplateParams, healthchecks bool) (IServicesPool, error) {
+	r.mu.RLock()
+	t, ok := r.templates[template]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrTemplateNotFound{Name: template}
+	}
+
+	opts, err := t.instantiate(params)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Create(opts, healthchecks)
+}
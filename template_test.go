@@ -0,0 +1,59 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/discovery"
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestPoolRegistryTemplates(t *testing.T) {
+	registry := NewPoolRegistry(&PoolRegistryOpts{})
+	defer registry.Close()
+
+	var networks []string
+
+	registry.RegisterTemplate("provers", &PoolTemplate{
+		Pool: ServicesPoolsOpts{
+			Name: "provers-${network}-${zone}",
+			ListOpts: &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  1 * time.Second,
+				ChecksInterval: 1 * time.Second,
+				ReviewPolicy:   &ReviewPolicy{WebhookURL: "https://review.gateway.fm/${network}"},
+			},
+		},
+		Defaults: TemplateParams{"zone": "eu"},
+		Discovery: func(params TemplateParams) (discovery.IServiceDiscovery, error) {
+			networks = append(networks, params["network"])
+			return &staticDiscovery{services: []service.IService{newHealthyService("https://" + params["network"] + ".gateway.fm")}}, nil
+		},
+	})
+
+	pool, err := registry.Instantiate("provers", TemplateParams{"network": "zkevm"}, false)
+	if err != nil {
+		t.Fatalf("unexpected instantiate error: %s", err)
+	}
+
+	if _, ok := registry.Get("provers-zkevm-eu"); !ok {
+		t.Fatalf("pool name should be substituted with params and defaults, got %v", registry.Names())
+	}
+
+	if err := pool.DiscoverServices(); err != nil || pool.Count() != 1 {
+		t.Fatalf("instance should use own discovery driver, err: %v", err)
+	}
+
+	if len(networks) != 1 || networks[0] != "zkevm" {
+		t.Errorf("unexpected discovery factory calls %v", networks)
+	}
+
+	if _, err := registry.Instantiate("provers", TemplateParams{}, false); !errors.As(err, &ErrTemplateParamMissing{}) {
+		t.Errorf("missing parameter error expected, got %v", err)
+	}
+
+	if _, err := registry.Instantiate("sequencers", TemplateParams{}, false); !errors.As(err, &ErrTemplateNotFound{}) {
+		t.Errorf("template not found error expected, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package pool
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/gateway-fm/prover-pool-lib/service"
@@ -47,7 +48,9 @@ func newHealthyService(addr string) service.IService {
 
 func newServicesPool(discoveryInterval time.Duration, hcInterval time.Duration, mutationFunc func(srv service.IService) (service.IService, error)) IServicesPool {
 	opts := &ServicesPoolsOpts{
-		Name: "TestServicePool",
+		Name:              "TestServicePool",
+		DiscoveryInterval: discoveryInterval,
+		MutationFnc:       mutationFunc,
 		ListOpts: &ServicesListOpts{
 			TryUpTries:     5,
 			TryUpInterval:  1 * time.Second,
@@ -57,3 +60,31 @@ func newServicesPool(discoveryInterval time.Duration, hcInterval time.Duration,
 
 	return NewServicesPool(opts)
 }
+
+type unhealthyService struct {
+	*service.BaseService
+}
+
+func (s *unhealthyService) HealthCheck() error {
+	return fmt.Errorf("service %s is unreachable", s.Address())
+}
+
+func newUnhealthyService(addr string) service.IService {
+	return &unhealthyService{newHealthyService(addr).(*service.BaseService)}
+}
+
+type switchableService struct {
+	*service.BaseService
+	down atomic.Bool
+}
+
+func (s *switchableService) HealthCheck() error {
+	if s.down.Load() {
+		return fmt.Errorf("service %s is down", s.Address())
+	}
+	return nil
+}
+
+func newSwitchableService(addr string) *switchableService {
+	return &switchableService{BaseService: newHealthyService(addr).(*service.BaseService)}
+}
@@ -0,0 +1,100 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultDiscoveryTimeout = 10 * time.Second
+	defaultCheckTimeout     = 5 * time.Second
+	defaultDialTimeout      = 5 * time.Second
+	defaultRequestTimeout   = 10 * time.Minute
+)
+
+// Timeouts is hierarchy of deadlines of pool operations. Zero
+// fields are inherited from the parent level: defaults, then
+// registry, pool and list timeouts, so every operation has
+// explicit deadline. Durations are strings like "10s"
+type Timeouts struct {
+	Discovery time.Duration `json:"discovery" yaml:"discovery"` // one discovery round, shorter than discovery interval (10s by default)
+	Check     time.Duration `json:"check" yaml:"check"`         // one healthcheck of service, shorter than checks interval (5s by default)
+	Dial      time.Duration `json:"dial" yaml:"dial"`           // connection to service, not longer than check and request timeouts (5s by default)
+	Request   time.Duration `json:"request" yaml:"request"`     // one request proxied to service (10m by default)
+	Drain     time.Duration `json:"drain" yaml:"drain"`         // work given to finish on shutdown (25s by default)
+}
+
+// DefaultTimeouts return timeouts the hierarchy starts from
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Discovery: defaultDiscoveryTimeout,
+		Check:     defaultCheckTimeout,
+		Dial:      defaultDialTimeout,
+		Request:   defaultRequestTimeout,
+		Drain:     defaultDrainTimeout,
+	}
+}
+
+// Inherit return timeouts with zero fields taken
+// from given parent, parent is returned for nil
+func (t *Timeouts) Inherit(parent Timeouts) Timeouts {
+	if t == nil {
+		return parent
+	}
+
+	inherited := *t
+	for _, field := range []struct {
+		value  *time.Duration
+		parent time.Duration
+	}{
+		{&inherited.Discovery, parent.Discovery},
+		{&inherited.Check, parent.Check},
+		{&inherited.Dial, parent.Dial},
+		{&inherited.Request, parent.Request},
+		{&inherited.Drain, parent.Drain},
+	} {
+		if *field.value <= 0 {
+			*field.value = field.parent
+		}
+	}
+
+	return inherited
+}
+
+// Validate check consistency of timeouts with each other and with
+// given intervals, zero timeouts and intervals are not checked
+func (t Timeouts) Validate(discoveryInterval, checksInterval time.Duration) error {
+	var errs []error
+	invalid := func(name, reason string, args ...any) {
+		errs = append(errs, ErrInvalidTimeout{Timeout: name, Reason: fmt.Sprintf(reason, args...)})
+	}
+
+	if t.Discovery > 0 && discoveryInterval > 0 && t.Discovery >= discoveryInterval {
+		invalid("discovery", "%s should be shorter than discovery interval %s", t.Discovery, discoveryInterval)
+	}
+	if t.Check > 0 && checksInterval > 0 && t.Check >= checksInterval {
+		invalid("check", "%s should be shorter than checks interval %s", t.Check, checksInterval)
+	}
+	if t.Dial > 0 && t.Check > 0 && t.Dial > t.Check {
+		invalid("dial", "%s should not be longer than check timeout %s", t.Dial, t.Check)
+	}
+	if t.Dial > 0 && t.Request > 0 && t.Dial > t.Request {
+		invalid("dial", "%s should not be longer than request timeout %s", t.Dial, t.Request)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Timeouts return deadlines of list
+// operations with inherited defaults
+func (l *ServicesList) Timeouts() Timeouts {
+	return l.timeouts
+}
+
+// Timeouts return deadlines of list operations
+// with inherited defaults, they are the same
+// for all shards
+func (l *ShardedServicesList) Timeouts() Timeouts {
+	return l.shards[0].Timeouts()
+}
@@ -0,0 +1,92 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestTimeoutsInherit(t *testing.T) {
+	var unset *Timeouts
+	if unset.Inherit(DefaultTimeouts()) != DefaultTimeouts() {
+		t.Errorf("unset timeouts should inherit all parent timeouts")
+	}
+
+	pool := &Timeouts{Check: time.Second}
+	list := &Timeouts{Dial: 500 * time.Millisecond}
+
+	inherited := list.Inherit(pool.Inherit(DefaultTimeouts()))
+	expected := DefaultTimeouts()
+	expected.Check, expected.Dial = time.Second, 500*time.Millisecond
+	if inherited != expected {
+		t.Errorf("unexpected inherited timeouts %+v", inherited)
+	}
+
+	err := Timeouts{Check: 2 * time.Second, Dial: 3 * time.Second}.Validate(0, time.Second)
+
+	var invalid ErrInvalidTimeout
+	if !errors.As(err, &invalid) || invalid.Timeout != "check" {
+		t.Fatalf("check timeout longer than interval should be rejected, got %v", err)
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf("all inconsistencies should be reported, got %v", err)
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		Timeouts:       &Timeouts{Check: 20 * time.Millisecond},
+		HealthChecker: HealthCheckerFunc(func(ctx context.Context, _ service.IService) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	})
+	defer list.Close()
+
+	if list.Timeouts().Drain != defaultDrainTimeout {
+		t.Errorf("unset timeouts should be inherited from defaults")
+	}
+
+	err := list.CheckHealth(context.Background(), newHealthyService("https://1gateway.fm"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("check should be interrupted by check timeout, got %v", err)
+	}
+}
+
+func TestPoolRegistryTimeouts(t *testing.T) {
+	registry := NewPoolRegistry(&PoolRegistryOpts{
+		Timeouts: &Timeouts{Check: 2 * time.Second, Request: time.Minute},
+	})
+	defer registry.Close()
+
+	listOpts := &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Second,
+	}
+
+	_, err := registry.Create(&ServicesPoolsOpts{Name: "provers", ListOpts: listOpts}, false)
+	if !errors.As(err, &ErrInvalidTimeout{}) {
+		t.Fatalf("registry check timeout longer than pool checks interval should be rejected, got %v", err)
+	}
+
+	pool, err := registry.Create(&ServicesPoolsOpts{
+		Name:     "provers",
+		ListOpts: listOpts,
+		Timeouts: &Timeouts{Check: 500 * time.Millisecond},
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected create error: %s", err)
+	}
+
+	timeouts := pool.List().Timeouts()
+	if timeouts.Check != 500*time.Millisecond || timeouts.Request != time.Minute || timeouts.Discovery != defaultDiscoveryTimeout {
+		t.Errorf("unexpected pool timeouts %+v", timeouts)
+	}
+}
@@ -0,0 +1,198 @@
+package pool
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// Tombstone is record of service removed from the list,
+// it is kept for configured period so late reports and
+// completions could still resolve the service id
+type Tombstone struct {
+	Service service.IService `json:"-"`
+	ID      string           `json:"id"`
+	Address string           `json:"address"`
+	Reason  string           `json:"reason"`
+	Removed time.Time        `json:"removed"`
+}
+
+// tombstones is set of tombstones of removed services,
+// expired tombstones are pruned lazily on access
+type tombstones struct {
+	ttl time.Duration
+	max int
+
+	mu      sync.Mutex
+	entries map[string]Tombstone
+}
+
+// newTombstones create tombstones set keeping tombstones for
+// given period, at most given number of tombstones is kept
+// (0 for unbounded). Tombstones are disabled for zero period
+func newTombstones(ttl time.Duration, budget *MemoryBudget) *tombstones {
+	t := &tombstones{
+		ttl:     ttl,
+		entries: make(map[string]Tombstone),
+	}
+	if budget != nil {
+		t.max = budget.MaxTombstones
+	}
+
+	return t
+}
+
+// bury add tombstone of given removed service
+func (t *tombstones) bury(srv service.IService, reason string) {
+	if t.ttl <= 0 {
+		return
+	}
+
+	defer t.mu.Unlock()
+	t.mu.Lock()
+
+	now := time.Now()
+	t.prune(now)
+
+	t.entries[srv.ID()] = Tombstone{
+		Service: srv,
+		ID:      srv.ID(),
+		Address: srv.Address(),
+		Reason:  reason,
+		Removed: now,
+	}
+
+	if t.max > 0 && len(t.entries) > t.max {
+		oldest := ""
+		for id, tombstone := range t.entries {
+			if oldest == "" || tombstone.Removed.Before(t.entries[oldest].Removed) {
+				oldest = id
+			}
+		}
+		delete(t.entries, oldest)
+	}
+}
+
+// forget remove tombstone of service
+// with given id, e.g. when it rejoins
+func (t *tombstones) forget(id string) {
+	defer t.mu.Unlock()
+	t.mu.Lock()
+
+	delete(t.entries, id)
+}
+
+// count return number of kept tombstones
+func (t *tombstones) count() int {
+	defer t.mu.Unlock()
+	t.mu.Lock()
+
+	return len(t.entries)
+}
+
+// lookup return tombstone of service with given id
+func (t *tombstones) lookup(id string) (Tombstone, bool) {
+	defer t.mu.Unlock()
+	t.mu.Lock()
+
+	t.prune(time.Now())
+
+	tombstone, ok := t.entries[id]
+	return tombstone, ok
+}
+
+// list return tombstones ordered by removal
+func (t *tombstones) list() []Tombstone {
+	t.mu.Lock()
+	t.prune(time.Now())
+
+	list := make([]Tombstone, 0, len(t.entries))
+	for _, tombstone := range t.entries {
+		list = append(list, tombstone)
+	}
+	t.mu.Unlock()
+
+	sortTombstones(list)
+
+	return list
+}
+
+// prune remove expired tombstones.
+// Should be called under tombstones lock
+func (t *tombstones) prune(now time.Time) {
+	for id, tombstone := range t.entries {
+		if now.Sub(tombstone.Removed) >= t.ttl {
+			delete(t.entries, id)
+		}
+	}
+}
+
+// Resolve return service with given id that is member of the list
+// or was removed recently and still has tombstone, nil is returned
+// for unknown services. Removed flag is set for tombstoned services
+func (l *ServicesList) Resolve(id string) (srv service.IService, removed bool) {
+	l.mu.RLock()
+	srv = l.member(id)
+	l.mu.RUnlock()
+
+	if srv != nil {
+		return srv, false
+	}
+
+	if tombstone, ok := l.tombstones.lookup(id); ok {
+		return tombstone.Service, true
+	}
+
+	return nil, false
+}
+
+// Tombstones return tombstones of recently
+// removed services ordered by removal
+func (l *ServicesList) Tombstones() []Tombstone {
+	return l.tombstones.list()
+}
+
+// member return member of the list with given
+// id or nil. Should be called under the list lock
+func (l *ServicesList) member(id string) service.IService {
+	if srv, ok := l.jail[id]; ok {
+		return srv
+	}
+
+	if item, ok := l.review[id]; ok {
+		return item.Service
+	}
+
+	return findService(l.healthy, id)
+}
+
+// Resolve return member or recently
+// removed service with given id
+func (l *ShardedServicesList) Resolve(id string) (service.IService, bool) {
+	return l.shard(id).Resolve(id)
+}
+
+// Tombstones return tombstones of recently removed
+// services of all shards ordered by removal
+func (l *ShardedServicesList) Tombstones() []Tombstone {
+	var list []Tombstone
+	for _, shard := range l.shards {
+		list = append(list, shard.Tombstones()...)
+	}
+
+	sortTombstones(list)
+
+	return list
+}
+
+// sortTombstones sort given tombstones by removal and id
+func sortTombstones(list []Tombstone) {
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Removed.Equal(list[j].Removed) {
+			return list[i].Removed.Before(list[j].Removed)
+		}
+		return list[i].ID < list[j].ID
+	})
+}
@@ -0,0 +1,92 @@
+package pool
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTombstones(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		ReviewPolicy:   &ReviewPolicy{VerificationFailureThreshold: 1, Window: time.Minute},
+		TombstoneTTL:   100 * time.Millisecond,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	if resolved, removed := list.Resolve(srv.ID()); resolved != srv || removed {
+		t.Fatalf("member should be resolved")
+	}
+
+	list.RemoveFromHealthyByIndex(0)
+
+	resolved, removed := list.Resolve(srv.ID())
+	if resolved != srv || !removed {
+		t.Fatalf("removed service should be resolved by tombstone")
+	}
+
+	// late verification failure does not bring removed service back
+	list.ReportVerificationFailure(srv)
+	if len(list.UnderReview()) != 0 || list.IsServiceExists(srv) {
+		t.Errorf("removed service should not be quarantined")
+	}
+
+	handler := NewAdminHandler(list)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/tombstones", nil))
+
+	var tombstones []Tombstone
+	if err := json.NewDecoder(rec.Body).Decode(&tombstones); err != nil {
+		t.Fatalf("decode tombstones: %s", err)
+	}
+	if len(tombstones) != 1 || tombstones[0].ID != srv.ID() || tombstones[0].Reason != "removed" {
+		t.Errorf("unexpected tombstones %+v", tombstones)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if resolved, _ := list.Resolve(srv.ID()); resolved != nil {
+		t.Errorf("expired tombstone should not be resolved")
+	}
+
+	// rejoined service is member again
+	list.Add(srv)
+	list.RemoveFromHealthyByIndex(0)
+	list.Add(srv)
+
+	if _, removed := list.Resolve(srv.ID()); removed || len(list.Tombstones()) != 0 {
+		t.Errorf("tombstone of rejoined service should be removed")
+	}
+}
+
+func TestTombstonesBudget(t *testing.T) {
+	list := newServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		TombstoneTTL:   time.Minute,
+		MemoryBudget:   &MemoryBudget{MaxTombstones: 1},
+	})
+	defer list.Close()
+
+	first := newHealthyService("https://1gateway.fm")
+	second := newHealthyService("https://2gateway.fm")
+	list.Add(first)
+	list.Add(second)
+
+	list.RemoveFromHealthyByIndex(0)
+	time.Sleep(time.Millisecond)
+	list.RemoveFromHealthyByIndex(0)
+
+	if tombstones := list.Tombstones(); len(tombstones) != 1 || tombstones[0].ID != second.ID() {
+		t.Errorf("the oldest tombstone should be evicted, got %+v", tombstones)
+	}
+}
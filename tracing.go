@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+const (
+	tracerName = "github.com/gateway-fm/prover-pool-lib"
+
+	spanDiscover    = "pool.discover"
+	spanHealthCheck = "pool.healthcheck"
+	spanNext        = "pool.next"
+
+	attrPool           = "pool.name"
+	attrDiscovered     = "pool.discovered"
+	attrChanged        = "pool.discovery.changed"
+	attrServiceID      = "pool.service.id"
+	attrServiceAddress = "pool.service.address"
+	attrServiceNode    = "pool.service.node"
+	attrSelected       = "pool.selected"
+)
+
+// newTracer create tracer of pool spans with given
+// provider, global provider is used for nil
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	return provider.Tracer(tracerName, trace.WithInstrumentationVersion(ReadBuildInfo().Version))
+}
+
+// serviceAttributes return span attributes of given service
+func serviceAttributes(srv service.IService) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String(attrServiceID, srv.ID()),
+		attribute.String(attrServiceAddress, srv.Address()),
+		attribute.String(attrServiceNode, srv.NodeName()),
+	}
+}
+
+// endSpan record given error in given span and end it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// traceNext start span of selection in given context, selections
+// are traced only as part of existing traces, e.g. of request
+// handling, so background selections don't produce root spans
+func traceNext(ctx context.Context, tracer trace.Tracer, pool string) (context.Context, trace.Span, bool) {
+	if tracer == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx, nil, false
+	}
+
+	ctx, span := tracer.Start(ctx, spanNext, trace.WithAttributes(attribute.String(attrPool, pool)))
+
+	return ctx, span, true
+}
+
+// endNext record selected service in given span of selection and end it
+func endNext(span trace.Span, srv service.IService) {
+	if srv == nil {
+		span.SetAttributes(attribute.Bool(attrSelected, false))
+		span.SetStatus(codes.Error, "no healthy services are selected")
+		span.End()
+		return
+	}
+
+	span.SetAttributes(attribute.Bool(attrSelected, true))
+	span.SetAttributes(serviceAttributes(srv)...)
+	span.End()
+}
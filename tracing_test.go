@@ -0,0 +1,194 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// recordingProvider is trace provider recording ended spans
+type recordingProvider struct {
+	noop.TracerProvider
+
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{provider: p}
+}
+
+// ended return ended spans with given name
+func (p *recordingProvider) ended(name string) []*recordedSpan {
+	defer p.mu.Unlock()
+	p.mu.Lock()
+
+	var spans []*recordedSpan
+	for _, span := range p.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+type recordingTracer struct {
+	noop.Tracer
+	provider *recordingProvider
+}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	span := &recordedSpan{
+		name:       name,
+		provider:   t.provider,
+		attributes: make(map[attribute.Key]attribute.Value),
+		parent:     trace.SpanContextFromContext(ctx),
+	}
+	config := trace.NewSpanStartConfig(opts...)
+	span.SetAttributes(config.Attributes()...)
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordedSpan struct {
+	noop.Span
+
+	name       string
+	provider   *recordingProvider
+	parent     trace.SpanContext
+	attributes map[attribute.Key]attribute.Value
+	status     codes.Code
+}
+
+func (s *recordedSpan) SetAttributes(kv ...attribute.KeyValue) {
+	for _, attr := range kv {
+		s.attributes[attr.Key] = attr.Value
+	}
+}
+
+func (s *recordedSpan) SetStatus(code codes.Code, _ string) {
+	s.status = code
+}
+
+func (s *recordedSpan) End(...trace.SpanEndOption) {
+	defer s.provider.mu.Unlock()
+	s.provider.mu.Lock()
+
+	s.provider.spans = append(s.provider.spans, s)
+}
+
+// failingDiscovery is discovery failing with given error
+type failingDiscovery struct {
+	err error
+}
+
+func (d failingDiscovery) Discover(string) ([]service.IService, error) {
+	return nil, d.err
+}
+
+func TestTracingNextAndHealthCheck(t *testing.T) {
+	provider := &recordingProvider{}
+
+	list := NewServicesList("traced", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  time.Hour,
+		ChecksInterval: time.Hour,
+		TracerProvider: provider,
+	})
+	defer list.Close()
+
+	srv := newSwitchableService("https://1gateway.fm")
+	list.Add(srv)
+
+	// selections outside of traces are not traced
+	list.Next()
+	if spans := provider.ended(spanNext); len(spans) != 0 {
+		t.Fatalf("expected no selection spans without parent, got %d", len(spans))
+	}
+
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:  trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), parent)
+
+	if list.NextContext(ctx) == nil {
+		t.Fatalf("unexpected no healthy services")
+	}
+
+	spans := provider.ended(spanNext)
+	if len(spans) != 1 {
+		t.Fatalf("expected one selection span, got %d", len(spans))
+	}
+	if spans[0].parent.TraceID() != parent.TraceID() {
+		t.Errorf("selection span should be part of the request trace")
+	}
+	if id := spans[0].attributes[attrServiceID].AsString(); id != srv.ID() {
+		t.Errorf("expected selected service %s, got %s", srv.ID(), id)
+	}
+
+	srv.down.Store(true)
+	if err := list.CheckHealth(context.Background(), srv); err == nil {
+		t.Fatalf("expected healthcheck error")
+	}
+
+	checks := provider.ended(spanHealthCheck)
+	if len(checks) == 0 || checks[len(checks)-1].status != codes.Error {
+		t.Fatalf("expected failed healthcheck span")
+	}
+	if pool := checks[len(checks)-1].attributes[attrPool].AsString(); pool != "traced" {
+		t.Errorf("expected pool traced, got %s", pool)
+	}
+}
+
+func TestTracingDiscover(t *testing.T) {
+	provider := &recordingProvider{}
+
+	pool := NewServicesPool(&ServicesPoolsOpts{
+		Name: "traced",
+		Discovery: &staticDiscovery{services: []service.IService{
+			newHealthyService("https://1gateway.fm"),
+			newHealthyService("https://2gateway.fm"),
+		}},
+		ListOpts:       &ServicesListOpts{TryUpTries: 5, TryUpInterval: time.Hour, ChecksInterval: time.Hour},
+		TracerProvider: provider,
+	}).(*ServicesPool)
+	defer pool.Close()
+
+	if err := pool.DiscoverServices(); err != nil {
+		t.Fatalf("unexpected discovery error: %s", err)
+	}
+
+	spans := provider.ended(spanDiscover)
+	if len(spans) != 1 {
+		t.Fatalf("expected one discovery span, got %d", len(spans))
+	}
+	if discovered := spans[0].attributes[attrDiscovered].AsInt64(); discovered != 2 {
+		t.Errorf("expected 2 discovered services, got %d", discovered)
+	}
+
+	// list inherits provider of the pool
+	if pool.list.(*ServicesList).tracer.(recordingTracer).provider != provider {
+		t.Errorf("list should inherit tracer provider of the pool")
+	}
+
+	failing := errors.New("registry is down")
+	pool.discovery = failingDiscovery{err: failing}
+	if err := pool.DiscoverServices(); !errors.Is(err, failing) {
+		t.Fatalf("expected discovery error, got %v", err)
+	}
+
+	if spans := provider.ended(spanDiscover); len(spans) != 2 || spans[1].status != codes.Error {
+		t.Errorf("expected failed discovery span")
+	}
+}
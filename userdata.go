@@ -0,0 +1,92 @@
+package pool
+
+import (
+	"io"
+)
+
+// SetUserData attach given value with given key to the list entry
+// of service with given id. Data lives while service is a member
+// of the list and is released on its removal, values implementing
+// io.Closer are closed
+func (l *ServicesList) SetUserData(id, key string, value interface{}) error {
+	defer l.mu.Unlock()
+	l.mu.Lock()
+
+	if !l.isMember(id) {
+		return ErrServiceNotFound{ID: id}
+	}
+
+	data, ok := l.userData[id]
+	if !ok {
+		data = make(map[string]interface{})
+		l.userData[id] = data
+	}
+	data[key] = value
+
+	return nil
+}
+
+// UserData return value attached with given key
+// to the list entry of service with given id
+func (l *ServicesList) UserData(id, key string) (interface{}, bool) {
+	defer l.mu.RUnlock()
+	l.mu.RLock()
+
+	value, ok := l.userData[id][key]
+	return value, ok
+}
+
+// isMember check if service with given id is in healthy,
+// jail or review. Should be called under the list lock
+func (l *ServicesList) isMember(id string) bool {
+	return l.member(id) != nil
+}
+
+// releasedUserData is value implementing io.Closer
+// released from the list entry of removed service
+type releasedUserData struct {
+	id     string
+	key    string
+	closer io.Closer
+}
+
+// releaseUserData remove all data attached to the list entry of
+// service with given id and queue values implementing io.Closer
+// to be closed once the list lock is released by flushEvents, so
+// slow closers don't block the list. Should be called under the
+// list lock
+func (l *ServicesList) releaseUserData(id string) {
+	data, ok := l.userData[id]
+	if !ok {
+		return
+	}
+	delete(l.userData, id)
+
+	for key, value := range data {
+		if closer, ok := value.(io.Closer); ok {
+			l.pendingClosers = append(l.pendingClosers, releasedUserData{id: id, key: key, closer: closer})
+		}
+	}
+}
+
+// closeUserData close given released user data.
+// Should not be called under the list lock
+func (l *ServicesList) closeUserData(released []releasedUserData) {
+	for _, data := range released {
+		if err := data.closer.Close(); err != nil {
+			l.log().Warn("close user data error", "service", data.id, "key", data.key, "error", err)
+		}
+	}
+}
+
+// SetUserData attach given value with given key to
+// the list entry of service with given id
+func (l *ShardedServicesList) SetUserData(id, key string, value interface{}) error {
+	return l.shard(id).SetUserData(id, key, value)
+}
+
+// UserData return value attached with given key
+// to the list entry of service with given id
+func (l *ShardedServicesList) UserData(id, key string) (interface{}, bool) {
+	return l.shard(id).UserData(id, key)
+}
@@ -0,0 +1,93 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type closerData struct {
+	closed bool
+}
+
+func (d *closerData) Close() error {
+	d.closed = true
+	return nil
+}
+
+func TestServicesListUserData(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+
+	srv := newHealthyService("https://1gateway.fm")
+
+	if err := list.SetUserData(srv.ID(), "client", &closerData{}); !errors.As(err, &ErrServiceNotFound{}) {
+		t.Fatalf("user data should not be attached to non-member service, got %v", err)
+	}
+
+	list.Add(srv)
+
+	data := &closerData{}
+	if err := list.SetUserData(srv.ID(), "client", data); err != nil {
+		t.Fatalf("unexpected user data error: %s", err)
+	}
+
+	if value, ok := list.UserData(srv.ID(), "client"); !ok || value != data {
+		t.Fatalf("attached user data expected")
+	}
+
+	list.FromHealthyToJail(srv.ID())
+
+	if _, ok := list.UserData(srv.ID(), "client"); !ok || data.closed {
+		t.Fatalf("user data should be kept while service is a member")
+	}
+
+	list.RemoveFromJail(srv)
+
+	if _, ok := list.UserData(srv.ID(), "client"); ok || !data.closed {
+		t.Errorf("user data should be released and closed on removal")
+	}
+}
+
+// listCloserData is user data which
+// reads the list when it is closed
+type listCloserData struct {
+	list IServicesList
+}
+
+func (d *listCloserData) Close() error {
+	d.list.CountAll()
+	return nil
+}
+
+func TestServicesListUserDataClosedUnlocked(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+	})
+	defer list.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+	list.Add(srv)
+
+	if err := list.SetUserData(srv.ID(), "client", &listCloserData{list: list}); err != nil {
+		t.Fatalf("unexpected user data error: %s", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		list.RemoveByID(srv.ID())
+		close(done)
+	}()
+
+	// user data is closed after the list lock is released
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("user data should be closed without holding the list lock")
+	}
+}
@@ -1,6 +1,8 @@
 package pool
 
 import (
+	"context"
+	"slices"
 	"time"
 
 	"github.com/gateway-fm/prover-pool-lib/service"
@@ -18,11 +20,57 @@ func Sleep(t time.Duration, cancelCh <-chan struct{}) {
 	}
 }
 
-// deleteFromSlice delete item with
-// given index from provided slice
-func deleteFromSlice(slice []service.IService, index int) []service.IService {
-	//https://stackoverflow.com/questions/37334119/how-to-delete-an-element-from-a-slice-in-golang
-	temp := make([]service.IService, 0)
-	temp = append(temp, slice[:index]...)
-	return append(temp, slice[index+1:]...)
+// SleepContext sleep given time or until given context is done
+func SleepContext(ctx context.Context, t time.Duration) {
+	Sleep(t, ctx.Done())
+}
+
+// RemovalStrategy represent how services
+// are removed from the healthy slice
+type RemovalStrategy int
+
+const (
+	// RemovalPreserveOrder shift services after the removed one,
+	// it's O(n) but keeps round-robin order, so every remaining
+	// service is selected exactly once per round
+	RemovalPreserveOrder RemovalStrategy = iota
+
+	// RemovalSwap move the last service to the place of the removed
+	// one, it's O(1) but the moved service could be skipped
+	// or selected twice in the current round-robin round
+	RemovalSwap
+)
+
+// String return removal strategy name
+func (s RemovalStrategy) String() string {
+	switch s {
+	case RemovalSwap:
+		return "swap"
+	default:
+		return "preserve_order"
+	}
+}
+
+// deleteFromSlice delete item with given index from
+// provided slice according to given removal strategy
+func deleteFromSlice(slice []service.IService, index int, strategy RemovalStrategy) []service.IService {
+	if strategy == RemovalSwap {
+		last := len(slice) - 1
+		slice[index] = slice[last]
+		slice[last] = nil
+		return slice[:last]
+	}
+
+	return slices.Delete(slice, index, index+1)
+}
+
+// maxServiceWeight is weight of service without load
+const maxServiceWeight = 100
+
+// serviceWeight return weight of given service in [1, 101]
+// range which is higher for less loaded services
+func serviceWeight(srv service.IService) uint16 {
+	load := min(max(srv.Load(), 0), 1)
+
+	return uint16((1-load)*maxServiceWeight) + 1
 }
@@ -0,0 +1,51 @@
+package pool
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestServicesListRemovalStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy RemovalStrategy
+		expected []int // indexes of originally added services selected after removal
+	}{
+		{"preserve order", RemovalPreserveOrder, []int{3, 4, 0, 1}},
+		{"swap", RemovalSwap, []int{4, 3, 0, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := NewServicesList("testServicesList", &ServicesListOpts{
+				TryUpTries:     5,
+				TryUpInterval:  1 * time.Second,
+				ChecksInterval: 1 * time.Second,
+				Removal:        tt.strategy,
+			})
+
+			services := make([]service.IService, 5)
+			for i := range services {
+				services[i] = newHealthyService(fmt.Sprintf("https://%dgateway.fm", i))
+				list.Add(services[i])
+			}
+
+			// select services 1 and 2, then remove the last selected one
+			list.Next()
+			removed := list.Next()
+			if removed != services[2] {
+				t.Fatalf("unexpected selected service %s", removed.Address())
+			}
+			list.FromHealthyToJail(removed.ID())
+
+			for _, i := range tt.expected {
+				if srv := list.Next(); srv != services[i] {
+					t.Errorf("expected %s to be selected, got %s", services[i].Address(), srv.Address())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package pool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// Functions that could be exported by WASM policy module.
+// Service is passed to the functions as json written to
+// the module memory allocated by the exported alloc function
+const (
+	wasmAllocFunc = "alloc" // alloc(size i32) -> ptr i32, required
+	wasmAdmitFunc = "admit" // admit(ptr i32, len i32) -> i32, non-zero to admit
+	wasmAllowFunc = "allow" // allow(ptr i32, len i32) -> i32, non-zero to allow
+	wasmScoreFunc = "score" // score(ptr i32, len i32, score f64) -> f64
+)
+
+// defaultWasmCallTimeout is default limit of single WASM policy call
+const defaultWasmCallTimeout = 100 * time.Millisecond
+
+// IReloadablePolicy is policy which implementation
+// could be replaced at runtime by given module
+type IReloadablePolicy interface {
+	IPolicy
+
+	// Reload replace policy implementation by given module
+	Reload(module []byte) error
+}
+
+// WasmPolicy is IPolicy implementation that run policy
+// functions from sandboxed WASM module which could
+// be hot-swapped at runtime without restart. Every
+// call runs on fresh module instance, so memory
+// allocated by the module is not accumulated and
+// call interrupted by the timeout is not affecting
+// the following ones
+type WasmPolicy struct {
+	name    string
+	timeout time.Duration
+
+	ctx     context.Context
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+
+	mu sync.RWMutex
+}
+
+// WasmPolicyOpts is options of WasmPolicy
+type WasmPolicyOpts struct {
+	Timeout time.Duration // limit of single module call, call is failed once exceeded (100ms by default)
+}
+
+// wasmServiceInput is json representation
+// of service passed to the WASM module
+type wasmServiceInput struct {
+	ID       string   `json:"id"`
+	Address  string   `json:"address"`
+	NodeName string   `json:"node_name"`
+	Status   string   `json:"status"`
+	Tags     []string `json:"tags"`
+	Load     float32  `json:"load"`
+}
+
+// NewWasmPolicy create new WasmPolicy
+// with given name from given module
+func NewWasmPolicy(name string, module []byte) (*WasmPolicy, error) {
+	return NewWasmPolicyWithOpts(name, module, &WasmPolicyOpts{})
+}
+
+// NewWasmPolicyWithOpts create new WasmPolicy with
+// given name from given module and given options
+func NewWasmPolicyWithOpts(name string, module []byte, opts *WasmPolicyOpts) (*WasmPolicy, error) {
+	ctx := context.Background()
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultWasmCallTimeout
+	}
+
+	// module execution is stopped once
+	// context of the call is done
+	config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+
+	p := &WasmPolicy{
+		name:    name,
+		timeout: timeout,
+		ctx:     ctx,
+		runtime: wazero.NewRuntimeWithConfig(ctx, config),
+	}
+
+	// most of the toolchains require wasi
+	// even for modules without any io
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, p.runtime); err != nil {
+		return nil, fmt.Errorf("instantiate wasi: %w", err)
+	}
+
+	if err := p.Reload(module); err != nil {
+		_ = p.runtime.Close(ctx)
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Name return policy name
+func (p *WasmPolicy) Name() string {
+	return p.name
+}
+
+// Reload replace policy implementation by given module, the
+// previous module is closed once its calls are finished
+func (p *WasmPolicy) Reload(module []byte) error {
+	compiled, err := p.runtime.CompileModule(p.ctx, module)
+	if err != nil {
+		return fmt.Errorf("compile wasm policy %s: %w", p.name, err)
+	}
+
+	if _, ok := compiled.ExportedFunctions()[wasmAllocFunc]; !ok {
+		_ = compiled.Close(p.ctx)
+		return fmt.Errorf("wasm policy %s does not export %s function", p.name, wasmAllocFunc)
+	}
+
+	// module is instantiated once, so module
+	// which could not be run is rejected early
+	instance, err := p.instantiate(p.ctx, compiled)
+	if err != nil {
+		_ = compiled.Close(p.ctx)
+		return fmt.Errorf("instantiate wasm policy %s: %w", p.name, err)
+	}
+	_ = instance.Close(p.ctx)
+
+	p.mu.Lock()
+	previous := p.module
+	p.module = compiled
+	p.mu.Unlock()
+
+	if previous != nil {
+		_ = previous.Close(p.ctx)
+	}
+
+	return nil
+}
+
+// instantiate create new anonymous instance of given
+// module, which execution is limited by the call timeout
+func (p *WasmPolicy) instantiate(ctx context.Context, module wazero.CompiledModule) (api.Module, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	return p.runtime.InstantiateModule(ctx, module, wazero.NewModuleConfig().WithName(""))
+}
+
+// Admit call module admit function if it is exported
+func (p *WasmPolicy) Admit(srv service.IService) error {
+	results, err := p.call(wasmAdmitFunc, srv)
+	if err != nil {
+		return err
+	}
+
+	if results != nil && results[0] == 0 {
+		return errors.New("not admitted by wasm policy")
+	}
+
+	return nil
+}
+
+// Allow call module allow function if it is exported,
+// service is not allowed if module call is failed
+func (p *WasmPolicy) Allow(srv service.IService) bool {
+	results, err := p.call(wasmAllowFunc, srv)
+	if err != nil {
+		return false
+	}
+
+	return results == nil || results[0] != 0
+}
+
+// Score call module score function if it is exported,
+// given score is returned as is if module call is failed
+func (p *WasmPolicy) Score(srv service.IService, score float64) float64 {
+	results, err := p.call(wasmScoreFunc, srv, api.EncodeF64(score))
+	if err != nil || results == nil {
+		return score
+	}
+
+	return api.DecodeF64(results[0])
+}
+
+// Close release module and runtime resources
+func (p *WasmPolicy) Close() error {
+	return p.runtime.Close(p.ctx)
+}
+
+// call write given service to the memory of fresh module
+// instance and call function with given name, returns nil
+// results if function is not exported by the module. Call
+// is failed if it's not finished within the timeout
+func (p *WasmPolicy) call(name string, srv service.IService, params ...uint64) ([]uint64, error) {
+	defer p.mu.RUnlock()
+	p.mu.RLock()
+
+	if _, ok := p.module.ExportedFunctions()[name]; !ok {
+		return nil, nil
+	}
+
+	input, err := json.Marshal(newWasmServiceInput(srv))
+	if err != nil {
+		return nil, fmt.Errorf("marshal wasm policy input: %w", err)
+	}
+
+	if len(input) > math.MaxUint32 {
+		return nil, errors.New("wasm policy input is too big")
+	}
+
+	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
+	defer cancel()
+
+	instance, err := p.runtime.InstantiateModule(ctx, p.module, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return nil, fmt.Errorf("instantiate wasm policy %s: %w", p.name, err)
+	}
+	defer func() { _ = instance.Close(p.ctx) }()
+
+	allocated, err := instance.ExportedFunction(wasmAllocFunc).Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, fmt.Errorf("alloc wasm policy input: %w", err)
+	}
+
+	ptr := uint32(allocated[0])
+	if instance.Memory() == nil || !instance.Memory().Write(ptr, input) {
+		return nil, fmt.Errorf("write wasm policy input: out of memory range")
+	}
+
+	results, err := instance.ExportedFunction(name).Call(ctx, append([]uint64{uint64(ptr), uint64(len(input))}, params...)...)
+	if err != nil {
+		return nil, fmt.Errorf("call wasm policy %s function: %w", name, err)
+	}
+
+	return results, nil
+}
+
+// newWasmServiceInput create wasmServiceInput from given service
+func newWasmServiceInput(srv service.IService) *wasmServiceInput {
+	input := &wasmServiceInput{
+		ID:       srv.ID(),
+		Address:  srv.Address(),
+		NodeName: srv.NodeName(),
+		Status:   srv.Status().String(),
+		Tags:     make([]string, 0, len(srv.Tags())),
+		Load:     srv.Load(),
+	}
+
+	for tag := range srv.Tags() {
+		input.Tags = append(input.Tags, tag)
+	}
+
+	return input
+}
@@ -0,0 +1,110 @@
+package pool
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// constAllowModule return minimal WASM module that exports
+// memory, alloc and allow function returning given value
+func constAllowModule(allow byte) []byte {
+	return []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // magic and version
+		0x01, 0x0c, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f, // types
+		0x03, 0x03, 0x02, 0x00, 0x01, // functions
+		0x05, 0x03, 0x01, 0x00, 0x01, // memory
+		0x07, 0x1a, 0x03, // exports
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x05, 'a', 'l', 'l', 'o', 'c', 0x00, 0x00,
+		0x05, 'a', 'l', 'l', 'o', 'w', 0x00, 0x01,
+		0x0a, 0x0c, 0x02, // code
+		0x05, 0x00, 0x41, 0x80, 0x08, 0x0b, // alloc returns 1024
+		0x04, 0x00, 0x41, allow, 0x0b, // allow returns given value
+	}
+}
+
+// loopingAllowModule return minimal WASM module that exports
+// memory, alloc and allow function which never returns
+func loopingAllowModule() []byte {
+	return []byte{
+		0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // magic and version
+		0x01, 0x0c, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7f, // types
+		0x03, 0x03, 0x02, 0x00, 0x01, // functions
+		0x05, 0x03, 0x01, 0x00, 0x01, // memory
+		0x07, 0x1a, 0x03, // exports
+		0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+		0x05, 'a', 'l', 'l', 'o', 'c', 0x00, 0x00,
+		0x05, 'a', 'l', 'l', 'o', 'w', 0x00, 0x01,
+		0x0a, 0x11, 0x02, // code
+		0x05, 0x00, 0x41, 0x80, 0x08, 0x0b, // alloc returns 1024
+		0x09, 0x00, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x41, 0x01, 0x0b, // allow loops forever
+	}
+}
+
+func TestWasmPolicyTimeout(t *testing.T) {
+	policy, err := NewWasmPolicyWithOpts("wasm", loopingAllowModule(), &WasmPolicyOpts{Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected wasm policy error: %s", err)
+	}
+	defer policy.Close()
+
+	srv := newHealthyService("https://1gateway.fm")
+
+	for i := 0; i < 2; i++ {
+		done := make(chan bool)
+		go func() {
+			done <- policy.Allow(srv)
+		}()
+
+		select {
+		case allowed := <-done:
+			if allowed {
+				t.Errorf("service should not be allowed by interrupted call")
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("looping module call should be interrupted by the timeout")
+		}
+	}
+
+	// interrupted calls don't break the policy
+	if err := policy.Reload(constAllowModule(1)); err != nil {
+		t.Fatalf("unexpected reload error: %s", err)
+	}
+	if !policy.Allow(srv) {
+		t.Errorf("service should be allowed by reloaded wasm policy")
+	}
+}
+
+func TestWasmPolicyReload(t *testing.T) {
+	policy, err := NewWasmPolicy("wasm", constAllowModule(1))
+	if err != nil {
+		t.Fatalf("unexpected wasm policy error: %s", err)
+	}
+	defer policy.Close()
+
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Policies:       []IPolicy{policy},
+	})
+	list.Add(newHealthyService("https://1gateway.fm"))
+
+	if list.Next() == nil {
+		t.Fatalf("service should be allowed by wasm policy")
+	}
+
+	rec := httptest.NewRecorder()
+	NewAdminHandlerWithOpts(list, &AdminHandlerOpts{Unauthenticated: true}).ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/policies/wasm", bytes.NewReader(constAllowModule(0))))
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("unexpected reload status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if list.Next() != nil {
+		t.Errorf("service should be filtered out by reloaded wasm policy")
+	}
+}
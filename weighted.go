@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+// weightedRoundRobinStrategy select candidates in
+// turn proportionally to theirs weights
+type weightedRoundRobinStrategy struct {
+	mu      sync.Mutex
+	weights map[string]int // service id -> current weight
+}
+
+// WeightedRoundRobin return strategy that select candidates using
+// smooth weighted round-robin: every selection each candidate gains
+// its weight and the selected one loses total weight of candidates,
+// so selections of heavy services are interleaved with others rather
+// than done in bursts. Weight is read by service.Weight, current
+// weights of services that are no longer candidates are forgotten
+func WeightedRoundRobin() IBalancingStrategy {
+	return &weightedRoundRobinStrategy{weights: make(map[string]int)}
+}
+
+// Name return strategy name
+func (s *weightedRoundRobinStrategy) Name() string {
+	return BalancingWeightedRoundRobin.String()
+}
+
+// Select return candidate with the highest current weight
+func (s *weightedRoundRobinStrategy) Select(_ context.Context, candidates []service.IService) service.IService {
+	defer s.mu.Unlock()
+	s.mu.Lock()
+
+	var (
+		selected service.IService
+		total    int
+	)
+
+	for _, srv := range candidates {
+		weight := service.Weight(srv)
+		s.weights[srv.ID()] += weight
+		total += weight
+
+		if selected == nil || s.weights[srv.ID()] > s.weights[selected.ID()] {
+			selected = srv
+		}
+	}
+
+	if selected != nil {
+		s.weights[selected.ID()] -= total
+	}
+
+	if len(s.weights) > len(candidates) {
+		s.forget(candidates)
+	}
+
+	return selected
+}
+
+// forget drop current weights of services
+// that are not among given candidates
+func (s *weightedRoundRobinStrategy) forget(candidates []service.IService) {
+	ids := make(map[string]struct{}, len(candidates))
+	for _, srv := range candidates {
+		ids[srv.ID()] = struct{}{}
+	}
+
+	for id := range s.weights {
+		if _, ok := ids[id]; !ok {
+			delete(s.weights, id)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gateway-fm/prover-pool-lib/service"
+)
+
+func TestWeightedRoundRobin(t *testing.T) {
+	list := NewServicesList("testServicesList", &ServicesListOpts{
+		TryUpTries:     5,
+		TryUpInterval:  1 * time.Second,
+		ChecksInterval: 1 * time.Second,
+		Balancing:      BalancingWeightedRoundRobin,
+	})
+
+	large := newHealthyService("https://1gateway.fm").(*service.BaseService)
+	large.SetWeight(3)
+	medium := newMetadataService("https://2gateway.fm", map[string]string{service.WeightMetadataKey: "2"})
+	small := newHealthyService("https://3gateway.fm")
+
+	list.Add(large)
+	list.Add(medium)
+	list.Add(small)
+
+	var (
+		selected = make(map[string]int)
+		previous string
+		streak   int
+	)
+	for i := 0; i < 60; i++ {
+		id := list.Next().ID()
+		selected[id]++
+
+		if id == previous {
+			streak++
+		} else {
+			previous, streak = id, 1
+		}
+		if streak > 2 {
+			t.Fatalf("selections of heavy service should be interleaved with others")
+		}
+	}
+
+	if selected[large.ID()] != 30 || selected[medium.ID()] != 20 || selected[small.ID()] != 10 {
+		t.Errorf("selections should be proportional to weights, got %v", selected)
+	}
+
+	// jailed service is skipped, the rest keep theirs proportions
+	list.FromHealthyToJail(large.ID())
+
+	selected = make(map[string]int)
+	for i := 0; i < 30; i++ {
+		selected[list.Next().ID()]++
+	}
+
+	if selected[medium.ID()] != 20 || selected[small.ID()] != 10 {
+		t.Errorf("selections should be proportional to weights of remaining services, got %v", selected)
+	}
+}
@@ -0,0 +1,41 @@
+package pool
+
+import "sync"
+
+// workers track goroutines of the list, so Close
+// could wait for them before the list is released
+type workers struct {
+	mu     sync.Mutex
+	closed bool
+
+	wg sync.WaitGroup
+}
+
+// add register new worker and report if it could be
+// started, no workers are registered once closed
+func (w *workers) add() bool {
+	defer w.mu.Unlock()
+	w.mu.Lock()
+
+	if w.closed {
+		return false
+	}
+	w.wg.Add(1)
+
+	return true
+}
+
+// done unregister finished worker
+func (w *workers) done() {
+	w.wg.Done()
+}
+
+// close stop registering new workers
+// and wait for running ones to finish
+func (w *workers) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}